@@ -0,0 +1,65 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ConsoleSpec defines the requested console access.
+type ConsoleSpec struct {
+	// Hostname of the hypervisor the domain is running on.
+	Hypervisor string `json:"hypervisor"`
+	// UUID of the domain whose serial console is requested.
+	Domain string `json:"domain"`
+	// Point in time after which the console access is revoked.
+	ExpiresAt metav1.Time `json:"expiresAt"`
+}
+
+// ConsoleStatus defines the observed state of Console.
+type ConsoleStatus struct {
+	// WebSocket url under which the node agent serves the console.
+	// Clients need to authenticate with a certificate issued by the
+	// libvirt certificate authority.
+	URL string `json:"url,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Hypervisor",type=string,JSONPath=`.spec.hypervisor`
+// +kubebuilder:printcolumn:name="Domain",type=string,JSONPath=`.spec.domain`
+// +kubebuilder:printcolumn:name="Expires",type=date,JSONPath=`.spec.expiresAt`
+// +kubebuilder:printcolumn:name="URL",type=string,JSONPath=`.status.url`
+
+// Console is a request for break-glass access to the serial console of a domain.
+type Console struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata"`
+
+	Spec   ConsoleSpec   `json:"spec"`
+	Status ConsoleStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// ConsoleList contains a list of Console.
+type ConsoleList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+	Items           []Console `json:"items"`
+}
@@ -40,8 +40,14 @@ var (
 // addKnownTypes registers the API types with the given scheme.
 func addKnownTypes(scheme *runtime.Scheme) error {
 	scheme.AddKnownTypes(GroupVersion,
+		&Console{},
+		&ConsoleList{},
+		&Instance{},
+		&InstanceList{},
 		&Migration{},
 		&MigrationList{},
+		&MigrationPrecheck{},
+		&MigrationPrecheckList{},
 	)
 	metav1.AddToGroupVersion(scheme, GroupVersion)
 	return nil
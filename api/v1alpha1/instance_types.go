@@ -0,0 +1,388 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Label on the Instance resources holding the hypervisor the domain runs on.
+const LabelHypervisor = "kvm.cloud.sap/hypervisor"
+
+// ConditionTypeDiskIOError is True while disks of the domain report io
+// errors, e.g. because the storage backend is gone or full.
+const ConditionTypeDiskIOError = "DiskIOError"
+
+// ConditionTypeShutdown follows the shutdown requested in the spec of the
+// instance. It is False while the guest shuts down, and True once the
+// domain is off.
+const ConditionTypeShutdown = "Shutdown"
+
+// ConditionTypePowerAction follows the power action requested in the spec
+// of the instance. It is True once the action succeeded.
+const ConditionTypePowerAction = "PowerAction"
+
+// InstancePowerAction is an operation on the power state of the domain.
+// +kubebuilder:validation:Enum=Suspend;Resume;ManagedSave;Restore
+type InstancePowerAction string
+
+const (
+	// PowerActionSuspend pauses the vcpus of the running domain.
+	PowerActionSuspend InstancePowerAction = "Suspend"
+	// PowerActionResume continues the paused domain.
+	PowerActionResume InstancePowerAction = "Resume"
+	// PowerActionManagedSave saves the memory of the domain to disk and
+	// stops it, which frees its host memory.
+	PowerActionManagedSave InstancePowerAction = "ManagedSave"
+	// PowerActionRestore starts the domain from its managed save image.
+	PowerActionRestore InstancePowerAction = "Restore"
+)
+
+// InstanceIOTune are the io limits of a disk, zero means unlimited. The
+// total limits can't be combined with the read and write limits of the
+// same kind.
+type InstanceIOTune struct {
+	// +kubebuilder:validation:Minimum=0
+	TotalIOPS int64 `json:"totalIOPS,omitempty"`
+	// +kubebuilder:validation:Minimum=0
+	ReadIOPS int64 `json:"readIOPS,omitempty"`
+	// +kubebuilder:validation:Minimum=0
+	WriteIOPS int64 `json:"writeIOPS,omitempty"`
+	// +kubebuilder:validation:Minimum=0
+	TotalBytesPerSec int64 `json:"totalBytesPerSec,omitempty"`
+	// +kubebuilder:validation:Minimum=0
+	ReadBytesPerSec int64 `json:"readBytesPerSec,omitempty"`
+	// +kubebuilder:validation:Minimum=0
+	WriteBytesPerSec int64 `json:"writeBytesPerSec,omitempty"`
+}
+
+// InstanceDiskIOTune are the io limits to apply to a disk of the domain.
+type InstanceDiskIOTune struct {
+	// Target device of the disk, e.g. "vda".
+	Target         string `json:"target"`
+	InstanceIOTune `json:",inline"`
+}
+
+// InstanceShutdown requests the shutdown of the domain by the node agent,
+// e.g. while nova is unavailable.
+type InstanceShutdown struct {
+	// Time the guest gets to shut down, one minute if unset.
+	GracePeriod *metav1.Duration `json:"gracePeriod,omitempty"`
+	// Destroy the domain if the guest didn't shut down within the grace
+	// period.
+	Force bool `json:"force,omitempty"`
+}
+
+// InstanceSpec defines the desired state of Instance. Instances are
+// managed by the node agent, only the io limits of the disks can be
+// configured. They are applied to the running domain and its persistent
+// config, disks without an entry are left alone.
+type InstanceSpec struct {
+	// +listType=map
+	// +listMapKey=target
+	IOTune []InstanceDiskIOTune `json:"ioTune,omitempty"`
+	// Shut the domain down. The shutdown is performed once per generation
+	// of the instance and followed in the Shutdown condition, a domain
+	// started again afterwards is left running.
+	Shutdown *InstanceShutdown `json:"shutdown,omitempty"`
+	// Power action to perform on the domain. The action is performed once
+	// per generation of the instance and followed in the PowerAction
+	// condition.
+	PowerAction InstancePowerAction `json:"powerAction,omitempty"`
+}
+
+// InstanceFlavor is the nova flavor the domain was created with.
+type InstanceFlavor struct {
+	Name string `json:"name,omitempty"`
+	// Memory of the flavor in MiB.
+	MemoryMB int `json:"memoryMB,omitempty"`
+	VCPUs    int `json:"vcpus,omitempty"`
+	// Root disk size of the flavor in GiB.
+	DiskGB int `json:"diskGB,omitempty"`
+}
+
+// InstanceOwner is the openstack project and user owning the domain.
+type InstanceOwner struct {
+	ProjectID string `json:"projectID,omitempty"`
+	UserID    string `json:"userID,omitempty"`
+}
+
+// InstanceVCPUPin pins a vcpu of the domain to a set of host cpus.
+type InstanceVCPUPin struct {
+	VCPU   int    `json:"vcpu"`
+	CPUSet string `json:"cpuset"`
+	// Host numa nodes of the cpus in the cpuset.
+	NUMANodes []int `json:"numaNodes,omitempty"`
+}
+
+// InstancePinning describes the cpu and numa placement of the domain.
+type InstancePinning struct {
+	VCPUs []InstanceVCPUPin `json:"vcpus,omitempty"`
+	// Host cpus the emulator threads are pinned to.
+	EmulatorCPUSet string `json:"emulatorCPUSet,omitempty"`
+	// Host numa nodes the memory of the domain is allocated from.
+	MemoryNodeset string `json:"memoryNodeset,omitempty"`
+}
+
+// InstanceDisk is a disk attached to the domain.
+type InstanceDisk struct {
+	// Device type, e.g. "disk" or "cdrom".
+	Device string `json:"device,omitempty"`
+	Target string `json:"target,omitempty"`
+	Bus    string `json:"bus,omitempty"`
+	Source string `json:"source,omitempty"`
+	// Io limits of the disk currently configured in the domain.
+	IOTune *InstanceIOTune `json:"ioTune,omitempty"`
+}
+
+// InstanceInterface is a network interface attached to the domain.
+type InstanceInterface struct {
+	// Interface type, e.g. "bridge" or "vhostuser".
+	Type       string `json:"type,omitempty"`
+	MACAddress string `json:"macAddress,omitempty"`
+	Target     string `json:"target,omitempty"`
+	Model      string `json:"model,omitempty"`
+	Bridge     string `json:"bridge,omitempty"`
+	// Socket path of vhostuser interfaces.
+	SocketPath string `json:"socketPath,omitempty"`
+	// Backend driver of virtio interfaces, e.g. "vhost" or "qemu".
+	Driver string `json:"driver,omitempty"`
+	// Number of queues configured for the interface.
+	Queues int `json:"queues,omitempty"`
+	// Size of the rx and tx virtqueues, if configured.
+	RxQueueSize int `json:"rxQueueSize,omitempty"`
+	TxQueueSize int `json:"txQueueSize,omitempty"`
+	// Set if the flavor requests multiqueue (hw:vif_multiqueue_enabled),
+	// but only a single queue is configured.
+	QueueMismatch bool `json:"queueMismatch,omitempty"`
+}
+
+// InstanceRNG is a random number generator device attached to the domain.
+type InstanceRNG struct {
+	// Device model, e.g. "virtio".
+	Model string `json:"model,omitempty"`
+	// Backend model, e.g. "random" or "builtin".
+	Backend string `json:"backend,omitempty"`
+	// Host device the entropy is read from, e.g. "/dev/urandom".
+	Source string `json:"source,omitempty"`
+}
+
+// InstanceFilesystem is a host directory shared with the guest.
+type InstanceFilesystem struct {
+	// Driver of the filesystem, e.g. "virtiofs".
+	Driver string `json:"driver,omitempty"`
+	// Shared host directory, or the socket of an externally started
+	// virtiofsd.
+	Source string `json:"source,omitempty"`
+	// Tag the guest mounts the filesystem by.
+	Tag string `json:"tag,omitempty"`
+	// Size of the request queue of the device.
+	QueueSize int `json:"queueSize,omitempty"`
+}
+
+// InstanceTPM is the trusted platform module of the domain, either emulated
+// by swtpm or passed through from the host.
+type InstanceTPM struct {
+	// Device model, e.g. "tpm-crb".
+	Model string `json:"model,omitempty"`
+	// Backend type, "emulator" or "passthrough".
+	Backend string `json:"backend,omitempty"`
+	// Version of the tpm, e.g. "2.0".
+	Version string `json:"version,omitempty"`
+	// Host directory the state of the emulated tpm is kept in.
+	StatePath string `json:"statePath,omitempty"`
+	// Whether the state of the emulated tpm was found on the host. Without
+	// it the guest loses the secrets sealed by the tpm.
+	StatePresent bool `json:"statePresent,omitempty"`
+	// Size of the state of the emulated tpm on the host.
+	StateBytes int64 `json:"stateBytes,omitempty"`
+	// Whether the state is encrypted with a libvirt secret.
+	Encrypted bool `json:"encrypted,omitempty"`
+	// Whether the state is kept when the domain is undefined.
+	PersistentState bool `json:"persistentState,omitempty"`
+	// Whether the swtpm process of the active domain accepts connections,
+	// unset if unknown.
+	Running *bool `json:"running,omitempty"`
+}
+
+// InstanceDevices are the devices attached to the domain.
+type InstanceDevices struct {
+	Disks      []InstanceDisk      `json:"disks,omitempty"`
+	Interfaces []InstanceInterface `json:"interfaces,omitempty"`
+	// Random number generator devices. Guests without one may hang at boot
+	// waiting for entropy.
+	RNGs []InstanceRNG `json:"rngs,omitempty"`
+	// Host directories shared with the guest, e.g. of shared filesystem
+	// flavors.
+	Filesystems []InstanceFilesystem `json:"filesystems,omitempty"`
+}
+
+// InstanceMigrationBlocker is a reason preventing the live migration of
+// the domain.
+type InstanceMigrationBlocker struct {
+	// Machine readable reason, e.g. "PassthroughDevice".
+	Reason  string `json:"reason"`
+	Message string `json:"message,omitempty"`
+}
+
+// InstanceMigration describes whether the domain can be live migrated.
+type InstanceMigration struct {
+	LiveMigratable bool                       `json:"liveMigratable"`
+	Blockers       []InstanceMigrationBlocker `json:"blockers,omitempty"`
+}
+
+// InstancePauses accounts the time the domain spent paused on this
+// hypervisor, as observed from the libvirt lifecycle events.
+type InstancePauses struct {
+	// Number of times the domain was paused.
+	Count int64 `json:"count,omitempty"`
+	// Time the domain spent in completed pauses.
+	Duration metav1.Duration `json:"duration,omitempty"`
+	// Start of the ongoing pause, unset while the domain runs.
+	Since *metav1.Time `json:"since,omitempty"`
+	// Reason of the last pause from the libvirt event detail, e.g.
+	// "ioerror" or "migrated".
+	LastPausedReason string `json:"lastPausedReason,omitempty"`
+}
+
+// InstanceBlockJob is a block job running on a disk of the domain, e.g. the
+// copy of a volume retype or the commit of a snapshot flatten.
+type InstanceBlockJob struct {
+	// Target device of the disk, e.g. "vda".
+	Disk string `json:"disk"`
+	// Type of the job, one of pull, copy, commit, active-commit or backup.
+	Type string `json:"type"`
+	// Progress of the job in percent.
+	Progress int32 `json:"progress"`
+	// Bytes processed so far and in total, the total may still grow while
+	// the guest writes to the disk.
+	Current int64 `json:"current,omitempty"`
+	End     int64 `json:"end,omitempty"`
+	// Bandwidth limit of the job in bytes per second, 0 if unlimited.
+	Bandwidth int64 `json:"bandwidth,omitempty"`
+	// Whether a copy or active commit mirrors all writes and can be
+	// pivoted to the new image.
+	Ready bool `json:"ready,omitempty"`
+}
+
+// InstanceGuestAgent is the state of the qemu guest agent of the domain,
+// which is needed for a graceful shutdown through the agent and for the ip
+// addresses reported by the guest.
+type InstanceGuestAgent struct {
+	// Whether the guest agent is connected, i.e. running in the guest.
+	Connected bool `json:"connected"`
+	// Time the agent connected or disconnected, unset if the change
+	// happened before the node agent started.
+	LastTransitionTime *metav1.Time `json:"lastTransitionTime,omitempty"`
+	// Reason of the last change, "domain-started" or "channel".
+	Reason string `json:"reason,omitempty"`
+	// Hostname configured inside the guest, reported by the connected
+	// agent.
+	Hostname string `json:"hostname,omitempty"`
+	// Ip addresses of the guest interfaces, excluding loopback.
+	IPs []string `json:"ips,omitempty"`
+	// Name of the guest operating system, e.g. "Ubuntu 24.04 LTS".
+	OS string `json:"os,omitempty"`
+}
+
+// InstanceWatchdog records the expirations of the watchdog device of the
+// domain, as observed from the libvirt watchdog events.
+type InstanceWatchdog struct {
+	// Number of times the watchdog expired.
+	Expirations int64 `json:"expirations,omitempty"`
+	// Time of the last expiration.
+	LastExpired *metav1.Time `json:"lastExpired,omitempty"`
+	// Action taken on the last expiration, e.g. "reset" or "poweroff".
+	LastAction string `json:"lastAction,omitempty"`
+}
+
+// InstanceStatus defines the observed state of Instance.
+type InstanceStatus struct {
+	// Hostname of the hypervisor the domain is defined on.
+	Hypervisor string `json:"hypervisor,omitempty"`
+	// Libvirt name of the domain.
+	DomainName string `json:"domainName,omitempty"`
+	// Libvirt driver the domain is defined in, e.g. qemu or ch.
+	Driver string `json:"driver,omitempty"`
+	// Name of the instance in nova.
+	InstanceName string `json:"instanceName,omitempty"`
+	// Time the instance was created in nova.
+	CreationTime *metav1.Time `json:"creationTime,omitempty"`
+	// Whether the domain is running.
+	Active  bool            `json:"active"`
+	Flavor  InstanceFlavor  `json:"flavor,omitempty"`
+	Owner   InstanceOwner   `json:"owner,omitempty"`
+	Pinning InstancePinning `json:"pinning,omitempty"`
+	Devices InstanceDevices `json:"devices,omitempty"`
+	// Whether the inactive domain has a managed save image, which is
+	// restored on its next start.
+	ManagedSave bool `json:"managedSave,omitempty"`
+	// Access mode of the guest memory, "shared" if it is shared with other
+	// processes as virtio-fs requires.
+	MemoryAccess string `json:"memoryAccess,omitempty"`
+	// Trusted platform module of the domain, unset if it has none.
+	TPM *InstanceTPM `json:"tpm,omitempty"`
+	// Fixed ip addresses of the nova ports, IPv4 addresses first.
+	FixedIPs []string `json:"fixedIPs,omitempty"`
+	// Live migration eligibility, to plan evacuations.
+	Migration InstanceMigration `json:"migration,omitempty"`
+	// Time spent paused, which explains jumps of the guest clock.
+	Pauses *InstancePauses `json:"pauses,omitempty"`
+	// Expirations of the watchdog device, which the guest doesn't notice.
+	Watchdog *InstanceWatchdog `json:"watchdog,omitempty"`
+	// State of the guest agent, unset if the domain has no guest agent
+	// channel.
+	GuestAgent *InstanceGuestAgent `json:"guestAgent,omitempty"`
+	// Block jobs running on the disks of the domain.
+	// +listType=map
+	// +listMapKey=disk
+	BlockJobs []InstanceBlockJob `json:"blockJobs,omitempty"`
+
+	// Conditions of the instance, e.g. io errors of its disks.
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Hypervisor",type=string,JSONPath=`.status.hypervisor`
+// +kubebuilder:printcolumn:name="Domain",type=string,JSONPath=`.status.domainName`
+// +kubebuilder:printcolumn:name="Flavor",type=string,JSONPath=`.status.flavor.name`
+// +kubebuilder:printcolumn:name="Active",type=boolean,JSONPath=`.status.active`
+// +kubebuilder:printcolumn:name="Live Migratable",type=boolean,JSONPath=`.status.migration.liveMigratable`,priority=1
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// Instance mirrors a single domain of a hypervisor. It is named after the
+// domain uuid and managed by the node agent of the hypervisor.
+type Instance struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata"`
+
+	Spec   InstanceSpec   `json:"spec"`
+	Status InstanceStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// InstanceList contains a list of Instance.
+type InstanceList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+	Items           []Instance `json:"items"`
+}
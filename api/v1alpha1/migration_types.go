@@ -24,48 +24,100 @@ import (
 // EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
 // NOTE: json tags are required.  Any new fields you add must have json tags for the fields to be serialized.
 
+// MigrationPhase is the phase of a migration.
+// +kubebuilder:validation:Enum=Preparing;PreCopy;PostCopy;Completed;Failed;Cancelled
+type MigrationPhase string
+
+const (
+	// The migration job was started, but no memory was transferred yet.
+	MigrationPhasePreparing MigrationPhase = "Preparing"
+	// The memory is copied to the destination while the domain keeps running on the source.
+	MigrationPhasePreCopy MigrationPhase = "PreCopy"
+	// The domain runs on the destination and fetches the remaining memory on demand.
+	MigrationPhasePostCopy MigrationPhase = "PostCopy"
+	// The migration finished successfully.
+	MigrationPhaseCompleted MigrationPhase = "Completed"
+	// The migration failed.
+	MigrationPhaseFailed MigrationPhase = "Failed"
+	// The migration was aborted.
+	MigrationPhaseCancelled MigrationPhase = "Cancelled"
+)
+
+const (
+	// ConditionTypeMigrationCompleted is True once the migration finished
+	// successfully, and False with the current phase as reason otherwise.
+	ConditionTypeMigrationCompleted = "Completed"
+	// ConditionTypeMigrationFailed is True if the migration failed or was cancelled.
+	ConditionTypeMigrationFailed = "Failed"
+)
+
+const (
+	// The host is the source of the migration.
+	MigrationDirectionOutgoing = "outgoing"
+	// The host is the destination of the migration.
+	MigrationDirectionIncoming = "incoming"
+)
+
+// MigrationEndpoint describes how a single host takes part in a migration.
+type MigrationEndpoint struct {
+	// Direction of the migration as seen by this host, either
+	// "outgoing" on the source or "incoming" on the destination.
+	Direction string `json:"direction"`
+	// Hostname of the other end of the migration, if known.
+	Peer string `json:"peer,omitempty"`
+}
+
 // MigrationSpec defines the desired state of Migration.
 type MigrationSpec struct {
 }
 
 // MigrationStatus defines the observed state of Migration.
 type MigrationStatus struct {
-	Origin               string      `json:"origin,omitempty"`
-	Destination          string      `json:"destination,omitempty"`
-	Type                 string      `json:"type,omitempty"`
-	Started              metav1.Time `json:"started"`
-	ErrMsg               string      `json:"errMsg,omitempty"`
-	AutoConvergeThrottle string      `json:"autoConvergeThrottle,omitempty"`
-	DiskBps              string      `json:"diskBps,omitempty"`
-	DiskRemaining        string      `json:"diskRemaining,omitempty"`
-	DiskProcessed        string      `json:"diskProcessed,omitempty"`
-	DiskTotal            string      `json:"diskTotal,omitempty"`
-	MemPostcopyRequests  uint64      `json:"memPostcopyRequests,omitempty"`
-	MemIteration         uint64      `json:"memIteration,omitempty"`
-	MemPageSize          string      `json:"memPageSize,omitempty"`
-	MemDirtyRate         string      `json:"memDirtyRate,omitempty"`
-	MemBps               string      `json:"memBps,omitempty"`
-	MemNormalBytes       string      `json:"memNormalBytes,omitempty"`
-	MemNormal            uint64      `json:"memNormal,omitempty"`
-	MemConstant          uint64      `json:"memConstant,omitempty"`
-	MemRemaining         string      `json:"memRemaining,omitempty"`
-	MemProcessed         string      `json:"memProcessed,omitempty"`
-	MemTotal             string      `json:"memTotal,omitempty"`
-	DataRemaining        string      `json:"dataRemaining,omitempty"`
-	DataProcessed        string      `json:"dataProcessed,omitempty"`
-	DataTotal            string      `json:"dataTotal,omitempty"`
-	SetupTime            string      `json:"setupTime,omitempty"`
-	TimeElapsed          string      `json:"timeElapsed,omitempty"`
-	TimeRemaining        string      `json:"timeRemaining,omitempty"`
-	Downtime             string      `json:"downtime,omitempty"`
-	Operation            string      `json:"operation,omitempty"`
+	Origin               string         `json:"origin,omitempty"`
+	Destination          string         `json:"destination,omitempty"`
+	Phase                MigrationPhase `json:"phase,omitempty"`
+	Started              metav1.Time    `json:"started"`
+	ErrMsg               string         `json:"errMsg,omitempty"`
+	AutoConvergeThrottle string         `json:"autoConvergeThrottle,omitempty"`
+	DiskBps              string         `json:"diskBps,omitempty"`
+	DiskRemaining        string         `json:"diskRemaining,omitempty"`
+	DiskProcessed        string         `json:"diskProcessed,omitempty"`
+	DiskTotal            string         `json:"diskTotal,omitempty"`
+	MemPostcopyRequests  uint64         `json:"memPostcopyRequests,omitempty"`
+	MemIteration         uint64         `json:"memIteration,omitempty"`
+	MemPageSize          string         `json:"memPageSize,omitempty"`
+	MemDirtyRate         string         `json:"memDirtyRate,omitempty"`
+	MemBps               string         `json:"memBps,omitempty"`
+	MemNormalBytes       string         `json:"memNormalBytes,omitempty"`
+	MemNormal            uint64         `json:"memNormal,omitempty"`
+	MemConstant          uint64         `json:"memConstant,omitempty"`
+	MemRemaining         string         `json:"memRemaining,omitempty"`
+	MemProcessed         string         `json:"memProcessed,omitempty"`
+	MemTotal             string         `json:"memTotal,omitempty"`
+	DataRemaining        string         `json:"dataRemaining,omitempty"`
+	DataProcessed        string         `json:"dataProcessed,omitempty"`
+	DataTotal            string         `json:"dataTotal,omitempty"`
+	SetupTime            string         `json:"setupTime,omitempty"`
+	TimeElapsed          string         `json:"timeElapsed,omitempty"`
+	TimeRemaining        string         `json:"timeRemaining,omitempty"`
+	Downtime             string         `json:"downtime,omitempty"`
+	Operation            string         `json:"operation,omitempty"`
+	// Both ends of the migration keyed by hostname. Each host only
+	// reports its own entry, so the source and the destination remain
+	// visible regardless of which host patched the status last.
+	Endpoints map[string]MigrationEndpoint `json:"endpoints,omitempty"`
+
+	// Conditions of the migration, e.g. to wait for its completion.
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
 }
 
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
 // +kubebuilder:printcolumn:name="Origin",type=string,JSONPath=`.status.origin`
 // +kubebuilder:printcolumn:name="Destination",type=string,JSONPath=`.status.destination`
-// +kubebuilder:printcolumn:name="Type",type=string,JSONPath=`.status.type`
+// +kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
 // +kubebuilder:printcolumn:name="Operation",type=string,JSONPath=`.status.operation`
 // +kubebuilder:printcolumn:name="Started",type=date,JSONPath=`.status.started`
 // +kubebuilder:printcolumn:name="Elapsed",type=string,JSONPath=`.status.timeElapsed`
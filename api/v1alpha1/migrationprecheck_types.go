@@ -0,0 +1,89 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ConditionTypeCompatible is True if the domain of the instance can be live
+// migrated to the target, False if not and Unknown if the target didn't
+// publish enough of its capabilities to tell.
+const ConditionTypeCompatible = "Compatible"
+
+// MigrationPrecheckSpec names the instance and the hypervisor it is to be
+// migrated to.
+type MigrationPrecheckSpec struct {
+	// UUID of the instance to migrate, i.e. the name of its Instance.
+	Instance string `json:"instance"`
+	// Hostname of the hypervisor the instance is to be migrated to.
+	Target string `json:"target"`
+}
+
+// MigrationIncompatibility is a reason the domain can't be live migrated to
+// the target.
+type MigrationIncompatibility struct {
+	// Reason in CamelCase, e.g. "CPUModel" or "CPUFeatures".
+	Reason  string `json:"reason"`
+	Message string `json:"message,omitempty"`
+}
+
+// MigrationPrecheckStatus is the verdict of the node agent on the source.
+type MigrationPrecheckStatus struct {
+	// Hostname of the hypervisor the domain runs on, which checked it.
+	Source string `json:"source,omitempty"`
+	// Cpu mode of the domain the verdict is based on.
+	CPUMode string `json:"cpuMode,omitempty"`
+	// Reasons the domain can't be migrated to the target.
+	Incompatibilities []MigrationIncompatibility `json:"incompatibilities,omitempty"`
+
+	// Conditions of the precheck, the verdict is the Compatible condition.
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Instance",type=string,JSONPath=`.spec.instance`
+// +kubebuilder:printcolumn:name="Source",type=string,JSONPath=`.status.source`
+// +kubebuilder:printcolumn:name="Target",type=string,JSONPath=`.spec.target`
+// +kubebuilder:printcolumn:name="Compatible",type=string,JSONPath=`.status.conditions[?(@.type=="Compatible")].status`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// MigrationPrecheck asks the node agent running the domain of an instance
+// whether it can be live migrated to another hypervisor, before nova even
+// attempts the migration. The agent compares the cpu of the domain with the
+// capabilities the target published, the check is repeated for every
+// generation of the spec.
+type MigrationPrecheck struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata"`
+
+	Spec   MigrationPrecheckSpec   `json:"spec"`
+	Status MigrationPrecheckStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// MigrationPrecheckList contains a list of MigrationPrecheck.
+type MigrationPrecheckList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+	Items           []MigrationPrecheck `json:"items"`
+}
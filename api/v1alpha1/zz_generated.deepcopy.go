@@ -22,9 +22,603 @@ limitations under the License.
 package v1alpha1
 
 import (
-	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/apis/meta/v1"
+	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Console) DeepCopyInto(out *Console) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	out.Status = in.Status
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Console.
+func (in *Console) DeepCopy() *Console {
+	if in == nil {
+		return nil
+	}
+	out := new(Console)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *Console) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ConsoleList) DeepCopyInto(out *ConsoleList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]Console, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConsoleList.
+func (in *ConsoleList) DeepCopy() *ConsoleList {
+	if in == nil {
+		return nil
+	}
+	out := new(ConsoleList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ConsoleList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ConsoleSpec) DeepCopyInto(out *ConsoleSpec) {
+	*out = *in
+	in.ExpiresAt.DeepCopyInto(&out.ExpiresAt)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConsoleSpec.
+func (in *ConsoleSpec) DeepCopy() *ConsoleSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ConsoleSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ConsoleStatus) DeepCopyInto(out *ConsoleStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConsoleStatus.
+func (in *ConsoleStatus) DeepCopy() *ConsoleStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ConsoleStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Instance) DeepCopyInto(out *Instance) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Instance.
+func (in *Instance) DeepCopy() *Instance {
+	if in == nil {
+		return nil
+	}
+	out := new(Instance)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *Instance) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InstanceBlockJob) DeepCopyInto(out *InstanceBlockJob) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InstanceBlockJob.
+func (in *InstanceBlockJob) DeepCopy() *InstanceBlockJob {
+	if in == nil {
+		return nil
+	}
+	out := new(InstanceBlockJob)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InstanceDevices) DeepCopyInto(out *InstanceDevices) {
+	*out = *in
+	if in.Disks != nil {
+		in, out := &in.Disks, &out.Disks
+		*out = make([]InstanceDisk, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Interfaces != nil {
+		in, out := &in.Interfaces, &out.Interfaces
+		*out = make([]InstanceInterface, len(*in))
+		copy(*out, *in)
+	}
+	if in.RNGs != nil {
+		in, out := &in.RNGs, &out.RNGs
+		*out = make([]InstanceRNG, len(*in))
+		copy(*out, *in)
+	}
+	if in.Filesystems != nil {
+		in, out := &in.Filesystems, &out.Filesystems
+		*out = make([]InstanceFilesystem, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InstanceDevices.
+func (in *InstanceDevices) DeepCopy() *InstanceDevices {
+	if in == nil {
+		return nil
+	}
+	out := new(InstanceDevices)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InstanceDisk) DeepCopyInto(out *InstanceDisk) {
+	*out = *in
+	if in.IOTune != nil {
+		in, out := &in.IOTune, &out.IOTune
+		*out = new(InstanceIOTune)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InstanceDisk.
+func (in *InstanceDisk) DeepCopy() *InstanceDisk {
+	if in == nil {
+		return nil
+	}
+	out := new(InstanceDisk)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InstanceDiskIOTune) DeepCopyInto(out *InstanceDiskIOTune) {
+	*out = *in
+	out.InstanceIOTune = in.InstanceIOTune
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InstanceDiskIOTune.
+func (in *InstanceDiskIOTune) DeepCopy() *InstanceDiskIOTune {
+	if in == nil {
+		return nil
+	}
+	out := new(InstanceDiskIOTune)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InstanceFilesystem) DeepCopyInto(out *InstanceFilesystem) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InstanceFilesystem.
+func (in *InstanceFilesystem) DeepCopy() *InstanceFilesystem {
+	if in == nil {
+		return nil
+	}
+	out := new(InstanceFilesystem)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InstanceFlavor) DeepCopyInto(out *InstanceFlavor) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InstanceFlavor.
+func (in *InstanceFlavor) DeepCopy() *InstanceFlavor {
+	if in == nil {
+		return nil
+	}
+	out := new(InstanceFlavor)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InstanceGuestAgent) DeepCopyInto(out *InstanceGuestAgent) {
+	*out = *in
+	if in.LastTransitionTime != nil {
+		in, out := &in.LastTransitionTime, &out.LastTransitionTime
+		*out = (*in).DeepCopy()
+	}
+	if in.IPs != nil {
+		in, out := &in.IPs, &out.IPs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InstanceGuestAgent.
+func (in *InstanceGuestAgent) DeepCopy() *InstanceGuestAgent {
+	if in == nil {
+		return nil
+	}
+	out := new(InstanceGuestAgent)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InstanceIOTune) DeepCopyInto(out *InstanceIOTune) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InstanceIOTune.
+func (in *InstanceIOTune) DeepCopy() *InstanceIOTune {
+	if in == nil {
+		return nil
+	}
+	out := new(InstanceIOTune)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InstanceInterface) DeepCopyInto(out *InstanceInterface) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InstanceInterface.
+func (in *InstanceInterface) DeepCopy() *InstanceInterface {
+	if in == nil {
+		return nil
+	}
+	out := new(InstanceInterface)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InstanceList) DeepCopyInto(out *InstanceList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]Instance, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InstanceList.
+func (in *InstanceList) DeepCopy() *InstanceList {
+	if in == nil {
+		return nil
+	}
+	out := new(InstanceList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *InstanceList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InstanceMigration) DeepCopyInto(out *InstanceMigration) {
+	*out = *in
+	if in.Blockers != nil {
+		in, out := &in.Blockers, &out.Blockers
+		*out = make([]InstanceMigrationBlocker, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InstanceMigration.
+func (in *InstanceMigration) DeepCopy() *InstanceMigration {
+	if in == nil {
+		return nil
+	}
+	out := new(InstanceMigration)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InstanceMigrationBlocker) DeepCopyInto(out *InstanceMigrationBlocker) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InstanceMigrationBlocker.
+func (in *InstanceMigrationBlocker) DeepCopy() *InstanceMigrationBlocker {
+	if in == nil {
+		return nil
+	}
+	out := new(InstanceMigrationBlocker)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InstanceOwner) DeepCopyInto(out *InstanceOwner) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InstanceOwner.
+func (in *InstanceOwner) DeepCopy() *InstanceOwner {
+	if in == nil {
+		return nil
+	}
+	out := new(InstanceOwner)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InstancePauses) DeepCopyInto(out *InstancePauses) {
+	*out = *in
+	out.Duration = in.Duration
+	if in.Since != nil {
+		in, out := &in.Since, &out.Since
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InstancePauses.
+func (in *InstancePauses) DeepCopy() *InstancePauses {
+	if in == nil {
+		return nil
+	}
+	out := new(InstancePauses)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InstancePinning) DeepCopyInto(out *InstancePinning) {
+	*out = *in
+	if in.VCPUs != nil {
+		in, out := &in.VCPUs, &out.VCPUs
+		*out = make([]InstanceVCPUPin, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InstancePinning.
+func (in *InstancePinning) DeepCopy() *InstancePinning {
+	if in == nil {
+		return nil
+	}
+	out := new(InstancePinning)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InstanceRNG) DeepCopyInto(out *InstanceRNG) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InstanceRNG.
+func (in *InstanceRNG) DeepCopy() *InstanceRNG {
+	if in == nil {
+		return nil
+	}
+	out := new(InstanceRNG)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InstanceShutdown) DeepCopyInto(out *InstanceShutdown) {
+	*out = *in
+	if in.GracePeriod != nil {
+		in, out := &in.GracePeriod, &out.GracePeriod
+		*out = new(v1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InstanceShutdown.
+func (in *InstanceShutdown) DeepCopy() *InstanceShutdown {
+	if in == nil {
+		return nil
+	}
+	out := new(InstanceShutdown)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InstanceSpec) DeepCopyInto(out *InstanceSpec) {
+	*out = *in
+	if in.IOTune != nil {
+		in, out := &in.IOTune, &out.IOTune
+		*out = make([]InstanceDiskIOTune, len(*in))
+		copy(*out, *in)
+	}
+	if in.Shutdown != nil {
+		in, out := &in.Shutdown, &out.Shutdown
+		*out = new(InstanceShutdown)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InstanceSpec.
+func (in *InstanceSpec) DeepCopy() *InstanceSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(InstanceSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InstanceStatus) DeepCopyInto(out *InstanceStatus) {
+	*out = *in
+	if in.CreationTime != nil {
+		in, out := &in.CreationTime, &out.CreationTime
+		*out = (*in).DeepCopy()
+	}
+	out.Flavor = in.Flavor
+	out.Owner = in.Owner
+	in.Pinning.DeepCopyInto(&out.Pinning)
+	in.Devices.DeepCopyInto(&out.Devices)
+	if in.TPM != nil {
+		in, out := &in.TPM, &out.TPM
+		*out = new(InstanceTPM)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.FixedIPs != nil {
+		in, out := &in.FixedIPs, &out.FixedIPs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	in.Migration.DeepCopyInto(&out.Migration)
+	if in.Pauses != nil {
+		in, out := &in.Pauses, &out.Pauses
+		*out = new(InstancePauses)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Watchdog != nil {
+		in, out := &in.Watchdog, &out.Watchdog
+		*out = new(InstanceWatchdog)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.GuestAgent != nil {
+		in, out := &in.GuestAgent, &out.GuestAgent
+		*out = new(InstanceGuestAgent)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.BlockJobs != nil {
+		in, out := &in.BlockJobs, &out.BlockJobs
+		*out = make([]InstanceBlockJob, len(*in))
+		copy(*out, *in)
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InstanceStatus.
+func (in *InstanceStatus) DeepCopy() *InstanceStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(InstanceStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InstanceTPM) DeepCopyInto(out *InstanceTPM) {
+	*out = *in
+	if in.Running != nil {
+		in, out := &in.Running, &out.Running
+		*out = new(bool)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InstanceTPM.
+func (in *InstanceTPM) DeepCopy() *InstanceTPM {
+	if in == nil {
+		return nil
+	}
+	out := new(InstanceTPM)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InstanceVCPUPin) DeepCopyInto(out *InstanceVCPUPin) {
+	*out = *in
+	if in.NUMANodes != nil {
+		in, out := &in.NUMANodes, &out.NUMANodes
+		*out = make([]int, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InstanceVCPUPin.
+func (in *InstanceVCPUPin) DeepCopy() *InstanceVCPUPin {
+	if in == nil {
+		return nil
+	}
+	out := new(InstanceVCPUPin)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InstanceWatchdog) DeepCopyInto(out *InstanceWatchdog) {
+	*out = *in
+	if in.LastExpired != nil {
+		in, out := &in.LastExpired, &out.LastExpired
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InstanceWatchdog.
+func (in *InstanceWatchdog) DeepCopy() *InstanceWatchdog {
+	if in == nil {
+		return nil
+	}
+	out := new(InstanceWatchdog)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Migration) DeepCopyInto(out *Migration) {
 	*out = *in
@@ -52,6 +646,36 @@ func (in *Migration) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MigrationEndpoint) DeepCopyInto(out *MigrationEndpoint) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MigrationEndpoint.
+func (in *MigrationEndpoint) DeepCopy() *MigrationEndpoint {
+	if in == nil {
+		return nil
+	}
+	out := new(MigrationEndpoint)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MigrationIncompatibility) DeepCopyInto(out *MigrationIncompatibility) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MigrationIncompatibility.
+func (in *MigrationIncompatibility) DeepCopy() *MigrationIncompatibility {
+	if in == nil {
+		return nil
+	}
+	out := new(MigrationIncompatibility)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *MigrationList) DeepCopyInto(out *MigrationList) {
 	*out = *in
@@ -84,6 +708,107 @@ func (in *MigrationList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MigrationPrecheck) DeepCopyInto(out *MigrationPrecheck) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec = in.Spec
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MigrationPrecheck.
+func (in *MigrationPrecheck) DeepCopy() *MigrationPrecheck {
+	if in == nil {
+		return nil
+	}
+	out := new(MigrationPrecheck)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *MigrationPrecheck) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MigrationPrecheckList) DeepCopyInto(out *MigrationPrecheckList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]MigrationPrecheck, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MigrationPrecheckList.
+func (in *MigrationPrecheckList) DeepCopy() *MigrationPrecheckList {
+	if in == nil {
+		return nil
+	}
+	out := new(MigrationPrecheckList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *MigrationPrecheckList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MigrationPrecheckSpec) DeepCopyInto(out *MigrationPrecheckSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MigrationPrecheckSpec.
+func (in *MigrationPrecheckSpec) DeepCopy() *MigrationPrecheckSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(MigrationPrecheckSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MigrationPrecheckStatus) DeepCopyInto(out *MigrationPrecheckStatus) {
+	*out = *in
+	if in.Incompatibilities != nil {
+		in, out := &in.Incompatibilities, &out.Incompatibilities
+		*out = make([]MigrationIncompatibility, len(*in))
+		copy(*out, *in)
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MigrationPrecheckStatus.
+func (in *MigrationPrecheckStatus) DeepCopy() *MigrationPrecheckStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(MigrationPrecheckStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *MigrationSpec) DeepCopyInto(out *MigrationSpec) {
 	*out = *in
@@ -103,6 +828,20 @@ func (in *MigrationSpec) DeepCopy() *MigrationSpec {
 func (in *MigrationStatus) DeepCopyInto(out *MigrationStatus) {
 	*out = *in
 	in.Started.DeepCopyInto(&out.Started)
+	if in.Endpoints != nil {
+		in, out := &in.Endpoints, &out.Endpoints
+		*out = make(map[string]MigrationEndpoint, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MigrationStatus.
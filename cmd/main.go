@@ -23,14 +23,32 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"slices"
+	"strings"
+	"time"
 
 	logger "sigs.k8s.io/controller-runtime/pkg/log"
 
+	"github.com/cobaltcore-dev/kvm-node-agent/api/v1alpha1"
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/boot"
 	"github.com/cobaltcore-dev/kvm-node-agent/internal/certificates"
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/chaos"
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/config"
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/console"
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/diagnostics"
 	"github.com/cobaltcore-dev/kvm-node-agent/internal/emulator"
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/hostprep"
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/hoststorage"
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/journal"
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/ksm"
 	"github.com/cobaltcore-dev/kvm-node-agent/internal/libvirt"
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/logging"
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/memory"
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/nfd"
 	"github.com/cobaltcore-dev/kvm-node-agent/internal/sys"
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/sysctl"
 	"github.com/cobaltcore-dev/kvm-node-agent/internal/systemd"
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/tracing"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
@@ -38,8 +56,12 @@ import (
 
 	certmanagerv1 "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
 	"github.com/sapcc/go-api-declarations/bininfo"
+	uberzap "go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
@@ -68,6 +90,7 @@ func init() {
 
 	utilruntime.Must(kvmv1.AddToScheme(scheme))
 	utilruntime.Must(certmanagerv1.AddToScheme(scheme))
+	utilruntime.Must(v1alpha1.AddToScheme(scheme))
 	// +kubebuilder:scaffold:scheme
 }
 
@@ -77,6 +100,51 @@ func main() {
 	var probeAddr string
 	var secureMetrics bool
 	var enableHTTP2 bool
+	var consoleAddr string
+	var consoleAllowedClients string
+	var nodeFeatureDiscovery string
+	var enableDebugAPI bool
+	var debugAddr string
+	var diagnosticsAddr string
+	var scrapeTargetsPort int
+	var domainPolicy string
+	var managedSaveMaxAge time.Duration
+	var janitor string
+	var libvirtDaemons string
+	var libvirtURIs string
+	var tlsSmokeTestPeer string
+	var cpuBaselineLabel string
+	var nodeLabels string
+	var syncNodeTaints bool
+	var hypervisorFinalizer bool
+	var manageKernelParameters bool
+	var manageSysctls bool
+	var allowedSysctls string
+	var manageKSM bool
+	var journalEvents bool
+	var watchUnits string
+	var rebootOrchestration bool
+	var cordonNode bool
+	var updateProgress bool
+	var bootEntries bool
+	var osImageDir string
+	var firmwareDescriptors string
+	var certificateProvider string
+	var vaultIssuePath string
+	var certificateOptions certificates.CertificateOptions
+	var certificateDNSNames string
+	var cpuOvercommit float64
+	var memoryOvercommit float64
+	var diskWatermark float64
+	var memoryPressure string
+	var memoryPressureThresholds memory.Thresholds
+	var memoryMitigations string
+	var storagePaths string
+	var tracingEndpoint string
+	var configFile string
+	var runtimeConfigMap string
+	var caBundleConfigMap, caBundleKey string
+	var emulatorEventInterval time.Duration
 	flag.StringVar(&metricsAddr, "metrics-bind-address", "0", "The address the metrics endpoint binds to. "+
 		"Use :8443 for HTTPS or :8080 for HTTP, or leave as 0 to disable the metrics service.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
@@ -87,6 +155,156 @@ func main() {
 		"If set, the metrics endpoint is served securely via HTTPS. Use --metrics-secure=false to use HTTP instead.")
 	flag.BoolVar(&enableHTTP2, "enable-http2", false,
 		"If set, HTTP/2 will be enabled for the metrics and webhook servers")
+	flag.StringVar(&consoleAddr, "console-bind-address", "0", "The address the serial console proxy binds to. "+
+		"Use e.g. :9443 to serve requested consoles via mTLS-authenticated WebSockets, or leave as 0 to disable it.")
+	flag.StringVar(&consoleAllowedClients, "console-allowed-clients", "",
+		"Comma separated common or dns names of the client certificates allowed to read consoles. The libvirt CA "+
+			"issues the certificates of all hypervisors, so the clients have to be listed explicitly.")
+	flag.StringVar(&nodeFeatureDiscovery, "node-feature-discovery", string(nfd.ModeOff),
+		"Integration with node-feature-discovery. Use produce to publish cpu, kernel and iommu features "+
+			"via the NFD local feature source, consume to read them from the existing NFD node labels, or off.")
+	flag.BoolVar(&enableDebugAPI, "enable-debug-api", false,
+		"Feature gate for the debug api to inject libvirt disconnects, unit failures and shutdown signals. "+
+			"Only enable this on staging nodes to rehearse operational runbooks.")
+	flag.StringVar(&debugAddr, "debug-bind-address", "127.0.0.1:8082",
+		"The address the debug api binds to if enabled. The api is unauthenticated, keep it on localhost.")
+	flag.StringVar(&diagnosticsAddr, "diagnostics-bind-address", "0",
+		"The address pprof, goroutine dumps and the internal state are served on, e.g. 127.0.0.1:8083, "+
+			"or leave as 0 to disable it. The diagnostics are unauthenticated and only listen on localhost.")
+	flag.IntVar(&scrapeTargetsPort, "scrape-targets-port", 0,
+		"Port of the exporters inside the guests. If set, the fixed ips of all instances are published "+
+			"as Prometheus HTTP SD targets in a config map per host, or leave as 0 to disable it.")
+	flag.StringVar(&domainPolicy, "domain-policy", string(libvirt.DomainPolicyOff),
+		"Policy that domains created by nova neither have autostart enabled nor a managed save image. "+
+			"Use dry-run to only report violations in the hypervisor status, enforce to fix them, or off.")
+	flag.DurationVar(&managedSaveMaxAge, "managed-save-max-age", 0,
+		"Age after which the managed save image of a nova domain is taken for stale and removed when the "+
+			"domain policy is enforced, e.g. 720h. Leave as 0 to only report them, nova keeps the memory of "+
+			"suspended instances in the image.")
+	flag.StringVar(&janitor, "janitor", string(libvirt.DomainPolicyOff),
+		"Look for tap devices, vhost-user sockets and qemu state directories left behind by crashed domains. "+
+			"Use dry-run to only report them in the hypervisor status, enforce to also remove the sockets and "+
+			"state directories, or off.")
+	flag.StringVar(&libvirtDaemons, "libvirt-daemons", string(libvirt.DaemonsMonolithic),
+		"Whether the host runs the monolithic libvirtd or the modular, socket activated daemons like "+
+			"virtqemud. With modular, the daemon sockets are reported and the TLS certificate of virtproxyd is reloaded.")
+	flag.StringVar(&libvirtURIs, "libvirt-uris", "",
+		"Comma separated libvirt uris to connect to on hosts with multiple drivers, e.g. "+
+			"\"ch:///system,qemu:///system\". The first one is the primary driver, defaults to LIBVIRT_DEFAULT_URI.")
+	flag.StringVar(&cpuBaselineLabel, "cpu-baseline-label", "",
+		"Label grouping the hypervisors, e.g. by availability zone. If set, the baseline cpu model of the "+
+			"hypervisors sharing the value of the label with this one is published in its annotations.")
+	flag.StringVar(&nodeLabels, "node-labels", "",
+		"Comma separated labels of the node copied into the labels of the hypervisor, e.g. "+
+			"\"topology.kubernetes.io/zone,rack\", so that placement constraints can rely on them.")
+	flag.BoolVar(&syncNodeTaints, "sync-node-taints", false,
+		"If set, the taints of the node are published in the kvm.cloud.sap/node-taints annotation of the hypervisor.")
+	flag.BoolVar(&hypervisorFinalizer, "hypervisor-finalizer", false,
+		"If set, the hypervisor is detached from its node, so that it is kept and adopted when the node is "+
+			"recreated with the same name, and its deletion is blocked by a finalizer while domains are running on it. "+
+			"The finalizer of a host lost with its domains has to be removed by hand once the heartbeat is stale.")
+	flag.StringVar(&tlsSmokeTestPeer, "tls-smoke-test-peer", "",
+		"Host name of another hypervisor. If set, the TLS handshake of a live migration to it is checked "+
+			"after installing a new certificate, in addition to the handshake with the own libvirt.")
+	flag.BoolVar(&manageKernelParameters, "manage-kernel-parameters", false,
+		"If set, the kernel parameters requested by the kernel.kvm.cloud.sap/ annotations of the hypervisor "+
+			"are written into a kernel command line snippet, and a pending reboot is reported.")
+	flag.BoolVar(&manageSysctls, "manage-sysctls", false,
+		"If set, the sysctls requested by the sysctl.kvm.cloud.sap/ annotations of the hypervisor are applied "+
+			"to the host, and values changed behind the back of the agent are reset and reported.")
+	flag.StringVar(&allowedSysctls, "allowed-sysctls", strings.Join(sysctl.DefaultAllowed, ","),
+		"Comma separated sysctls the annotations of the hypervisor may set with --manage-sysctls. "+
+			"Entries ending with a dot or an underscore are prefixes, e.g. vm., the others exact names.")
+	flag.BoolVar(&manageKSM, "manage-ksm", false,
+		"If set, the ksm parameters requested by the ksm.kvm.cloud.sap/ annotations of the hypervisor are "+
+			"applied to the host, and the pages deduplicated by ksm are reported.")
+	flag.BoolVar(&journalEvents, "journal-events", false,
+		"If set, errors logged to the systemd journal by libvirt and the qemu or cloud-hypervisor processes "+
+			"are forwarded as events of the hypervisor.")
+	flag.StringVar(&journal.Directory, "journal-directory", journal.Directory,
+		"Journal directory of the host read for --journal-events and --update-progress.")
+	flag.StringVar(&watchUnits, "watch-units", "",
+		"Comma separated systemd units reported as conditions of the hypervisor in addition to libvirtd.service "+
+			"and openvswitch-switch.service, e.g. virtlogd.service,multipathd.service.")
+	flag.BoolVar(&rebootOrchestration, "reboot-orchestration", false,
+		"If set, the reboot after an operating system update waits for the kvm.cloud.sap/reboot-window "+
+			"annotation of the hypervisor and the evacuation of its instances, instead of rebooting right away.")
+	flag.BoolVar(&cordonNode, "cordon-node", false,
+		"If set, the node is cordoned and tainted with kvm.cloud.sap/maintenance while the host is evacuated "+
+			"or reboots into an operating system update, until it booted again.")
+	flag.BoolVar(&updateProgress, "update-progress", false,
+		"If set, the download progress and target partition of operating system updates are read from the "+
+			"systemd journal and reported in the OperatingSystemUpdate condition of the hypervisor.")
+	flag.BoolVar(&bootEntries, "boot-entries", false,
+		"If set, the systemd-boot entries of the host are reported, and the kvm.cloud.sap/rollback annotation "+
+			"of the hypervisor makes the previous operating system version the default boot entry.")
+	flag.StringVar(&osImageDir, "os-image-dir", "",
+		"Directory the operating system images requested by the kvm.cloud.sap/os-image-url annotation of the "+
+			"hypervisor are verified and staged in for systemd-sysupdate, or leave empty to disable it.")
+	flag.StringVar(&firmwareDescriptors, "firmware-descriptors", "",
+		"Directory of the qemu firmware descriptors, e.g. /usr/share/qemu/firmware, whose nvram templates are "+
+			"published in the kvm.cloud.sap/nvram-templates annotation of the hypervisor, or leave empty to disable it.")
+	flag.StringVar(&certificateProvider, "certificate-provider", certificates.ProviderCertManager,
+		"Provider of the libvirt TLS certificate: cert-manager to create a Certificate, vault to issue it with "+
+			"the Vault PKI secrets engine at VAULT_ADDR with VAULT_TOKEN, or secret if it is provisioned externally. "+
+			"The Vault token is never renewed, it has to be valid for as long as the agent runs.")
+	flag.StringVar(&vaultIssuePath, "vault-issue-path", "pki/issue/libvirt",
+		"Path of the issue endpoint of the Vault PKI secrets engine, if the certificate provider is vault.")
+	flag.StringVar(&certificateOptions.KeyAlgorithm, "certificate-key-algorithm", "rsa",
+		"Algorithm of the private key of the libvirt TLS certificate requested from cert-manager, rsa or ecdsa.")
+	flag.IntVar(&certificateOptions.KeySize, "certificate-key-size", 0,
+		"Size of the private key of the libvirt TLS certificate, defaults to 4096 for rsa and 256 for ecdsa.")
+	flag.DurationVar(&certificateOptions.Duration, "certificate-duration", 8*time.Hour,
+		"Validity of the libvirt TLS certificate.")
+	flag.DurationVar(&certificateOptions.RenewBefore, "certificate-renew-before", 2*time.Hour,
+		"Remaining validity of the libvirt TLS certificate below which it is renewed.")
+	flag.StringVar(&certificateDNSNames, "certificate-dns-names", "",
+		"Comma separated DNS names of the libvirt TLS certificate in addition to the host name.")
+	flag.StringVar(&certificateOptions.IssuerKind, "certificate-issuer-kind", "Issuer",
+		"Kind of the cert-manager issuer named by ISSUER_NAME, Issuer or ClusterIssuer.")
+	flag.Float64Var(&cpuOvercommit, "cpu-overcommit", 1.0,
+		"Overcommit ratio of the cpus in the effective capacity, if the spec of the hypervisor sets none. "+
+			"Like the cpu_allocation_ratio of nova.")
+	flag.Float64Var(&memoryOvercommit, "memory-overcommit", 1.0,
+		"Overcommit ratio of the memory in the effective capacity, if the spec of the hypervisor sets none. "+
+			"Like the ram_allocation_ratio of nova.")
+	flag.Float64Var(&diskWatermark, "disk-watermark", 0,
+		"Share of the capacity of an ephemeral disk of the instances or of the filesystem holding them "+
+			"from which disk pressure is reported, e.g. 0.9. 0 disables the check.")
+	flag.StringVar(&memoryPressure, "memory-pressure", string(libvirt.DomainPolicyOff),
+		"Watch the memory pressure of the host and mitigate it once critical. "+
+			"Use dry-run to only report the mitigations in the hypervisor status, enforce to perform them, or off.")
+	flag.Float64Var(&memoryPressureThresholds.SomeAvg10, "memory-pressure-stall", 20,
+		"Share of the time in percent in which some tasks stalled on memory in the last 10 seconds "+
+			"from which the memory pressure is critical. 0 disables the threshold.")
+	flag.Float64Var(&memoryPressureThresholds.MinAvailable, "memory-pressure-available", 0.05,
+		"Share of the host memory available below which the memory pressure is critical. "+
+			"0 disables the threshold.")
+	flag.StringVar(&memoryMitigations, "memory-pressure-mitigations", "alert,balloon,pause",
+		"Comma separated mitigations of critical memory pressure, performed at most one per minute in order: "+
+			"alert emits an event, balloon shrinks and pause pauses the instances with the "+
+			controller.MemoryPriorityAnnotation+" annotation, lowest priority first. "+
+			"The instances are resumed and their balloons deflated once the pressure ends.")
+	flag.StringVar(&storagePaths, "storage-paths", "",
+		"Comma separated host paths whose free space and inodes are reported, e.g. "+
+			strings.Join(hoststorage.DefaultPaths, ",")+". Empty disables the check.")
+	flag.StringVar(&tracingEndpoint, "tracing-endpoint", "",
+		"URL of the OTLP gRPC collector the traces are exported to, e.g. http://otel-collector:4317. "+
+			"Empty falls back to OTEL_EXPORTER_OTLP_ENDPOINT, tracing is disabled if neither is set.")
+	flag.StringVar(&configFile, "config", "",
+		"Path of the yaml configuration file of the agent. The environment variables, e.g. PKI_PATH, "+
+			"take precedence over it.")
+	flag.StringVar(&runtimeConfigMap, "runtime-config-map", "",
+		"Name of the ConfigMap in the namespace of the agent the runtime configuration, e.g. the stats interval "+
+			"or the log level, is reloaded from as it changes. Empty disables the reload, which otherwise caches "+
+			"all ConfigMaps of the namespace.")
+	flag.StringVar(&caBundleConfigMap, "ca-bundle-configmap", "",
+		"Name of a ConfigMap in the namespace of the certificate Secret with a CA bundle merged into the CA "+
+			"certificate of libvirt, e.g. if the cluster CA is distributed separately. Empty disables the merge.")
+	flag.StringVar(&caBundleKey, "ca-bundle-key", "ca.crt", "Key of the CA bundle in the --ca-bundle-configmap.")
+	flag.DurationVar(&emulatorEventInterval, "emulator-event-interval", 10*time.Second,
+		"Interval the emulated libvirt synthesizes domain lifecycle and migration events in, if EMULATE is set, "+
+			"or 0 to disable them.")
 	versionFlag := flag.Bool("version", false, "Print application version")
 	opts := zap.Options{
 		Development: true,
@@ -99,8 +317,118 @@ func main() {
 		os.Exit(0)
 	}
 
+	// Keep the levels changeable per subsystem for the runtime
+	// configuration.
+	flagLogLevel := zapcore.InfoLevel
+	if opts.Development {
+		flagLogLevel = zapcore.DebugLevel
+	}
+	if level, ok := opts.Level.(uberzap.AtomicLevel); ok {
+		flagLogLevel = level.Level()
+	}
+	logLevels := logging.NewLevels(flagLogLevel)
+	opts.Level = logLevels
+	opts.ZapOpts = append(opts.ZapOpts, uberzap.WrapCore(logLevels.WrapCore))
 	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
 
+	cfg, err := config.Load(configFile)
+	if err != nil {
+		setupLog.Error(err, "invalid configuration", "path", configFile)
+		os.Exit(1)
+	}
+	sys.Namespace = cfg.Namespace
+	sys.Hostname = cfg.Hostname
+	sys.NodeLabelName = cfg.NodeLabel
+	libvirt.Configure(cfg.Libvirt)
+	certificates.Configure(cfg.PKI)
+	if cfg.Host.Root != "" {
+		paths := cfg.Host.Paths
+		if len(paths) == 0 {
+			paths = hostprep.DefaultPaths(cfg.PKI.Owner)
+		}
+		changes, err := hostprep.Prepare(cfg.Host.Root, paths)
+		for _, change := range changes {
+			setupLog.Info("prepared host", "change", change)
+		}
+		if err != nil {
+			setupLog.Error(err, "unable to prepare host", "root", cfg.Host.Root)
+			os.Exit(1)
+		}
+	}
+
+	nfdMode, err := nfd.ParseMode(nodeFeatureDiscovery)
+	if err != nil {
+		setupLog.Error(err, "invalid flag", "flag", "node-feature-discovery")
+		os.Exit(1)
+	}
+	domainPolicyMode, err := libvirt.ParseDomainPolicyMode(domainPolicy)
+	if err != nil {
+		setupLog.Error(err, "invalid flag", "flag", "domain-policy")
+		os.Exit(1)
+	}
+	janitorMode, err := libvirt.ParseDomainPolicyMode(janitor)
+	if err != nil {
+		setupLog.Error(err, "invalid flag", "flag", "janitor")
+		os.Exit(1)
+	}
+	libvirtDaemonMode, err := libvirt.ParseDaemonMode(libvirtDaemons)
+	if err != nil {
+		setupLog.Error(err, "invalid flag", "flag", "libvirt-daemons")
+		os.Exit(1)
+	}
+	defaultOvercommit := map[kvmv1.ResourceName]float64{
+		kvmv1.ResourceCPU:    cpuOvercommit,
+		kvmv1.ResourceMemory: memoryOvercommit,
+	}
+	for resourceName, overcommit := range defaultOvercommit {
+		if overcommit < 1.0 {
+			setupLog.Error(fmt.Errorf("overcommit ratio %v is less than 1.0", overcommit),
+				"invalid flag", "flag", string(resourceName)+"-overcommit")
+			os.Exit(1)
+		}
+	}
+	if diskWatermark < 0 || diskWatermark > 1 {
+		setupLog.Error(fmt.Errorf("disk watermark %v is not between 0 and 1", diskWatermark),
+			"invalid flag", "flag", "disk-watermark")
+		os.Exit(1)
+	}
+	memoryPressureMode, err := libvirt.ParseDomainPolicyMode(memoryPressure)
+	if err != nil {
+		setupLog.Error(err, "invalid flag", "flag", "memory-pressure")
+		os.Exit(1)
+	}
+	memoryMitigationChain, err := memory.ParseMitigations(memoryMitigations)
+	if err != nil {
+		setupLog.Error(err, "invalid flag", "flag", "memory-pressure-mitigations")
+		os.Exit(1)
+	}
+	if journalEvents || updateProgress {
+		// Fail right away instead of retrying to read the journal forever.
+		if err := journal.Check(context.Background()); err != nil {
+			setupLog.Error(err, "unable to read the systemd journal of the host")
+			os.Exit(1)
+		}
+	}
+	var memoryPressureReader memory.Interface
+	if memoryPressureMode != libvirt.DomainPolicyOff {
+		memoryPressureReader = memory.NewSystemReader()
+	}
+	certificateOptions.DNSNames = splitList(certificateDNSNames)
+	certProvider, err := certificates.NewProvider(certificateProvider, vaultIssuePath, cfg.Vault, certificateOptions)
+	if err != nil {
+		setupLog.Error(err, "invalid certificate flags")
+		os.Exit(1)
+	}
+
+	shutdownTracing := func(context.Context) error { return nil }
+	if tracing.Enabled(tracingEndpoint) {
+		shutdownTracing, err = tracing.Setup(context.Background(), tracingEndpoint)
+		if err != nil {
+			setupLog.Error(err, "unable to set up tracing")
+			os.Exit(1)
+		}
+	}
+
 	// if the enable-http2 flag is false (the default), http/2 should be disabled
 	// due to its vulnerabilities. More specifically, disabling http/2 will
 	// prevent from being vulnerable to the HTTP/2 Stream Cancellation and
@@ -171,9 +499,16 @@ func main() {
 				&kvmv1.Hypervisor{}: {
 					Field: fields.ParseSelectorOrDie("metadata.name=" + sys.Hostname),
 				},
+				// Secrets and config maps are only read in the namespace
+				// of the agent, which is all its role grants.
 				&corev1.Secret{}: {
-					Field: fields.ParseSelectorOrDie("metadata.name=" + secretName),
+					Namespaces: map[string]cache.Config{sys.Namespace: {}},
+					Field:      fields.ParseSelectorOrDie("metadata.name=" + secretName),
+				},
+				&v1alpha1.Instance{}: {
+					Label: labels.SelectorFromSet(labels.Set{v1alpha1.LabelHypervisor: sys.NodeLabelName}),
 				},
+				&corev1.ConfigMap{}: configMapCache(runtimeConfigMap, caBundleConfigMap),
 			},
 		},
 	})
@@ -182,41 +517,342 @@ func main() {
 		os.Exit(1)
 	}
 
+	var runtimeConfig *config.RuntimeStore
+	if runtimeConfigMap != "" {
+		runtimeConfig = config.NewRuntimeStore()
+		runtimeConfig.Subscribe(func(runtime config.Runtime) {
+			// The levels are validated when the configuration is loaded.
+			level := flagLogLevel
+			if runtime.LogLevel != "" {
+				level, _ = config.ParseLogLevel(runtime.LogLevel)
+			}
+			subsystems := make(map[string]zapcore.Level, len(runtime.LogLevels))
+			for subsystem, value := range runtime.LogLevels {
+				subsystems[subsystem], _ = config.ParseLogLevel(value)
+			}
+			if logLevels.Global() != level {
+				setupLog.Info("changing log level", "level", level.String())
+			}
+			logLevels.Set(level, subsystems)
+		})
+		if err = (&controller.RuntimeConfigReconciler{
+			Client:   mgr.GetClient(),
+			Store:    runtimeConfig,
+			Name:     runtimeConfigMap,
+			Recorder: mgr.GetEventRecorder("kvm-node-agent"),
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "RuntimeConfig")
+			os.Exit(1)
+		}
+	}
+
 	var sysd systemd.Interface
 	var libv libvirt.Interface
-	if os.Getenv("EMULATE") != "" {
+	var consoleOpener console.Opener
+	var domainPolicyEnforcer libvirt.DomainPolicyEnforcer
+	var leftoverJanitor libvirt.LeftoverJanitor
+	var domainDriftDetector libvirt.DomainDriftDetector
+	var hostTopology libvirt.HostTopology
+	var hostCPU libvirt.HostCPUDescriber
+	var stateDumper libvirt.StateDumper
+	var confidentialComputing libvirt.ConfidentialComputingDescriber
+	var firmware libvirt.FirmwareDescriber
+	var cpuBaseline libvirt.CPUBaseliner
+	var hostIOMMU libvirt.HostIOMMU
+	var connectionProber libvirt.ConnectionProber
+	var diskUsage libvirt.DiskUsageReporter
+	var hostStorage hoststorage.Interface
+	var domainShutdown libvirt.DomainShutdowner
+	var domainStopper libvirt.DomainStopper
+	var domainPower libvirt.DomainPowerManager
+	var domainCPU libvirt.DomainCPUDescriber
+	var memoryReclaimer libvirt.MemoryReclaimer
+	var domainCapabilities libvirt.DomainCapabilitiesCache
+	var tlsSmokeTest *certificates.SmokeTest
+	var sysctls sysctl.Interface
+	var ksmManager ksm.Interface
+	var unitWatcher systemd.UnitWatcher
+	var updateTracker journal.UpdateProgressTracker
+	var bootLoader boot.Interface
+	var imageStager systemd.ImageStager
+	if cfg.Emulate {
 		ctx := logger.IntoContext(context.Background(), setupLog)
-		libv = emulator.NewLibVirtEmulator(ctx)
+		libv = emulator.NewLibVirtEmulator(ctx, emulatorEventInterval)
 		sysd = emulator.NewSystemdEmulator(ctx)
 	} else {
-		var err error
 		ctx := logger.IntoContext(context.Background(), setupLog)
-		libv = libvirt.NewLibVirt(mgr.GetClient())
-		sysd, err = systemd.NewSystemd(ctx)
+		if uris := splitList(libvirtURIs); len(uris) > 1 {
+			virt := libvirt.NewMultiLibVirt(mgr.GetClient(), uris)
+			libv = virt
+			consoleOpener = virt
+			domainPolicyEnforcer = virt
+			leftoverJanitor = virt
+			domainDriftDetector = virt
+			hostTopology = virt
+			hostCPU = virt
+			stateDumper = virt
+			confidentialComputing = virt
+			firmware = virt
+			cpuBaseline = virt
+			hostIOMMU = virt
+			connectionProber = virt
+			diskUsage = virt
+			domainShutdown = virt
+			domainStopper = virt
+			domainPower = virt
+			domainCPU = virt
+			memoryReclaimer = virt
+			domainCapabilities = virt
+			virt.SetDefaultOvercommit(defaultOvercommit)
+			virt.SetEventRecorder(mgr.GetEventRecorder("kvm-node-agent"))
+			if err := mgr.Add(virt); err != nil {
+				setupLog.Error(err, "unable to add libvirt loops")
+				os.Exit(1)
+			}
+		} else {
+			uri := libvirt.DefaultURI()
+			if len(uris) == 1 {
+				uri = uris[0]
+			}
+			virt := libvirt.NewLibVirtForURI(mgr.GetClient(), uri)
+			libv = virt
+			consoleOpener = virt
+			domainPolicyEnforcer = virt
+			leftoverJanitor = virt
+			domainDriftDetector = virt
+			hostTopology = virt
+			hostCPU = virt
+			stateDumper = virt
+			confidentialComputing = virt
+			firmware = virt
+			cpuBaseline = virt
+			hostIOMMU = virt
+			connectionProber = virt
+			diskUsage = virt
+			domainShutdown = virt
+			domainStopper = virt
+			domainPower = virt
+			domainCPU = virt
+			memoryReclaimer = virt
+			domainCapabilities = virt
+			virt.SetDefaultOvercommit(defaultOvercommit)
+			virt.SetEventRecorder(mgr.GetEventRecorder("kvm-node-agent"))
+			if err := mgr.Add(virt); err != nil {
+				setupLog.Error(err, "unable to add libvirt loops")
+				os.Exit(1)
+			}
+		}
+		tlsSmokeTest = certificates.NewSmokeTest(tlsSmokeTestPeer)
+		if manageSysctls {
+			sysctls = sysctl.NewManager(sysctl.DefaultRoot, splitList(allowedSysctls))
+		}
+		if manageKSM {
+			ksmManager = ksm.NewManager(ksm.DefaultRoot)
+		}
+		if paths := splitList(storagePaths); len(paths) > 0 {
+			hostStorage = hoststorage.NewSystemReader(paths)
+		}
+		conn, err := systemd.NewSystemd(ctx)
 		if err != nil {
 			setupLog.Error(err, "unable to create systemd instance")
 			os.Exit(1)
 		}
+		sysd = conn
+		unitWatcher = conn
+		if bootEntries {
+			bootLoader = boot.NewLoader(boot.DefaultEfivarsPath)
+		}
+		if osImageDir != "" {
+			imageStager = &systemd.ImageDownloader{Dir: osImageDir}
+		}
+		if updateProgress {
+			tracker := &journal.UpdateTracker{}
+			if err := mgr.Add(tracker); err != nil {
+				setupLog.Error(err, "unable to add update progress tracker")
+				os.Exit(1)
+			}
+			updateTracker = tracker
+		}
+	}
+
+	if enableDebugAPI {
+		chaosSystemd := chaos.NewSystemd(sysd)
+		sysd = chaosSystemd
+		if err = mgr.Add(&chaos.Server{
+			BindAddress: debugAddr,
+			Libvirt:     libv,
+			Systemd:     chaosSystemd,
+		}); err != nil {
+			setupLog.Error(err, "unable to add debug api")
+			os.Exit(1)
+		}
 	}
 
+	health := controller.NewHealthChecks(sysd)
 	if err = (&controller.HypervisorReconciler{
-		Client:  mgr.GetClient(),
-		Scheme:  mgr.GetScheme(),
-		Systemd: sysd,
-		Libvirt: libv,
+		Client:   mgr.GetClient(),
+		Scheme:   mgr.GetScheme(),
+		Systemd:  sysd,
+		Libvirt:  libv,
+		Recorder: mgr.GetEventRecorder("kvm-node-agent"),
+
+		UnitWatcher:              unitWatcher,
+		Units:                    splitList(watchUnits),
+		RuntimeConfig:            runtimeConfig,
+		LibvirtDaemons:           libvirtDaemonMode,
+		LibvirtURIs:              splitList(libvirtURIs),
+		NodeFeatureDiscovery:     nfdMode,
+		NodeLabels:               splitList(nodeLabels),
+		SyncNodeTaints:           syncNodeTaints,
+		Finalizer:                hypervisorFinalizer,
+		ManageKernelParameters:   manageKernelParameters,
+		Sysctl:                   sysctls,
+		KSM:                      ksmManager,
+		DomainPolicy:             domainPolicyEnforcer,
+		DomainPolicyMode:         domainPolicyMode,
+		ManagedSaveMaxAge:        managedSaveMaxAge,
+		Janitor:                  leftoverJanitor,
+		JanitorMode:              janitorMode,
+		DomainDrift:              domainDriftDetector,
+		HostTopology:             hostTopology,
+		HostCPU:                  hostCPU,
+		CPUBaseline:              cpuBaseline,
+		CPUBaselineLabel:         cpuBaselineLabel,
+		ConfidentialComputing:    confidentialComputing,
+		Firmware:                 firmware,
+		FirmwareDescriptors:      firmwareDescriptors,
+		APIReader:                mgr.GetAPIReader(),
+		HostIOMMU:                hostIOMMU,
+		ConnectionProber:         connectionProber,
+		DiskUsage:                diskUsage,
+		DiskWatermark:            diskWatermark,
+		HostStorage:              hostStorage,
+		DomainShutdown:           domainShutdown,
+		MemoryPressure:           memoryPressureReader,
+		MemoryPressureThresholds: memoryPressureThresholds,
+		MemoryPressureMode:       memoryPressureMode,
+		MemoryMitigations:        memoryMitigationChain,
+		MemoryReclaimer:          memoryReclaimer,
+		DomainCapabilities:       domainCapabilities,
+		RebootOrchestration:      rebootOrchestration,
+		CordonNode:               cordonNode,
+		UpdateProgress:           updateTracker,
+		BootLoader:               bootLoader,
+		ImageStager:              imageStager,
+		CertificateProvider:      certProvider,
+		Health:                   health,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Hypervisor")
 		os.Exit(1)
 	}
 
 	if err = (&controller.SecretReconciler{
-		Client:  mgr.GetClient(),
-		Scheme:  mgr.GetScheme(),
-		Systemd: sysd,
+		Client:         mgr.GetClient(),
+		Scheme:         mgr.GetScheme(),
+		Systemd:        sysd,
+		SmokeTest:      tlsSmokeTest,
+		LibvirtDaemons: libvirtDaemonMode,
+
+		CABundleConfigMap: caBundleConfigMap,
+		CABundleKey:       caBundleKey,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Secret")
 		os.Exit(1)
 	}
+
+	if scrapeTargetsPort != 0 {
+		if err = (&controller.ScrapeTargetsReconciler{
+			Client: mgr.GetClient(),
+			Scheme: mgr.GetScheme(),
+			Port:   scrapeTargetsPort,
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "ScrapeTargets")
+			os.Exit(1)
+		}
+	}
+
+	if domainStopper != nil {
+		if err = (&controller.InstanceShutdownReconciler{
+			Client:  mgr.GetClient(),
+			Scheme:  mgr.GetScheme(),
+			Domains: domainStopper,
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "InstanceShutdown")
+			os.Exit(1)
+		}
+	}
+
+	if domainPower != nil {
+		if err = (&controller.InstancePowerReconciler{
+			Client:  mgr.GetClient(),
+			Scheme:  mgr.GetScheme(),
+			Domains: domainPower,
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "InstancePower")
+			os.Exit(1)
+		}
+	}
+
+	if domainCPU != nil {
+		if err = (&controller.MigrationPrecheckReconciler{
+			Client:  mgr.GetClient(),
+			Scheme:  mgr.GetScheme(),
+			Reader:  mgr.GetAPIReader(),
+			Domains: domainCPU,
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "MigrationPrecheck")
+			os.Exit(1)
+		}
+	}
+
+	if diagnosticsAddr != "0" {
+		if err := diagnostics.CheckBindAddress(diagnosticsAddr); err != nil {
+			setupLog.Error(err, "invalid flag", "flag", "diagnostics-bind-address")
+			os.Exit(1)
+		}
+		if err = mgr.Add(&diagnostics.Server{
+			BindAddress: diagnosticsAddr,
+			State:       stateDumper,
+			Config:      cfg,
+		}); err != nil {
+			setupLog.Error(err, "unable to add diagnostics")
+			os.Exit(1)
+		}
+	}
+
+	if consoleAddr != "0" && consoleOpener != nil {
+		caFile, certFile, keyFile := certificates.TLSFiles()
+		consoleServer := &console.Server{
+			BindAddress:    consoleAddr,
+			Reader:         mgr.GetAPIReader(),
+			Opener:         consoleOpener,
+			CAFile:         caFile,
+			CertFile:       certFile,
+			KeyFile:        keyFile,
+			AllowedClients: splitList(consoleAllowedClients),
+		}
+		if err = mgr.Add(consoleServer); err != nil {
+			setupLog.Error(err, "unable to add console server")
+			os.Exit(1)
+		}
+		if err = (&controller.ConsoleReconciler{
+			Client: mgr.GetClient(),
+			Scheme: mgr.GetScheme(),
+			URL:    consoleServer.URL,
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "Console")
+			os.Exit(1)
+		}
+	}
+	if journalEvents {
+		if err = mgr.Add(&journal.Capture{
+			Recorder:   mgr.GetEventRecorder("kvm-node-agent"),
+			Hypervisor: &kvmv1.Hypervisor{ObjectMeta: metav1.ObjectMeta{Name: sys.Hostname}},
+		}); err != nil {
+			setupLog.Error(err, "unable to add journal capture")
+			os.Exit(1)
+		}
+	}
 	// +kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
@@ -227,10 +863,63 @@ func main() {
 		setupLog.Error(err, "unable to set up ready check")
 		os.Exit(1)
 	}
+	// A lost dbus connection isn't reestablished and a stalled reconcile
+	// doesn't recover, so both restart the agent. Libvirt is reconnected
+	// by the reconciles and only fails the readiness.
+	for name, check := range map[string]healthz.Checker{
+		"systemd":   health.SystemdCheck,
+		"reconcile": health.ReconcileCheck,
+	} {
+		if err := mgr.AddHealthzCheck(name, check); err != nil {
+			setupLog.Error(err, "unable to set up health check", "check", name)
+			os.Exit(1)
+		}
+	}
+	for name, check := range map[string]healthz.Checker{
+		"libvirt":   health.LibvirtCheck,
+		"systemd":   health.SystemdCheck,
+		"reconcile": health.ReconcileCheck,
+	} {
+		if err := mgr.AddReadyzCheck(name, check); err != nil {
+			setupLog.Error(err, "unable to set up ready check", "check", name)
+			os.Exit(1)
+		}
+	}
 
 	setupLog.Info("starting manager")
 	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
 		setupLog.Error(err, "problem running manager")
 		os.Exit(1)
 	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := shutdownTracing(ctx); err != nil {
+		setupLog.Error(err, "unable to flush traces")
+	}
+}
+
+// Cache of the ConfigMaps, only the migration pairs unless further ones
+// are watched, e.g. the runtime configuration, as field selectors can't
+// select two names.
+func configMapCache(watched ...string) cache.ByObject {
+	if slices.IndexFunc(watched, func(name string) bool { return name != "" }) < 0 {
+		return cache.ByObject{
+			Namespaces: map[string]cache.Config{sys.Namespace: {}},
+			Field:      fields.ParseSelectorOrDie("metadata.name=" + libvirt.MigrationPairsConfigMapName),
+		}
+	}
+	return cache.ByObject{
+		Namespaces: map[string]cache.Config{sys.Namespace: {}},
+	}
+}
+
+// Split a comma separated flag value, ignoring empty items.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
 }
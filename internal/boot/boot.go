@@ -0,0 +1,288 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package boot reads the boot loader entries of systemd-boot and selects
+// the default entry via the EFI variables of the boot loader interface.
+package boot
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"unicode/utf16"
+	"unsafe"
+)
+
+// DefaultEfivarsPath is the mount point of the efivarfs of the host.
+const DefaultEfivarsPath = "/sys/firmware/efi/efivars"
+
+// Vendor guid of the variables of the boot loader interface.
+const loaderGUID = "4a67b082-0a4c-41cf-b6c7-440b29bb8c4f"
+
+// Variables of the boot loader interface.
+const (
+	varEntries  = "LoaderEntries"
+	varSelected = "LoaderEntrySelected"
+	varDefault  = "LoaderEntryDefault"
+)
+
+// Attributes of the written variables: non volatile, boot service and
+// runtime access.
+const efiAttributes = 0x7
+
+// Flags of the variable files, see ioctl_iflags(2).
+const (
+	fsIocGetFlags = 0x80086601
+	fsIocSetFlags = 0x40086602
+	fsImmutableFl = 0x10
+)
+
+var (
+	// Boot counter suffix of the entry, e.g. "+3" or "+2-1".
+	counterRegexp = regexp.MustCompile(`\+\d+(-\d+)?$`)
+	versionRegexp = regexp.MustCompile(`\d+(\.\d+)+`)
+)
+
+// Entry is a boot loader entry.
+type Entry struct {
+	// Identifier of the entry, e.g. "gardenlinux-1877.3.efi".
+	ID string
+	// Operating system version of the entry, empty if unknown.
+	Version string
+}
+
+// Parse the entry from its identifier.
+func NewEntry(id string) Entry {
+	name := strings.TrimSuffix(strings.TrimSuffix(id, ".conf"), ".efi")
+	name = counterRegexp.ReplaceAllString(name, "")
+	return Entry{ID: id, Version: versionRegexp.FindString(name)}
+}
+
+// Summary for humans, e.g. "1877.3 (gardenlinux-1877.3.efi)".
+func (e Entry) String() string {
+	if e.Version == "" {
+		return e.ID
+	}
+	return fmt.Sprintf("%s (%s)", e.Version, e.ID)
+}
+
+// Entries of the boot loader.
+type Entries struct {
+	// Entry the running system was booted from.
+	Current Entry
+	// Newest entry older than the current one, empty if there is none.
+	Fallback Entry
+	// Identifier of the entry configured as default, empty if the boot
+	// loader picks the newest entry.
+	Default string
+	// All entries in the order of the boot menu.
+	All []Entry
+}
+
+// Summary for humans, e.g. "current 1877.3 (...), fallback 1592.6 (...)".
+func (e *Entries) String() string {
+	parts := []string{"current " + e.Current.String()}
+	if e.Fallback.ID != "" {
+		parts = append(parts, "fallback "+e.Fallback.String())
+	}
+	if e.Default != "" {
+		parts = append(parts, "default "+e.Default)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Compare two versions like "1877.3" numerically by their components.
+func compareVersions(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		x, _ := strconv.Atoi(as[i])
+		y, _ := strconv.Atoi(bs[i])
+		if x != y {
+			return x - y
+		}
+	}
+	return len(as) - len(bs)
+}
+
+// Select the newest entry older than the current one.
+func fallback(current Entry, all []Entry) Entry {
+	var result Entry
+	if current.Version == "" {
+		return result
+	}
+	for _, entry := range all {
+		if entry.Version == "" || compareVersions(entry.Version, current.Version) >= 0 {
+			continue
+		}
+		if result.ID == "" || compareVersions(entry.Version, result.Version) > 0 {
+			result = entry
+		}
+	}
+	return result
+}
+
+// Interface to the boot loader.
+type Interface interface {
+	// Entries returns the entries of the boot loader.
+	Entries() (*Entries, error)
+	// SetDefault configures the entry booted by default.
+	SetDefault(id string) error
+}
+
+// Loader reads and writes the variables of the boot loader interface.
+type Loader struct {
+	path string
+}
+
+// NewLoader accesses the variables in the efivarfs mounted at path.
+func NewLoader(path string) *Loader {
+	return &Loader{path: path}
+}
+
+func (l *Loader) variablePath(name string) string {
+	return filepath.Join(l.path, name+"-"+loaderGUID)
+}
+
+// Read the strings of a variable, which are UTF-16 encoded and terminated
+// by zero characters.
+func (l *Loader) readStrings(name string) ([]string, error) {
+	data, err := os.ReadFile(l.variablePath(name))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < 4 {
+		return nil, fmt.Errorf("variable %s too short", name)
+	}
+	// Skip the attributes.
+	data = data[4:]
+	chars := make([]uint16, len(data)/2)
+	for i := range chars {
+		chars[i] = binary.LittleEndian.Uint16(data[2*i:])
+	}
+	var result []string
+	for _, s := range strings.Split(string(utf16.Decode(chars)), "\x00") {
+		if s != "" {
+			result = append(result, s)
+		}
+	}
+	return result, nil
+}
+
+func (l *Loader) readString(name string) (string, error) {
+	values, err := l.readStrings(name)
+	if err != nil || len(values) == 0 {
+		return "", err
+	}
+	return values[0], nil
+}
+
+// Entries returns the entries of the boot loader.
+func (l *Loader) Entries() (*Entries, error) {
+	ids, err := l.readStrings(varEntries)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read boot loader entries: %w", err)
+	}
+	selected, err := l.readString(varSelected)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read selected boot loader entry: %w", err)
+	}
+	defaultID, err := l.readString(varDefault)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("unable to read default boot loader entry: %w", err)
+	}
+
+	entries := &Entries{
+		Current: NewEntry(selected),
+		Default: defaultID,
+	}
+	for _, id := range ids {
+		entries.All = append(entries.All, NewEntry(id))
+	}
+	entries.Fallback = fallback(entries.Current, entries.All)
+	return entries, nil
+}
+
+// SetDefault configures the entry booted by default, like bootctl
+// set-default.
+func (l *Loader) SetDefault(id string) error {
+	ids, err := l.readStrings(varEntries)
+	if err != nil {
+		return fmt.Errorf("unable to read boot loader entries: %w", err)
+	}
+	if !slices.Contains(ids, id) {
+		return fmt.Errorf("unknown boot loader entry %s", id)
+	}
+
+	chars := utf16.Encode([]rune(id + "\x00"))
+	data := make([]byte, 4+2*len(chars))
+	binary.LittleEndian.PutUint32(data, efiAttributes)
+	for i, c := range chars {
+		binary.LittleEndian.PutUint16(data[4+2*i:], c)
+	}
+
+	path := l.variablePath(varDefault)
+	if err := clearImmutable(path); err != nil {
+		return fmt.Errorf("unable to make %s writable: %w", varDefault, err)
+	}
+	// The efivarfs expects the variable in a single write.
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("unable to write %s: %w", varDefault, err)
+	}
+	return nil
+}
+
+// The kernel marks existing variables immutable, which has to be cleared
+// before they can be written.
+func clearImmutable(path string) error {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var flags int32
+	if err := ioctl(file, fsIocGetFlags, &flags); err != nil {
+		// Not supported by the file system, e.g. in tests.
+		if errors.Is(err, syscall.ENOTTY) || errors.Is(err, syscall.EINVAL) || errors.Is(err, syscall.EOPNOTSUPP) {
+			return nil
+		}
+		return err
+	}
+	if flags&fsImmutableFl == 0 {
+		return nil
+	}
+	flags &^= fsImmutableFl
+	return ioctl(file, fsIocSetFlags, &flags)
+}
+
+func ioctl(file *os.File, request uintptr, flags *int32) error {
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, file.Fd(), request, uintptr(unsafe.Pointer(flags)))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package boot
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf16"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeVariable(t *testing.T, dir, name string, values ...string) {
+	t.Helper()
+	chars := utf16.Encode([]rune(strings.Join(values, "\x00") + "\x00"))
+	data := make([]byte, 4+2*len(chars))
+	binary.LittleEndian.PutUint32(data, 0x6)
+	for i, c := range chars {
+		binary.LittleEndian.PutUint16(data[4+2*i:], c)
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name+"-"+loaderGUID), data, 0o644))
+}
+
+func TestNewEntry(t *testing.T) {
+	assert.Equal(t, Entry{ID: "gardenlinux-1877.3+2-1.efi", Version: "1877.3"}, NewEntry("gardenlinux-1877.3+2-1.efi"))
+	assert.Equal(t, "1592.6", NewEntry("gardenlinux-1592.6-6.12.44-amd64.conf").Version)
+	assert.Equal(t, "", NewEntry("auto-efi-shell").Version)
+	assert.Equal(t, "auto-efi-shell", NewEntry("auto-efi-shell").String())
+}
+
+func TestLoader(t *testing.T) {
+	dir := t.TempDir()
+	writeVariable(t, dir, varEntries,
+		"gardenlinux-1877.3.efi", "gardenlinux-1592.6.efi", "gardenlinux-1443.10.efi", "auto-reboot-to-firmware-setup")
+	writeVariable(t, dir, varSelected, "gardenlinux-1877.3.efi")
+
+	loader := NewLoader(dir)
+	entries, err := loader.Entries()
+	require.NoError(t, err)
+	assert.Equal(t, Entry{ID: "gardenlinux-1877.3.efi", Version: "1877.3"}, entries.Current)
+	assert.Equal(t, Entry{ID: "gardenlinux-1592.6.efi", Version: "1592.6"}, entries.Fallback)
+	assert.Empty(t, entries.Default)
+	assert.Len(t, entries.All, 4)
+	assert.Equal(t, "current 1877.3 (gardenlinux-1877.3.efi), fallback 1592.6 (gardenlinux-1592.6.efi)",
+		entries.String())
+
+	require.NoError(t, loader.SetDefault("gardenlinux-1592.6.efi"))
+	data, err := os.ReadFile(filepath.Join(dir, varDefault+"-"+loaderGUID))
+	require.NoError(t, err)
+	assert.Equal(t, uint32(efiAttributes), binary.LittleEndian.Uint32(data))
+	entries, err = loader.Entries()
+	require.NoError(t, err)
+	assert.Equal(t, "gardenlinux-1592.6.efi", entries.Default)
+
+	assert.Error(t, loader.SetDefault("gardenlinux-1.0.efi"))
+
+	// Without an older entry there is nothing to fall back to.
+	writeVariable(t, dir, varSelected, "gardenlinux-1443.10.efi")
+	entries, err = loader.Entries()
+	require.NoError(t, err)
+	assert.Empty(t, entries.Fallback.ID)
+
+	_, err = NewLoader(t.TempDir()).Entries()
+	assert.Error(t, err)
+}
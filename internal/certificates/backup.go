@@ -0,0 +1,110 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package certificates
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	logger "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/logging"
+)
+
+// Number of previously installed certificates kept for a rollback.
+const keptBackups = 3
+
+// Directory of the previously installed certificates, one versioned
+// directory per installation.
+func backupDir() string {
+	return filepath.Join(pki, "CA", ".previous")
+}
+
+// BackupTLSCertificate copies the installed certificate into a new
+// versioned directory before it is replaced, and removes all but the
+// latest backups. Returns the directory, empty if no certificate is
+// installed yet.
+func BackupTLSCertificate(now time.Time) (string, error) {
+	caFile, certFile, keyFile := TLSFiles()
+	files := map[string]string{"ca.crt": caFile, "tls.crt": certFile, "tls.key": keyFile}
+	data := make(map[string][]byte, len(files))
+	for source, file := range files {
+		content, err := os.ReadFile(file)
+		if errors.Is(err, os.ErrNotExist) {
+			return "", nil
+		}
+		if err != nil {
+			return "", err
+		}
+		data[source] = content
+	}
+
+	dir := filepath.Join(backupDir(), now.UTC().Format("20060102T150405.000000000"))
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+	for source, content := range data {
+		if err := writeFileAtomic(filepath.Join(dir, source), content, 0600, -1, -1); err != nil {
+			return "", err
+		}
+	}
+	return dir, pruneBackups()
+}
+
+// Remove all but the latest keptBackups backups, which sort by their time.
+func pruneBackups() error {
+	entries, err := os.ReadDir(backupDir())
+	if err != nil {
+		return err
+	}
+	var versions []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			versions = append(versions, entry.Name())
+		}
+	}
+	slices.Sort(versions)
+	for len(versions) > keptBackups {
+		if err := os.RemoveAll(filepath.Join(backupDir(), versions[0])); err != nil {
+			return err
+		}
+		versions = versions[1:]
+	}
+	return nil
+}
+
+// RestoreTLSCertificate installs the certificate of the backup directory
+// again. It is not validated, a certificate libvirt worked with is
+// preferred over the one it failed with, also if it expired meanwhile.
+func RestoreTLSCertificate(ctx context.Context, dir string) error {
+	logger.FromContext(ctx).WithName(logging.Certificates).Info("restoring TLS certificates for libvirt", "backup", dir)
+	data := make(map[string][]byte, len(secretToFileMap))
+	for source := range secretToFileMap {
+		content, err := os.ReadFile(filepath.Join(dir, source))
+		if err != nil {
+			return fmt.Errorf("failed to read backup: %w", err)
+		}
+		data[source] = content
+	}
+	return installTLSCertificate(data)
+}
@@ -0,0 +1,102 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package certificates
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestBackupAndRestoreTLSCertificate(t *testing.T) {
+	old := pki
+	pki = t.TempDir()
+	t.Cleanup(func() { pki = old })
+
+	dir, err := BackupTLSCertificate(time.Now())
+	if err != nil || dir != "" {
+		t.Fatalf("Expected no backup without installed certificate, got %q, %v", dir, err)
+	}
+
+	ca := newTestCertificate(t, "ca", nil)
+	previous := newTestCertificate(t, "previous", ca)
+	current := newTestCertificate(t, "current", ca)
+	ctx := context.Background()
+	if err := UpdateTLSCertificate(ctx, map[string][]byte{
+		"ca.crt": ca.certPEM, "tls.crt": previous.certPEM, "tls.key": previous.keyPEM,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	dir, err = BackupTLSCertificate(time.Now())
+	if err != nil || dir == "" {
+		t.Fatalf("Expected a backup, got %q, %v", dir, err)
+	}
+	if err := UpdateTLSCertificate(ctx, map[string][]byte{
+		"ca.crt": ca.certPEM, "tls.crt": current.certPEM, "tls.key": current.keyPEM,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := RestoreTLSCertificate(ctx, dir); err != nil {
+		t.Fatal(err)
+	}
+	_, certFile, keyFile := TLSFiles()
+	for file, expected := range map[string][]byte{certFile: previous.certPEM, keyFile: previous.keyPEM} {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != string(expected) {
+			t.Errorf("Expected the previous certificate in %s", file)
+		}
+	}
+}
+
+func TestPruneBackups(t *testing.T) {
+	old := pki
+	pki = t.TempDir()
+	t.Cleanup(func() { pki = old })
+
+	ca := newTestCertificate(t, "ca", nil)
+	cert := newTestCertificate(t, "host", ca)
+	if err := UpdateTLSCertificate(context.Background(), map[string][]byte{
+		"ca.crt": ca.certPEM, "tls.crt": cert.certPEM, "tls.key": cert.keyPEM,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	var latest string
+	for i := range 5 {
+		dir, err := BackupTLSCertificate(start.Add(time.Duration(i) * time.Second))
+		if err != nil {
+			t.Fatal(err)
+		}
+		latest = dir
+	}
+	entries, err := os.ReadDir(backupDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != keptBackups {
+		t.Errorf("Expected %d backups, got %d", keptBackups, len(entries))
+	}
+	if _, err := os.Stat(latest); err != nil {
+		t.Errorf("Expected the latest backup to be kept: %v", err)
+	}
+}
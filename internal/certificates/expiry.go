@@ -0,0 +1,62 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package certificates
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var tlsCertExpiry = prometheus.NewGauge(prometheus.GaugeOpts{
+	Name: "libvirt_tls_cert_expiry_timestamp",
+	Help: "Expiry of the libvirt TLS certificate installed on the host in seconds since the epoch.",
+})
+
+func init() {
+	metrics.Registry.MustRegister(tlsCertExpiry)
+}
+
+// CertificateExpiry returns the NotAfter of the first certificate in the
+// PEM data, i.e. of the leaf certificate of a chain.
+func CertificateExpiry(data []byte) (time.Time, error) {
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			return time.Time{}, errors.New("no certificate found")
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return cert.NotAfter, nil
+	}
+}
+
+// SetInstalledExpiry exports the expiry of the installed certificate.
+func SetInstalledExpiry(expiry time.Time) {
+	tlsCertExpiry.Set(float64(expiry.Unix()))
+}
@@ -0,0 +1,41 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package certificates
+
+import (
+	"testing"
+)
+
+func TestCertificateExpiry(t *testing.T) {
+	ca := newTestCertificate(t, "ca", nil)
+	cert := newTestCertificate(t, "host", ca)
+
+	// The leaf certificate comes first in a chain, keys are skipped.
+	data := append(append(append([]byte{}, cert.keyPEM...), cert.certPEM...), ca.certPEM...)
+	expiry, err := CertificateExpiry(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !expiry.Equal(cert.cert.NotAfter) {
+		t.Errorf("expected expiry %s, got %s", cert.cert.NotAfter, expiry)
+	}
+
+	if _, err := CertificateExpiry([]byte("test-cert")); err == nil {
+		t.Error("expected an error for data without a certificate")
+	}
+}
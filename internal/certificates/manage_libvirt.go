@@ -24,6 +24,9 @@ import (
 	"net"
 	"os"
 	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
 	cmapi "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
@@ -33,6 +36,8 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	logger "sigs.k8s.io/controller-runtime/pkg/log"
 
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/config"
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/logging"
 	"github.com/cobaltcore-dev/kvm-node-agent/internal/sys"
 )
 
@@ -40,23 +45,40 @@ func GetSecretAndCertName(host string) (secretName, certName string) {
 	return "tls-libvirt-" + host, "libvirt-" + host
 }
 
+// Settings of the pki, set from the agent configuration.
 var (
-	pki = os.Getenv("PKI_PATH")
+	pki string
+	// Owner of the written files as numeric uid:gid, e.g. of the qemu user
+	// of the host, the files are owned by the agent if empty.
+	pkiOwner string
+	// Issuer of the cert-manager certificate.
+	issuerName string
+	// Address of the host if its hostname doesn't resolve.
+	hostIPAddress string
+	// Additional pki directories provisioned with the client certificate.
+	clientTargets []config.ClientTarget
 )
 
-// EnsureCertificate ensures that a certificate exists for the given host and IPs
-// TODO: move this code to a controller, so the node-agent doesn't need to have the rights
-// to create certificates for any host
-func EnsureCertificate(ctx context.Context, c client.Client, host string) error {
-	log := logger.FromContext(ctx)
+// Configure the pki from the agent configuration.
+func Configure(cfg config.PKI) {
+	pki, pkiOwner, issuerName, hostIPAddress = cfg.Path, cfg.Owner, cfg.IssuerName, cfg.HostIPAddress
+	clientTargets = cfg.ClientTargets
+}
+
+// PKIPath returns the directory the certificate is installed into.
+func PKIPath() string {
+	return pki
+}
 
+// Get the IPv4 addresses of the host for the certificate, falling back
+// to the configured host ip address.
+func hostIPAddresses() ([]string, error) {
 	var ipAddresses []string
 	if ips, err := net.LookupIP(sys.Hostname); err != nil {
-		if ip, ok := os.LookupEnv("HOST_IP_ADDRESS"); !ok {
-			return fmt.Errorf("failed to resolve hostname %s: %w", sys.Hostname, err)
-		} else {
-			ipAddresses = append(ipAddresses, ip)
+		if hostIPAddress == "" {
+			return nil, fmt.Errorf("failed to resolve hostname %s: %w", sys.Hostname, err)
 		}
+		ipAddresses = append(ipAddresses, hostIPAddress)
 	} else {
 		for _, ip := range ips {
 			if ipv4 := ip.To4(); ipv4 != nil {
@@ -64,6 +86,31 @@ func EnsureCertificate(ctx context.Context, c client.Client, host string) error
 			}
 		}
 	}
+	return ipAddresses, nil
+}
+
+// The host name followed by the additional DNS names.
+func dnsNames(host string, extra []string) []string {
+	names := []string{host}
+	for _, name := range extra {
+		if !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// EnsureCertificate ensures that a certificate exists for the given host and IPs
+// TODO: move this code to a controller, so the node-agent doesn't need to have the rights
+// to create certificates for any host
+func EnsureCertificate(ctx context.Context, c client.Client, host string, options CertificateOptions) error {
+	log := logger.FromContext(ctx).WithName(logging.Certificates)
+	options = options.withDefaults()
+
+	ipAddresses, err := hostIPAddresses()
+	if err != nil {
+		return err
+	}
 
 	apiVersion := "cert-manager.io/v1"
 	secretName, certName := GetSecretAndCertName(host)
@@ -83,13 +130,12 @@ func EnsureCertificate(ctx context.Context, c client.Client, host string) error
 		certificate.Spec = cmapi.CertificateSpec{
 			SecretName: secretName,
 			PrivateKey: &cmapi.CertificatePrivateKey{
-				Algorithm: cmapi.RSAKeyAlgorithm,
+				Algorithm: keyAlgorithms[options.KeyAlgorithm],
 				Encoding:  cmapi.PKCS1,
-				Size:      4096,
+				Size:      options.KeySize,
 			},
-			// Values for testing, increase for production to something sensible
-			Duration:    &metav1.Duration{Duration: 8 * time.Hour},
-			RenewBefore: &metav1.Duration{Duration: 2 * time.Hour},
+			Duration:    &metav1.Duration{Duration: options.Duration},
+			RenewBefore: &metav1.Duration{Duration: options.RenewBefore},
 			IsCA:        false,
 			Usages: []cmapi.KeyUsage{
 				cmapi.UsageServerAuth,
@@ -102,11 +148,11 @@ func EnsureCertificate(ctx context.Context, c client.Client, host string) error
 				Organizations: []string{"nova"},
 			},
 			CommonName:  host,
-			DNSNames:    []string{host},
+			DNSNames:    dnsNames(host, options.DNSNames),
 			IPAddresses: ipAddresses,
 			IssuerRef: v1.IssuerReference{
-				Name:  os.Getenv("ISSUER_NAME"),
-				Kind:  cmapi.IssuerKind,
+				Name:  issuerName,
+				Kind:  options.IssuerKind,
 				Group: "cert-manager.io",
 			},
 		}
@@ -137,27 +183,116 @@ var symLinkMap = map[string][]string{
 	"server-key.pem":  {"qemu/client-key.pem", "ch/client-key.pem"},
 }
 
+// Files of the client certificate in the pki of other libvirt clients.
+var clientFileMap = map[string]string{
+	"ca.crt":  "CA/cacert.pem",
+	"tls.crt": "libvirt/clientcert.pem",
+	"tls.key": "libvirt/private/clientkey.pem",
+}
+
+// Get the paths of the CA certificate, the server certificate and the
+// server key as written by UpdateTLSCertificate.
+func TLSFiles() (caFile, certFile, keyFile string) {
+	return filepath.Join(pki, secretToFileMap["ca.crt"][0]),
+		filepath.Join(pki, secretToFileMap["tls.crt"][0]),
+		filepath.Join(pki, secretToFileMap["tls.key"][0])
+}
+
+// Parse the owner of the files, -1 if not configured.
+func parseOwner(owner string) (uid, gid int, err error) {
+	if owner == "" {
+		return -1, -1, nil
+	}
+	u, g, ok := strings.Cut(owner, ":")
+	if uid, err = strconv.Atoi(u); err != nil || !ok {
+		return 0, 0, fmt.Errorf("invalid owner %q, expected uid:gid", owner)
+	}
+	if gid, err = strconv.Atoi(g); err != nil {
+		return 0, 0, fmt.Errorf("invalid owner %q, expected uid:gid", owner)
+	}
+	return uid, gid, nil
+}
+
+// Replace the file atomically, so that libvirt never reads a partially
+// written file: the data is written to a temporary file in the same
+// directory, synced and renamed over the target.
+func writeFileAtomic(target string, data []byte, perm os.FileMode, uid, gid int) error {
+	dir := filepath.Dir(target)
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(target)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if uid >= 0 {
+		if err := tmp.Chown(uid, gid); err != nil {
+			tmp.Close()
+			return err
+		}
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), target); err != nil {
+		return err
+	}
+	// Persist the rename.
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
+
 func UpdateTLSCertificate(ctx context.Context, data map[string][]byte) error {
-	log := logger.FromContext(ctx)
+	log := logger.FromContext(ctx).WithName(logging.Certificates)
 	log.Info("updating TLS certificates for libvirt", "path", pki)
 
+	// Don't replace a working certificate with a broken one.
+	if err := ValidateTLSCertificate(data, time.Now()); err != nil {
+		return err
+	}
+	return installTLSCertificate(data)
+}
+
+// Write the certificate files and the symlinks of the client certificate.
+func installTLSCertificate(data map[string][]byte) error {
+	uid, gid, err := parseOwner(pkiOwner)
+	if err != nil {
+		return fmt.Errorf("invalid pki owner: %w", err)
+	}
+
 	// write files
 	for source, targets := range secretToFileMap {
+		// the private key is only readable by the owner and its group
+		perm := os.FileMode(0644)
+		if source == "tls.key" {
+			perm = 0640
+		}
 		for _, target := range targets {
 			// prepend the pki path for the target
 			target = filepath.Join(pki, target)
 
-			if _, ok := data[source]; !ok {
-				return fmt.Errorf("missing data for secret key %s", source)
-			}
-
 			// ensure the target directory exists
 			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
 				return fmt.Errorf("failed to create directory %s: %w", filepath.Dir(target), err)
 			}
 
 			// write the file
-			if err := os.WriteFile(target, data[source], 0644); err != nil {
+			if err := writeFileAtomic(target, data[source], perm, uid, gid); err != nil {
 				return fmt.Errorf("failed to write targetFile %s: %w", target, err)
 			}
 		}
@@ -207,5 +342,46 @@ func UpdateTLSCertificate(ctx context.Context, data map[string][]byte) error {
 			}
 		}
 	}
+
+	// provision the client certificate for the other consumers on the host
+	for _, target := range clientTargets {
+		if err := installClientCertificate(target, data); err != nil {
+			return fmt.Errorf("failed to install client certificate into %s: %w", target.Path, err)
+		}
+	}
+	return nil
+}
+
+// Write the client certificate into the pki of another libvirt client,
+// e.g. of the nova-compute pod.
+func installClientCertificate(target config.ClientTarget, data map[string][]byte) error {
+	owner := target.Owner
+	if owner == "" {
+		owner = pkiOwner
+	}
+	uid, gid, err := parseOwner(owner)
+	if err != nil {
+		return err
+	}
+	keyMode := os.FileMode(0640)
+	if target.KeyMode != "" {
+		if keyMode, err = config.ParseMode(target.KeyMode); err != nil {
+			return fmt.Errorf("invalid key mode: %w", err)
+		}
+	}
+
+	for source, file := range clientFileMap {
+		perm := os.FileMode(0644)
+		if source == "tls.key" {
+			perm = keyMode
+		}
+		file = filepath.Join(target.Path, file)
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			return fmt.Errorf("failed to create directory %s: %w", filepath.Dir(file), err)
+		}
+		if err := writeFileAtomic(file, data[source], perm, uid, gid); err != nil {
+			return fmt.Errorf("failed to write targetFile %s: %w", file, err)
+		}
+	}
 	return nil
 }
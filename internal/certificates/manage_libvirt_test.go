@@ -0,0 +1,126 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package certificates
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/config"
+)
+
+func TestUpdateTLSCertificate(t *testing.T) {
+	old := pki
+	pki = t.TempDir()
+	t.Cleanup(func() { pki = old })
+
+	ca := newTestCertificate(t, "ca", nil)
+	cert := newTestCertificate(t, "host", ca)
+	other := newTestCertificate(t, "other", ca)
+
+	// A key not matching the certificate is rejected before writing.
+	err := UpdateTLSCertificate(context.Background(), map[string][]byte{
+		"ca.crt": ca.certPEM, "tls.crt": cert.certPEM, "tls.key": other.keyPEM,
+	})
+	if err == nil {
+		t.Fatal("expected an error for a mismatching key")
+	}
+	if _, err := os.Stat(filepath.Join(pki, "libvirt")); !os.IsNotExist(err) {
+		t.Error("expected no files to be written")
+	}
+
+	err = UpdateTLSCertificate(context.Background(), map[string][]byte{
+		"ca.crt": ca.certPEM, "tls.crt": cert.certPEM, "tls.key": cert.keyPEM,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, certFile, keyFile := TLSFiles()
+	data, err := os.ReadFile(certFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != string(cert.certPEM) {
+		t.Error("unexpected certificate written")
+	}
+	info, err := os.Stat(keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0640 {
+		t.Errorf("expected key permissions 0640, got %o", info.Mode().Perm())
+	}
+	// No temporary files are left behind.
+	entries, err := os.ReadDir(filepath.Dir(certFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		if entry.Name()[0] == '.' {
+			t.Errorf("temporary file %s left behind", entry.Name())
+		}
+	}
+}
+
+func TestUpdateTLSCertificate_ClientTargets(t *testing.T) {
+	oldPKI, oldTargets := pki, clientTargets
+	pki = t.TempDir()
+	nova := t.TempDir()
+	clientTargets = []config.ClientTarget{{Path: nova, KeyMode: "0600"}}
+	t.Cleanup(func() { pki, clientTargets = oldPKI, oldTargets })
+
+	ca := newTestCertificate(t, "ca", nil)
+	cert := newTestCertificate(t, "host", ca)
+	data := map[string][]byte{"ca.crt": ca.certPEM, "tls.crt": cert.certPEM, "tls.key": cert.keyPEM}
+	if err := UpdateTLSCertificate(context.Background(), data); err != nil {
+		t.Fatal(err)
+	}
+
+	for source, file := range clientFileMap {
+		written, err := os.ReadFile(filepath.Join(nova, file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(written) != string(data[source]) {
+			t.Errorf("unexpected content of %s", file)
+		}
+	}
+	info, err := os.Stat(filepath.Join(nova, clientFileMap["tls.key"]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("expected key permissions 0600, got %o", info.Mode().Perm())
+	}
+}
+
+func TestParseOwner(t *testing.T) {
+	if uid, gid, err := parseOwner(""); err != nil || uid != -1 || gid != -1 {
+		t.Errorf("expected no owner, got %d:%d %v", uid, gid, err)
+	}
+	if uid, gid, err := parseOwner("107:108"); err != nil || uid != 107 || gid != 108 {
+		t.Errorf("expected 107:108, got %d:%d %v", uid, gid, err)
+	}
+	for _, owner := range []string{"qemu", "qemu:kvm", "107"} {
+		if _, _, err := parseOwner(owner); err == nil {
+			t.Errorf("expected an error for %q", owner)
+		}
+	}
+}
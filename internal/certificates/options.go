@@ -0,0 +1,98 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package certificates
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	cmapi "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
+)
+
+// CertificateOptions of the libvirt TLS certificate requested for the host.
+type CertificateOptions struct {
+	// Algorithm of the private key, rsa or ecdsa, defaults to rsa.
+	KeyAlgorithm string
+	// Size of the private key in bits, defaults to 4096 for rsa and 256
+	// for ecdsa.
+	KeySize int
+	// Validity of the certificate, defaults to 8 hours.
+	Duration time.Duration
+	// Remaining validity below which the certificate is renewed, defaults
+	// to 2 hours.
+	RenewBefore time.Duration
+	// DNS names of the certificate in addition to the host name.
+	DNSNames []string
+	// Kind of the cert-manager issuer, Issuer or ClusterIssuer, defaults
+	// to Issuer.
+	IssuerKind string
+}
+
+var keySizes = map[string][]int{
+	"rsa":   {2048, 3072, 4096},
+	"ecdsa": {256, 384, 521},
+}
+
+// Private key algorithms of cert-manager by the name used in the options.
+var keyAlgorithms = map[string]cmapi.PrivateKeyAlgorithm{
+	"rsa":   cmapi.RSAKeyAlgorithm,
+	"ecdsa": cmapi.ECDSAKeyAlgorithm,
+}
+
+// Fill in the defaults of the options not set.
+func (o CertificateOptions) withDefaults() CertificateOptions {
+	if o.KeyAlgorithm == "" {
+		o.KeyAlgorithm = "rsa"
+	}
+	if o.KeySize == 0 {
+		o.KeySize = 4096
+		if o.KeyAlgorithm == "ecdsa" {
+			o.KeySize = 256
+		}
+	}
+	if o.Duration == 0 {
+		o.Duration = 8 * time.Hour
+	}
+	if o.RenewBefore == 0 {
+		o.RenewBefore = 2 * time.Hour
+	}
+	if o.IssuerKind == "" {
+		o.IssuerKind = cmapi.IssuerKind
+	}
+	return o
+}
+
+// Validate the options, unset options are valid and defaulted.
+func (o CertificateOptions) Validate() error {
+	o = o.withDefaults()
+	sizes, ok := keySizes[o.KeyAlgorithm]
+	if !ok {
+		return fmt.Errorf("invalid key algorithm %q, expected rsa or ecdsa", o.KeyAlgorithm)
+	}
+	if !slices.Contains(sizes, o.KeySize) {
+		return fmt.Errorf("invalid %s key size %d, expected one of %v", o.KeyAlgorithm, o.KeySize, sizes)
+	}
+	if o.RenewBefore >= o.Duration {
+		return fmt.Errorf("renew before %s has to be shorter than the duration %s", o.RenewBefore, o.Duration)
+	}
+	if o.IssuerKind != cmapi.IssuerKind && o.IssuerKind != cmapi.ClusterIssuerKind {
+		return fmt.Errorf("invalid issuer kind %q, expected Issuer or ClusterIssuer", o.IssuerKind)
+	}
+	return nil
+}
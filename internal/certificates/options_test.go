@@ -0,0 +1,62 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package certificates
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestCertificateOptions(t *testing.T) {
+	defaults := CertificateOptions{}.withDefaults()
+	if defaults.KeyAlgorithm != "rsa" || defaults.KeySize != 4096 || defaults.Duration != 8*time.Hour ||
+		defaults.RenewBefore != 2*time.Hour || defaults.IssuerKind != "Issuer" {
+		t.Errorf("unexpected defaults %+v", defaults)
+	}
+	if size := (CertificateOptions{KeyAlgorithm: "ecdsa"}).withDefaults().KeySize; size != 256 {
+		t.Errorf("expected ecdsa key size 256, got %d", size)
+	}
+
+	valid := []CertificateOptions{
+		{},
+		{KeyAlgorithm: "ecdsa", KeySize: 384, Duration: 90 * 24 * time.Hour, RenewBefore: 30 * 24 * time.Hour},
+		{IssuerKind: "ClusterIssuer"},
+	}
+	for _, options := range valid {
+		if err := options.Validate(); err != nil {
+			t.Errorf("expected %+v to be valid: %v", options, err)
+		}
+	}
+	invalid := []CertificateOptions{
+		{KeyAlgorithm: "dsa"},
+		{KeyAlgorithm: "ecdsa", KeySize: 4096},
+		{Duration: time.Hour},
+		{IssuerKind: "ExternalIssuer"},
+	}
+	for _, options := range invalid {
+		if err := options.Validate(); err == nil {
+			t.Errorf("expected %+v to be invalid", options)
+		}
+	}
+
+	if names := dnsNames("host", []string{"host.example.com", "host"}); !slices.Equal(names,
+		[]string{"host", "host.example.com"}) {
+		t.Errorf("unexpected dns names %v", names)
+	}
+}
@@ -0,0 +1,251 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package certificates
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	logger "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/config"
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/logging"
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/sys"
+)
+
+// +kubebuilder:rbac:groups="",namespace=monsoon3,resources=secrets,verbs=get;list;watch;create;update;patch
+
+// Provider issues the certificate of the libvirt TLS endpoints of a host
+// into the secret installed by the secret controller.
+type Provider interface {
+	// EnsureCertificate makes sure the secret of the host holds a valid
+	// certificate, or is going to.
+	EnsureCertificate(ctx context.Context, c client.Client, host string) error
+}
+
+// Names of the certificate providers.
+const (
+	ProviderCertManager = "cert-manager"
+	ProviderSecret      = "secret"
+	ProviderVault       = "vault"
+)
+
+// Create the certificate provider from its flag value. The vault provider
+// is configured by the address and token of the agent configuration, the
+// key options only apply to cert-manager.
+func NewProvider(name, vaultIssuePath string, vault config.Vault, options CertificateOptions) (Provider, error) {
+	if err := options.Validate(); err != nil {
+		return nil, err
+	}
+	switch name {
+	case ProviderCertManager:
+		return CertManager{Options: options}, nil
+	case ProviderSecret:
+		return Secret{}, nil
+	case ProviderVault:
+		if vault.Address == "" || vault.Token == "" {
+			return nil, errors.New("the vault certificate provider requires the vault address and token")
+		}
+		return &Vault{
+			Address:     vault.Address,
+			Token:       vault.Token,
+			IssuePath:   vaultIssuePath,
+			TTL:         options.Duration,
+			RenewBefore: options.RenewBefore,
+			DNSNames:    options.DNSNames,
+		}, nil
+	}
+	return nil, fmt.Errorf("invalid certificate provider %q, expected one of cert-manager, secret, vault", name)
+}
+
+// CertManager requests the certificate with a cert-manager Certificate,
+// which writes it into the secret.
+type CertManager struct {
+	Options CertificateOptions
+}
+
+// EnsureCertificate creates or updates the Certificate of the host.
+func (p CertManager) EnsureCertificate(ctx context.Context, c client.Client, host string) error {
+	return EnsureCertificate(ctx, c, host, p.Options)
+}
+
+// Secret expects the secret to be provisioned by other means, e.g. by an
+// external secrets operator.
+type Secret struct{}
+
+// EnsureCertificate checks that the secret of the host exists.
+func (Secret) EnsureCertificate(ctx context.Context, c client.Client, host string) error {
+	secretName, _ := GetSecretAndCertName(host)
+	secret := &corev1.Secret{}
+	if err := c.Get(ctx, types.NamespacedName{Name: secretName, Namespace: sys.Namespace}, secret); err != nil {
+		if apierrors.IsNotFound(err) {
+			return fmt.Errorf("secret %s/%s with the libvirt certificate is not provisioned", sys.Namespace, secretName)
+		}
+		return err
+	}
+	return nil
+}
+
+// Vault issues the certificate with the PKI secrets engine of Vault and
+// writes it into the secret, renewing it before it expires.
+type Vault struct {
+	// Address of Vault, e.g. https://vault.example.com.
+	Address string
+	// Token to authenticate with. It is never renewed by the agent, so it
+	// has to be valid for as long as the agent runs, e.g. a token without
+	// expiry limited to the issue endpoint. A rotated token only takes
+	// effect once the agent is restarted.
+	Token string
+	// Path of the issue endpoint of the PKI secrets engine, e.g.
+	// pki/issue/libvirt.
+	IssuePath string
+	// Validity of the issued certificates, defaults to 8 hours.
+	TTL time.Duration
+	// Remaining validity below which the certificate is renewed, defaults
+	// to 2 hours.
+	RenewBefore time.Duration
+	// DNS names of the certificate in addition to the host name.
+	DNSNames []string
+	// Client Vault is requested with, defaults to http.DefaultClient.
+	// Requests are aborted after vaultRequestTimeout nevertheless.
+	HTTPClient *http.Client
+}
+
+// Timeout of a request to Vault, so that a hanging Vault doesn't block the
+// renewal of the certificate forever.
+var vaultRequestTimeout = 30 * time.Second
+
+// Response of the issue endpoint, see
+// https://developer.hashicorp.com/vault/api-docs/secret/pki#generate-certificate-and-key
+type vaultIssueResponse struct {
+	Data struct {
+		Certificate string `json:"certificate"`
+		IssuingCA   string `json:"issuing_ca"`
+		PrivateKey  string `json:"private_key"`
+	} `json:"data"`
+	Errors []string `json:"errors"`
+}
+
+// EnsureCertificate issues a certificate into the secret of the host,
+// unless the secret holds one that is valid long enough.
+func (v *Vault) EnsureCertificate(ctx context.Context, c client.Client, host string) error {
+	log := logger.FromContext(ctx).WithName(logging.Certificates)
+
+	renewBefore := v.RenewBefore
+	if renewBefore == 0 {
+		renewBefore = 2 * time.Hour
+	}
+	secretName, _ := GetSecretAndCertName(host)
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: secretName, Namespace: sys.Namespace},
+	}
+	err := c.Get(ctx, client.ObjectKeyFromObject(secret), secret)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	if err == nil {
+		expiry, err := CertificateExpiry(secret.Data["tls.crt"])
+		if err == nil && time.Until(expiry) > renewBefore {
+			return nil
+		}
+	}
+
+	ipAddresses, err := hostIPAddresses()
+	if err != nil {
+		return err
+	}
+	issued, err := v.issue(ctx, host, ipAddresses)
+	if err != nil {
+		return err
+	}
+
+	update, err := controllerutil.CreateOrUpdate(ctx, c, secret, func() error {
+		secret.Type = corev1.SecretTypeTLS
+		secret.Data = map[string][]byte{
+			"ca.crt":  []byte(issued.Data.IssuingCA),
+			"tls.crt": []byte(issued.Data.Certificate),
+			"tls.key": []byte(issued.Data.PrivateKey),
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	log.Info(fmt.Sprintf("Secret %s %s with certificate issued by vault", secretName, update))
+	return nil
+}
+
+func (v *Vault) issue(ctx context.Context, host string, ipAddresses []string) (*vaultIssueResponse, error) {
+	ttl := v.TTL
+	if ttl == 0 {
+		ttl = 8 * time.Hour
+	}
+	body, err := json.Marshal(map[string]string{
+		"common_name": host,
+		"alt_names":   strings.Join(dnsNames(host, v.DNSNames), ","),
+		"ip_sans":     strings.Join(ipAddresses, ","),
+		"ttl":         ttl.String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, vaultRequestTimeout)
+	defer cancel()
+	url := strings.TrimSuffix(v.Address, "/") + "/v1/" + strings.TrimPrefix(v.IssuePath, "/")
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Vault-Token", v.Token)
+	req.Header.Set("Content-Type", "application/json")
+
+	httpClient := v.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to issue certificate with vault: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var issued vaultIssueResponse
+	if err := json.NewDecoder(resp.Body).Decode(&issued); err != nil && resp.StatusCode == http.StatusOK {
+		return nil, fmt.Errorf("failed to decode vault response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to issue certificate with vault: %s %s",
+			resp.Status, strings.Join(issued.Errors, ", "))
+	}
+	if issued.Data.Certificate == "" || issued.Data.PrivateKey == "" {
+		return nil, fmt.Errorf("vault issued no certificate")
+	}
+	return &issued, nil
+}
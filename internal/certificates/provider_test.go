@@ -0,0 +1,139 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package certificates
+
+import (
+	"context"
+	"errors"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/sys"
+)
+
+func TestVault(t *testing.T) {
+	old := hostIPAddress
+	hostIPAddress = "10.0.0.1"
+	t.Cleanup(func() { hostIPAddress = old })
+	ca := newTestCertificate(t, "ca", nil)
+	cert := newTestCertificate(t, "host", ca)
+
+	var requests []map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/pki/issue/libvirt" || r.Header.Get("X-Vault-Token") != "token" {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"errors":["permission denied"]}`))
+			return
+		}
+		var request map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Error(err)
+		}
+		requests = append(requests, request)
+		_ = json.NewEncoder(w).Encode(map[string]any{"data": map[string]string{
+			"certificate": string(cert.certPEM),
+			"issuing_ca":  string(ca.certPEM),
+			"private_key": string(cert.keyPEM),
+		}})
+	}))
+	defer server.Close()
+
+	scheme := runtime.NewScheme()
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).Build()
+	vault := &Vault{
+		Address:     server.URL,
+		Token:       "token",
+		IssuePath:   "pki/issue/libvirt",
+		RenewBefore: 30 * time.Minute,
+	}
+	ctx := context.Background()
+	if err := vault.EnsureCertificate(ctx, c, "host"); err != nil {
+		t.Fatal(err)
+	}
+	if len(requests) != 1 || requests[0]["common_name"] != "host" || requests[0]["ttl"] != "8h0m0s" {
+		t.Fatalf("unexpected requests %v", requests)
+	}
+	secretName, _ := GetSecretAndCertName("host")
+	secret := &corev1.Secret{}
+	if err := c.Get(ctx, types.NamespacedName{Name: secretName, Namespace: sys.Namespace}, secret); err != nil {
+		t.Fatal(err)
+	}
+	if string(secret.Data["tls.crt"]) != string(cert.certPEM) || string(secret.Data["ca.crt"]) != string(ca.certPEM) {
+		t.Error("secret doesn't hold the issued certificate")
+	}
+
+	// The certificate is valid long enough.
+	if err := vault.EnsureCertificate(ctx, c, "host"); err != nil {
+		t.Fatal(err)
+	}
+	if len(requests) != 1 {
+		t.Errorf("expected no renewal, got %d requests", len(requests))
+	}
+
+	vault.RenewBefore = 2 * time.Hour
+	vault.Token = "expired"
+	if err := vault.EnsureCertificate(ctx, c, "host"); err == nil {
+		t.Error("expected an error for a rejected token")
+	}
+}
+
+func TestVault_Timeout(t *testing.T) {
+	old, oldTimeout := hostIPAddress, vaultRequestTimeout
+	hostIPAddress, vaultRequestTimeout = "10.0.0.1", 50*time.Millisecond
+	t.Cleanup(func() { hostIPAddress, vaultRequestTimeout = old, oldTimeout })
+
+	hang := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-hang
+	}))
+	defer server.Close()
+	defer close(hang)
+
+	scheme := runtime.NewScheme()
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).Build()
+	vault := &Vault{Address: server.URL, Token: "token", IssuePath: "pki/issue/libvirt"}
+	err := vault.EnsureCertificate(context.Background(), c, "host")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the request to time out, got %v", err)
+	}
+}
+
+func TestSecret(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).Build()
+	if err := (Secret{}).EnsureCertificate(context.Background(), c, "host"); err == nil {
+		t.Error("expected an error for a missing secret")
+	}
+}
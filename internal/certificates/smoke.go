@@ -0,0 +1,168 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package certificates
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/sys"
+)
+
+// Port of the libvirt TLS endpoint.
+const LibvirtTLSPort = "16514"
+
+// SmokeTest checks that the libvirt TLS endpoints accept the installed
+// certificate, after libvirt was told to reload it.
+type SmokeTest struct {
+	// Address of the own libvirt TLS endpoint.
+	Address string
+	// Name the certificate of the own endpoint is verified against.
+	ServerName string
+	// Host name of another hypervisor, optionally with port. If set, the
+	// TLS handshake a live migration to it would do is checked with the
+	// qemu certificate, without migrating anything.
+	MigrationPeer string
+	// How long to wait for libvirt to serve the installed certificate.
+	Timeout time.Duration
+	// Interval between two handshakes while waiting.
+	Interval time.Duration
+}
+
+// NewSmokeTest creates a smoke test against the libvirt TLS endpoint of
+// this host, and of the migration peer if not empty.
+func NewSmokeTest(migrationPeer string) *SmokeTest {
+	return &SmokeTest{
+		Address:       net.JoinHostPort(sys.Hostname, LibvirtTLSPort),
+		ServerName:    sys.Hostname,
+		MigrationPeer: migrationPeer,
+		Timeout:       30 * time.Second,
+		Interval:      2 * time.Second,
+	}
+}
+
+// Run the handshakes and return a summary of the results for humans.
+func (s *SmokeTest) Run(ctx context.Context) (string, error) {
+	caFile, certFile, keyFile := TLSFiles()
+	installed, err := readCertificate(certFile)
+	if err != nil {
+		return "", err
+	}
+
+	// Reloading the certificate is asynchronous, retry until libvirt serves
+	// the installed certificate.
+	var lastErr error
+	err = wait.PollUntilContextTimeout(ctx, s.Interval, s.Timeout, true, func(ctx context.Context) (bool, error) {
+		served, err := handshake(ctx, s.Address, s.ServerName, caFile, certFile, keyFile)
+		switch {
+		case err != nil && lastErr != nil && errors.Is(err, context.DeadlineExceeded):
+			// Cut off by the timeout, keep the error of the previous attempt.
+		case err != nil:
+			lastErr = err
+		case !served.Equal(installed):
+			lastErr = errors.New("libvirt still serves the previous certificate")
+		default:
+			return true, nil
+		}
+		return false, nil
+	})
+	if err != nil {
+		if lastErr == nil {
+			lastErr = err
+		}
+		return "", fmt.Errorf("loopback handshake with %s failed: %w", s.Address, lastErr)
+	}
+	results := []string{fmt.Sprintf("loopback handshake succeeded, certificate valid until %s",
+		installed.NotAfter.UTC().Format(time.RFC3339))}
+
+	if s.MigrationPeer != "" {
+		// Qemu uses its own copy of the certificates for migrations.
+		address, host := s.MigrationPeer, s.MigrationPeer
+		if h, _, err := net.SplitHostPort(address); err == nil {
+			host = h
+		} else {
+			address = net.JoinHostPort(address, LibvirtTLSPort)
+		}
+		if _, err := handshake(ctx, address, host,
+			filepath.Join(pki, secretToFileMap["ca.crt"][1]),
+			filepath.Join(pki, secretToFileMap["tls.crt"][1]),
+			filepath.Join(pki, secretToFileMap["tls.key"][1]),
+		); err != nil {
+			return "", fmt.Errorf("migration handshake with %s failed: %w", address, err)
+		}
+		results = append(results, fmt.Sprintf("migration handshake with %s succeeded", s.MigrationPeer))
+	}
+	return strings.Join(results, "; "), nil
+}
+
+// Do a mutual TLS handshake with the endpoint and return the certificate
+// it presented.
+func handshake(ctx context.Context, address, serverName, caFile, certFile, keyFile string) (*x509.Certificate, error) {
+	ca, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return nil, fmt.Errorf("no certificates found in %s", caFile)
+	}
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	dialer := tls.Dialer{
+		NetDialer: &net.Dialer{Timeout: 5 * time.Second},
+		Config: &tls.Config{
+			RootCAs:      pool,
+			Certificates: []tls.Certificate{cert},
+			ServerName:   serverName,
+			MinVersion:   tls.VersionTLS12,
+		},
+	}
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	return conn.(*tls.Conn).ConnectionState().PeerCertificates[0], nil
+}
+
+// Read the first certificate of a PEM file.
+func readCertificate(path string) (*x509.Certificate, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, fmt.Errorf("no certificate found in %s", path)
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
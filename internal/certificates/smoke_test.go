@@ -0,0 +1,203 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package certificates
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testCertificate struct {
+	cert    *x509.Certificate
+	key     *ecdsa.PrivateKey
+	certPEM []byte
+	keyPEM  []byte
+}
+
+func newTestCertificate(t *testing.T, name string, parent *testCertificate) *testCertificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		DNSNames:     []string{name},
+	}
+	signer, signerKey := template, key
+	if parent == nil {
+		template.IsCA, template.BasicConstraintsValid = true, true
+	} else {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testCertificate{
+		cert:    cert,
+		key:     key,
+		certPEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		keyPEM:  pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+	}
+}
+
+// Install the certificate into a temporary pki directory.
+func installTestCertificate(t *testing.T, ca, cert *testCertificate) {
+	t.Helper()
+	old := pki
+	pki = t.TempDir()
+	t.Cleanup(func() { pki = old })
+	data := map[string][]byte{"ca.crt": ca.certPEM, "tls.crt": cert.certPEM, "tls.key": cert.keyPEM}
+	for source, targets := range secretToFileMap {
+		for _, target := range targets {
+			target = filepath.Join(pki, target)
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(target, data[source], 0600); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+}
+
+// Serve TLS handshakes requiring a client certificate signed by the ca.
+func serveTLS(t *testing.T, ca, cert *testCertificate) string {
+	t.Helper()
+	pair, err := tls.X509KeyPair(cert.certPEM, cert.keyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{pair},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    pool,
+		MinVersion:   tls.VersionTLS12,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			_ = conn.(*tls.Conn).Handshake()
+			conn.Close()
+		}
+	}()
+	return listener.Addr().String()
+}
+
+func TestSmokeTest(t *testing.T) {
+	ca := newTestCertificate(t, "ca", nil)
+	cert := newTestCertificate(t, "localhost", ca)
+	installTestCertificate(t, ca, cert)
+	address := serveTLS(t, ca, cert)
+
+	smokeTest := &SmokeTest{
+		Address:       address,
+		ServerName:    "localhost",
+		MigrationPeer: address,
+		Timeout:       time.Second,
+		Interval:      10 * time.Millisecond,
+	}
+	// The peer is verified against its host name from the address.
+	if _, err := smokeTest.Run(context.Background()); err == nil {
+		t.Errorf("Expected migration handshake to fail for peer without certificate for 127.0.0.1")
+	}
+
+	smokeTest.MigrationPeer = ""
+	result, err := smokeTest.Run(context.Background())
+	if err != nil {
+		t.Fatalf("Expected smoke test to succeed: %v", err)
+	}
+	if !strings.HasPrefix(result, "loopback handshake succeeded") {
+		t.Errorf("Unexpected result: %s", result)
+	}
+}
+
+func TestSmokeTest_PreviousCertificate(t *testing.T) {
+	ca := newTestCertificate(t, "ca", nil)
+	previous := newTestCertificate(t, "localhost", ca)
+	installTestCertificate(t, ca, newTestCertificate(t, "localhost", ca))
+	address := serveTLS(t, ca, previous)
+
+	smokeTest := &SmokeTest{
+		Address:    address,
+		ServerName: "localhost",
+		Timeout:    100 * time.Millisecond,
+		Interval:   10 * time.Millisecond,
+	}
+	_, err := smokeTest.Run(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "previous certificate") {
+		t.Errorf("Expected smoke test to detect the previous certificate, got %v", err)
+	}
+}
+
+func TestSmokeTest_Unreachable(t *testing.T) {
+	ca := newTestCertificate(t, "ca", nil)
+	installTestCertificate(t, ca, newTestCertificate(t, "localhost", ca))
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := listener.Addr().String()
+	listener.Close()
+
+	smokeTest := &SmokeTest{
+		Address:    address,
+		ServerName: "localhost",
+		Timeout:    100 * time.Millisecond,
+		Interval:   10 * time.Millisecond,
+	}
+	if _, err := smokeTest.Run(context.Background()); err == nil {
+		t.Errorf("Expected smoke test to fail without endpoint")
+	}
+}
@@ -0,0 +1,133 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package certificates
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// ErrInvalidCertificate is returned for certificate data which would
+// break the TLS endpoints of libvirt. Nothing is installed then.
+var ErrInvalidCertificate = errors.New("invalid TLS certificate")
+
+// ValidateTLSCertificate checks the data of the certificate Secret before
+// it is installed: the key matches the certificate, the certificate chains
+// up to the CA, and all of them are valid at the time. libvirtd refuses to
+// start otherwise, with errors pointing to neither of them.
+func ValidateTLSCertificate(data map[string][]byte, now time.Time) error {
+	for source := range secretToFileMap {
+		if _, ok := data[source]; !ok {
+			return fmt.Errorf("%w: missing data for secret key %s", ErrInvalidCertificate, source)
+		}
+	}
+	if _, err := tls.X509KeyPair(data["tls.crt"], data["tls.key"]); err != nil {
+		return fmt.Errorf("%w: invalid certificate and key pair: %w", ErrInvalidCertificate, err)
+	}
+	chain, err := parseCertificates(data["tls.crt"])
+	if err != nil {
+		return fmt.Errorf("%w: tls.crt: %w", ErrInvalidCertificate, err)
+	}
+	cas, err := parseCertificates(data["ca.crt"])
+	if err != nil {
+		return fmt.Errorf("%w: ca.crt: %w", ErrInvalidCertificate, err)
+	}
+
+	leaf := chain[0]
+	if now.After(leaf.NotAfter) {
+		return fmt.Errorf("%w: certificate expired at %s", ErrInvalidCertificate, leaf.NotAfter.UTC().Format(time.RFC3339))
+	}
+	if now.Before(leaf.NotBefore) {
+		return fmt.Errorf("%w: certificate is not valid before %s",
+			ErrInvalidCertificate, leaf.NotBefore.UTC().Format(time.RFC3339))
+	}
+	roots := x509.NewCertPool()
+	for _, ca := range cas {
+		roots.AddCert(ca)
+	}
+	intermediates := x509.NewCertPool()
+	for _, cert := range chain[1:] {
+		intermediates.AddCert(cert)
+	}
+	// The certificate is used by libvirt as server and client certificate,
+	// the usages are left to the issuer.
+	if _, err := leaf.Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		CurrentTime:   now,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	}); err != nil {
+		return fmt.Errorf("%w: certificate does not chain up to the CA: %w", ErrInvalidCertificate, err)
+	}
+	return nil
+}
+
+// Parse the certificates of the PEM data, skipping other blocks.
+func parseCertificates(data []byte) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return nil, errors.New("no certificate found")
+	}
+	return certs, nil
+}
+
+// MergeCABundle appends the certificates of the bundle missing in the CA
+// certificate, e.g. of a cluster CA distributed separately from the
+// certificate of the host.
+func MergeCABundle(ca, bundle []byte) ([]byte, error) {
+	cas, err := parseCertificates(ca)
+	if err != nil {
+		return nil, fmt.Errorf("ca.crt: %w", err)
+	}
+	additional, err := parseCertificates(bundle)
+	if err != nil {
+		return nil, fmt.Errorf("CA bundle: %w", err)
+	}
+	merged := slices.Clone(ca)
+	for _, cert := range additional {
+		if slices.ContainsFunc(cas, cert.Equal) {
+			continue
+		}
+		if len(merged) > 0 && merged[len(merged)-1] != '\n' {
+			merged = append(merged, '\n')
+		}
+		merged = append(merged, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})...)
+		cas = append(cas, cert)
+	}
+	return merged, nil
+}
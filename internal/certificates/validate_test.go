@@ -0,0 +1,86 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package certificates
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidateTLSCertificate(t *testing.T) {
+	ca := newTestCertificate(t, "ca", nil)
+	cert := newTestCertificate(t, "host", ca)
+	other := newTestCertificate(t, "other", nil)
+	now := time.Now()
+
+	valid := map[string][]byte{"ca.crt": ca.certPEM, "tls.crt": cert.certPEM, "tls.key": cert.keyPEM}
+	if err := ValidateTLSCertificate(valid, now); err != nil {
+		t.Fatalf("Expected a valid certificate, got %v", err)
+	}
+
+	for _, tc := range []struct {
+		name    string
+		data    map[string][]byte
+		now     time.Time
+		message string
+	}{
+		{"missing key", map[string][]byte{"ca.crt": ca.certPEM, "tls.crt": cert.certPEM}, now, "missing data"},
+		{"mismatching key", map[string][]byte{"ca.crt": ca.certPEM, "tls.crt": cert.certPEM, "tls.key": other.keyPEM},
+			now, "key pair"},
+		{"invalid ca", map[string][]byte{"ca.crt": []byte("garbage"), "tls.crt": cert.certPEM, "tls.key": cert.keyPEM},
+			now, "ca.crt: no certificate found"},
+		{"other ca", map[string][]byte{"ca.crt": other.certPEM, "tls.crt": cert.certPEM, "tls.key": cert.keyPEM},
+			now, "does not chain up to the CA"},
+		{"expired", valid, now.Add(2 * time.Hour), "expired at"},
+		{"not yet valid", valid, now.Add(-2 * time.Hour), "not valid before"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateTLSCertificate(tc.data, tc.now)
+			if !errors.Is(err, ErrInvalidCertificate) {
+				t.Fatalf("Expected an invalid certificate, got %v", err)
+			}
+			if !strings.Contains(err.Error(), tc.message) {
+				t.Errorf("Expected %q in %q", tc.message, err.Error())
+			}
+		})
+	}
+}
+
+func TestMergeCABundle(t *testing.T) {
+	ca := newTestCertificate(t, "ca", nil)
+	cluster := newTestCertificate(t, "cluster", nil)
+
+	merged, err := MergeCABundle(ca.certPEM, append(slices.Clone(cluster.certPEM), ca.certPEM...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	certs, err := parseCertificates(merged)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(certs) != 2 || !certs[0].Equal(ca.cert) || !certs[1].Equal(cluster.cert) {
+		t.Errorf("Expected the CA followed by the cluster CA, got %d certificates", len(certs))
+	}
+
+	if _, err := MergeCABundle(ca.certPEM, []byte("garbage")); err == nil {
+		t.Error("Expected an error for an invalid bundle")
+	}
+}
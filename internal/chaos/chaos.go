@@ -0,0 +1,133 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package chaos provides fault injection for rehearsing operational runbooks,
+// e.g. evacuation and libvirt reconnection, on staging nodes. The faults are
+// injected through a debug API which is only served when explicitly enabled.
+package chaos
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/coreos/go-systemd/v22/dbus"
+	logger "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/systemd"
+)
+
+// Systemd wraps a systemd.Interface and reports injected unit failures
+// instead of the real unit state. It also captures the shutdown callback so
+// that a PrepareForShutdown signal can be faked.
+type Systemd struct {
+	systemd.Interface
+
+	mu sync.Mutex
+	// Units which are reported as failed.
+	failedUnits map[string]struct{}
+	// Callback registered with EnableShutdownInhibit, nil if not enabled.
+	shutdownCallback func(context.Context) error
+}
+
+// NewSystemd wraps the given systemd interface.
+func NewSystemd(inner systemd.Interface) *Systemd {
+	return &Systemd{Interface: inner, failedUnits: make(map[string]struct{})}
+}
+
+// Report the given unit as failed until the failure is cleared.
+func (s *Systemd) FailUnit(unit string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failedUnits[unit] = struct{}{}
+}
+
+// Clear an injected unit failure.
+func (s *Systemd) ClearUnit(unit string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.failedUnits, unit)
+}
+
+// Override the state of units with an injected failure.
+func (s *Systemd) inject(status dbus.UnitStatus) dbus.UnitStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.failedUnits[status.Name]; ok {
+		status.ActiveState = systemd.FAILED
+		status.SubState = systemd.FAILED
+	}
+	return status
+}
+
+func (s *Systemd) ListUnitsByNames(ctx context.Context, units []string) ([]dbus.UnitStatus, error) {
+	statuses, err := s.Interface.ListUnitsByNames(ctx, units)
+	if err != nil {
+		return nil, err
+	}
+	for i := range statuses {
+		statuses[i] = s.inject(statuses[i])
+	}
+	return statuses, nil
+}
+
+func (s *Systemd) GetUnitByName(ctx context.Context, unit string) (dbus.UnitStatus, error) {
+	status, err := s.Interface.GetUnitByName(ctx, unit)
+	if err != nil {
+		return status, err
+	}
+	return s.inject(status), nil
+}
+
+func (s *Systemd) EnableShutdownInhibit(ctx context.Context, cb func(ctx2 context.Context) error) error {
+	if err := s.Interface.EnableShutdownInhibit(ctx, cb); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.shutdownCallback = cb
+	return nil
+}
+
+func (s *Systemd) DisableShutdownInhibit() error {
+	if err := s.Interface.DisableShutdownInhibit(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.shutdownCallback = nil
+	return nil
+}
+
+// ErrShutdownInhibitDisabled is returned when a PrepareForShutdown signal is
+// faked while no shutdown callback is registered, i.e. the hypervisor is not
+// configured to evacuate on reboot.
+var ErrShutdownInhibitDisabled = errors.New("shutdown inhibition is not enabled")
+
+// Run the registered shutdown callback as if logind sent a PrepareForShutdown
+// signal. Unlike a real shutdown, the inhibition lock is kept, since the
+// node is not going down.
+func (s *Systemd) PrepareForShutdown(ctx context.Context) error {
+	s.mu.Lock()
+	cb := s.shutdownCallback
+	s.mu.Unlock()
+	if cb == nil {
+		return ErrShutdownInhibitDisabled
+	}
+	logger.FromContext(ctx).Info("faking PrepareForShutdown signal")
+	return cb(ctx)
+}
@@ -0,0 +1,141 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package chaos
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/coreos/go-systemd/v22/dbus"
+
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/systemd"
+)
+
+type mockDisconnecter struct {
+	closed int
+}
+
+func (m *mockDisconnecter) Close() error {
+	m.closed++
+	return nil
+}
+
+func newTestServer() (*Server, *mockDisconnecter) {
+	inner := &systemd.InterfaceMock{
+		ListUnitsByNamesFunc: func(_ context.Context, units []string) ([]dbus.UnitStatus, error) {
+			var statuses []dbus.UnitStatus
+			for _, unit := range units {
+				statuses = append(statuses, dbus.UnitStatus{Name: unit, ActiveState: systemd.ACTIVE})
+			}
+			return statuses, nil
+		},
+		EnableShutdownInhibitFunc:  func(context.Context, func(context.Context) error) error { return nil },
+		DisableShutdownInhibitFunc: func() error { return nil },
+	}
+	libvirt := &mockDisconnecter{}
+	return &Server{Libvirt: libvirt, Systemd: NewSystemd(inner)}, libvirt
+}
+
+func do(t *testing.T, handler http.Handler, method, path string) int {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(method, path, http.NoBody))
+	return rec.Code
+}
+
+func TestDisconnectLibvirt(t *testing.T) {
+	server, libvirt := newTestServer()
+	if code := do(t, server.Handler(), http.MethodPost, "/debug/libvirt/disconnect"); code != http.StatusNoContent {
+		t.Errorf("Expected status %d, got %d", http.StatusNoContent, code)
+	}
+	if libvirt.closed != 1 {
+		t.Errorf("Expected libvirt to be closed once, got %d", libvirt.closed)
+	}
+	if code := do(t, server.Handler(), http.MethodGet, "/debug/libvirt/disconnect"); code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status %d, got %d", http.StatusMethodNotAllowed, code)
+	}
+}
+
+func TestUnitFailure(t *testing.T) {
+	server, _ := newTestServer()
+	units := []string{"libvirtd.service", "openvswitch-switch.service"}
+	states := func() map[string]string {
+		statuses, err := server.Systemd.ListUnitsByNames(context.Background(), units)
+		if err != nil {
+			t.Fatalf("Failed to list units: %v", err)
+		}
+		result := make(map[string]string)
+		for _, status := range statuses {
+			result[status.Name] = status.ActiveState
+		}
+		return result
+	}
+
+	path := "/debug/systemd/units/libvirtd.service/failure"
+	if code := do(t, server.Handler(), http.MethodPost, path); code != http.StatusNoContent {
+		t.Errorf("Expected status %d, got %d", http.StatusNoContent, code)
+	}
+	got := states()
+	if got["libvirtd.service"] != systemd.FAILED {
+		t.Errorf("Expected libvirtd.service to be failed, got %s", got["libvirtd.service"])
+	}
+	if got["openvswitch-switch.service"] != systemd.ACTIVE {
+		t.Errorf("Expected openvswitch-switch.service to be active, got %s", got["openvswitch-switch.service"])
+	}
+
+	if code := do(t, server.Handler(), http.MethodDelete, path); code != http.StatusNoContent {
+		t.Errorf("Expected status %d, got %d", http.StatusNoContent, code)
+	}
+	if got := states(); got["libvirtd.service"] != systemd.ACTIVE {
+		t.Errorf("Expected libvirtd.service to be active again, got %s", got["libvirtd.service"])
+	}
+}
+
+func TestPrepareForShutdown(t *testing.T) {
+	server, _ := newTestServer()
+	path := "/debug/systemd/prepare-for-shutdown"
+
+	// Without shutdown inhibition there is nothing to trigger.
+	if code := do(t, server.Handler(), http.MethodPost, path); code != http.StatusConflict {
+		t.Errorf("Expected status %d, got %d", http.StatusConflict, code)
+	}
+
+	evicted := 0
+	evict := func(context.Context) error {
+		evicted++
+		return nil
+	}
+	if err := server.Systemd.EnableShutdownInhibit(context.Background(), evict); err != nil {
+		t.Fatalf("Failed to enable shutdown inhibit: %v", err)
+	}
+	if code := do(t, server.Handler(), http.MethodPost, path); code != http.StatusNoContent {
+		t.Errorf("Expected status %d, got %d", http.StatusNoContent, code)
+	}
+	if evicted != 1 {
+		t.Errorf("Expected shutdown callback to be called once, got %d", evicted)
+	}
+
+	if err := server.Systemd.DisableShutdownInhibit(); err != nil {
+		t.Fatalf("Failed to disable shutdown inhibit: %v", err)
+	}
+	if code := do(t, server.Handler(), http.MethodPost, path); code != http.StatusConflict {
+		t.Errorf("Expected status %d, got %d", http.StatusConflict, code)
+	}
+}
@@ -0,0 +1,123 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package chaos
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+
+	logger "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// Disconnecter closes the connection to libvirt. Like a real connection
+// loss, this fails the libvirt event loop, which can't be restarted without
+// a connection, so the manager stops and the pod is restarted by the kubelet.
+type Disconnecter interface {
+	Close() error
+}
+
+// Server serves the debug API to inject faults:
+//
+//	POST   /debug/libvirt/disconnect              close the libvirt connection, restarting the agent
+//	POST   /debug/systemd/units/{unit}/failure    report the unit as failed
+//	DELETE /debug/systemd/units/{unit}/failure    clear the injected failure
+//	POST   /debug/systemd/prepare-for-shutdown    fake a PrepareForShutdown signal
+//
+// The API is not authenticated, so it should only listen on localhost.
+type Server struct {
+	// Address the server listens on, e.g. "127.0.0.1:8082".
+	BindAddress string
+	// Libvirt connection to disconnect.
+	Libvirt Disconnecter
+	// Systemd wrapper to inject the unit failures and shutdown signals into.
+	Systemd *Systemd
+}
+
+// Handler returns the handler of the debug API.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /debug/libvirt/disconnect", s.disconnectLibvirt)
+	mux.HandleFunc("POST /debug/systemd/units/{unit}/failure", s.failUnit)
+	mux.HandleFunc("DELETE /debug/systemd/units/{unit}/failure", s.clearUnit)
+	mux.HandleFunc("POST /debug/systemd/prepare-for-shutdown", s.prepareForShutdown)
+	return mux
+}
+
+// Start the server and block until the context is cancelled.
+func (s *Server) Start(ctx context.Context) error {
+	log := logger.FromContext(ctx).WithName("chaos")
+	srv := &http.Server{
+		Addr:              s.BindAddress,
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+		BaseContext:       func(_ net.Listener) context.Context { return ctx },
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			log.Error(err, "failed to shut down debug api")
+		}
+	}()
+
+	log.Info("serving debug api, faults can be injected", "address", s.BindAddress)
+	if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+func (s *Server) disconnectLibvirt(w http.ResponseWriter, r *http.Request) {
+	logger.FromContext(r.Context()).Info("injecting libvirt disconnect")
+	if err := s.Libvirt.Close(); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) failUnit(w http.ResponseWriter, r *http.Request) {
+	unit := r.PathValue("unit")
+	logger.FromContext(r.Context()).Info("injecting unit failure", "unit", unit)
+	s.Systemd.FailUnit(unit)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) clearUnit(w http.ResponseWriter, r *http.Request) {
+	unit := r.PathValue("unit")
+	logger.FromContext(r.Context()).Info("clearing injected unit failure", "unit", unit)
+	s.Systemd.ClearUnit(unit)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) prepareForShutdown(w http.ResponseWriter, r *http.Request) {
+	if err := s.Systemd.PrepareForShutdown(r.Context()); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, ErrShutdownInhibitDisabled) {
+			status = http.StatusConflict
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
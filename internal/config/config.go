@@ -0,0 +1,284 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package config loads the configuration of the agent from a yaml file,
+// with the environment variables of the daemonset taking precedence, and
+// validates it at startup.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+
+	"sigs.k8s.io/yaml"
+
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/sys"
+)
+
+// Config of the agent.
+type Config struct {
+	// Namespace of the objects of the agent, NAMESPACE.
+	Namespace string `json:"namespace"`
+	// Name of the node and the hypervisor, HOSTNAME.
+	Hostname string `json:"hostname"`
+	// Name of the node in the labels of the agent's objects, e.g. the
+	// migrations, NODE_LABEL. Defaults to the hostname.
+	NodeLabel string `json:"nodeLabel"`
+	// Whether libvirt and systemd are emulated for development, EMULATE.
+	Emulate bool `json:"emulate"`
+
+	Libvirt Libvirt `json:"libvirt"`
+	PKI     PKI     `json:"pki"`
+	Vault   Vault   `json:"vault"`
+	Host    Host    `json:"host"`
+}
+
+// Libvirt connection of the agent.
+type Libvirt struct {
+	// Unix socket of libvirt, LIBVIRT_SOCKET.
+	Socket string `json:"socket"`
+	// Uri connected to without --libvirt-uris, LIBVIRT_DEFAULT_URI.
+	DefaultURI string `json:"defaultURI"`
+}
+
+// PKI of the libvirt TLS certificate.
+type PKI struct {
+	// Directory the certificate is installed into, PKI_PATH.
+	Path string `json:"path"`
+	// Owner of the installed files as numeric uid:gid, e.g. of the qemu
+	// user of the host, PKI_OWNER. The files are owned by the agent if
+	// empty.
+	Owner string `json:"owner"`
+	// Issuer of the cert-manager certificate, ISSUER_NAME.
+	IssuerName string `json:"issuerName"`
+	// Address of the host in the certificate if the hostname doesn't
+	// resolve, HOST_IP_ADDRESS.
+	HostIPAddress string `json:"hostIPAddress"`
+	// Additional pki directories provisioned with the client certificate,
+	// e.g. a host path mounted into the nova-compute pod.
+	ClientTargets []ClientTarget `json:"clientTargets,omitempty"`
+}
+
+// ClientTarget is a pki directory the client certificate and key are
+// installed into with the libvirt layout, i.e. CA/cacert.pem,
+// libvirt/clientcert.pem and libvirt/private/clientkey.pem.
+type ClientTarget struct {
+	// Directory the client certificate is installed into.
+	Path string `json:"path"`
+	// Owner of the installed files as numeric uid:gid, the owner of the
+	// pki if empty.
+	Owner string `json:"owner,omitempty"`
+	// Octal permissions of the client key, 0640 if empty.
+	KeyMode string `json:"keyMode,omitempty"`
+}
+
+// Vault issuing the certificate with the vault certificate provider.
+type Vault struct {
+	// Address of vault, VAULT_ADDR.
+	Address string `json:"address"`
+	// Token of the agent, VAULT_TOKEN. Redacted in dumps.
+	Token string `json:"token"`
+}
+
+// Host preparation at startup, ensuring the paths libvirt and its clients
+// rely on exist with the expected ownership and permissions.
+type Host struct {
+	// Mount point of the root file system of the host, HOST_ROOT. The host
+	// isn't prepared if empty.
+	Root string `json:"root"`
+	// Paths prepared on the host, the defaults of the hostprep package if
+	// empty.
+	Paths []HostPath `json:"paths,omitempty"`
+}
+
+// Types of a prepared host path.
+const (
+	// Directory created if missing.
+	HostPathDirectory = "Directory"
+	// Socket of a daemon, e.g. of libvirt, only adjusted if it exists.
+	HostPathSocket = "Socket"
+)
+
+// HostPath is a path on the host with its ownership and permissions.
+type HostPath struct {
+	// Absolute path on the host.
+	Path string `json:"path"`
+	// Type of the path, Directory if empty.
+	Type string `json:"type,omitempty"`
+	// Owner as numeric uid:gid, left alone if empty.
+	Owner string `json:"owner,omitempty"`
+	// Octal permissions, e.g. 0755, left alone if empty.
+	Mode string `json:"mode,omitempty"`
+}
+
+// ParseMode parses octal file permissions, e.g. 0640.
+func ParseMode(mode string) (os.FileMode, error) {
+	perm, err := strconv.ParseUint(mode, 8, 32)
+	if err != nil || perm&^0777 != 0 {
+		return 0, fmt.Errorf("%q is not an octal permission, e.g. 0640", mode)
+	}
+	return os.FileMode(perm), nil
+}
+
+const redacted = "<redacted>"
+
+var ownerPattern = regexp.MustCompile(`^[0-9]+:[0-9]+$`)
+
+// Environment variables overriding the configuration file.
+var envOverrides = []struct {
+	name  string
+	value func(*Config) *string
+}{
+	{"NAMESPACE", func(c *Config) *string { return &c.Namespace }},
+	{"HOSTNAME", func(c *Config) *string { return &c.Hostname }},
+	{"NODE_LABEL", func(c *Config) *string { return &c.NodeLabel }},
+	{"LIBVIRT_SOCKET", func(c *Config) *string { return &c.Libvirt.Socket }},
+	{"LIBVIRT_DEFAULT_URI", func(c *Config) *string { return &c.Libvirt.DefaultURI }},
+	{"PKI_PATH", func(c *Config) *string { return &c.PKI.Path }},
+	{"PKI_OWNER", func(c *Config) *string { return &c.PKI.Owner }},
+	{"ISSUER_NAME", func(c *Config) *string { return &c.PKI.IssuerName }},
+	{"HOST_IP_ADDRESS", func(c *Config) *string { return &c.PKI.HostIPAddress }},
+	{"VAULT_ADDR", func(c *Config) *string { return &c.Vault.Address }},
+	{"VAULT_TOKEN", func(c *Config) *string { return &c.Vault.Token }},
+	{"HOST_ROOT", func(c *Config) *string { return &c.Host.Root }},
+}
+
+// Default returns the configuration used without file and environment.
+func Default() *Config {
+	return &Config{
+		Namespace: "monsoon3",
+		Hostname:  sys.Hostname,
+		Libvirt: Libvirt{
+			Socket:     "/run/libvirt/libvirt-sock",
+			DefaultURI: "ch:///system",
+		},
+	}
+}
+
+// Load the configuration from the yaml file, if the path is not empty, and
+// the environment, and validate it.
+func Load(path string) (*Config, error) {
+	return load(path, os.LookupEnv)
+}
+
+func load(path string, lookupEnv func(string) (string, bool)) (*Config, error) {
+	cfg := Default()
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		// Unknown keys are rejected, a typo must not silently fall back
+		// to the default.
+		if err := yaml.UnmarshalStrict(data, cfg); err != nil {
+			return nil, fmt.Errorf("invalid config %s: %w", path, err)
+		}
+	}
+	for _, env := range envOverrides {
+		if value, ok := lookupEnv(env.name); ok && value != "" {
+			*env.value(cfg) = value
+		}
+	}
+	if value, ok := lookupEnv("EMULATE"); ok && value != "" {
+		cfg.Emulate = true
+	}
+	if cfg.NodeLabel == "" {
+		cfg.NodeLabel = cfg.Hostname
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// Validate the configuration, returns all problems at once.
+func (c *Config) Validate() error {
+	var errs []error
+	if c.Namespace == "" {
+		errs = append(errs, errors.New("namespace is empty"))
+	}
+	if c.Hostname == "" {
+		errs = append(errs, errors.New("hostname is empty"))
+	}
+	if !filepath.IsAbs(c.Libvirt.Socket) {
+		errs = append(errs, fmt.Errorf("libvirt socket %q is not an absolute path", c.Libvirt.Socket))
+	}
+	if uri, err := url.Parse(c.Libvirt.DefaultURI); err != nil || uri.Scheme == "" {
+		errs = append(errs, fmt.Errorf("libvirt default uri %q is not an uri, e.g. qemu:///system", c.Libvirt.DefaultURI))
+	}
+	if c.PKI.Owner != "" && !ownerPattern.MatchString(c.PKI.Owner) {
+		errs = append(errs, fmt.Errorf("pki owner %q is not a numeric uid:gid", c.PKI.Owner))
+	}
+	for _, target := range c.PKI.ClientTargets {
+		if !filepath.IsAbs(target.Path) {
+			errs = append(errs, fmt.Errorf("pki client target %q is not an absolute path", target.Path))
+		}
+		if target.Owner != "" && !ownerPattern.MatchString(target.Owner) {
+			errs = append(errs, fmt.Errorf("pki client target owner %q is not a numeric uid:gid", target.Owner))
+		}
+		if target.KeyMode != "" {
+			if _, err := ParseMode(target.KeyMode); err != nil {
+				errs = append(errs, fmt.Errorf("pki client target key mode %w", err))
+			}
+		}
+	}
+	if c.PKI.HostIPAddress != "" && net.ParseIP(c.PKI.HostIPAddress) == nil {
+		errs = append(errs, fmt.Errorf("host ip address %q is not an ip address", c.PKI.HostIPAddress))
+	}
+	if c.Host.Root != "" && !filepath.IsAbs(c.Host.Root) {
+		errs = append(errs, fmt.Errorf("host root %q is not an absolute path", c.Host.Root))
+	}
+	for _, path := range c.Host.Paths {
+		if !filepath.IsAbs(path.Path) {
+			errs = append(errs, fmt.Errorf("host path %q is not an absolute path", path.Path))
+		}
+		if path.Type != "" && path.Type != HostPathDirectory && path.Type != HostPathSocket {
+			errs = append(errs, fmt.Errorf("host path %s has type %q, expected %s or %s",
+				path.Path, path.Type, HostPathDirectory, HostPathSocket))
+		}
+		if path.Owner != "" && !ownerPattern.MatchString(path.Owner) {
+			errs = append(errs, fmt.Errorf("host path %s owner %q is not a numeric uid:gid", path.Path, path.Owner))
+		}
+		if path.Mode != "" {
+			if _, err := ParseMode(path.Mode); err != nil {
+				errs = append(errs, fmt.Errorf("host path %s mode %w", path.Path, err))
+			}
+		}
+	}
+	if c.Vault.Address != "" {
+		if address, err := url.Parse(c.Vault.Address); err != nil || address.Scheme == "" || address.Host == "" {
+			errs = append(errs, fmt.Errorf("vault address %q is not an url", c.Vault.Address))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Redacted returns a copy of the configuration without secrets, e.g. to
+// dump it for debugging.
+func (c *Config) Redacted() *Config {
+	redactedConfig := *c
+	if redactedConfig.Vault.Token != "" {
+		redactedConfig.Vault.Token = redacted
+	}
+	return &redactedConfig
+}
@@ -0,0 +1,138 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func lookup(env map[string]string) func(string) (string, bool) {
+	return func(name string) (string, bool) {
+		value, ok := env[name]
+		return value, ok
+	}
+}
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadDefaults(t *testing.T) {
+	cfg, err := load("", lookup(map[string]string{"HOSTNAME": "node-1"}))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if cfg.Namespace != "monsoon3" || cfg.Libvirt.Socket != "/run/libvirt/libvirt-sock" ||
+		cfg.Libvirt.DefaultURI != "ch:///system" {
+		t.Errorf("Unexpected defaults %+v", cfg)
+	}
+	if cfg.NodeLabel != "node-1" {
+		t.Errorf("Expected the node label to default to the hostname, got %q", cfg.NodeLabel)
+	}
+	if cfg.Emulate {
+		t.Error("Expected no emulation")
+	}
+}
+
+func TestLoadFileWithEnvOverrides(t *testing.T) {
+	path := writeConfig(t, `
+namespace: kvm
+hostname: node-1
+libvirt:
+  defaultURI: qemu:///system
+pki:
+  path: /pki
+  owner: "107:107"
+vault:
+  address: https://vault:8200
+`)
+	cfg, err := load(path, lookup(map[string]string{
+		"PKI_PATH":    "/etc/pki",
+		"VAULT_TOKEN": "token",
+		"NODE_LABEL":  "",
+		"EMULATE":     "1",
+	}))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if cfg.Namespace != "kvm" || cfg.Libvirt.DefaultURI != "qemu:///system" || cfg.PKI.Owner != "107:107" {
+		t.Errorf("Expected the values of the file, got %+v", cfg)
+	}
+	if cfg.PKI.Path != "/etc/pki" || cfg.Vault.Token != "token" {
+		t.Errorf("Expected the environment to take precedence, got %+v", cfg)
+	}
+	if cfg.NodeLabel != "node-1" {
+		t.Errorf("Expected empty variables to be ignored, got node label %q", cfg.NodeLabel)
+	}
+	if !cfg.Emulate {
+		t.Error("Expected emulation")
+	}
+}
+
+func TestLoadRejectsUnknownKeys(t *testing.T) {
+	path := writeConfig(t, "hostname: node-1\npki:\n  paht: /pki\n")
+	if _, err := load(path, lookup(nil)); err == nil || !strings.Contains(err.Error(), "paht") {
+		t.Errorf("Expected the unknown key to be rejected, got %v", err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	cfg := Default()
+	cfg.Hostname = "node-1"
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Expected the defaults to be valid, got %v", err)
+	}
+
+	cfg = &Config{
+		Libvirt: Libvirt{Socket: "libvirt-sock", DefaultURI: "system"},
+		PKI: PKI{Owner: "qemu", HostIPAddress: "host", ClientTargets: []ClientTarget{
+			{Path: "nova", Owner: "nova", KeyMode: "rw"},
+		}},
+		Vault: Vault{Address: "vault"},
+		Host:  Host{Root: "host", Paths: []HostPath{{Path: "run", Type: "File", Owner: "root", Mode: "0888"}}},
+	}
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("Expected an error")
+	}
+	for _, problem := range []string{"namespace", "hostname", "socket", "default uri", "owner", "ip address", "vault", "client target", "key mode",
+		"host root", "host path", "type", "mode"} {
+		if !strings.Contains(err.Error(), problem) {
+			t.Errorf("Expected %q to be reported, got %v", problem, err)
+		}
+	}
+}
+
+func TestRedacted(t *testing.T) {
+	cfg := Default()
+	cfg.Vault.Token = "token"
+	if got := cfg.Redacted().Vault.Token; got != redacted {
+		t.Errorf("Expected the token to be redacted, got %q", got)
+	}
+	if cfg.Vault.Token != "token" {
+		t.Error("Expected the configuration to be left alone")
+	}
+}
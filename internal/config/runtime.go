@@ -0,0 +1,232 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/logging"
+)
+
+// Key of the runtime configuration in the data of the ConfigMap.
+const RuntimeKey = "config.yaml"
+
+// Lower bound of the stats interval, so that a typo doesn't flood the
+// api server with status patches.
+const minStatsInterval = 10 * time.Second
+
+// Runtime configuration of the agent, which is safe to change without a
+// restart and reloaded from the ConfigMap of the agent as it changes.
+type Runtime struct {
+	// Interval in which the status of the hypervisor, e.g. the domain
+	// stats, is refreshed without events. Defaults to 1 minute.
+	StatsInterval metav1.Duration `json:"statsInterval"`
+	// Systemd units reported as conditions in addition to --watch-units.
+	// Changes of the units are only noticed on refresh, not as they
+	// happen.
+	Units []string `json:"units"`
+	// Level of the log, either debug, info, error or a verbosity like 2.
+	// The level of the flags is kept if empty.
+	LogLevel string `json:"logLevel"`
+	// Levels of the log of the subsystems, e.g. {migration: debug},
+	// overriding LogLevel for their loggers. See logging.Subsystems.
+	LogLevels map[string]string `json:"logLevels"`
+	// Evacuation policy used unless overridden by the annotations of the
+	// hypervisor.
+	Evacuation Evacuation `json:"evacuation"`
+}
+
+// Evacuation policy of the host on shutdown, see the kvm.cloud.sap/evacuation-*
+// annotations of the hypervisor for the values.
+type Evacuation struct {
+	Strategy    string          `json:"strategy"`
+	Timeout     metav1.Duration `json:"timeout"`
+	Order       string          `json:"order"`
+	Parallelism int             `json:"parallelism"`
+}
+
+// DefaultRuntime returns the runtime configuration used without ConfigMap.
+func DefaultRuntime() Runtime {
+	return Runtime{StatsInterval: metav1.Duration{Duration: time.Minute}}
+}
+
+// ParseRuntime parses and validates the runtime configuration from the
+// data of the ConfigMap. Returns the configuration and its generation, a
+// hash of the data which is the same on all hosts reading it.
+func ParseRuntime(data string) (Runtime, string, error) {
+	runtime := DefaultRuntime()
+	if err := yaml.UnmarshalStrict([]byte(data), &runtime); err != nil {
+		return Runtime{}, "", fmt.Errorf("invalid runtime config: %w", err)
+	}
+	if err := runtime.Validate(); err != nil {
+		return Runtime{}, "", err
+	}
+	sum := sha256.Sum256([]byte(data))
+	return runtime, hex.EncodeToString(sum[:])[:12], nil
+}
+
+// Validate the runtime configuration, returns all problems at once. The
+// evacuation policy is validated by the evacuation package.
+func (r *Runtime) Validate() error {
+	var errs []error
+	if r.StatsInterval.Duration < minStatsInterval {
+		errs = append(errs, fmt.Errorf("stats interval %s is below %s", r.StatsInterval.Duration, minStatsInterval))
+	}
+	for _, unit := range r.Units {
+		if unit == "" {
+			errs = append(errs, errors.New("units contain an empty name"))
+		}
+	}
+	if r.LogLevel != "" {
+		if _, err := ParseLogLevel(r.LogLevel); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	for subsystem, level := range r.LogLevels {
+		if !slices.Contains(logging.Subsystems, subsystem) {
+			errs = append(errs, fmt.Errorf("unknown log subsystem %q, expected one of %s",
+				subsystem, strings.Join(logging.Subsystems, ", ")))
+		}
+		if _, err := ParseLogLevel(level); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", subsystem, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ParseLogLevel parses the level like the --zap-log-level flag, either a
+// name or a verbosity which is logged by V(verbosity).
+func ParseLogLevel(value string) (zapcore.Level, error) {
+	if verbosity, err := strconv.Atoi(value); err == nil {
+		if verbosity < 0 {
+			return 0, fmt.Errorf("log level %q is negative", value)
+		}
+		return zapcore.Level(-verbosity), nil
+	}
+	level, err := zapcore.ParseLevel(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid log level %q, expected debug, info, error or a verbosity", value)
+	}
+	return level, nil
+}
+
+// RuntimeStore holds the active runtime configuration, which is read by
+// the reconcilers on each use. A nil store returns the default.
+type RuntimeStore struct {
+	mu          sync.RWMutex
+	runtime     Runtime
+	generation  string
+	rejected    error
+	subscribers []func(Runtime)
+}
+
+// NewRuntimeStore returns a store holding the default runtime configuration.
+func NewRuntimeStore() *RuntimeStore {
+	return &RuntimeStore{runtime: DefaultRuntime()}
+}
+
+// Get the active runtime configuration.
+func (s *RuntimeStore) Get() Runtime {
+	if s == nil {
+		return DefaultRuntime()
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.runtime
+}
+
+// Generation of the active runtime configuration, empty if the default
+// is active.
+func (s *RuntimeStore) Generation() string {
+	if s == nil {
+		return ""
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.generation
+}
+
+// Rejected returns why the last configuration was rejected, nil if the
+// active configuration is the last one.
+func (s *RuntimeStore) Rejected() error {
+	if s == nil {
+		return nil
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.rejected
+}
+
+// Set the active runtime configuration and notify the subscribers if the
+// generation changed.
+func (s *RuntimeStore) Set(runtime Runtime, generation string) {
+	s.mu.Lock()
+	changed := s.generation != generation || s.rejected != nil
+	s.runtime = runtime
+	s.generation = generation
+	s.rejected = nil
+	s.mu.Unlock()
+	if changed {
+		s.notify()
+	}
+}
+
+// Reject an invalid configuration, keeping the active one, and notify the
+// subscribers.
+func (s *RuntimeStore) Reject(err error) {
+	s.mu.Lock()
+	changed := s.rejected == nil || s.rejected.Error() != err.Error()
+	s.rejected = err
+	s.mu.Unlock()
+	if changed {
+		s.notify()
+	}
+}
+
+func (s *RuntimeStore) notify() {
+	s.mu.RLock()
+	runtime := s.runtime
+	subscribers := s.subscribers
+	s.mu.RUnlock()
+	for _, subscriber := range subscribers {
+		subscriber(runtime)
+	}
+}
+
+// Subscribe to changes of the runtime configuration. The subscriber is
+// called synchronously and must not block.
+func (s *RuntimeStore) Subscribe(subscriber func(Runtime)) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.subscribers = append(s.subscribers, subscriber)
+}
@@ -0,0 +1,124 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+func TestParseRuntime(t *testing.T) {
+	runtime, generation, err := ParseRuntime(`
+statsInterval: 30s
+units: [multipathd.service]
+logLevel: "2"
+logLevels:
+  migration: debug
+evacuation:
+  strategy: shutdown
+  parallelism: 2
+`)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if runtime.StatsInterval.Duration != 30*time.Second || len(runtime.Units) != 1 ||
+		runtime.Evacuation.Strategy != "shutdown" || runtime.Evacuation.Parallelism != 2 ||
+		runtime.LogLevels["migration"] != "debug" {
+		t.Errorf("Unexpected runtime config %+v", runtime)
+	}
+	if len(generation) != 12 {
+		t.Errorf("Expected a short hash as generation, got %q", generation)
+	}
+	if _, again, _ := ParseRuntime("statsInterval: 30s"); again == generation {
+		t.Error("Expected the generation to change with the data")
+	}
+}
+
+func TestParseRuntimeDefaults(t *testing.T) {
+	runtime, _, err := ParseRuntime("")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if runtime.StatsInterval.Duration != time.Minute {
+		t.Errorf("Expected the default stats interval, got %s", runtime.StatsInterval.Duration)
+	}
+}
+
+func TestParseRuntimeInvalid(t *testing.T) {
+	for _, data := range []string{
+		"statsInterval: 1s",
+		"logLevel: verbose",
+		"units: ['']",
+		"logLevels: {network: debug}",
+		"logLevels: {libvirt: loud}",
+		"statInterval: 1m",
+	} {
+		if _, _, err := ParseRuntime(data); err == nil {
+			t.Errorf("Expected an error for %q", data)
+		}
+	}
+}
+
+func TestParseLogLevel(t *testing.T) {
+	for value, expected := range map[string]zapcore.Level{
+		"debug": zapcore.DebugLevel,
+		"error": zapcore.ErrorLevel,
+		"3":     zapcore.Level(-3),
+	} {
+		level, err := ParseLogLevel(value)
+		if err != nil || level != expected {
+			t.Errorf("Expected %s for %q, got %s, %v", expected, value, level, err)
+		}
+	}
+	if _, err := ParseLogLevel("-1"); err == nil {
+		t.Error("Expected an error for a negative verbosity")
+	}
+}
+
+func TestRuntimeStore(t *testing.T) {
+	var nilStore *RuntimeStore
+	if nilStore.Get().StatsInterval.Duration != time.Minute || nilStore.Generation() != "" {
+		t.Error("Expected the default from a nil store")
+	}
+
+	store := NewRuntimeStore()
+	notified := 0
+	store.Subscribe(func(Runtime) { notified++ })
+	runtime := DefaultRuntime()
+	runtime.LogLevel = "debug"
+	store.Set(runtime, "abc")
+	store.Set(runtime, "abc")
+	if notified != 1 {
+		t.Errorf("Expected one notification, got %d", notified)
+	}
+	if store.Get().LogLevel != "debug" || store.Generation() != "abc" {
+		t.Errorf("Unexpected store content %+v %q", store.Get(), store.Generation())
+	}
+
+	store.Reject(errors.New("invalid"))
+	if store.Rejected() == nil || store.Generation() != "abc" || notified != 2 {
+		t.Errorf("Expected the rejection to keep the active config, got %v %q", store.Rejected(), store.Generation())
+	}
+	store.Set(runtime, "abc")
+	if store.Rejected() != nil || notified != 3 {
+		t.Errorf("Expected the rejection to be cleared, got %v", store.Rejected())
+	}
+}
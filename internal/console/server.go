@@ -0,0 +1,221 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package console provides break-glass access to the serial console of
+// domains. Consoles are requested through Console resources and served
+// read-only over mTLS-authenticated WebSockets.
+package console
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"golang.org/x/net/websocket"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logger "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/cobaltcore-dev/kvm-node-agent/api/v1alpha1"
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/sys"
+)
+
+// Path under which the consoles are served, followed by the name of the
+// Console resource.
+const PathPrefix = "/consoles/"
+
+// Opener opens the serial console of a domain and streams its output.
+type Opener interface {
+	OpenConsole(ctx context.Context, uuid string, w io.Writer) error
+}
+
+// Server serves the consoles requested for domains on this host.
+//
+// Clients have to present a certificate issued by the libvirt CA, whose
+// common name or one of its dns names is in AllowedClients. The CA issues
+// the certificates of all hypervisors as well, which must not be able to
+// read the consoles of each other. The server certificate and the CA are
+// reloaded on every handshake, so certificate rotations are picked up
+// without a restart.
+type Server struct {
+	// Address the server listens on, e.g. ":9443".
+	BindAddress string
+	// Reader to look up the Console resources.
+	Reader client.Reader
+	// Opener used to attach to the domain consoles.
+	Opener Opener
+	// Paths of the CA certificate and the server key pair.
+	CAFile   string
+	CertFile string
+	KeyFile  string
+	// Common or dns names of the client certificates allowed to read the
+	// consoles. No client is allowed if empty.
+	AllowedClients []string
+}
+
+// URL under which the console with the given name is served.
+func (s *Server) URL(name string) string {
+	_, port, err := net.SplitHostPort(s.BindAddress)
+	if err != nil {
+		return ""
+	}
+	return "wss://" + net.JoinHostPort(sys.Hostname, port) + PathPrefix + name
+}
+
+// Start the server and block until the context is cancelled.
+func (s *Server) Start(ctx context.Context) error {
+	log := logger.FromContext(ctx).WithName("console")
+	mux := http.NewServeMux()
+	mux.Handle(PathPrefix, s)
+	srv := &http.Server{
+		Addr:              s.BindAddress,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+		TLSConfig: &tls.Config{
+			MinVersion:         tls.VersionTLS12,
+			GetConfigForClient: s.tlsConfig,
+		},
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			log.Error(err, "failed to shut down console server")
+		}
+	}()
+
+	log.Info("serving consoles", "address", s.BindAddress)
+	if err := srv.ListenAndServeTLS("", ""); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// Load the current server certificate and CA for a new connection.
+func (s *Server) tlsConfig(_ *tls.ClientHelloInfo) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(s.CertFile, s.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load server certificate: %w", err)
+	}
+	caPEM, err := os.ReadFile(s.CAFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA certificate: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caPEM) {
+		return nil, fmt.Errorf("no CA certificate found in %s", s.CAFile)
+	}
+	return &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{cert},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    pool,
+		// Called after the chain was verified against the CA.
+		VerifyPeerCertificate: s.authorizeClient,
+	}, nil
+}
+
+// Reject client certificates which aren't in the allowed clients.
+func (s *Server) authorizeClient(_ [][]byte, verifiedChains [][]*x509.Certificate) error {
+	if len(verifiedChains) == 0 || len(verifiedChains[0]) == 0 {
+		return errors.New("no verified client certificate")
+	}
+	leaf := verifiedChains[0][0]
+	for _, name := range append([]string{leaf.Subject.CommonName}, leaf.DNSNames...) {
+		if name != "" && slices.Contains(s.AllowedClients, name) {
+			return nil
+		}
+	}
+	return fmt.Errorf("client %q is not allowed to read consoles", leaf.Subject.CommonName)
+}
+
+// Validate the requested console and attach the WebSocket to it.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context()).WithName("console")
+	name := strings.TrimPrefix(r.URL.Path, PathPrefix)
+	if name == "" || strings.Contains(name, "/") {
+		http.NotFound(w, r)
+		return
+	}
+
+	var console v1alpha1.Console
+	key := client.ObjectKey{Name: name, Namespace: sys.Namespace}
+	if err := s.Reader.Get(r.Context(), key, &console); err != nil {
+		if client.IgnoreNotFound(err) != nil {
+			log.Error(err, "failed to get console", "console", name)
+			http.Error(w, "failed to get console", http.StatusInternalServerError)
+			return
+		}
+		http.NotFound(w, r)
+		return
+	}
+	if console.Spec.Hypervisor != sys.NodeLabelName {
+		http.NotFound(w, r)
+		return
+	}
+	expiresAt := console.Spec.ExpiresAt.Time
+	if !time.Now().Before(expiresAt) {
+		http.Error(w, "console access expired", http.StatusGone)
+		return
+	}
+
+	var clientName string
+	if r.TLS != nil && len(r.TLS.PeerCertificates) > 0 {
+		clientName = r.TLS.PeerCertificates[0].Subject.CommonName
+	}
+	consoleLog := log.WithValues("console", name, "server", console.Spec.Domain, "client", clientName)
+
+	wsServer := websocket.Server{
+		// Clients authenticate with their certificate, so there is no need
+		// to restrict the origin (which cli clients do not send anyway).
+		Handshake: func(*websocket.Config, *http.Request) error { return nil },
+		Handler: func(ws *websocket.Conn) {
+			defer ws.Close()
+			ws.PayloadType = websocket.BinaryFrame
+
+			// Revoke the access once the console expires, which aborts
+			// the console stream.
+			ctx, cancel := context.WithDeadline(context.Background(), expiresAt)
+			defer cancel()
+
+			// The console is read-only, discard client input until the
+			// client disconnects, which also ends the console stream.
+			go func() {
+				_, _ = io.Copy(io.Discard, ws)
+				cancel()
+			}()
+
+			consoleLog.Info("console session started")
+			if err := s.Opener.OpenConsole(ctx, console.Spec.Domain, ws); err != nil {
+				consoleLog.Error(err, "console session failed")
+				return
+			}
+			consoleLog.Info("console session ended")
+		},
+	}
+	wsServer.ServeHTTP(w, r)
+}
@@ -0,0 +1,285 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package console
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/websocket"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/cobaltcore-dev/kvm-node-agent/api/v1alpha1"
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/sys"
+)
+
+type mockOpener struct {
+	opened []string
+}
+
+func (m *mockOpener) OpenConsole(_ context.Context, uuid string, w io.Writer) error {
+	m.opened = append(m.opened, uuid)
+	return nil
+}
+
+func newTestServer(t *testing.T, consoles ...*v1alpha1.Console) (*Server, *mockOpener) {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	if err := v1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatalf("Failed to build scheme: %v", err)
+	}
+	builder := fake.NewClientBuilder().WithScheme(scheme)
+	for _, c := range consoles {
+		builder = builder.WithObjects(c)
+	}
+	opener := &mockOpener{}
+	return &Server{BindAddress: ":9443", Reader: builder.Build(), Opener: opener}, opener
+}
+
+// blockingOpener streams until the session is ended by the server.
+type blockingOpener struct {
+	ended chan error
+}
+
+func (b *blockingOpener) OpenConsole(ctx context.Context, _ string, _ io.Writer) error {
+	<-ctx.Done()
+	b.ended <- ctx.Err()
+	return nil
+}
+
+func newConsole(name, hypervisor string, expiresAt time.Time) *v1alpha1.Console {
+	return &v1alpha1.Console{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: sys.Namespace},
+		Spec: v1alpha1.ConsoleSpec{
+			Hypervisor: hypervisor,
+			Domain:     "0a1b2c3d-4e5f-6071-8293-a4b5c6d7e8f9",
+			ExpiresAt:  metav1.NewTime(expiresAt),
+		},
+	}
+}
+
+func TestServeHTTP_Rejections(t *testing.T) {
+	server, opener := newTestServer(t,
+		newConsole("other-host", "some-other-node", time.Now().Add(time.Hour)),
+		newConsole("expired", sys.NodeLabelName, time.Now().Add(-time.Minute)),
+	)
+	tests := []struct {
+		name     string
+		path     string
+		expected int
+	}{
+		{name: "missing name", path: PathPrefix, expected: http.StatusNotFound},
+		{name: "nested path", path: PathPrefix + "a/b", expected: http.StatusNotFound},
+		{name: "unknown console", path: PathPrefix + "unknown", expected: http.StatusNotFound},
+		{name: "console of other host", path: PathPrefix + "other-host", expected: http.StatusNotFound},
+		{name: "expired console", path: PathPrefix + "expired", expected: http.StatusGone},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, http.NoBody))
+			if rec.Code != tt.expected {
+				t.Errorf("Expected status %d, got %d", tt.expected, rec.Code)
+			}
+		})
+	}
+	if len(opener.opened) != 0 {
+		t.Errorf("Expected no console to be opened, got %v", opener.opened)
+	}
+}
+
+func TestURL(t *testing.T) {
+	server := &Server{BindAddress: ":9443"}
+	expected := "wss://" + sys.Hostname + ":9443" + PathPrefix + "my-console"
+	if got := server.URL("my-console"); got != expected {
+		t.Errorf("Expected url %s, got %s", expected, got)
+	}
+
+	server.BindAddress = "invalid"
+	if got := server.URL("my-console"); got != "" {
+		t.Errorf("Expected empty url for invalid bind address, got %s", got)
+	}
+}
+
+func TestServeHTTP_EndsStreamWithSession(t *testing.T) {
+	tests := []struct {
+		name       string
+		expiresIn  time.Duration
+		disconnect bool
+	}{
+		{name: "client disconnects", expiresIn: time.Hour, disconnect: true},
+		{name: "session expires", expiresIn: 2 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, _ := newTestServer(t, newConsole("session", sys.NodeLabelName, time.Now().Add(tt.expiresIn)))
+			opener := &blockingOpener{ended: make(chan error, 1)}
+			server.Opener = opener
+			httpServer := httptest.NewServer(server)
+			defer httpServer.Close()
+
+			url := "ws" + strings.TrimPrefix(httpServer.URL, "http") + PathPrefix + "session"
+			ws, err := websocket.Dial(url, "", httpServer.URL)
+			if err != nil {
+				t.Fatalf("Failed to dial console: %v", err)
+			}
+			defer ws.Close()
+			if tt.disconnect {
+				ws.Close()
+			}
+
+			select {
+			case <-opener.ended:
+			case <-time.After(5 * time.Second):
+				t.Fatal("Expected the console stream to be aborted")
+			}
+		})
+	}
+}
+
+type testCertificate struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	tls  tls.Certificate
+}
+
+// Issue a certificate for the name, self-signed as CA if parent is nil.
+func newTestCertificate(t *testing.T, name string, parent *testCertificate) *testCertificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		DNSNames:     []string{name},
+	}
+	signer, signerKey := template, key
+	if parent == nil {
+		template.IsCA, template.BasicConstraintsValid = true, true
+	} else {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testCertificate{
+		cert: cert,
+		key:  key,
+		tls:  tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key},
+	}
+}
+
+// Write the certificate and its key as PEM files into dir.
+func writeTestCertificate(t *testing.T, dir, name string, c *testCertificate) (certFile, keyFile string) {
+	t.Helper()
+	keyDER, err := x509.MarshalECPrivateKey(c.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile, keyFile = filepath.Join(dir, name+"cert.pem"), filepath.Join(dir, name+"key.pem")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.cert.Raw}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
+
+func TestTLSConfig_AuthorizesClients(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCertificate(t, "libvirt-ca", nil)
+	caFile, _ := writeTestCertificate(t, dir, "ca", ca)
+	certFile, keyFile := writeTestCertificate(t, dir, "server", newTestCertificate(t, "node001", ca))
+	server := &Server{CAFile: caFile, CertFile: certFile, KeyFile: keyFile, AllowedClients: []string{"console-operator"}}
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{MinVersion: tls.VersionTLS12, GetConfigForClient: server.tlsConfig})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	tests := []struct {
+		name    string
+		client  *testCertificate
+		allowed bool
+	}{
+		{name: "allowed client", client: newTestCertificate(t, "console-operator", ca), allowed: true},
+		{name: "peer hypervisor", client: newTestCertificate(t, "node002", ca)},
+		{name: "other ca", client: newTestCertificate(t, "console-operator", newTestCertificate(t, "other-ca", nil))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			serverErr := make(chan error, 1)
+			go func() {
+				conn, err := listener.Accept()
+				if err != nil {
+					serverErr <- err
+					return
+				}
+				defer conn.Close()
+				serverErr <- conn.(*tls.Conn).Handshake()
+			}()
+			conn, err := tls.Dial("tcp", listener.Addr().String(), &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				RootCAs:      pool,
+				ServerName:   "node001",
+				Certificates: []tls.Certificate{tt.client.tls},
+			})
+			if err == nil {
+				defer conn.Close()
+			}
+			err = <-serverErr
+			if tt.allowed && err != nil {
+				t.Errorf("Expected the client to be allowed, got %v", err)
+			}
+			if !tt.allowed && err == nil {
+				t.Error("Expected the client to be rejected")
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logger "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	"github.com/cobaltcore-dev/kvm-node-agent/api/v1alpha1"
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/sys"
+)
+
+// ConsoleReconciler publishes the url of consoles requested for this host
+// and removes them once they expired.
+type ConsoleReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+	// URL returns the url under which the console with the given name is served.
+	URL func(name string) string
+}
+
+// +kubebuilder:rbac:groups=kvm.cloud.sap,resources=consoles,verbs=get;list;watch;delete
+// +kubebuilder:rbac:groups=kvm.cloud.sap,resources=consoles/status,verbs=get;update;patch
+
+// Reconcile is part of the main kubernetes reconciliation loop which aims to
+// move the current state of the cluster closer to the desired state.
+func (r *ConsoleReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := logger.FromContext(ctx)
+
+	console := &v1alpha1.Console{}
+	if err := r.Get(ctx, req.NamespacedName, console); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	remaining := time.Until(console.Spec.ExpiresAt.Time)
+	if remaining <= 0 {
+		log.Info("console access expired, removing console")
+		return ctrl.Result{}, client.IgnoreNotFound(r.Delete(ctx, console))
+	}
+
+	if url := r.URL(console.Name); console.Status.URL != url {
+		base := console.DeepCopy()
+		console.Status.URL = url
+		if err := r.Status().Patch(ctx, console, client.MergeFrom(base)); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	// Come back once the console expired to clean it up.
+	return ctrl.Result{RequeueAfter: remaining}, nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *ConsoleReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	// Only consoles of domains on this host are of interest.
+	onThisHost := predicate.NewPredicateFuncs(func(o client.Object) bool {
+		console, ok := o.(*v1alpha1.Console)
+		return ok && console.Namespace == sys.Namespace &&
+			console.Spec.Hypervisor == sys.NodeLabelName
+	})
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("console").
+		For(&v1alpha1.Console{}, builder.WithPredicates(onThisHost)).
+		Complete(r)
+}
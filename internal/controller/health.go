@@ -0,0 +1,110 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/systemd"
+)
+
+// Time without reconcile of the hypervisor after which the agent is
+// considered wedged. Failing reconciles are retried with a backoff of up
+// to about 17 minutes, which must not count as stalled.
+const reconcileStallTimeout = 30 * time.Minute
+
+var errLibvirtNotConnected = errors.New("libvirt connection not established yet")
+
+// HealthChecks of the agent for the health and readiness probes of the
+// manager, fed by the reconciles of the hypervisor. The checks fail while
+// libvirt or systemd can't be reached, or once the reconcile loop stalled.
+type HealthChecks struct {
+	// Connection to systemd, nil if not checked.
+	Systemd systemd.Interface
+	// Time without reconcile after which the reconcile loop is stalled.
+	StallTimeout time.Duration
+
+	lock       sync.Mutex
+	reconciled time.Time
+	libvirtErr error
+}
+
+// NewHealthChecks returns the checks of the agent, which aren't ready
+// until libvirt is connected.
+func NewHealthChecks(sd systemd.Interface) *HealthChecks {
+	return &HealthChecks{
+		Systemd:      sd,
+		StallTimeout: reconcileStallTimeout,
+		libvirtErr:   errLibvirtNotConnected,
+	}
+}
+
+// Record that the reconcile loop is alive. Nil checks ignore it.
+func (h *HealthChecks) beat(now time.Time) {
+	if h == nil {
+		return
+	}
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.reconciled = now
+}
+
+// Record the result of the last connect to libvirt, nil if connected. Nil
+// checks ignore it.
+func (h *HealthChecks) libvirtConnection(err error) {
+	if h == nil {
+		return
+	}
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.libvirtErr = err
+}
+
+// LibvirtCheck fails while the last reconcile couldn't connect to libvirt.
+func (h *HealthChecks) LibvirtCheck(_ *http.Request) error {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	return h.libvirtErr
+}
+
+// SystemdCheck fails while the connection to systemd over dbus is down.
+func (h *HealthChecks) SystemdCheck(_ *http.Request) error {
+	if h.Systemd == nil || h.Systemd.IsConnected() {
+		return nil
+	}
+	return errors.New("not connected to systemd")
+}
+
+// ReconcileCheck fails once the hypervisor wasn't reconciled for the stall
+// timeout, e.g. because the reconcile hangs on a blocked call. Before the
+// first reconcile, e.g. while the hypervisor doesn't exist, it passes.
+func (h *HealthChecks) ReconcileCheck(_ *http.Request) error {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	if h.reconciled.IsZero() {
+		return nil
+	}
+	if since := time.Since(h.reconciled); since > h.StallTimeout {
+		return fmt.Errorf("hypervisor not reconciled for %s", since.Round(time.Second))
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/systemd"
+)
+
+var _ = Describe("Health checks", func() {
+	var (
+		connected bool
+		health    *HealthChecks
+	)
+
+	BeforeEach(func() {
+		connected = true
+		health = NewHealthChecks(&systemd.InterfaceMock{
+			IsConnectedFunc: func() bool { return connected },
+		})
+	})
+
+	It("should not be ready until libvirt is connected", func() {
+		Expect(health.LibvirtCheck(nil)).To(MatchError(errLibvirtNotConnected))
+		health.libvirtConnection(nil)
+		Expect(health.LibvirtCheck(nil)).To(Succeed())
+		health.libvirtConnection(errors.New("connection refused"))
+		Expect(health.LibvirtCheck(nil)).To(MatchError("connection refused"))
+	})
+
+	It("should fail while systemd is not connected", func() {
+		Expect(health.SystemdCheck(nil)).To(Succeed())
+		connected = false
+		Expect(health.SystemdCheck(nil)).To(MatchError("not connected to systemd"))
+	})
+
+	It("should fail once the reconcile loop stalled", func() {
+		Expect(health.ReconcileCheck(nil)).To(Succeed())
+		health.beat(time.Now().Add(-time.Minute))
+		Expect(health.ReconcileCheck(nil)).To(Succeed())
+		health.beat(time.Now().Add(-reconcileStallTimeout - time.Minute))
+		Expect(health.ReconcileCheck(nil)).To(MatchError(ContainSubstring("not reconciled for 31m")))
+	})
+
+	It("should ignore the reconciles without checks", func() {
+		var none *HealthChecks
+		none.beat(time.Now())
+		none.libvirtConnection(nil)
+	})
+})
@@ -19,29 +19,61 @@ package controller
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"maps"
+	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
 	kvmv1 "github.com/cobaltcore-dev/openstack-hypervisor-operator/api/v1"
 	golibvirt "github.com/digitalocean/go-libvirt"
+	"github.com/sapcc/go-api-declarations/bininfo"
+	"go.opentelemetry.io/otel/attribute"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/events"
+	"k8s.io/client-go/util/retry"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	logger "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	"sigs.k8s.io/controller-runtime/pkg/source"
 
+	"github.com/cobaltcore-dev/kvm-node-agent/api/v1alpha1"
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/boot"
 	"github.com/cobaltcore-dev/kvm-node-agent/internal/certificates"
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/config"
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/cordon"
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/entropy"
 	"github.com/cobaltcore-dev/kvm-node-agent/internal/evacuation"
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/hoststorage"
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/iommu"
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/journal"
 	"github.com/cobaltcore-dev/kvm-node-agent/internal/kernel"
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/ksm"
 	"github.com/cobaltcore-dev/kvm-node-agent/internal/libvirt"
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/libvirt/virterr"
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/memory"
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/nfd"
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/ovs"
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/reboot"
 	"github.com/cobaltcore-dev/kvm-node-agent/internal/sys"
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/sysctl"
 	"github.com/cobaltcore-dev/kvm-node-agent/internal/systemd"
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/tracing"
 )
 
 // HypervisorReconciler reconciles a Hypervisor object
@@ -51,10 +83,176 @@ type HypervisorReconciler struct {
 	Systemd      systemd.Interface
 	Libvirt      libvirt.Interface
 	KernelReader kernel.Interface
+	OVS          ovs.Interface
+	Entropy      entropy.Interface
+	IOMMU        iommu.Interface
+	// Reads the free space and inodes of the host paths, nil if they are
+	// not checked.
+	HostStorage hoststorage.Interface
+	// Recorder of the events of the hypervisor, e.g. of the evacuation on
+	// shutdown. Nil if no events are emitted.
+	Recorder events.EventRecorder
+	// Notifies about unit changes as they happen, nil if the units are
+	// only checked on reconcile.
+	UnitWatcher systemd.UnitWatcher
+
+	// Systemd units reported as conditions in addition to the default
+	// units, e.g. virtlogd.service or multipathd.service.
+	Units []string
+	// Runtime configuration reloaded from the ConfigMap of the agent, with
+	// the stats interval, further units and the evacuation policy. Nil to
+	// use the defaults.
+	RuntimeConfig *config.RuntimeStore
+	// Whether the host runs the monolithic libvirtd or the modular daemons,
+	// defaults to monolithic.
+	LibvirtDaemons libvirt.DaemonMode
+	// Uris of the libvirt drivers connected to, defaults to
+	// libvirt.DefaultURI.
+	LibvirtURIs []string
+
+	// Integration with node-feature-discovery, defaults to off.
+	NodeFeatureDiscovery nfd.Mode
+	// Labels of the node copied into the labels of the hypervisor, e.g. the
+	// zone or the rack, so that placement constraints can rely on them.
+	NodeLabels []string
+	// Whether the taints of the node are published in the annotations of
+	// the hypervisor.
+	SyncNodeTaints bool
+	// Whether the hypervisor is detached from its node, so that it is kept
+	// and adopted by a recreated node of the same name, and its deletion is
+	// blocked with HypervisorFinalizer while domains are running on it.
+	Finalizer bool
+	// Path of the NFD feature file written in produce mode.
+	FeatureFilePath string
+	// Whether the kernel parameters requested by the annotations of the
+	// hypervisor are written into a kernel command line snippet.
+	ManageKernelParameters bool
+	// Path of the kernel command line snippet, defaults to
+	// kernel.DefaultSnippetPath.
+	KernelSnippetPath string
+	// Applies the sysctls requested by the annotations of the hypervisor,
+	// nil if sysctls are not managed.
+	Sysctl sysctl.Interface
+	// Applies the ksm parameters requested by the annotations of the
+	// hypervisor and reads the ksm counters, nil if ksm is not managed.
+	KSM ksm.Interface
+	// Checks the domains for autostart and managed save images.
+	DomainPolicy libvirt.DomainPolicyEnforcer
+	// Whether domain policy violations are only reported or also fixed,
+	// defaults to off.
+	DomainPolicyMode libvirt.DomainPolicyMode
+	// Age after which the managed save image of a domain is taken for stale
+	// and removed when the policy is enforced. Zero only reports them, as
+	// nova keeps the memory of suspended instances in the image.
+	ManagedSaveMaxAge time.Duration
+	// Finds the tap devices, vhost-user sockets and qemu state of crashed
+	// domains, nil if they aren't looked for.
+	Janitor libvirt.LeftoverJanitor
+	// Whether leftovers are only reported or also removed, defaults to off.
+	JanitorMode libvirt.DomainPolicyMode
+	// Compares the live and persistent definitions of the domains.
+	DomainDrift libvirt.DomainDriftDetector
+	// Provides the host cpus to report which of them are isolated for the
+	// vcpus of domains.
+	HostTopology libvirt.HostTopology
+	// Provides the cpu model of the host, which is published in the
+	// annotations of the hypervisor. Nil if it isn't published.
+	HostCPU libvirt.HostCPUDescriber
+	// Computes the baseline cpu of the hypervisors sharing the value of the
+	// CPUBaselineLabel with this one, which is published in the annotations
+	// of the hypervisor. Nil if it isn't published.
+	CPUBaseline libvirt.CPUBaseliner
+	// Provides the support of the host for confidential domains, which is
+	// published in the annotations of the hypervisor. Nil if it isn't
+	// published.
+	ConfidentialComputing libvirt.ConfidentialComputingDescriber
+	// Provides the firmware loaders of the host, which are published in the
+	// annotations of the hypervisor. Nil if they aren't published.
+	Firmware libvirt.FirmwareDescriber
+	// Directory of the firmware descriptors of qemu the nvram templates are
+	// read from, e.g. /usr/share/qemu/firmware. Empty if they aren't
+	// published.
+	FirmwareDescriptors string
+	// Label grouping the hypervisors for the baseline cpu, e.g. the
+	// availability zone or a cluster label.
+	CPUBaselineLabel string
+	// Reader to list the other hypervisors, which are not in the cache.
+	APIReader client.Reader
+	// Provides whether libvirt found an iommu on the host, which is
+	// checked in addition to the iommu groups of the kernel.
+	HostIOMMU libvirt.HostIOMMU
+	// Probes the rpc connection to libvirt, to report a degraded
+	// connection before it drops. Nil if the connection isn't probed.
+	ConnectionProber libvirt.ConnectionProber
+	// Health checks of the manager which are told about the reconciles
+	// and the libvirt connection. Nil if there are none.
+	Health *HealthChecks
+	// Provides the usage of the ephemeral disks of the domains, which is
+	// checked against DiskWatermark. Nil if the disks aren't checked.
+	DiskUsage libvirt.DiskUsageReporter
+	// Share of the capacity of a disk or of the filesystem holding the
+	// disks from which disk pressure is reported, e.g. 0.9.
+	DiskWatermark float64
+	// Shuts down the domains on shutdown of the host if the evacuation
+	// policy of the hypervisor asks for it.
+	DomainShutdown libvirt.DomainShutdowner
+	// Reads the memory pressure of the host, nil if it isn't watched.
+	MemoryPressure memory.Interface
+	// Thresholds from which the memory pressure of the host is critical.
+	MemoryPressureThresholds memory.Thresholds
+	// Whether the mitigations of critical memory pressure are only
+	// reported or also performed, defaults to off.
+	MemoryPressureMode libvirt.DomainPolicyMode
+	// Mitigations of critical memory pressure, performed in order at most
+	// once per memoryMitigationInterval while the pressure lasts, the last
+	// one repeatedly.
+	MemoryMitigations []memory.Mitigation
+	// Reclaims memory from the instances selected by their memory priority.
+	MemoryReclaimer libvirt.MemoryReclaimer
+	// Cache of the domain capabilities, invalidated once an operating
+	// system update is installed. Nil if they are not cached.
+	DomainCapabilities libvirt.DomainCapabilitiesCache
+	// Whether the reboot after an operating system update is deferred to
+	// the maintenance window of the hypervisor and the evacuation of its
+	// instances, instead of rebooting right after the installation.
+	RebootOrchestration bool
+	// Whether the node is cordoned and tainted while the host is evacuated
+	// or reboots into an operating system update.
+	CordonNode bool
+	// Follows the progress of operating system updates, nil if only the
+	// state of the update unit is reported.
+	UpdateProgress journal.UpdateProgressTracker
+	// Reads and selects the boot loader entries of the host, nil if they
+	// are not reported.
+	BootLoader boot.Interface
+	// Stages the operating system images requested by the annotations of
+	// the hypervisor for systemd-sysupdate, nil if images are not staged.
+	ImageStager systemd.ImageStager
+	// Issues the libvirt TLS certificate of the host if requested by the
+	// spec, defaults to cert-manager.
+	CertificateProvider certificates.Provider
+	// Minimum interval between two status patches. Changes within the
+	// interval are batched into the next patch, defaults to 10 seconds.
+	StatusPatchInterval time.Duration
 
 	osDescriptor     *systemd.Descriptor
 	kernelParameters *kernel.Parameters
 	evacuateOnReboot bool
+	lastStatusPatch  time.Time
+	// Next mitigation of the critical memory pressure, the ones performed
+	// since the pressure became critical and when the last one was.
+	memoryMitigationStep int
+	memoryMitigated      []string
+	memoryMitigatedAt    time.Time
+	// Instances paused and ballooned by the mitigations, restored once the
+	// pressure ends. The ballooned ones with the memory in KiB to restore.
+	memoryPaused    []string
+	memoryBallooned map[string]uint64
+	// Host cpus and result of the last baseline cpu computation.
+	cpuBaselineInputs string
+	cpuBaseline       libvirt.HostCPUModel
+	// Returns the boot time of the host, defaults to sys.BootTime.
+	bootTime func() (time.Time, error)
 
 	// Channel that can be used to trigger reconcile events.
 	reconcileCh chan event.GenericEvent
@@ -65,20 +263,176 @@ type HypervisorReconciler struct {
 	libvirtConnectInterval time.Duration
 }
 
+// Systemd units always reported as conditions of the hypervisor, in
+// addition to the units of the libvirt daemons.
+var defaultUnitNames = []string{"openvswitch-switch.service"}
+
+const (
+	OSUpdateType      = "OperatingSystemUpdate"
+	LibVirtType       = "LibVirtConnection"
+	NFDType           = "NodeFeatureDiscovery"
+	OVSType           = "OpenvSwitch"
+	PolicyType        = "DomainPolicy"
+	DriftType         = "DomainDefinitions"
+	EntropyType       = "Entropy"
+	RebootType        = "RebootRequired"
+	ConfigType        = "Configuration"
+	SysctlType        = "Sysctl"
+	CPUType           = "CPUIsolation"
+	UnitActionType    = "UnitAction"
+	RebootPendingType = "RebootPending"
+	BootType          = "BootEntries"
+	ImageType         = "OperatingSystemImage"
+	IOMMUType         = "IOMMUReady"
+	CapacityType      = "EvacuationCapacity"
+	InhibitType       = "ShutdownInhibit"
+	DegradedType      = "LibVirtDegraded"
+	DiskPressureType  = "DiskPressure"
+	HostStorageType   = "HostStorage"
+	LeftoversType     = "Leftovers"
+	MemoryType        = "MemoryPressure"
+	KSMType           = "KSM"
+	RuntimeConfigType = "RuntimeConfiguration"
+	DeletionType      = "DeletionBlocked"
+)
+
 const (
-	OSUpdateType = "OperatingSystemUpdate"
-	LibVirtType  = "LibVirtConnection"
+	// Annotation of the hypervisor set by a central operator to roll out a
+	// change of the node-local configuration managed by the agent, e.g. a
+	// counter or a hash of the parameters.
+	ConfigGenerationAnnotation = "kvm.cloud.sap/config-generation"
+	// Annotation of the hypervisor echoing the config generation once the
+	// agent applied the configuration.
+	ObservedConfigGenerationAnnotation = "kvm.cloud.sap/observed-config-generation"
+	// Annotation of the hypervisor requesting the restart of units reported
+	// as conditions, a comma separated list like "libvirtd.service". The
+	// agent removes the annotation before restarting the units.
+	RestartUnitsAnnotation = "kvm.cloud.sap/restart-units"
+	// Annotation of the hypervisor restricting the reboot after an operating
+	// system update to maintenance windows in UTC, e.g. "Sat,Sun 02:00-06:00".
+	// The host may be rebooted at any time without the annotation.
+	RebootWindowAnnotation = "kvm.cloud.sap/reboot-window"
+	// Annotation of the hypervisor with the time the agent started the reboot
+	// into an operating system update, in RFC 3339. The reboot is done once
+	// the host booted after that.
+	RebootRequestedAnnotation = "kvm.cloud.sap/reboot-requested"
+	// Annotation of the hypervisor requesting to boot the previous operating
+	// system version from now on. The agent removes the annotation after
+	// changing the default boot entry, the host isn't rebooted.
+	RollbackAnnotation = "kvm.cloud.sap/rollback"
+	// Annotations of the hypervisor with the url and sha256 checksum of the
+	// operating system image to stage for the next update.
+	OSImageURLAnnotation    = "kvm.cloud.sap/os-image-url"
+	OSImageSHA256Annotation = "kvm.cloud.sap/os-image-sha256"
+	// Annotations of the hypervisor with the cpu of the host as detected by
+	// libvirt, to check the live migration compatibility of hosts centrally.
+	// The features are a sorted, comma separated list.
+	HostCPUModelAnnotation     = "kvm.cloud.sap/host-cpu-model"
+	HostCPUVendorAnnotation    = "kvm.cloud.sap/host-cpu-vendor"
+	HostCPUMicrocodeAnnotation = "kvm.cloud.sap/host-cpu-microcode"
+	HostCPUFeaturesAnnotation  = "kvm.cloud.sap/host-cpu-features"
+	// Annotations of the hypervisor with the frequency of the time stamp
+	// counter of the host in Hz, and whether the host can scale the counter
+	// of domains migrated from a host with another frequency. Only set if
+	// libvirt knows the frequency.
+	HostTSCFrequencyAnnotation = "kvm.cloud.sap/host-tsc-frequency"
+	HostTSCScalingAnnotation   = "kvm.cloud.sap/host-tsc-scaling"
+	// Annotations of the hypervisor with the cpu model all hypervisors of
+	// its group provide, to configure the cpu of domains for live migrations
+	// within the group, and the number of hypervisors it is based on.
+	BaselineCPUModelAnnotation    = "kvm.cloud.sap/baseline-cpu-model"
+	BaselineCPUFeaturesAnnotation = "kvm.cloud.sap/baseline-cpu-features"
+	BaselineCPUHostsAnnotation    = "kvm.cloud.sap/baseline-cpu-hosts"
+	// Annotations of the hypervisor with the number of SEV and SEV-ES
+	// domains it can run and the version of its SEV firmware, to schedule
+	// confidential domains to capable hypervisors. Whether SEV, SEV-ES and
+	// TDX are available is part of the supported features of the domain
+	// capabilities.
+	SEVMaxGuestsAnnotation   = "kvm.cloud.sap/sev-max-guests"
+	SEVESMaxGuestsAnnotation = "kvm.cloud.sap/sev-es-max-guests"
+	SEVFirmwareAnnotation    = "kvm.cloud.sap/sev-firmware-version"
+	// Annotations of the hypervisor with the firmware loaders and the nvram
+	// templates available to domains, comma separated, to check that uefi
+	// images will boot on the hypervisor. Whether secure boot is supported
+	// is part of the supported features of the domain capabilities.
+	FirmwareLoadersAnnotation = "kvm.cloud.sap/firmware-loaders"
+	NVRAMTemplatesAnnotation  = "kvm.cloud.sap/nvram-templates"
+	// Annotation of the hypervisor with the taints of its node as sorted,
+	// comma separated key=value:Effect, empty if the node has no taints.
+	NodeTaintsAnnotation = "kvm.cloud.sap/node-taints"
+	// Annotations of the hypervisor with whether the dpdk datapath of Open
+	// vSwitch is initialized, its version and whether hardware offload is
+	// enabled, to schedule vhost-user and offloaded ports. Only set if Open
+	// vSwitch runs on the host.
+	OVSDPDKAnnotation        = "kvm.cloud.sap/ovs-dpdk"
+	OVSDPDKVersionAnnotation = "kvm.cloud.sap/ovs-dpdk-version"
+	OVSHWOffloadAnnotation   = "kvm.cloud.sap/ovs-hw-offload"
+	// Finalizer of the hypervisor blocking its deletion while domains are
+	// running on it. The agent only holds it while the hypervisor has
+	// instances, so an evacuated host can be decommissioned. Once the
+	// HeartbeatAnnotation is stale the agent is gone and won't remove it,
+	// then a central operator or an administrator has to.
+	HypervisorFinalizer = "kvm.cloud.sap/kvm-node-agent"
+	// Annotation of the hypervisor with the uid of its node. With the
+	// finalizer the hypervisor isn't owned by its node, so that deleting the
+	// node, e.g. to reimage the host, doesn't garbage collect it. Another uid
+	// means that the node was recreated.
+	NodeUIDAnnotation = "kvm.cloud.sap/node-uid"
+	// Annotation of the hypervisor with the time of the last reconcile of
+	// the agent in RFC 3339 format, refreshed at least every
+	// heartbeatInterval. A central operator considers the agent dead if
+	// the heartbeat is stale.
+	HeartbeatAnnotation = "kvm.cloud.sap/heartbeat"
+	// Annotations of the hypervisor with the version and the commit and
+	// build date of the agent, to detect version skew across the fleet.
+	AgentVersionAnnotation = "kvm.cloud.sap/agent-version"
+	AgentBuildAnnotation   = "kvm.cloud.sap/agent-build"
+	// Annotation of instances selecting them for the reclaim of memory on
+	// critical memory pressure of the host, an integer. Instances with the
+	// lowest priority are paused first, instances without the annotation
+	// are left alone.
+	MemoryPriorityAnnotation = "kvm.cloud.sap/memory-priority"
 )
 
+// Share of the flavor memory the balloon of an instance leaves to the guest
+// on critical memory pressure of the host.
+const memoryBalloonRatio = 0.75
+
+// Minimum time between two mitigations of critical memory pressure, so that
+// the effect of one shows in the pressure before the next one is performed.
+// Reconciles are triggered by events far more often.
+const memoryMitigationInterval = time.Minute
+
+// Interval in which the heartbeat of the agent is refreshed. Reconciles
+// happen at least every minute, the interval is shorter so that each of
+// them refreshes the heartbeat, while the reconciles triggered by the
+// patch itself don't.
+const heartbeatInterval = 30 * time.Second
+
+// Systemd target rebooting into the installed operating system update.
+const sysUpdateRebootTarget = "systemd-sysupdate-reboot.target"
+
 // +kubebuilder:rbac:groups=kvm.cloud.sap,resources=hypervisors,verbs=get;list;watch;update;patch;delete
 // +kubebuilder:rbac:groups=kvm.cloud.sap,resources=hypervisors/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=kvm.cloud.sap,resources=hypervisors/finalizers,verbs=update
 // +kubebuilder:rbac:groups=kvm.cloud.sap,resources=evictions,verbs=get;create
 // +kubebuilder:rbac:groups=kvm.cloud.sap,resources=migrations,verbs=get;list;watch;create
 // +kubebuilder:rbac:groups=kvm.cloud.sap,resources=migrations/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=kvm.cloud.sap,resources=instances,verbs=get;list;watch;create;delete
+// +kubebuilder:rbac:groups=kvm.cloud.sap,resources=instances/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=cert-manager.io,resources=certificates,verbs=get;list;watch;create;update;patch
+// +kubebuilder:rbac:groups="",namespace=monsoon3,resources=configmaps,verbs=get;list;watch;create;update;patch
+// +kubebuilder:rbac:groups="",resources=nodes,verbs=get;list;watch;patch
 
 func (r *HypervisorReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	ctx, span := tracing.Start(ctx, "HypervisorReconciler.Reconcile", attribute.String("hypervisor", req.Name))
+	r.Health.beat(time.Now())
+	result, err := r.reconcile(ctx, req)
+	tracing.End(span, err)
+	return result, err
+}
+
+func (r *HypervisorReconciler) reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	log := logger.FromContext(ctx, "controller", "hypervisor")
 
 	// only reconcile the node I am running on
@@ -95,32 +449,56 @@ func (r *HypervisorReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 
 	base := hypervisor.DeepCopy()
 
+	// The heartbeat comes first, a failing reconcile step doesn't mean
+	// that the agent is dead.
+	if err := r.reconcileHeartbeat(ctx, &hypervisor, base); err != nil {
+		log.Error(err, "unable to update heartbeat")
+		return ctrl.Result{}, err
+	}
+	if err := r.reconcileNodeOwner(ctx, &hypervisor, base); err != nil {
+		log.Error(err, "unable to detach hypervisor from node")
+		return ctrl.Result{}, err
+	}
+
 	// ====================================================================================================
 	// Systemd
 	// ====================================================================================================
 
 	if r.Systemd.IsConnected() {
-		var unitNames = []string{"libvirtd.service", "openvswitch-switch.service"}
-		units, err := r.Systemd.ListUnitsByNames(ctx, unitNames)
+		if err := r.reconcileUnitActions(ctx, &hypervisor, base); err != nil {
+			log.Error(err, "unable to acknowledge unit restart")
+			return ctrl.Result{}, err
+		}
+
+		units, err := r.Systemd.ListUnitsByNames(ctx, r.unitNames())
 		if err != nil {
 			log.Error(err, "unable to list units")
 			return ctrl.Result{}, err
 		}
 
 		var unitReasonsMap = map[string]string{
-			"active":   "Running",
-			"inactive": "Stopped",
-			"failed":   "Failed",
+			"active":       "Running",
+			"reloading":    "Reloading",
+			"inactive":     "Stopped",
+			"failed":       "Failed",
+			"activating":   "Starting",
+			"deactivating": "Stopping",
 		}
 		var unitStatusesMap = map[string]metav1.ConditionStatus{
-			"active":   metav1.ConditionTrue,
-			"inactive": metav1.ConditionFalse,
-			"failed":   metav1.ConditionFalse,
+			"active":       metav1.ConditionTrue,
+			"reloading":    metav1.ConditionTrue,
+			"inactive":     metav1.ConditionFalse,
+			"failed":       metav1.ConditionFalse,
+			"activating":   metav1.ConditionFalse,
+			"deactivating": metav1.ConditionFalse,
 		}
 
 		for _, unit := range units {
-			reason := unitReasonsMap[unit.ActiveState]
+			reason, ok := unitReasonsMap[unit.ActiveState]
 			status := unitStatusesMap[unit.ActiveState]
+			if !ok {
+				reason, status = "Unknown", metav1.ConditionUnknown
+			}
 			meta.SetStatusCondition(&hypervisor.Status.Conditions, metav1.Condition{
 				Type:    unit.Name,
 				Status:  status,
@@ -164,7 +542,15 @@ func (r *HypervisorReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 
 		if hypervisor.Spec.EvacuateOnReboot != r.evacuateOnReboot {
 			if hypervisor.Spec.EvacuateOnReboot {
-				e := &evacuation.EvictionController{Client: r.Client}
+				e := &evacuation.EvictionController{
+					Client:     r.Client,
+					APIReader:  r.APIReader,
+					Recorder:   r.Recorder,
+					Domains:    r.DomainShutdown,
+					CordonNode: r.CordonNode,
+
+					RuntimeConfig: r.RuntimeConfig,
+				}
 				if err := r.Systemd.EnableShutdownInhibit(ctx, e.EvictCurrentHost); err != nil {
 					return ctrl.Result{}, err
 				}
@@ -182,18 +568,21 @@ func (r *HypervisorReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 	// ====================================================================================================
 
 	// Try (re)connect to Libvirt, update status
-	if meta.IsStatusConditionFalse(hypervisor.Status.Conditions, "libvirtd.service") {
+	if unit := r.LibvirtDaemons.ConnectionUnit(r.libvirtURIs()[0]); meta.IsStatusConditionFalse(hypervisor.Status.Conditions, unit) {
 		// libvirtd service is not running, skip libvirt connection, systemd socket activation could
-		// be blocking the libvirt connection. Could reconnect with next reconcile loop.
-		log.Info("libvirtd.service is not running, skipping libvirt connection")
+		// be blocking the libvirt connection. Could reconnect with next reconcile loop. The sockets
+		// of the modular daemons start the daemon on connect, so only the socket has to be active.
+		log.Info("libvirt daemon is not running, skipping libvirt connection", "unit", unit)
+		r.Health.libvirtConnection(fmt.Errorf("%s is not running", unit))
 		meta.SetStatusCondition(&hypervisor.Status.Conditions, metav1.Condition{
 			Type:    LibVirtType,
 			Status:  metav1.ConditionFalse,
-			Message: "libvirtd service is not running",
+			Message: fmt.Sprintf("%s is not running", unit),
 			Reason:  "LibVirtServiceNotRunning",
 		})
-	} else if err := r.Libvirt.Connect(); err != nil {
+	} else if err := r.connectLibvirt(ctx); err != nil {
 		log.Error(err, "unable to connect to Libvirt system bus")
+		r.Health.libvirtConnection(err)
 		meta.SetStatusCondition(&hypervisor.Status.Conditions, metav1.Condition{
 			Type:    LibVirtType,
 			Status:  metav1.ConditionFalse,
@@ -205,6 +594,7 @@ func (r *HypervisorReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		// able to detect capacity and other scheduling-relevant details.
 	} else {
 		// We're connected.
+		r.Health.libvirtConnection(nil)
 		meta.SetStatusCondition(&hypervisor.Status.Conditions, metav1.Condition{
 			Type:   LibVirtType,
 			Status: metav1.ConditionTrue,
@@ -212,7 +602,7 @@ func (r *HypervisorReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		})
 
 		var err error
-		hypervisor, err = r.Libvirt.Process(hypervisor)
+		hypervisor, err = r.Libvirt.Process(ctx, hypervisor)
 		if err != nil {
 			log.Error(err, "unable to process hypervisor via libvirt")
 			meta.SetStatusCondition(&hypervisor.Status.Conditions, metav1.Condition{
@@ -226,7 +616,9 @@ func (r *HypervisorReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 	}
 
 	// Reconcile operating system update
-	if hypervisor.Spec.OperatingSystemVersion != "" &&
+	imageStaged := r.reconcileOSImage(ctx, &hypervisor)
+	if (imageStaged || hypervisor.Status.Update.InProgress) &&
+		hypervisor.Spec.OperatingSystemVersion != "" &&
 		// only update if the version is different to current running version
 		hypervisor.Spec.OperatingSystemVersion != hypervisor.Status.OperatingSystem.Version &&
 		// only update if the version is different to the installed version
@@ -237,13 +629,15 @@ func (r *HypervisorReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 
 			// reset retry count
 			hypervisor.Status.Update.Retry = 3
-			if err := r.Status().Patch(ctx, &hypervisor, client.MergeFromWithOptions(base, client.MergeFromWithOptimisticLock{})); err != nil {
+			if err := r.applyStatus(ctx, &hypervisor); err != nil {
 				log.Error(err, "unable to update hypervisor status spec")
 				return ctrl.Result{}, err
 			}
 			hypervisor.Spec.OperatingSystemVersion = ""
 
-			if err := r.Status().Patch(ctx, &hypervisor, client.MergeFromWithOptions(base, client.MergeFromWithOptimisticLock{})); err != nil {
+			// The merge patch only touches the version, so there is no need
+			// for an optimistic lock against the writes of the operator.
+			if err := r.Patch(ctx, &hypervisor, client.MergeFrom(base)); err != nil {
 				log.Error(err, "unable to update hypervisor spec")
 				return ctrl.Result{}, err
 			}
@@ -253,7 +647,13 @@ func (r *HypervisorReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		}
 
 		// Reconcile operating system update
-		running, err := r.Systemd.ReconcileSysUpdate(ctx, &hypervisor)
+		update := &hypervisor
+		if r.RebootOrchestration {
+			// The reboot is started by reconcileReboot instead.
+			update = hypervisor.DeepCopy()
+			update.Spec.Reboot = false
+		}
+		running, err := r.Systemd.ReconcileSysUpdate(ctx, update)
 
 		// failed
 		if err != nil {
@@ -274,12 +674,26 @@ func (r *HypervisorReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 
 		// started
 		if !hypervisor.Status.Update.InProgress && running {
+			// A reboot of a previous update doesn't count for this one.
+			meta.RemoveStatusCondition(&hypervisor.Status.Conditions, RebootPendingType)
+			if r.UpdateProgress != nil {
+				r.UpdateProgress.Reset()
+			}
+		}
+
+		// running
+		if running {
+			message := fmt.Sprintf("Operating system update to %s is running", hypervisor.Spec.OperatingSystemVersion)
+			if r.UpdateProgress != nil {
+				if progress := r.UpdateProgress.Progress(); !progress.IsZero() {
+					message += ": " + progress.String()
+				}
+			}
 			meta.SetStatusCondition(&hypervisor.Status.Conditions, metav1.Condition{
-				Type:   OSUpdateType,
-				Status: metav1.ConditionTrue,
-				Reason: "Running",
-				Message: fmt.Sprintf("Operating system update to %s is running",
-					hypervisor.Spec.OperatingSystemVersion),
+				Type:    OSUpdateType,
+				Status:  metav1.ConditionTrue,
+				Reason:  "Running",
+				Message: message,
 			})
 		}
 
@@ -293,141 +707,2546 @@ func (r *HypervisorReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 					hypervisor.Spec.OperatingSystemVersion),
 			})
 			hypervisor.Status.Update.Installed = hypervisor.Spec.OperatingSystemVersion
+			if r.DomainCapabilities != nil {
+				// The update may come with a new libvirt or hypervisor.
+				r.DomainCapabilities.InvalidateDomainCapabilities()
+			}
 		}
 		hypervisor.Status.Update.InProgress = running
 	}
 
+	r.reconcileLibvirtHealth(ctx, &hypervisor)
+	r.reconcileNodeFeatureDiscovery(ctx, &hypervisor)
+	r.reconcileKernelParameters(ctx, &hypervisor)
+	r.reconcileSysctls(ctx, &hypervisor)
+	r.reconcileKSM(ctx, &hypervisor)
+	r.reconcileEntropy(ctx, &hypervisor)
+	r.reconcileIOMMU(ctx, &hypervisor)
+	r.reconcileEvacuationCapacity(ctx, &hypervisor)
+	r.reconcileDiskPressure(ctx, &hypervisor)
+	r.reconcileMemoryPressure(ctx, &hypervisor)
+	r.reconcileHostStorage(ctx, &hypervisor)
+	r.reconcileShutdownInhibit(ctx, &hypervisor)
+	r.reconcileDomainPolicy(ctx, &hypervisor)
+	r.reconcileLeftovers(ctx, &hypervisor)
+	r.reconcileDomainDrift(ctx, &hypervisor)
+	r.reconcileCPUIsolation(ctx, &hypervisor)
+	if err := r.reconcileHostCPU(ctx, &hypervisor, base); err != nil {
+		log.Error(err, "unable to publish host cpu model")
+		return ctrl.Result{}, err
+	}
+	if err := r.reconcileOVS(ctx, &hypervisor, base); err != nil {
+		log.Error(err, "unable to publish open vswitch datapath")
+		return ctrl.Result{}, err
+	}
+	if err := r.reconcileNodeMetadata(ctx, &hypervisor, base); err != nil {
+		log.Error(err, "unable to sync node labels")
+		return ctrl.Result{}, err
+	}
+	if err := r.reconcileCPUBaseline(ctx, &hypervisor, base); err != nil {
+		log.Error(err, "unable to publish baseline cpu model")
+		return ctrl.Result{}, err
+	}
+	if err := r.reconcileConfidentialComputing(ctx, &hypervisor, base); err != nil {
+		log.Error(err, "unable to publish confidential computing support")
+		return ctrl.Result{}, err
+	}
+	if err := r.reconcileFirmware(ctx, &hypervisor, base); err != nil {
+		log.Error(err, "unable to publish firmware inventory")
+		return ctrl.Result{}, err
+	}
+	if err := r.reconcileBootEntries(ctx, &hypervisor, base); err != nil {
+		log.Error(err, "unable to roll back operating system")
+		return ctrl.Result{}, err
+	}
+	if err := r.reconcileReboot(ctx, &hypervisor, base); err != nil {
+		log.Error(err, "unable to reboot into operating system update")
+		return ctrl.Result{}, err
+	}
+	if err := r.reconcileCordon(ctx); err != nil {
+		// Not critical, retried with the next reconcile.
+		log.Error(err, "unable to uncordon node")
+	}
+	if err := r.reconcileConfigGeneration(ctx, &hypervisor, base); err != nil {
+		log.Error(err, "unable to update observed config generation")
+		return ctrl.Result{}, err
+	}
+	r.reconcileRuntimeConfig(&hypervisor)
+
+	// The flag of the spec predates the other providers.
 	if hypervisor.Spec.CreateCertManagerCertificate {
-		if err := certificates.EnsureCertificate(ctx, r.Client, sys.Hostname); err != nil {
+		provider := r.CertificateProvider
+		if provider == nil {
+			provider = certificates.CertManager{}
+		}
+		if err := provider.EnsureCertificate(ctx, r.Client, sys.Hostname); err != nil {
 			return ctrl.Result{}, err
 		}
 	}
 
-	if err := r.Status().Patch(ctx, &hypervisor, client.MergeFromWithOptions(base, client.MergeFromWithOptimisticLock{})); err != nil {
+	if deleted, err := r.reconcileFinalizer(ctx, &hypervisor, base); err != nil || deleted {
+		if err != nil {
+			log.Error(err, "unable to update finalizer")
+		}
+		return ctrl.Result{}, err
+	}
+
+	delay, err := r.patchStatus(ctx, &hypervisor, base)
+	if err != nil {
 		log.Error(err, "unable to update hypervisor status")
 		return ctrl.Result{}, err
 	}
-	return ctrl.Result{RequeueAfter: 1 * time.Minute}, nil
+	if delay > 0 {
+		// Come back once the pending changes can be written.
+		return ctrl.Result{RequeueAfter: delay}, nil
+	}
+	return ctrl.Result{RequeueAfter: r.RuntimeConfig.Get().StatsInterval.Duration}, nil
 }
 
-// Trigger a reconcile event for the managed hypervisor through the
-// event channel which is watched by the controller manager.
-func (r *HypervisorReconciler) triggerReconcile() {
-	r.reconcileCh <- event.GenericEvent{
-		Object: &kvmv1.Hypervisor{
-			TypeMeta: metav1.TypeMeta{
-				Kind:       "Hypervisor",
-				APIVersion: "kvm.cloud.sap/v1",
-			},
-			ObjectMeta: metav1.ObjectMeta{
-				Name:      sys.Hostname,
-				Namespace: sys.Namespace,
-			},
-		},
+// Hash the hypervisor status to detect changes.
+func statusHash(status kvmv1.HypervisorStatus) (string, error) {
+	data, err := json.Marshal(status)
+	if err != nil {
+		return "", err
 	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
 }
 
-// Start is called when the manager starts. It starts the libvirt
-// event subscription to receive events when the hypervisor needs to be
-// reconciled.
-func (r *HypervisorReconciler) Start(ctx context.Context) error {
-	log := logger.FromContext(ctx, "controller", "hypervisor")
-	log.Info("starting libvirt event subscription")
+// Patch the hypervisor status if it changed compared to base. Patches are
+// rate limited to one per StatusPatchInterval, if the last patch is more
+// recent nothing is written and the remaining time is returned instead.
+func (r *HypervisorReconciler) patchStatus(ctx context.Context, hypervisor, base *kvmv1.Hypervisor) (time.Duration, error) {
+	log := logger.FromContext(ctx)
 
-	// Get the hypervisor we will reconcile.
-	var hypervisor kvmv1.Hypervisor
-	key := client.ObjectKey{Name: sys.Hostname} // Cluster-scoped
-	if err := r.Get(ctx, key, &hypervisor); err != nil {
-		return fmt.Errorf("unable to get hypervisor: %w", err)
+	hash, err := statusHash(hypervisor.Status)
+	if err != nil {
+		return 0, fmt.Errorf("unable to hash hypervisor status: %w", err)
+	}
+	baseHash, err := statusHash(base.Status)
+	if err != nil {
+		return 0, fmt.Errorf("unable to hash hypervisor status: %w", err)
+	}
+	if hash == baseHash {
+		log.V(1).Info("hypervisor status unchanged, skipping patch", "hash", hash)
+		return 0, nil
 	}
 
-	// Block until we're connected to libvirt.
-	for {
-		// Exit if the context is done, e.g. when the manager is shutting down.
-		if ctx.Err() != nil {
-			return fmt.Errorf("context done while trying to connect to libvirt: %w", ctx.Err())
+	interval := r.StatusPatchInterval
+	if interval == 0 {
+		interval = 10 * time.Second
+	}
+	if remaining := interval - time.Since(r.lastStatusPatch); remaining > 0 {
+		log.V(1).Info("hypervisor status patched recently, delaying patch", "remaining", remaining)
+		return remaining, nil
+	}
+
+	if err := r.applyStatus(ctx, hypervisor); err != nil {
+		return 0, err
+	}
+	r.lastStatusPatch = time.Now()
+	log.V(1).Info("patched hypervisor status", "hash", hash)
+	return 0, nil
+}
+
+// Name of the field manager of the status fields owned by this agent.
+func fieldManager() string {
+	return "kvm-node-agent/" + sys.Hostname
+}
+
+// Status fields written by the openstack-hypervisor-operator, which are
+// left out when applying the status of the agent.
+var operatorStatusFields = []string{
+	"hypervisorId", "serviceId", "traits", "aggregates", "internalIp", "evicted", "specHash",
+}
+
+// (Re)connect to libvirt, traced as the connect may block on socket activation.
+func (r *HypervisorReconciler) connectLibvirt(ctx context.Context) error {
+	_, span := tracing.Start(ctx, "libvirt.Connect")
+	err := r.Libvirt.Connect()
+	tracing.End(span, err)
+	return err
+}
+
+// Get the uris of the libvirt drivers, the first one is the primary driver.
+func (r *HypervisorReconciler) libvirtURIs() []string {
+	if len(r.LibvirtURIs) == 0 {
+		return []string{libvirt.DefaultURI()}
+	}
+	return r.LibvirtURIs
+}
+
+// Systemd units reported as conditions of the hypervisor, the units of the
+// libvirt daemons and the default units followed by the configured ones.
+func (r *HypervisorReconciler) unitNames() []string {
+	var units []string
+	for _, uri := range r.libvirtURIs() {
+		for _, unit := range r.LibvirtDaemons.Units(uri) {
+			if !slices.Contains(units, unit) {
+				units = append(units, unit)
+			}
 		}
-		err := r.Libvirt.Connect()
-		if err == nil {
-			log.Info("connected to libvirt")
-			break // Connected successfully
+	}
+	units = append(units, defaultUnitNames...)
+	for _, unit := range slices.Concat(r.Units, r.RuntimeConfig.Get().Units) {
+		if !slices.Contains(units, unit) {
+			units = append(units, unit)
 		}
-		log.Error(err, "unable to connect to libvirt")
-		// Set the hypervisor's LibVirtType condition to false with the
-		// error message, so that it's visible in the status.
-		base := hypervisor.DeepCopy()
+	}
+	return units
+}
+
+// Restart the units requested by the annotation of the hypervisor and record
+// the outcome in a condition. Only units reported as conditions can be
+// restarted. The annotation is removed before restarting, so that a restart
+// taking down the agent isn't repeated.
+func (r *HypervisorReconciler) reconcileUnitActions(ctx context.Context, hypervisor, base *kvmv1.Hypervisor) error {
+	value, ok := hypervisor.Annotations[RestartUnitsAnnotation]
+	if !ok {
+		return nil
+	}
+	log := logger.FromContext(ctx)
+
+	patched := base.DeepCopy()
+	delete(patched.Annotations, RestartUnitsAnnotation)
+	if err := r.Patch(ctx, patched, client.MergeFrom(base)); err != nil {
+		return err
+	}
+	delete(hypervisor.Annotations, RestartUnitsAnnotation)
+
+	var restarted, failed []string
+	for _, unit := range strings.Split(value, ",") {
+		if unit = strings.TrimSpace(unit); unit == "" {
+			continue
+		}
+		if !slices.Contains(r.unitNames(), unit) {
+			failed = append(failed, unit+": not managed by the agent")
+			continue
+		}
+		log.Info("restarting unit", "unit", unit)
+		restartCtx, cancel := context.WithTimeout(ctx, 2*time.Minute)
+		result, err := r.Systemd.RestartUnit(restartCtx, unit)
+		cancel()
+		switch {
+		case err != nil:
+			failed = append(failed, fmt.Sprintf("%s: %v", unit, err))
+		case result != "done":
+			failed = append(failed, fmt.Sprintf("%s: restart %s", unit, result))
+		default:
+			restarted = append(restarted, unit)
+		}
+	}
+
+	if len(failed) > 0 {
+		log.Info("unable to restart units", "failed", failed)
 		meta.SetStatusCondition(&hypervisor.Status.Conditions, metav1.Condition{
-			Type:    LibVirtType, // TODO: This should be a kvmv1 condition.
+			Type:    UnitActionType,
 			Status:  metav1.ConditionFalse,
-			Message: fmt.Sprintf("unable to connect to libvirt: %v", err),
-			Reason:  "ConnectFailed",
+			Reason:  "RestartFailed",
+			Message: strings.Join(failed, ", "),
 		})
-		patch := client.MergeFromWithOptions(base, client.MergeFromWithOptimisticLock{})
-		if err := r.Status().Patch(ctx, &hypervisor, patch); err != nil {
-			log.Error(err, "unable to update hypervisor status after failed libvirt connection")
+		return nil
+	}
+	meta.SetStatusCondition(&hypervisor.Status.Conditions, metav1.Condition{
+		Type:    UnitActionType,
+		Status:  metav1.ConditionTrue,
+		Reason:  "Restarted",
+		Message: "restarted " + strings.Join(restarted, ", "),
+	})
+	return nil
+}
+
+// Check if the condition type is written by the agent. Conditions of other
+// types belong to the openstack-hypervisor-operator.
+func (r *HypervisorReconciler) isAgentCondition(conditionType string) bool {
+	switch conditionType {
+	case LibVirtType, OSUpdateType, NFDType, OVSType, PolicyType, DriftType, EntropyType, RebootType, ConfigType,
+		SysctlType, CPUType, UnitActionType, RebootPendingType, BootType, ImageType, IOMMUType,
+		CapacityType, InhibitType, DegradedType, DiskPressureType, HostStorageType, LeftoversType, MemoryType,
+		KSMType, RuntimeConfigType, DeletionType:
+		return true
+	}
+	if strings.HasPrefix(conditionType, libvirt.DriverConditionPrefix) {
+		return true
+	}
+	return slices.Contains(r.unitNames(), conditionType)
+}
+
+// Apply the status fields owned by the agent with server-side apply, so that
+// the fields and conditions of the openstack-hypervisor-operator are neither
+// overwritten nor rejected because of a stale resource version.
+func (r *HypervisorReconciler) applyStatus(ctx context.Context, hypervisor *kvmv1.Hypervisor) error {
+	status, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&hypervisor.Status)
+	if err != nil {
+		return fmt.Errorf("unable to convert hypervisor status: %w", err)
+	}
+	for _, field := range operatorStatusFields {
+		delete(status, field)
+	}
+	var conditions []any
+	for _, condition := range hypervisor.Status.Conditions {
+		if !r.isAgentCondition(condition.Type) {
+			continue
 		}
-		log.Info("updated hypervisor status after failed libvirt connection")
-		timeToSleep := r.libvirtConnectInterval
-		if timeToSleep == 0 {
-			timeToSleep = 5 * time.Second // default value
+		converted, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&condition)
+		if err != nil {
+			return fmt.Errorf("unable to convert condition %s: %w", condition.Type, err)
 		}
-		log.Info("retrying libvirt connection after sleeping", "duration", timeToSleep)
-		time.Sleep(timeToSleep)
+		conditions = append(conditions, converted)
 	}
+	status["conditions"] = conditions
+	pruneNil(status)
 
-	// Run a ticker which reconciles the hypervisor resource every minute.
-	// This ensures that we periodically reconcile the hypervisor even
-	// if no events are received from libvirt.
-	go func() {
-		ticker := time.NewTicker(1 * time.Minute)
-		defer ticker.Stop()
-		for {
-			select {
-			case <-ticker.C:
-				r.triggerReconcile()
-			case <-ctx.Done():
-				return
+	obj := &unstructured.Unstructured{Object: map[string]any{"status": status}}
+	obj.SetAPIVersion(kvmv1.GroupVersion.String())
+	obj.SetKind("Hypervisor")
+	obj.SetName(hypervisor.Name)
+
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		return r.Status().Apply(ctx, client.ApplyConfigurationFromUnstructured(obj),
+			client.FieldOwner(fieldManager()), client.ForceOwnership)
+	})
+}
+
+// Remove nil values, which are not allowed in an apply configuration.
+func pruneNil(obj map[string]any) {
+	for key, value := range obj {
+		switch value := value.(type) {
+		case nil:
+			delete(obj, key)
+		case map[string]any:
+			pruneNil(value)
+		}
+	}
+}
+
+// Produce or consume the node-feature-discovery labels depending on the
+// configured mode and reflect the outcome in the hypervisor conditions.
+func (r *HypervisorReconciler) reconcileNodeFeatureDiscovery(ctx context.Context, hypervisor *kvmv1.Hypervisor) {
+	log := logger.FromContext(ctx)
+
+	var features nfd.Features
+	var reason string
+	switch r.NodeFeatureDiscovery {
+	case nfd.ModeProduce:
+		found, err := nfd.NewSystemReader().Read()
+		if err == nil {
+			features = *found
+			_, err = nfd.WriteFeatureFile(r.featureFilePath(), features)
+		}
+		if err != nil {
+			log.Error(err, "unable to produce node-feature-discovery labels")
+			meta.SetStatusCondition(&hypervisor.Status.Conditions, metav1.Condition{
+				Type:    NFDType,
+				Status:  metav1.ConditionFalse,
+				Reason:  "ProduceFailed",
+				Message: err.Error(),
+			})
+			return
+		}
+		reason = "Produced"
+	case nfd.ModeConsume:
+		// NFD already discovered the features, so read them from the node
+		// instead of discovering them again. The node cache of the manager
+		// only holds the own node, see the cache options in main.
+		var node corev1.Node
+		if err := r.Get(ctx, client.ObjectKey{Name: sys.Hostname}, &node); err != nil {
+			log.Error(err, "unable to get node for node-feature-discovery labels")
+			meta.SetStatusCondition(&hypervisor.Status.Conditions, metav1.Condition{
+				Type:    NFDType,
+				Status:  metav1.ConditionFalse,
+				Reason:  "ConsumeFailed",
+				Message: err.Error(),
+			})
+			return
+		}
+		if features = nfd.FromLabels(node.Labels); features.Empty() {
+			meta.SetStatusCondition(&hypervisor.Status.Conditions, metav1.Condition{
+				Type:    NFDType,
+				Status:  metav1.ConditionFalse,
+				Reason:  "LabelsMissing",
+				Message: "no node-feature-discovery labels found on node " + node.Name,
+			})
+			return
+		}
+		reason = "Consumed"
+	default:
+		meta.RemoveStatusCondition(&hypervisor.Status.Conditions, NFDType)
+		return
+	}
+
+	iommu := "disabled"
+	if features.IOMMUEnabled {
+		iommu = "enabled"
+	}
+	meta.SetStatusCondition(&hypervisor.Status.Conditions, metav1.Condition{
+		Type:   NFDType,
+		Status: metav1.ConditionTrue,
+		Reason: reason,
+		Message: fmt.Sprintf("kernel %s, iommu %s, %d cpu features",
+			features.KernelRelease, iommu, len(features.CPUFeatures)),
+	})
+}
+
+// Report the leftovers of crashed domains on the host, e.g. vhost-user
+// sockets and qemu state directories, and remove them in enforce mode.
+func (r *HypervisorReconciler) reconcileLeftovers(ctx context.Context, hypervisor *kvmv1.Hypervisor) {
+	if r.Janitor == nil || r.JanitorMode == "" || r.JanitorMode == libvirt.DomainPolicyOff {
+		meta.RemoveStatusCondition(&hypervisor.Status.Conditions, LeftoversType)
+		return
+	}
+	if !meta.IsStatusConditionTrue(hypervisor.Status.Conditions, LibVirtType) {
+		// Without the running domains everything looks like a leftover.
+		return
+	}
+	log := logger.FromContext(ctx)
+
+	// Open vSwitch serves the sockets of its dpdkvhostuser interfaces
+	// itself, they are no leftovers even without a domain.
+	var ovsSockets []string
+	if r.OVS != nil {
+		status, err := r.OVS.Status(ctx)
+		switch {
+		case errors.Is(err, ovs.ErrNotPresent):
+		case err != nil:
+			log.Error(err, "unable to read open vswitch status")
+			meta.SetStatusCondition(&hypervisor.Status.Conditions, metav1.Condition{
+				Type:    LeftoversType,
+				Status:  metav1.ConditionUnknown,
+				Reason:  "CheckFailed",
+				Message: err.Error(),
+			})
+			return
+		default:
+			for _, port := range status.Ports {
+				if port.Type == "dpdkvhostuser" {
+					ovsSockets = append(ovsSockets, port.Name)
+				}
 			}
 		}
-	}()
+	}
 
-	// Domain lifecycle events impact the list of active/inactive domains,
-	// as well as the allocation of resources on the hypervisor.
-	r.Libvirt.WatchDomainChanges(
-		golibvirt.DomainEventIDLifecycle,
-		"reconcile-on-domain-lifecycle",
-		func(_ context.Context, _ any) { r.triggerReconcile() },
-	)
+	dryRun := r.JanitorMode == libvirt.DomainPolicyDryRun
+	leftovers, err := r.Janitor.CleanLeftovers(dryRun, ovsSockets)
+	if err != nil {
+		log.Error(err, "unable to clean up leftovers of crashed domains")
+		meta.SetStatusCondition(&hypervisor.Status.Conditions, metav1.Condition{
+			Type:    LeftoversType,
+			Status:  metav1.ConditionUnknown,
+			Reason:  "CheckFailed",
+			Message: err.Error(),
+		})
+		return
+	}
+	if len(leftovers) == 0 {
+		meta.SetStatusCondition(&hypervisor.Status.Conditions, metav1.Condition{
+			Type:    LeftoversType,
+			Status:  metav1.ConditionFalse,
+			Reason:  "NoLeftovers",
+			Message: "no leftovers of crashed domains found",
+		})
+		return
+	}
 
-	return nil
+	// Tap devices are never removed by the agent, the other leftovers only
+	// once they were found on consecutive passes.
+	remaining := leftovers
+	if !dryRun {
+		var removed []libvirt.Leftover
+		remaining = nil
+		for _, leftover := range leftovers {
+			if leftover.Removed {
+				removed = append(removed, leftover)
+			} else {
+				remaining = append(remaining, leftover)
+			}
+		}
+		if len(removed) > 0 {
+			log.Info("removed leftovers of crashed domains", "leftovers", len(removed))
+		}
+		if len(remaining) == 0 {
+			meta.SetStatusCondition(&hypervisor.Status.Conditions, metav1.Condition{
+				Type:    LeftoversType,
+				Status:  metav1.ConditionFalse,
+				Reason:  "Removed",
+				Message: "removed " + summarize(removed),
+			})
+			return
+		}
+	}
+	log.Info("found leftovers of crashed domains", "leftovers", len(remaining))
+	meta.SetStatusCondition(&hypervisor.Status.Conditions, metav1.Condition{
+		Type:    LeftoversType,
+		Status:  metav1.ConditionTrue,
+		Reason:  "LeftoversFound",
+		Message: summarize(remaining),
+	})
 }
 
-// SetupWithManager sets up the controller with the Manager.
-func (r *HypervisorReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	ctx := context.Background()
+// Report domains which have autostart enabled or a managed save image, and
+// fix them if the policy is enforced.
+func (r *HypervisorReconciler) reconcileDomainPolicy(ctx context.Context, hypervisor *kvmv1.Hypervisor) {
+	if r.DomainPolicy == nil || r.DomainPolicyMode == "" || r.DomainPolicyMode == libvirt.DomainPolicyOff {
+		meta.RemoveStatusCondition(&hypervisor.Status.Conditions, PolicyType)
+		return
+	}
+	if !meta.IsStatusConditionTrue(hypervisor.Status.Conditions, LibVirtType) {
+		// Keep the last known state until libvirt is back.
+		return
+	}
+	log := logger.FromContext(ctx)
 
-	var err error
-	if r.osDescriptor, err = r.Systemd.Describe(ctx); err != nil {
-		return fmt.Errorf("unable to get Systemd hostname describe(): %w", err)
+	dryRun := r.DomainPolicyMode == libvirt.DomainPolicyDryRun
+	violations, err := r.DomainPolicy.EnforceDomainPolicy(dryRun, r.ManagedSaveMaxAge)
+	if err != nil {
+		log.Error(err, "unable to enforce domain policy")
+		meta.SetStatusCondition(&hypervisor.Status.Conditions, metav1.Condition{
+			Type:    PolicyType,
+			Status:  metav1.ConditionFalse,
+			Reason:  "CheckFailed",
+			Message: err.Error(),
+		})
+		return
+	}
+	if len(violations) == 0 {
+		meta.SetStatusCondition(&hypervisor.Status.Conditions, metav1.Condition{
+			Type:    PolicyType,
+			Status:  metav1.ConditionTrue,
+			Reason:  "Compliant",
+			Message: "no domain has autostart enabled or a managed save image",
+		})
+		return
 	}
 
-	if r.KernelReader == nil {
-		r.KernelReader = kernel.NewSystemReader()
+	summary := summarize(violations)
+	if dryRun {
+		log.Info("domains violate the domain policy", "violations", len(violations))
+		meta.SetStatusCondition(&hypervisor.Status.Conditions, metav1.Condition{
+			Type:    PolicyType,
+			Status:  metav1.ConditionFalse,
+			Reason:  "ViolationsFound",
+			Message: summary,
+		})
+		return
 	}
-	if r.kernelParameters, err = r.KernelReader.ReadParameters(); err != nil {
-		return fmt.Errorf("unable to read kernel parameters: %w", err)
+	log.Info("fixed domains violating the domain policy", "violations", len(violations))
+	var kept []libvirt.DomainPolicyViolation
+	for _, violation := range violations {
+		if !violation.Fixable() {
+			kept = append(kept, violation)
+		}
+	}
+	if len(kept) > 0 {
+		meta.SetStatusCondition(&hypervisor.Status.Conditions, metav1.Condition{
+			Type:    PolicyType,
+			Status:  metav1.ConditionFalse,
+			Reason:  "ManagedSaveKept",
+			Message: "fixed " + summary + "; kept the managed save images of suspended domains",
+		})
+		return
 	}
+	meta.SetStatusCondition(&hypervisor.Status.Conditions, metav1.Condition{
+		Type:    PolicyType,
+		Status:  metav1.ConditionTrue,
+		Reason:  "Enforced",
+		Message: "fixed " + summary,
+	})
+}
 
-	// Prepare an event channel that will trigger a reconcile event.
-	r.reconcileCh = make(chan event.GenericEvent)
-	src := source.Channel(r.reconcileCh, &handler.EnqueueRequestForObject{})
-	// Run the Start(ctx context.Context) method when the manager starts.
-	if err := mgr.Add(r); err != nil {
-		return err
+// Report running domains whose live definition drifted from the persistent
+// one, e.g. because devices were hot-plugged or memory was changed by hand.
+func (r *HypervisorReconciler) reconcileDomainDrift(ctx context.Context, hypervisor *kvmv1.Hypervisor) {
+	if r.DomainDrift == nil {
+		meta.RemoveStatusCondition(&hypervisor.Status.Conditions, DriftType)
+		return
+	}
+	if !meta.IsStatusConditionTrue(hypervisor.Status.Conditions, LibVirtType) {
+		// Keep the last known state until libvirt is back.
+		return
 	}
+	log := logger.FromContext(ctx)
 
-	return ctrl.NewControllerManagedBy(mgr).
-		For(&kvmv1.Hypervisor{}).
-		WatchesRawSource(src).
-		Complete(r)
+	drifts, err := r.DomainDrift.DetectDomainDrift()
+	if err != nil {
+		log.Error(err, "unable to detect domain drift")
+		meta.SetStatusCondition(&hypervisor.Status.Conditions, metav1.Condition{
+			Type:    DriftType,
+			Status:  metav1.ConditionFalse,
+			Reason:  "CheckFailed",
+			Message: err.Error(),
+		})
+		return
+	}
+	if len(drifts) == 0 {
+		meta.SetStatusCondition(&hypervisor.Status.Conditions, metav1.Condition{
+			Type:    DriftType,
+			Status:  metav1.ConditionTrue,
+			Reason:  "InSync",
+			Message: "live definitions of all domains match their persistent definitions",
+		})
+		return
+	}
+	log.Info("live domain definitions drifted", "domains", len(drifts))
+	meta.SetStatusCondition(&hypervisor.Status.Conditions, metav1.Condition{
+		Type:    DriftType,
+		Status:  metav1.ConditionFalse,
+		Reason:  "DriftDetected",
+		Message: summarize(drifts),
+	})
+}
+
+// Report whether an ephemeral disk of the domains or the filesystem holding
+// them crossed the watermark, before the guests see io errors. An event is
+// emitted once the watermark is crossed.
+func (r *HypervisorReconciler) reconcileDiskPressure(ctx context.Context, hypervisor *kvmv1.Hypervisor) {
+	if r.DiskUsage == nil || r.DiskWatermark <= 0 {
+		meta.RemoveStatusCondition(&hypervisor.Status.Conditions, DiskPressureType)
+		return
+	}
+	if !meta.IsStatusConditionTrue(hypervisor.Status.Conditions, LibVirtType) {
+		// Keep the last known state until libvirt is back.
+		return
+	}
+	log := logger.FromContext(ctx)
+
+	disks, filesystem, err := r.DiskUsage.InstanceDiskUsage()
+	if err != nil {
+		log.Error(err, "unable to get disk usage")
+		meta.SetStatusCondition(&hypervisor.Status.Conditions, metav1.Condition{
+			Type:    DiskPressureType,
+			Status:  metav1.ConditionUnknown,
+			Reason:  "CheckFailed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	var full []libvirt.DiskUsage
+	for _, disk := range disks {
+		if disk.Ratio() >= r.DiskWatermark {
+			full = append(full, disk)
+		}
+	}
+	var reason, message string
+	switch {
+	case filesystem.Ratio() >= r.DiskWatermark:
+		reason, message = "FilesystemAboveWatermark", filesystem.String()
+		if len(full) > 0 {
+			message += ", disks " + summarize(full)
+		}
+	case len(full) > 0:
+		reason, message = "DisksAboveWatermark", summarize(full)
+	default:
+		meta.SetStatusCondition(&hypervisor.Status.Conditions, metav1.Condition{
+			Type:   DiskPressureType,
+			Status: metav1.ConditionFalse,
+			Reason: "BelowWatermark",
+			Message: fmt.Sprintf("%d disks and the filesystem are below %.0f%% usage",
+				len(disks), r.DiskWatermark*100),
+		})
+		return
+	}
+
+	crossed := !meta.IsStatusConditionTrue(hypervisor.Status.Conditions, DiskPressureType)
+	meta.SetStatusCondition(&hypervisor.Status.Conditions, metav1.Condition{
+		Type:    DiskPressureType,
+		Status:  metav1.ConditionTrue,
+		Reason:  reason,
+		Message: message,
+	})
+	if crossed {
+		log.Info("disk usage above watermark", "reason", reason, "usage", message)
+		if r.Recorder != nil {
+			r.Recorder.Eventf(hypervisor, nil, corev1.EventTypeWarning, reason, "CheckDiskUsage", "%s", message)
+		}
+	}
+}
+
+// Report critical memory pressure of the host, and perform the next of the
+// mitigations while it lasts, before the kernel kills domains. The
+// mitigations performed are listed in the condition. Once the pressure
+// ends, the instances paused and ballooned by the agent are restored.
+func (r *HypervisorReconciler) reconcileMemoryPressure(ctx context.Context, hypervisor *kvmv1.Hypervisor) {
+	if r.MemoryPressure == nil || r.MemoryPressureMode == "" || r.MemoryPressureMode == libvirt.DomainPolicyOff {
+		meta.RemoveStatusCondition(&hypervisor.Status.Conditions, MemoryType)
+		return
+	}
+	log := logger.FromContext(ctx)
+
+	pressure, err := r.MemoryPressure.ReadPressure()
+	if err != nil {
+		log.Error(err, "unable to read memory pressure")
+		meta.SetStatusCondition(&hypervisor.Status.Conditions, metav1.Condition{
+			Type:    MemoryType,
+			Status:  metav1.ConditionUnknown,
+			Reason:  "CheckFailed",
+			Message: err.Error(),
+		})
+		return
+	}
+	if !pressure.Critical(r.MemoryPressureThresholds) {
+		if r.memoryMitigationStep > 0 {
+			log.Info("memory pressure resolved", "mitigations", r.memoryMitigated)
+		}
+		r.memoryMitigationStep, r.memoryMitigated, r.memoryMitigatedAt = 0, nil, time.Time{}
+		message := pressure.String()
+		if restored := r.restoreMemoryMitigations(ctx); len(restored) > 0 {
+			log.Info("restored instances after memory pressure", "restored", restored)
+			message += "; " + summarize(restored)
+		}
+		meta.SetStatusCondition(&hypervisor.Status.Conditions, metav1.Condition{
+			Type:    MemoryType,
+			Status:  metav1.ConditionFalse,
+			Reason:  "BelowThreshold",
+			Message: message,
+		})
+		return
+	}
+
+	if r.memoryMitigationStep == 0 {
+		log.Info("memory pressure critical", "pressure", pressure.String())
+	}
+	if time.Since(r.memoryMitigatedAt) >= memoryMitigationInterval {
+		if len(r.MemoryMitigations) > 0 {
+			mitigation := r.MemoryMitigations[min(r.memoryMitigationStep, len(r.MemoryMitigations)-1)]
+			mitigated, err := r.mitigateMemoryPressure(ctx, hypervisor, mitigation, pressure)
+			if err != nil {
+				log.Error(err, "unable to mitigate memory pressure", "mitigation", mitigation)
+				mitigated = fmt.Sprintf("%s failed: %s", mitigation, err)
+			}
+			if mitigated != "" {
+				log.Info("mitigated memory pressure", "mitigation", mitigated)
+				r.memoryMitigated = append(r.memoryMitigated, mitigated)
+			}
+		}
+		r.memoryMitigationStep++
+		r.memoryMitigatedAt = time.Now()
+	}
+
+	message := pressure.String()
+	if len(r.memoryMitigated) > 0 {
+		message += "; " + summarize(r.memoryMitigated)
+	}
+	meta.SetStatusCondition(&hypervisor.Status.Conditions, metav1.Condition{
+		Type:    MemoryType,
+		Status:  metav1.ConditionTrue,
+		Reason:  "Critical",
+		Message: message,
+	})
+}
+
+// Perform a mitigation of critical memory pressure, and describe what was
+// done. Empty if there was nothing to do.
+func (r *HypervisorReconciler) mitigateMemoryPressure(
+	ctx context.Context, hypervisor *kvmv1.Hypervisor, mitigation memory.Mitigation, pressure *memory.Pressure,
+) (string, error) {
+	if mitigation == memory.MitigationAlert {
+		if r.Recorder != nil {
+			r.Recorder.Eventf(hypervisor, nil, corev1.EventTypeWarning, "MemoryPressure", "CheckMemoryPressure",
+				"memory pressure critical: %s", pressure)
+		}
+		return "alerted", nil
+	}
+	if r.MemoryReclaimer == nil {
+		return "", nil
+	}
+
+	var instances v1alpha1.InstanceList
+	if err := r.List(ctx, &instances,
+		client.InNamespace(sys.Namespace),
+		client.MatchingLabels{v1alpha1.LabelHypervisor: sys.NodeLabelName},
+	); err != nil {
+		return "", err
+	}
+	// The instances already paused or ballooned by the agent are left
+	// alone, the status of the instances may not show it yet.
+	candidates := slices.DeleteFunc(memoryReclaimCandidates(instances.Items), func(instance v1alpha1.Instance) bool {
+		_, ballooned := r.memoryBallooned[instance.Name]
+		return slices.Contains(r.memoryPaused, instance.Name) ||
+			(ballooned && mitigation == memory.MitigationBalloon)
+	})
+	if len(candidates) == 0 {
+		return "", nil
+	}
+	dryRun := r.MemoryPressureMode == libvirt.DomainPolicyDryRun
+	describe := func(verb string, names ...string) string {
+		if dryRun {
+			verb = "would have " + verb
+		}
+		return verb + " " + strings.Join(names, ", ")
+	}
+
+	switch mitigation {
+	case memory.MitigationBalloon:
+		var ballooned []string
+		var errs []error
+		for _, instance := range candidates {
+			target := uint64(float64(instance.Status.Flavor.MemoryMB<<10) * memoryBalloonRatio)
+			if target == 0 {
+				continue
+			}
+			if !dryRun {
+				if err := r.MemoryReclaimer.BalloonDomain(instance.Name, target); err != nil {
+					errs = append(errs, err)
+					continue
+				}
+				if r.memoryBallooned == nil {
+					r.memoryBallooned = make(map[string]uint64)
+				}
+				r.memoryBallooned[instance.Name] = uint64(instance.Status.Flavor.MemoryMB << 10)
+			}
+			ballooned = append(ballooned, instance.Name)
+		}
+		if len(ballooned) == 0 {
+			return "", errors.Join(errs...)
+		}
+		return describe("ballooned", ballooned...), errors.Join(errs...)
+	case memory.MitigationPause:
+		instance := candidates[0]
+		if !dryRun {
+			if err := r.MemoryReclaimer.SuspendDomain(instance.Name); err != nil {
+				return "", err
+			}
+			r.memoryPaused = append(r.memoryPaused, instance.Name)
+		}
+		return describe("paused", instance.Name), nil
+	}
+	return "", fmt.Errorf("unknown mitigation %q", mitigation)
+}
+
+// Resume the instances paused and deflate the balloons of the instances
+// ballooned on the memory pressure, and describe what was done. Failures
+// are retried on the next reconcile, unless the domain is gone.
+func (r *HypervisorReconciler) restoreMemoryMitigations(ctx context.Context) []string {
+	if r.MemoryReclaimer == nil {
+		return nil
+	}
+	log := logger.FromContext(ctx)
+	// Not running is only reported after checking the state of the domain,
+	// other invalid operations are retried.
+	gone := func(err error) bool {
+		return errors.Is(err, virterr.ErrDomainNotFound) || errors.Is(err, virterr.ErrNotRunning)
+	}
+
+	var resumed, deflated, paused []string
+	for _, name := range r.memoryPaused {
+		err := r.MemoryReclaimer.ResumeDomain(name)
+		switch {
+		case err == nil:
+			resumed = append(resumed, name)
+		case gone(err):
+			log.Info("instance paused on memory pressure is gone", "instance", name)
+		default:
+			log.Error(err, "unable to resume instance paused on memory pressure", "instance", name)
+			paused = append(paused, name)
+		}
+	}
+	r.memoryPaused = paused
+	for _, name := range slices.Sorted(maps.Keys(r.memoryBallooned)) {
+		err := r.MemoryReclaimer.BalloonDomain(name, r.memoryBallooned[name])
+		switch {
+		case err == nil:
+			deflated = append(deflated, name)
+		case gone(err):
+			log.Info("instance ballooned on memory pressure is gone", "instance", name)
+		default:
+			log.Error(err, "unable to deflate balloon of instance", "instance", name)
+			continue
+		}
+		delete(r.memoryBallooned, name)
+	}
+
+	var restored []string
+	if len(resumed) > 0 {
+		restored = append(restored, "resumed "+strings.Join(resumed, ", "))
+	}
+	if len(deflated) > 0 {
+		restored = append(restored, "deflated "+strings.Join(deflated, ", "))
+	}
+	return restored
+}
+
+// Select the running instances memory can be reclaimed from, the ones with
+// the lowest memory priority first. Instances without a valid priority
+// and paused instances are skipped.
+func memoryReclaimCandidates(instances []v1alpha1.Instance) []v1alpha1.Instance {
+	type candidate struct {
+		instance v1alpha1.Instance
+		priority int
+	}
+	var candidates []candidate
+	for _, instance := range instances {
+		priority, err := strconv.Atoi(instance.Annotations[MemoryPriorityAnnotation])
+		if err != nil || !instance.Status.Active {
+			continue
+		}
+		if pauses := instance.Status.Pauses; pauses != nil && pauses.Since != nil {
+			continue
+		}
+		candidates = append(candidates, candidate{instance, priority})
+	}
+	slices.SortStableFunc(candidates, func(a, b candidate) int {
+		if a.priority != b.priority {
+			return a.priority - b.priority
+		}
+		return strings.Compare(a.instance.Name, b.instance.Name)
+	})
+	selected := make([]v1alpha1.Instance, 0, len(candidates))
+	for _, c := range candidates {
+		selected = append(selected, c.instance)
+	}
+	return selected
+}
+
+// Report the host paths running out of space or inodes, which fails
+// migrations and snapshots.
+func (r *HypervisorReconciler) reconcileHostStorage(ctx context.Context, hypervisor *kvmv1.Hypervisor) {
+	if r.HostStorage == nil {
+		meta.RemoveStatusCondition(&hypervisor.Status.Conditions, HostStorageType)
+		return
+	}
+	log := logger.FromContext(ctx)
+
+	usages, err := r.HostStorage.ReadUsage()
+	hoststorage.UpdateMetrics(usages)
+	var low []hoststorage.Usage
+	for _, usage := range usages {
+		if usage.Low() {
+			low = append(low, usage)
+		}
+	}
+	switch {
+	case len(low) > 0:
+		log.Info("host storage running out", "paths", summarize(low))
+		meta.SetStatusCondition(&hypervisor.Status.Conditions, metav1.Condition{
+			Type:    HostStorageType,
+			Status:  metav1.ConditionFalse,
+			Reason:  "LowStorage",
+			Message: summarize(low),
+		})
+	case err != nil:
+		log.Error(err, "unable to read host storage usage")
+		meta.SetStatusCondition(&hypervisor.Status.Conditions, metav1.Condition{
+			Type:    HostStorageType,
+			Status:  metav1.ConditionFalse,
+			Reason:  "ReadFailed",
+			Message: err.Error(),
+		})
+	default:
+		// The usage is left out, so that the status doesn't change with
+		// every write to the paths.
+		meta.SetStatusCondition(&hypervisor.Status.Conditions, metav1.Condition{
+			Type:   HostStorageType,
+			Status: metav1.ConditionTrue,
+			Reason: "Sufficient",
+			Message: fmt.Sprintf("%d paths have at least %.0f%% of the space and inodes free",
+				len(usages), hoststorage.LowFreeRatio*100),
+		})
+	}
+}
+
+// Join the items for a condition message, keeping the message short on
+// hosts with many items.
+func summarize[T any](items []T) string {
+	const maxListed = 10
+	summaries := make([]string, 0, maxListed+1)
+	for i, item := range items {
+		if i == maxListed {
+			summaries = append(summaries, fmt.Sprintf("and %d more", len(items)-maxListed))
+			break
+		}
+		summaries = append(summaries, fmt.Sprint(item))
+	}
+	return strings.Join(summaries, "; ")
+}
+
+// Report the datapath configuration of Open vSwitch and check that it
+// can serve the interface types the domains on this host expect. The
+// datapath is published in the annotations of the hypervisor.
+func (r *HypervisorReconciler) reconcileOVS(ctx context.Context, hypervisor, base *kvmv1.Hypervisor) error {
+	if r.OVS == nil {
+		return nil
+	}
+	log := logger.FromContext(ctx)
+
+	status, err := r.OVS.Status(ctx)
+	ovs.UpdateMetrics(status)
+	if errors.Is(err, ovs.ErrNotPresent) {
+		meta.RemoveStatusCondition(&hypervisor.Status.Conditions, OVSType)
+		return nil
+	}
+	if err != nil {
+		log.Error(err, "unable to read open vswitch status")
+		meta.SetStatusCondition(&hypervisor.Status.Conditions, metav1.Condition{
+			Type:    OVSType,
+			Status:  metav1.ConditionFalse,
+			Reason:  "ReadFailed",
+			Message: err.Error(),
+		})
+		return nil
+	}
+	if err := r.patchAnnotations(ctx, hypervisor, base, map[string]string{
+		OVSDPDKAnnotation:        strconv.FormatBool(status.DPDKInitialized),
+		OVSDPDKVersionAnnotation: status.DPDKVersion,
+		OVSHWOffloadAnnotation:   strconv.FormatBool(status.HWOffload),
+	}); err != nil {
+		return err
+	}
+
+	var instances v1alpha1.InstanceList
+	if err := r.List(ctx, &instances,
+		client.InNamespace(sys.Namespace),
+		client.MatchingLabels{v1alpha1.LabelHypervisor: sys.NodeLabelName},
+	); err != nil {
+		log.Error(err, "unable to list instances")
+		meta.SetStatusCondition(&hypervisor.Status.Conditions, metav1.Condition{
+			Type:    OVSType,
+			Status:  metav1.ConditionUnknown,
+			Reason:  "CheckFailed",
+			Message: fmt.Sprintf("unable to list instances: %v", err),
+		})
+		return nil
+	}
+	var vhostUser []string
+	for _, instance := range instances.Items {
+		for _, iface := range instance.Status.Devices.Interfaces {
+			if iface.Type == "vhostuser" {
+				vhostUser = append(vhostUser, instance.Name)
+				break
+			}
+		}
+	}
+
+	if len(vhostUser) > 0 && !status.SupportsVhostUser() {
+		meta.SetStatusCondition(&hypervisor.Status.Conditions, metav1.Condition{
+			Type:   OVSType,
+			Status: metav1.ConditionFalse,
+			Reason: "DPDKNotConfigured",
+			Message: fmt.Sprintf("%d domains expect vhost-user interfaces, but open vswitch has no dpdk datapath (%s)",
+				len(vhostUser), status),
+		})
+		return nil
+	}
+	orphaned, down := ovsPortProblems(status, instances.Items)
+	if len(orphaned) > 0 {
+		meta.SetStatusCondition(&hypervisor.Status.Conditions, metav1.Condition{
+			Type:   OVSType,
+			Status: metav1.ConditionFalse,
+			Reason: "OrphanedPorts",
+			Message: fmt.Sprintf("%d ports are plugged for domains not on this host: %s",
+				len(orphaned), summarize(orphaned)),
+		})
+		return nil
+	}
+	if len(down) > 0 {
+		meta.SetStatusCondition(&hypervisor.Status.Conditions, metav1.Condition{
+			Type:   OVSType,
+			Status: metav1.ConditionFalse,
+			Reason: "PortsDown",
+			Message: fmt.Sprintf("%d ports of running domains are down: %s",
+				len(down), summarize(down)),
+		})
+		return nil
+	}
+	meta.SetStatusCondition(&hypervisor.Status.Conditions, metav1.Condition{
+		Type:    OVSType,
+		Status:  metav1.ConditionTrue,
+		Reason:  "Configured",
+		Message: status.String(),
+	})
+	return nil
+}
+
+// Find the ports plugged for domains which have no interface on this host
+// anymore, e.g. after a failed unplug, and the ports of running domains
+// whose link is down. Ports are matched by the target device of the domain
+// interfaces, or the socket of vhost-user interfaces.
+func ovsPortProblems(status *ovs.Status, instances []v1alpha1.Instance) (orphaned, down []string) {
+	active := make(map[string]bool)
+	for _, instance := range instances {
+		for _, iface := range instance.Status.Devices.Interfaces {
+			if iface.Target != "" {
+				active[iface.Target] = instance.Status.Active
+			}
+			if iface.SocketPath != "" {
+				active[filepath.Base(iface.SocketPath)] = instance.Status.Active
+			}
+		}
+	}
+	for _, port := range status.Ports {
+		if !port.IsInstancePort() {
+			continue
+		}
+		running, ok := active[port.Name]
+		switch {
+		case !ok:
+			orphaned = append(orphaned, port.Name)
+		case running && port.LinkState == "down":
+			down = append(down, port.Name)
+		}
+	}
+	return orphaned, down
+}
+
+// Report if libvirt answers slowly or not at all while still connected.
+func (r *HypervisorReconciler) reconcileLibvirtHealth(ctx context.Context, hypervisor *kvmv1.Hypervisor) {
+	if r.ConnectionProber == nil || !meta.IsStatusConditionTrue(hypervisor.Status.Conditions, LibVirtType) {
+		return
+	}
+
+	health := r.ConnectionProber.ProbeConnection()
+	switch {
+	case !health.Degraded():
+		// The round trip is left out, so that the status doesn't change
+		// with every probe.
+		meta.SetStatusCondition(&hypervisor.Status.Conditions, metav1.Condition{
+			Type:    DegradedType,
+			Status:  metav1.ConditionFalse,
+			Reason:  "Responsive",
+			Message: "libvirt answers rpc calls",
+		})
+	case health.Err != nil:
+		logger.FromContext(ctx).Info("libvirt connection degraded", "health", health.String())
+		meta.SetStatusCondition(&hypervisor.Status.Conditions, metav1.Condition{
+			Type:    DegradedType,
+			Status:  metav1.ConditionTrue,
+			Reason:  "Unresponsive",
+			Message: health.String(),
+		})
+	default:
+		logger.FromContext(ctx).Info("libvirt connection degraded", "health", health.String())
+		meta.SetStatusCondition(&hypervisor.Status.Conditions, metav1.Condition{
+			Type:    DegradedType,
+			Status:  metav1.ConditionTrue,
+			Reason:  "SlowResponses",
+			Message: health.String(),
+		})
+	}
+}
+
+// Report the entropy sources of the host and the domains without a random
+// number generator device, which may hang at boot waiting for entropy. The
+// domains are reported whether or not the host has a hardware source.
+func (r *HypervisorReconciler) reconcileEntropy(ctx context.Context, hypervisor *kvmv1.Hypervisor) {
+	if r.Entropy == nil {
+		return
+	}
+	log := logger.FromContext(ctx)
+
+	sources, err := r.Entropy.ReadSources()
+	if err != nil {
+		log.Error(err, "unable to read entropy sources")
+		meta.SetStatusCondition(&hypervisor.Status.Conditions, metav1.Condition{
+			Type:    EntropyType,
+			Status:  metav1.ConditionFalse,
+			Reason:  "ReadFailed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	var instances v1alpha1.InstanceList
+	if err := r.List(ctx, &instances,
+		client.InNamespace(sys.Namespace),
+		client.MatchingLabels{v1alpha1.LabelHypervisor: sys.NodeLabelName},
+	); err != nil {
+		log.Error(err, "unable to list instances")
+		meta.SetStatusCondition(&hypervisor.Status.Conditions, metav1.Condition{
+			Type:    EntropyType,
+			Status:  metav1.ConditionUnknown,
+			Reason:  "CheckFailed",
+			Message: fmt.Sprintf("%s; unable to list instances: %v", sources, err),
+		})
+		return
+	}
+	var withoutRNG []string
+	for _, instance := range instances.Items {
+		if len(instance.Status.Devices.RNGs) == 0 {
+			withoutRNG = append(withoutRNG, instance.Name)
+		}
+	}
+
+	message := sources.String()
+	if len(withoutRNG) > 0 {
+		message = fmt.Sprintf("%s; %d domains have no rng device: %s",
+			sources, len(withoutRNG), summarize(withoutRNG))
+	}
+	if !sources.HasHardwareSource() {
+		meta.SetStatusCondition(&hypervisor.Status.Conditions, metav1.Condition{
+			Type:    EntropyType,
+			Status:  metav1.ConditionFalse,
+			Reason:  "NoHardwareSource",
+			Message: message,
+		})
+		return
+	}
+	if len(withoutRNG) > 0 {
+		meta.SetStatusCondition(&hypervisor.Status.Conditions, metav1.Condition{
+			Type:    EntropyType,
+			Status:  metav1.ConditionFalse,
+			Reason:  "DomainsWithoutRNG",
+			Message: message,
+		})
+		return
+	}
+	meta.SetStatusCondition(&hypervisor.Status.Conditions, metav1.Condition{
+		Type:    EntropyType,
+		Status:  metav1.ConditionTrue,
+		Reason:  "Available",
+		Message: sources.String(),
+	})
+}
+
+// Report if devices can be passed through to domains, so that hosts which
+// booted without the iommu enabled, e.g. without intel_iommu=on, or without
+// the vfio modules can be excluded from scheduling passthrough instances.
+func (r *HypervisorReconciler) reconcileIOMMU(ctx context.Context, hypervisor *kvmv1.Hypervisor) {
+	if r.IOMMU == nil {
+		return
+	}
+	log := logger.FromContext(ctx)
+
+	status, err := r.IOMMU.ReadStatus()
+	if err != nil {
+		log.Error(err, "unable to read iommu status")
+		meta.SetStatusCondition(&hypervisor.Status.Conditions, metav1.Condition{
+			Type:    IOMMUType,
+			Status:  metav1.ConditionFalse,
+			Reason:  "ReadFailed",
+			Message: err.Error(),
+		})
+		return
+	}
+	if r.HostIOMMU != nil && meta.IsStatusConditionTrue(hypervisor.Status.Conditions, LibVirtType) {
+		supported, err := r.HostIOMMU.HostIOMMUSupported()
+		if err != nil {
+			// Not critical, the iommu groups tell the same.
+			log.Error(err, "unable to get iommu support from libvirt")
+		} else if !supported {
+			meta.SetStatusCondition(&hypervisor.Status.Conditions, metav1.Condition{
+				Type:    IOMMUType,
+				Status:  metav1.ConditionFalse,
+				Reason:  "IOMMUDisabled",
+				Message: "libvirt found no iommu; " + status.String(),
+			})
+			return
+		}
+	}
+	if status.Groups == 0 {
+		meta.SetStatusCondition(&hypervisor.Status.Conditions, metav1.Condition{
+			Type:    IOMMUType,
+			Status:  metav1.ConditionFalse,
+			Reason:  "IOMMUDisabled",
+			Message: status.String(),
+		})
+		return
+	}
+	if !status.Ready() {
+		meta.SetStatusCondition(&hypervisor.Status.Conditions, metav1.Condition{
+			Type:    IOMMUType,
+			Status:  metav1.ConditionFalse,
+			Reason:  "VFIOModulesMissing",
+			Message: status.String(),
+		})
+		return
+	}
+	meta.SetStatusCondition(&hypervisor.Status.Conditions, metav1.Condition{
+		Type:    IOMMUType,
+		Status:  metav1.ConditionTrue,
+		Reason:  "Ready",
+		Message: status.String(),
+	})
+}
+
+// Report if the other hypervisors have room for the instances of the host,
+// as a dry run of the evacuation on reboot. The same check is done before
+// the eviction is created, which is skipped if it can't succeed.
+func (r *HypervisorReconciler) reconcileEvacuationCapacity(ctx context.Context, hypervisor *kvmv1.Hypervisor) {
+	if !hypervisor.Spec.EvacuateOnReboot {
+		meta.RemoveStatusCondition(&hypervisor.Status.Conditions, CapacityType)
+		return
+	}
+	// The cache of the client only holds the hypervisor of this host.
+	capacity, err := evacuation.CheckCapacity(ctx, r.APIReader, hypervisor)
+	if err != nil {
+		logger.FromContext(ctx).Error(err, "unable to check capacity for evacuation")
+		meta.SetStatusCondition(&hypervisor.Status.Conditions, metav1.Condition{
+			Type:    CapacityType,
+			Status:  metav1.ConditionUnknown,
+			Reason:  "CheckFailed",
+			Message: err.Error(),
+		})
+		return
+	}
+	if !capacity.Sufficient() {
+		meta.SetStatusCondition(&hypervisor.Status.Conditions, metav1.Condition{
+			Type:    CapacityType,
+			Status:  metav1.ConditionFalse,
+			Reason:  "InsufficientCapacity",
+			Message: capacity.String(),
+		})
+		return
+	}
+	meta.SetStatusCondition(&hypervisor.Status.Conditions, metav1.Condition{
+		Type:    CapacityType,
+		Status:  metav1.ConditionTrue,
+		Reason:  "Sufficient",
+		Message: capacity.String(),
+	})
+}
+
+// Shortest shutdown delay in which the instances left on the host can still
+// be shut down, if they can't be migrated in time.
+const minShutdownInhibitDelay = 2 * time.Minute
+
+// Report for how long logind delays the shutdown for the evacuation, which
+// is cut short once InhibitDelayMaxSec of logind.conf is reached.
+func (r *HypervisorReconciler) reconcileShutdownInhibit(ctx context.Context, hypervisor *kvmv1.Hypervisor) {
+	if !r.evacuateOnReboot {
+		meta.RemoveStatusCondition(&hypervisor.Status.Conditions, InhibitType)
+		return
+	}
+	delay, err := r.Systemd.InhibitDelayMax(ctx)
+	if err != nil {
+		logger.FromContext(ctx).Error(err, "unable to get shutdown inhibit delay")
+		meta.SetStatusCondition(&hypervisor.Status.Conditions, metav1.Condition{
+			Type:    InhibitType,
+			Status:  metav1.ConditionUnknown,
+			Reason:  "ReadFailed",
+			Message: err.Error(),
+		})
+		return
+	}
+	if delay < minShutdownInhibitDelay {
+		meta.SetStatusCondition(&hypervisor.Status.Conditions, metav1.Condition{
+			Type:   InhibitType,
+			Status: metav1.ConditionFalse,
+			Reason: "DelayTooShort",
+			Message: fmt.Sprintf("shutdown is delayed for up to %s, raise InhibitDelayMaxSec to at least %s",
+				delay, minShutdownInhibitDelay),
+		})
+		return
+	}
+	meta.SetStatusCondition(&hypervisor.Status.Conditions, metav1.Condition{
+		Type:    InhibitType,
+		Status:  metav1.ConditionTrue,
+		Reason:  "Delayed",
+		Message: fmt.Sprintf("shutdown is delayed for up to %s", delay),
+	})
+}
+
+// Write the kernel parameters requested by the annotations of the hypervisor
+// into the kernel command line snippet, and report if a reboot is required
+// to apply them.
+//
+// The agent doesn't reboot on its own. The parameters are applied with the
+// next reboot, e.g. the one after an operating system update.
+func (r *HypervisorReconciler) reconcileKernelParameters(ctx context.Context, hypervisor *kvmv1.Hypervisor) {
+	if !r.ManageKernelParameters {
+		meta.RemoveStatusCondition(&hypervisor.Status.Conditions, RebootType)
+		return
+	}
+	log := logger.FromContext(ctx)
+
+	desired, err := kernel.DesiredParameters(hypervisor.Annotations)
+	if err != nil {
+		// Keep the last snippet until the annotations are fixed.
+		log.Error(err, "invalid kernel parameters requested")
+		meta.SetStatusCondition(&hypervisor.Status.Conditions, metav1.Condition{
+			Type:    RebootType,
+			Status:  metav1.ConditionUnknown,
+			Reason:  "InvalidParameters",
+			Message: err.Error(),
+		})
+		return
+	}
+	changed, err := kernel.WriteSnippet(r.kernelSnippetPath(), desired)
+	if err != nil {
+		log.Error(err, "unable to write kernel command line snippet")
+		meta.SetStatusCondition(&hypervisor.Status.Conditions, metav1.Condition{
+			Type:    RebootType,
+			Status:  metav1.ConditionUnknown,
+			Reason:  "WriteFailed",
+			Message: err.Error(),
+		})
+		return
+	}
+	if changed {
+		log.Info("updated kernel command line snippet", "parameters", desired)
+	}
+
+	var live kernel.Parameters
+	if r.kernelParameters != nil {
+		live = *r.kernelParameters
+	}
+	missing := live.Missing(desired)
+	switch {
+	case len(missing) == 0:
+		meta.SetStatusCondition(&hypervisor.Status.Conditions, metav1.Condition{
+			Type:    RebootType,
+			Status:  metav1.ConditionFalse,
+			Reason:  "UpToDate",
+			Message: fmt.Sprintf("kernel was booted with all %d managed parameters", len(desired)),
+		})
+	case hypervisor.Spec.Reboot && hypervisor.Status.Update.InProgress:
+		meta.SetStatusCondition(&hypervisor.Status.Conditions, metav1.Condition{
+			Type:   RebootType,
+			Status: metav1.ConditionTrue,
+			Reason: "RebootScheduled",
+			Message: fmt.Sprintf("kernel parameters %s are applied with the reboot after the operating system update",
+				strings.Join(missing, " ")),
+		})
+	default:
+		meta.SetStatusCondition(&hypervisor.Status.Conditions, metav1.Condition{
+			Type:    RebootType,
+			Status:  metav1.ConditionTrue,
+			Reason:  "KernelParametersChanged",
+			Message: fmt.Sprintf("kernel was booted without parameters %s", strings.Join(missing, " ")),
+		})
+	}
+}
+
+// Download and verify the operating system image requested by the
+// annotations of the hypervisor, and stage it for systemd-sysupdate.
+// Returns false while the requested image is not staged, so that the
+// update isn't started before.
+func (r *HypervisorReconciler) reconcileOSImage(ctx context.Context, hypervisor *kvmv1.Hypervisor) bool {
+	imageURL, ok := hypervisor.Annotations[OSImageURLAnnotation]
+	if r.ImageStager == nil || !ok {
+		meta.RemoveStatusCondition(&hypervisor.Status.Conditions, ImageType)
+		return true
+	}
+	log := logger.FromContext(ctx)
+
+	image := systemd.Image{
+		URL:    imageURL,
+		SHA256: strings.ToLower(hypervisor.Annotations[OSImageSHA256Annotation]),
+	}
+	if err := image.Validate(); err != nil {
+		meta.SetStatusCondition(&hypervisor.Status.Conditions, metav1.Condition{
+			Type:    ImageType,
+			Status:  metav1.ConditionFalse,
+			Reason:  "InvalidImage",
+			Message: err.Error(),
+		})
+		return false
+	}
+
+	status := r.ImageStager.StageImage(image)
+	switch {
+	case !status.Done:
+		meta.SetStatusCondition(&hypervisor.Status.Conditions, metav1.Condition{
+			Type:    ImageType,
+			Status:  metav1.ConditionUnknown,
+			Reason:  "Downloading",
+			Message: fmt.Sprintf("downloading %s", image.URL),
+		})
+		return false
+	case errors.Is(status.Err, systemd.ErrChecksumMismatch):
+		log.Error(status.Err, "operating system image failed verification", "url", image.URL)
+		meta.SetStatusCondition(&hypervisor.Status.Conditions, metav1.Condition{
+			Type:    ImageType,
+			Status:  metav1.ConditionFalse,
+			Reason:  "VerificationFailed",
+			Message: status.Err.Error(),
+		})
+		return false
+	case status.Err != nil:
+		meta.SetStatusCondition(&hypervisor.Status.Conditions, metav1.Condition{
+			Type:    ImageType,
+			Status:  metav1.ConditionFalse,
+			Reason:  "DownloadFailed",
+			Message: status.Err.Error(),
+		})
+		return false
+	}
+	meta.SetStatusCondition(&hypervisor.Status.Conditions, metav1.Condition{
+		Type:    ImageType,
+		Status:  metav1.ConditionTrue,
+		Reason:  "Staged",
+		Message: fmt.Sprintf("%s verified and staged for systemd-sysupdate", status.Path),
+	})
+	return true
+}
+
+// Report the operating system versions the host boots, and change the
+// default boot entry to the previous version on request of the rollback
+// annotation, as an escape hatch when an update misbehaves.
+func (r *HypervisorReconciler) reconcileBootEntries(ctx context.Context, hypervisor, base *kvmv1.Hypervisor) error {
+	if r.BootLoader == nil {
+		meta.RemoveStatusCondition(&hypervisor.Status.Conditions, BootType)
+		return nil
+	}
+	log := logger.FromContext(ctx)
+
+	entries, err := r.BootLoader.Entries()
+	if err != nil {
+		log.Error(err, "unable to read boot loader entries")
+		meta.SetStatusCondition(&hypervisor.Status.Conditions, metav1.Condition{
+			Type:    BootType,
+			Status:  metav1.ConditionUnknown,
+			Reason:  "ReadFailed",
+			Message: err.Error(),
+		})
+		return nil
+	}
+
+	if _, ok := hypervisor.Annotations[RollbackAnnotation]; ok {
+		patched := base.DeepCopy()
+		delete(patched.Annotations, RollbackAnnotation)
+		if err := r.Patch(ctx, patched, client.MergeFrom(base)); err != nil {
+			return err
+		}
+		delete(hypervisor.Annotations, RollbackAnnotation)
+
+		if entries.Fallback.ID == "" {
+			err = fmt.Errorf("no boot entry older than %s", entries.Current)
+		} else {
+			log.Info("rolling back operating system", "entry", entries.Fallback.ID)
+			err = r.BootLoader.SetDefault(entries.Fallback.ID)
+		}
+		if err != nil {
+			meta.SetStatusCondition(&hypervisor.Status.Conditions, metav1.Condition{
+				Type:    BootType,
+				Status:  metav1.ConditionFalse,
+				Reason:  "RollbackFailed",
+				Message: err.Error(),
+			})
+			return nil
+		}
+		entries.Default = entries.Fallback.ID
+	}
+
+	// The default sticks, also newer versions installed later on are not
+	// booted until it is reset.
+	if entries.Default != "" && entries.Default != entries.Current.ID {
+		meta.SetStatusCondition(&hypervisor.Status.Conditions, metav1.Condition{
+			Type:    BootType,
+			Status:  metav1.ConditionTrue,
+			Reason:  "DefaultChanged",
+			Message: fmt.Sprintf("%s is booted with the next reboot, %s", entries.Default, entries),
+		})
+		return nil
+	}
+	meta.SetStatusCondition(&hypervisor.Status.Conditions, metav1.Condition{
+		Type:    BootType,
+		Status:  metav1.ConditionTrue,
+		Reason:  "Available",
+		Message: entries.String(),
+	})
+	return nil
+}
+
+// Check if the installed operating system update waits for the reboot.
+func rebootPending(hypervisor *kvmv1.Hypervisor) bool {
+	update := hypervisor.Status.Update
+	if !hypervisor.Spec.Reboot || update.InProgress || update.Installed == "" ||
+		update.Installed != hypervisor.Spec.OperatingSystemVersion ||
+		update.Installed == hypervisor.Status.OperatingSystem.Version {
+		return false
+	}
+	// Only reboot once per update, the running version is not known for
+	// updates to the latest version.
+	condition := meta.FindStatusCondition(hypervisor.Status.Conditions, RebootPendingType)
+	return condition == nil || condition.Reason != "Rebooted"
+}
+
+// Reboot into the installed operating system update within the maintenance
+// window of the hypervisor, once its instances are evacuated. The progress
+// is reported by the RebootPending condition, which is removed when the
+// next update starts.
+//
+// The time of the reboot and the condition are written before the reboot is
+// started, so that they record the reboot even if the status can't be
+// written afterwards. Once the host booted after that time, the reboot is
+// reported as done. The transition time of the condition can't be used, it
+// is kept if the condition was true before.
+func (r *HypervisorReconciler) reconcileReboot(ctx context.Context, hypervisor, base *kvmv1.Hypervisor) error {
+	if !r.RebootOrchestration {
+		meta.RemoveStatusCondition(&hypervisor.Status.Conditions, RebootPendingType)
+		return nil
+	}
+	log := logger.FromContext(ctx)
+
+	condition := meta.FindStatusCondition(hypervisor.Status.Conditions, RebootPendingType)
+	if condition != nil && condition.Reason == "Rebooting" {
+		bootTime := r.bootTime
+		if bootTime == nil {
+			bootTime = sys.BootTime
+		}
+		booted, err := bootTime()
+		if err != nil {
+			log.Error(err, "unable to get boot time")
+			return nil
+		}
+		requested, err := time.Parse(time.RFC3339, hypervisor.Annotations[RebootRequestedAnnotation])
+		if err != nil {
+			// Requested by an agent predating the annotation.
+			requested = condition.LastTransitionTime.Time
+		}
+		if booted.After(requested) {
+			meta.SetStatusCondition(&hypervisor.Status.Conditions, metav1.Condition{
+				Type:    RebootPendingType,
+				Status:  metav1.ConditionFalse,
+				Reason:  "Rebooted",
+				Message: fmt.Sprintf("rebooted at %s", booted.UTC().Format(time.RFC3339)),
+			})
+		}
+		return nil
+	}
+	if !rebootPending(hypervisor) {
+		if condition != nil && condition.Reason != "Rebooted" {
+			meta.RemoveStatusCondition(&hypervisor.Status.Conditions, RebootPendingType)
+		}
+		return nil
+	}
+
+	if spec, ok := hypervisor.Annotations[RebootWindowAnnotation]; ok {
+		windows, err := reboot.ParseWindows(spec)
+		if err != nil {
+			meta.SetStatusCondition(&hypervisor.Status.Conditions, metav1.Condition{
+				Type:    RebootPendingType,
+				Status:  metav1.ConditionTrue,
+				Reason:  "InvalidWindow",
+				Message: err.Error(),
+			})
+			return nil
+		}
+		if !reboot.InWindow(windows, time.Now()) {
+			meta.SetStatusCondition(&hypervisor.Status.Conditions, metav1.Condition{
+				Type:    RebootPendingType,
+				Status:  metav1.ConditionTrue,
+				Reason:  "OutsideWindow",
+				Message: fmt.Sprintf("waiting for maintenance window %s", spec),
+			})
+			return nil
+		}
+	}
+
+	if hypervisor.Spec.EvacuateOnReboot && !hypervisor.Status.Evicted {
+		var active int
+		for _, instance := range hypervisor.Status.Instances {
+			if instance.Active {
+				active++
+			}
+		}
+		if active > 0 {
+			meta.SetStatusCondition(&hypervisor.Status.Conditions, metav1.Condition{
+				Type:    RebootPendingType,
+				Status:  metav1.ConditionTrue,
+				Reason:  "WaitingForEvacuation",
+				Message: fmt.Sprintf("waiting for the evacuation of %d active instances", active),
+			})
+			return nil
+		}
+	}
+
+	if err := r.patchAnnotations(ctx, hypervisor, base, map[string]string{
+		RebootRequestedAnnotation: time.Now().UTC().Format(time.RFC3339),
+	}); err != nil {
+		return err
+	}
+	meta.SetStatusCondition(&hypervisor.Status.Conditions, metav1.Condition{
+		Type:   RebootPendingType,
+		Status: metav1.ConditionTrue,
+		Reason: "Rebooting",
+		Message: fmt.Sprintf("rebooting into operating system update %s",
+			hypervisor.Status.Update.Installed),
+	})
+	if err := r.applyStatus(ctx, hypervisor); err != nil {
+		return err
+	}
+	log.Info("rebooting into operating system update", "version", hypervisor.Status.Update.Installed)
+	if r.CordonNode {
+		if err := cordon.Cordon(ctx, r.Client, sys.Hostname, cordon.ReasonOSUpdate); err != nil {
+			log.Error(err, "unable to cordon node")
+		}
+	}
+	if _, err := r.Systemd.StartUnit(ctx, sysUpdateRebootTarget); err != nil {
+		meta.SetStatusCondition(&hypervisor.Status.Conditions, metav1.Condition{
+			Type:    RebootPendingType,
+			Status:  metav1.ConditionTrue,
+			Reason:  "RebootFailed",
+			Message: err.Error(),
+		})
+		if r.CordonNode {
+			if err := cordon.Uncordon(ctx, r.Client, sys.Hostname); err != nil {
+				log.Error(err, "unable to uncordon node")
+			}
+		}
+		return nil
+	}
+	return nil
+}
+
+// Uncordon the node once the host booted after the agent cordoned it for
+// an evacuation or the reboot into an operating system update.
+func (r *HypervisorReconciler) reconcileCordon(ctx context.Context) error {
+	if !r.CordonNode {
+		return nil
+	}
+	var node corev1.Node
+	if err := r.Get(ctx, client.ObjectKey{Name: sys.Hostname}, &node); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	since, ok := cordon.CordonedSince(&node)
+	if !ok {
+		return nil
+	}
+	bootTime := r.bootTime
+	if bootTime == nil {
+		bootTime = sys.BootTime
+	}
+	booted, err := bootTime()
+	if err != nil {
+		return err
+	}
+	if booted.Before(since) {
+		return nil
+	}
+	logger.FromContext(ctx).Info("uncordoning node after reboot", "cordoned", since, "booted", booted)
+	return cordon.Uncordon(ctx, r.Client, sys.Hostname)
+}
+
+// Report which host cpus the kernel command line isolates for the vcpus of
+// domains with dedicated cpus, and which are left for the system tasks, so
+// that the placement of dedicated cpus can be checked against it.
+func (r *HypervisorReconciler) reconcileCPUIsolation(ctx context.Context, hypervisor *kvmv1.Hypervisor) {
+	if r.HostTopology == nil || r.kernelParameters == nil {
+		meta.RemoveStatusCondition(&hypervisor.Status.Conditions, CPUType)
+		return
+	}
+	if !meta.IsStatusConditionTrue(hypervisor.Status.Conditions, LibVirtType) {
+		// Keep the last known state until libvirt is back.
+		return
+	}
+	log := logger.FromContext(ctx)
+
+	isolation, err := r.kernelParameters.Isolation()
+	if err != nil {
+		meta.SetStatusCondition(&hypervisor.Status.Conditions, metav1.Condition{
+			Type:    CPUType,
+			Status:  metav1.ConditionFalse,
+			Reason:  "InvalidParameters",
+			Message: err.Error(),
+		})
+		return
+	}
+	cells, err := r.HostTopology.HostCPUs()
+	if err != nil {
+		log.Error(err, "unable to get host cpus")
+		meta.SetStatusCondition(&hypervisor.Status.Conditions, metav1.Condition{
+			Type:    CPUType,
+			Status:  metav1.ConditionUnknown,
+			Reason:  "TopologyUnavailable",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	layout := isolation.Layout(cells)
+	switch {
+	case len(layout.Unknown) > 0:
+		meta.SetStatusCondition(&hypervisor.Status.Conditions, metav1.Condition{
+			Type:   CPUType,
+			Status: metav1.ConditionFalse,
+			Reason: "UnknownCPUs",
+			Message: fmt.Sprintf("isolated cpus %s do not exist on the host, %s",
+				kernel.FormatCPUList(layout.Unknown), layout),
+		})
+	case len(layout.Dedicated()) == 0:
+		meta.SetStatusCondition(&hypervisor.Status.Conditions, metav1.Condition{
+			Type:    CPUType,
+			Status:  metav1.ConditionFalse,
+			Reason:  "NotIsolated",
+			Message: layout.String(),
+		})
+	default:
+		message := layout.String()
+		if len(layout.WithoutNoHZ) > 0 {
+			message += ", nohz_full missing for " + kernel.FormatCPUList(layout.WithoutNoHZ)
+		}
+		if len(layout.WithoutRCUNoCBs) > 0 {
+			message += ", rcu_nocbs missing for " + kernel.FormatCPUList(layout.WithoutRCUNoCBs)
+		}
+		meta.SetStatusCondition(&hypervisor.Status.Conditions, metav1.Condition{
+			Type:    CPUType,
+			Status:  metav1.ConditionTrue,
+			Reason:  "Isolated",
+			Message: message,
+		})
+	}
+}
+
+// Publish the cpu model of the host in the annotations of the hypervisor.
+// The annotations are only patched if the model changed, e.g. after a
+// microcode update.
+func (r *HypervisorReconciler) reconcileHostCPU(ctx context.Context, hypervisor, base *kvmv1.Hypervisor) error {
+	if r.HostCPU == nil || !meta.IsStatusConditionTrue(hypervisor.Status.Conditions, LibVirtType) {
+		return nil
+	}
+	model, err := r.HostCPU.HostCPUModel()
+	if err != nil {
+		// Not critical, keep the last published model.
+		logger.FromContext(ctx).Error(err, "unable to get host cpu model")
+		return nil
+	}
+	annotations := map[string]string{
+		HostCPUModelAnnotation:     model.Model,
+		HostCPUVendorAnnotation:    model.Vendor,
+		HostCPUMicrocodeAnnotation: model.Microcode,
+		HostCPUFeaturesAnnotation:  strings.Join(model.Features, ","),
+	}
+	if model.TSCFrequency > 0 {
+		annotations[HostTSCFrequencyAnnotation] = strconv.FormatUint(model.TSCFrequency, 10)
+		annotations[HostTSCScalingAnnotation] = strconv.FormatBool(model.TSCScaling)
+	}
+	return r.patchAnnotations(ctx, hypervisor, base, annotations)
+}
+
+// Publish the number of confidential domains the host can run and its
+// firmware version in the annotations of the hypervisor. Hosts without SEV
+// are left alone.
+func (r *HypervisorReconciler) reconcileConfidentialComputing(
+	ctx context.Context, hypervisor, base *kvmv1.Hypervisor,
+) error {
+	if r.ConfidentialComputing == nil || !meta.IsStatusConditionTrue(hypervisor.Status.Conditions, LibVirtType) {
+		return nil
+	}
+	cc, err := r.ConfidentialComputing.ConfidentialComputing()
+	if err != nil {
+		// Not critical, keep the last published support.
+		logger.FromContext(ctx).Error(err, "unable to get confidential computing support")
+		return nil
+	}
+	if !cc.SEV {
+		return nil
+	}
+	return r.patchAnnotations(ctx, hypervisor, base, map[string]string{
+		SEVMaxGuestsAnnotation:   strconv.Itoa(cc.SEVMaxGuests),
+		SEVESMaxGuestsAnnotation: strconv.Itoa(cc.SEVESMaxGuests),
+		SEVFirmwareAnnotation:    cc.SEVFirmwareVersion,
+	})
+}
+
+// Publish the firmware loaders and nvram templates of the host in the
+// annotations of the hypervisor.
+func (r *HypervisorReconciler) reconcileFirmware(ctx context.Context, hypervisor, base *kvmv1.Hypervisor) error {
+	if r.Firmware == nil || !meta.IsStatusConditionTrue(hypervisor.Status.Conditions, LibVirtType) {
+		return nil
+	}
+	log := logger.FromContext(ctx)
+	firmware, err := r.Firmware.Firmware()
+	if err != nil {
+		// Not critical, keep the last published inventory.
+		log.Error(err, "unable to get firmware inventory")
+		return nil
+	}
+	annotations := map[string]string{
+		FirmwareLoadersAnnotation: strings.Join(firmware.Loaders, ","),
+	}
+	if r.FirmwareDescriptors != "" {
+		templates, err := libvirt.NVRAMTemplates(r.FirmwareDescriptors)
+		if err != nil {
+			log.Error(err, "unable to read nvram templates")
+			return nil
+		}
+		annotations[NVRAMTemplatesAnnotation] = strings.Join(templates, ",")
+	}
+	return r.patchAnnotations(ctx, hypervisor, base, annotations)
+}
+
+// Compute the baseline cpu of the hypervisors in the group of this one
+// from the host cpus they published, and publish it in the annotations of
+// the hypervisor. Libvirt is only asked again once the host cpus change.
+func (r *HypervisorReconciler) reconcileCPUBaseline(ctx context.Context, hypervisor, base *kvmv1.Hypervisor) error {
+	if r.CPUBaseline == nil || r.CPUBaselineLabel == "" {
+		return nil
+	}
+	group, ok := hypervisor.Labels[r.CPUBaselineLabel]
+	if !ok || !meta.IsStatusConditionTrue(hypervisor.Status.Conditions, LibVirtType) {
+		return nil
+	}
+	log := logger.FromContext(ctx)
+
+	var hypervisors kvmv1.HypervisorList
+	if err := r.APIReader.List(ctx, &hypervisors, client.MatchingLabels{r.CPUBaselineLabel: group}); err != nil {
+		// Not critical, keep the last published baseline.
+		log.Error(err, "unable to list hypervisors for the baseline cpu")
+		return nil
+	}
+	arch := hypervisor.Status.Capabilities.HostCpuArch
+	var cpus []libvirt.HostCPUModel
+	for i := range hypervisors.Items {
+		// Hypervisors of another architecture can't share a cpu model.
+		cpu, ok := hostCPUOf(&hypervisors.Items[i])
+		if ok && hypervisors.Items[i].Status.Capabilities.HostCpuArch == arch {
+			cpus = append(cpus, cpu)
+		}
+	}
+	if len(cpus) == 0 {
+		return nil
+	}
+	if inputs := fmt.Sprint(arch, cpus); inputs != r.cpuBaselineInputs {
+		baseline, err := r.CPUBaseline.BaselineCPU(arch, cpus)
+		if err != nil {
+			log.Error(err, "unable to compute baseline cpu", "hypervisors", len(cpus))
+			return nil
+		}
+		r.cpuBaselineInputs, r.cpuBaseline = inputs, baseline
+	}
+	return r.patchAnnotations(ctx, hypervisor, base, map[string]string{
+		BaselineCPUModelAnnotation:    r.cpuBaseline.Model,
+		BaselineCPUFeaturesAnnotation: strings.Join(r.cpuBaseline.Features, ","),
+		BaselineCPUHostsAnnotation:    strconv.Itoa(len(cpus)),
+	})
+}
+
+// Detach the hypervisor from its node, so that the garbage collector keeps
+// it when the node is deleted, e.g. to reimage the host. The uid of the node
+// is kept in NodeUIDAnnotation instead, a recreated node of the same name
+// adopts the hypervisor.
+func (r *HypervisorReconciler) reconcileNodeOwner(ctx context.Context, hypervisor, base *kvmv1.Hypervisor) error {
+	if !r.Finalizer || !hypervisor.DeletionTimestamp.IsZero() {
+		return nil
+	}
+	var node corev1.Node
+	if err := r.Get(ctx, client.ObjectKey{Name: hypervisor.Name}, &node); err != nil {
+		// Not critical, the node may not be in the cache yet.
+		logger.FromContext(ctx).Error(err, "unable to get node for adopting the hypervisor")
+		return nil
+	}
+	isNode := func(owner metav1.OwnerReference) bool {
+		return owner.APIVersion == "v1" && owner.Kind == "Node" && owner.Name == node.Name
+	}
+	previous := types.UID(hypervisor.Annotations[NodeUIDAnnotation])
+	if index := slices.IndexFunc(hypervisor.OwnerReferences, isNode); index >= 0 && previous == "" {
+		previous = hypervisor.OwnerReferences[index].UID
+	}
+	if previous == node.UID && !slices.ContainsFunc(hypervisor.OwnerReferences, isNode) {
+		return nil
+	}
+
+	patched := base.DeepCopy()
+	patched.OwnerReferences = slices.DeleteFunc(slices.Clone(hypervisor.OwnerReferences), isNode)
+	if patched.Annotations == nil {
+		patched.Annotations = make(map[string]string)
+	}
+	patched.Annotations[NodeUIDAnnotation] = string(node.UID)
+	if err := r.Patch(ctx, patched, client.MergeFrom(base)); err != nil {
+		return err
+	}
+	hypervisor.OwnerReferences = slices.Clone(patched.OwnerReferences)
+	if hypervisor.Annotations == nil {
+		hypervisor.Annotations = make(map[string]string)
+	}
+	hypervisor.Annotations[NodeUIDAnnotation] = string(node.UID)
+	if previous == "" || previous == node.UID {
+		logger.FromContext(ctx).Info("detached hypervisor from node", "uid", node.UID)
+		return nil
+	}
+	logger.FromContext(ctx).Info("adopted hypervisor for recreated node", "previousUID", previous, "uid", node.UID)
+	if r.Recorder != nil {
+		r.Recorder.Eventf(hypervisor, nil, corev1.EventTypeNormal, "Adopted", "AdoptHypervisor",
+			"Adopted hypervisor for recreated node %s, previously %s", node.UID, previous)
+	}
+	return nil
+}
+
+// Hold HypervisorFinalizer while domains are running on the hypervisor,
+// and report why the deletion is blocked. It returns whether the finalizer
+// was removed from the deleted hypervisor, so the hypervisor may be gone.
+func (r *HypervisorReconciler) reconcileFinalizer(ctx context.Context, hypervisor, base *kvmv1.Hypervisor) (bool, error) {
+	deleting := !hypervisor.DeletionTimestamp.IsZero()
+	held := controllerutil.ContainsFinalizer(hypervisor, HypervisorFinalizer)
+	if !deleting || !held {
+		meta.RemoveStatusCondition(&hypervisor.Status.Conditions, DeletionType)
+	}
+	if !r.Finalizer && !held {
+		return false, nil
+	}
+	if !meta.IsStatusConditionTrue(hypervisor.Status.Conditions, LibVirtType) {
+		// The domains of the host are unknown without libvirt.
+		if deleting && held {
+			meta.SetStatusCondition(&hypervisor.Status.Conditions, metav1.Condition{
+				Type:    DeletionType,
+				Status:  metav1.ConditionTrue,
+				Reason:  "LibVirtUnavailable",
+				Message: "waiting for libvirt to check for running domains",
+			})
+		}
+		return false, nil
+	}
+
+	// Patch a copy of the base, so that only the finalizers are written
+	// and the pending status changes are kept.
+	patched := base.DeepCopy()
+	switch {
+	case hypervisor.Status.NumInstances > 0 && deleting && held:
+		meta.SetStatusCondition(&hypervisor.Status.Conditions, metav1.Condition{
+			Type:    DeletionType,
+			Status:  metav1.ConditionTrue,
+			Reason:  "InstancesPresent",
+			Message: fmt.Sprintf("%d domains still running on the hypervisor", hypervisor.Status.NumInstances),
+		})
+		return false, nil
+	case hypervisor.Status.NumInstances > 0 && r.Finalizer && !deleting && !held:
+		controllerutil.AddFinalizer(patched, HypervisorFinalizer)
+		if err := r.Patch(ctx, patched, client.MergeFrom(base)); err != nil {
+			return false, err
+		}
+		controllerutil.AddFinalizer(hypervisor, HypervisorFinalizer)
+		return false, nil
+	case hypervisor.Status.NumInstances == 0 && held:
+		controllerutil.RemoveFinalizer(patched, HypervisorFinalizer)
+		if err := r.Patch(ctx, patched, client.MergeFrom(base)); err != nil {
+			return false, client.IgnoreNotFound(err)
+		}
+		controllerutil.RemoveFinalizer(hypervisor, HypervisorFinalizer)
+		if deleting {
+			logger.FromContext(ctx).Info("removed finalizer of deleted hypervisor")
+		}
+		return deleting, nil
+	}
+	return false, nil
+}
+
+// Copy the NodeLabels of the node into the labels of the hypervisor, and
+// its taints into the annotations with SyncNodeTaints. Labels removed from
+// the node are removed from the hypervisor.
+func (r *HypervisorReconciler) reconcileNodeMetadata(ctx context.Context, hypervisor, base *kvmv1.Hypervisor) error {
+	if len(r.NodeLabels) == 0 && !r.SyncNodeTaints {
+		return nil
+	}
+	var node corev1.Node
+	if err := r.Get(ctx, client.ObjectKey{Name: sys.Hostname}, &node); err != nil {
+		// Not critical, keep the last synced labels.
+		logger.FromContext(ctx).Error(err, "unable to get node for its labels")
+		return nil
+	}
+
+	labels := map[string]string{}
+	var removed []string
+	for _, key := range r.NodeLabels {
+		if value, ok := node.Labels[key]; ok {
+			labels[key] = value
+		} else {
+			removed = append(removed, key)
+		}
+	}
+	if err := r.patchLabels(ctx, hypervisor, base, labels, removed); err != nil {
+		return err
+	}
+	if !r.SyncNodeTaints {
+		return nil
+	}
+	taints := make([]string, 0, len(node.Spec.Taints))
+	for _, taint := range node.Spec.Taints {
+		taints = append(taints, taint.ToString())
+	}
+	slices.Sort(taints)
+	return r.patchAnnotations(ctx, hypervisor, base, map[string]string{
+		NodeTaintsAnnotation: strings.Join(taints, ","),
+	})
+}
+
+// Set the labels of the hypervisor and remove the removed ones, patching
+// them only if any of them changed.
+func (r *HypervisorReconciler) patchLabels(
+	ctx context.Context, hypervisor, base *kvmv1.Hypervisor, labels map[string]string, removed []string,
+) error {
+	changed := false
+	for key, value := range labels {
+		if current, ok := hypervisor.Labels[key]; !ok || current != value {
+			changed = true
+		}
+	}
+	for _, key := range removed {
+		if _, ok := hypervisor.Labels[key]; ok {
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+
+	// Patch a copy of the base, so that only the labels are written and
+	// the pending status changes are kept.
+	patched := base.DeepCopy()
+	if patched.Labels == nil {
+		patched.Labels = map[string]string{}
+	}
+	maps.Copy(patched.Labels, labels)
+	for _, key := range removed {
+		delete(patched.Labels, key)
+	}
+	if err := r.Patch(ctx, patched, client.MergeFrom(base)); err != nil {
+		return err
+	}
+	if hypervisor.Labels == nil {
+		hypervisor.Labels = map[string]string{}
+	}
+	maps.Copy(hypervisor.Labels, labels)
+	for _, key := range removed {
+		delete(hypervisor.Labels, key)
+	}
+	return nil
+}
+
+// Set the annotations of the hypervisor, patching them only if any of them
+// changed.
+func (r *HypervisorReconciler) patchAnnotations(
+	ctx context.Context, hypervisor, base *kvmv1.Hypervisor, annotations map[string]string,
+) error {
+	changed := false
+	for key, value := range annotations {
+		if hypervisor.Annotations[key] != value {
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+
+	// Patch a copy of the base, so that only the annotations are written
+	// and the pending status changes are kept.
+	patched := base.DeepCopy()
+	if patched.Annotations == nil {
+		patched.Annotations = map[string]string{}
+	}
+	maps.Copy(patched.Annotations, annotations)
+	if err := r.Patch(ctx, patched, client.MergeFrom(base)); err != nil {
+		return err
+	}
+	if hypervisor.Annotations == nil {
+		hypervisor.Annotations = map[string]string{}
+	}
+	maps.Copy(hypervisor.Annotations, annotations)
+	return nil
+}
+
+// Refresh the heartbeat and the version of the agent in the annotations
+// of the hypervisor, if the heartbeat is older than heartbeatInterval or
+// the agent was updated.
+func (r *HypervisorReconciler) reconcileHeartbeat(ctx context.Context, hypervisor, base *kvmv1.Hypervisor) error {
+	now := time.Now()
+	annotations := map[string]string{
+		AgentVersionAnnotation: bininfo.VersionOr("unknown"),
+		AgentBuildAnnotation:   bininfo.CommitOr("unknown") + " " + bininfo.BuildDateOr("unknown"),
+	}
+	changed := false
+	for key, value := range annotations {
+		if hypervisor.Annotations[key] != value {
+			changed = true
+		}
+	}
+	last, err := time.Parse(time.RFC3339, hypervisor.Annotations[HeartbeatAnnotation])
+	if !changed && err == nil && now.Sub(last) < heartbeatInterval {
+		return nil
+	}
+	annotations[HeartbeatAnnotation] = now.UTC().Format(time.RFC3339)
+
+	// Patch a copy of the base, so that only the annotations are written
+	// and the pending status changes are kept.
+	patched := base.DeepCopy()
+	if patched.Annotations == nil {
+		patched.Annotations = map[string]string{}
+	}
+	maps.Copy(patched.Annotations, annotations)
+	if err := r.Patch(ctx, patched, client.MergeFrom(base)); err != nil {
+		return err
+	}
+	if hypervisor.Annotations == nil {
+		hypervisor.Annotations = map[string]string{}
+	}
+	maps.Copy(hypervisor.Annotations, annotations)
+	return nil
+}
+
+// Report the generation of the active runtime configuration, or why the
+// last one was rejected, so that the rollout of a ConfigMap change can be
+// tracked across the fleet.
+func (r *HypervisorReconciler) reconcileRuntimeConfig(hypervisor *kvmv1.Hypervisor) {
+	if r.RuntimeConfig == nil {
+		meta.RemoveStatusCondition(&hypervisor.Status.Conditions, RuntimeConfigType)
+		return
+	}
+	generation := r.RuntimeConfig.Generation()
+	active := "the default runtime config is active"
+	if generation != "" {
+		active = fmt.Sprintf("runtime config generation %s is active", generation)
+	}
+	if err := r.RuntimeConfig.Rejected(); err != nil {
+		meta.SetStatusCondition(&hypervisor.Status.Conditions, metav1.Condition{
+			Type:    RuntimeConfigType,
+			Status:  metav1.ConditionFalse,
+			Reason:  "Rejected",
+			Message: fmt.Sprintf("%s, rejected the last one: %v", active, err),
+		})
+		return
+	}
+	reason := "Applied"
+	if generation == "" {
+		reason = "Default"
+	}
+	meta.SetStatusCondition(&hypervisor.Status.Conditions, metav1.Condition{
+		Type:    RuntimeConfigType,
+		Status:  metav1.ConditionTrue,
+		Reason:  reason,
+		Message: active,
+	})
+}
+
+// Check if the node-local configuration managed by the agent is applied,
+// based on the conditions reported by the other reconcile steps. Returns
+// the reason and message of the condition if not.
+func pendingConfiguration(conditions []metav1.Condition) (string, string) {
+	if condition := meta.FindStatusCondition(conditions, NFDType); condition != nil &&
+		condition.Reason == "ProduceFailed" {
+		return "ApplyFailed", "node-feature-discovery labels: " + condition.Message
+	}
+	if condition := meta.FindStatusCondition(conditions, PolicyType); condition != nil &&
+		condition.Reason == "CheckFailed" {
+		return "ApplyFailed", "domain policy: " + condition.Message
+	}
+	if condition := meta.FindStatusCondition(conditions, SysctlType); condition != nil &&
+		condition.Status == metav1.ConditionFalse {
+		return "ApplyFailed", "sysctls: " + condition.Message
+	}
+	if condition := meta.FindStatusCondition(conditions, KSMType); condition != nil &&
+		condition.Status == metav1.ConditionFalse {
+		return "ApplyFailed", "ksm: " + condition.Message
+	}
+	if condition := meta.FindStatusCondition(conditions, RebootType); condition != nil {
+		switch condition.Status {
+		case metav1.ConditionUnknown:
+			return "ApplyFailed", "kernel parameters: " + condition.Message
+		case metav1.ConditionTrue:
+			return "RebootRequired", "kernel parameters: " + condition.Message
+		}
+	}
+	return "", ""
+}
+
+// Echo the config generation requested by the annotation of the hypervisor
+// once the node-local configuration managed by the agent is applied, so that
+// a central operator can track the rollout of a configuration change across
+// the fleet. The generation is opaque to the agent.
+func (r *HypervisorReconciler) reconcileConfigGeneration(
+	ctx context.Context, hypervisor, base *kvmv1.Hypervisor,
+) error {
+	generation, ok := hypervisor.Annotations[ConfigGenerationAnnotation]
+	if !ok {
+		meta.RemoveStatusCondition(&hypervisor.Status.Conditions, ConfigType)
+		return nil
+	}
+
+	if reason, message := pendingConfiguration(hypervisor.Status.Conditions); reason != "" {
+		meta.SetStatusCondition(&hypervisor.Status.Conditions, metav1.Condition{
+			Type:    ConfigType,
+			Status:  metav1.ConditionFalse,
+			Reason:  reason,
+			Message: fmt.Sprintf("config generation %s is not applied, %s", generation, message),
+		})
+		return nil
+	}
+
+	if hypervisor.Annotations[ObservedConfigGenerationAnnotation] != generation {
+		// Patch a copy of the base, so that only the annotation is written
+		// and the pending status changes are kept.
+		patched := base.DeepCopy()
+		if patched.Annotations == nil {
+			patched.Annotations = map[string]string{}
+		}
+		patched.Annotations[ObservedConfigGenerationAnnotation] = generation
+		if err := r.Patch(ctx, patched, client.MergeFrom(base)); err != nil {
+			return err
+		}
+		hypervisor.Annotations[ObservedConfigGenerationAnnotation] = generation
+		logger.FromContext(ctx).Info("applied config generation", "generation", generation)
+	}
+	meta.SetStatusCondition(&hypervisor.Status.Conditions, metav1.Condition{
+		Type:    ConfigType,
+		Status:  metav1.ConditionTrue,
+		Reason:  "Applied",
+		Message: fmt.Sprintf("config generation %s is applied", generation),
+	})
+	return nil
+}
+
+// Apply the sysctls requested by the annotations of the hypervisor, and
+// report sysctls which were changed behind the back of the agent or which
+// the kernel didn't accept.
+func (r *HypervisorReconciler) reconcileSysctls(ctx context.Context, hypervisor *kvmv1.Hypervisor) {
+	if r.Sysctl == nil {
+		meta.RemoveStatusCondition(&hypervisor.Status.Conditions, SysctlType)
+		return
+	}
+	log := logger.FromContext(ctx)
+
+	settings, invalid := sysctl.DesiredSettings(hypervisor.Annotations)
+	result, err := r.Sysctl.Apply(settings)
+	if err = errors.Join(invalid, err); err != nil {
+		log.Error(err, "unable to apply sysctls")
+		meta.SetStatusCondition(&hypervisor.Status.Conditions, metav1.Condition{
+			Type:    SysctlType,
+			Status:  metav1.ConditionFalse,
+			Reason:  "ApplyFailed",
+			Message: err.Error(),
+		})
+		return
+	}
+	if len(result.Rejected) > 0 {
+		meta.SetStatusCondition(&hypervisor.Status.Conditions, metav1.Condition{
+			Type:    SysctlType,
+			Status:  metav1.ConditionFalse,
+			Reason:  "Rejected",
+			Message: "kernel did not accept " + summarize(result.Rejected),
+		})
+		return
+	}
+	if len(result.Corrected) > 0 {
+		log.Info("corrected sysctls", "sysctls", len(result.Corrected))
+		meta.SetStatusCondition(&hypervisor.Status.Conditions, metav1.Condition{
+			Type:    SysctlType,
+			Status:  metav1.ConditionTrue,
+			Reason:  "DriftCorrected",
+			Message: "corrected " + summarize(result.Corrected),
+		})
+		return
+	}
+	meta.SetStatusCondition(&hypervisor.Status.Conditions, metav1.Condition{
+		Type:    SysctlType,
+		Status:  metav1.ConditionTrue,
+		Reason:  "Applied",
+		Message: fmt.Sprintf("all %d managed sysctls are applied", len(settings)),
+	})
+}
+
+// Apply the ksm parameters requested by the annotations of the hypervisor
+// like the sysctls, and report how much memory ksm deduplicates.
+func (r *HypervisorReconciler) reconcileKSM(ctx context.Context, hypervisor *kvmv1.Hypervisor) {
+	if r.KSM == nil {
+		meta.RemoveStatusCondition(&hypervisor.Status.Conditions, KSMType)
+		return
+	}
+	log := logger.FromContext(ctx)
+
+	settings, invalid := ksm.DesiredSettings(hypervisor.Annotations)
+	result, err := r.KSM.Apply(settings)
+	if err = errors.Join(invalid, err); err != nil {
+		log.Error(err, "unable to apply ksm parameters")
+		meta.SetStatusCondition(&hypervisor.Status.Conditions, metav1.Condition{
+			Type:    KSMType,
+			Status:  metav1.ConditionFalse,
+			Reason:  "ApplyFailed",
+			Message: err.Error(),
+		})
+		return
+	}
+	if len(result.Rejected) > 0 {
+		meta.SetStatusCondition(&hypervisor.Status.Conditions, metav1.Condition{
+			Type:    KSMType,
+			Status:  metav1.ConditionFalse,
+			Reason:  "Rejected",
+			Message: "kernel did not accept " + summarize(result.Rejected),
+		})
+		return
+	}
+
+	stats, err := r.KSM.ReadStats()
+	if err != nil {
+		log.Error(err, "unable to read ksm counters")
+		meta.SetStatusCondition(&hypervisor.Status.Conditions, metav1.Condition{
+			Type:    KSMType,
+			Status:  metav1.ConditionUnknown,
+			Reason:  "ReadFailed",
+			Message: err.Error(),
+		})
+		return
+	}
+	ksm.UpdateMetrics(stats)
+
+	if len(result.Corrected) > 0 {
+		log.Info("corrected ksm parameters", "parameters", len(result.Corrected))
+		meta.SetStatusCondition(&hypervisor.Status.Conditions, metav1.Condition{
+			Type:    KSMType,
+			Status:  metav1.ConditionTrue,
+			Reason:  "DriftCorrected",
+			Message: fmt.Sprintf("corrected %s, %s", summarize(result.Corrected), stats),
+		})
+		return
+	}
+	meta.SetStatusCondition(&hypervisor.Status.Conditions, metav1.Condition{
+		Type:    KSMType,
+		Status:  metav1.ConditionTrue,
+		Reason:  "Applied",
+		Message: fmt.Sprintf("all %d managed ksm parameters are applied, %s", len(settings), stats),
+	})
+}
+
+func (r *HypervisorReconciler) kernelSnippetPath() string {
+	if r.KernelSnippetPath != "" {
+		return r.KernelSnippetPath
+	}
+	return kernel.DefaultSnippetPath
+}
+
+func (r *HypervisorReconciler) featureFilePath() string {
+	if r.FeatureFilePath != "" {
+		return r.FeatureFilePath
+	}
+	return nfd.DefaultFeatureFilePath
+}
+
+// Trigger a reconcile event for the managed hypervisor through the
+// event channel which is watched by the controller manager.
+func (r *HypervisorReconciler) triggerReconcile() {
+	r.reconcileCh <- event.GenericEvent{
+		Object: &kvmv1.Hypervisor{
+			TypeMeta: metav1.TypeMeta{
+				Kind:       "Hypervisor",
+				APIVersion: "kvm.cloud.sap/v1",
+			},
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      sys.Hostname,
+				Namespace: sys.Namespace,
+			},
+		},
+	}
+}
+
+// Start is called when the manager starts. It starts the libvirt
+// event subscription to receive events when the hypervisor needs to be
+// reconciled.
+func (r *HypervisorReconciler) Start(ctx context.Context) error {
+	log := logger.FromContext(ctx, "controller", "hypervisor")
+	log.Info("starting libvirt event subscription")
+
+	// Get the hypervisor we will reconcile.
+	var hypervisor kvmv1.Hypervisor
+	key := client.ObjectKey{Name: sys.Hostname} // Cluster-scoped
+	if err := r.Get(ctx, key, &hypervisor); err != nil {
+		return fmt.Errorf("unable to get hypervisor: %w", err)
+	}
+
+	// Reconcile as soon as a unit changes instead of waiting for the ticker.
+	// The units of the runtime configuration are looked up on every change.
+	if r.UnitWatcher != nil {
+		if err := r.UnitWatcher.WatchUnits(ctx, r.unitNames, func(unit string) {
+			log.V(1).Info("unit changed", "unit", unit)
+			r.triggerReconcile()
+		}); err != nil {
+			log.Error(err, "unable to watch units, only checking them on reconcile")
+		}
+	}
+
+	// Block until we're connected to libvirt.
+	for {
+		// Exit if the context is done, e.g. when the manager is shutting down.
+		if ctx.Err() != nil {
+			return fmt.Errorf("context done while trying to connect to libvirt: %w", ctx.Err())
+		}
+		err := r.Libvirt.Connect()
+		if err == nil {
+			log.Info("connected to libvirt")
+			break // Connected successfully
+		}
+		log.Error(err, "unable to connect to libvirt")
+		// Set the hypervisor's LibVirtType condition to false with the
+		// error message, so that it's visible in the status.
+		meta.SetStatusCondition(&hypervisor.Status.Conditions, metav1.Condition{
+			Type:    LibVirtType, // TODO: This should be a kvmv1 condition.
+			Status:  metav1.ConditionFalse,
+			Message: fmt.Sprintf("unable to connect to libvirt: %v", err),
+			Reason:  "ConnectFailed",
+		})
+		if err := r.applyStatus(ctx, &hypervisor); err != nil {
+			log.Error(err, "unable to update hypervisor status after failed libvirt connection")
+		}
+		log.Info("updated hypervisor status after failed libvirt connection")
+		timeToSleep := r.libvirtConnectInterval
+		if timeToSleep == 0 {
+			timeToSleep = 5 * time.Second // default value
+		}
+		log.Info("retrying libvirt connection after sleeping", "duration", timeToSleep)
+		time.Sleep(timeToSleep)
+	}
+
+	// Run a timer which reconciles the hypervisor resource every stats
+	// interval. This ensures that we periodically reconcile the hypervisor
+	// even if no events are received from libvirt.
+	go func() {
+		for {
+			select {
+			case <-time.After(r.RuntimeConfig.Get().StatsInterval.Duration):
+				r.triggerReconcile()
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	// Report the generation of the runtime configuration as soon as it is
+	// applied.
+	r.RuntimeConfig.Subscribe(func(config.Runtime) {
+		go r.triggerReconcile()
+	})
+
+	// Domain lifecycle events impact the list of active/inactive domains,
+	// as well as the allocation of resources on the hypervisor.
+	r.Libvirt.WatchDomainChanges(
+		golibvirt.DomainEventIDLifecycle,
+		"reconcile-on-domain-lifecycle",
+		func(_ context.Context, _ any) { r.triggerReconcile() },
+	)
+
+	return nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *HypervisorReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	ctx := context.Background()
+
+	var err error
+	if r.osDescriptor, err = r.Systemd.Describe(ctx); err != nil {
+		return fmt.Errorf("unable to get Systemd hostname describe(): %w", err)
+	}
+
+	if r.KernelReader == nil {
+		r.KernelReader = kernel.NewSystemReader()
+	}
+	if r.kernelParameters, err = r.KernelReader.ReadParameters(); err != nil {
+		return fmt.Errorf("unable to read kernel parameters: %w", err)
+	}
+
+	if r.OVS == nil {
+		r.OVS = ovs.NewClient()
+	}
+	if r.Entropy == nil {
+		r.Entropy = entropy.NewSystemReader()
+	}
+	if r.IOMMU == nil {
+		r.IOMMU = iommu.NewSystemReader()
+	}
+
+	// Prepare an event channel that will trigger a reconcile event.
+	r.reconcileCh = make(chan event.GenericEvent)
+	src := source.Channel(r.reconcileCh, &handler.EnqueueRequestForObject{})
+	// Run the Start(ctx context.Context) method when the manager starts.
+	if err := mgr.Add(r); err != nil {
+		return err
+	}
+
+	builder := ctrl.NewControllerManagedBy(mgr).
+		For(&kvmv1.Hypervisor{}).
+		WatchesRawSource(src)
+	if len(r.NodeLabels) > 0 || r.SyncNodeTaints || r.Finalizer {
+		// The hypervisor is named like its node.
+		builder = builder.Watches(&corev1.Node{}, handler.EnqueueRequestsFromMapFunc(
+			func(ctx context.Context, a client.Object) []reconcile.Request {
+				return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: a.GetName()}}}
+			}))
+	}
+	return builder.Complete(r)
 }
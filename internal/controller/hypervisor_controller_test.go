@@ -20,6 +20,10 @@ package controller
 import (
 	"context"
 	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
 	"strings"
 	"time"
 
@@ -28,21 +32,2014 @@ import (
 	golibvirt "github.com/digitalocean/go-libvirt"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/events"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
+	"github.com/cobaltcore-dev/kvm-node-agent/api/v1alpha1"
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/boot"
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/config"
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/entropy"
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/hoststorage"
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/iommu"
 	"github.com/cobaltcore-dev/kvm-node-agent/internal/kernel"
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/ksm"
 	"github.com/cobaltcore-dev/kvm-node-agent/internal/libvirt"
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/libvirt/virterr"
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/memory"
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/ovs"
 	"github.com/cobaltcore-dev/kvm-node-agent/internal/sys"
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/sysctl"
 	"github.com/cobaltcore-dev/kvm-node-agent/internal/systemd"
 )
 
 var _ = Describe("Hypervisor Controller", func() {
+	Context("When checking the domain policy", func() {
+		var (
+			hypervisor *kvmv1.Hypervisor
+			dryRuns    []bool
+			violations []libvirt.DomainPolicyViolation
+			reconciler *HypervisorReconciler
+		)
+
+		BeforeEach(func() {
+			hypervisor = &kvmv1.Hypervisor{}
+			meta.SetStatusCondition(&hypervisor.Status.Conditions, metav1.Condition{
+				Type:   LibVirtType,
+				Status: metav1.ConditionTrue,
+				Reason: "Connected",
+			})
+			dryRuns = nil
+			violations = []libvirt.DomainPolicyViolation{
+				{UUID: "uuid-1", Name: "instance-0001", Autostart: true},
+			}
+			reconciler = &HypervisorReconciler{
+				DomainPolicy: domainPolicyFunc(func(dryRun bool) ([]libvirt.DomainPolicyViolation, error) {
+					dryRuns = append(dryRuns, dryRun)
+					return violations, nil
+				}),
+			}
+		})
+
+		It("should only report violations in dry-run mode", func() {
+			reconciler.DomainPolicyMode = libvirt.DomainPolicyDryRun
+			reconciler.reconcileDomainPolicy(context.Background(), hypervisor)
+			Expect(dryRuns).To(Equal([]bool{true}))
+			condition := meta.FindStatusCondition(hypervisor.Status.Conditions, PolicyType)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+			Expect(condition.Reason).To(Equal("ViolationsFound"))
+			Expect(condition.Message).To(Equal("instance-0001: autostart"))
+		})
+
+		It("should fix violations in enforce mode", func() {
+			reconciler.DomainPolicyMode = libvirt.DomainPolicyEnforce
+			reconciler.reconcileDomainPolicy(context.Background(), hypervisor)
+			Expect(dryRuns).To(Equal([]bool{false}))
+			condition := meta.FindStatusCondition(hypervisor.Status.Conditions, PolicyType)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+			Expect(condition.Reason).To(Equal("Enforced"))
+		})
+
+		It("should report the managed save images kept in enforce mode", func() {
+			reconciler.DomainPolicyMode = libvirt.DomainPolicyEnforce
+			violations = append(violations,
+				libvirt.DomainPolicyViolation{UUID: "uuid-2", Name: "instance-0002", ManagedSave: true},
+				libvirt.DomainPolicyViolation{UUID: "uuid-3", Name: "instance-0003", ManagedSave: true, StaleManagedSave: true},
+			)
+			reconciler.reconcileDomainPolicy(context.Background(), hypervisor)
+			condition := meta.FindStatusCondition(hypervisor.Status.Conditions, PolicyType)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+			Expect(condition.Reason).To(Equal("ManagedSaveKept"))
+		})
+
+		It("should report compliance without violations", func() {
+			reconciler.DomainPolicyMode = libvirt.DomainPolicyDryRun
+			violations = nil
+			reconciler.reconcileDomainPolicy(context.Background(), hypervisor)
+			Expect(meta.IsStatusConditionTrue(hypervisor.Status.Conditions, PolicyType)).To(BeTrue())
+		})
+
+		It("should not check the policy when turned off", func() {
+			reconciler.DomainPolicyMode = libvirt.DomainPolicyOff
+			reconciler.reconcileDomainPolicy(context.Background(), hypervisor)
+			Expect(dryRuns).To(BeEmpty())
+			Expect(meta.FindStatusCondition(hypervisor.Status.Conditions, PolicyType)).To(BeNil())
+		})
+	})
+
+	Context("When looking for leftovers of crashed domains", func() {
+		var (
+			hypervisor *kvmv1.Hypervisor
+			dryRuns    []bool
+			leftovers  []libvirt.Leftover
+			reconciler *HypervisorReconciler
+		)
+
+		BeforeEach(func() {
+			hypervisor = &kvmv1.Hypervisor{}
+			meta.SetStatusCondition(&hypervisor.Status.Conditions, metav1.Condition{
+				Type:   LibVirtType,
+				Status: metav1.ConditionTrue,
+				Reason: "Connected",
+			})
+			dryRuns = nil
+			leftovers = []libvirt.Leftover{
+				{Kind: libvirt.LeftoverStateDir, Path: "/var/lib/libvirt/qemu/domain-3-instance-0003"},
+			}
+			reconciler = &HypervisorReconciler{
+				Janitor: leftoverJanitorFunc(func(dryRun bool, ovsSockets []string) ([]libvirt.Leftover, error) {
+					dryRuns = append(dryRuns, dryRun)
+					found := slices.Clone(leftovers)
+					for i := range found {
+						found[i].Removed = !dryRun && found[i].Kind != libvirt.LeftoverTapDevice &&
+							!slices.Contains(ovsSockets, filepath.Base(found[i].Path))
+					}
+					return found, nil
+				}),
+			}
+		})
+
+		It("should only report leftovers in dry-run mode", func() {
+			reconciler.JanitorMode = libvirt.DomainPolicyDryRun
+			reconciler.reconcileLeftovers(context.Background(), hypervisor)
+			Expect(dryRuns).To(Equal([]bool{true}))
+			condition := meta.FindStatusCondition(hypervisor.Status.Conditions, LeftoversType)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+			Expect(condition.Reason).To(Equal("LeftoversFound"))
+			Expect(condition.Message).To(Equal("StateDir domain-3-instance-0003"))
+		})
+
+		It("should remove leftovers in enforce mode", func() {
+			reconciler.JanitorMode = libvirt.DomainPolicyEnforce
+			reconciler.reconcileLeftovers(context.Background(), hypervisor)
+			Expect(dryRuns).To(Equal([]bool{false}))
+			condition := meta.FindStatusCondition(hypervisor.Status.Conditions, LeftoversType)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+			Expect(condition.Reason).To(Equal("Removed"))
+		})
+
+		It("should keep reporting tap devices in enforce mode", func() {
+			reconciler.JanitorMode = libvirt.DomainPolicyEnforce
+			leftovers = append(leftovers, libvirt.Leftover{Kind: libvirt.LeftoverTapDevice, Path: "/host/sys/class/net/tap0001"})
+			reconciler.reconcileLeftovers(context.Background(), hypervisor)
+			condition := meta.FindStatusCondition(hypervisor.Status.Conditions, LeftoversType)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+			Expect(condition.Message).To(Equal("TapDevice tap0001"))
+		})
+
+		It("should keep the sockets served by open vswitch", func() {
+			reconciler.JanitorMode = libvirt.DomainPolicyEnforce
+			reconciler.OVS = ovsFunc(func(context.Context) (*ovs.Status, error) {
+				return &ovs.Status{Ports: []ovs.Port{
+					{Name: "vhu0001", Type: "dpdkvhostuser"},
+					{Name: "vhu0002", Type: "dpdkvhostuserclient"},
+				}}, nil
+			})
+			leftovers = []libvirt.Leftover{{Kind: libvirt.LeftoverVhostUserSocket, Path: "/run/openvswitch/vhu0001"}}
+			reconciler.reconcileLeftovers(context.Background(), hypervisor)
+			condition := meta.FindStatusCondition(hypervisor.Status.Conditions, LeftoversType)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+			Expect(condition.Message).To(Equal("VhostUserSocket vhu0001"))
+		})
+
+		It("should not clean up leftovers without the open vswitch status", func() {
+			reconciler.JanitorMode = libvirt.DomainPolicyEnforce
+			reconciler.OVS = ovsFunc(func(context.Context) (*ovs.Status, error) {
+				return nil, errors.New("connection refused")
+			})
+			reconciler.reconcileLeftovers(context.Background(), hypervisor)
+			Expect(dryRuns).To(BeEmpty())
+			condition := meta.FindStatusCondition(hypervisor.Status.Conditions, LeftoversType)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionUnknown))
+		})
+
+		It("should not look for leftovers without a libvirt connection", func() {
+			reconciler.JanitorMode = libvirt.DomainPolicyDryRun
+			meta.SetStatusCondition(&hypervisor.Status.Conditions, metav1.Condition{
+				Type:   LibVirtType,
+				Status: metav1.ConditionFalse,
+				Reason: "Disconnected",
+			})
+			reconciler.reconcileLeftovers(context.Background(), hypervisor)
+			Expect(dryRuns).To(BeEmpty())
+		})
+
+		It("should not look for leftovers when turned off", func() {
+			reconciler.JanitorMode = libvirt.DomainPolicyOff
+			reconciler.reconcileLeftovers(context.Background(), hypervisor)
+			Expect(dryRuns).To(BeEmpty())
+			Expect(meta.FindStatusCondition(hypervisor.Status.Conditions, LeftoversType)).To(BeNil())
+		})
+	})
+
+	Context("When checking the domain definitions for drift", func() {
+		var (
+			hypervisor *kvmv1.Hypervisor
+			drifts     []libvirt.DomainDrift
+			reconciler *HypervisorReconciler
+		)
+
+		BeforeEach(func() {
+			hypervisor = &kvmv1.Hypervisor{}
+			meta.SetStatusCondition(&hypervisor.Status.Conditions, metav1.Condition{
+				Type:   LibVirtType,
+				Status: metav1.ConditionTrue,
+				Reason: "Connected",
+			})
+			drifts = []libvirt.DomainDrift{
+				{UUID: "uuid-1", Name: "instance-0001", Kinds: []string{libvirt.DriftDevices, libvirt.DriftMemory}},
+			}
+			reconciler = &HypervisorReconciler{
+				DomainDrift: domainDriftFunc(func() ([]libvirt.DomainDrift, error) {
+					return drifts, nil
+				}),
+			}
+		})
+
+		It("should report drifted domains", func() {
+			reconciler.reconcileDomainDrift(context.Background(), hypervisor)
+			condition := meta.FindStatusCondition(hypervisor.Status.Conditions, DriftType)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+			Expect(condition.Reason).To(Equal("DriftDetected"))
+			Expect(condition.Message).To(Equal("instance-0001: devices, memory"))
+		})
+
+		It("should report domains in sync without drift", func() {
+			drifts = nil
+			reconciler.reconcileDomainDrift(context.Background(), hypervisor)
+			Expect(meta.IsStatusConditionTrue(hypervisor.Status.Conditions, DriftType)).To(BeTrue())
+		})
+
+		It("should not check for drift without a detector", func() {
+			reconciler.DomainDrift = nil
+			reconciler.reconcileDomainDrift(context.Background(), hypervisor)
+			Expect(meta.FindStatusCondition(hypervisor.Status.Conditions, DriftType)).To(BeNil())
+		})
+	})
+
+	Context("When checking the entropy sources", func() {
+		var (
+			hypervisor *kvmv1.Hypervisor
+			sources    entropy.Sources
+			reconciler *HypervisorReconciler
+		)
+
+		BeforeEach(func() {
+			hypervisor = &kvmv1.Hypervisor{}
+			sources = entropy.Sources{RDRAND: true, RDSEED: true, EntropyAvailable: 256}
+			reconciler = &HypervisorReconciler{
+				Client: k8sClient,
+				Entropy: entropyFunc(func() (*entropy.Sources, error) {
+					return &sources, nil
+				}),
+			}
+		})
+
+		It("should report available hardware sources", func() {
+			reconciler.reconcileEntropy(context.Background(), hypervisor)
+			condition := meta.FindStatusCondition(hypervisor.Status.Conditions, EntropyType)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+			Expect(condition.Reason).To(Equal("Available"))
+			Expect(condition.Message).To(Equal("rdrand, rdseed, 256 bits available"))
+		})
+
+		It("should report hosts without hardware source", func() {
+			sources = entropy.Sources{EntropyAvailable: 3}
+			reconciler.reconcileEntropy(context.Background(), hypervisor)
+			condition := meta.FindStatusCondition(hypervisor.Status.Conditions, EntropyType)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+			Expect(condition.Reason).To(Equal("NoHardwareSource"))
+		})
+
+		It("should report domains without rng device on hosts without hardware source", func() {
+			scheme := runtime.NewScheme()
+			Expect(v1alpha1.AddToScheme(scheme)).To(Succeed())
+			reconciler.Client = fake.NewClientBuilder().WithScheme(scheme).WithObjects(&v1alpha1.Instance{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "instance-0001",
+					Namespace: sys.Namespace,
+					Labels:    map[string]string{v1alpha1.LabelHypervisor: sys.NodeLabelName},
+				},
+			}).Build()
+			sources = entropy.Sources{EntropyAvailable: 3}
+			reconciler.reconcileEntropy(context.Background(), hypervisor)
+			condition := meta.FindStatusCondition(hypervisor.Status.Conditions, EntropyType)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+			Expect(condition.Reason).To(Equal("NoHardwareSource"))
+			Expect(condition.Message).To(HaveSuffix("; 1 domains have no rng device: instance-0001"))
+		})
+
+		It("should report an unknown state if the instances can't be listed", func() {
+			// The instances can't be listed without their types in the scheme.
+			reconciler.Client = fake.NewClientBuilder().WithScheme(runtime.NewScheme()).Build()
+			reconciler.reconcileEntropy(context.Background(), hypervisor)
+			condition := meta.FindStatusCondition(hypervisor.Status.Conditions, EntropyType)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionUnknown))
+			Expect(condition.Reason).To(Equal("CheckFailed"))
+		})
+	})
+
+	Context("When reporting the shutdown inhibit delay", func() {
+		It("should report a delay too short for the evacuation", func() {
+			hypervisor := &kvmv1.Hypervisor{}
+			delay := 5 * time.Second
+			reconciler := &HypervisorReconciler{
+				Client: k8sClient,
+				Systemd: &systemd.InterfaceMock{
+					InhibitDelayMaxFunc: func(context.Context) (time.Duration, error) {
+						return delay, nil
+					},
+				},
+			}
+
+			By("Not reporting without evacuation on reboot")
+			reconciler.reconcileShutdownInhibit(context.Background(), hypervisor)
+			Expect(meta.FindStatusCondition(hypervisor.Status.Conditions, InhibitType)).To(BeNil())
+
+			By("Reporting the default delay of logind as too short")
+			reconciler.evacuateOnReboot = true
+			reconciler.reconcileShutdownInhibit(context.Background(), hypervisor)
+			condition := meta.FindStatusCondition(hypervisor.Status.Conditions, InhibitType)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+			Expect(condition.Reason).To(Equal("DelayTooShort"))
+
+			By("Reporting a sufficient delay")
+			delay = 30 * time.Minute
+			reconciler.reconcileShutdownInhibit(context.Background(), hypervisor)
+			condition = meta.FindStatusCondition(hypervisor.Status.Conditions, InhibitType)
+			Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+			Expect(condition.Message).To(Equal("shutdown is delayed for up to 30m0s"))
+		})
+	})
+
+	Context("When probing the libvirt connection", func() {
+		var (
+			hypervisor *kvmv1.Hypervisor
+			health     libvirt.ConnectionHealth
+			reconciler *HypervisorReconciler
+		)
+
+		BeforeEach(func() {
+			hypervisor = &kvmv1.Hypervisor{}
+			meta.SetStatusCondition(&hypervisor.Status.Conditions, metav1.Condition{
+				Type:   LibVirtType,
+				Status: metav1.ConditionTrue,
+				Reason: "Connected",
+			})
+			health = libvirt.ConnectionHealth{Latency: 2 * time.Millisecond}
+			reconciler = &HypervisorReconciler{
+				Client: k8sClient,
+				ConnectionProber: connectionProberFunc(func() libvirt.ConnectionHealth {
+					return health
+				}),
+			}
+		})
+
+		It("should report a responsive connection", func() {
+			reconciler.reconcileLibvirtHealth(context.Background(), hypervisor)
+			condition := meta.FindStatusCondition(hypervisor.Status.Conditions, DegradedType)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+			Expect(condition.Reason).To(Equal("Responsive"))
+		})
+
+		It("should report slow responses", func() {
+			health = libvirt.ConnectionHealth{Latency: 3 * time.Second}
+			reconciler.reconcileLibvirtHealth(context.Background(), hypervisor)
+			condition := meta.FindStatusCondition(hypervisor.Status.Conditions, DegradedType)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+			Expect(condition.Reason).To(Equal("SlowResponses"))
+			Expect(condition.Message).To(Equal("rpc round trip 3s"))
+		})
+
+		It("should report failing probes", func() {
+			health = libvirt.ConnectionHealth{ConsecutiveFailures: 3, Err: errors.New("broken pipe")}
+			reconciler.reconcileLibvirtHealth(context.Background(), hypervisor)
+			condition := meta.FindStatusCondition(hypervisor.Status.Conditions, DegradedType)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+			Expect(condition.Reason).To(Equal("Unresponsive"))
+			Expect(condition.Message).To(Equal("3 probes failed in a row: broken pipe"))
+		})
+
+		It("should not probe without a connection", func() {
+			meta.SetStatusCondition(&hypervisor.Status.Conditions, metav1.Condition{
+				Type:   LibVirtType,
+				Status: metav1.ConditionFalse,
+				Reason: "ConnectFailed",
+			})
+			reconciler.reconcileLibvirtHealth(context.Background(), hypervisor)
+			Expect(meta.FindStatusCondition(hypervisor.Status.Conditions, DegradedType)).To(BeNil())
+		})
+	})
+
+	Context("When checking the disk usage", func() {
+		var (
+			hypervisor *kvmv1.Hypervisor
+			disks      []libvirt.DiskUsage
+			filesystem libvirt.FilesystemUsage
+			recorder   *events.FakeRecorder
+			reconciler *HypervisorReconciler
+		)
+
+		BeforeEach(func() {
+			hypervisor = &kvmv1.Hypervisor{}
+			meta.SetStatusCondition(&hypervisor.Status.Conditions, metav1.Condition{
+				Type:   LibVirtType,
+				Status: metav1.ConditionTrue,
+				Reason: "Connected",
+			})
+			disks = []libvirt.DiskUsage{{Domain: "uuid-1", Device: "vda", Allocation: 50, Capacity: 100}}
+			filesystem = libvirt.FilesystemUsage{Path: libvirt.InstancesPath, Used: 40, Size: 100}
+			recorder = events.NewFakeRecorder(10)
+			reconciler = &HypervisorReconciler{
+				Client:   k8sClient,
+				Recorder: recorder,
+				DiskUsage: diskUsageFunc(func() ([]libvirt.DiskUsage, libvirt.FilesystemUsage, error) {
+					return disks, filesystem, nil
+				}),
+				DiskWatermark: 0.9,
+			}
+		})
+
+		It("should report no pressure below the watermark", func() {
+			reconciler.reconcileDiskPressure(context.Background(), hypervisor)
+			condition := meta.FindStatusCondition(hypervisor.Status.Conditions, DiskPressureType)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+			Expect(condition.Reason).To(Equal("BelowWatermark"))
+			Expect(recorder.Events).To(BeEmpty())
+		})
+
+		It("should report disks above the watermark once", func() {
+			disks = append(disks, libvirt.DiskUsage{Domain: "uuid-2", Device: "vdb", Allocation: 95, Capacity: 100})
+			reconciler.reconcileDiskPressure(context.Background(), hypervisor)
+			condition := meta.FindStatusCondition(hypervisor.Status.Conditions, DiskPressureType)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+			Expect(condition.Reason).To(Equal("DisksAboveWatermark"))
+			Expect(condition.Message).To(Equal("uuid-2/vdb 95%"))
+			Expect(recorder.Events).To(HaveLen(1))
+
+			By("Not repeating the event while the watermark stays crossed")
+			reconciler.reconcileDiskPressure(context.Background(), hypervisor)
+			Expect(recorder.Events).To(HaveLen(1))
+		})
+
+		It("should report the filesystem above the watermark", func() {
+			filesystem.Used = 92
+			reconciler.reconcileDiskPressure(context.Background(), hypervisor)
+			condition := meta.FindStatusCondition(hypervisor.Status.Conditions, DiskPressureType)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+			Expect(condition.Reason).To(Equal("FilesystemAboveWatermark"))
+			Expect(condition.Message).To(Equal(libvirt.InstancesPath + " 92%"))
+		})
+
+		It("should not check the disks without a watermark", func() {
+			reconciler.DiskWatermark = 0
+			reconciler.reconcileDiskPressure(context.Background(), hypervisor)
+			Expect(meta.FindStatusCondition(hypervisor.Status.Conditions, DiskPressureType)).To(BeNil())
+		})
+	})
+
+	Context("When watching the memory pressure", func() {
+		var (
+			hypervisor *kvmv1.Hypervisor
+			pressure   memory.Pressure
+			reclaimer  *fakeMemoryReclaimer
+			recorder   *events.FakeRecorder
+			reconciler *HypervisorReconciler
+		)
+
+		instance := func(name, priority string, memoryMB int) *v1alpha1.Instance {
+			instance := &v1alpha1.Instance{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      name,
+					Namespace: sys.Namespace,
+					Labels:    map[string]string{v1alpha1.LabelHypervisor: sys.NodeLabelName},
+				},
+				Status: v1alpha1.InstanceStatus{
+					Active: true,
+					Flavor: v1alpha1.InstanceFlavor{MemoryMB: memoryMB},
+				},
+			}
+			if priority != "" {
+				instance.Annotations = map[string]string{MemoryPriorityAnnotation: priority}
+			}
+			return instance
+		}
+
+		BeforeEach(func() {
+			hypervisor = &kvmv1.Hypervisor{}
+			pressure = memory.Pressure{SomeAvg10: 1, TotalKiB: 1 << 30, AvailableKiB: 1 << 29}
+			reclaimer = &fakeMemoryReclaimer{}
+			recorder = events.NewFakeRecorder(10)
+			paused := instance("d", "0", 1024)
+			paused.Status.Pauses = &v1alpha1.InstancePauses{Since: &metav1.Time{Time: time.Now()}}
+			scheme := runtime.NewScheme()
+			Expect(v1alpha1.AddToScheme(scheme)).To(Succeed())
+			reconciler = &HypervisorReconciler{
+				Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+					instance("a", "5", 1024), instance("b", "1", 2048), instance("c", "", 1024), paused,
+				).Build(),
+				Recorder: recorder,
+				MemoryPressure: memoryPressureFunc(func() (*memory.Pressure, error) {
+					return &pressure, nil
+				}),
+				MemoryPressureThresholds: memory.Thresholds{SomeAvg10: 20, MinAvailable: 0.05},
+				MemoryPressureMode:       libvirt.DomainPolicyEnforce,
+				MemoryMitigations: []memory.Mitigation{
+					memory.MitigationAlert, memory.MitigationBalloon, memory.MitigationPause,
+				},
+				MemoryReclaimer: reclaimer,
+			}
+		})
+
+		It("should report no pressure below the thresholds", func() {
+			reconciler.reconcileMemoryPressure(context.Background(), hypervisor)
+			condition := meta.FindStatusCondition(hypervisor.Status.Conditions, MemoryType)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+			Expect(condition.Reason).To(Equal("BelowThreshold"))
+			Expect(recorder.Events).To(BeEmpty())
+		})
+
+		// Reconcile once the interval since the last mitigation passed.
+		escalate := func() {
+			reconciler.memoryMitigatedAt = reconciler.memoryMitigatedAt.Add(-memoryMitigationInterval)
+			reconciler.reconcileMemoryPressure(context.Background(), hypervisor)
+		}
+
+		It("should perform the mitigations in order while the pressure is critical", func() {
+			pressure.SomeAvg10 = 42
+			reconciler.reconcileMemoryPressure(context.Background(), hypervisor)
+			Expect(recorder.Events).To(HaveLen(1))
+			Expect(reclaimer.calls).To(BeEmpty())
+
+			escalate()
+			Expect(reclaimer.calls).To(Equal([]string{"balloon b 1572864", "balloon a 786432"}))
+
+			escalate()
+			Expect(reclaimer.calls).To(HaveLen(3))
+			Expect(reclaimer.calls[2]).To(Equal("suspend b"))
+			condition := meta.FindStatusCondition(hypervisor.Status.Conditions, MemoryType)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+			Expect(condition.Message).To(HaveSuffix("; alerted; ballooned b, a; paused b"))
+
+			By("Pausing the next instance instead of the one already paused")
+			escalate()
+			Expect(reclaimer.calls).To(HaveLen(4))
+			Expect(reclaimer.calls[3]).To(Equal("suspend a"))
+
+			By("Restoring the instances once the pressure is resolved")
+			reclaimer.calls = nil
+			pressure.SomeAvg10 = 1
+			reconciler.reconcileMemoryPressure(context.Background(), hypervisor)
+			Expect(reclaimer.calls).To(Equal([]string{
+				"resume b", "resume a", "balloon a 1048576", "balloon b 2097152",
+			}))
+			condition = meta.FindStatusCondition(hypervisor.Status.Conditions, MemoryType)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+			Expect(condition.Message).To(HaveSuffix("; resumed b, a; deflated a, b"))
+
+			By("Starting over once the pressure is critical again")
+			reclaimer.calls = nil
+			pressure.AvailableKiB = 1 << 20
+			reconciler.reconcileMemoryPressure(context.Background(), hypervisor)
+			Expect(recorder.Events).To(HaveLen(2))
+			Expect(reclaimer.calls).To(BeEmpty())
+		})
+
+		It("should wait between two mitigations", func() {
+			pressure.SomeAvg10 = 42
+			reconciler.reconcileMemoryPressure(context.Background(), hypervisor)
+			reconciler.reconcileMemoryPressure(context.Background(), hypervisor)
+			Expect(recorder.Events).To(HaveLen(1))
+			Expect(reclaimer.calls).To(BeEmpty())
+			condition := meta.FindStatusCondition(hypervisor.Status.Conditions, MemoryType)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Message).To(HaveSuffix("; alerted"))
+		})
+
+		It("should retry restoring instances until they are gone", func() {
+			pressure.SomeAvg10 = 42
+			reconciler.MemoryMitigations = []memory.Mitigation{memory.MitigationPause}
+			reconciler.reconcileMemoryPressure(context.Background(), hypervisor)
+			escalate()
+			Expect(reclaimer.calls).To(Equal([]string{"suspend b", "suspend a"}))
+
+			reclaimer.calls = nil
+			reclaimer.errs = map[string]error{
+				// e.g. a conflicting job of a running domain
+				"a": fmt.Errorf("failed to resume domain a: %w", virterr.ErrOperationInvalid),
+				"b": fmt.Errorf("failed to lookup domain b: %w", virterr.ErrDomainNotFound),
+			}
+			pressure.SomeAvg10 = 1
+			reconciler.reconcileMemoryPressure(context.Background(), hypervisor)
+			Expect(reclaimer.calls).To(Equal([]string{"resume b", "resume a"}))
+			Expect(reconciler.memoryPaused).To(Equal([]string{"a"}))
+
+			reclaimer.calls, reclaimer.errs = nil, nil
+			reconciler.reconcileMemoryPressure(context.Background(), hypervisor)
+			Expect(reclaimer.calls).To(Equal([]string{"resume a"}))
+			Expect(reconciler.memoryPaused).To(BeEmpty())
+		})
+
+		It("should only report the mitigations in dry-run mode", func() {
+			pressure.AvailableKiB = 1 << 20
+			reconciler.MemoryPressureMode = libvirt.DomainPolicyDryRun
+			reconciler.MemoryMitigations = []memory.Mitigation{memory.MitigationPause}
+			reconciler.reconcileMemoryPressure(context.Background(), hypervisor)
+			Expect(reclaimer.calls).To(BeEmpty())
+			condition := meta.FindStatusCondition(hypervisor.Status.Conditions, MemoryType)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Message).To(HaveSuffix("; would have paused b"))
+		})
+
+		It("should not watch the memory pressure when turned off", func() {
+			reconciler.MemoryPressureMode = libvirt.DomainPolicyOff
+			reconciler.reconcileMemoryPressure(context.Background(), hypervisor)
+			Expect(meta.FindStatusCondition(hypervisor.Status.Conditions, MemoryType)).To(BeNil())
+		})
+	})
+
+	Context("When checking the open vswitch ports", func() {
+		It("should find orphaned ports and ports down", func() {
+			status := &ovs.Status{Ports: []ovs.Port{
+				{Name: "br-int", Type: "internal", LinkState: "up"},
+				{Name: "tap1", LinkState: "down", InstanceID: "uuid-1"},
+				{Name: "tap2", LinkState: "down", InstanceID: "uuid-2"},
+				{Name: "tap3", Error: "could not open network device tap3 (No such device)", InstanceID: "uuid-3"},
+				{Name: "vhu4", Type: "dpdkvhostuserclient", LinkState: "up", InstanceID: "uuid-4"},
+			}}
+			instances := []v1alpha1.Instance{
+				{Status: v1alpha1.InstanceStatus{Active: true, Devices: v1alpha1.InstanceDevices{
+					Interfaces: []v1alpha1.InstanceInterface{{Type: "bridge", Target: "tap1"}},
+				}}},
+				{Status: v1alpha1.InstanceStatus{Active: false, Devices: v1alpha1.InstanceDevices{
+					Interfaces: []v1alpha1.InstanceInterface{{Type: "bridge", Target: "tap2"}},
+				}}},
+				{Status: v1alpha1.InstanceStatus{Active: true, Devices: v1alpha1.InstanceDevices{
+					Interfaces: []v1alpha1.InstanceInterface{{Type: "vhostuser", SocketPath: "/run/openvswitch/vhu4"}},
+				}}},
+			}
+			orphaned, down := ovsPortProblems(status, instances)
+			Expect(orphaned).To(Equal([]string{"tap3"}))
+			Expect(down).To(Equal([]string{"tap1"}))
+		})
+
+		It("should publish the datapath and not report a failed check as configured", func() {
+			ctx := context.Background()
+			scheme := runtime.NewScheme()
+			Expect(kvmv1.AddToScheme(scheme)).To(Succeed())
+			hypervisor := &kvmv1.Hypervisor{ObjectMeta: metav1.ObjectMeta{Name: sys.Hostname}}
+			// The instances can't be listed without their types in the scheme.
+			c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(hypervisor).Build()
+			reconciler := &HypervisorReconciler{
+				Client: c,
+				OVS: ovsFunc(func(context.Context) (*ovs.Status, error) {
+					return &ovs.Status{DPDKEnabled: true, DPDKInitialized: true, DPDKVersion: "DPDK 23.11.1"}, nil
+				}),
+			}
+			Expect(c.Get(ctx, types.NamespacedName{Name: sys.Hostname}, hypervisor)).To(Succeed())
+			Expect(reconciler.reconcileOVS(ctx, hypervisor, hypervisor.DeepCopy())).To(Succeed())
+
+			condition := meta.FindStatusCondition(hypervisor.Status.Conditions, OVSType)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionUnknown))
+			Expect(condition.Reason).To(Equal("CheckFailed"))
+
+			updated := &kvmv1.Hypervisor{}
+			Expect(c.Get(ctx, types.NamespacedName{Name: sys.Hostname}, updated)).To(Succeed())
+			Expect(updated.Annotations).To(HaveKeyWithValue(OVSDPDKAnnotation, "true"))
+			Expect(updated.Annotations).To(HaveKeyWithValue(OVSDPDKVersionAnnotation, "DPDK 23.11.1"))
+			Expect(updated.Annotations).To(HaveKeyWithValue(OVSHWOffloadAnnotation, "false"))
+		})
+	})
+
+	Context("When checking the host storage", func() {
+		var (
+			hypervisor *kvmv1.Hypervisor
+			usages     []hoststorage.Usage
+			readErr    error
+			reconciler *HypervisorReconciler
+		)
+
+		BeforeEach(func() {
+			hypervisor = &kvmv1.Hypervisor{}
+			usages = []hoststorage.Usage{
+				{Path: "/var/lib/nova", FreeBytes: 50, SizeBytes: 100, FreeInodes: 50, Inodes: 100},
+				{Path: "/var/lib/libvirt", FreeBytes: 50, SizeBytes: 100},
+			}
+			readErr = nil
+			reconciler = &HypervisorReconciler{
+				Client: k8sClient,
+				HostStorage: hostStorageFunc(func() ([]hoststorage.Usage, error) {
+					return usages, readErr
+				}),
+			}
+		})
+
+		It("should report sufficient storage", func() {
+			reconciler.reconcileHostStorage(context.Background(), hypervisor)
+			condition := meta.FindStatusCondition(hypervisor.Status.Conditions, HostStorageType)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+			Expect(condition.Reason).To(Equal("Sufficient"))
+		})
+
+		It("should report paths running out of inodes", func() {
+			usages[0].FreeInodes = 1
+			readErr = errors.New("no such file or directory")
+			reconciler.reconcileHostStorage(context.Background(), hypervisor)
+			condition := meta.FindStatusCondition(hypervisor.Status.Conditions, HostStorageType)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+			Expect(condition.Reason).To(Equal("LowStorage"))
+			Expect(condition.Message).To(Equal("/var/lib/nova 50% free, 1% inodes free"))
+		})
+
+		It("should report paths which can't be read", func() {
+			readErr = errors.New("no such file or directory")
+			reconciler.reconcileHostStorage(context.Background(), hypervisor)
+			condition := meta.FindStatusCondition(hypervisor.Status.Conditions, HostStorageType)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+			Expect(condition.Reason).To(Equal("ReadFailed"))
+		})
+	})
+
+	Context("When checking the iommu", func() {
+		var (
+			hypervisor *kvmv1.Hypervisor
+			status     iommu.Status
+			supported  bool
+			reconciler *HypervisorReconciler
+		)
+
+		BeforeEach(func() {
+			hypervisor = &kvmv1.Hypervisor{}
+			meta.SetStatusCondition(&hypervisor.Status.Conditions, metav1.Condition{
+				Type:   LibVirtType,
+				Status: metav1.ConditionTrue,
+				Reason: "Connected",
+			})
+			status = iommu.Status{Groups: 42}
+			supported = true
+			reconciler = &HypervisorReconciler{
+				Client: k8sClient,
+				IOMMU: iommuFunc(func() (*iommu.Status, error) {
+					return &status, nil
+				}),
+				HostIOMMU: hostIOMMUFunc(func() (bool, error) {
+					return supported, nil
+				}),
+			}
+		})
+
+		It("should report hosts ready for passthrough", func() {
+			reconciler.reconcileIOMMU(context.Background(), hypervisor)
+			condition := meta.FindStatusCondition(hypervisor.Status.Conditions, IOMMUType)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+			Expect(condition.Reason).To(Equal("Ready"))
+			Expect(condition.Message).To(Equal("42 iommu groups, vfio loaded"))
+		})
+
+		It("should report hosts booted without the iommu", func() {
+			status = iommu.Status{}
+			reconciler.reconcileIOMMU(context.Background(), hypervisor)
+			condition := meta.FindStatusCondition(hypervisor.Status.Conditions, IOMMUType)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+			Expect(condition.Reason).To(Equal("IOMMUDisabled"))
+		})
+
+		It("should report an iommu libvirt doesn't support", func() {
+			supported = false
+			reconciler.reconcileIOMMU(context.Background(), hypervisor)
+			condition := meta.FindStatusCondition(hypervisor.Status.Conditions, IOMMUType)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+			Expect(condition.Reason).To(Equal("IOMMUDisabled"))
+			Expect(condition.Message).To(HavePrefix("libvirt found no iommu"))
+		})
+
+		It("should report missing vfio modules", func() {
+			status.MissingModules = []string{"vfio_pci"}
+			reconciler.reconcileIOMMU(context.Background(), hypervisor)
+			condition := meta.FindStatusCondition(hypervisor.Status.Conditions, IOMMUType)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+			Expect(condition.Reason).To(Equal("VFIOModulesMissing"))
+		})
+	})
+
+	Context("When managing kernel parameters", func() {
+		var (
+			hypervisor  *kvmv1.Hypervisor
+			snippetPath string
+			reconciler  *HypervisorReconciler
+		)
+
+		BeforeEach(func() {
+			hypervisor = &kvmv1.Hypervisor{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{
+						kernel.AnnotationPrefix + "hugepagesz": "1G",
+						kernel.AnnotationPrefix + "isolcpus":   "2-31",
+					},
+				},
+			}
+			snippetPath = filepath.Join(GinkgoT().TempDir(), "90-kvm-node-agent.cfg")
+			reconciler = &HypervisorReconciler{
+				ManageKernelParameters: true,
+				KernelSnippetPath:      snippetPath,
+				kernelParameters:       &kernel.Parameters{CommandLine: "console=tty0 hugepagesz=1G"},
+			}
+		})
+
+		It("should write the snippet and require a reboot", func() {
+			reconciler.reconcileKernelParameters(context.Background(), hypervisor)
+			content, err := os.ReadFile(snippetPath)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(content)).To(ContainSubstring("hugepagesz=1G isolcpus=2-31"))
+			condition := meta.FindStatusCondition(hypervisor.Status.Conditions, RebootType)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+			Expect(condition.Reason).To(Equal("KernelParametersChanged"))
+			Expect(condition.Message).To(ContainSubstring("isolcpus=2-31"))
+		})
+
+		It("should report the reboot after a running update", func() {
+			hypervisor.Spec.Reboot = true
+			hypervisor.Status.Update.InProgress = true
+			reconciler.reconcileKernelParameters(context.Background(), hypervisor)
+			condition := meta.FindStatusCondition(hypervisor.Status.Conditions, RebootType)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Reason).To(Equal("RebootScheduled"))
+		})
+
+		It("should not require a reboot if the kernel was booted with the parameters", func() {
+			reconciler.kernelParameters.CommandLine = "console=tty0 hugepagesz=1G isolcpus=2-31"
+			reconciler.reconcileKernelParameters(context.Background(), hypervisor)
+			Expect(meta.IsStatusConditionFalse(hypervisor.Status.Conditions, RebootType)).To(BeTrue())
+		})
+
+		It("should reject invalid parameters without writing the snippet", func() {
+			hypervisor.Annotations[kernel.AnnotationPrefix+"isolcpus"] = "2-31\" $(reboot) \""
+			reconciler.reconcileKernelParameters(context.Background(), hypervisor)
+			_, err := os.Stat(snippetPath)
+			Expect(os.IsNotExist(err)).To(BeTrue())
+			condition := meta.FindStatusCondition(hypervisor.Status.Conditions, RebootType)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionUnknown))
+			Expect(condition.Reason).To(Equal("InvalidParameters"))
+		})
+
+		It("should not touch the snippet when turned off", func() {
+			reconciler.ManageKernelParameters = false
+			reconciler.reconcileKernelParameters(context.Background(), hypervisor)
+			_, err := os.Stat(snippetPath)
+			Expect(os.IsNotExist(err)).To(BeTrue())
+			Expect(meta.FindStatusCondition(hypervisor.Status.Conditions, RebootType)).To(BeNil())
+		})
+	})
+
+	Context("When configuring the units", func() {
+		It("should report the configured units in addition to the default units", func() {
+			reconciler := &HypervisorReconciler{
+				Units: []string{"virtlogd.service", "libvirtd.service", "multipathd.service"},
+			}
+			Expect(reconciler.unitNames()).To(Equal([]string{
+				"libvirtd.service", "openvswitch-switch.service", "virtlogd.service", "multipathd.service",
+			}))
+			Expect(reconciler.isAgentCondition("multipathd.service")).To(BeTrue())
+			Expect(reconciler.isAgentCondition("sshd.service")).To(BeFalse())
+			Expect(reconciler.isAgentCondition("Ready")).To(BeFalse())
+		})
+
+		It("should follow the units of the runtime config", func() {
+			reconciler := &HypervisorReconciler{RuntimeConfig: config.NewRuntimeStore()}
+			Expect(reconciler.unitNames()).NotTo(ContainElement("multipathd.service"))
+			runtime := config.DefaultRuntime()
+			runtime.Units = []string{"multipathd.service"}
+			reconciler.RuntimeConfig.Set(runtime, "1")
+			Expect(reconciler.unitNames()).To(ContainElement("multipathd.service"))
+		})
+
+		It("should report the sockets of the modular libvirt daemons", func() {
+			reconciler := &HypervisorReconciler{LibvirtDaemons: libvirt.DaemonsModular}
+			Expect(reconciler.unitNames()).To(Equal([]string{
+				"virtchd.socket", "virtnetworkd.socket", "virtstoraged.socket", "virtproxyd.socket",
+				"openvswitch-switch.service",
+			}))
+		})
+
+		It("should report the sockets of all libvirt drivers once", func() {
+			reconciler := &HypervisorReconciler{
+				LibvirtDaemons: libvirt.DaemonsModular,
+				LibvirtURIs:    []string{"ch:///system", "qemu:///system"},
+			}
+			Expect(reconciler.unitNames()).To(Equal([]string{
+				"virtchd.socket", "virtnetworkd.socket", "virtstoraged.socket", "virtproxyd.socket",
+				"virtqemud.socket", "openvswitch-switch.service",
+			}))
+			Expect(reconciler.isAgentCondition("LibVirtDriver.qemu")).To(BeTrue())
+		})
+	})
+
+	Context("When restarting units", func() {
+		It("should restart the requested units and remove the annotation", func() {
+			ctx := context.Background()
+
+			hypervisor := &kvmv1.Hypervisor{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "restart-units-test-hypervisor",
+					Annotations: map[string]string{
+						RestartUnitsAnnotation: "libvirtd.service, sshd.service,virtlogd.service",
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, hypervisor)).To(Succeed())
+			defer func() {
+				Expect(k8sClient.Delete(ctx, hypervisor)).To(Succeed())
+			}()
+
+			var restarted []string
+			reconciler := &HypervisorReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+				Units:  []string{"virtlogd.service"},
+				Systemd: &systemd.InterfaceMock{
+					RestartUnitFunc: func(ctx context.Context, unit string) (string, error) {
+						restarted = append(restarted, unit)
+						if unit == "virtlogd.service" {
+							return "failed", nil
+						}
+						return "done", nil
+					},
+				},
+			}
+			Expect(reconciler.reconcileUnitActions(ctx, hypervisor, hypervisor.DeepCopy())).To(Succeed())
+			Expect(restarted).To(Equal([]string{"libvirtd.service", "virtlogd.service"}))
+			Expect(hypervisor.Annotations).NotTo(HaveKey(RestartUnitsAnnotation))
+			condition := meta.FindStatusCondition(hypervisor.Status.Conditions, UnitActionType)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+			Expect(condition.Reason).To(Equal("RestartFailed"))
+			Expect(condition.Message).To(Equal("sshd.service: not managed by the agent, virtlogd.service: restart failed"))
+
+			updated := &kvmv1.Hypervisor{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: hypervisor.Name}, updated)).To(Succeed())
+			Expect(updated.Annotations).NotTo(HaveKey(RestartUnitsAnnotation))
+
+			By("Doing nothing without the annotation")
+			restarted = nil
+			Expect(reconciler.reconcileUnitActions(ctx, updated, updated.DeepCopy())).To(Succeed())
+			Expect(restarted).To(BeEmpty())
+		})
+	})
+
+	Context("When orchestrating the reboot", func() {
+		It("should reboot within the window once the instances are evacuated", func() {
+			ctx := context.Background()
+
+			// A window starting in two hours never contains the current time.
+			start := time.Now().UTC().Add(2 * time.Hour)
+			hypervisor := &kvmv1.Hypervisor{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "reboot-test-hypervisor",
+					Annotations: map[string]string{
+						RebootWindowAnnotation: start.Format("15:04") + "-" + start.Add(time.Hour).Format("15:04"),
+					},
+				},
+				Spec: kvmv1.HypervisorSpec{
+					OperatingSystemVersion: "1877.3",
+					Reboot:                 true,
+					EvacuateOnReboot:       true,
+				},
+			}
+			Expect(k8sClient.Create(ctx, hypervisor)).To(Succeed())
+			defer func() {
+				Expect(k8sClient.Delete(ctx, hypervisor)).To(Succeed())
+			}()
+			hypervisor.Status.OperatingSystem.Version = "1592.6"
+			hypervisor.Status.Update.Installed = "1877.3"
+			hypervisor.Status.Instances = []kvmv1.Instance{{ID: "instance-1", Name: "instance-1", Active: true}}
+
+			var started []string
+			booted := time.Now().Add(-time.Hour)
+			reconciler := &HypervisorReconciler{
+				Client:              k8sClient,
+				Scheme:              k8sClient.Scheme(),
+				RebootOrchestration: true,
+				Systemd: &systemd.InterfaceMock{
+					StartUnitFunc: func(ctx context.Context, unit string) (int, error) {
+						started = append(started, unit)
+						return 0, nil
+					},
+				},
+				bootTime: func() (time.Time, error) {
+					return booted, nil
+				},
+			}
+			expectReason := func(status metav1.ConditionStatus, reason string) {
+				GinkgoHelper()
+				condition := meta.FindStatusCondition(hypervisor.Status.Conditions, RebootPendingType)
+				Expect(condition).NotTo(BeNil())
+				Expect(condition.Status).To(Equal(status))
+				Expect(condition.Reason).To(Equal(reason))
+			}
+
+			Expect(reconciler.reconcileReboot(ctx, hypervisor, hypervisor.DeepCopy())).To(Succeed())
+			expectReason(metav1.ConditionTrue, "OutsideWindow")
+
+			By("Waiting for the evacuation without a window")
+			delete(hypervisor.Annotations, RebootWindowAnnotation)
+			Expect(reconciler.reconcileReboot(ctx, hypervisor, hypervisor.DeepCopy())).To(Succeed())
+			expectReason(metav1.ConditionTrue, "WaitingForEvacuation")
+			Expect(started).To(BeEmpty())
+
+			By("Rebooting once evicted")
+			// The host crashed and booted while waiting, the condition
+			// stays true and keeps its transition time.
+			meta.FindStatusCondition(hypervisor.Status.Conditions, RebootPendingType).LastTransitionTime =
+				metav1.NewTime(time.Now().Add(-2 * time.Hour))
+			hypervisor.Status.Evicted = true
+			Expect(reconciler.reconcileReboot(ctx, hypervisor, hypervisor.DeepCopy())).To(Succeed())
+			expectReason(metav1.ConditionTrue, "Rebooting")
+			Expect(started).To(Equal([]string{sysUpdateRebootTarget}))
+			Expect(hypervisor.Annotations).To(HaveKey(RebootRequestedAnnotation))
+
+			updated := &kvmv1.Hypervisor{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: hypervisor.Name}, updated)).To(Succeed())
+			Expect(meta.FindStatusCondition(updated.Status.Conditions, RebootPendingType)).NotTo(BeNil())
+			Expect(updated.Annotations).To(HaveKey(RebootRequestedAnnotation))
+
+			By("Reporting the reboot once booted again")
+			Expect(reconciler.reconcileReboot(ctx, hypervisor, hypervisor.DeepCopy())).To(Succeed())
+			expectReason(metav1.ConditionTrue, "Rebooting")
+			booted = time.Now().Add(time.Minute)
+			Expect(reconciler.reconcileReboot(ctx, hypervisor, hypervisor.DeepCopy())).To(Succeed())
+			expectReason(metav1.ConditionFalse, "Rebooted")
+
+			By("Not rebooting again for the same update")
+			Expect(reconciler.reconcileReboot(ctx, hypervisor, hypervisor.DeepCopy())).To(Succeed())
+			expectReason(metav1.ConditionFalse, "Rebooted")
+			Expect(started).To(HaveLen(1))
+		})
+	})
+
+	Context("When rolling back the operating system", func() {
+		It("should make the previous version the default boot entry", func() {
+			ctx := context.Background()
+
+			hypervisor := &kvmv1.Hypervisor{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "rollback-test-hypervisor",
+					Annotations: map[string]string{RollbackAnnotation: "true"},
+				},
+			}
+			Expect(k8sClient.Create(ctx, hypervisor)).To(Succeed())
+			defer func() {
+				Expect(k8sClient.Delete(ctx, hypervisor)).To(Succeed())
+			}()
+
+			loader := &bootLoader{entries: boot.Entries{
+				Current:  boot.NewEntry("gardenlinux-1877.3.efi"),
+				Fallback: boot.NewEntry("gardenlinux-1592.6.efi"),
+			}}
+			reconciler := &HypervisorReconciler{
+				Client:     k8sClient,
+				Scheme:     k8sClient.Scheme(),
+				BootLoader: loader,
+			}
+			Expect(reconciler.reconcileBootEntries(ctx, hypervisor, hypervisor.DeepCopy())).To(Succeed())
+			Expect(loader.entries.Default).To(Equal("gardenlinux-1592.6.efi"))
+			Expect(hypervisor.Annotations).NotTo(HaveKey(RollbackAnnotation))
+			condition := meta.FindStatusCondition(hypervisor.Status.Conditions, BootType)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Reason).To(Equal("DefaultChanged"))
+
+			updated := &kvmv1.Hypervisor{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: hypervisor.Name}, updated)).To(Succeed())
+			Expect(updated.Annotations).NotTo(HaveKey(RollbackAnnotation))
+
+			By("Reporting the entries once booted")
+			loader.entries = boot.Entries{
+				Current: boot.NewEntry("gardenlinux-1592.6.efi"),
+				Default: "gardenlinux-1592.6.efi",
+			}
+			Expect(reconciler.reconcileBootEntries(ctx, updated, updated.DeepCopy())).To(Succeed())
+			condition = meta.FindStatusCondition(updated.Status.Conditions, BootType)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+			Expect(condition.Reason).To(Equal("Available"))
+			Expect(condition.Message).To(Equal("current 1592.6 (gardenlinux-1592.6.efi), default gardenlinux-1592.6.efi"))
+
+			By("Failing without an older version")
+			updated.Annotations = map[string]string{RollbackAnnotation: "true"}
+			Expect(reconciler.reconcileBootEntries(ctx, updated, updated.DeepCopy())).To(Succeed())
+			condition = meta.FindStatusCondition(updated.Status.Conditions, BootType)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+			Expect(condition.Reason).To(Equal("RollbackFailed"))
+		})
+	})
+
+	Context("When publishing the host cpu model", func() {
+		It("should annotate the hypervisor with the cpu model", func() {
+			ctx := context.Background()
+			hypervisor := &kvmv1.Hypervisor{
+				ObjectMeta: metav1.ObjectMeta{Name: "host-cpu-test-hypervisor"},
+			}
+			Expect(k8sClient.Create(ctx, hypervisor)).To(Succeed())
+			defer func() {
+				Expect(k8sClient.Delete(ctx, hypervisor)).To(Succeed())
+			}()
+			meta.SetStatusCondition(&hypervisor.Status.Conditions, metav1.Condition{
+				Type:   LibVirtType,
+				Status: metav1.ConditionTrue,
+				Reason: "Connected",
+			})
+
+			model := libvirt.HostCPUModel{
+				Model:        "EPYC-Milan",
+				Vendor:       "AMD",
+				Microcode:    "167776721",
+				Features:     []string{"invtsc", "x2apic"},
+				TSCFrequency: 2994374000,
+			}
+			reconciler := &HypervisorReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+				HostCPU: hostCPUFunc(func() (libvirt.HostCPUModel, error) {
+					return model, nil
+				}),
+			}
+			Expect(reconciler.reconcileHostCPU(ctx, hypervisor, hypervisor.DeepCopy())).To(Succeed())
+
+			updated := &kvmv1.Hypervisor{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: hypervisor.Name}, updated)).To(Succeed())
+			Expect(updated.Annotations).To(HaveKeyWithValue(HostCPUModelAnnotation, "EPYC-Milan"))
+			Expect(updated.Annotations).To(HaveKeyWithValue(HostCPUVendorAnnotation, "AMD"))
+			Expect(updated.Annotations).To(HaveKeyWithValue(HostCPUMicrocodeAnnotation, "167776721"))
+			Expect(updated.Annotations).To(HaveKeyWithValue(HostCPUFeaturesAnnotation, "invtsc,x2apic"))
+			Expect(updated.Annotations).To(HaveKeyWithValue(HostTSCFrequencyAnnotation, "2994374000"))
+			Expect(updated.Annotations).To(HaveKeyWithValue(HostTSCScalingAnnotation, "false"))
+
+			By("Updating the microcode version")
+			model.Microcode = "167776722"
+			updated.Status.Conditions = hypervisor.Status.Conditions
+			Expect(reconciler.reconcileHostCPU(ctx, updated, updated.DeepCopy())).To(Succeed())
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: hypervisor.Name}, updated)).To(Succeed())
+			Expect(updated.Annotations).To(HaveKeyWithValue(HostCPUMicrocodeAnnotation, "167776722"))
+		})
+	})
+
+	Context("When publishing the baseline cpu", func() {
+		hypervisor := func(name, zone, arch, model string) *kvmv1.Hypervisor {
+			hypervisor := &kvmv1.Hypervisor{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:   name,
+					Labels: map[string]string{"topology.kubernetes.io/zone": zone},
+					Annotations: map[string]string{
+						HostCPUModelAnnotation:    model,
+						HostCPUVendorAnnotation:   "Intel",
+						HostCPUFeaturesAnnotation: "invtsc",
+					},
+				},
+			}
+			hypervisor.Status.Capabilities.HostCpuArch = arch
+			meta.SetStatusCondition(&hypervisor.Status.Conditions, metav1.Condition{
+				Type:   LibVirtType,
+				Status: metav1.ConditionTrue,
+				Reason: "Connected",
+			})
+			return hypervisor
+		}
+
+		It("should publish the baseline of the hypervisors in the group", func() {
+			ctx := context.Background()
+			self := hypervisor("a", "az-1", "x86_64", "Cascadelake-Server")
+			scheme := runtime.NewScheme()
+			Expect(kvmv1.AddToScheme(scheme)).To(Succeed())
+			c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+				self,
+				hypervisor("b", "az-1", "x86_64", "Skylake-Server-IBRS"),
+				hypervisor("c", "az-1", "aarch64", "Neoverse-N1"),
+				hypervisor("d", "az-2", "x86_64", "Haswell"),
+			).Build()
+			var computed [][]libvirt.HostCPUModel
+			reconciler := &HypervisorReconciler{
+				Client:           c,
+				APIReader:        c,
+				CPUBaselineLabel: "topology.kubernetes.io/zone",
+				CPUBaseline: cpuBaselineFunc(func(arch string, cpus []libvirt.HostCPUModel) (libvirt.HostCPUModel, error) {
+					Expect(arch).To(Equal("x86_64"))
+					computed = append(computed, cpus)
+					return libvirt.HostCPUModel{Model: "Skylake-Server", Vendor: "Intel", Features: []string{"invtsc", "ss"}}, nil
+				}),
+			}
+			Expect(c.Get(ctx, types.NamespacedName{Name: "a"}, self)).To(Succeed())
+			Expect(reconciler.reconcileCPUBaseline(ctx, self, self.DeepCopy())).To(Succeed())
+			Expect(computed).To(HaveLen(1))
+			Expect(computed[0]).To(HaveLen(2))
+
+			updated := &kvmv1.Hypervisor{}
+			Expect(c.Get(ctx, types.NamespacedName{Name: "a"}, updated)).To(Succeed())
+			Expect(updated.Annotations).To(HaveKeyWithValue(BaselineCPUModelAnnotation, "Skylake-Server"))
+			Expect(updated.Annotations).To(HaveKeyWithValue(BaselineCPUFeaturesAnnotation, "invtsc,ss"))
+			Expect(updated.Annotations).To(HaveKeyWithValue(BaselineCPUHostsAnnotation, "2"))
+
+			By("Not computing the baseline again for the same host cpus")
+			Expect(reconciler.reconcileCPUBaseline(ctx, updated, updated.DeepCopy())).To(Succeed())
+			Expect(computed).To(HaveLen(1))
+		})
+
+		It("should not publish a baseline without the group label", func() {
+			self := hypervisor("a", "", "x86_64", "Cascadelake-Server")
+			self.Labels = nil
+			reconciler := &HypervisorReconciler{
+				CPUBaselineLabel: "topology.kubernetes.io/zone",
+				CPUBaseline: cpuBaselineFunc(func(string, []libvirt.HostCPUModel) (libvirt.HostCPUModel, error) {
+					Fail("baseline computed without group")
+					return libvirt.HostCPUModel{}, nil
+				}),
+			}
+			Expect(reconciler.reconcileCPUBaseline(context.Background(), self, self.DeepCopy())).To(Succeed())
+			Expect(self.Annotations).NotTo(HaveKey(BaselineCPUModelAnnotation))
+		})
+	})
+
+	Context("When syncing the labels of the node", func() {
+		It("should copy the configured labels and the taints of the node", func() {
+			ctx := context.Background()
+			scheme := runtime.NewScheme()
+			Expect(kvmv1.AddToScheme(scheme)).To(Succeed())
+			Expect(corev1.AddToScheme(scheme)).To(Succeed())
+			node := &corev1.Node{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:   sys.Hostname,
+					Labels: map[string]string{"topology.kubernetes.io/zone": "az-1", "rack": "r1", "other": "x"},
+				},
+				Spec: corev1.NodeSpec{Taints: []corev1.Taint{
+					{Key: "maintenance", Value: "true", Effect: corev1.TaintEffectNoSchedule},
+					{Key: "cpu-generation", Effect: corev1.TaintEffectPreferNoSchedule},
+				}},
+			}
+			hypervisor := &kvmv1.Hypervisor{ObjectMeta: metav1.ObjectMeta{
+				Name:   sys.Hostname,
+				Labels: map[string]string{"rack": "r0", "cpu-generation": "gen1"},
+			}}
+			c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(node, hypervisor).Build()
+			reconciler := &HypervisorReconciler{
+				Client:         c,
+				NodeLabels:     []string{"topology.kubernetes.io/zone", "rack", "cpu-generation"},
+				SyncNodeTaints: true,
+			}
+			Expect(c.Get(ctx, types.NamespacedName{Name: sys.Hostname}, hypervisor)).To(Succeed())
+			Expect(reconciler.reconcileNodeMetadata(ctx, hypervisor, hypervisor.DeepCopy())).To(Succeed())
+
+			updated := &kvmv1.Hypervisor{}
+			Expect(c.Get(ctx, types.NamespacedName{Name: sys.Hostname}, updated)).To(Succeed())
+			Expect(updated.Labels).To(Equal(map[string]string{"topology.kubernetes.io/zone": "az-1", "rack": "r1"}))
+			Expect(updated.Annotations).To(HaveKeyWithValue(NodeTaintsAnnotation,
+				"cpu-generation:PreferNoSchedule,maintenance=true:NoSchedule"))
+
+			By("Not patching the hypervisor again without changes")
+			resourceVersion := updated.ResourceVersion
+			Expect(reconciler.reconcileNodeMetadata(ctx, updated, updated.DeepCopy())).To(Succeed())
+			Expect(c.Get(ctx, types.NamespacedName{Name: sys.Hostname}, updated)).To(Succeed())
+			Expect(updated.ResourceVersion).To(Equal(resourceVersion))
+		})
+	})
+
+	Context("When checking the capacity for the evacuation", func() {
+		It("should read the other hypervisors past the cache of the own one", func() {
+			scheme := runtime.NewScheme()
+			Expect(kvmv1.AddToScheme(scheme)).To(Succeed())
+			newHypervisor := func(name, capacity, allocation string) *kvmv1.Hypervisor {
+				hypervisor := &kvmv1.Hypervisor{ObjectMeta: metav1.ObjectMeta{Name: name}}
+				hypervisor.Spec.EvacuateOnReboot = true
+				hypervisor.Status.Capacity = map[kvmv1.ResourceName]resource.Quantity{
+					kvmv1.ResourceMemory: resource.MustParse(capacity),
+				}
+				hypervisor.Status.Allocation = map[kvmv1.ResourceName]resource.Quantity{
+					kvmv1.ResourceMemory: resource.MustParse(allocation),
+				}
+				return hypervisor
+			}
+			hypervisor := newHypervisor(sys.Hostname, "512Gi", "96Gi")
+			other := newHypervisor("other-host", "512Gi", "256Gi")
+			// The cache of the manager only holds the own hypervisor.
+			reconciler := &HypervisorReconciler{
+				Client:    fake.NewClientBuilder().WithScheme(scheme).WithObjects(hypervisor.DeepCopy()).Build(),
+				APIReader: fake.NewClientBuilder().WithScheme(scheme).WithObjects(hypervisor.DeepCopy(), other).Build(),
+			}
+
+			reconciler.reconcileEvacuationCapacity(context.Background(), hypervisor)
+			condition := meta.FindStatusCondition(hypervisor.Status.Conditions, CapacityType)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+			Expect(condition.Message).To(Equal("requires memory=96Gi; 1 hypervisors have memory=256Gi free"))
+		})
+	})
+
+	Context("When guarding the hypervisor with a finalizer", func() {
+		var scheme *runtime.Scheme
+		BeforeEach(func() {
+			scheme = runtime.NewScheme()
+			Expect(kvmv1.AddToScheme(scheme)).To(Succeed())
+			Expect(corev1.AddToScheme(scheme)).To(Succeed())
+		})
+
+		It("should detach the hypervisor from its node and adopt it for a recreated node", func() {
+			ctx := context.Background()
+			node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: sys.Hostname, UID: "node"}}
+			hypervisor := &kvmv1.Hypervisor{ObjectMeta: metav1.ObjectMeta{
+				Name: sys.Hostname,
+				OwnerReferences: []metav1.OwnerReference{
+					{APIVersion: "v1", Kind: "Node", Name: sys.Hostname, UID: "node"},
+				},
+			}}
+			c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(node, hypervisor).Build()
+			recorder := events.NewFakeRecorder(10)
+			reconciler := &HypervisorReconciler{Client: c, Recorder: recorder, Finalizer: true}
+			Expect(c.Get(ctx, types.NamespacedName{Name: sys.Hostname}, hypervisor)).To(Succeed())
+			Expect(reconciler.reconcileNodeOwner(ctx, hypervisor, hypervisor.DeepCopy())).To(Succeed())
+
+			updated := &kvmv1.Hypervisor{}
+			Expect(c.Get(ctx, types.NamespacedName{Name: sys.Hostname}, updated)).To(Succeed())
+			Expect(updated.OwnerReferences).To(BeEmpty())
+			Expect(updated.Annotations).To(HaveKeyWithValue(NodeUIDAnnotation, "node"))
+			Expect(recorder.Events).To(BeEmpty())
+
+			By("Adopting the hypervisor once the node was recreated")
+			Expect(c.Delete(ctx, node)).To(Succeed())
+			node = &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: sys.Hostname, UID: "new-node"}}
+			Expect(c.Create(ctx, node)).To(Succeed())
+			Expect(reconciler.reconcileNodeOwner(ctx, updated, updated.DeepCopy())).To(Succeed())
+			Expect(c.Get(ctx, types.NamespacedName{Name: sys.Hostname}, updated)).To(Succeed())
+			Expect(updated.Annotations).To(HaveKeyWithValue(NodeUIDAnnotation, "new-node"))
+			Expect(recorder.Events).To(HaveLen(1))
+			Expect(<-recorder.Events).To(ContainSubstring("Adopted"))
+		})
+
+		It("should only hold the finalizer while domains are running", func() {
+			ctx := context.Background()
+			hypervisor := &kvmv1.Hypervisor{ObjectMeta: metav1.ObjectMeta{Name: sys.Hostname}}
+			c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(hypervisor).Build()
+			Expect(c.Get(ctx, types.NamespacedName{Name: sys.Hostname}, hypervisor)).To(Succeed())
+			meta.SetStatusCondition(&hypervisor.Status.Conditions, metav1.Condition{
+				Type:   LibVirtType,
+				Status: metav1.ConditionTrue,
+				Reason: "Connected",
+			})
+			hypervisor.Status.NumInstances = 1
+			reconciler := &HypervisorReconciler{Client: c, Finalizer: true}
+
+			deleted, err := reconciler.reconcileFinalizer(ctx, hypervisor, hypervisor.DeepCopy())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(deleted).To(BeFalse())
+			updated := &kvmv1.Hypervisor{}
+			Expect(c.Get(ctx, types.NamespacedName{Name: sys.Hostname}, updated)).To(Succeed())
+			Expect(updated.Finalizers).To(ConsistOf(HypervisorFinalizer))
+
+			By("Releasing the finalizer once the host is evacuated")
+			base := updated.DeepCopy()
+			updated.Status = hypervisor.Status
+			updated.Status.NumInstances = 0
+			deleted, err = reconciler.reconcileFinalizer(ctx, updated, base)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(deleted).To(BeFalse())
+			Expect(c.Get(ctx, types.NamespacedName{Name: sys.Hostname}, updated)).To(Succeed())
+			Expect(updated.Finalizers).To(BeEmpty())
+		})
+
+		It("should block the deletion while domains are running", func() {
+			ctx := context.Background()
+			hypervisor := &kvmv1.Hypervisor{ObjectMeta: metav1.ObjectMeta{
+				Name:       sys.Hostname,
+				Finalizers: []string{HypervisorFinalizer},
+			}}
+			c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(hypervisor).Build()
+			Expect(c.Delete(ctx, hypervisor)).To(Succeed())
+			Expect(c.Get(ctx, types.NamespacedName{Name: sys.Hostname}, hypervisor)).To(Succeed())
+			meta.SetStatusCondition(&hypervisor.Status.Conditions, metav1.Condition{
+				Type:   LibVirtType,
+				Status: metav1.ConditionTrue,
+				Reason: "Connected",
+			})
+			hypervisor.Status.NumInstances = 2
+			reconciler := &HypervisorReconciler{Client: c, Finalizer: true}
+
+			deleted, err := reconciler.reconcileFinalizer(ctx, hypervisor, hypervisor.DeepCopy())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(deleted).To(BeFalse())
+			condition := meta.FindStatusCondition(hypervisor.Status.Conditions, DeletionType)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Reason).To(Equal("InstancesPresent"))
+
+			By("Removing the finalizer once the domains are gone")
+			hypervisor.Status.NumInstances = 0
+			deleted, err = reconciler.reconcileFinalizer(ctx, hypervisor, hypervisor.DeepCopy())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(deleted).To(BeTrue())
+			err = c.Get(ctx, types.NamespacedName{Name: sys.Hostname}, hypervisor)
+			Expect(apierrors.IsNotFound(err)).To(BeTrue())
+		})
+	})
+
+	Context("When publishing the confidential computing support", func() {
+		It("should annotate sev hypervisors with their guest limits and firmware", func() {
+			ctx := context.Background()
+			hypervisor := &kvmv1.Hypervisor{
+				ObjectMeta: metav1.ObjectMeta{Name: "sev-test-hypervisor"},
+			}
+			Expect(k8sClient.Create(ctx, hypervisor)).To(Succeed())
+			defer func() {
+				Expect(k8sClient.Delete(ctx, hypervisor)).To(Succeed())
+			}()
+			meta.SetStatusCondition(&hypervisor.Status.Conditions, metav1.Condition{
+				Type:   LibVirtType,
+				Status: metav1.ConditionTrue,
+				Reason: "Connected",
+			})
+
+			cc := libvirt.ConfidentialComputing{TDX: true}
+			reconciler := &HypervisorReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+				ConfidentialComputing: confidentialComputingFunc(func() (libvirt.ConfidentialComputing, error) {
+					return cc, nil
+				}),
+			}
+			Expect(reconciler.reconcileConfidentialComputing(ctx, hypervisor, hypervisor.DeepCopy())).To(Succeed())
+			updated := &kvmv1.Hypervisor{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: hypervisor.Name}, updated)).To(Succeed())
+			Expect(updated.Annotations).NotTo(HaveKey(SEVMaxGuestsAnnotation))
+
+			By("Enabling sev")
+			cc = libvirt.ConfidentialComputing{
+				SEV:                true,
+				SEVES:              true,
+				SEVMaxGuests:       15,
+				SEVESMaxGuests:     494,
+				SEVFirmwareVersion: "1.55.21",
+			}
+			updated.Status.Conditions = hypervisor.Status.Conditions
+			Expect(reconciler.reconcileConfidentialComputing(ctx, updated, updated.DeepCopy())).To(Succeed())
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: hypervisor.Name}, updated)).To(Succeed())
+			Expect(updated.Annotations).To(HaveKeyWithValue(SEVMaxGuestsAnnotation, "15"))
+			Expect(updated.Annotations).To(HaveKeyWithValue(SEVESMaxGuestsAnnotation, "494"))
+			Expect(updated.Annotations).To(HaveKeyWithValue(SEVFirmwareAnnotation, "1.55.21"))
+		})
+	})
+
+	Context("When publishing the firmware inventory", func() {
+		It("should annotate the hypervisor with the loaders and nvram templates", func() {
+			ctx := context.Background()
+			hypervisor := &kvmv1.Hypervisor{
+				ObjectMeta: metav1.ObjectMeta{Name: "firmware-test-hypervisor"},
+			}
+			Expect(k8sClient.Create(ctx, hypervisor)).To(Succeed())
+			defer func() {
+				Expect(k8sClient.Delete(ctx, hypervisor)).To(Succeed())
+			}()
+			meta.SetStatusCondition(&hypervisor.Status.Conditions, metav1.Condition{
+				Type:   LibVirtType,
+				Status: metav1.ConditionTrue,
+				Reason: "Connected",
+			})
+
+			dir := GinkgoT().TempDir()
+			descriptor := `{"mapping": {"device": "flash", "nvram-template": {"filename": "/usr/share/OVMF/OVMF_VARS_4M.ms.fd"}}}`
+			Expect(os.WriteFile(filepath.Join(dir, "40-edk2.json"), []byte(descriptor), 0o644)).To(Succeed())
+			reconciler := &HypervisorReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+				Firmware: firmwareFunc(func() (libvirt.Firmware, error) {
+					return libvirt.Firmware{
+						Types:      []string{"efi"},
+						SecureBoot: true,
+						Loaders:    []string{"/usr/share/OVMF/OVMF_CODE_4M.fd", "/usr/share/OVMF/OVMF_CODE_4M.secboot.fd"},
+					}, nil
+				}),
+				FirmwareDescriptors: dir,
+			}
+			Expect(reconciler.reconcileFirmware(ctx, hypervisor, hypervisor.DeepCopy())).To(Succeed())
+
+			updated := &kvmv1.Hypervisor{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: hypervisor.Name}, updated)).To(Succeed())
+			Expect(updated.Annotations).To(HaveKeyWithValue(FirmwareLoadersAnnotation,
+				"/usr/share/OVMF/OVMF_CODE_4M.fd,/usr/share/OVMF/OVMF_CODE_4M.secboot.fd"))
+			Expect(updated.Annotations).To(HaveKeyWithValue(NVRAMTemplatesAnnotation, "/usr/share/OVMF/OVMF_VARS_4M.ms.fd"))
+		})
+	})
+
+	Context("When reporting the heartbeat of the agent", func() {
+		It("should only refresh a stale heartbeat", func() {
+			ctx := context.Background()
+			hypervisor := &kvmv1.Hypervisor{
+				ObjectMeta: metav1.ObjectMeta{Name: "heartbeat-test-hypervisor"},
+			}
+			Expect(k8sClient.Create(ctx, hypervisor)).To(Succeed())
+			defer func() {
+				Expect(k8sClient.Delete(ctx, hypervisor)).To(Succeed())
+			}()
+
+			reconciler := &HypervisorReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+			Expect(reconciler.reconcileHeartbeat(ctx, hypervisor, hypervisor.DeepCopy())).To(Succeed())
+
+			updated := &kvmv1.Hypervisor{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: hypervisor.Name}, updated)).To(Succeed())
+			Expect(updated.Annotations).To(HaveKeyWithValue(AgentVersionAnnotation, "unknown"))
+			Expect(updated.Annotations).To(HaveKeyWithValue(AgentBuildAnnotation, "unknown unknown"))
+			heartbeat, err := time.Parse(time.RFC3339, updated.Annotations[HeartbeatAnnotation])
+			Expect(err).NotTo(HaveOccurred())
+			Expect(heartbeat).To(BeTemporally("~", time.Now(), 5*time.Second))
+
+			By("Reconciling again right away")
+			version := updated.ResourceVersion
+			Expect(reconciler.reconcileHeartbeat(ctx, updated, updated.DeepCopy())).To(Succeed())
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: hypervisor.Name}, updated)).To(Succeed())
+			Expect(updated.ResourceVersion).To(Equal(version))
+
+			By("Reconciling with a stale heartbeat")
+			stale := time.Now().Add(-2 * heartbeatInterval).UTC().Format(time.RFC3339)
+			updated.Annotations[HeartbeatAnnotation] = stale
+			Expect(reconciler.reconcileHeartbeat(ctx, updated, updated.DeepCopy())).To(Succeed())
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: hypervisor.Name}, updated)).To(Succeed())
+			Expect(updated.Annotations[HeartbeatAnnotation]).NotTo(Equal(stale))
+		})
+	})
+
+	Context("When staging the operating system image", func() {
+		It("should only allow the update once the image is verified", func() {
+			ctx := context.Background()
+			checksum := strings.Repeat("ab", 32)
+			hypervisor := &kvmv1.Hypervisor{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "os-image-test-hypervisor",
+					Annotations: map[string]string{
+						OSImageURLAnnotation:    "https://example.com/gardenlinux_1877.3.raw",
+						OSImageSHA256Annotation: strings.ToUpper(checksum),
+					},
+				},
+			}
+
+			status := systemd.ImageStatus{}
+			var staged []systemd.Image
+			reconciler := &HypervisorReconciler{
+				ImageStager: imageStagerFunc(func(image systemd.Image) systemd.ImageStatus {
+					staged = append(staged, image)
+					status.Image = image
+					return status
+				}),
+			}
+			expectReason := func(reason string) {
+				GinkgoHelper()
+				condition := meta.FindStatusCondition(hypervisor.Status.Conditions, ImageType)
+				Expect(condition).NotTo(BeNil())
+				Expect(condition.Reason).To(Equal(reason))
+			}
+
+			Expect(reconciler.reconcileOSImage(ctx, hypervisor)).To(BeFalse())
+			expectReason("Downloading")
+			Expect(staged).To(Equal([]systemd.Image{{
+				URL:    "https://example.com/gardenlinux_1877.3.raw",
+				SHA256: checksum,
+			}}))
+
+			status.Done = true
+			status.Err = fmt.Errorf("%w: mismatch", systemd.ErrChecksumMismatch)
+			Expect(reconciler.reconcileOSImage(ctx, hypervisor)).To(BeFalse())
+			expectReason("VerificationFailed")
+
+			status.Err = nil
+			status.Path = "/var/lib/images/gardenlinux_1877.3.raw"
+			Expect(reconciler.reconcileOSImage(ctx, hypervisor)).To(BeTrue())
+			expectReason("Staged")
+
+			By("Rejecting invalid checksums")
+			hypervisor.Annotations[OSImageSHA256Annotation] = "abc"
+			Expect(reconciler.reconcileOSImage(ctx, hypervisor)).To(BeFalse())
+			expectReason("InvalidImage")
+
+			By("Not requiring an image without the annotation")
+			delete(hypervisor.Annotations, OSImageURLAnnotation)
+			Expect(reconciler.reconcileOSImage(ctx, hypervisor)).To(BeTrue())
+			Expect(meta.FindStatusCondition(hypervisor.Status.Conditions, ImageType)).To(BeNil())
+		})
+	})
+
+	Context("When reporting the cpu isolation", func() {
+		var (
+			hypervisor *kvmv1.Hypervisor
+			reconciler *HypervisorReconciler
+		)
+
+		BeforeEach(func() {
+			hypervisor = &kvmv1.Hypervisor{}
+			meta.SetStatusCondition(&hypervisor.Status.Conditions, metav1.Condition{
+				Type:   LibVirtType,
+				Status: metav1.ConditionTrue,
+				Reason: "Connected",
+			})
+			reconciler = &HypervisorReconciler{
+				HostTopology: hostTopologyFunc(func() (map[uint64][]int, error) {
+					return map[uint64][]int{0: {0, 1, 2, 3}, 1: {4, 5, 6, 7}}, nil
+				}),
+				kernelParameters: &kernel.Parameters{CommandLine: "isolcpus=domain,2-3,6-7 nohz_full=2-3"},
+			}
+		})
+
+		It("should report the dedicated and housekeeping cpus", func() {
+			reconciler.reconcileCPUIsolation(context.Background(), hypervisor)
+			condition := meta.FindStatusCondition(hypervisor.Status.Conditions, CPUType)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+			Expect(condition.Reason).To(Equal("Isolated"))
+			Expect(condition.Message).To(Equal("dedicated 2-3,6-7 (cell 0: 2-3, cell 1: 6-7), housekeeping 0-1,4-5, " +
+				"nohz_full missing for 6-7"))
+		})
+
+		It("should report isolated cpus missing on the host", func() {
+			reconciler.kernelParameters.CommandLine = "isolcpus=2-3,8-9"
+			reconciler.reconcileCPUIsolation(context.Background(), hypervisor)
+			condition := meta.FindStatusCondition(hypervisor.Status.Conditions, CPUType)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+			Expect(condition.Reason).To(Equal("UnknownCPUs"))
+			Expect(condition.Message).To(HavePrefix("isolated cpus 8-9 do not exist"))
+		})
+
+		It("should report hosts without isolated cpus", func() {
+			reconciler.kernelParameters.CommandLine = "quiet"
+			reconciler.reconcileCPUIsolation(context.Background(), hypervisor)
+			condition := meta.FindStatusCondition(hypervisor.Status.Conditions, CPUType)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Reason).To(Equal("NotIsolated"))
+			Expect(condition.Message).To(Equal("dedicated none, housekeeping 0-7"))
+		})
+
+		It("should report an unavailable topology", func() {
+			reconciler.HostTopology = hostTopologyFunc(func() (map[uint64][]int, error) {
+				return nil, errors.New("connection lost")
+			})
+			reconciler.reconcileCPUIsolation(context.Background(), hypervisor)
+			condition := meta.FindStatusCondition(hypervisor.Status.Conditions, CPUType)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionUnknown))
+		})
+	})
+
+	Context("When managing sysctls", func() {
+		var (
+			hypervisor *kvmv1.Hypervisor
+			applied    []sysctl.Setting
+			result     sysctl.Result
+			applyErr   error
+			reconciler *HypervisorReconciler
+		)
+
+		BeforeEach(func() {
+			hypervisor = &kvmv1.Hypervisor{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{
+						sysctl.AnnotationPrefix + "vm.swappiness": "10",
+					},
+				},
+			}
+			applied = nil
+			result = sysctl.Result{}
+			applyErr = nil
+			reconciler = &HypervisorReconciler{
+				Sysctl: sysctlFunc(func(settings []sysctl.Setting) (*sysctl.Result, error) {
+					applied = settings
+					return &result, applyErr
+				}),
+			}
+		})
+
+		It("should apply the sysctls of the annotations", func() {
+			reconciler.reconcileSysctls(context.Background(), hypervisor)
+			Expect(applied).To(Equal([]sysctl.Setting{{Name: "vm.swappiness", Value: "10"}}))
+			condition := meta.FindStatusCondition(hypervisor.Status.Conditions, SysctlType)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+			Expect(condition.Reason).To(Equal("Applied"))
+		})
+
+		It("should report corrected drift", func() {
+			result.Corrected = []sysctl.Drift{{
+				Setting: sysctl.Setting{Name: "vm.swappiness", Value: "10"},
+				Found:   "60",
+			}}
+			reconciler.reconcileSysctls(context.Background(), hypervisor)
+			condition := meta.FindStatusCondition(hypervisor.Status.Conditions, SysctlType)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Reason).To(Equal("DriftCorrected"))
+			Expect(condition.Message).To(ContainSubstring("vm.swappiness=60 (want 10)"))
+		})
+
+		It("should report sysctls the kernel did not accept", func() {
+			result.Rejected = []sysctl.Drift{{
+				Setting: sysctl.Setting{Name: "vm.swappiness", Value: "1000"},
+				Found:   "200",
+			}}
+			reconciler.reconcileSysctls(context.Background(), hypervisor)
+			condition := meta.FindStatusCondition(hypervisor.Status.Conditions, SysctlType)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+			Expect(condition.Reason).To(Equal("Rejected"))
+		})
+
+		It("should report failures", func() {
+			applyErr = errors.New("permission denied")
+			reconciler.reconcileSysctls(context.Background(), hypervisor)
+			condition := meta.FindStatusCondition(hypervisor.Status.Conditions, SysctlType)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Reason).To(Equal("ApplyFailed"))
+			Expect(condition.Message).To(ContainSubstring("permission denied"))
+		})
+
+		It("should not report a condition when turned off", func() {
+			reconciler.Sysctl = nil
+			reconciler.reconcileSysctls(context.Background(), hypervisor)
+			Expect(meta.FindStatusCondition(hypervisor.Status.Conditions, SysctlType)).To(BeNil())
+		})
+	})
+
+	Context("When managing ksm", func() {
+		var (
+			hypervisor *kvmv1.Hypervisor
+			manager    *fakeKSM
+			reconciler *HypervisorReconciler
+		)
+
+		BeforeEach(func() {
+			hypervisor = &kvmv1.Hypervisor{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{
+						ksm.AnnotationPrefix + "run":           "1",
+						ksm.AnnotationPrefix + "pages_to_scan": "1000",
+					},
+				},
+			}
+			manager = &fakeKSM{
+				stats: &ksm.Stats{Running: true, PagesShared: 256, PagesSharing: 1024},
+			}
+			reconciler = &HypervisorReconciler{KSM: manager}
+		})
+
+		It("should apply the ksm parameters of the annotations", func() {
+			reconciler.reconcileKSM(context.Background(), hypervisor)
+			Expect(manager.applied).To(Equal([]sysctl.Setting{
+				{Name: "pages_to_scan", Value: "1000"},
+				{Name: "run", Value: "1"},
+			}))
+			condition := meta.FindStatusCondition(hypervisor.Status.Conditions, KSMType)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+			Expect(condition.Reason).To(Equal("Applied"))
+			Expect(condition.Message).To(ContainSubstring("1024 pages sharing 256 deduplicated pages"))
+		})
+
+		It("should report corrected drift", func() {
+			manager.result.Corrected = []sysctl.Drift{{
+				Setting: sysctl.Setting{Name: "run", Value: "1"},
+				Found:   "0",
+			}}
+			reconciler.reconcileKSM(context.Background(), hypervisor)
+			condition := meta.FindStatusCondition(hypervisor.Status.Conditions, KSMType)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Reason).To(Equal("DriftCorrected"))
+			Expect(condition.Message).To(ContainSubstring("run=0 (want 1)"))
+		})
+
+		It("should reject unknown parameters", func() {
+			hypervisor.Annotations[ksm.AnnotationPrefix+"pages_shared"] = "0"
+			reconciler.reconcileKSM(context.Background(), hypervisor)
+			condition := meta.FindStatusCondition(hypervisor.Status.Conditions, KSMType)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+			Expect(condition.Reason).To(Equal("ApplyFailed"))
+			Expect(condition.Message).To(ContainSubstring(`unknown ksm parameter "pages_shared"`))
+		})
+
+		It("should report unreadable counters", func() {
+			manager.statsErr = errors.New("no such file or directory")
+			reconciler.reconcileKSM(context.Background(), hypervisor)
+			condition := meta.FindStatusCondition(hypervisor.Status.Conditions, KSMType)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionUnknown))
+			Expect(condition.Reason).To(Equal("ReadFailed"))
+		})
+
+		It("should not report a condition when turned off", func() {
+			reconciler.KSM = nil
+			reconciler.reconcileKSM(context.Background(), hypervisor)
+			Expect(meta.FindStatusCondition(hypervisor.Status.Conditions, KSMType)).To(BeNil())
+		})
+	})
+
+	Context("When echoing the config generation", func() {
+		It("should not report a condition without the annotation", func() {
+			hypervisor := &kvmv1.Hypervisor{}
+			meta.SetStatusCondition(&hypervisor.Status.Conditions, metav1.Condition{
+				Type:   ConfigType,
+				Status: metav1.ConditionTrue,
+				Reason: "Applied",
+			})
+			reconciler := &HypervisorReconciler{}
+			Expect(reconciler.reconcileConfigGeneration(context.Background(), hypervisor, hypervisor.DeepCopy())).
+				To(Succeed())
+			Expect(meta.FindStatusCondition(hypervisor.Status.Conditions, ConfigType)).To(BeNil())
+		})
+
+		It("should not echo the generation while a reboot is required", func() {
+			hypervisor := &kvmv1.Hypervisor{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{ConfigGenerationAnnotation: "7"},
+				},
+			}
+			meta.SetStatusCondition(&hypervisor.Status.Conditions, metav1.Condition{
+				Type:    RebootType,
+				Status:  metav1.ConditionTrue,
+				Reason:  "KernelParametersChanged",
+				Message: "kernel was booted without parameters isolcpus=2-31",
+			})
+			reconciler := &HypervisorReconciler{}
+			Expect(reconciler.reconcileConfigGeneration(context.Background(), hypervisor, hypervisor.DeepCopy())).
+				To(Succeed())
+			Expect(hypervisor.Annotations).NotTo(HaveKey(ObservedConfigGenerationAnnotation))
+			condition := meta.FindStatusCondition(hypervisor.Status.Conditions, ConfigType)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+			Expect(condition.Reason).To(Equal("RebootRequired"))
+			Expect(condition.Message).To(ContainSubstring("isolcpus=2-31"))
+		})
+
+		It("should echo the applied generation", func() {
+			ctx := context.Background()
+
+			hypervisor := &kvmv1.Hypervisor{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "config-generation-test-hypervisor",
+					Annotations: map[string]string{ConfigGenerationAnnotation: "7"},
+				},
+			}
+			Expect(k8sClient.Create(ctx, hypervisor)).To(Succeed())
+			defer func() {
+				Expect(k8sClient.Delete(ctx, hypervisor)).To(Succeed())
+			}()
+
+			reconciler := &HypervisorReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+			base := hypervisor.DeepCopy()
+			hypervisor.Status.HypervisorVersion = "1.0.0"
+			Expect(reconciler.reconcileConfigGeneration(ctx, hypervisor, base)).To(Succeed())
+			Expect(hypervisor.Annotations).To(HaveKeyWithValue(ObservedConfigGenerationAnnotation, "7"))
+			Expect(hypervisor.Status.HypervisorVersion).To(Equal("1.0.0"))
+			Expect(meta.IsStatusConditionTrue(hypervisor.Status.Conditions, ConfigType)).To(BeTrue())
+
+			updated := &kvmv1.Hypervisor{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: hypervisor.Name}, updated)).To(Succeed())
+			Expect(updated.Annotations).To(HaveKeyWithValue(ObservedConfigGenerationAnnotation, "7"))
+		})
+	})
+
+	Context("When applying the status", func() {
+		It("should keep the fields and conditions of the operator", func() {
+			ctx := context.Background()
+
+			hypervisor := &kvmv1.Hypervisor{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "apply-status-test-hypervisor",
+				},
+			}
+			Expect(k8sClient.Create(ctx, hypervisor)).To(Succeed())
+			defer func() {
+				Expect(k8sClient.Delete(ctx, hypervisor)).To(Succeed())
+			}()
+
+			By("Writing the status as the operator")
+			base := hypervisor.DeepCopy()
+			hypervisor.Status.ServiceID = "service-1"
+			meta.SetStatusCondition(&hypervisor.Status.Conditions, metav1.Condition{
+				Type:   "Ready",
+				Status: metav1.ConditionTrue,
+				Reason: "Ready",
+			})
+			Expect(k8sClient.Status().Patch(ctx, hypervisor, client.MergeFrom(base),
+				client.FieldOwner("openstack-hypervisor-operator"))).To(Succeed())
+
+			By("Applying the status of the agent from a stale copy")
+			controllerReconciler := &HypervisorReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+			stale := base.DeepCopy()
+			stale.Status.HypervisorVersion = "1.0.0"
+			meta.SetStatusCondition(&stale.Status.Conditions, metav1.Condition{
+				Type:   LibVirtType,
+				Status: metav1.ConditionTrue,
+				Reason: "Connected",
+			})
+			Expect(controllerReconciler.applyStatus(ctx, stale)).To(Succeed())
+
+			updated := &kvmv1.Hypervisor{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: hypervisor.Name}, updated)).To(Succeed())
+			Expect(updated.Status.HypervisorVersion).To(Equal("1.0.0"))
+			Expect(updated.Status.ServiceID).To(Equal("service-1"))
+			Expect(meta.IsStatusConditionTrue(updated.Status.Conditions, "Ready")).To(BeTrue())
+			Expect(meta.IsStatusConditionTrue(updated.Status.Conditions, LibVirtType)).To(BeTrue())
+		})
+	})
+
+	Context("When patching the status", func() {
+		It("should skip unchanged status and rate limit patches", func() {
+			ctx := context.Background()
+
+			hypervisor := &kvmv1.Hypervisor{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "patch-status-test-hypervisor",
+				},
+			}
+			Expect(k8sClient.Create(ctx, hypervisor)).To(Succeed())
+			defer func() {
+				Expect(k8sClient.Delete(ctx, hypervisor)).To(Succeed())
+			}()
+
+			controllerReconciler := &HypervisorReconciler{
+				Client:              k8sClient,
+				Scheme:              k8sClient.Scheme(),
+				StatusPatchInterval: time.Hour,
+			}
+
+			By("Skipping the patch without changes")
+			base := hypervisor.DeepCopy()
+			delay, err := controllerReconciler.patchStatus(ctx, hypervisor, base)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(delay).To(BeZero())
+			Expect(controllerReconciler.lastStatusPatch).To(BeZero())
+
+			By("Patching the first change immediately")
+			hypervisor.Status.HypervisorVersion = "1.0.0"
+			delay, err = controllerReconciler.patchStatus(ctx, hypervisor, base)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(delay).To(BeZero())
+			Expect(controllerReconciler.lastStatusPatch).NotTo(BeZero())
+
+			By("Delaying the next change")
+			base = hypervisor.DeepCopy()
+			hypervisor.Status.HypervisorVersion = "2.0.0"
+			delay, err = controllerReconciler.patchStatus(ctx, hypervisor, base)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(delay).To(BeNumerically(">", 59*time.Minute))
+
+			updated := &kvmv1.Hypervisor{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: hypervisor.Name}, updated)).To(Succeed())
+			Expect(updated.Status.HypervisorVersion).To(Equal("1.0.0"))
+		})
+	})
+
 	Context("When testing Start method", func() {
 		It("should successfully start and subscribe to libvirt events", func() {
 			ctx := context.Background()
@@ -451,7 +2448,7 @@ var _ = Describe("Hypervisor Controller", func() {
 					ConnectFunc: func() error {
 						return nil
 					},
-					ProcessFunc: func(hv kvmv1.Hypervisor) (kvmv1.Hypervisor, error) {
+					ProcessFunc: func(_ context.Context, hv kvmv1.Hypervisor) (kvmv1.Hypervisor, error) {
 						hv.Status.Instances = []kvmv1.Instance{
 							{
 								ID:     "25e2ea06-f6be-4bac-856d-8c2d0bdbcdee",
@@ -542,3 +2539,165 @@ var _ = Describe("Hypervisor Controller", func() {
 		})
 	})
 })
+
+// Adapts a function to the DomainPolicyEnforcer interface.
+type domainPolicyFunc func(dryRun bool) ([]libvirt.DomainPolicyViolation, error)
+
+func (f domainPolicyFunc) EnforceDomainPolicy(dryRun bool, _ time.Duration) ([]libvirt.DomainPolicyViolation, error) {
+	return f(dryRun)
+}
+
+type leftoverJanitorFunc func(dryRun bool, ovsSockets []string) ([]libvirt.Leftover, error)
+
+func (f leftoverJanitorFunc) CleanLeftovers(dryRun bool, ovsSockets []string) ([]libvirt.Leftover, error) {
+	return f(dryRun, ovsSockets)
+}
+
+type domainDriftFunc func() ([]libvirt.DomainDrift, error)
+
+type memoryPressureFunc func() (*memory.Pressure, error)
+
+func (f memoryPressureFunc) ReadPressure() (*memory.Pressure, error) {
+	return f()
+}
+
+// Fake memory reclaimer recording the operations, failing those on the
+// domains with an error.
+type fakeMemoryReclaimer struct {
+	calls []string
+	errs  map[string]error
+}
+
+func (f *fakeMemoryReclaimer) BalloonDomain(uuid string, memoryKiB uint64) error {
+	f.calls = append(f.calls, fmt.Sprintf("balloon %s %d", uuid, memoryKiB))
+	return f.errs[uuid]
+}
+
+func (f *fakeMemoryReclaimer) SuspendDomain(uuid string) error {
+	f.calls = append(f.calls, "suspend "+uuid)
+	return f.errs[uuid]
+}
+
+func (f *fakeMemoryReclaimer) ResumeDomain(uuid string) error {
+	f.calls = append(f.calls, "resume "+uuid)
+	return f.errs[uuid]
+}
+
+func (f domainDriftFunc) DetectDomainDrift() ([]libvirt.DomainDrift, error) {
+	return f()
+}
+
+// Fake boot loader keeping the entries in memory.
+type bootLoader struct {
+	entries boot.Entries
+}
+
+func (l *bootLoader) Entries() (*boot.Entries, error) {
+	entries := l.entries
+	return &entries, nil
+}
+
+func (l *bootLoader) SetDefault(id string) error {
+	l.entries.Default = id
+	return nil
+}
+
+type imageStagerFunc func(image systemd.Image) systemd.ImageStatus
+
+func (f imageStagerFunc) StageImage(image systemd.Image) systemd.ImageStatus {
+	return f(image)
+}
+
+type cpuBaselineFunc func(arch string, cpus []libvirt.HostCPUModel) (libvirt.HostCPUModel, error)
+
+func (f cpuBaselineFunc) BaselineCPU(arch string, cpus []libvirt.HostCPUModel) (libvirt.HostCPUModel, error) {
+	return f(arch, cpus)
+}
+
+type confidentialComputingFunc func() (libvirt.ConfidentialComputing, error)
+
+func (f confidentialComputingFunc) ConfidentialComputing() (libvirt.ConfidentialComputing, error) {
+	return f()
+}
+
+type firmwareFunc func() (libvirt.Firmware, error)
+
+func (f firmwareFunc) Firmware() (libvirt.Firmware, error) {
+	return f()
+}
+
+type hostCPUFunc func() (libvirt.HostCPUModel, error)
+
+func (f hostCPUFunc) HostCPUModel() (libvirt.HostCPUModel, error) {
+	return f()
+}
+
+type hostTopologyFunc func() (map[uint64][]int, error)
+
+func (f hostTopologyFunc) HostCPUs() (map[uint64][]int, error) {
+	return f()
+}
+
+type sysctlFunc func(settings []sysctl.Setting) (*sysctl.Result, error)
+
+func (f sysctlFunc) Apply(settings []sysctl.Setting) (*sysctl.Result, error) {
+	return f(settings)
+}
+
+type fakeKSM struct {
+	applied  []sysctl.Setting
+	result   sysctl.Result
+	stats    *ksm.Stats
+	statsErr error
+}
+
+func (f *fakeKSM) Apply(settings []sysctl.Setting) (*sysctl.Result, error) {
+	f.applied = settings
+	return &f.result, nil
+}
+
+func (f *fakeKSM) ReadStats() (*ksm.Stats, error) {
+	return f.stats, f.statsErr
+}
+
+type ovsFunc func(ctx context.Context) (*ovs.Status, error)
+
+func (f ovsFunc) Status(ctx context.Context) (*ovs.Status, error) {
+	return f(ctx)
+}
+
+type entropyFunc func() (*entropy.Sources, error)
+
+func (f entropyFunc) ReadSources() (*entropy.Sources, error) {
+	return f()
+}
+
+type iommuFunc func() (*iommu.Status, error)
+
+func (f iommuFunc) ReadStatus() (*iommu.Status, error) {
+	return f()
+}
+
+type connectionProberFunc func() libvirt.ConnectionHealth
+
+func (f connectionProberFunc) ProbeConnection() libvirt.ConnectionHealth {
+	return f()
+}
+
+type hostStorageFunc func() ([]hoststorage.Usage, error)
+
+func (f hostStorageFunc) ReadUsage() ([]hoststorage.Usage, error) {
+	return f()
+}
+
+type diskUsageFunc func() ([]libvirt.DiskUsage, libvirt.FilesystemUsage, error)
+
+func (f diskUsageFunc) InstanceDiskUsage() ([]libvirt.DiskUsage, libvirt.FilesystemUsage, error) {
+	return f()
+}
+
+type hostIOMMUFunc func() (bool, error)
+
+func (f hostIOMMUFunc) HostIOMMUSupported() (bool, error) {
+	return f()
+}
@@ -0,0 +1,142 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logger "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	"github.com/cobaltcore-dev/kvm-node-agent/api/v1alpha1"
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/libvirt"
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/sys"
+)
+
+// Reasons of the PowerAction condition of instances.
+const (
+	PowerActionReasonInProgress = "InProgress"
+	PowerActionReasonSucceeded  = "Succeeded"
+	PowerActionReasonFailed     = "Failed"
+)
+
+// InstancePowerReconciler performs the power actions requested by the
+// instances of this host, e.g. to save domains to disk to reclaim host
+// memory in an emergency.
+type InstancePowerReconciler struct {
+	client.Client
+	Scheme  *runtime.Scheme
+	Domains libvirt.DomainPowerManager
+}
+
+// Check if the power action requested by the instance is still to be
+// performed. An action interrupted by a restart of the agent is performed
+// again.
+func powerActionPending(instance *v1alpha1.Instance) bool {
+	if instance.Spec.PowerAction == "" {
+		return false
+	}
+	condition := meta.FindStatusCondition(instance.Status.Conditions, v1alpha1.ConditionTypePowerAction)
+	return condition == nil || condition.ObservedGeneration != instance.Generation ||
+		condition.Reason == PowerActionReasonInProgress
+}
+
+// Perform the power action on the domain.
+func (r *InstancePowerReconciler) perform(uuid string, action v1alpha1.InstancePowerAction) error {
+	switch action {
+	case v1alpha1.PowerActionSuspend:
+		return r.Domains.SuspendDomain(uuid)
+	case v1alpha1.PowerActionResume:
+		return r.Domains.ResumeDomain(uuid)
+	case v1alpha1.PowerActionManagedSave:
+		return r.Domains.ManagedSaveDomain(uuid)
+	case v1alpha1.PowerActionRestore:
+		return r.Domains.RestoreDomain(uuid)
+	}
+	return fmt.Errorf("unknown power action %q", action)
+}
+
+// Report the progress of the power action in the PowerAction condition.
+func (r *InstancePowerReconciler) setCondition(
+	ctx context.Context, instance *v1alpha1.Instance, status metav1.ConditionStatus, reason, message string,
+) error {
+	base := instance.DeepCopy()
+	meta.SetStatusCondition(&instance.Status.Conditions, metav1.Condition{
+		Type:               v1alpha1.ConditionTypePowerAction,
+		Status:             status,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: instance.Generation,
+	})
+	return r.Status().Patch(ctx, instance, client.MergeFrom(base))
+}
+
+// +kubebuilder:rbac:groups=kvm.cloud.sap,resources=instances,verbs=get;list;watch
+// +kubebuilder:rbac:groups=kvm.cloud.sap,resources=instances/status,verbs=get;update;patch
+
+// Reconcile is part of the main kubernetes reconciliation loop which aims to
+// move the current state of the cluster closer to the desired state.
+func (r *InstancePowerReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := logger.FromContext(ctx)
+
+	instance := &v1alpha1.Instance{}
+	if err := r.Get(ctx, req.NamespacedName, instance); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	if !powerActionPending(instance) {
+		return ctrl.Result{}, nil
+	}
+
+	action := instance.Spec.PowerAction
+	log.Info("performing power action", "action", action)
+	if err := r.setCondition(ctx, instance, metav1.ConditionFalse, PowerActionReasonInProgress,
+		fmt.Sprintf("%s in progress", action)); err != nil {
+		return ctrl.Result{}, err
+	}
+	// A failed action is not retried, the domain is likely in a state
+	// the action doesn't apply to.
+	if err := r.perform(instance.Name, action); err != nil {
+		log.Error(err, "power action failed", "action", action)
+		return ctrl.Result{}, r.setCondition(ctx, instance, metav1.ConditionFalse, PowerActionReasonFailed, err.Error())
+	}
+	return ctrl.Result{}, r.setCondition(ctx, instance, metav1.ConditionTrue, PowerActionReasonSucceeded,
+		fmt.Sprintf("%s succeeded", action))
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *InstancePowerReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	// Only instances of domains on this host asking for a power action are
+	// of interest.
+	powerActionOnThisHost := predicate.NewPredicateFuncs(func(o client.Object) bool {
+		instance, ok := o.(*v1alpha1.Instance)
+		return ok && instance.Namespace == sys.Namespace &&
+			instance.Labels[v1alpha1.LabelHypervisor] == sys.NodeLabelName &&
+			instance.Spec.PowerAction != ""
+	})
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("instance-power").
+		For(&v1alpha1.Instance{}, builder.WithPredicates(powerActionOnThisHost)).
+		Complete(r)
+}
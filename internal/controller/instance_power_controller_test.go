@@ -0,0 +1,96 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/cobaltcore-dev/kvm-node-agent/api/v1alpha1"
+)
+
+var _ = Describe("InstancePower Controller", func() {
+	instance := func(action v1alpha1.InstancePowerAction, conditions ...metav1.Condition) *v1alpha1.Instance {
+		return &v1alpha1.Instance{
+			ObjectMeta: metav1.ObjectMeta{Name: "a", Generation: 2},
+			Spec:       v1alpha1.InstanceSpec{PowerAction: action},
+			Status:     v1alpha1.InstanceStatus{Conditions: conditions},
+		}
+	}
+	condition := func(generation int64, reason string) metav1.Condition {
+		return metav1.Condition{
+			Type:               v1alpha1.ConditionTypePowerAction,
+			Reason:             reason,
+			ObservedGeneration: generation,
+		}
+	}
+
+	It("should perform the action once per generation", func() {
+		Expect(powerActionPending(instance(""))).To(BeFalse())
+		Expect(powerActionPending(instance(v1alpha1.PowerActionSuspend))).To(BeTrue())
+		Expect(powerActionPending(instance(v1alpha1.PowerActionSuspend,
+			condition(1, PowerActionReasonSucceeded)))).To(BeTrue())
+		Expect(powerActionPending(instance(v1alpha1.PowerActionSuspend,
+			condition(2, PowerActionReasonSucceeded)))).To(BeFalse())
+		Expect(powerActionPending(instance(v1alpha1.PowerActionSuspend,
+			condition(2, PowerActionReasonFailed)))).To(BeFalse())
+	})
+
+	It("should perform an interrupted action again", func() {
+		Expect(powerActionPending(instance(v1alpha1.PowerActionManagedSave,
+			condition(2, PowerActionReasonInProgress)))).To(BeTrue())
+	})
+
+	It("should call the libvirt operation of the action", func() {
+		domains := &fakePowerManager{}
+		r := &InstancePowerReconciler{Domains: domains}
+		for _, action := range []v1alpha1.InstancePowerAction{
+			v1alpha1.PowerActionSuspend, v1alpha1.PowerActionResume,
+			v1alpha1.PowerActionManagedSave, v1alpha1.PowerActionRestore,
+		} {
+			Expect(r.perform("a", action)).To(Succeed())
+		}
+		Expect(domains.calls).To(Equal([]string{"suspend a", "resume a", "save a", "restore a"}))
+		Expect(r.perform("a", "Reboot")).To(HaveOccurred())
+
+		domains.err = errors.New("domain is not running")
+		Expect(r.perform("a", v1alpha1.PowerActionSuspend)).To(MatchError(domains.err))
+	})
+})
+
+// fakePowerManager records the power operations.
+type fakePowerManager struct {
+	calls []string
+	err   error
+}
+
+func (f *fakePowerManager) record(call, uuid string) error {
+	f.calls = append(f.calls, call+" "+uuid)
+	return f.err
+}
+
+func (f *fakePowerManager) SuspendDomain(uuid string) error { return f.record("suspend", uuid) }
+
+func (f *fakePowerManager) ResumeDomain(uuid string) error { return f.record("resume", uuid) }
+
+func (f *fakePowerManager) ManagedSaveDomain(uuid string) error { return f.record("save", uuid) }
+
+func (f *fakePowerManager) RestoreDomain(uuid string) error { return f.record("restore", uuid) }
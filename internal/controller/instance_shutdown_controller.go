@@ -0,0 +1,175 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logger "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	"github.com/cobaltcore-dev/kvm-node-agent/api/v1alpha1"
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/libvirt"
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/sys"
+)
+
+// Reasons of the Shutdown condition of instances.
+const (
+	ShutdownReasonShuttingDown = "ShuttingDown"
+	ShutdownReasonShutOff      = "ShutOff"
+	ShutdownReasonDestroyed    = "Destroyed"
+	ShutdownReasonTimedOut     = "TimedOut"
+)
+
+// Time the guest gets to shut down if the instance doesn't ask for another.
+const defaultShutdownGracePeriod = time.Minute
+
+// Action to take on the domain to progress its shutdown.
+type shutdownAction int
+
+const (
+	shutdownNone shutdownAction = iota
+	shutdownRequest
+	shutdownDestroy
+)
+
+// InstanceShutdownReconciler shuts down the domains of this host whose
+// instance asks for it, so that they can be stopped while nova is
+// unavailable.
+type InstanceShutdownReconciler struct {
+	client.Client
+	Scheme  *runtime.Scheme
+	Domains libvirt.DomainStopper
+}
+
+// Decide the next step of the shutdown requested by the instance. Returns
+// the action to take, the condition to report, nil if it is unchanged, and
+// when to look again, zero if there is nothing to wait for.
+func nextShutdownStep(instance *v1alpha1.Instance, now time.Time) (shutdownAction, *metav1.Condition, time.Duration) {
+	spec := instance.Spec.Shutdown
+	if spec == nil {
+		return shutdownNone, nil, 0
+	}
+	grace := defaultShutdownGracePeriod
+	if spec.GracePeriod != nil {
+		grace = spec.GracePeriod.Duration
+	}
+	condition := meta.FindStatusCondition(instance.Status.Conditions, v1alpha1.ConditionTypeShutdown)
+	current := condition != nil && condition.ObservedGeneration == instance.Generation
+	if current && condition.Reason != ShutdownReasonShuttingDown {
+		// This generation was handled already.
+		return shutdownNone, nil, 0
+	}
+
+	next := &metav1.Condition{
+		Type:               v1alpha1.ConditionTypeShutdown,
+		ObservedGeneration: instance.Generation,
+	}
+	switch {
+	case !instance.Status.Active:
+		next.Status = metav1.ConditionTrue
+		next.Reason = ShutdownReasonShutOff
+		next.Message = "domain is shut off"
+		return shutdownNone, next, 0
+	case !current:
+		next.Status = metav1.ConditionFalse
+		next.Reason = ShutdownReasonShuttingDown
+		next.Message = fmt.Sprintf("guest asked to shut down within %s", grace)
+		next.LastTransitionTime = metav1.NewTime(now)
+		return shutdownRequest, next, grace
+	}
+	if elapsed := now.Sub(condition.LastTransitionTime.Time); elapsed < grace {
+		return shutdownNone, nil, grace - elapsed
+	}
+	if spec.Force {
+		next.Status = metav1.ConditionTrue
+		next.Reason = ShutdownReasonDestroyed
+		next.Message = fmt.Sprintf("guest didn't shut down within %s, domain destroyed", grace)
+		return shutdownDestroy, next, 0
+	}
+	next.Status = metav1.ConditionFalse
+	next.Reason = ShutdownReasonTimedOut
+	next.Message = fmt.Sprintf("guest didn't shut down within %s", grace)
+	return shutdownNone, next, 0
+}
+
+// +kubebuilder:rbac:groups=kvm.cloud.sap,resources=instances,verbs=get;list;watch
+// +kubebuilder:rbac:groups=kvm.cloud.sap,resources=instances/status,verbs=get;update;patch
+
+// Reconcile is part of the main kubernetes reconciliation loop which aims to
+// move the current state of the cluster closer to the desired state.
+func (r *InstanceShutdownReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := logger.FromContext(ctx)
+
+	instance := &v1alpha1.Instance{}
+	if err := r.Get(ctx, req.NamespacedName, instance); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	action, condition, requeue := nextShutdownStep(instance, time.Now())
+	switch action {
+	case shutdownRequest:
+		log.Info("shutting down domain", "grace", requeue)
+		if err := r.Domains.RequestShutdown(instance.Name); err != nil {
+			return ctrl.Result{}, err
+		}
+	case shutdownDestroy:
+		log.Info("destroying domain that didn't shut down")
+		if err := r.Domains.DestroyDomain(instance.Name); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	if condition != nil {
+		base := instance.DeepCopy()
+		if action == shutdownRequest {
+			// Start the grace period now, even if the condition was False
+			// for an earlier generation already.
+			meta.RemoveStatusCondition(&instance.Status.Conditions, v1alpha1.ConditionTypeShutdown)
+		}
+		meta.SetStatusCondition(&instance.Status.Conditions, *condition)
+		if err := r.Status().Patch(ctx, instance, client.MergeFrom(base)); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+	return ctrl.Result{RequeueAfter: requeue}, nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *InstanceShutdownReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	// Only instances of domains on this host asking for a shutdown are of
+	// interest.
+	shutdownOnThisHost := predicate.NewPredicateFuncs(func(o client.Object) bool {
+		instance, ok := o.(*v1alpha1.Instance)
+		return ok && instance.Namespace == sys.Namespace &&
+			instance.Labels[v1alpha1.LabelHypervisor] == sys.NodeLabelName &&
+			instance.Spec.Shutdown != nil
+	})
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("instance-shutdown").
+		For(&v1alpha1.Instance{}, builder.WithPredicates(shutdownOnThisHost)).
+		Complete(r)
+}
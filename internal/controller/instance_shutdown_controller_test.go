@@ -0,0 +1,110 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/cobaltcore-dev/kvm-node-agent/api/v1alpha1"
+)
+
+var _ = Describe("InstanceShutdown Controller", func() {
+	now := time.Now()
+	instance := func(force bool, conditions ...metav1.Condition) *v1alpha1.Instance {
+		return &v1alpha1.Instance{
+			ObjectMeta: metav1.ObjectMeta{Name: "a", Generation: 2},
+			Spec: v1alpha1.InstanceSpec{Shutdown: &v1alpha1.InstanceShutdown{
+				GracePeriod: &metav1.Duration{Duration: 5 * time.Minute},
+				Force:       force,
+			}},
+			Status: v1alpha1.InstanceStatus{Active: true, Conditions: conditions},
+		}
+	}
+	shuttingDown := func(generation int64, since time.Time) metav1.Condition {
+		return metav1.Condition{
+			Type:               v1alpha1.ConditionTypeShutdown,
+			Status:             metav1.ConditionFalse,
+			Reason:             ShutdownReasonShuttingDown,
+			ObservedGeneration: generation,
+			LastTransitionTime: metav1.NewTime(since),
+		}
+	}
+
+	It("should leave instances without shutdown alone", func() {
+		action, condition, requeue := nextShutdownStep(&v1alpha1.Instance{}, now)
+		Expect(action).To(Equal(shutdownNone))
+		Expect(condition).To(BeNil())
+		Expect(requeue).To(BeZero())
+	})
+
+	It("should request the shutdown of a new generation", func() {
+		old := shuttingDown(1, now.Add(-time.Hour))
+		old.Reason = ShutdownReasonTimedOut
+		action, condition, requeue := nextShutdownStep(instance(false, old), now)
+		Expect(action).To(Equal(shutdownRequest))
+		Expect(condition.Reason).To(Equal(ShutdownReasonShuttingDown))
+		Expect(condition.ObservedGeneration).To(Equal(int64(2)))
+		Expect(condition.LastTransitionTime.Time).To(Equal(now))
+		Expect(requeue).To(Equal(5 * time.Minute))
+	})
+
+	It("should wait for the guest within the grace period", func() {
+		action, condition, requeue := nextShutdownStep(
+			instance(true, shuttingDown(2, now.Add(-time.Minute))), now)
+		Expect(action).To(Equal(shutdownNone))
+		Expect(condition).To(BeNil())
+		Expect(requeue).To(Equal(4 * time.Minute))
+	})
+
+	It("should report the shut off domain", func() {
+		shutOff := instance(true, shuttingDown(2, now.Add(-time.Minute)))
+		shutOff.Status.Active = false
+		action, condition, _ := nextShutdownStep(shutOff, now)
+		Expect(action).To(Equal(shutdownNone))
+		Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+		Expect(condition.Reason).To(Equal(ShutdownReasonShutOff))
+	})
+
+	It("should destroy the domain after the grace period if forced", func() {
+		action, condition, _ := nextShutdownStep(
+			instance(true, shuttingDown(2, now.Add(-10*time.Minute))), now)
+		Expect(action).To(Equal(shutdownDestroy))
+		Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+		Expect(condition.Reason).To(Equal(ShutdownReasonDestroyed))
+	})
+
+	It("should time out after the grace period if not forced", func() {
+		action, condition, _ := nextShutdownStep(
+			instance(false, shuttingDown(2, now.Add(-10*time.Minute))), now)
+		Expect(action).To(Equal(shutdownNone))
+		Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+		Expect(condition.Reason).To(Equal(ShutdownReasonTimedOut))
+	})
+
+	It("should not shut down a domain started again after the shutdown", func() {
+		done := shuttingDown(2, now.Add(-time.Hour))
+		done.Status, done.Reason = metav1.ConditionTrue, ShutdownReasonShutOff
+		action, condition, _ := nextShutdownStep(instance(false, done), now)
+		Expect(action).To(Equal(shutdownNone))
+		Expect(condition).To(BeNil())
+	})
+})
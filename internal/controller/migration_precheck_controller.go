@@ -0,0 +1,304 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	kvmv1 "github.com/cobaltcore-dev/openstack-hypervisor-operator/api/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logger "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	"github.com/cobaltcore-dev/kvm-node-agent/api/v1alpha1"
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/libvirt"
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/libvirt/dominfo"
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/sys"
+)
+
+// Reasons the domain of an instance can't be live migrated to the target.
+const (
+	IncompatibleArch           = "Architecture"
+	IncompatibleHypervisorType = "HypervisorType"
+	IncompatibleCPUMode        = "CPUMode"
+	IncompatibleCPUVendor      = "CPUVendor"
+	IncompatibleCPUModel       = "CPUModel"
+	IncompatibleCPUFeatures    = "CPUFeatures"
+	IncompatibleTSCFrequency   = "TSCFrequency"
+)
+
+// Deviation of the tsc frequency of the target from the one of the source
+// tolerated by libvirt, in parts per million.
+const tscTolerancePPM = 250
+
+// Reasons of the Compatible condition of migration prechecks.
+const (
+	PrecheckReasonCompatible    = "Compatible"
+	PrecheckReasonIncompatible  = "Incompatible"
+	PrecheckReasonTargetUnknown = "TargetUnknown"
+)
+
+// The target didn't publish the cpu of its host yet.
+var errTargetCPUUnknown = errors.New("target didn't publish its host cpu")
+
+// MigrationPrecheckReconciler checks whether the domains of this host can
+// be live migrated to the target of a migration precheck, so that an
+// incompatible target is known before nova attempts the migration.
+type MigrationPrecheckReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+	// Reader to look up the target hypervisor, which is not in the cache
+	// of the hypervisors.
+	Reader  client.Reader
+	Domains libvirt.DomainCPUDescriber
+}
+
+// Get the host cpu published by the agent of the hypervisor, false if it
+// didn't publish one yet.
+func hostCPUOf(hypervisor *kvmv1.Hypervisor) (libvirt.HostCPUModel, bool) {
+	model, ok := hypervisor.Annotations[HostCPUModelAnnotation]
+	if !ok {
+		return libvirt.HostCPUModel{}, false
+	}
+	cpu := libvirt.HostCPUModel{Model: model, Vendor: hypervisor.Annotations[HostCPUVendorAnnotation]}
+	if features := hypervisor.Annotations[HostCPUFeaturesAnnotation]; features != "" {
+		cpu.Features = strings.Split(features, ",")
+	}
+	// Hosts which didn't publish their tsc are treated as unknown.
+	if frequency, err := strconv.ParseUint(hypervisor.Annotations[HostTSCFrequencyAnnotation], 10, 64); err == nil {
+		cpu.TSCFrequency = frequency
+		cpu.TSCScaling = hypervisor.Annotations[HostTSCScalingAnnotation] == "true"
+	}
+	return cpu, true
+}
+
+// Get the cpu mode libvirt uses for domains without one. Kvm on aarch64
+// only provides the cpu of the host, elsewhere libvirt defaults to a custom
+// cpu.
+func defaultCPUMode(arch string) string {
+	if arch == "aarch64" {
+		return "host-passthrough"
+	}
+	return "custom"
+}
+
+// Get the features which are not in the available ones, sorted.
+func missingFeatures(required, available []string) []string {
+	var missing []string
+	for _, feature := range required {
+		if !slices.Contains(available, feature) {
+			missing = append(missing, feature)
+		}
+	}
+	slices.Sort(missing)
+	return missing
+}
+
+// Check if the tsc frequency of the target differs from the one of the
+// source by more than libvirt tolerates.
+func tscMismatch(source, target uint64) bool {
+	diff := max(source, target) - min(source, target)
+	return diff*1_000_000 > source*tscTolerancePPM
+}
+
+// Compare the cpu of a domain on the source with the capabilities the
+// target published. The cpu of the source host matters for the modes which
+// pass it through to the guest. Returns errTargetCPUUnknown if the target
+// didn't publish enough to tell.
+func checkMigrationCompatibility(
+	domain *dominfo.DomainCPU, source, target *kvmv1.Hypervisor,
+) ([]v1alpha1.MigrationIncompatibility, error) {
+	var incompatibilities []v1alpha1.MigrationIncompatibility
+	incompatible := func(reason, format string, args ...any) {
+		incompatibilities = append(incompatibilities, v1alpha1.MigrationIncompatibility{
+			Reason:  reason,
+			Message: fmt.Sprintf(format, args...),
+		})
+	}
+
+	if from, to := source.Status.Capabilities.HostCpuArch, target.Status.Capabilities.HostCpuArch; from != to {
+		incompatible(IncompatibleArch, "target has a %s cpu instead of %s", to, from)
+	}
+	if from, to := source.Status.DomainCapabilities.HypervisorType, target.Status.DomainCapabilities.HypervisorType; from != to {
+		incompatible(IncompatibleHypervisorType, "target runs %s domains instead of %s", to, from)
+	}
+
+	mode := defaultCPUMode(source.Status.Capabilities.HostCpuArch)
+	if domain != nil && domain.Mode != "" {
+		mode = domain.Mode
+	}
+	if !slices.Contains(target.Status.DomainCapabilities.SupportedCpuModes, "mode/"+mode) {
+		incompatible(IncompatibleCPUMode, "target doesn't support the cpu mode %s", mode)
+	}
+
+	targetCPU, ok := hostCPUOf(target)
+	if !ok {
+		return incompatibilities, errTargetCPUUnknown
+	}
+	var invariantTSC bool
+	switch mode {
+	case "host-passthrough", "maximum":
+		// The guest sees the cpu of the source host, which the target has
+		// to provide as a whole.
+		sourceCPU, ok := hostCPUOf(source)
+		if !ok {
+			return incompatibilities, errors.New("source didn't publish its host cpu")
+		}
+		if sourceCPU.Vendor != targetCPU.Vendor {
+			incompatible(IncompatibleCPUVendor, "target has a %s cpu instead of %s", targetCPU.Vendor, sourceCPU.Vendor)
+		}
+		if sourceCPU.Model != targetCPU.Model {
+			incompatible(IncompatibleCPUModel, "target has a %s cpu instead of %s", targetCPU.Model, sourceCPU.Model)
+		}
+		if missing := missingFeatures(sourceCPU.Features, targetCPU.Features); len(missing) > 0 {
+			incompatible(IncompatibleCPUFeatures, "target lacks the cpu features %s", strings.Join(missing, ", "))
+		}
+		invariantTSC = slices.Contains(sourceCPU.Features, "invtsc")
+	default:
+		// A custom cpu, which a host-model cpu is expanded to once the domain
+		// runs. The features of the model itself are checked by libvirt on
+		// the target, only the explicitly required ones are known here.
+		if domain == nil {
+			break
+		}
+		if domain.Vendor != "" && domain.Vendor != targetCPU.Vendor {
+			incompatible(IncompatibleCPUVendor, "target has a %s cpu instead of %s", targetCPU.Vendor, domain.Vendor)
+		}
+		var required []string
+		for _, feature := range domain.Features {
+			if feature.Policy == "" || feature.Policy == "require" || feature.Policy == "force" {
+				required = append(required, feature.Name)
+			}
+		}
+		if missing := missingFeatures(required, targetCPU.Features); len(missing) > 0 {
+			incompatible(IncompatibleCPUFeatures, "target lacks the cpu features %s", strings.Join(missing, ", "))
+		}
+		invariantTSC = slices.Contains(required, "invtsc")
+	}
+
+	// The clock of a guest relying on an invariant tsc jumps if the tsc
+	// runs at another frequency on the target and isn't scaled.
+	if invariantTSC && !targetCPU.TSCScaling {
+		sourceCPU, _ := hostCPUOf(source)
+		from, to := sourceCPU.TSCFrequency, targetCPU.TSCFrequency
+		if from > 0 && to > 0 && tscMismatch(from, to) {
+			incompatible(IncompatibleTSCFrequency, "target has a tsc frequency of %d Hz instead of %d Hz and can't scale it",
+				to, from)
+		}
+	}
+	return incompatibilities, nil
+}
+
+// +kubebuilder:rbac:groups=kvm.cloud.sap,resources=migrationprechecks,verbs=get;list;watch
+// +kubebuilder:rbac:groups=kvm.cloud.sap,resources=migrationprechecks/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=kvm.cloud.sap,resources=instances,verbs=get;list;watch
+// +kubebuilder:rbac:groups=kvm.cloud.sap,resources=hypervisors,verbs=get;list;watch
+
+// Reconcile is part of the main kubernetes reconciliation loop which aims to
+// move the current state of the cluster closer to the desired state.
+func (r *MigrationPrecheckReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := logger.FromContext(ctx)
+
+	precheck := &v1alpha1.MigrationPrecheck{}
+	if err := r.Get(ctx, req.NamespacedName, precheck); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	condition := meta.FindStatusCondition(precheck.Status.Conditions, v1alpha1.ConditionTypeCompatible)
+	if condition != nil && condition.ObservedGeneration == precheck.Generation {
+		// This generation was checked already.
+		return ctrl.Result{}, nil
+	}
+
+	// Only the agent of the host running the domain can check it, the
+	// cache only holds the instances of this host.
+	instance := &v1alpha1.Instance{}
+	key := client.ObjectKey{Namespace: sys.Namespace, Name: precheck.Spec.Instance}
+	if err := r.Get(ctx, key, instance); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	if instance.Labels[v1alpha1.LabelHypervisor] != sys.NodeLabelName {
+		return ctrl.Result{}, nil
+	}
+
+	source := &kvmv1.Hypervisor{}
+	if err := r.Get(ctx, client.ObjectKey{Name: sys.Hostname}, source); err != nil {
+		return ctrl.Result{}, err
+	}
+	cpu, err := r.Domains.DomainCPU(instance.Name)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	next := metav1.Condition{
+		Type:               v1alpha1.ConditionTypeCompatible,
+		ObservedGeneration: precheck.Generation,
+	}
+	var incompatibilities []v1alpha1.MigrationIncompatibility
+	target := &kvmv1.Hypervisor{}
+	if err := r.Reader.Get(ctx, client.ObjectKey{Name: precheck.Spec.Target}, target); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return ctrl.Result{}, err
+		}
+		err = fmt.Errorf("target hypervisor %s not found", precheck.Spec.Target)
+		next.Status, next.Reason, next.Message = metav1.ConditionUnknown, PrecheckReasonTargetUnknown, err.Error()
+	} else if incompatibilities, err = checkMigrationCompatibility(cpu, source, target); len(incompatibilities) > 0 {
+		messages := make([]string, 0, len(incompatibilities))
+		for _, incompatibility := range incompatibilities {
+			messages = append(messages, incompatibility.Message)
+		}
+		next.Status, next.Reason, next.Message = metav1.ConditionFalse, PrecheckReasonIncompatible, summarize(messages)
+	} else if err != nil {
+		next.Status, next.Reason, next.Message = metav1.ConditionUnknown, PrecheckReasonTargetUnknown, err.Error()
+	} else {
+		next.Status, next.Reason = metav1.ConditionTrue, PrecheckReasonCompatible
+		next.Message = "domain can be live migrated to " + precheck.Spec.Target
+	}
+	log.Info("checked migration", "target", precheck.Spec.Target, "compatible", next.Status)
+
+	base := precheck.DeepCopy()
+	precheck.Status.Source = sys.Hostname
+	precheck.Status.CPUMode = ""
+	if cpu != nil {
+		precheck.Status.CPUMode = cpu.Mode
+	}
+	precheck.Status.Incompatibilities = incompatibilities
+	meta.SetStatusCondition(&precheck.Status.Conditions, next)
+	return ctrl.Result{}, r.Status().Patch(ctx, precheck, client.MergeFrom(base))
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *MigrationPrecheckReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	inNamespace := predicate.NewPredicateFuncs(func(o client.Object) bool {
+		return o.GetNamespace() == sys.Namespace
+	})
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("migration-precheck").
+		For(&v1alpha1.MigrationPrecheck{}, builder.WithPredicates(inNamespace, predicate.GenerationChangedPredicate{})).
+		Complete(r)
+}
@@ -0,0 +1,169 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	kvmv1 "github.com/cobaltcore-dev/openstack-hypervisor-operator/api/v1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/cobaltcore-dev/kvm-node-agent/api/v1alpha1"
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/libvirt/dominfo"
+)
+
+var _ = Describe("MigrationPrecheck Controller", func() {
+	hypervisor := func(model, features string) *kvmv1.Hypervisor {
+		hypervisor := &kvmv1.Hypervisor{
+			ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{
+				HostCPUModelAnnotation:    model,
+				HostCPUVendorAnnotation:   "Intel",
+				HostCPUFeaturesAnnotation: features,
+			}},
+		}
+		hypervisor.Status.Capabilities.HostCpuArch = "x86_64"
+		hypervisor.Status.DomainCapabilities.HypervisorType = "kvm"
+		hypervisor.Status.DomainCapabilities.SupportedCpuModes = []string{
+			"mode/host-passthrough", "mode/host-passthrough/on", "mode/custom",
+		}
+		return hypervisor
+	}
+	reasons := func(incompatibilities []v1alpha1.MigrationIncompatibility) []string {
+		var reasons []string
+		for _, incompatibility := range incompatibilities {
+			reasons = append(reasons, incompatibility.Reason)
+		}
+		return reasons
+	}
+
+	It("should accept a host-passthrough cpu on an identical host", func() {
+		incompatibilities, err := checkMigrationCompatibility(
+			&dominfo.DomainCPU{Mode: "host-passthrough"},
+			hypervisor("Skylake-Server-IBRS", "pdpe1gb,ss"),
+			hypervisor("Skylake-Server-IBRS", "pdpe1gb,ss,vmx"),
+		)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(incompatibilities).To(BeEmpty())
+	})
+
+	It("should reject a host-passthrough cpu on another model", func() {
+		incompatibilities, err := checkMigrationCompatibility(
+			&dominfo.DomainCPU{Mode: "host-passthrough"},
+			hypervisor("Cascadelake-Server", "pdpe1gb,ss"),
+			hypervisor("Skylake-Server-IBRS", "ss"),
+		)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(reasons(incompatibilities)).To(Equal([]string{IncompatibleCPUModel, IncompatibleCPUFeatures}))
+		Expect(incompatibilities[1].Message).To(Equal("target lacks the cpu features pdpe1gb"))
+	})
+
+	It("should check the required features of a custom cpu", func() {
+		cpu := &dominfo.DomainCPU{
+			Mode:   "custom",
+			Model:  &dominfo.DomainCPUModel{Value: "Skylake-Server-IBRS"},
+			Vendor: "Intel",
+			Features: []dominfo.DomainCPUFeature{
+				{Policy: "require", Name: "vmx"},
+				{Policy: "disable", Name: "mpx"},
+			},
+		}
+		source := hypervisor("Cascadelake-Server", "mpx,vmx")
+		incompatibilities, err := checkMigrationCompatibility(cpu, source, hypervisor("Skylake-Server-IBRS", "vmx"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(incompatibilities).To(BeEmpty())
+
+		incompatibilities, err = checkMigrationCompatibility(cpu, source, hypervisor("Skylake-Server-IBRS", "ss"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(reasons(incompatibilities)).To(Equal([]string{IncompatibleCPUFeatures}))
+	})
+
+	It("should check a domain without cpu mode on aarch64 like a host-passthrough cpu", func() {
+		arm := func(model string) *kvmv1.Hypervisor {
+			hypervisor := hypervisor(model, "fp,asimd")
+			hypervisor.Annotations[HostCPUVendorAnnotation] = "ARM"
+			hypervisor.Status.Capabilities.HostCpuArch = "aarch64"
+			hypervisor.Status.DomainCapabilities.SupportedCpuModes = []string{"mode/host-passthrough", "mode/maximum"}
+			return hypervisor
+		}
+		incompatibilities, err := checkMigrationCompatibility(&dominfo.DomainCPU{}, arm("Neoverse-N1"), arm("Neoverse-N1"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(incompatibilities).To(BeEmpty())
+
+		incompatibilities, err = checkMigrationCompatibility(nil, arm("Neoverse-N1"), arm("Neoverse-V1"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(reasons(incompatibilities)).To(Equal([]string{IncompatibleCPUModel}))
+	})
+
+	It("should reject unsupported cpu modes and architectures", func() {
+		target := hypervisor("Skylake-Server-IBRS", "")
+		target.Status.Capabilities.HostCpuArch = "aarch64"
+		target.Status.DomainCapabilities.SupportedCpuModes = []string{"mode/custom"}
+		incompatibilities, err := checkMigrationCompatibility(
+			&dominfo.DomainCPU{Mode: "host-passthrough"},
+			hypervisor("Skylake-Server-IBRS", ""),
+			target,
+		)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(reasons(incompatibilities)).To(Equal([]string{IncompatibleArch, IncompatibleCPUMode}))
+	})
+
+	It("should reject another tsc frequency for guests with an invariant tsc", func() {
+		tsc := func(hypervisor *kvmv1.Hypervisor, frequency, scaling string) *kvmv1.Hypervisor {
+			hypervisor.Annotations[HostTSCFrequencyAnnotation] = frequency
+			hypervisor.Annotations[HostTSCScalingAnnotation] = scaling
+			return hypervisor
+		}
+		cpu := &dominfo.DomainCPU{Mode: "host-passthrough"}
+		source := tsc(hypervisor("Skylake-Server-IBRS", "invtsc"), "2100000000", "false")
+
+		// Within the tolerance of libvirt.
+		target := tsc(hypervisor("Skylake-Server-IBRS", "invtsc"), "2100400000", "false")
+		incompatibilities, err := checkMigrationCompatibility(cpu, source, target)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(incompatibilities).To(BeEmpty())
+
+		target = tsc(hypervisor("Skylake-Server-IBRS", "invtsc"), "2300000000", "false")
+		incompatibilities, err = checkMigrationCompatibility(cpu, source, target)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(reasons(incompatibilities)).To(Equal([]string{IncompatibleTSCFrequency}))
+
+		By("Scaling the tsc on the target")
+		target.Annotations[HostTSCScalingAnnotation] = "true"
+		incompatibilities, err = checkMigrationCompatibility(cpu, source, target)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(incompatibilities).To(BeEmpty())
+
+		By("Not exposing an invariant tsc to the guest")
+		target.Annotations[HostTSCScalingAnnotation] = "false"
+		incompatibilities, err = checkMigrationCompatibility(&dominfo.DomainCPU{Mode: "custom"}, source, target)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(incompatibilities).To(BeEmpty())
+	})
+
+	It("should not decide without the cpu of the target", func() {
+		target := hypervisor("", "")
+		target.Annotations = nil
+		incompatibilities, err := checkMigrationCompatibility(
+			&dominfo.DomainCPU{Mode: "host-passthrough"},
+			hypervisor("Skylake-Server-IBRS", ""),
+			target,
+		)
+		Expect(err).To(MatchError(errTargetCPUUnknown))
+		Expect(incompatibilities).To(BeEmpty())
+	})
+})
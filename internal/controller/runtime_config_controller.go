@@ -0,0 +1,112 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/events"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logger "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/config"
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/evacuation"
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/sys"
+)
+
+// RuntimeConfigReconciler reloads the runtime configuration of the agent
+// from its ConfigMap as it changes.
+type RuntimeConfigReconciler struct {
+	client.Client
+	// Store of the active runtime configuration.
+	Store *config.RuntimeStore
+	// Name of the ConfigMap in the namespace of the agent.
+	Name string
+	// Recorder of the rejected configurations, nil if no events are
+	// emitted.
+	Recorder events.EventRecorder
+}
+
+// +kubebuilder:rbac:groups="",namespace=monsoon3,resources=configmaps,verbs=get;list;watch
+
+// Reconcile the runtime configuration with the ConfigMap. An invalid
+// configuration is rejected and the active one is kept.
+func (r *RuntimeConfigReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := logger.FromContext(ctx)
+
+	var configMap corev1.ConfigMap
+	if err := r.Get(ctx, req.NamespacedName, &configMap); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return ctrl.Result{}, err
+		}
+		if r.Store.Generation() != "" {
+			log.Info("runtime config removed, using defaults")
+		}
+		r.Store.Set(config.DefaultRuntime(), "")
+		return ctrl.Result{}, nil
+	}
+
+	runtime, generation, err := r.parse(configMap.Data)
+	if err != nil {
+		log.Error(err, "rejected runtime config, keeping the active one", "generation", r.Store.Generation())
+		if r.Recorder != nil && r.Store.Rejected() == nil {
+			r.Recorder.Eventf(&configMap, nil, corev1.EventTypeWarning, "InvalidRuntimeConfig", "LoadRuntimeConfig",
+				"%s rejected the runtime config: %v", sys.Hostname, err)
+		}
+		r.Store.Reject(err)
+		return ctrl.Result{}, nil
+	}
+	if generation != r.Store.Generation() {
+		log.Info("applying runtime config", "generation", generation)
+	}
+	r.Store.Set(runtime, generation)
+	return ctrl.Result{}, nil
+}
+
+func (r *RuntimeConfigReconciler) parse(data map[string]string) (config.Runtime, string, error) {
+	value, ok := data[config.RuntimeKey]
+	if !ok {
+		return config.Runtime{}, "", fmt.Errorf("missing key %s", config.RuntimeKey)
+	}
+	runtime, generation, err := config.ParseRuntime(value)
+	if err != nil {
+		return config.Runtime{}, "", err
+	}
+	if _, err := evacuation.PolicyFromConfig(runtime.Evacuation); err != nil {
+		return config.Runtime{}, "", fmt.Errorf("invalid runtime config: %w", err)
+	}
+	return runtime, generation, nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *RuntimeConfigReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	key := types.NamespacedName{Namespace: sys.Namespace, Name: r.Name}
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("runtime-config").
+		For(&corev1.ConfigMap{}, builder.WithPredicates(predicate.NewPredicateFuncs(func(object client.Object) bool {
+			return client.ObjectKeyFromObject(object) == key
+		}))).
+		Complete(r)
+}
@@ -0,0 +1,130 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	kvmv1 "github.com/cobaltcore-dev/openstack-hypervisor-operator/api/v1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/events"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/config"
+)
+
+var _ = Describe("Runtime Config Controller", func() {
+	var (
+		ctx        context.Context
+		configMap  *corev1.ConfigMap
+		store      *config.RuntimeStore
+		recorder   *events.FakeRecorder
+		reconciler *RuntimeConfigReconciler
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		configMap = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "kvm-node-agent-runtime", Namespace: "monsoon3"},
+			Data: map[string]string{config.RuntimeKey: `
+statsInterval: 30s
+units: [multipathd.service]
+evacuation:
+  strategy: shutdown
+`},
+		}
+		scheme := runtime.NewScheme()
+		Expect(corev1.AddToScheme(scheme)).To(Succeed())
+		store = config.NewRuntimeStore()
+		recorder = events.NewFakeRecorder(10)
+		reconciler = &RuntimeConfigReconciler{
+			Client:   fake.NewClientBuilder().WithScheme(scheme).WithObjects(configMap).Build(),
+			Store:    store,
+			Name:     configMap.Name,
+			Recorder: recorder,
+		}
+	})
+
+	reconcile := func() {
+		_, err := reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(configMap)})
+		Expect(err).NotTo(HaveOccurred())
+	}
+
+	It("should apply the runtime config", func() {
+		reconcile()
+		Expect(store.Generation()).NotTo(BeEmpty())
+		Expect(store.Get().StatsInterval.Duration).To(Equal(30 * time.Second))
+		Expect(store.Get().Units).To(ConsistOf("multipathd.service"))
+		Expect(store.Get().Evacuation.Strategy).To(Equal("shutdown"))
+	})
+
+	It("should keep the active config when the new one is invalid", func() {
+		reconcile()
+		generation := store.Generation()
+
+		configMap.Data[config.RuntimeKey] = "evacuation: {strategy: cold-migrate}"
+		Expect(reconciler.Update(ctx, configMap)).To(Succeed())
+		reconcile()
+		Expect(store.Generation()).To(Equal(generation))
+		Expect(store.Rejected()).To(MatchError(ContainSubstring("cold-migrate")))
+		Expect(recorder.Events).To(Receive(ContainSubstring("InvalidRuntimeConfig")))
+
+		// Reported once, not on every reconcile.
+		reconcile()
+		Expect(recorder.Events).NotTo(Receive())
+	})
+
+	It("should fall back to the defaults once the ConfigMap is deleted", func() {
+		reconcile()
+		Expect(reconciler.Delete(ctx, configMap)).To(Succeed())
+		reconcile()
+		Expect(store.Generation()).To(BeEmpty())
+		Expect(store.Get()).To(Equal(config.DefaultRuntime()))
+	})
+
+	It("should report the active generation in the hypervisor status", func() {
+		hypervisorReconciler := &HypervisorReconciler{RuntimeConfig: store}
+		hypervisor := &kvmv1.Hypervisor{}
+		hypervisorReconciler.reconcileRuntimeConfig(hypervisor)
+		condition := meta.FindStatusCondition(hypervisor.Status.Conditions, RuntimeConfigType)
+		Expect(condition).NotTo(BeNil())
+		Expect(condition.Reason).To(Equal("Default"))
+
+		reconcile()
+		hypervisorReconciler.reconcileRuntimeConfig(hypervisor)
+		condition = meta.FindStatusCondition(hypervisor.Status.Conditions, RuntimeConfigType)
+		Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+		Expect(condition.Message).To(ContainSubstring(store.Generation()))
+
+		store.Reject(errors.New("invalid runtime config"))
+		hypervisorReconciler.reconcileRuntimeConfig(hypervisor)
+		condition = meta.FindStatusCondition(hypervisor.Status.Conditions, RuntimeConfigType)
+		Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+		Expect(condition.Reason).To(Equal("Rejected"))
+		Expect(condition.Message).To(ContainSubstring(store.Generation()))
+	})
+})
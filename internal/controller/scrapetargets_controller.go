@@ -0,0 +1,126 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"net"
+	"slices"
+	"strconv"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	corev1ac "k8s.io/client-go/applyconfigurations/core/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/cobaltcore-dev/kvm-node-agent/api/v1alpha1"
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/sys"
+)
+
+const (
+	// Label marking the config maps holding Prometheus HTTP SD targets.
+	LabelScrapeTargets = "kvm.cloud.sap/scrape-targets"
+	// Key of the target groups in the config map.
+	ScrapeTargetsKey = "targets.json"
+)
+
+// Name of the config map holding the scrape targets of this host.
+func ScrapeTargetsConfigMapName() string {
+	return "kvm-node-agent-scrape-targets-" + sys.Hostname
+}
+
+// ScrapeTargetGroup is a target group in the Prometheus HTTP SD format.
+type ScrapeTargetGroup struct {
+	Targets []string          `json:"targets"`
+	Labels  map[string]string `json:"labels"`
+}
+
+// ScrapeTargetsReconciler publishes the primary fixed ip of every instance
+// on this host as Prometheus HTTP SD target, so that exporters running
+// inside the guests can be scraped.
+type ScrapeTargetsReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+	// Port the exporters listen on inside the guests.
+	Port int
+}
+
+// +kubebuilder:rbac:groups=kvm.cloud.sap,resources=instances,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",namespace=monsoon3,resources=configmaps,verbs=get;list;watch;create;update;patch
+
+// Reconcile rebuilds the scrape targets from all instances of this host,
+// regardless of the instance that changed.
+func (r *ScrapeTargetsReconciler) Reconcile(ctx context.Context, _ ctrl.Request) (ctrl.Result, error) {
+	var instances v1alpha1.InstanceList
+	if err := r.List(ctx, &instances,
+		client.InNamespace(sys.Namespace),
+		client.MatchingLabels{v1alpha1.LabelHypervisor: sys.NodeLabelName},
+	); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	data, err := json.Marshal(scrapeTargets(instances.Items, r.Port))
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	cm := corev1ac.ConfigMap(ScrapeTargetsConfigMapName(), sys.Namespace).
+		WithLabels(map[string]string{
+			LabelScrapeTargets:       "true",
+			v1alpha1.LabelHypervisor: sys.NodeLabelName,
+		}).
+		WithData(map[string]string{ScrapeTargetsKey: string(data)})
+	// Apply instead of get and update, the config map is not cached.
+	err = r.Apply(ctx, cm, client.FieldOwner("kvm-node-agent"), client.ForceOwnership)
+	return ctrl.Result{}, err
+}
+
+// Build one target group per instance with a fixed ip, sorted by the
+// instance uuid to keep the config map stable.
+func scrapeTargets(instances []v1alpha1.Instance, port int) []ScrapeTargetGroup {
+	groups := make([]ScrapeTargetGroup, 0, len(instances))
+	for _, instance := range instances {
+		if len(instance.Status.FixedIPs) == 0 {
+			continue
+		}
+		groups = append(groups, ScrapeTargetGroup{
+			Targets: []string{net.JoinHostPort(instance.Status.FixedIPs[0], strconv.Itoa(port))},
+			Labels: map[string]string{
+				"hypervisor":    instance.Status.Hypervisor,
+				"domain":        instance.Status.DomainName,
+				"instance_uuid": instance.Name,
+				"instance_name": instance.Status.InstanceName,
+				"project_id":    instance.Status.Owner.ProjectID,
+			},
+		})
+	}
+	slices.SortFunc(groups, func(a, b ScrapeTargetGroup) int {
+		return cmp.Compare(a.Labels["instance_uuid"], b.Labels["instance_uuid"])
+	})
+	return groups
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *ScrapeTargetsReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	// The cache only holds the instances of this host.
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("scrapetargets").
+		For(&v1alpha1.Instance{}).
+		Complete(r)
+}
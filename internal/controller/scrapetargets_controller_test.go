@@ -0,0 +1,64 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/cobaltcore-dev/kvm-node-agent/api/v1alpha1"
+)
+
+var _ = Describe("ScrapeTargets Controller", func() {
+	instance := func(uuid string, ips ...string) v1alpha1.Instance {
+		return v1alpha1.Instance{
+			ObjectMeta: metav1.ObjectMeta{Name: uuid},
+			Status: v1alpha1.InstanceStatus{
+				Hypervisor:   "hv-1",
+				DomainName:   "instance-" + uuid,
+				InstanceName: "vm-" + uuid,
+				Owner:        v1alpha1.InstanceOwner{ProjectID: "project"},
+				FixedIPs:     ips,
+			},
+		}
+	}
+
+	It("should publish one target group per instance with a fixed ip", func() {
+		groups := scrapeTargets([]v1alpha1.Instance{
+			instance("b", "fd00::1"),
+			instance("c"),
+			instance("a", "10.0.0.1", "fd00::2"),
+		}, 9100)
+
+		Expect(groups).To(HaveLen(2))
+		Expect(groups[0].Targets).To(Equal([]string{"10.0.0.1:9100"}))
+		Expect(groups[0].Labels).To(Equal(map[string]string{
+			"hypervisor":    "hv-1",
+			"domain":        "instance-a",
+			"instance_uuid": "a",
+			"instance_name": "vm-a",
+			"project_id":    "project",
+		}))
+		Expect(groups[1].Targets).To(Equal([]string{"[fd00::1]:9100"}))
+	})
+
+	It("should publish an empty list without instances", func() {
+		Expect(scrapeTargets(nil, 9100)).To(BeEmpty())
+	})
+})
@@ -18,17 +18,25 @@ limitations under the License.
 package controller
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"maps"
 	"os"
 	"path/filepath"
+	"time"
 
 	kvmv1 "github.com/cobaltcore-dev/openstack-hypervisor-operator/api/v1"
+	"github.com/coreos/go-systemd/v22/dbus"
+	"go.opentelemetry.io/otel/attribute"
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/util/retry"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -37,8 +45,11 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	"github.com/cobaltcore-dev/kvm-node-agent/internal/certificates"
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/libvirt"
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/logging"
 	"github.com/cobaltcore-dev/kvm-node-agent/internal/sys"
 	"github.com/cobaltcore-dev/kvm-node-agent/internal/systemd"
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/tracing"
 )
 
 // SecretReconciler reconciles a Secret object
@@ -46,18 +57,60 @@ type SecretReconciler struct {
 	client.Client
 	Scheme  *runtime.Scheme
 	Systemd systemd.Interface
+	// Checks the libvirt TLS endpoints after installing a certificate,
+	// skipped if nil.
+	SmokeTest *certificates.SmokeTest
+	// Path of the installed server certificate, defaults to the one
+	// written by certificates.UpdateTLSCertificate.
+	CertFile string
+	// Remaining validity of the certificate below which it is reported as
+	// expiring, defaults to 30 minutes.
+	ExpiryWarning time.Duration
+	// Whether the TLS certificate is served by the monolithic libvirtd or
+	// by virtproxyd, defaults to monolithic.
+	LibvirtDaemons libvirt.DaemonMode
 
+	// Name of a ConfigMap in the namespace of the Secret with a CA bundle
+	// merged into the CA certificate of the Secret, e.g. if the cluster CA
+	// is distributed separately. Not merged if empty.
+	CABundleConfigMap string
+	// Key of the CA bundle in the ConfigMap, defaults to ca.crt.
+	CABundleKey string
+
+	// Resource version of the installed Secret, and of the CA bundle
+	// ConfigMap separated by a slash.
 	lastResourceVersion string
+	// Resource version of the Secret whose certificate was rolled back, it
+	// isn't installed again.
+	rolledBackVersion string
+	// Interval in which the started units are checked until their job
+	// finished, defaults to 1 second.
+	unitPollInterval time.Duration
 }
 
-// +kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch
+// Condition of the hypervisor reporting that libvirt failed to reload the
+// certificate of the Secret and the previous one was restored.
+const TLSRollbackType = "TLSRollback"
+
+// Time to wait for the job of a started unit to finish.
+const unitJobTimeout = 2 * time.Minute
+
+// +kubebuilder:rbac:groups="",namespace=monsoon3,resources=secrets,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",namespace=monsoon3,resources=configmaps,verbs=get;list;watch
 // +kubebuilder:rbac:groups=kvm.cloud.sap,resources=hypervisors,verbs=get;list;watch
 // +kubebuilder:rbac:groups=kvm.cloud.sap,resources=hypervisors/status,verbs=get;update;patch
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
 func (r *SecretReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
-	log := logger.FromContext(ctx)
+	ctx, span := tracing.Start(ctx, "SecretReconciler.Reconcile", attribute.String("secret", req.Name))
+	result, err := r.reconcile(ctx, req)
+	tracing.End(span, err)
+	return result, err
+}
+
+func (r *SecretReconciler) reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := logger.FromContext(ctx).WithName(logging.Certificates)
 
 	// Fetch the Secret instance
 	secret := &v1.Secret{}
@@ -77,9 +130,35 @@ func (r *SecretReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 		return ctrl.Result{}, nil
 	}
 
-	if secret.ResourceVersion == r.lastResourceVersion {
-		return ctrl.Result{}, r.setTLSStatusCondition(ctx, metav1.ConditionTrue, "Ready",
-			"TLS certificate is ready and up to date")
+	data, version, err := r.certificateData(ctx, secret)
+	if err != nil {
+		if err := r.setTLSStatusCondition(ctx, metav1.ConditionFalse,
+			"FailedToMergeCABundle", fmt.Sprintf("Failed to merge CA bundle: %v", err)); err != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{}, err
+	}
+
+	if version == r.lastResourceVersion {
+		// The installed certificate may still differ, e.g. after the host
+		// was restored from an image. It is replaced before it expires.
+		if r.isInstalled(data["tls.crt"]) {
+			return r.checkExpiry(ctx, data["tls.crt"], "TLS certificate is ready and up to date")
+		}
+		log.Info("Installed TLS certificate differs from Secret, reinstalling")
+	}
+
+	if version == r.rolledBackVersion {
+		log.Info("TLS certificate of the Secret was rolled back, waiting for a new one")
+		return ctrl.Result{}, nil
+	}
+
+	// Keep the installed certificate until the Secret is fixed, e.g. by
+	// the renewal of the certificate.
+	if err = certificates.ValidateTLSCertificate(data, time.Now()); err != nil {
+		log.Error(err, "Refusing to install TLS certificate")
+		return ctrl.Result{}, r.setTLSStatusCondition(ctx, metav1.ConditionFalse,
+			"InvalidCertificate", fmt.Sprintf("Refusing to install TLS certificate: %v", err))
 	}
 
 	if err = r.setTLSStatusCondition(ctx, metav1.ConditionFalse,
@@ -87,7 +166,11 @@ func (r *SecretReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 		return ctrl.Result{}, err
 	}
 
-	if err = certificates.UpdateTLSCertificate(ctx, secret.Data); err != nil {
+	backup, err := certificates.BackupTLSCertificate(time.Now())
+	if err != nil {
+		log.Error(err, "failed to back up the installed TLS certificate, installing without rollback")
+	}
+	if err = certificates.UpdateTLSCertificate(ctx, data); err != nil {
 		// update conditions
 		if err := r.setTLSStatusCondition(ctx, metav1.ConditionFalse,
 			"FailedToUpdateTLSCertificate", fmt.Sprintf("Failed to update TLS certificate: %v", err)); err != nil {
@@ -96,38 +179,206 @@ func (r *SecretReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 		return ctrl.Result{}, err
 	}
 
-	// Reload the libvirtd service
-	if _, err = r.Systemd.StartUnit(ctx, "virt-admin-server-update-tls.service"); err != nil {
-		if err := r.setTLSStatusCondition(ctx, metav1.ConditionFalse,
-			"FailedToStartUpdateTLSService",
-			fmt.Sprintf("Failed to start virt-admin-server-update-tls service: %v", err)); err != nil {
+	if err = r.reloadTLSCertificate(ctx); err != nil {
+		if backup == "" {
+			if err := r.setTLSStatusCondition(ctx, metav1.ConditionFalse,
+				"FailedToStartUpdateTLSService", err.Error()); err != nil {
+				return ctrl.Result{}, err
+			}
 			return ctrl.Result{}, err
 		}
-		log.Error(err, "failed to start virt-admin-server-update-tls service")
-		// Start the libvirtd service
-		if _, err = r.Systemd.StartUnit(ctx, "libvirtd.service"); err != nil {
+		return r.rollback(ctx, version, backup, err)
+	}
+	if err = r.setStatusCondition(ctx, func(conditions *[]metav1.Condition) {
+		if meta.FindStatusCondition(*conditions, TLSRollbackType) != nil {
+			meta.SetStatusCondition(conditions, metav1.Condition{
+				Type:    TLSRollbackType,
+				Status:  metav1.ConditionFalse,
+				Reason:  "Installed",
+				Message: "TLS certificate of the Secret is installed",
+			})
+		}
+	}); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	message := "TLS certificate is ready and updated"
+	if r.SmokeTest != nil {
+		result, err := r.SmokeTest.Run(ctx)
+		if err != nil {
+			// Not saving the resource version, so that the installation is
+			// retried.
+			if err := r.setTLSStatusCondition(ctx, metav1.ConditionFalse,
+				"SmokeTestFailed", fmt.Sprintf("TLS certificate installed, but %v", err)); err != nil {
+				return ctrl.Result{}, err
+			}
 			return ctrl.Result{}, err
 		}
+		message += "; " + result
 	}
 
 	// Save the last resource version to file system
-	pki := os.Getenv("PKI_PATH")
+	pki := certificates.PKIPath()
 	path := filepath.Join(pki, "CA", ".last_resource_version")
-	if err = os.WriteFile(path, []byte(secret.ResourceVersion), 0600); err != nil {
+	if err = os.WriteFile(path, []byte(version), 0600); err != nil {
 		// not a failure condition, just log the error
 		log.Error(err, "failed to write last resource version", "path", path)
 	}
-	r.lastResourceVersion = secret.ResourceVersion
+	r.lastResourceVersion = version
+
+	return r.checkExpiry(ctx, data["tls.crt"], message)
+}
+
+// Get the certificate data of the Secret with the CA bundle merged into
+// its CA certificate, and their resource version.
+func (r *SecretReconciler) certificateData(ctx context.Context, secret *v1.Secret) (map[string][]byte, string, error) {
+	if r.CABundleConfigMap == "" {
+		return secret.Data, secret.ResourceVersion, nil
+	}
+	configMap := &v1.ConfigMap{}
+	key := types.NamespacedName{Namespace: secret.Namespace, Name: r.CABundleConfigMap}
+	if err := r.Get(ctx, key, configMap); err != nil {
+		return nil, "", err
+	}
+	bundleKey := r.CABundleKey
+	if bundleKey == "" {
+		bundleKey = "ca.crt"
+	}
+	bundle, ok := configMap.Data[bundleKey]
+	if !ok {
+		return nil, "", fmt.Errorf("missing key %s in ConfigMap %s", bundleKey, r.CABundleConfigMap)
+	}
+	ca, err := certificates.MergeCABundle(secret.Data["ca.crt"], []byte(bundle))
+	if err != nil {
+		return nil, "", err
+	}
+	data := maps.Clone(secret.Data)
+	data["ca.crt"] = ca
+	return data, secret.ResourceVersion + "/" + configMap.ResourceVersion, nil
+}
 
-	return ctrl.Result{}, r.setTLSStatusCondition(ctx, metav1.ConditionTrue, "Ready",
-		"TLS certificate is ready and updated")
+// Reload the TLS certificate of libvirtd or virtproxyd. If the update
+// service fails, e.g. as the daemon isn't running, the daemon serving
+// remote connections is started with the certificate instead. Returns an
+// error if neither worked.
+func (r *SecretReconciler) reloadTLSCertificate(ctx context.Context) error {
+	log := logger.FromContext(ctx).WithName(logging.Certificates)
+	updateUnit := r.LibvirtDaemons.TLSUpdateUnit()
+	updateErr := r.startUnit(ctx, updateUnit)
+	if updateErr == nil {
+		return nil
+	}
+	log.Error(updateErr, "failed to start TLS update service", "unit", updateUnit)
+	if err := r.startUnit(ctx, r.LibvirtDaemons.RemoteUnit()); err != nil {
+		return errors.Join(updateErr, err)
+	}
+	return nil
+}
+
+// Start the unit and wait for its job to finish. Returns an error if the
+// unit failed.
+func (r *SecretReconciler) startUnit(ctx context.Context, unit string) error {
+	if _, err := r.Systemd.StartUnit(ctx, unit); err != nil {
+		return fmt.Errorf("failed to start %s: %w", unit, err)
+	}
+	interval := r.unitPollInterval
+	if interval == 0 {
+		interval = time.Second
+	}
+	var status dbus.UnitStatus
+	err := wait.PollUntilContextTimeout(ctx, interval, unitJobTimeout, true, func(ctx context.Context) (bool, error) {
+		var err error
+		status, err = r.Systemd.GetUnitByName(ctx, unit)
+		if err != nil {
+			return false, err
+		}
+		return status.JobId == 0 && status.ActiveState != systemd.ACTIVATING, nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to wait for %s: %w", unit, err)
+	}
+	if status.ActiveState == systemd.FAILED {
+		return fmt.Errorf("%s failed: %s", unit, status.SubState)
+	}
+	return nil
+}
+
+// Restore the previous certificate after libvirt failed to reload the one
+// of the Secret, so that the host keeps working TLS. The certificate of
+// the Secret isn't installed again until the Secret changes.
+func (r *SecretReconciler) rollback(
+	ctx context.Context, version string, backup string, reloadErr error,
+) (ctrl.Result, error) {
+	log := logger.FromContext(ctx).WithName(logging.Certificates)
+	log.Error(reloadErr, "libvirt failed to reload the TLS certificate, rolling back", "backup", backup)
+	if err := certificates.RestoreTLSCertificate(ctx, backup); err != nil {
+		return ctrl.Result{}, errors.Join(reloadErr, err)
+	}
+	if err := r.reloadTLSCertificate(ctx); err != nil {
+		return ctrl.Result{}, errors.Join(reloadErr, fmt.Errorf("rolled back, but %w", err))
+	}
+	r.rolledBackVersion = version
+
+	message := fmt.Sprintf("Rolled back to the previous TLS certificate, libvirt failed to reload the one "+
+		"of version %s: %v", version, reloadErr)
+	if err := r.setStatusCondition(ctx, func(conditions *[]metav1.Condition) {
+		meta.SetStatusCondition(conditions, metav1.Condition{
+			Type:    TLSRollbackType,
+			Status:  metav1.ConditionTrue,
+			Reason:  "ReloadFailed",
+			Message: message,
+		})
+		meta.SetStatusCondition(conditions, metav1.Condition{
+			Type:    "TLSCertificateInstalled",
+			Status:  metav1.ConditionFalse,
+			Reason:  "RolledBack",
+			Message: message,
+		})
+	}); err != nil {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{}, nil
+}
+
+// Check if the certificate is the one installed on the host.
+func (r *SecretReconciler) isInstalled(cert []byte) bool {
+	path := r.CertFile
+	if path == "" {
+		_, path, _ = certificates.TLSFiles()
+	}
+	installed, err := os.ReadFile(path)
+	return err == nil && bytes.Equal(installed, cert)
+}
+
+// Report the installed certificate as ready unless it is about to expire,
+// and check it again before it expires, also if the Secret doesn't change.
+func (r *SecretReconciler) checkExpiry(ctx context.Context, cert []byte, message string) (ctrl.Result, error) {
+	expiry, err := certificates.CertificateExpiry(cert)
+	if err != nil {
+		logger.FromContext(ctx).WithName(logging.Certificates).Info("Unable to parse TLS certificate, not tracking its expiry", "error", err.Error())
+		return ctrl.Result{}, r.setTLSStatusCondition(ctx, metav1.ConditionTrue, "Ready", message)
+	}
+	certificates.SetInstalledExpiry(expiry)
+
+	warning := r.ExpiryWarning
+	if warning == 0 {
+		warning = 30 * time.Minute
+	}
+	remaining := time.Until(expiry)
+	if remaining < warning {
+		return ctrl.Result{RequeueAfter: time.Minute}, r.setTLSStatusCondition(ctx, metav1.ConditionFalse,
+			"Expiring", fmt.Sprintf("TLS certificate expires at %s and was not renewed",
+				expiry.UTC().Format(time.RFC3339)))
+	}
+	return ctrl.Result{RequeueAfter: remaining - warning},
+		r.setTLSStatusCondition(ctx, metav1.ConditionTrue, "Ready", message)
 }
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *SecretReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	// Load the last resource version from file system, so we can skip
 	// processing if the resource version hasn't changed
-	pki := os.Getenv("PKI_PATH")
+	pki := certificates.PKIPath()
 	path := filepath.Join(pki, "CA", ".last_resource_version")
 	if buf, err := os.ReadFile(path); err != nil {
 		logger.Log.Info("No last resource version found for PKI secrets", "path", path)
@@ -146,16 +397,41 @@ func (r *SecretReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		}
 		return nil
 	})
-	return ctrl.NewControllerManagedBy(mgr).
+	builder := ctrl.NewControllerManagedBy(mgr).
 		Named("secret").
-		Watches(&v1.Secret{}, evHandler).
-		Complete(r)
+		Watches(&v1.Secret{}, evHandler)
+	if r.CABundleConfigMap != "" {
+		// Reinstall the certificate of the host with the changed bundle.
+		builder = builder.Watches(&v1.ConfigMap{}, handler.EnqueueRequestsFromMapFunc(
+			func(ctx context.Context, a client.Object) []reconcile.Request {
+				if a.GetName() != r.CABundleConfigMap {
+					return nil
+				}
+				return []reconcile.Request{
+					{NamespacedName: types.NamespacedName{Name: secretName, Namespace: a.GetNamespace()}},
+				}
+			}))
+	}
+	return builder.Complete(r)
 }
 
 func (r *SecretReconciler) setTLSStatusCondition(ctx context.Context, status metav1.ConditionStatus,
 	reason, message string) error {
 
-	log := logger.FromContext(ctx)
+	return r.setStatusCondition(ctx, func(conditions *[]metav1.Condition) {
+		meta.SetStatusCondition(conditions, metav1.Condition{
+			Type:    "TLSCertificateInstalled",
+			Status:  status,
+			Reason:  reason,
+			Message: message,
+		})
+	})
+}
+
+// Update the conditions of the hypervisor, patching them only if they
+// changed.
+func (r *SecretReconciler) setStatusCondition(ctx context.Context, update func(conditions *[]metav1.Condition)) error {
+	log := logger.FromContext(ctx).WithName(logging.Certificates)
 	hv := &kvmv1.Hypervisor{}
 
 	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
@@ -165,12 +441,10 @@ func (r *SecretReconciler) setTLSStatusCondition(ctx context.Context, status met
 		}
 
 		base := hv.DeepCopy()
-		meta.SetStatusCondition(&hv.Status.Conditions, metav1.Condition{
-			Type:    "TLSCertificateInstalled",
-			Status:  status,
-			Reason:  reason,
-			Message: message,
-		})
+		update(&hv.Status.Conditions)
+		if equality.Semantic.DeepEqual(base.Status.Conditions, hv.Status.Conditions) {
+			return nil
+		}
 
 		return r.Status().Patch(ctx, hv, client.MergeFromWithOptions(base, client.MergeFromWithOptimisticLock{}))
 	})
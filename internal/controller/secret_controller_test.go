@@ -19,10 +19,19 @@ package controller
 
 import (
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
 	"os"
 	"path/filepath"
+	"time"
 
 	kvmv1 "github.com/cobaltcore-dev/openstack-hypervisor-operator/api/v1"
+	"github.com/coreos/go-systemd/v22/dbus"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	v1 "k8s.io/api/core/v1"
@@ -34,7 +43,10 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/certificates"
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/config"
 	"github.com/cobaltcore-dev/kvm-node-agent/internal/sys"
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/systemd"
 )
 
 var _ = Describe("Secret Controller", func() {
@@ -60,7 +72,7 @@ var _ = Describe("Secret Controller", func() {
 		tempPKIPath, err = os.MkdirTemp("", "pki-test-*")
 		Expect(err).NotTo(HaveOccurred())
 		Expect(os.MkdirAll(filepath.Join(tempPKIPath, "CA"), 0755)).To(Succeed())
-		os.Setenv("PKI_PATH", tempPKIPath)
+		certificates.Configure(config.PKI{Path: tempPKIPath})
 
 		// Setup scheme
 		scheme = runtime.NewScheme()
@@ -102,21 +114,23 @@ var _ = Describe("Secret Controller", func() {
 
 		// Create reconciler
 		reconciler = &SecretReconciler{
-			Client: fakeClient,
-			Scheme: scheme,
+			Client:   fakeClient,
+			Scheme:   scheme,
+			CertFile: filepath.Join(tempPKIPath, "servercert.pem"),
 		}
 	})
 
 	AfterEach(func() {
 		// Clean up temporary directory
 		os.RemoveAll(tempPKIPath)
-		os.Unsetenv("PKI_PATH")
+		certificates.Configure(config.PKI{})
 	})
 
 	Context("When reconciling a resource", func() {
 		It("should set status condition to Ready when resource version hasn't changed", func() {
 			// Set the last resource version to match the secret's version
 			reconciler.lastResourceVersion = testSecret.ResourceVersion
+			Expect(os.WriteFile(reconciler.CertFile, testSecret.Data["tls.crt"], 0644)).To(Succeed())
 
 			// Reconcile the secret
 			req := ctrl.Request{
@@ -142,6 +156,147 @@ var _ = Describe("Secret Controller", func() {
 			Expect(condition.Message).To(Equal("TLS certificate is ready and up to date"))
 		})
 
+		It("should check the installed certificate again before it expires", func() {
+			testSecret.Data["tls.crt"] = selfSignedCertificate(time.Now().Add(3 * time.Hour))
+			Expect(fakeClient.Update(ctx, testSecret)).To(Succeed())
+			reconciler.lastResourceVersion = testSecret.ResourceVersion
+			Expect(os.WriteFile(reconciler.CertFile, testSecret.Data["tls.crt"], 0644)).To(Succeed())
+
+			req := ctrl.Request{
+				NamespacedName: types.NamespacedName{
+					Name:      testSecretName,
+					Namespace: testNamespace,
+				},
+			}
+			result, err := reconciler.Reconcile(ctx, req)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(BeNumerically("~", 150*time.Minute, time.Minute))
+
+			By("Reporting a certificate about to expire")
+			reconciler.ExpiryWarning = 4 * time.Hour
+			result, err = reconciler.Reconcile(ctx, req)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(Equal(time.Minute))
+
+			updatedHV := &kvmv1.Hypervisor{}
+			Expect(fakeClient.Get(ctx, types.NamespacedName{Name: sys.Hostname}, updatedHV)).To(Succeed())
+			condition := meta.FindStatusCondition(updatedHV.Status.Conditions, "TLSCertificateInstalled")
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+			Expect(condition.Reason).To(Equal("Expiring"))
+		})
+
+		It("should refuse to install an invalid certificate", func() {
+			req := ctrl.Request{
+				NamespacedName: types.NamespacedName{
+					Name:      testSecretName,
+					Namespace: testNamespace,
+				},
+			}
+			result, err := reconciler.Reconcile(ctx, req)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result).To(Equal(ctrl.Result{}))
+			Expect(reconciler.lastResourceVersion).To(BeEmpty())
+			_, certFile, _ := certificates.TLSFiles()
+			Expect(certFile).NotTo(BeAnExistingFile())
+
+			updatedHV := &kvmv1.Hypervisor{}
+			Expect(fakeClient.Get(ctx, types.NamespacedName{Name: sys.Hostname}, updatedHV)).To(Succeed())
+			condition := meta.FindStatusCondition(updatedHV.Status.Conditions, "TLSCertificateInstalled")
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+			Expect(condition.Reason).To(Equal("InvalidCertificate"))
+			Expect(condition.Message).To(ContainSubstring("missing data for secret key ca.crt"))
+		})
+
+		It("should roll back to the previous certificate if libvirt fails to reload it", func() {
+			previous := selfSignedSecretData(time.Now().Add(time.Hour))
+			Expect(certificates.UpdateTLSCertificate(ctx, previous)).To(Succeed())
+			testSecret.Data = selfSignedSecretData(time.Now().Add(2 * time.Hour))
+			Expect(fakeClient.Update(ctx, testSecret)).To(Succeed())
+
+			// The update service and the daemon fail with the new certificate.
+			var started []string
+			reconciler.unitPollInterval = time.Millisecond
+			reconciler.Systemd = &systemd.InterfaceMock{
+				StartUnitFunc: func(ctx context.Context, unit string) (int, error) {
+					started = append(started, unit)
+					return 1, nil
+				},
+				GetUnitByNameFunc: func(ctx context.Context, unit string) (dbus.UnitStatus, error) {
+					if len(started) <= 2 {
+						return dbus.UnitStatus{Name: unit, ActiveState: systemd.FAILED, SubState: "failed"}, nil
+					}
+					return dbus.UnitStatus{Name: unit, ActiveState: systemd.INACTIVE}, nil
+				},
+			}
+
+			req := ctrl.Request{
+				NamespacedName: types.NamespacedName{
+					Name:      testSecretName,
+					Namespace: testNamespace,
+				},
+			}
+			result, err := reconciler.Reconcile(ctx, req)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result).To(Equal(ctrl.Result{}))
+			Expect(started).To(Equal([]string{
+				"virt-admin-server-update-tls.service", "libvirtd.service", "virt-admin-server-update-tls.service",
+			}))
+			_, certFile, _ := certificates.TLSFiles()
+			Expect(os.ReadFile(certFile)).To(Equal(previous["tls.crt"]))
+
+			updatedHV := &kvmv1.Hypervisor{}
+			Expect(fakeClient.Get(ctx, types.NamespacedName{Name: sys.Hostname}, updatedHV)).To(Succeed())
+			Expect(meta.IsStatusConditionTrue(updatedHV.Status.Conditions, TLSRollbackType)).To(BeTrue())
+			condition := meta.FindStatusCondition(updatedHV.Status.Conditions, "TLSCertificateInstalled")
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Reason).To(Equal("RolledBack"))
+
+			By("Not installing the rolled back certificate again")
+			_, err = reconciler.Reconcile(ctx, req)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(started).To(HaveLen(3))
+		})
+
+		It("should merge the CA bundle of the ConfigMap into the CA certificate", func() {
+			host := selfSignedSecretData(time.Now().Add(time.Hour))
+			other := selfSignedSecretData(time.Now().Add(time.Hour))
+			// The CA of the host certificate is only in the bundle.
+			testSecret.Data = map[string][]byte{
+				"ca.crt": other["ca.crt"], "tls.crt": host["tls.crt"], "tls.key": host["tls.key"],
+			}
+			Expect(fakeClient.Update(ctx, testSecret)).To(Succeed())
+			bundle := &v1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: "cluster-ca", Namespace: testNamespace},
+				Data:       map[string]string{"bundle.pem": string(host["ca.crt"])},
+			}
+			Expect(fakeClient.Create(ctx, bundle)).To(Succeed())
+			reconciler.CABundleConfigMap = bundle.Name
+			reconciler.CABundleKey = "bundle.pem"
+			reconciler.Systemd = &systemd.InterfaceMock{
+				StartUnitFunc: func(ctx context.Context, unit string) (int, error) { return 1, nil },
+				GetUnitByNameFunc: func(ctx context.Context, unit string) (dbus.UnitStatus, error) {
+					return dbus.UnitStatus{Name: unit, ActiveState: systemd.ACTIVE}, nil
+				},
+			}
+
+			req := ctrl.Request{
+				NamespacedName: types.NamespacedName{
+					Name:      testSecretName,
+					Namespace: testNamespace,
+				},
+			}
+			_, err := reconciler.Reconcile(ctx, req)
+			Expect(err).NotTo(HaveOccurred())
+			caFile, _, _ := certificates.TLSFiles()
+			installed, err := os.ReadFile(caFile)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(installed)).To(HavePrefix(string(other["ca.crt"])))
+			Expect(string(installed)).To(ContainSubstring(string(host["ca.crt"])))
+			Expect(reconciler.lastResourceVersion).To(Equal(testSecret.ResourceVersion + "/" + bundle.ResourceVersion))
+		})
+
 		It("should skip reconciliation when InstallCertificate is false", func() {
 			// Update the hypervisor to not require certificate installation
 			testHV.Spec.InstallCertificate = false
@@ -207,3 +362,31 @@ var _ = Describe("Secret Controller", func() {
 		})
 	})
 })
+
+// Create a self-signed PEM encoded certificate expiring at notAfter.
+func selfSignedCertificate(notAfter time.Time) []byte {
+	return selfSignedSecretData(notAfter)["tls.crt"]
+}
+
+// Create the data of a certificate Secret with a self-signed certificate
+// expiring at notAfter, which is its own CA.
+func selfSignedSecretData(notAfter time.Time) map[string][]byte {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	Expect(err).NotTo(HaveOccurred())
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: sys.Hostname},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	Expect(err).NotTo(HaveOccurred())
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	Expect(err).NotTo(HaveOccurred())
+	cert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	return map[string][]byte{
+		"ca.crt":  cert,
+		"tls.crt": cert,
+		"tls.key": pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+	}
+}
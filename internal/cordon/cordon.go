@@ -0,0 +1,120 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package cordon takes the node of the agent out of the scheduling of
+// kubernetes while its instances are evacuated or it reboots, so that the
+// kube scheduler and the scheduler of the instances agree on the host.
+package cordon
+
+import (
+	"context"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// Key of the taint set on the cordoned node, its value is the reason,
+	// e.g. "evacuation".
+	TaintKey = "kvm.cloud.sap/maintenance"
+	// Annotation of the node with the time the agent cordoned it, so that
+	// nodes cordoned by an administrator are left alone.
+	Annotation = "kvm.cloud.sap/cordoned"
+)
+
+const (
+	ReasonEvacuation = "evacuation"
+	ReasonOSUpdate   = "os-update"
+)
+
+// Cordon marks the node as unschedulable and taints it with the reason.
+// Nodes which are already unschedulable without having been cordoned by
+// the agent are not touched, so that the agent never uncordons them.
+func Cordon(ctx context.Context, c client.Client, name, reason string) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		var node corev1.Node
+		if err := c.Get(ctx, client.ObjectKey{Name: name}, &node); err != nil {
+			return err
+		}
+		_, cordoned := node.Annotations[Annotation]
+		if node.Spec.Unschedulable && !cordoned {
+			return nil
+		}
+
+		base := node.DeepCopy()
+		node.Spec.Unschedulable = true
+		if node.Annotations == nil {
+			node.Annotations = map[string]string{}
+		}
+		if !cordoned {
+			node.Annotations[Annotation] = time.Now().UTC().Format(time.RFC3339)
+		}
+		node.Spec.Taints = append(removeTaint(node.Spec.Taints), corev1.Taint{
+			Key:    TaintKey,
+			Value:  reason,
+			Effect: corev1.TaintEffectNoSchedule,
+		})
+		return c.Patch(ctx, &node, client.MergeFromWithOptions(base, client.MergeFromWithOptimisticLock{}))
+	})
+}
+
+// Uncordon reverts Cordon. Nodes not cordoned by the agent are not touched.
+func Uncordon(ctx context.Context, c client.Client, name string) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		var node corev1.Node
+		if err := c.Get(ctx, client.ObjectKey{Name: name}, &node); err != nil {
+			return err
+		}
+		if _, cordoned := node.Annotations[Annotation]; !cordoned {
+			return nil
+		}
+
+		base := node.DeepCopy()
+		node.Spec.Unschedulable = false
+		delete(node.Annotations, Annotation)
+		node.Spec.Taints = removeTaint(node.Spec.Taints)
+		return c.Patch(ctx, &node, client.MergeFromWithOptions(base, client.MergeFromWithOptimisticLock{}))
+	})
+}
+
+// CordonedSince returns when the agent cordoned the node, false if it
+// didn't.
+func CordonedSince(node *corev1.Node) (time.Time, bool) {
+	value, ok := node.Annotations[Annotation]
+	if !ok {
+		return time.Time{}, false
+	}
+	since, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		// Uncordon nodes with a broken annotation rather than never.
+		return time.Time{}, true
+	}
+	return since, true
+}
+
+// Remove the taint of the agent from the taints.
+func removeTaint(taints []corev1.Taint) []corev1.Taint {
+	var kept []corev1.Taint
+	for _, taint := range taints {
+		if taint.Key != TaintKey {
+			kept = append(kept, taint)
+		}
+	}
+	return kept
+}
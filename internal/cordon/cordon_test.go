@@ -0,0 +1,103 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cordon
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestCordonAndUncordon(t *testing.T) {
+	ctx := context.Background()
+	foreign := corev1.Taint{Key: "example.com/other", Effect: corev1.TaintEffectNoExecute}
+	c := fake.NewClientBuilder().WithObjects(&corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node"},
+		Spec:       corev1.NodeSpec{Taints: []corev1.Taint{foreign}},
+	}).Build()
+
+	if err := Cordon(ctx, c, "node", ReasonEvacuation); err != nil {
+		t.Fatalf("Failed to cordon node: %v", err)
+	}
+	var node corev1.Node
+	if err := c.Get(ctx, client.ObjectKey{Name: "node"}, &node); err != nil {
+		t.Fatal(err)
+	}
+	if !node.Spec.Unschedulable {
+		t.Error("Expected node to be unschedulable")
+	}
+	if len(node.Spec.Taints) != 2 || node.Spec.Taints[1].Value != ReasonEvacuation {
+		t.Errorf("Expected the evacuation taint in addition to the other one, got %v", node.Spec.Taints)
+	}
+	if _, ok := CordonedSince(&node); !ok {
+		t.Error("Expected node to be cordoned by the agent")
+	}
+
+	// Cordoning again replaces the reason instead of adding a taint.
+	if err := Cordon(ctx, c, "node", ReasonOSUpdate); err != nil {
+		t.Fatalf("Failed to cordon node: %v", err)
+	}
+	if err := c.Get(ctx, client.ObjectKey{Name: "node"}, &node); err != nil {
+		t.Fatal(err)
+	}
+	if len(node.Spec.Taints) != 2 || node.Spec.Taints[1].Value != ReasonOSUpdate {
+		t.Errorf("Expected the os-update taint, got %v", node.Spec.Taints)
+	}
+
+	if err := Uncordon(ctx, c, "node"); err != nil {
+		t.Fatalf("Failed to uncordon node: %v", err)
+	}
+	if err := c.Get(ctx, client.ObjectKey{Name: "node"}, &node); err != nil {
+		t.Fatal(err)
+	}
+	if node.Spec.Unschedulable {
+		t.Error("Expected node to be schedulable")
+	}
+	if len(node.Spec.Taints) != 1 || node.Spec.Taints[0].Key != foreign.Key {
+		t.Errorf("Expected only the other taint to be left, got %v", node.Spec.Taints)
+	}
+	if _, ok := CordonedSince(&node); ok {
+		t.Error("Expected node not to be cordoned by the agent")
+	}
+}
+
+func TestCordonLeavesNodesCordonedByAdministrator(t *testing.T) {
+	ctx := context.Background()
+	c := fake.NewClientBuilder().WithObjects(&corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node"},
+		Spec:       corev1.NodeSpec{Unschedulable: true},
+	}).Build()
+
+	if err := Cordon(ctx, c, "node", ReasonEvacuation); err != nil {
+		t.Fatalf("Failed to cordon node: %v", err)
+	}
+	if err := Uncordon(ctx, c, "node"); err != nil {
+		t.Fatalf("Failed to uncordon node: %v", err)
+	}
+	var node corev1.Node
+	if err := c.Get(ctx, client.ObjectKey{Name: "node"}, &node); err != nil {
+		t.Fatal(err)
+	}
+	if !node.Spec.Unschedulable || len(node.Spec.Taints) != 0 {
+		t.Errorf("Expected node to be left alone, got %+v", node.Spec)
+	}
+}
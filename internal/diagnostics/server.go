@@ -0,0 +1,172 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package diagnostics serves pprof profiles, goroutine dumps and the
+// internal state of the agent, to diagnose e.g. stuck migrations.
+package diagnostics
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	runtimepprof "runtime/pprof"
+	"strings"
+	"time"
+
+	logger "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/config"
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/libvirt"
+)
+
+// Server serves the diagnostics:
+//
+//	GET /debug/pprof/                   pprof profiles
+//	GET /debug/goroutines               stacks of all goroutines
+//	GET /debug/goroutines?loop={loop}   stacks of a libvirt loop, e.g. migration-watch or event-loop
+//	GET /debug/state                    internal state of the libvirt connections
+//	GET /debug/config                   configuration of the agent without secrets
+//
+// The server is not authenticated and the profiles expose memory, so it
+// should only listen on localhost.
+type Server struct {
+	// Address the server listens on, e.g. "127.0.0.1:8083".
+	BindAddress string
+	// Libvirt connections whose state is dumped, nil if not connected.
+	State libvirt.StateDumper
+	// Configuration of the agent, nil if not dumped.
+	Config *config.Config
+}
+
+// CheckBindAddress returns an error if the address doesn't listen on
+// localhost only, e.g. :8083.
+func CheckBindAddress(address string) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if host == "localhost" {
+		return nil
+	}
+	if ip := net.ParseIP(host); ip == nil || !ip.IsLoopback() {
+		return fmt.Errorf("diagnostics have to listen on localhost, not %q", host)
+	}
+	return nil
+}
+
+// Handler returns the handler of the diagnostics.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /debug/pprof/", pprof.Index)
+	mux.HandleFunc("GET /debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("GET /debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("GET /debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("GET /debug/pprof/trace", pprof.Trace)
+	mux.HandleFunc("GET /debug/goroutines", s.goroutines)
+	mux.HandleFunc("GET /debug/state", s.state)
+	mux.HandleFunc("GET /debug/config", s.config)
+	return mux
+}
+
+// Start the server and block until the context is cancelled.
+func (s *Server) Start(ctx context.Context) error {
+	log := logger.FromContext(ctx).WithName("diagnostics")
+	srv := &http.Server{
+		Addr:              s.BindAddress,
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+		BaseContext:       func(_ net.Listener) context.Context { return ctx },
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			log.Error(err, "failed to shut down diagnostics")
+		}
+	}()
+
+	log.Info("serving diagnostics", "address", s.BindAddress)
+	if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+func (s *Server) goroutines(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	loop := r.URL.Query().Get("loop")
+	if loop == "" {
+		_ = runtimepprof.Lookup("goroutine").WriteTo(w, 2)
+		return
+	}
+	// Only the grouped dump carries the labels of the goroutines.
+	var buf bytes.Buffer
+	if err := runtimepprof.Lookup("goroutine").WriteTo(&buf, 1); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	_, _ = fmt.Fprint(w, FilterLoop(buf.String(), loop))
+}
+
+// FilterLoop returns the groups of a goroutine dump with debug=1 whose
+// goroutines run in the given libvirt loop.
+func FilterLoop(dump, loop string) string {
+	label := fmt.Sprintf("%q:%q", libvirt.LoopLabel, loop)
+	var groups []string
+	for group := range strings.SplitSeq(dump, "\n\n") {
+		for line := range strings.SplitSeq(group, "\n") {
+			if strings.HasPrefix(line, "# labels: ") && strings.Contains(line, label) {
+				groups = append(groups, group)
+				break
+			}
+		}
+	}
+	if len(groups) == 0 {
+		return ""
+	}
+	return strings.Join(groups, "\n\n") + "\n"
+}
+
+func (s *Server) state(w http.ResponseWriter, _ *http.Request) {
+	states := []libvirt.State{}
+	if s.State != nil {
+		states = s.State.DumpState()
+	}
+	writeJSON(w, map[string]any{"libvirt": states})
+}
+
+func (s *Server) config(w http.ResponseWriter, _ *http.Request) {
+	if s.Config == nil {
+		http.Error(w, "no configuration", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, s.Config.Redacted())
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	_ = encoder.Encode(v)
+}
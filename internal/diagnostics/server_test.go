@@ -0,0 +1,159 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package diagnostics
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"runtime/pprof"
+	"strings"
+	"testing"
+
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/config"
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/libvirt"
+)
+
+type stateFunc func() []libvirt.State
+
+func (f stateFunc) DumpState() []libvirt.State {
+	return f()
+}
+
+func serve(handler http.Handler, path string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, http.NoBody))
+	return rec
+}
+
+// Blocks in a labeled goroutine until the test ends.
+func parkedLoop(done <-chan struct{}) {
+	<-done
+}
+
+func TestGoroutinesOfLoop(t *testing.T) {
+	done := make(chan struct{})
+	defer close(done)
+	started := make(chan struct{})
+	go pprof.Do(context.Background(), pprof.Labels(libvirt.LoopLabel, "migration-watch"), func(context.Context) {
+		close(started)
+		parkedLoop(done)
+	})
+	<-started
+
+	handler := (&Server{}).Handler()
+	rec := serve(handler, "/debug/goroutines?loop=migration-watch")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), "parkedLoop") {
+		t.Errorf("Expected the goroutine of the loop, got %q", rec.Body.String())
+	}
+	if strings.Contains(rec.Body.String(), "testing.tRunner") {
+		t.Errorf("Expected only the goroutines of the loop, got %q", rec.Body.String())
+	}
+	if rec := serve(handler, "/debug/goroutines?loop=event-loop"); strings.Contains(rec.Body.String(), "parkedLoop") {
+		t.Errorf("Expected no goroutines of another loop, got %q", rec.Body.String())
+	}
+	if rec := serve(handler, "/debug/goroutines"); !strings.Contains(rec.Body.String(), "testing.tRunner") {
+		t.Errorf("Expected all goroutines, got %q", rec.Body.String())
+	}
+}
+
+func TestFilterLoop(t *testing.T) {
+	dump := "goroutine profile: total 3\n" +
+		"1 @ 0x1\n# labels: {\"libvirt-loop\":\"event-loop\"}\n#\t0x1\tmain.events\n\n" +
+		"2 @ 0x2\n#\t0x2\tmain.other\n"
+	if got := FilterLoop(dump, "event-loop"); !strings.Contains(got, "main.events") || strings.Contains(got, "main.other") {
+		t.Errorf("Unexpected filtered dump %q", got)
+	}
+	if got := FilterLoop(dump, "block-stats"); got != "" {
+		t.Errorf("Expected empty dump, got %q", got)
+	}
+}
+
+func TestState(t *testing.T) {
+	handler := (&Server{State: stateFunc(func() []libvirt.State {
+		return []libvirt.State{{URI: "qemu:///system", MigrationWatches: []string{"instance-1"}}}
+	})}).Handler()
+	rec := serve(handler, "/debug/state")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rec.Code)
+	}
+	var body struct {
+		Libvirt []libvirt.State `json:"libvirt"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("Expected json, got %v", err)
+	}
+	if len(body.Libvirt) != 1 || body.Libvirt[0].MigrationWatches[0] != "instance-1" {
+		t.Errorf("Unexpected state %+v", body.Libvirt)
+	}
+
+	rec = serve((&Server{}).Handler(), "/debug/state")
+	if strings.TrimSpace(rec.Body.String()) != "{\n  \"libvirt\": []\n}" {
+		t.Errorf("Expected empty state, got %q", rec.Body.String())
+	}
+}
+
+func TestConfig(t *testing.T) {
+	cfg := config.Default()
+	cfg.Vault = config.Vault{Address: "https://vault:8200", Token: "secret"}
+	rec := serve((&Server{Config: cfg}).Handler(), "/debug/config")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rec.Code)
+	}
+	if strings.Contains(rec.Body.String(), "secret") {
+		t.Errorf("Expected the vault token to be redacted, got %q", rec.Body.String())
+	}
+	var dumped config.Config
+	if err := json.Unmarshal(rec.Body.Bytes(), &dumped); err != nil {
+		t.Fatalf("Expected json, got %v", err)
+	}
+	if dumped.Vault.Address != "https://vault:8200" || dumped.Libvirt.Socket != cfg.Libvirt.Socket {
+		t.Errorf("Unexpected config %+v", dumped)
+	}
+
+	if rec := serve((&Server{}).Handler(), "/debug/config"); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 without config, got %d", rec.Code)
+	}
+}
+
+func TestCheckBindAddress(t *testing.T) {
+	for address, ok := range map[string]bool{
+		"127.0.0.1:8083": true,
+		"[::1]:8083":     true,
+		"localhost:8083": true,
+		":8083":          false,
+		"0.0.0.0:8083":   false,
+		"10.0.0.1:8083":  false,
+		"127.0.0.1":      false,
+	} {
+		if err := CheckBindAddress(address); (err == nil) != ok {
+			t.Errorf("CheckBindAddress(%q) = %v, expected ok %v", address, err, ok)
+		}
+	}
+}
+
+func TestPprof(t *testing.T) {
+	rec := serve((&Server{}).Handler(), "/debug/pprof/")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "goroutine") {
+		t.Errorf("Expected the pprof index, got %d %q", rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,231 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package emulator
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	v1 "github.com/cobaltcore-dev/openstack-hypervisor-operator/api/v1"
+	golibvirt "github.com/digitalocean/go-libvirt"
+	logger "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/libvirt"
+)
+
+// The number of domains the event source cycles through.
+const emulatedDomains = 3
+
+// The phases an emulated domain goes through, one per tick.
+const (
+	phaseUndefined = iota
+	phaseRunning
+	phaseMigrated
+	phaseStopped
+	phaseCount
+)
+
+// emulatedDomain is a fake domain and the phase the event source last
+// reported for it.
+type emulatedDomain struct {
+	dom   golibvirt.Domain
+	phase int
+}
+
+type emulatedEvent struct {
+	id    golibvirt.DomainEventID
+	event any
+}
+
+// eventSource synthesizes the lifecycle and migration events of a few fake
+// domains on a timer, and delivers them to the handlers registered with
+// WatchDomainChanges, like the event loop of the libvirt client does.
+type eventSource struct {
+	interval time.Duration
+
+	handlersLock sync.Mutex
+	handlers     map[golibvirt.DomainEventID]map[string]func(context.Context, any)
+
+	domainsLock sync.Mutex
+	domains     []*emulatedDomain
+	next        int
+
+	// Connect is called concurrently by the controllers.
+	runLock sync.Mutex
+	cancel  context.CancelFunc
+	done    chan struct{}
+}
+
+func newEventSource(interval time.Duration) *eventSource {
+	s := &eventSource{
+		interval: interval,
+		handlers: make(map[golibvirt.DomainEventID]map[string]func(context.Context, any)),
+	}
+	for i := range emulatedDomains {
+		dom := golibvirt.Domain{Name: fmt.Sprintf("instance-%08x", i+1)}
+		// Stable uuids, so that the instances keep their identity across
+		// restarts of the agent.
+		copy(dom.UUID[:], fmt.Sprintf("emulator-dom-%04d", i+1))
+		s.domains = append(s.domains, &emulatedDomain{dom: dom})
+	}
+	return s
+}
+
+func (s *eventSource) watch(eventId golibvirt.DomainEventID, handlerId string, handler func(context.Context, any)) {
+	s.handlersLock.Lock()
+	defer s.handlersLock.Unlock()
+	if _, exists := s.handlers[eventId]; !exists {
+		s.handlers[eventId] = make(map[string]func(context.Context, any))
+	}
+	s.handlers[eventId][handlerId] = handler
+}
+
+// start emits the events of the next step on every tick until stop is called.
+// Starting a running event source is a no-op.
+func (s *eventSource) start(ctx context.Context) {
+	s.runLock.Lock()
+	defer s.runLock.Unlock()
+	if s.interval <= 0 || s.cancel != nil {
+		return
+	}
+	ctx, s.cancel = context.WithCancel(ctx)
+	s.done = make(chan struct{})
+	go func() {
+		defer close(s.done)
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				s.tick(ctx)
+			}
+		}
+	}()
+}
+
+func (s *eventSource) stop() {
+	s.runLock.Lock()
+	defer s.runLock.Unlock()
+	if s.cancel == nil {
+		return
+	}
+	s.cancel()
+	<-s.done
+	s.cancel = nil
+}
+
+// tick advances one domain to its next phase: it is defined and started,
+// live migrated, stopped and finally undefined again. The domains are
+// stepped in turn, so the host always has instances in different phases.
+func (s *eventSource) tick(ctx context.Context) {
+	s.domainsLock.Lock()
+	d := s.domains[s.next]
+	s.next = (s.next + 1) % len(s.domains)
+	d.phase = (d.phase + 1) % phaseCount
+	events := phaseEvents(d.dom, d.phase)
+	s.domainsLock.Unlock()
+
+	for _, e := range events {
+		s.dispatch(ctx, e.id, e.event)
+	}
+}
+
+// phaseEvents returns the events libvirt emits when a domain enters the phase.
+func phaseEvents(dom golibvirt.Domain, phase int) []emulatedEvent {
+	lifecycle := func(event golibvirt.DomainEventType, detail int32) emulatedEvent {
+		return emulatedEvent{golibvirt.DomainEventIDLifecycle, &golibvirt.DomainEventCallbackLifecycleMsg{
+			Msg: golibvirt.DomainEventLifecycleMsg{Dom: dom, Event: int32(event), Detail: detail},
+		}}
+	}
+	switch phase {
+	case phaseRunning:
+		return []emulatedEvent{
+			lifecycle(golibvirt.DomainEventDefined, int32(golibvirt.DomainEventDefinedAdded)),
+			lifecycle(golibvirt.DomainEventStarted, int32(golibvirt.DomainEventStartedBooted)),
+		}
+	case phaseMigrated:
+		return []emulatedEvent{
+			{golibvirt.DomainEventIDMigrationIteration,
+				&golibvirt.DomainEventCallbackMigrationIterationMsg{Dom: dom, Iteration: 1}},
+			{golibvirt.DomainEventIDMigrationIteration,
+				&golibvirt.DomainEventCallbackMigrationIterationMsg{Dom: dom, Iteration: 2}},
+			{golibvirt.DomainEventIDJobCompleted, &golibvirt.DomainEventCallbackJobCompletedMsg{Dom: dom}},
+			lifecycle(golibvirt.DomainEventResumed, int32(golibvirt.DomainEventResumedMigrated)),
+		}
+	case phaseStopped:
+		return []emulatedEvent{
+			lifecycle(golibvirt.DomainEventStopped, int32(golibvirt.DomainEventStoppedShutdown)),
+		}
+	default:
+		return []emulatedEvent{
+			lifecycle(golibvirt.DomainEventUndefined, int32(golibvirt.DomainEventUndefinedRemoved)),
+		}
+	}
+}
+
+func (s *eventSource) dispatch(ctx context.Context, eventId golibvirt.DomainEventID, event any) {
+	s.handlersLock.Lock()
+	handlers := make([]func(context.Context, any), 0, len(s.handlers[eventId]))
+	for _, handler := range s.handlers[eventId] {
+		handlers = append(handlers, handler)
+	}
+	s.handlersLock.Unlock()
+	for _, handler := range handlers {
+		handler(ctx, event)
+	}
+}
+
+// instances returns the currently defined domains as hypervisor instances.
+func (s *eventSource) instances() []v1.Instance {
+	s.domainsLock.Lock()
+	defer s.domainsLock.Unlock()
+	instances := make([]v1.Instance, 0, len(s.domains))
+	for _, d := range s.domains {
+		if d.phase == phaseUndefined {
+			continue
+		}
+		instances = append(instances, v1.Instance{
+			ID:     libvirt.GetOpenstackUUID(d.dom),
+			Name:   d.dom.Name,
+			Active: d.phase != phaseStopped,
+		})
+	}
+	return instances
+}
+
+// logEvents logs the synthesized events, so that a demo shows what the
+// controllers react to.
+func (s *eventSource) logEvents(ctx context.Context) {
+	log := logger.FromContext(ctx)
+	s.watch(golibvirt.DomainEventIDLifecycle, "emulator-log", func(_ context.Context, event any) {
+		e := event.(*golibvirt.DomainEventCallbackLifecycleMsg)
+		log.Info("emulated lifecycle event", "domain", e.Msg.Dom.Name, "event", e.Msg.Event, "detail", e.Msg.Detail)
+	})
+	s.watch(golibvirt.DomainEventIDMigrationIteration, "emulator-log", func(_ context.Context, event any) {
+		e := event.(*golibvirt.DomainEventCallbackMigrationIterationMsg)
+		log.Info("emulated migration iteration", "domain", e.Dom.Name, "iteration", e.Iteration)
+	})
+	s.watch(golibvirt.DomainEventIDJobCompleted, "emulator-log", func(_ context.Context, event any) {
+		e := event.(*golibvirt.DomainEventCallbackJobCompletedMsg)
+		log.Info("emulated job completed", "domain", e.Dom.Name)
+	})
+}
@@ -19,27 +19,44 @@ package emulator
 
 import (
 	"context"
+	"time"
 
 	v1 "github.com/cobaltcore-dev/openstack-hypervisor-operator/api/v1"
+	golibvirt "github.com/digitalocean/go-libvirt"
 	logger "sigs.k8s.io/controller-runtime/pkg/log"
 
 	"github.com/cobaltcore-dev/kvm-node-agent/internal/libvirt"
 )
 
-func NewLibVirtEmulator(ctx context.Context) *libvirt.InterfaceMock {
+// NewLibVirtEmulator returns a libvirt interface that runs off-host. While
+// connected, it synthesizes the lifecycle and migration events of a few fake
+// domains every eventInterval, and reports them as the instances of the
+// hypervisor. A zero eventInterval disables the events.
+func NewLibVirtEmulator(ctx context.Context, eventInterval time.Duration) *libvirt.InterfaceMock {
 	log := logger.FromContext(ctx, "controller", "libvirt-emulator")
+	events := newEventSource(eventInterval)
+	events.logEvents(logger.IntoContext(ctx, log))
 	mockedInterface := &libvirt.InterfaceMock{
 		CloseFunc: func() error {
 			log.Info("CloseFunc called")
+			events.stop()
 			return nil
 		},
 		ConnectFunc: func() error {
 			log.Info("Connect Func called")
+			events.start(ctx)
 			return nil
 		},
-		ProcessFunc: func(hv v1.Hypervisor) (v1.Hypervisor, error) {
+		WatchDomainChangesFunc: func(eventId golibvirt.DomainEventID, handlerId string, handler func(context.Context, any)) {
+			log.Info("WatchDomainChangesFunc called", "eventId", eventId, "handlerId", handlerId)
+			events.watch(eventId, handlerId, handler)
+		},
+		ProcessFunc: func(_ context.Context, hv v1.Hypervisor) (v1.Hypervisor, error) {
 			log.Info("Process Func called")
-			return hv, nil
+			newHv := *hv.DeepCopy()
+			newHv.Status.Instances = events.instances()
+			newHv.Status.NumInstances = len(newHv.Status.Instances)
+			return newHv, nil
 		},
 	}
 	return mockedInterface
@@ -53,6 +53,10 @@ func NewSystemdEmulator(ctx context.Context) *systemd.InterfaceMock {
 			log.Info("GetUnitByNameFunc called")
 			return 0, nil
 		},
+		RestartUnitFunc: func(ctx context.Context, unit string) (string, error) {
+			log.Info("RestartUnitFunc called with unit = " + unit)
+			return "done", nil
+		},
 		EnableShutdownInhibitFunc: func(ctx context.Context, cb func(ctx context.Context) error) error {
 			log.Info("GetUnitByNameFunc called")
 			return nil
@@ -0,0 +1,158 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package entropy reads the entropy sources of the host, which back the
+// random number generator devices of the domains.
+package entropy
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Sources are the entropy sources of the host.
+type Sources struct {
+	// Whether the hardware random number generator device is present.
+	HWRNG bool
+	// Name of the hardware random number generator in use, if any.
+	HWRNGName string
+	// Whether the cpu provides the RDRAND and RDSEED instructions.
+	RDRAND bool
+	RDSEED bool
+	// Entropy available in the kernel pool in bits.
+	EntropyAvailable int
+}
+
+// Check if the host has any hardware entropy source.
+func (s Sources) HasHardwareSource() bool {
+	return s.HWRNG || s.RDRAND || s.RDSEED
+}
+
+// Summary of the sources for humans, e.g. for condition messages.
+func (s Sources) String() string {
+	var sources []string
+	if s.HWRNG {
+		name := s.HWRNGName
+		if name == "" {
+			name = "unknown"
+		}
+		sources = append(sources, "hwrng="+name)
+	}
+	if s.RDRAND {
+		sources = append(sources, "rdrand")
+	}
+	if s.RDSEED {
+		sources = append(sources, "rdseed")
+	}
+	if len(sources) == 0 {
+		sources = append(sources, "no hardware source")
+	}
+	return fmt.Sprintf("%s, %d bits available", strings.Join(sources, ", "), s.EntropyAvailable)
+}
+
+// Interface provides the entropy sources of the host.
+type Interface interface {
+	// ReadSources reads the entropy sources of the host.
+	ReadSources() (*Sources, error)
+}
+
+// SystemReader reads the entropy sources from the system files.
+type SystemReader struct {
+	hwrngPath        string
+	rngCurrentPath   string
+	cpuinfoPath      string
+	entropyAvailPath string
+}
+
+// NewSystemReader creates a new SystemReader with the default system paths.
+func NewSystemReader() *SystemReader {
+	return &SystemReader{
+		hwrngPath:        "/dev/hwrng",
+		rngCurrentPath:   "/sys/class/misc/hw_random/rng_current",
+		cpuinfoPath:      "/proc/cpuinfo",
+		entropyAvailPath: "/proc/sys/kernel/random/entropy_avail",
+	}
+}
+
+// ReadSources reads the entropy sources of the host.
+func (r *SystemReader) ReadSources() (*Sources, error) {
+	var s Sources
+
+	if _, err := os.Stat(r.hwrngPath); err == nil {
+		s.HWRNG = true
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	if s.HWRNG {
+		// The device may be present without a driver providing entropy.
+		current, err := os.ReadFile(r.rngCurrentPath)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+		s.HWRNGName = strings.TrimSpace(string(current))
+		if s.HWRNGName == "none" {
+			s.HWRNG, s.HWRNGName = false, ""
+		}
+	}
+
+	var err error
+	if s.RDRAND, s.RDSEED, err = readRandomInstructions(r.cpuinfoPath); err != nil {
+		return nil, err
+	}
+
+	avail, err := os.ReadFile(r.entropyAvailPath)
+	if err != nil {
+		return nil, err
+	}
+	if s.EntropyAvailable, err = strconv.Atoi(strings.TrimSpace(string(avail))); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", r.entropyAvailPath, err)
+	}
+	return &s, nil
+}
+
+// Check the cpu flags of the first processor in cpuinfo for the random
+// number instructions.
+func readRandomInstructions(path string) (rdrand, rdseed bool, err error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return false, false, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), ":")
+		if !ok || strings.TrimSpace(key) != "flags" {
+			continue
+		}
+		for flag := range strings.FieldsSeq(value) {
+			switch flag {
+			case "rdrand":
+				rdrand = true
+			case "rdseed":
+				rdseed = true
+			}
+		}
+		return rdrand, rdseed, nil
+	}
+	return false, false, scanner.Err()
+}
@@ -0,0 +1,91 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package entropy
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSystemReaderReadSources(t *testing.T) {
+	tmpDir := t.TempDir()
+	cpuinfo := "processor\t: 0\nflags\t\t: fpu rdrand vmx rdseed\n\nprocessor\t: 1\nflags\t\t: fpu\n"
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "cpuinfo"), []byte(cpuinfo), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "hwrng"), nil, 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "rng_current"), []byte("tpm-rng-0\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "entropy_avail"), []byte("256\n"), 0644))
+
+	reader := &SystemReader{
+		hwrngPath:        filepath.Join(tmpDir, "hwrng"),
+		rngCurrentPath:   filepath.Join(tmpDir, "rng_current"),
+		cpuinfoPath:      filepath.Join(tmpDir, "cpuinfo"),
+		entropyAvailPath: filepath.Join(tmpDir, "entropy_avail"),
+	}
+	sources, err := reader.ReadSources()
+	require.NoError(t, err)
+	assert.Equal(t, Sources{
+		HWRNG:            true,
+		HWRNGName:        "tpm-rng-0",
+		RDRAND:           true,
+		RDSEED:           true,
+		EntropyAvailable: 256,
+	}, *sources)
+	assert.Equal(t, "hwrng=tpm-rng-0, rdrand, rdseed, 256 bits available", sources.String())
+
+	// A device without a driver providing entropy doesn't count.
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "rng_current"), []byte("none\n"), 0644))
+	sources, err = reader.ReadSources()
+	require.NoError(t, err)
+	assert.False(t, sources.HWRNG)
+	assert.Empty(t, sources.HWRNGName)
+}
+
+func TestSystemReaderReadSourcesWithoutHardware(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "cpuinfo"), []byte("flags\t\t: fpu vmx\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "entropy_avail"), []byte("3\n"), 0644))
+
+	reader := &SystemReader{
+		hwrngPath:        filepath.Join(tmpDir, "hwrng"),
+		rngCurrentPath:   filepath.Join(tmpDir, "rng_current"),
+		cpuinfoPath:      filepath.Join(tmpDir, "cpuinfo"),
+		entropyAvailPath: filepath.Join(tmpDir, "entropy_avail"),
+	}
+	sources, err := reader.ReadSources()
+	require.NoError(t, err)
+	assert.False(t, sources.HasHardwareSource())
+	assert.Equal(t, "no hardware source, 3 bits available", sources.String())
+}
+
+func TestSystemReaderReadSourcesMissingEntropyPool(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "cpuinfo"), []byte("flags\t\t: fpu\n"), 0644))
+
+	reader := &SystemReader{
+		hwrngPath:        filepath.Join(tmpDir, "hwrng"),
+		rngCurrentPath:   filepath.Join(tmpDir, "rng_current"),
+		cpuinfoPath:      filepath.Join(tmpDir, "cpuinfo"),
+		entropyAvailPath: filepath.Join(tmpDir, "entropy_avail"),
+	}
+	_, err := reader.ReadSources()
+	assert.Error(t, err)
+}
@@ -0,0 +1,117 @@
+/*
+SPDX-FileCopyrightText: Copyright 2024 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, LibVirtVersion 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package evacuation
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	kvmv1 "github.com/cobaltcore-dev/openstack-hypervisor-operator/api/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Capacity compares the resources allocated by the instances of a host with
+// the free capacity of the other hypervisors, which would have to absorb
+// them in an evacuation.
+type Capacity struct {
+	// Resources allocated by the instances of the host.
+	Required map[kvmv1.ResourceName]resource.Quantity
+	// Free resources summed up over the other hypervisors.
+	Available map[kvmv1.ResourceName]resource.Quantity
+	// Number of other hypervisors taken into account.
+	Hypervisors int
+}
+
+// Check if the other hypervisors have room for the instances of the host.
+// The free capacity is summed up over all hypervisors, so an instance may
+// still not fit on any single one of them. A failed check is definite,
+// a passed one is not.
+func (c Capacity) Sufficient() bool {
+	for name, required := range c.Required {
+		available := c.Available[name]
+		if available.Cmp(required) < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Summary of the capacity for humans, e.g. for condition messages.
+func (c Capacity) String() string {
+	return fmt.Sprintf("requires %s; %d hypervisors have %s free",
+		formatResources(c.Required), c.Hypervisors, formatResources(c.Available))
+}
+
+// Format the resources sorted by name, e.g. "cpu=8, memory=16Gi".
+func formatResources(resources map[kvmv1.ResourceName]resource.Quantity) string {
+	if len(resources) == 0 {
+		return "nothing"
+	}
+	var parts []string
+	for _, name := range slices.Sorted(maps.Keys(resources)) {
+		quantity := resources[name]
+		parts = append(parts, fmt.Sprintf("%s=%s", name, quantity.String()))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// CheckCapacity compares the allocation of the hypervisor with the free
+// capacity of the other hypervisors of the cluster.
+func CheckCapacity(ctx context.Context, c client.Reader, hypervisor *kvmv1.Hypervisor) (Capacity, error) {
+	var hypervisors kvmv1.HypervisorList
+	if err := c.List(ctx, &hypervisors); err != nil {
+		return Capacity{}, fmt.Errorf("could not list hypervisors: %w", err)
+	}
+	return capacity(hypervisor, hypervisors.Items), nil
+}
+
+// Sum up the free capacity of the hypervisors other than the host which can
+// take instances, i.e. which are not in maintenance.
+func capacity(host *kvmv1.Hypervisor, hypervisors []kvmv1.Hypervisor) Capacity {
+	c := Capacity{
+		Required:  maps.Clone(host.Status.Allocation),
+		Available: make(map[kvmv1.ResourceName]resource.Quantity),
+	}
+	for _, hypervisor := range hypervisors {
+		if hypervisor.Name == host.Name || hypervisor.Spec.Maintenance != kvmv1.MaintenanceUnset {
+			continue
+		}
+		c.Hypervisors++
+
+		capacity := hypervisor.Status.EffectiveCapacity
+		if len(capacity) == 0 {
+			capacity = hypervisor.Status.Capacity
+		}
+		for name, total := range capacity {
+			free := total.DeepCopy()
+			if allocated, ok := hypervisor.Status.Allocation[name]; ok {
+				free.Sub(allocated)
+			}
+			if free.Sign() <= 0 {
+				continue
+			}
+			available := c.Available[name]
+			available.Add(free)
+			c.Available[name] = available
+		}
+	}
+	return c
+}
@@ -0,0 +1,81 @@
+/*
+SPDX-FileCopyrightText: Copyright 2024 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, LibVirtVersion 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package evacuation
+
+import (
+	kvmv1 "github.com/cobaltcore-dev/openstack-hypervisor-operator/api/v1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("Evacuation Capacity", func() {
+	newHypervisor := func(name string, capacity, allocation string) kvmv1.Hypervisor {
+		hypervisor := kvmv1.Hypervisor{ObjectMeta: metav1.ObjectMeta{Name: name}}
+		hypervisor.Status.Capacity = map[kvmv1.ResourceName]resource.Quantity{
+			kvmv1.ResourceMemory: resource.MustParse(capacity),
+		}
+		hypervisor.Status.Allocation = map[kvmv1.ResourceName]resource.Quantity{
+			kvmv1.ResourceMemory: resource.MustParse(allocation),
+		}
+		return hypervisor
+	}
+
+	It("should sum up the free capacity of the other hypervisors", func() {
+		host := newHypervisor("host", "512Gi", "96Gi")
+		maintenance := newHypervisor("maintenance", "512Gi", "0")
+		maintenance.Spec.Maintenance = kvmv1.MaintenanceManual
+		hypervisors := []kvmv1.Hypervisor{
+			host,
+			newHypervisor("a", "512Gi", "448Gi"),
+			newHypervisor("b", "512Gi", "480Gi"),
+			newHypervisor("full", "512Gi", "576Gi"),
+			maintenance,
+		}
+
+		c := capacity(&host, hypervisors)
+		Expect(c.Hypervisors).To(Equal(3))
+		available := c.Available[kvmv1.ResourceMemory]
+		Expect(available.String()).To(Equal("96Gi"))
+		Expect(c.Sufficient()).To(BeTrue())
+		Expect(c.String()).To(Equal("requires memory=96Gi; 3 hypervisors have memory=96Gi free"))
+
+		host.Status.Allocation[kvmv1.ResourceMemory] = resource.MustParse("128Gi")
+		Expect(capacity(&host, hypervisors).Sufficient()).To(BeFalse())
+	})
+
+	It("should prefer the effective capacity", func() {
+		host := newHypervisor("host", "512Gi", "96Gi")
+		other := newHypervisor("other", "512Gi", "480Gi")
+		other.Status.EffectiveCapacity = map[kvmv1.ResourceName]resource.Quantity{
+			kvmv1.ResourceMemory: resource.MustParse("1Ti"),
+		}
+
+		c := capacity(&host, []kvmv1.Hypervisor{other})
+		available := c.Available[kvmv1.ResourceMemory]
+		Expect(available.String()).To(Equal("544Gi"))
+		Expect(c.Sufficient()).To(BeTrue())
+	})
+
+	It("should not fit anything without other hypervisors", func() {
+		host := newHypervisor("host", "512Gi", "96Gi")
+		c := capacity(&host, nil)
+		Expect(c.Sufficient()).To(BeFalse())
+		Expect(c.String()).To(Equal("requires memory=96Gi; 0 hypervisors have nothing free"))
+	})
+})
@@ -19,21 +19,46 @@ package evacuation
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
 	kvmv1 "github.com/cobaltcore-dev/openstack-hypervisor-operator/api/v1"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	corev1 "k8s.io/api/core/v1"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/events"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	logger "sigs.k8s.io/controller-runtime/pkg/log"
 
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/config"
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/cordon"
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/libvirt"
 	"github.com/cobaltcore-dev/kvm-node-agent/internal/sys"
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/systemd"
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/tracing"
 )
 
 type EvictionController struct {
 	client.Client
+	// Reads the hypervisors of the whole cluster for the capacity check,
+	// the cache of the client only holds the hypervisor of this host.
+	APIReader client.Reader
+	// Recorder of the events of the hypervisor, one per instance migrated
+	// off the host. Nil if no events are emitted.
+	Recorder events.EventRecorder
+	// Shuts down the instances if the evacuation policy of the hypervisor
+	// asks for it. Nil if instances are only live migrated.
+	Domains libvirt.DomainShutdowner
+	// Whether the node is cordoned and tainted during the evacuation, until
+	// the host booted again.
+	CordonNode bool
+	// Runtime configuration with the default evacuation policy, nil to
+	// live migrate unless the annotations ask otherwise.
+	RuntimeConfig *config.RuntimeStore
 }
 
 // EvictCurrentHost callback is allowed to block. It is called when the hypervisor is about to be rebooted.
@@ -41,6 +66,13 @@ type EvictionController struct {
 // It is able to block up to InhibitDelayMaxSec seconds to evict virtual machines.
 // see `systemd-analyze cat-config systemd/logind.conf` for the current setting.
 func (e *EvictionController) EvictCurrentHost(ctx context.Context) error {
+	ctx, span := tracing.Start(ctx, "EvictionController.EvictCurrentHost", attribute.String("hypervisor", sys.Hostname))
+	err := e.evictCurrentHost(ctx)
+	tracing.End(span, err)
+	return err
+}
+
+func (e *EvictionController) evictCurrentHost(ctx context.Context) error {
 	log := logger.FromContext(ctx)
 
 	// Check for running VMs before creating the eviction custom resource
@@ -54,6 +86,43 @@ func (e *EvictionController) EvictCurrentHost(ctx context.Context) error {
 		return nil
 	}
 
+	defaults, err := PolicyFromConfig(e.RuntimeConfig.Get().Evacuation)
+	if err != nil {
+		// Validated when the configuration is loaded.
+		log.Error(err, "invalid evacuation policy of the runtime config")
+		defaults = Policy{Strategy: StrategyLiveMigrate, Parallelism: defaultParallelism}
+	}
+	policy, err := defaults.Override(hypervisor.Annotations)
+	if err != nil {
+		// Evacuate the host nonetheless.
+		log.Error(err, "invalid evacuation policy, live migrating instances")
+		policy = Policy{Strategy: StrategyLiveMigrate, Parallelism: defaultParallelism}
+	}
+	if e.CordonNode {
+		if err := cordon.Cordon(ctx, e.Client, sys.Hostname, cordon.ReasonEvacuation); err != nil {
+			log.Error(err, "unable to cordon node")
+		}
+	}
+	if policy.Strategy == StrategyShutdown {
+		return e.shutdownInstances(ctx, &hypervisor, policy)
+	}
+
+	// Don't wait for an eviction which can't succeed until the shutdown is
+	// no longer inhibited.
+	if capacity, err := CheckCapacity(ctx, e.APIReader, &hypervisor); err != nil {
+		log.Error(err, "unable to check capacity for evacuation, creating eviction anyway")
+	} else if !capacity.Sufficient() {
+		log.Info("Insufficient capacity to evacuate current host", "capacity", capacity.String())
+		if err := e.reportProgress(ctx, &hypervisor, v1.ConditionFalse, "InsufficientCapacity",
+			capacity.String()); err != nil {
+			log.Error(err, "unable to report evacuation progress")
+		}
+		if e.Domains == nil {
+			return fmt.Errorf("insufficient capacity to evacuate current host: %s", capacity)
+		}
+		return e.shutdownInstances(ctx, &hypervisor, policy)
+	}
+
 	u := &unstructured.Unstructured{}
 	u.SetUnstructuredContent(map[string]any{
 		"spec": map[string]any{
@@ -86,12 +155,17 @@ func (e *EvictionController) EvictCurrentHost(ctx context.Context) error {
 
 	log.Info("Eviction custom resource created for current host")
 
+	var t tracker
+	started := time.Now()
 	for {
 		if ctx.Err() != nil {
-			return ctx.Err()
+			return e.abort(ctx, &hypervisor, u)
 		}
 
 		if err := e.Get(ctx, client.ObjectKeyFromObject(u), u); err != nil {
+			if ctx.Err() != nil {
+				return e.abort(ctx, &hypervisor, u)
+			}
 			return err
 		}
 
@@ -100,12 +174,85 @@ func (e *EvictionController) EvictCurrentHost(ctx context.Context) error {
 			return err
 		}
 
-		log.WithValues("node", u.GetName(), "state", state).Info("Eviction progress")
+		remaining, _, err := unstructured.NestedStringSlice(u.Object, "status", "outstandingInstances")
+		if err != nil {
+			return err
+		}
+		migrated, eta := t.update(time.Now(), remaining)
+		for _, instance := range migrated {
+			if e.Recorder != nil {
+				e.Recorder.Eventf(&hypervisor, nil, corev1.EventTypeNormal, "InstanceEvacuated", "Evacuate",
+					"Instance %s migrated off the host", instance)
+			}
+		}
+		migrating, err := e.outgoingMigrations(ctx)
+		if err != nil {
+			// Not critical, the progress is reported without them.
+			log.Error(err, "unable to list outgoing migrations")
+		}
+		progress := Progress{Remaining: remaining, Migrating: migrating, ETA: eta}
+
+		log.WithValues("node", u.GetName(), "state", state, "progress", progress.String()).Info("Eviction progress")
+		trace.SpanFromContext(ctx).AddEvent("EvictionProgress", trace.WithAttributes(
+			attribute.String("state", state),
+			attribute.Int("remaining", len(remaining)),
+			attribute.Int("migrating", len(migrating)),
+		))
 
 		if state == "Succeeded" {
+			if err := e.reportProgress(ctx, &hypervisor, v1.ConditionFalse, "Succeeded",
+				"All instances migrated off the host"); err != nil {
+				log.Error(err, "unable to report evacuation progress")
+			}
 			return nil
 		}
+		if err := e.reportProgress(ctx, &hypervisor, v1.ConditionTrue, "InProgress", progress.String()); err != nil {
+			log.Error(err, "unable to report evacuation progress")
+		}
+
+		if policy.Timeout > 0 && time.Since(started) > policy.Timeout {
+			log.Info("Eviction timed out, shutting down remaining instances", "timeout", policy.Timeout)
+			return e.shutdownInstances(ctx, &hypervisor, policy)
+		}
+		// The shutdown is only delayed for a limited time, leave enough of
+		// it to shut down the remaining instances instead of having them
+		// killed in the middle of a migration.
+		if deadline, ok := ctx.Deadline(); ok && e.Domains != nil && time.Until(deadline) < shutdownGrace+pollInterval {
+			log.Info("Shutdown delay running out, shutting down remaining instances", "deadline", deadline)
+			return e.shutdownInstances(ctx, &hypervisor, policy)
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+// Undo the evacuation if the shutdown was aborted, so that the host takes
+// instances again: the eviction is deleted and the evacuation condition is
+// reset. Returns the error of the context otherwise.
+func (e *EvictionController) abort(ctx context.Context, hypervisor *kvmv1.Hypervisor,
+	eviction *unstructured.Unstructured) error {
+	if !errors.Is(context.Cause(ctx), systemd.ErrShutdownAborted) {
+		return ctx.Err()
+	}
+	log := logger.FromContext(ctx)
+	log.Info("Shutdown aborted, cancelling eviction of current host")
 
-		time.Sleep(10 * time.Second)
+	// The context is cancelled, but the cleanup must still happen.
+	ctx = context.WithoutCancel(ctx)
+	if err := e.Delete(ctx, eviction); client.IgnoreNotFound(err) != nil {
+		return fmt.Errorf("could not delete eviction: %w", err)
+	}
+	if err := e.reportProgress(ctx, hypervisor, v1.ConditionFalse, "Aborted",
+		"Shutdown was aborted, eviction cancelled"); err != nil {
+		log.Error(err, "unable to report evacuation progress")
+	}
+	if e.CordonNode {
+		if err := cordon.Uncordon(ctx, e.Client, sys.Hostname); err != nil {
+			return fmt.Errorf("could not uncordon node: %w", err)
+		}
 	}
+	return nil
 }
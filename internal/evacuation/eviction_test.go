@@ -69,7 +69,7 @@ var _ = Describe("Evacuation Callback", func() {
 			sys.Hostname = resourceName
 			sys.Namespace = resourceNamespace
 
-			controller := EvictionController{k8sClient}
+			controller := EvictionController{Client: k8sClient, APIReader: k8sClient}
 			err = controller.EvictCurrentHost(context.Background())
 			Expect(err).NotTo(HaveOccurred())
 
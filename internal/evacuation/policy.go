@@ -0,0 +1,216 @@
+/*
+SPDX-FileCopyrightText: Copyright 2024 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, LibVirtVersion 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package evacuation
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	kvmv1 "github.com/cobaltcore-dev/openstack-hypervisor-operator/api/v1"
+	"go.opentelemetry.io/otel/attribute"
+	corev1 "k8s.io/api/core/v1"
+	logger "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/config"
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/libvirt"
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/tracing"
+)
+
+// Annotations of the hypervisor configuring the evacuation on shutdown.
+const (
+	// How the instances are evacuated, either live-migrate or shutdown.
+	// Defaults to live-migrate, which waits for the eviction by the
+	// openstack-hypervisor-operator.
+	StrategyAnnotation = "kvm.cloud.sap/evacuation-strategy"
+	// Time after which the instances not migrated yet are shut down, e.g.
+	// "10m". The agent waits for the eviction as long as the shutdown is
+	// inhibited by default.
+	TimeoutAnnotation = "kvm.cloud.sap/evacuation-timeout"
+	// Order in which the instances are shut down by the size of their
+	// flavor, either largest-first or smallest-first.
+	OrderAnnotation = "kvm.cloud.sap/evacuation-order"
+	// Number of instances shut down at once, defaults to 4.
+	ParallelismAnnotation = "kvm.cloud.sap/evacuation-parallelism"
+)
+
+// Strategy of the evacuation.
+type Strategy string
+
+const (
+	// Live migrate the instances with an eviction.
+	StrategyLiveMigrate Strategy = "live-migrate"
+	// Shut the instances down on the host.
+	StrategyShutdown Strategy = "shutdown"
+)
+
+// Order in which the instances are shut down.
+type Order string
+
+const (
+	OrderLargestFirst  Order = "largest-first"
+	OrderSmallestFirst Order = "smallest-first"
+)
+
+const (
+	defaultParallelism = 4
+	// Time the guest gets to power off before the domain is destroyed.
+	shutdownGrace = time.Minute
+	// Interval in which the eviction is checked.
+	pollInterval = 10 * time.Second
+)
+
+// Policy of the evacuation on shutdown.
+type Policy struct {
+	Strategy Strategy
+	// Time after which the instances not migrated yet are shut down, zero
+	// to wait for the eviction until the shutdown proceeds.
+	Timeout     time.Duration
+	Order       Order
+	Parallelism int
+}
+
+// Parse the evacuation policy from the annotations of the hypervisor.
+func ParsePolicy(annotations map[string]string) (Policy, error) {
+	return Policy{Strategy: StrategyLiveMigrate, Parallelism: defaultParallelism}.Override(annotations)
+}
+
+// PolicyFromConfig returns the evacuation policy of the runtime
+// configuration of the agent, which the annotations of the hypervisor
+// override.
+func PolicyFromConfig(cfg config.Evacuation) (Policy, error) {
+	annotations := map[string]string{}
+	if cfg.Strategy != "" {
+		annotations[StrategyAnnotation] = cfg.Strategy
+	}
+	if cfg.Timeout.Duration != 0 {
+		annotations[TimeoutAnnotation] = cfg.Timeout.Duration.String()
+	}
+	if cfg.Order != "" {
+		annotations[OrderAnnotation] = cfg.Order
+	}
+	if cfg.Parallelism != 0 {
+		annotations[ParallelismAnnotation] = strconv.Itoa(cfg.Parallelism)
+	}
+	return ParsePolicy(annotations)
+}
+
+// Override the policy with the annotations of the hypervisor.
+func (p Policy) Override(annotations map[string]string) (Policy, error) {
+	policy := p
+	if value, ok := annotations[StrategyAnnotation]; ok {
+		switch strategy := Strategy(value); strategy {
+		case StrategyLiveMigrate, StrategyShutdown:
+			policy.Strategy = strategy
+		default:
+			return Policy{}, fmt.Errorf("invalid evacuation strategy %q, expected one of live-migrate, shutdown", value)
+		}
+	}
+	if value, ok := annotations[TimeoutAnnotation]; ok {
+		timeout, err := time.ParseDuration(value)
+		if err != nil || timeout < 0 {
+			return Policy{}, fmt.Errorf("invalid evacuation timeout %q", value)
+		}
+		policy.Timeout = timeout
+	}
+	if value, ok := annotations[OrderAnnotation]; ok {
+		switch order := Order(value); order {
+		case OrderLargestFirst, OrderSmallestFirst:
+			policy.Order = order
+		default:
+			return Policy{}, fmt.Errorf("invalid evacuation order %q, expected one of largest-first, smallest-first", value)
+		}
+	}
+	if value, ok := annotations[ParallelismAnnotation]; ok {
+		parallelism, err := strconv.Atoi(value)
+		if err != nil || parallelism < 1 {
+			return Policy{}, fmt.Errorf("invalid evacuation parallelism %q", value)
+		}
+		policy.Parallelism = parallelism
+	}
+	return policy, nil
+}
+
+// Sort the domains in the order of the policy by the memory and then the
+// vcpus of their flavor.
+func (p Policy) sort(domains []libvirt.NovaDomain) {
+	if p.Order == "" {
+		return
+	}
+	slices.SortStableFunc(domains, func(a, b libvirt.NovaDomain) int {
+		c := cmp.Or(cmp.Compare(a.MemoryMiB, b.MemoryMiB), cmp.Compare(a.VCPUs, b.VCPUs))
+		if p.Order == OrderLargestFirst {
+			return -c
+		}
+		return c
+	})
+}
+
+// Shut down the instances remaining on the host in the order and with the
+// parallelism of the policy. Every instance shut down is recorded as an
+// event of the hypervisor.
+func (e *EvictionController) shutdownInstances(ctx context.Context, hypervisor *kvmv1.Hypervisor, policy Policy) error {
+	if e.Domains == nil {
+		return errors.New("shutting down instances is not supported without libvirt")
+	}
+	ctx, span := tracing.Start(ctx, "EvictionController.shutdownInstances",
+		attribute.String("strategy", string(policy.Strategy)))
+	defer span.End()
+	log := logger.FromContext(ctx)
+
+	domains, err := e.Domains.NovaDomains()
+	if err != nil {
+		return fmt.Errorf("could not get domains: %w", err)
+	}
+	policy.sort(domains)
+	grace := shutdownGrace
+	if deadline, ok := ctx.Deadline(); ok {
+		grace = max(min(grace, time.Until(deadline)), 0)
+	}
+	log.Info("Shutting down instances", "count", len(domains), "parallelism", policy.Parallelism, "grace", grace)
+
+	var lock sync.Mutex
+	var errs []error
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, policy.Parallelism)
+	for _, domain := range domains {
+		if ctx.Err() != nil {
+			break
+		}
+		slots <- struct{}{}
+		wg.Go(func() {
+			defer func() { <-slots }()
+			if err := e.Domains.ShutdownDomain(domain.UUID, grace); err != nil {
+				lock.Lock()
+				errs = append(errs, err)
+				lock.Unlock()
+				return
+			}
+			if e.Recorder != nil {
+				e.Recorder.Eventf(hypervisor, nil, corev1.EventTypeNormal, "InstanceShutdown", "Evacuate",
+					"Instance %s shut down", domain.UUID)
+			}
+		})
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
@@ -0,0 +1,129 @@
+/*
+SPDX-FileCopyrightText: Copyright 2024 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, LibVirtVersion 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package evacuation
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	kvmv1 "github.com/cobaltcore-dev/openstack-hypervisor-operator/api/v1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/config"
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/libvirt"
+)
+
+var _ = Describe("Evacuation Policy", func() {
+	It("should default to live migration", func() {
+		policy, err := ParsePolicy(nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(policy).To(Equal(Policy{Strategy: StrategyLiveMigrate, Parallelism: defaultParallelism}))
+	})
+
+	It("should parse the policy from the annotations", func() {
+		policy, err := ParsePolicy(map[string]string{
+			StrategyAnnotation:    "shutdown",
+			TimeoutAnnotation:     "10m",
+			OrderAnnotation:       "largest-first",
+			ParallelismAnnotation: "2",
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(policy).To(Equal(Policy{
+			Strategy:    StrategyShutdown,
+			Timeout:     10 * time.Minute,
+			Order:       OrderLargestFirst,
+			Parallelism: 2,
+		}))
+	})
+
+	It("should default to the policy of the runtime config", func() {
+		defaults, err := PolicyFromConfig(config.Evacuation{Strategy: "shutdown", Parallelism: 2})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(defaults).To(Equal(Policy{Strategy: StrategyShutdown, Parallelism: 2}))
+
+		policy, err := defaults.Override(map[string]string{OrderAnnotation: "smallest-first"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(policy).To(Equal(Policy{Strategy: StrategyShutdown, Order: OrderSmallestFirst, Parallelism: 2}))
+
+		_, err = PolicyFromConfig(config.Evacuation{Strategy: "cold-migrate"})
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should reject invalid annotations", func() {
+		for key, value := range map[string]string{
+			StrategyAnnotation:    "cold-migrate",
+			TimeoutAnnotation:     "soon",
+			OrderAnnotation:       "random",
+			ParallelismAnnotation: "0",
+		} {
+			_, err := ParsePolicy(map[string]string{key: value})
+			Expect(err).To(HaveOccurred(), key)
+		}
+	})
+
+	It("should shut down the instances in the order of their flavor size", func() {
+		shutdowner := &fakeShutdowner{domains: []libvirt.NovaDomain{
+			{UUID: "small", MemoryMiB: 1024, VCPUs: 1},
+			{UUID: "large", MemoryMiB: 8192, VCPUs: 4},
+			{UUID: "medium", MemoryMiB: 4096, VCPUs: 2},
+			{UUID: "medium-more-cpus", MemoryMiB: 4096, VCPUs: 8},
+		}}
+		controller := EvictionController{Client: k8sClient, Domains: shutdowner}
+		policy := Policy{Strategy: StrategyShutdown, Order: OrderLargestFirst, Parallelism: 1}
+
+		Expect(controller.shutdownInstances(context.Background(), &kvmv1.Hypervisor{}, policy)).To(Succeed())
+		Expect(shutdowner.shutdown).To(Equal([]string{"large", "medium-more-cpus", "medium", "small"}))
+	})
+
+	It("should report instances failing to shut down", func() {
+		shutdowner := &fakeShutdowner{
+			domains: []libvirt.NovaDomain{{UUID: "a"}, {UUID: "b"}, {UUID: "c"}},
+			err:     map[string]error{"b": errors.New("failed to destroy domain b")},
+		}
+		controller := EvictionController{Client: k8sClient, Domains: shutdowner}
+		policy := Policy{Strategy: StrategyShutdown, Parallelism: 2}
+
+		err := controller.shutdownInstances(context.Background(), &kvmv1.Hypervisor{}, policy)
+		Expect(err).To(MatchError(ContainSubstring("failed to destroy domain b")))
+		Expect(shutdowner.shutdown).To(ConsistOf("a", "c"))
+	})
+})
+
+type fakeShutdowner struct {
+	domains []libvirt.NovaDomain
+	err     map[string]error
+
+	lock     sync.Mutex
+	shutdown []string
+}
+
+func (f *fakeShutdowner) NovaDomains() ([]libvirt.NovaDomain, error) {
+	return f.domains, nil
+}
+
+func (f *fakeShutdowner) ShutdownDomain(uuid string, _ time.Duration) error {
+	if err := f.err[uuid]; err != nil {
+		return err
+	}
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.shutdown = append(f.shutdown, uuid)
+	return nil
+}
@@ -0,0 +1,147 @@
+/*
+SPDX-FileCopyrightText: Copyright 2024 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, LibVirtVersion 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package evacuation
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	kvmv1 "github.com/cobaltcore-dev/openstack-hypervisor-operator/api/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/cobaltcore-dev/kvm-node-agent/api/v1alpha1"
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/sys"
+)
+
+// Condition of the hypervisor reporting the progress of the evacuation on
+// shutdown.
+const ConditionType = "Evacuation"
+
+// Progress of the evacuation of the host.
+type Progress struct {
+	// Instances still to be migrated off the host.
+	Remaining []string
+	// Instances with an outgoing migration in progress.
+	Migrating []string
+	// Estimated time until all instances are migrated, zero if unknown.
+	ETA time.Duration
+}
+
+// Summary of the progress for humans, e.g. "3 instances remaining,
+// migrating 1fc1...; eta 4m0s".
+func (p Progress) String() string {
+	s := fmt.Sprintf("%d instances remaining", len(p.Remaining))
+	if len(p.Migrating) > 0 {
+		s += ", migrating " + strings.Join(p.Migrating, ", ")
+	}
+	if p.ETA > 0 {
+		s += "; eta " + p.ETA.String()
+	}
+	return s
+}
+
+// Tracks the instances remaining on the host between two polls of the
+// eviction, to tell which of them were migrated and how long the rest of
+// them is going to take.
+type tracker struct {
+	started time.Time
+	// Most instances seen on the host since the start.
+	total     int
+	remaining []string
+}
+
+// Update the tracker with the instances remaining on the host. Returns the
+// instances migrated off the host since the last update, and the estimated
+// time until all instances are migrated, zero if none was migrated yet.
+func (t *tracker) update(now time.Time, remaining []string) ([]string, time.Duration) {
+	if t.started.IsZero() {
+		t.started = now
+	}
+	var migrated []string
+	for _, instance := range t.remaining {
+		if !slices.Contains(remaining, instance) {
+			migrated = append(migrated, instance)
+		}
+	}
+	t.remaining = slices.Clone(remaining)
+	t.total = max(t.total, len(remaining))
+
+	done := t.total - len(remaining)
+	if done == 0 || len(remaining) == 0 {
+		return migrated, 0
+	}
+	elapsed := now.Sub(t.started)
+	eta := elapsed * time.Duration(len(remaining)) / time.Duration(done)
+	return migrated, eta.Round(time.Second)
+}
+
+// Get the instances with an outgoing migration off the host in progress.
+func (e *EvictionController) outgoingMigrations(ctx context.Context) ([]string, error) {
+	var migrations v1alpha1.MigrationList
+	if err := e.List(ctx, &migrations, client.InNamespace(sys.Namespace)); err != nil {
+		return nil, err
+	}
+	var outgoing []string
+	for _, migration := range migrations.Items {
+		endpoint, ok := migration.Status.Endpoints[sys.Hostname]
+		if !ok || endpoint.Direction != v1alpha1.MigrationDirectionOutgoing {
+			continue
+		}
+		switch migration.Status.Phase {
+		case v1alpha1.MigrationPhaseCompleted, v1alpha1.MigrationPhaseFailed, v1alpha1.MigrationPhaseCancelled:
+			continue
+		}
+		outgoing = append(outgoing, migration.Name)
+	}
+	slices.Sort(outgoing)
+	return outgoing, nil
+}
+
+// Set the evacuation condition of the hypervisor with server-side apply.
+// The condition is applied by its own field manager, so that the status
+// applied by the hypervisor controller in the meantime is kept.
+func (e *EvictionController) reportProgress(ctx context.Context, hypervisor *kvmv1.Hypervisor,
+	status v1.ConditionStatus, reason, message string) error {
+	meta.SetStatusCondition(&hypervisor.Status.Conditions, v1.Condition{
+		Type:    ConditionType,
+		Status:  status,
+		Reason:  reason,
+		Message: message,
+	})
+	condition, err := runtime.DefaultUnstructuredConverter.ToUnstructured(
+		meta.FindStatusCondition(hypervisor.Status.Conditions, ConditionType))
+	if err != nil {
+		return fmt.Errorf("unable to convert condition: %w", err)
+	}
+
+	obj := &unstructured.Unstructured{Object: map[string]any{
+		"status": map[string]any{"conditions": []any{condition}},
+	}}
+	obj.SetAPIVersion(kvmv1.GroupVersion.String())
+	obj.SetKind("Hypervisor")
+	obj.SetName(hypervisor.Name)
+	obj.SetNamespace(hypervisor.Namespace)
+	return e.Status().Apply(ctx, client.ApplyConfigurationFromUnstructured(obj),
+		client.FieldOwner("kvm-node-agent-evacuation/"+sys.Hostname), client.ForceOwnership)
+}
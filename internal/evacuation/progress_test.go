@@ -0,0 +1,99 @@
+/*
+SPDX-FileCopyrightText: Copyright 2024 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, LibVirtVersion 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package evacuation
+
+import (
+	"context"
+	"time"
+
+	kvmv1 "github.com/cobaltcore-dev/openstack-hypervisor-operator/api/v1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/cobaltcore-dev/kvm-node-agent/api/v1alpha1"
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/sys"
+)
+
+var _ = Describe("Evacuation Progress", func() {
+	It("should track the migrated instances and estimate the rest", func() {
+		var t tracker
+		start := time.Now()
+
+		migrated, eta := t.update(start, []string{"a", "b", "c", "d"})
+		Expect(migrated).To(BeEmpty())
+		Expect(eta).To(BeZero())
+
+		migrated, eta = t.update(start.Add(2*time.Minute), []string{"b", "d"})
+		Expect(migrated).To(Equal([]string{"a", "c"}))
+		Expect(eta).To(Equal(2 * time.Minute))
+
+		migrated, eta = t.update(start.Add(3*time.Minute), nil)
+		Expect(migrated).To(Equal([]string{"b", "d"}))
+		Expect(eta).To(BeZero())
+	})
+
+	It("should summarize the progress", func() {
+		progress := Progress{
+			Remaining: []string{"a", "b"},
+			Migrating: []string{"a"},
+			ETA:       90 * time.Second,
+		}
+		Expect(progress.String()).To(Equal("2 instances remaining, migrating a; eta 1m30s"))
+		Expect(Progress{}.String()).To(Equal("0 instances remaining"))
+	})
+
+	It("should only undo the eviction if the shutdown was aborted", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		controller := EvictionController{Client: k8sClient}
+		err := controller.abort(ctx, &kvmv1.Hypervisor{}, &unstructured.Unstructured{})
+		Expect(err).To(MatchError(context.Canceled))
+	})
+
+	It("should list the outgoing migrations in progress", func() {
+		ctx := context.Background()
+		sys.Hostname = "evacuated-host"
+		sys.Namespace = "default"
+
+		for name, status := range map[string]v1alpha1.MigrationStatus{
+			"outgoing": {
+				Phase:     v1alpha1.MigrationPhasePreCopy,
+				Endpoints: map[string]v1alpha1.MigrationEndpoint{"evacuated-host": {Direction: "outgoing"}},
+			},
+			"completed": {
+				Phase:     v1alpha1.MigrationPhaseCompleted,
+				Endpoints: map[string]v1alpha1.MigrationEndpoint{"evacuated-host": {Direction: "outgoing"}},
+			},
+			"incoming": {
+				Phase:     v1alpha1.MigrationPhasePreCopy,
+				Endpoints: map[string]v1alpha1.MigrationEndpoint{"evacuated-host": {Direction: "incoming"}},
+			},
+		} {
+			migration := &v1alpha1.Migration{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: sys.Namespace}}
+			Expect(k8sClient.Create(ctx, migration)).To(Succeed())
+			DeferCleanup(k8sClient.Delete, ctx, migration)
+			migration.Status = status
+			migration.Status.Started = metav1.Now()
+			Expect(k8sClient.Status().Update(ctx, migration)).To(Succeed())
+		}
+
+		controller := EvictionController{Client: k8sClient}
+		Expect(controller.outgoingMigrations(ctx)).To(Equal([]string{"outgoing"}))
+	})
+})
@@ -0,0 +1,137 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package hostprep prepares the host at startup, ensuring the libvirt
+// socket, the pki and the log directories exist with the expected ownership
+// and permissions, which freshly imaged nodes often lack.
+package hostprep
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/config"
+)
+
+// DefaultPaths returns the paths prepared without configured ones, with the
+// pki directories owned by pkiOwner, e.g. the qemu user of the host.
+func DefaultPaths(pkiOwner string) []config.HostPath {
+	return []config.HostPath{
+		{Path: "/run/libvirt", Mode: "0755"},
+		{Path: "/etc/pki/CA", Owner: pkiOwner, Mode: "0755"},
+		{Path: "/etc/pki/libvirt", Owner: pkiOwner, Mode: "0755"},
+		{Path: "/etc/pki/libvirt/private", Owner: pkiOwner, Mode: "0750"},
+		{Path: "/etc/pki/qemu", Owner: pkiOwner, Mode: "0755"},
+		{Path: "/var/lib/libvirt/ch/pki", Owner: pkiOwner, Mode: "0755"},
+		{Path: "/var/log/libvirt", Mode: "0700"},
+		{Path: "/var/log/libvirt/qemu", Mode: "0700"},
+	}
+}
+
+// Prepare the paths on the host with its root file system mounted at root.
+// Missing directories are created, missing sockets are left to their
+// daemon. It returns the changes made for logging, paths failing to be
+// prepared are reported in the error, the others are prepared nevertheless.
+func Prepare(root string, paths []config.HostPath) ([]string, error) {
+	var changes []string
+	var errs []error
+	for _, path := range paths {
+		pathChanges, err := prepare(root, path)
+		changes = append(changes, pathChanges...)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to prepare %s: %w", path.Path, err))
+		}
+	}
+	return changes, errors.Join(errs...)
+}
+
+func prepare(root string, path config.HostPath) ([]string, error) {
+	var changes []string
+	var mode os.FileMode
+	if path.Mode != "" {
+		var err error
+		if mode, err = config.ParseMode(path.Mode); err != nil {
+			return nil, err
+		}
+	}
+	uid, gid, err := parseOwner(path.Owner)
+	if err != nil {
+		return nil, err
+	}
+
+	target := filepath.Join(root, path.Path)
+	info, err := os.Stat(target)
+	switch {
+	case os.IsNotExist(err) && path.Type == config.HostPathSocket:
+		return nil, nil
+	case os.IsNotExist(err):
+		if err := os.MkdirAll(target, 0755); err != nil {
+			return nil, err
+		}
+		changes = append(changes, "created "+path.Path)
+		if info, err = os.Stat(target); err != nil {
+			return changes, err
+		}
+	case err != nil:
+		return nil, err
+	}
+
+	if path.Type == config.HostPathSocket {
+		if info.Mode().Type() != os.ModeSocket {
+			return changes, fmt.Errorf("not a socket but %s", info.Mode().Type())
+		}
+	} else if !info.IsDir() {
+		return changes, errors.New("not a directory")
+	}
+
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok && path.Owner != "" &&
+		(int(stat.Uid) != uid || int(stat.Gid) != gid) {
+		if err := os.Chown(target, uid, gid); err != nil {
+			return changes, err
+		}
+		changes = append(changes, fmt.Sprintf("changed owner of %s from %d:%d to %s",
+			path.Path, stat.Uid, stat.Gid, path.Owner))
+	}
+	if path.Mode != "" && info.Mode().Perm() != mode {
+		if err := os.Chmod(target, mode); err != nil {
+			return changes, err
+		}
+		changes = append(changes, fmt.Sprintf("changed mode of %s from %#o to %#o",
+			path.Path, info.Mode().Perm(), mode))
+	}
+	return changes, nil
+}
+
+// Parse the owner of a path, -1 if not configured.
+func parseOwner(owner string) (uid, gid int, err error) {
+	if owner == "" {
+		return -1, -1, nil
+	}
+	u, g, ok := strings.Cut(owner, ":")
+	if uid, err = strconv.Atoi(u); err != nil || !ok {
+		return 0, 0, fmt.Errorf("invalid owner %q, expected uid:gid", owner)
+	}
+	if gid, err = strconv.Atoi(g); err != nil {
+		return 0, 0, fmt.Errorf("invalid owner %q, expected uid:gid", owner)
+	}
+	return uid, gid, nil
+}
@@ -0,0 +1,101 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hostprep
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/config"
+)
+
+func TestPrepare(t *testing.T) {
+	root := t.TempDir()
+	owner := fmt.Sprintf("%d:%d", os.Getuid(), os.Getgid())
+	if err := os.MkdirAll(filepath.Join(root, "var/log/libvirt"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	listener, err := net.Listen("unix", filepath.Join(root, "libvirt-sock"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	paths := []config.HostPath{
+		{Path: "/etc/pki/libvirt/private", Owner: owner, Mode: "0750"},
+		{Path: "/var/log/libvirt", Mode: "0700"},
+		{Path: "/libvirt-sock", Type: config.HostPathSocket, Mode: "0660"},
+		{Path: "/run/libvirt/virtqemud-sock", Type: config.HostPathSocket, Mode: "0660"},
+	}
+	changes, err := Prepare(root, paths)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The directory, its mode, the mode of the log directory and the socket.
+	if len(changes) != 4 {
+		t.Fatalf("expected 4 changes, got %q", changes)
+	}
+	for _, change := range []string{"created /etc/pki/libvirt/private", "changed mode of /var/log/libvirt from 0755 to 0700"} {
+		if !slices.Contains(changes, change) {
+			t.Errorf("expected change %q, got %q", change, changes)
+		}
+	}
+	for path, mode := range map[string]os.FileMode{
+		"etc/pki/libvirt/private": 0750,
+		"var/log/libvirt":         0700,
+		"libvirt-sock":            0660,
+	} {
+		info, err := os.Stat(filepath.Join(root, path))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode().Perm() != mode {
+			t.Errorf("expected mode %#o of %s, got %#o", mode, path, info.Mode().Perm())
+		}
+	}
+	if _, err := os.Stat(filepath.Join(root, "run/libvirt")); !os.IsNotExist(err) {
+		t.Error("expected a missing socket to be left to its daemon")
+	}
+
+	// A prepared host is left alone.
+	if changes, err := Prepare(root, paths); err != nil || len(changes) != 0 {
+		t.Errorf("expected no changes, got %q, %v", changes, err)
+	}
+}
+
+func TestPrepare_WrongType(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "pki"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, err := Prepare(root, []config.HostPath{
+		{Path: "/pki", Mode: "0755"},
+		{Path: "/log", Mode: "0700"},
+	})
+	if err == nil {
+		t.Fatal("expected an error for a file instead of a directory")
+	}
+	// The other paths are prepared nevertheless.
+	if info, err := os.Stat(filepath.Join(root, "log")); err != nil || info.Mode().Perm() != 0700 {
+		t.Errorf("expected the log directory to be prepared, got %v", err)
+	}
+}
@@ -0,0 +1,151 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package hoststorage reports the free space and inodes of the host paths
+// the hypervisor needs to write to. Full filesystems are a prime cause of
+// failing migrations and snapshots.
+package hoststorage
+
+import (
+	"errors"
+	"fmt"
+	"syscall"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// Paths checked by default, as mounted into the agent by the chart.
+var DefaultPaths = []string{"/var/lib/nova", "/var/lib/libvirt", "/pki/libvirt"}
+
+// Share of free space or inodes below which a path is low on storage.
+const LowFreeRatio = 0.1
+
+var (
+	freeBytes = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "host_filesystem_free_bytes",
+		Help: "Bytes available to unprivileged users on the filesystem holding the path.",
+	}, []string{"path"})
+	sizeBytes = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "host_filesystem_size_bytes",
+		Help: "Size of the filesystem holding the path.",
+	}, []string{"path"})
+	freeInodes = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "host_filesystem_free_inodes",
+		Help: "Free inodes of the filesystem holding the path.",
+	}, []string{"path"})
+	inodes = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "host_filesystem_inodes",
+		Help: "Inodes of the filesystem holding the path.",
+	}, []string{"path"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(freeBytes, sizeBytes, freeInodes, inodes)
+}
+
+// Usage of the filesystem holding a path.
+type Usage struct {
+	Path       string
+	FreeBytes  uint64
+	SizeBytes  uint64
+	FreeInodes uint64
+	Inodes     uint64
+}
+
+// Share of the space available. Filesystems without a size, e.g. pseudo
+// filesystems, are never low.
+func (u Usage) FreeRatio() float64 {
+	if u.SizeBytes == 0 {
+		return 1
+	}
+	return float64(u.FreeBytes) / float64(u.SizeBytes)
+}
+
+// Share of the inodes available. Filesystems without a fixed number of
+// inodes, e.g. btrfs, are never low.
+func (u Usage) FreeInodesRatio() float64 {
+	if u.Inodes == 0 {
+		return 1
+	}
+	return float64(u.FreeInodes) / float64(u.Inodes)
+}
+
+// Check if the space or the inodes of the path run out.
+func (u Usage) Low() bool {
+	return u.FreeRatio() < LowFreeRatio || u.FreeInodesRatio() < LowFreeRatio
+}
+
+// Summary of the usage for humans, e.g. "/var/lib/nova 12% free, 80%
+// inodes free".
+func (u Usage) String() string {
+	return fmt.Sprintf("%s %.0f%% free, %.0f%% inodes free", u.Path, u.FreeRatio()*100, u.FreeInodesRatio()*100)
+}
+
+// Export the usage of the paths as metrics.
+func UpdateMetrics(usages []Usage) {
+	freeBytes.Reset()
+	sizeBytes.Reset()
+	freeInodes.Reset()
+	inodes.Reset()
+	for _, u := range usages {
+		freeBytes.WithLabelValues(u.Path).Set(float64(u.FreeBytes))
+		sizeBytes.WithLabelValues(u.Path).Set(float64(u.SizeBytes))
+		freeInodes.WithLabelValues(u.Path).Set(float64(u.FreeInodes))
+		inodes.WithLabelValues(u.Path).Set(float64(u.Inodes))
+	}
+}
+
+// Interface provides the storage usage of the host paths.
+type Interface interface {
+	// ReadUsage returns the usage of the paths which could be read, and
+	// an error for the others.
+	ReadUsage() ([]Usage, error)
+}
+
+// SystemReader reads the usage of the paths with statfs.
+type SystemReader struct {
+	paths  []string
+	statfs func(path string, stat *syscall.Statfs_t) error
+}
+
+// NewSystemReader creates a new SystemReader of the given paths.
+func NewSystemReader(paths []string) *SystemReader {
+	return &SystemReader{paths: paths, statfs: syscall.Statfs}
+}
+
+// ReadUsage reads the usage of the filesystems holding the paths.
+func (r *SystemReader) ReadUsage() ([]Usage, error) {
+	usages := make([]Usage, 0, len(r.paths))
+	var errs []error
+	for _, path := range r.paths {
+		var stat syscall.Statfs_t
+		if err := r.statfs(path, &stat); err != nil {
+			errs = append(errs, fmt.Errorf("failed to stat filesystem of %s: %w", path, err))
+			continue
+		}
+		blockSize := uint64(stat.Bsize)
+		usages = append(usages, Usage{
+			Path:       path,
+			FreeBytes:  stat.Bavail * blockSize,
+			SizeBytes:  stat.Blocks * blockSize,
+			FreeInodes: stat.Ffree,
+			Inodes:     stat.Files,
+		})
+	}
+	return usages, errors.Join(errs...)
+}
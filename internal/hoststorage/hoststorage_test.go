@@ -0,0 +1,73 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hoststorage
+
+import (
+	"errors"
+	"syscall"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSystemReaderReadUsage(t *testing.T) {
+	reader := &SystemReader{
+		paths: []string{"/var/lib/nova", "/var/lib/libvirt", "/missing"},
+		statfs: func(path string, stat *syscall.Statfs_t) error {
+			switch path {
+			case "/var/lib/nova":
+				*stat = syscall.Statfs_t{Bsize: 4096, Blocks: 1000, Bavail: 50, Files: 100, Ffree: 90}
+			case "/var/lib/libvirt":
+				*stat = syscall.Statfs_t{Bsize: 4096, Blocks: 1000, Bavail: 500}
+			default:
+				return syscall.ENOENT
+			}
+			return nil
+		},
+	}
+	usages, err := reader.ReadUsage()
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, syscall.ENOENT))
+	assert.Equal(t, []Usage{
+		{Path: "/var/lib/nova", FreeBytes: 50 * 4096, SizeBytes: 1000 * 4096, FreeInodes: 90, Inodes: 100},
+		{Path: "/var/lib/libvirt", FreeBytes: 500 * 4096, SizeBytes: 1000 * 4096},
+	}, usages)
+
+	assert.True(t, usages[0].Low())
+	assert.Equal(t, "/var/lib/nova 5% free, 90% inodes free", usages[0].String())
+	// Filesystems without a fixed number of inodes don't run out of them.
+	assert.False(t, usages[1].Low())
+	assert.Equal(t, "/var/lib/libvirt 50% free, 100% inodes free", usages[1].String())
+}
+
+func TestUsageLowInodes(t *testing.T) {
+	usage := Usage{Path: "/var/lib/libvirt", FreeBytes: 90, SizeBytes: 100, FreeInodes: 5, Inodes: 100}
+	assert.True(t, usage.Low())
+}
+
+func TestUpdateMetrics(t *testing.T) {
+	UpdateMetrics([]Usage{{Path: "/var/lib/nova", FreeBytes: 10, SizeBytes: 100, FreeInodes: 1, Inodes: 2}})
+	assert.InDelta(t, 10, testutil.ToFloat64(freeBytes.WithLabelValues("/var/lib/nova")), 0)
+	assert.InDelta(t, 2, testutil.ToFloat64(inodes.WithLabelValues("/var/lib/nova")), 0)
+
+	// Paths no longer read are removed.
+	UpdateMetrics(nil)
+	assert.Equal(t, 0, testutil.CollectAndCount(freeBytes))
+}
@@ -0,0 +1,97 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package iommu reads the iommu and vfio state of the host, which are
+// required to pass devices through to domains.
+package iommu
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Kernel modules required to pass pci devices through with vfio.
+var VFIOModules = []string{"vfio", "vfio_iommu_type1", "vfio_pci"}
+
+// Status is the iommu and vfio state of the host.
+type Status struct {
+	// Number of iommu groups, zero if the host booted without the iommu
+	// enabled, e.g. without intel_iommu=on.
+	Groups int
+	// Vfio modules which are neither loaded nor built into the kernel.
+	MissingModules []string
+}
+
+// Check if devices can be passed through to domains.
+func (s Status) Ready() bool {
+	return s.Groups > 0 && len(s.MissingModules) == 0
+}
+
+// Summary of the status for humans, e.g. for condition messages.
+func (s Status) String() string {
+	if len(s.MissingModules) == 0 {
+		return fmt.Sprintf("%d iommu groups, vfio loaded", s.Groups)
+	}
+	return fmt.Sprintf("%d iommu groups, missing modules %s",
+		s.Groups, strings.Join(s.MissingModules, ", "))
+}
+
+// Interface provides the iommu status of the host.
+type Interface interface {
+	// ReadStatus reads the iommu status of the host.
+	ReadStatus() (*Status, error)
+}
+
+// SystemReader reads the iommu status from the system files.
+type SystemReader struct {
+	iommuGroupsPath string
+	modulesPath     string
+}
+
+// NewSystemReader creates a new SystemReader with the default system paths.
+func NewSystemReader() *SystemReader {
+	return &SystemReader{
+		iommuGroupsPath: "/sys/kernel/iommu_groups",
+		modulesPath:     "/sys/module",
+	}
+}
+
+// ReadStatus reads the iommu status of the host.
+func (r *SystemReader) ReadStatus() (*Status, error) {
+	var s Status
+
+	groups, err := os.ReadDir(r.iommuGroupsPath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	s.Groups = len(groups)
+
+	// Loaded modules show up in /sys/module, just like built-in modules
+	// with parameters, which all of the vfio modules have.
+	for _, module := range VFIOModules {
+		if _, err := os.Stat(filepath.Join(r.modulesPath, module)); err == nil {
+			continue
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+		s.MissingModules = append(s.MissingModules, module)
+	}
+	return &s, nil
+}
@@ -0,0 +1,63 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package iommu
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSystemReaderReadStatus(t *testing.T) {
+	tmpDir := t.TempDir()
+	for _, dir := range []string{
+		"iommu_groups/0", "iommu_groups/1", "iommu_groups/2",
+		"module/vfio", "module/vfio_iommu_type1", "module/vfio_pci",
+	} {
+		require.NoError(t, os.MkdirAll(filepath.Join(tmpDir, dir), 0755))
+	}
+
+	reader := &SystemReader{
+		iommuGroupsPath: filepath.Join(tmpDir, "iommu_groups"),
+		modulesPath:     filepath.Join(tmpDir, "module"),
+	}
+	status, err := reader.ReadStatus()
+	require.NoError(t, err)
+	assert.Equal(t, Status{Groups: 3}, *status)
+	assert.True(t, status.Ready())
+	assert.Equal(t, "3 iommu groups, vfio loaded", status.String())
+}
+
+func TestSystemReaderReadStatusWithoutIOMMU(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(tmpDir, "module", "vfio"), 0755))
+
+	// Without the iommu enabled the kernel doesn't create the directory.
+	reader := &SystemReader{
+		iommuGroupsPath: filepath.Join(tmpDir, "iommu_groups"),
+		modulesPath:     filepath.Join(tmpDir, "module"),
+	}
+	status, err := reader.ReadStatus()
+	require.NoError(t, err)
+	assert.False(t, status.Ready())
+	assert.Equal(t, []string{"vfio_iommu_type1", "vfio_pci"}, status.MissingModules)
+	assert.Equal(t, "0 iommu groups, missing modules vfio_iommu_type1, vfio_pci", status.String())
+}
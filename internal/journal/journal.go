@@ -0,0 +1,197 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package journal forwards the errors logged to the systemd journal by
+// libvirt and the domain processes as Kubernetes events of the hypervisor.
+package journal
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/events"
+	logger "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+// +kubebuilder:rbac:groups=events.k8s.io,resources=events,verbs=create;patch
+
+// Units of the libvirt daemons whose errors are forwarded. The monolithic
+// libvirtd or the modular daemons of the qemu and cloud-hypervisor drivers.
+var Units = []string{"libvirtd.service", "virtqemud.service", "virtchd.service"}
+
+// Slice of the scopes libvirt runs the qemu and cloud-hypervisor processes
+// of the domains in.
+const MachineSlice = "machine.slice"
+
+// Directory is the journal directory of the host mounted into the
+// container.
+var Directory = "/var/log/journal"
+
+// Journal fields read from the entries.
+const (
+	fieldMessage    = "MESSAGE"
+	fieldPriority   = "PRIORITY"
+	fieldIdentifier = "SYSLOG_IDENTIFIER"
+	fieldUnit       = "_SYSTEMD_UNIT"
+	fieldSlice      = "_SYSTEMD_SLICE"
+)
+
+// Least severe priority forwarded, err.
+const maxPriority = 3
+
+// Maximum length of the note of an event accepted by the api server.
+const maxNoteLength = 1024
+
+// Entry is an entry of the systemd journal.
+type Entry struct {
+	Time       time.Time
+	Priority   int
+	Unit       string
+	Slice      string
+	Identifier string
+	Message    string
+}
+
+// Build the entry from the fields of a journal entry and its realtime
+// timestamp in microseconds. Entries without priority count as info.
+func NewEntry(fields map[string]string, realtime uint64) Entry {
+	priority, err := strconv.Atoi(fields[fieldPriority])
+	if err != nil {
+		priority = 6
+	}
+	return Entry{
+		Time:       time.UnixMicro(int64(realtime)),
+		Priority:   priority,
+		Unit:       fields[fieldUnit],
+		Slice:      fields[fieldSlice],
+		Identifier: fields[fieldIdentifier],
+		Message:    fields[fieldMessage],
+	}
+}
+
+// Reason of the event for the entry, false if the entry isn't forwarded.
+func (e Entry) Reason() (string, bool) {
+	if e.Priority > maxPriority {
+		return "", false
+	}
+	switch {
+	case slices.Contains(Units, e.Unit):
+		return "LibvirtError", true
+	case e.Slice == MachineSlice:
+		return "DomainError", true
+	}
+	return "", false
+}
+
+// Note of the event for the entry, e.g. "virtqemud: internal error ...".
+func (e Entry) Note() string {
+	source := e.Identifier
+	if source == "" {
+		source = strings.TrimSuffix(e.Unit, ".service")
+	}
+	note := source + ": " + strings.TrimSpace(e.Message)
+	if len(note) > maxNoteLength {
+		note = note[:maxNoteLength-3] + "..."
+	}
+	return note
+}
+
+// Reader reads the entries of the journal.
+type Reader interface {
+	// Next blocks until the next entry is appended to the journal, or
+	// until the context is done.
+	Next(ctx context.Context) (Entry, error)
+	// Close the journal.
+	Close() error
+}
+
+// Capture forwards the errors of libvirt and the domain processes logged
+// to the journal as warning events of the hypervisor. Repeated errors are
+// aggregated by the event recorder.
+type Capture struct {
+	// Recorder the events are emitted with.
+	Recorder events.EventRecorder
+	// Hypervisor the events are attached to.
+	Hypervisor runtime.Object
+	// Open the journal positioned at its end, defaults to NewSystemReader.
+	Open func() (Reader, error)
+	// Interval between attempts to reopen the journal after an error,
+	// defaults to 10 seconds.
+	RetryInterval time.Duration
+}
+
+// Start capturing and block until the context is cancelled. Failing to
+// read the journal is logged, but doesn't stop the manager.
+func (c *Capture) Start(ctx context.Context) error {
+	open := c.Open
+	if open == nil {
+		open = NewSystemReader
+	}
+	return follow(ctx, "journal", open, c.RetryInterval, func(entry Entry) {
+		if reason, ok := entry.Reason(); ok {
+			c.Recorder.Eventf(c.Hypervisor, nil, corev1.EventTypeWarning, reason, "Journal", "%s", entry.Note())
+		}
+	})
+}
+
+// Pass the new entries of the journal to handle until the context is
+// cancelled. The journal is reopened after errors, by default every 10
+// seconds.
+func follow(ctx context.Context, name string, open func() (Reader, error), interval time.Duration,
+	handle func(Entry)) error {
+	log := logger.FromContext(ctx).WithName(name)
+	if interval == 0 {
+		interval = 10 * time.Second
+	}
+
+	for {
+		if err := read(ctx, open, handle); err != nil {
+			log.Error(err, "unable to read journal, retrying", "interval", interval)
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(interval):
+		}
+	}
+}
+
+func read(ctx context.Context, open func() (Reader, error), handle func(Entry)) error {
+	reader, err := open()
+	if err != nil {
+		return fmt.Errorf("failed to open journal: %w", err)
+	}
+	defer reader.Close()
+
+	for {
+		entry, err := reader.Next(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read journal: %w", err)
+		}
+		handle(entry)
+	}
+}
@@ -0,0 +1,128 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package journal
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/events"
+)
+
+func TestEntry(t *testing.T) {
+	entry := NewEntry(map[string]string{
+		"MESSAGE":           "internal error: qemu unexpectedly closed the monitor\n",
+		"PRIORITY":          "3",
+		"SYSLOG_IDENTIFIER": "virtqemud",
+		"_SYSTEMD_UNIT":     "virtqemud.service",
+	}, 1700000000000000)
+	assert.Equal(t, time.UnixMicro(1700000000000000), entry.Time)
+	reason, ok := entry.Reason()
+	assert.True(t, ok)
+	assert.Equal(t, "LibvirtError", reason)
+	assert.Equal(t, "virtqemud: internal error: qemu unexpectedly closed the monitor", entry.Note())
+
+	entry = NewEntry(map[string]string{
+		"MESSAGE":        "kvm run failed Bad address",
+		"PRIORITY":       "2",
+		"_SYSTEMD_UNIT":  "machine-qemu\\x2d1\\x2dinstance\\x2d0001.scope",
+		"_SYSTEMD_SLICE": "machine.slice",
+	}, 0)
+	reason, ok = entry.Reason()
+	assert.True(t, ok)
+	assert.Equal(t, "DomainError", reason)
+
+	// Warnings and entries of other units are not forwarded.
+	_, ok = NewEntry(map[string]string{"PRIORITY": "4", "_SYSTEMD_UNIT": "libvirtd.service"}, 0).Reason()
+	assert.False(t, ok)
+	_, ok = NewEntry(map[string]string{"PRIORITY": "3", "_SYSTEMD_UNIT": "sshd.service"}, 0).Reason()
+	assert.False(t, ok)
+	_, ok = NewEntry(map[string]string{"_SYSTEMD_UNIT": "libvirtd.service"}, 0).Reason()
+	assert.False(t, ok)
+
+	long := Entry{Unit: "libvirtd.service", Message: strings.Repeat("x", 2000)}
+	assert.Len(t, long.Note(), maxNoteLength)
+	assert.True(t, strings.HasPrefix(long.Note(), "libvirtd: x"))
+}
+
+type fakeReader struct {
+	entries []Entry
+	err     error
+	closed  bool
+}
+
+func (r *fakeReader) Next(ctx context.Context) (Entry, error) {
+	if len(r.entries) > 0 {
+		entry := r.entries[0]
+		r.entries = r.entries[1:]
+		return entry, nil
+	}
+	if r.err != nil {
+		return Entry{}, r.err
+	}
+	<-ctx.Done()
+	return Entry{}, ctx.Err()
+}
+
+func (r *fakeReader) Close() error {
+	r.closed = true
+	return nil
+}
+
+func TestCapture(t *testing.T) {
+	recorder := events.NewFakeRecorder(10)
+	reader := &fakeReader{entries: []Entry{
+		{Priority: 6, Unit: "libvirtd.service", Message: "starting"},
+		{Priority: 3, Unit: "libvirtd.service", Identifier: "libvirtd", Message: "cannot open file"},
+	}}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	opened := make(chan struct{}, 10)
+	capture := &Capture{
+		Recorder:   recorder,
+		Hypervisor: &corev1.Node{},
+		Open: func() (Reader, error) {
+			opened <- struct{}{}
+			if len(opened) > 1 {
+				return nil, errors.New("journal gone")
+			}
+			return reader, nil
+		},
+		RetryInterval: time.Millisecond,
+	}
+	done := make(chan error)
+	go func() { done <- capture.Start(ctx) }()
+
+	select {
+	case event := <-recorder.Events:
+		assert.Equal(t, "Warning LibvirtError libvirtd: cannot open file", event)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected an event for the error entry")
+	}
+	cancel()
+	require.NoError(t, <-done)
+	assert.True(t, reader.closed)
+	assert.Empty(t, recorder.Events)
+}
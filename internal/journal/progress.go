@@ -0,0 +1,160 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package journal
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Syslog identifiers of systemd-sysupdate and the systemd-pull processes it
+// spawns to download the update.
+var UpdateIdentifiers = []string{"systemd-sysupdate", "systemd-pull"}
+
+var (
+	// systemd-pull, e.g. "Got 42% of https://example.com/usr.raw. 2min 3s left at 12.5M/s."
+	percentRegexp = regexp.MustCompile(`^Got (\d{1,3})% of `)
+	// systemd-pull, e.g. "Acquired 1.2G."
+	acquiredRegexp = regexp.MustCompile(`^Acquired (\d+(?:\.\d+)?)([BKMGT]?)\.?$`)
+	// systemd-sysupdate, e.g. "Adding new version '1877.3' to partition 'usr-b'."
+	partitionRegexp = regexp.MustCompile(`\b(?:to|into) partition '?([^\s']+?)'?\.?$`)
+)
+
+var byteUnits = []string{"B", "K", "M", "G", "T"}
+
+// UpdateProgress of an operating system update.
+type UpdateProgress struct {
+	// Percentage of the current download.
+	Percent int
+	// Bytes downloaded by the completed downloads.
+	DownloadedBytes int64
+	// Partition the update is written to.
+	Partition string
+}
+
+// Observe a message logged by the update, returns true if the progress
+// changed.
+func (p *UpdateProgress) Observe(message string) bool {
+	message = strings.TrimSpace(message)
+	before := *p
+	if match := percentRegexp.FindStringSubmatch(message); match != nil {
+		if percent, err := strconv.Atoi(match[1]); err == nil && percent <= 100 {
+			p.Percent = percent
+		}
+	}
+	if match := acquiredRegexp.FindStringSubmatch(message); match != nil {
+		if size, err := strconv.ParseFloat(match[1], 64); err == nil {
+			for _, unit := range byteUnits {
+				if unit == match[2] || (unit == "B" && match[2] == "") {
+					break
+				}
+				size *= 1024
+			}
+			p.DownloadedBytes += int64(size)
+			p.Percent = 100
+		}
+	}
+	if match := partitionRegexp.FindStringSubmatch(message); match != nil {
+		p.Partition = match[1]
+	}
+	return *p != before
+}
+
+// IsZero reports whether nothing is known about the progress yet.
+func (p UpdateProgress) IsZero() bool {
+	return p == UpdateProgress{}
+}
+
+// Summary for humans, e.g. "42%, 1.2 GiB downloaded, partition usr-b".
+func (p UpdateProgress) String() string {
+	parts := []string{fmt.Sprintf("%d%%", p.Percent)}
+	if p.DownloadedBytes > 0 {
+		parts = append(parts, formatBytes(p.DownloadedBytes)+" downloaded")
+	}
+	if p.Partition != "" {
+		parts = append(parts, "partition "+p.Partition)
+	}
+	return strings.Join(parts, ", ")
+}
+
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	value, exp := float64(n)/unit, 0
+	for value >= unit && exp < 3 {
+		value /= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", value, "KMGT"[exp])
+}
+
+// UpdateProgressTracker provides the progress of the running operating
+// system update.
+type UpdateProgressTracker interface {
+	// Progress of the update since the last reset.
+	Progress() UpdateProgress
+	// Reset the progress when a new update is started.
+	Reset()
+}
+
+// UpdateTracker follows the progress of operating system updates logged to
+// the journal by systemd-sysupdate.
+type UpdateTracker struct {
+	// Open the journal positioned at its end, defaults to NewUpdateReader.
+	Open func() (Reader, error)
+	// Interval between attempts to reopen the journal after an error,
+	// defaults to 10 seconds.
+	RetryInterval time.Duration
+
+	lock     sync.Mutex
+	progress UpdateProgress
+}
+
+// Start following the journal and block until the context is cancelled.
+func (t *UpdateTracker) Start(ctx context.Context) error {
+	open := t.Open
+	if open == nil {
+		open = NewUpdateReader
+	}
+	return follow(ctx, "update-progress", open, t.RetryInterval, func(entry Entry) {
+		t.lock.Lock()
+		defer t.lock.Unlock()
+		t.progress.Observe(entry.Message)
+	})
+}
+
+// Progress of the update since the last reset.
+func (t *UpdateTracker) Progress() UpdateProgress {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	return t.progress
+}
+
+// Reset the progress when a new update is started.
+func (t *UpdateTracker) Reset() {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.progress = UpdateProgress{}
+}
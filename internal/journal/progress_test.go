@@ -0,0 +1,70 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package journal
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUpdateProgress(t *testing.T) {
+	var progress UpdateProgress
+	assert.True(t, progress.IsZero())
+
+	assert.True(t, progress.Observe("Adding new version '1877.3' to partition 'usr-b'."))
+	assert.True(t, progress.Observe("Got 42% of https://example.com/usr.raw. 2min 3s left at 12.5M/s."))
+	assert.Equal(t, UpdateProgress{Percent: 42, Partition: "usr-b"}, progress)
+	assert.Equal(t, "42%, partition usr-b", progress.String())
+
+	assert.True(t, progress.Observe("Acquired 1.5G."))
+	assert.True(t, progress.Observe("Acquired 512K."))
+	assert.Equal(t, int64(1536<<20+512<<10), progress.DownloadedBytes)
+	assert.Equal(t, 100, progress.Percent)
+	assert.Equal(t, "100%, 1.5 GiB downloaded, partition usr-b", progress.String())
+
+	assert.False(t, progress.Observe("Selected update '1877.3' for install."))
+	assert.False(t, progress.Observe("Got 420% of nothing"))
+}
+
+func TestUpdateTracker(t *testing.T) {
+	reader := &fakeReader{entries: []Entry{
+		{Identifier: "systemd-pull", Message: "Got 7% of https://example.com/usr.raw."},
+	}}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	tracker := &UpdateTracker{
+		Open: func() (Reader, error) {
+			return reader, nil
+		},
+		RetryInterval: time.Millisecond,
+	}
+	done := make(chan error)
+	go func() { done <- tracker.Start(ctx) }()
+
+	assert.Eventually(t, func() bool {
+		return tracker.Progress().Percent == 7
+	}, 5*time.Second, time.Millisecond)
+	tracker.Reset()
+	assert.True(t, tracker.Progress().IsZero())
+	cancel()
+	assert.NoError(t, <-done)
+}
@@ -0,0 +1,140 @@
+//go:build sdjournal
+
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package journal
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/coreos/go-systemd/v22/sdjournal"
+)
+
+// SystemReader reads matching entries from the systemd journal of the host.
+type SystemReader struct {
+	journal *sdjournal.Journal
+}
+
+// NewSystemReader opens the journal of the host for the entries of libvirt
+// and the domain processes, positioned at its end so that only new entries
+// are read.
+func NewSystemReader() (Reader, error) {
+	return openReader(func(journal *sdjournal.Journal) error {
+		// Matches of the same field are or'ed, the disjunction adds the
+		// slice of the domain processes as an alternative to the units.
+		for _, unit := range Units {
+			if err := journal.AddMatch(fieldUnit + "=" + unit); err != nil {
+				return err
+			}
+		}
+		if err := journal.AddDisjunction(); err != nil {
+			return err
+		}
+		return journal.AddMatch(fieldSlice + "=" + MachineSlice)
+	})
+}
+
+// NewUpdateReader opens the journal of the host for the entries of the
+// operating system update, positioned at its end.
+func NewUpdateReader() (Reader, error) {
+	return openReader(func(journal *sdjournal.Journal) error {
+		for _, identifier := range UpdateIdentifiers {
+			if err := journal.AddMatch(fieldIdentifier + "=" + identifier); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func openReader(match func(*sdjournal.Journal) error) (Reader, error) {
+	journal, err := sdjournal.NewJournalFromDir(Directory)
+	if err != nil {
+		return nil, err
+	}
+	r := &SystemReader{journal: journal}
+	if err := r.seek(match); err != nil {
+		journal.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *SystemReader) seek(match func(*sdjournal.Journal) error) error {
+	if err := match(r.journal); err != nil {
+		return err
+	}
+	if err := r.journal.SeekTail(); err != nil {
+		return err
+	}
+	// Step back onto the last entry, so that the next entry is a new one.
+	_, err := r.journal.Previous()
+	return err
+}
+
+// Next blocks until the next entry is appended to the journal, or until the
+// context is done.
+func (r *SystemReader) Next(ctx context.Context) (Entry, error) {
+	for {
+		n, err := r.journal.Next()
+		if err != nil {
+			return Entry{}, err
+		}
+		if n > 0 {
+			raw, err := r.journal.GetEntry()
+			if err != nil {
+				return Entry{}, err
+			}
+			return NewEntry(raw.Fields, raw.RealtimeTimestamp), nil
+		}
+		if err := ctx.Err(); err != nil {
+			return Entry{}, err
+		}
+		r.journal.Wait(time.Second)
+	}
+}
+
+// Close the journal.
+func (r *SystemReader) Close() error {
+	return r.journal.Close()
+}
+
+// Check that the journal of the host can be read, i.e. libsystemd is in the
+// image and the journal directory is mounted and readable. Journal files
+// without permission are skipped silently, so at least one entry has to be
+// read.
+func Check(_ context.Context) error {
+	journal, err := sdjournal.NewJournalFromDir(Directory)
+	if err != nil {
+		return err
+	}
+	defer journal.Close()
+	if err := journal.SeekTail(); err != nil {
+		return err
+	}
+	n, err := journal.Previous()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return errors.New("no journal entries readable, check the mounts and the permissions")
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+//go:build !sdjournal
+
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package journal
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrNotSupported is returned when the agent is built without the
+// sdjournal tag, reading the journal requires cgo and the libsystemd
+// headers.
+var ErrNotSupported = errors.New("built without journal support, build with -tags sdjournal")
+
+// NewSystemReader fails, the agent is built without journal support.
+func NewSystemReader() (Reader, error) {
+	return nil, ErrNotSupported
+}
+
+// NewUpdateReader fails, the agent is built without journal support.
+func NewUpdateReader() (Reader, error) {
+	return nil, ErrNotSupported
+}
+
+// Check fails, the agent is built without journal support.
+func Check(_ context.Context) error {
+	return ErrNotSupported
+}
@@ -0,0 +1,245 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kernel
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Flags which may precede the cpu list of the isolcpus parameter.
+var isolcpusFlags = []string{"nohz", "domain", "managed_irq"}
+
+// Value returns the value of the last occurrence of the parameter on the
+// command line, which is the one the kernel applies.
+func (p Parameters) Value(name string) (string, bool) {
+	var value string
+	var found bool
+	for _, param := range strings.Fields(p.CommandLine) {
+		if v, ok := strings.CutPrefix(param, name+"="); ok {
+			value, found = v, true
+		}
+	}
+	return value, found
+}
+
+// ParseCPUList parses a kernel cpu list like "0-3,8,10-31:2/4" into the
+// sorted cpu ids.
+func ParseCPUList(list string) ([]int, error) {
+	var cpus []int
+	for _, group := range strings.Split(list, ",") {
+		if group == "" {
+			continue
+		}
+		rng, stride, hasStride := strings.Cut(group, ":")
+		first, last, isRange := strings.Cut(rng, "-")
+		start, err := strconv.Atoi(first)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cpu list %q: %w", list, err)
+		}
+		end := start
+		if isRange {
+			if end, err = strconv.Atoi(last); err != nil {
+				return nil, fmt.Errorf("invalid cpu list %q: %w", list, err)
+			}
+		}
+		if end < start {
+			return nil, fmt.Errorf("invalid cpu list %q: range %s is reversed", list, rng)
+		}
+		used, size := 1, 1
+		if hasStride {
+			usedStr, sizeStr, ok := strings.Cut(stride, "/")
+			if !ok || !isRange {
+				return nil, fmt.Errorf("invalid cpu list %q: invalid group %s", list, group)
+			}
+			used, err = strconv.Atoi(usedStr)
+			if err == nil {
+				size, err = strconv.Atoi(sizeStr)
+			}
+			if err != nil || used < 1 || size < used {
+				return nil, fmt.Errorf("invalid cpu list %q: invalid group %s", list, group)
+			}
+		}
+		for cpu := start; cpu <= end; cpu++ {
+			if (cpu-start)%size < used {
+				cpus = append(cpus, cpu)
+			}
+		}
+	}
+	slices.Sort(cpus)
+	return slices.Compact(cpus), nil
+}
+
+// FormatCPUList formats sorted cpu ids as a kernel cpu list like "0-3,8".
+func FormatCPUList(cpus []int) string {
+	var groups []string
+	for i := 0; i < len(cpus); {
+		j := i
+		for j+1 < len(cpus) && cpus[j+1] == cpus[j]+1 {
+			j++
+		}
+		if i == j {
+			groups = append(groups, strconv.Itoa(cpus[i]))
+		} else {
+			groups = append(groups, fmt.Sprintf("%d-%d", cpus[i], cpus[j]))
+		}
+		i = j + 1
+	}
+	return strings.Join(groups, ",")
+}
+
+// Isolation holds the cpus isolated from system tasks by the kernel
+// command line.
+type Isolation struct {
+	// Cpus removed from the scheduler domains by isolcpus, which only run
+	// tasks pinned to them, e.g. the vcpus of domains with dedicated cpus.
+	Isolated []int
+	// Cpus without scheduling-clock ticks by nohz_full.
+	NoHZFull []int
+	// Cpus whose rcu callbacks are offloaded by rcu_nocbs.
+	RCUNoCBs []int
+}
+
+// Isolation parses the isolcpus, nohz_full and rcu_nocbs parameters.
+func (p Parameters) Isolation() (*Isolation, error) {
+	var isolation Isolation
+	var err error
+	if value, ok := p.Value("isolcpus"); ok {
+		// Strip the flags, e.g. "domain,managed_irq,2-31".
+		groups := strings.Split(value, ",")
+		for len(groups) > 0 && slices.Contains(isolcpusFlags, groups[0]) {
+			groups = groups[1:]
+		}
+		if isolation.Isolated, err = ParseCPUList(strings.Join(groups, ",")); err != nil {
+			return nil, fmt.Errorf("isolcpus: %w", err)
+		}
+	}
+	if value, ok := p.Value("nohz_full"); ok {
+		if isolation.NoHZFull, err = ParseCPUList(value); err != nil {
+			return nil, fmt.Errorf("nohz_full: %w", err)
+		}
+	}
+	if value, ok := p.Value("rcu_nocbs"); ok {
+		if isolation.RCUNoCBs, err = ParseCPUList(value); err != nil {
+			return nil, fmt.Errorf("rcu_nocbs: %w", err)
+		}
+	}
+	return &isolation, nil
+}
+
+// CellLayout is the split of the cpus of a numa cell.
+type CellLayout struct {
+	ID uint64
+	// Isolated cpus, reserved for the vcpus of domains.
+	Dedicated []int
+	// Cpus running the system tasks, e.g. the agent, libvirt and the
+	// emulator threads.
+	Housekeeping []int
+}
+
+// Layout is the split of the host cpus into dedicated and housekeeping cpus.
+type Layout struct {
+	Cells []CellLayout
+	// Isolated cpus which don't exist on the host.
+	Unknown []int
+	// Isolated cpus which still get scheduling-clock ticks, only reported
+	// if nohz_full is used at all.
+	WithoutNoHZ []int
+	// Isolated cpus which still run rcu callbacks, only reported if
+	// rcu_nocbs is used at all.
+	WithoutRCUNoCBs []int
+}
+
+// Layout cross-references the isolation with the cpus of the numa cells of
+// the host, by cell id.
+func (i Isolation) Layout(cells map[uint64][]int) Layout {
+	var layout Layout
+	host := map[int]bool{}
+	ids := make([]uint64, 0, len(cells))
+	for id := range cells {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	for _, id := range ids {
+		cell := CellLayout{ID: id}
+		cpus := slices.Clone(cells[id])
+		slices.Sort(cpus)
+		for _, cpu := range cpus {
+			host[cpu] = true
+			if slices.Contains(i.Isolated, cpu) {
+				cell.Dedicated = append(cell.Dedicated, cpu)
+			} else {
+				cell.Housekeeping = append(cell.Housekeeping, cpu)
+			}
+		}
+		layout.Cells = append(layout.Cells, cell)
+	}
+	for _, cpu := range i.Isolated {
+		if !host[cpu] {
+			layout.Unknown = append(layout.Unknown, cpu)
+			continue
+		}
+		if len(i.NoHZFull) > 0 && !slices.Contains(i.NoHZFull, cpu) {
+			layout.WithoutNoHZ = append(layout.WithoutNoHZ, cpu)
+		}
+		if len(i.RCUNoCBs) > 0 && !slices.Contains(i.RCUNoCBs, cpu) {
+			layout.WithoutRCUNoCBs = append(layout.WithoutRCUNoCBs, cpu)
+		}
+	}
+	return layout
+}
+
+// Dedicated returns the dedicated cpus of all cells.
+func (l Layout) Dedicated() []int {
+	var cpus []int
+	for _, cell := range l.Cells {
+		cpus = append(cpus, cell.Dedicated...)
+	}
+	slices.Sort(cpus)
+	return cpus
+}
+
+// Housekeeping returns the housekeeping cpus of all cells.
+func (l Layout) Housekeeping() []int {
+	var cpus []int
+	for _, cell := range l.Cells {
+		cpus = append(cpus, cell.Housekeeping...)
+	}
+	slices.Sort(cpus)
+	return cpus
+}
+
+// Summary of the layout for humans, e.g. "dedicated 2-15,18-31 (cell 0:
+// 2-15, cell 1: 18-31), housekeeping 0-1,16-17".
+func (l Layout) String() string {
+	var cells []string
+	for _, cell := range l.Cells {
+		if len(cell.Dedicated) > 0 {
+			cells = append(cells, fmt.Sprintf("cell %d: %s", cell.ID, FormatCPUList(cell.Dedicated)))
+		}
+	}
+	dedicated := FormatCPUList(l.Dedicated())
+	if dedicated == "" {
+		dedicated = "none"
+	} else if len(l.Cells) > 1 {
+		dedicated += " (" + strings.Join(cells, ", ") + ")"
+	}
+	return fmt.Sprintf("dedicated %s, housekeeping %s", dedicated, FormatCPUList(l.Housekeeping()))
+}
@@ -0,0 +1,97 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kernel
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCPUList(t *testing.T) {
+	tests := []struct {
+		list     string
+		expected []int
+	}{
+		{"", nil},
+		{"3", []int{3}},
+		{"0-3,8", []int{0, 1, 2, 3, 8}},
+		{"8,0-1,1", []int{0, 1, 8}},
+		{"0-7:2/4", []int{0, 1, 4, 5}},
+	}
+	for _, tt := range tests {
+		cpus, err := ParseCPUList(tt.list)
+		require.NoError(t, err, tt.list)
+		assert.Equal(t, tt.expected, cpus, tt.list)
+	}
+
+	for _, list := range []string{"a", "3-1", "1-x", "3:1/2", "0-7:3/2", "0-7:1"} {
+		_, err := ParseCPUList(list)
+		assert.Error(t, err, list)
+	}
+}
+
+func TestFormatCPUList(t *testing.T) {
+	assert.Equal(t, "", FormatCPUList(nil))
+	assert.Equal(t, "0-3,8,10-11", FormatCPUList([]int{0, 1, 2, 3, 8, 10, 11}))
+}
+
+func TestIsolation(t *testing.T) {
+	params := Parameters{
+		CommandLine: "console=tty0 isolcpus=1 isolcpus=domain,managed_irq,2-5,10-13 nohz_full=2-5 rcu_nocbs=2-5,10-13",
+	}
+	value, ok := params.Value("isolcpus")
+	assert.True(t, ok)
+	assert.Equal(t, "domain,managed_irq,2-5,10-13", value)
+
+	isolation, err := params.Isolation()
+	require.NoError(t, err)
+	assert.Equal(t, []int{2, 3, 4, 5, 10, 11, 12, 13}, isolation.Isolated)
+	assert.Equal(t, []int{2, 3, 4, 5}, isolation.NoHZFull)
+
+	isolation, err = Parameters{CommandLine: "quiet"}.Isolation()
+	require.NoError(t, err)
+	assert.Empty(t, isolation.Isolated)
+
+	_, err = Parameters{CommandLine: "nohz_full=x"}.Isolation()
+	assert.ErrorContains(t, err, "nohz_full")
+}
+
+func TestLayout(t *testing.T) {
+	isolation := Isolation{
+		Isolated: []int{2, 3, 4, 5, 10, 11, 12, 13, 64},
+		NoHZFull: []int{2, 3, 4, 5},
+	}
+	layout := isolation.Layout(map[uint64][]int{
+		1: {8, 9, 10, 11, 12, 13, 14, 15},
+		0: {0, 1, 2, 3, 4, 5, 6, 7},
+	})
+	assert.Equal(t, []CellLayout{
+		{ID: 0, Dedicated: []int{2, 3, 4, 5}, Housekeeping: []int{0, 1, 6, 7}},
+		{ID: 1, Dedicated: []int{10, 11, 12, 13}, Housekeeping: []int{8, 9, 14, 15}},
+	}, layout.Cells)
+	assert.Equal(t, []int{64}, layout.Unknown)
+	assert.Equal(t, []int{10, 11, 12, 13}, layout.WithoutNoHZ)
+	assert.Empty(t, layout.WithoutRCUNoCBs)
+	assert.Equal(t, "dedicated 2-5,10-13 (cell 0: 2-5, cell 1: 10-13), housekeeping 0-1,6-9,14-15",
+		layout.String())
+
+	layout = Isolation{}.Layout(map[uint64][]int{0: {0, 1}})
+	assert.Equal(t, "dedicated none, housekeeping 0-1", layout.String())
+}
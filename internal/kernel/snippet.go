@@ -0,0 +1,138 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kernel
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+const (
+	// Prefix of the hypervisor annotations holding the desired value of a
+	// managed kernel parameter, e.g. "kernel.kvm.cloud.sap/isolcpus".
+	AnnotationPrefix = "kernel.kvm.cloud.sap/"
+
+	// Default path of the command line snippet picked up when the boot
+	// entries are generated.
+	DefaultSnippetPath = "/etc/kernel/cmdline.d/90-kvm-node-agent.cfg"
+)
+
+// Kernel parameters which can be managed through annotations, in the order
+// they are written to the command line. The huge page size has to precede
+// the number of huge pages.
+var ManagedParameters = []string{
+	"default_hugepagesz",
+	"hugepagesz",
+	"hugepages",
+	"isolcpus",
+	"iommu",
+	"intel_iommu",
+	"amd_iommu",
+}
+
+// Allowed kernel parameters, key[=value]. The snippet is sourced by a
+// shell, so quotes, $, backticks, whitespace and newlines are rejected.
+var parameterPattern = regexp.MustCompile(`^[A-Za-z0-9_.-]+(=[A-Za-z0-9_.,:/+@-]+)?$`)
+
+// ValidateParameter checks that the kernel parameter can be written into
+// the command line snippet safely, e.g. "isolcpus=2-31".
+func ValidateParameter(param string) error {
+	if !parameterPattern.MatchString(param) {
+		return fmt.Errorf("invalid kernel parameter %q", param)
+	}
+	return nil
+}
+
+// DesiredParameters returns the managed kernel parameters requested by the
+// annotations of the hypervisor, e.g. ["isolcpus=2-31"]. Returns an error
+// if any of them is invalid.
+func DesiredParameters(annotations map[string]string) ([]string, error) {
+	var params []string
+	var errs []error
+	for _, name := range ManagedParameters {
+		if value, ok := annotations[AnnotationPrefix+name]; ok && value != "" {
+			param := name + "=" + value
+			if err := ValidateParameter(param); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			params = append(params, param)
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return params, nil
+}
+
+// Missing returns the parameters which are not part of the command line
+// the kernel was booted with.
+func (p Parameters) Missing(params []string) []string {
+	live := strings.Fields(p.CommandLine)
+	var missing []string
+	for _, param := range params {
+		if !slices.Contains(live, param) {
+			missing = append(missing, param)
+		}
+	}
+	return missing
+}
+
+// Write the parameters into the command line snippet, or remove the snippet
+// if there are none. The file is only rewritten if its content changed.
+// Returns whether the snippet was changed. Invalid parameters are rejected
+// without touching the snippet.
+func WriteSnippet(path string, params []string) (bool, error) {
+	for _, param := range params {
+		if err := ValidateParameter(param); err != nil {
+			return false, err
+		}
+	}
+	if len(params) == 0 {
+		err := os.Remove(path)
+		if errors.Is(err, os.ErrNotExist) {
+			return false, nil
+		}
+		return err == nil, err
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("# Generated by kvm-node-agent, do not edit.\n")
+	fmt.Fprintf(&buf, "CMDLINE_LINUX=\"$CMDLINE_LINUX %s\"\n", strings.Join(params, " "))
+
+	if current, err := os.ReadFile(path); err == nil && bytes.Equal(current, buf.Bytes()) {
+		return false, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return false, fmt.Errorf("failed to create directory %s: %w", filepath.Dir(path), err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0644); err != nil {
+		return false, fmt.Errorf("failed to write kernel command line snippet %s: %w", tmp, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return false, fmt.Errorf("failed to rename kernel command line snippet %s: %w", tmp, err)
+	}
+	return true, nil
+}
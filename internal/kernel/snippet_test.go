@@ -0,0 +1,102 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kernel
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDesiredParameters(t *testing.T) {
+	params, err := DesiredParameters(map[string]string{
+		AnnotationPrefix + "hugepages":   "1024",
+		AnnotationPrefix + "hugepagesz":  "1G",
+		AnnotationPrefix + "isolcpus":    "2-31",
+		AnnotationPrefix + "intel_iommu": "",
+		AnnotationPrefix + "quiet":       "true",
+		"unrelated":                      "value",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"hugepagesz=1G", "hugepages=1024", "isolcpus=2-31"}, params)
+
+	params, err = DesiredParameters(nil)
+	require.NoError(t, err)
+	assert.Empty(t, params)
+
+	_, err = DesiredParameters(map[string]string{
+		AnnotationPrefix + "isolcpus": "2-31\"\nrm -rf /",
+	})
+	assert.Error(t, err)
+}
+
+func TestValidateParameter(t *testing.T) {
+	for _, param := range []string{"isolcpus=nohz,domain,2-31", "hugepagesz=1G", "iommu=pt", "quiet"} {
+		assert.NoError(t, ValidateParameter(param), param)
+	}
+	for _, param := range []string{
+		"", "isolcpus=", "isolcpus=$(reboot)", "isolcpus=`reboot`", "isolcpus=\"2-31\"",
+		"isolcpus=2-31 quiet", "isolcpus=2-31\nquiet", "isolcpus='2-31'",
+	} {
+		assert.Error(t, ValidateParameter(param), param)
+	}
+}
+
+func TestParametersMissing(t *testing.T) {
+	live := Parameters{CommandLine: "console=tty0 iommu=pt intel_iommu=on hugepagesz=2M hugepages=1024"}
+	assert.Empty(t, live.Missing([]string{"iommu=pt", "hugepages=1024"}))
+	assert.Equal(t, []string{"hugepagesz=1G", "isolcpus=2-31"},
+		live.Missing([]string{"hugepagesz=1G", "hugepages=1024", "isolcpus=2-31"}))
+}
+
+func TestWriteSnippet(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cmdline.d", "90-kvm-node-agent.cfg")
+
+	changed, err := WriteSnippet(path, []string{"hugepagesz=1G", "isolcpus=2-31"})
+	require.NoError(t, err)
+	assert.True(t, changed)
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "# Generated by kvm-node-agent, do not edit.\n"+
+		"CMDLINE_LINUX=\"$CMDLINE_LINUX hugepagesz=1G isolcpus=2-31\"\n", string(content))
+
+	// Unchanged parameters don't rewrite the snippet.
+	changed, err = WriteSnippet(path, []string{"hugepagesz=1G", "isolcpus=2-31"})
+	require.NoError(t, err)
+	assert.False(t, changed)
+
+	// Without parameters the snippet is removed.
+	changed, err = WriteSnippet(path, nil)
+	require.NoError(t, err)
+	assert.True(t, changed)
+	_, err = os.Stat(path)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+
+	changed, err = WriteSnippet(path, nil)
+	require.NoError(t, err)
+	assert.False(t, changed)
+
+	// Invalid parameters are not written.
+	_, err = WriteSnippet(path, []string{"isolcpus=$(reboot)"})
+	assert.Error(t, err)
+	_, err = os.Stat(path)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
@@ -0,0 +1,179 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package ksm applies the kernel samepage merging parameters of the host
+// requested by the annotations of the hypervisor, and reports how much
+// memory of the domains is deduplicated.
+package ksm
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/sysctl"
+)
+
+const (
+	// Prefix of the hypervisor annotations holding the desired value of a
+	// ksm parameter, e.g. "ksm.kvm.cloud.sap/run".
+	AnnotationPrefix = "ksm.kvm.cloud.sap/"
+
+	// Default directory of the ksm parameters and counters in sysfs.
+	DefaultRoot = "/sys/kernel/mm/ksm"
+)
+
+// Parameters of ksm which can be requested by annotations.
+var parameters = []string{
+	"max_page_sharing",
+	"merge_across_nodes",
+	"pages_to_scan",
+	"run",
+	"sleep_millisecs",
+	"use_zero_pages",
+}
+
+var (
+	pagesShared = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "host_ksm_pages_shared",
+		Help: "Number of deduplicated pages in use by ksm.",
+	})
+	pagesSharing = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "host_ksm_pages_sharing",
+		Help: "Number of pages sharing a deduplicated page, i.e. the pages saved.",
+	})
+	pagesUnshared = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "host_ksm_pages_unshared",
+		Help: "Number of pages unique but repeatedly checked for merging.",
+	})
+	fullScans = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "host_ksm_full_scans",
+		Help: "Number of times all mergeable areas have been scanned.",
+	})
+)
+
+func init() {
+	metrics.Registry.MustRegister(pagesShared, pagesSharing, pagesUnshared, fullScans)
+}
+
+// Stats are the counters of ksm.
+type Stats struct {
+	// Whether ksm is running.
+	Running       bool
+	PagesShared   int64
+	PagesSharing  int64
+	PagesUnshared int64
+	FullScans     int64
+}
+
+// Summary of the stats for humans, e.g. for condition messages.
+func (s Stats) String() string {
+	if !s.Running {
+		return "ksm is stopped"
+	}
+	return fmt.Sprintf("%d pages sharing %d deduplicated pages", s.PagesSharing, s.PagesShared)
+}
+
+// Export the counters of ksm as metrics.
+func UpdateMetrics(stats *Stats) {
+	if stats == nil {
+		return
+	}
+	pagesShared.Set(float64(stats.PagesShared))
+	pagesSharing.Set(float64(stats.PagesSharing))
+	pagesUnshared.Set(float64(stats.PagesUnshared))
+	fullScans.Set(float64(stats.FullScans))
+}
+
+// DesiredSettings returns the ksm parameters requested by the annotations
+// of the hypervisor, sorted by name. Annotations of unknown parameters are
+// skipped and reported in the error.
+func DesiredSettings(annotations map[string]string) ([]sysctl.Setting, error) {
+	var settings []sysctl.Setting
+	var errs []error
+	for key, value := range annotations {
+		name, ok := strings.CutPrefix(key, AnnotationPrefix)
+		if !ok {
+			continue
+		}
+		if !slices.Contains(parameters, name) {
+			errs = append(errs, fmt.Errorf("unknown ksm parameter %q", name))
+			continue
+		}
+		settings = append(settings, sysctl.Setting{Name: name, Value: value})
+	}
+	slices.SortFunc(settings, func(a, b sysctl.Setting) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return settings, errors.Join(errs...)
+}
+
+// Interface applies the ksm parameters and reads the ksm counters.
+type Interface interface {
+	// Apply writes the parameters whose value differs on the host, and
+	// verifies them by reading them back.
+	Apply(settings []sysctl.Setting) (*sysctl.Result, error)
+	// ReadStats reads the counters of ksm.
+	ReadStats() (*Stats, error)
+}
+
+// Manager applies the ksm parameters through sysfs. The parameters are
+// files without dots, which the sysctl manager handles just as well.
+type Manager struct {
+	*sysctl.Manager
+	root string
+}
+
+// NewManager returns a manager of the ksm directory at root.
+func NewManager(root string) *Manager {
+	return &Manager{Manager: sysctl.NewManager(root, parameters), root: root}
+}
+
+// ReadStats reads the counters of ksm.
+func (m *Manager) ReadStats() (*Stats, error) {
+	counters := map[string]*int64{
+		"pages_shared":   new(int64),
+		"pages_sharing":  new(int64),
+		"pages_unshared": new(int64),
+		"full_scans":     new(int64),
+		"run":            new(int64),
+	}
+	for name, value := range counters {
+		data, err := os.ReadFile(filepath.Join(m.root, name))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read ksm %s: %w", name, err)
+		}
+		if *value, err = strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64); err != nil {
+			return nil, fmt.Errorf("failed to parse ksm %s: %w", name, err)
+		}
+	}
+	return &Stats{
+		// 1 runs ksm, 0 stops it and 2 also unmerges the pages.
+		Running:       *counters["run"] == 1,
+		PagesShared:   *counters["pages_shared"],
+		PagesSharing:  *counters["pages_sharing"],
+		PagesUnshared: *counters["pages_unshared"],
+		FullScans:     *counters["full_scans"],
+	}, nil
+}
@@ -0,0 +1,105 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ksm
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/sysctl"
+)
+
+func TestDesiredSettings(t *testing.T) {
+	settings, err := DesiredSettings(map[string]string{
+		AnnotationPrefix + "run":             "1",
+		AnnotationPrefix + "sleep_millisecs": "20",
+		"sysctl.kvm.cloud.sap/vm.swappiness": "10",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []sysctl.Setting{
+		{Name: "run", Value: "1"},
+		{Name: "sleep_millisecs", Value: "20"},
+	}, settings)
+
+	settings, err = DesiredSettings(map[string]string{
+		AnnotationPrefix + "pages_shared":  "0",
+		AnnotationPrefix + "pages_to_scan": "1000",
+	})
+	assert.ErrorContains(t, err, `unknown ksm parameter "pages_shared"`)
+	assert.Equal(t, []sysctl.Setting{{Name: "pages_to_scan", Value: "1000"}}, settings)
+}
+
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, value := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(root, name), []byte(value), 0644))
+	}
+}
+
+func TestApply(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{"run": "0\n", "pages_to_scan": "100\n"})
+
+	result, err := NewManager(root).Apply([]sysctl.Setting{
+		{Name: "run", Value: "1"},
+		{Name: "pages_to_scan", Value: "100"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []sysctl.Drift{
+		{Setting: sysctl.Setting{Name: "run", Value: "1"}, Found: "0"},
+	}, result.Corrected)
+
+	data, err := os.ReadFile(filepath.Join(root, "run"))
+	require.NoError(t, err)
+	assert.Equal(t, "1\n", string(data))
+}
+
+func TestReadStats(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"run":            "1\n",
+		"pages_shared":   "256\n",
+		"pages_sharing":  "1024\n",
+		"pages_unshared": "4096\n",
+		"full_scans":     "12\n",
+	})
+
+	stats, err := NewManager(root).ReadStats()
+	require.NoError(t, err)
+	assert.Equal(t, &Stats{
+		Running:       true,
+		PagesShared:   256,
+		PagesSharing:  1024,
+		PagesUnshared: 4096,
+		FullScans:     12,
+	}, stats)
+	assert.Equal(t, "1024 pages sharing 256 deduplicated pages", stats.String())
+
+	writeFiles(t, root, map[string]string{"run": "2\n"})
+	stats, err = NewManager(root).ReadStats()
+	require.NoError(t, err)
+	assert.Equal(t, "ksm is stopped", stats.String())
+
+	require.NoError(t, os.Remove(filepath.Join(root, "full_scans")))
+	_, err = NewManager(root).ReadStats()
+	assert.ErrorContains(t, err, "failed to read ksm full_scans")
+}
@@ -0,0 +1,103 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package libvirt
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/digitalocean/go-libvirt"
+
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/libvirt/dominfo"
+)
+
+// CPUBaseliner computes the cpu model a set of hosts can all provide, so
+// that domains with this model can be live migrated between all of them.
+type CPUBaseliner interface {
+	// BaselineCPU returns the most capable cpu model and the features in
+	// addition to it which all the given host cpus of the architecture
+	// provide.
+	BaselineCPU(arch string, cpus []HostCPUModel) (HostCPUModel, error)
+}
+
+// Host cpu definition as libvirt expects it for the baseline computation,
+// which is the cpu of the host capabilities.
+type baselineInput struct {
+	XMLName  xml.Name                   `xml:"cpu"`
+	Arch     string                     `xml:"arch,omitempty"`
+	Model    string                     `xml:"model"`
+	Vendor   string                     `xml:"vendor,omitempty"`
+	Features []dominfo.DomainCPUFeature `xml:"feature"`
+}
+
+// Render the host cpus as the xml definitions libvirt expects.
+func baselineInputs(arch string, cpus []HostCPUModel) ([]string, error) {
+	inputs := make([]string, 0, len(cpus))
+	for _, cpu := range cpus {
+		input := baselineInput{Arch: arch, Model: cpu.Model, Vendor: cpu.Vendor}
+		for _, feature := range cpu.Features {
+			input.Features = append(input.Features, dominfo.DomainCPUFeature{Name: feature})
+		}
+		data, err := xml.Marshal(input)
+		if err != nil {
+			return nil, err
+		}
+		inputs = append(inputs, string(data))
+	}
+	return inputs, nil
+}
+
+// Parse the baseline cpu computed by libvirt, a custom cpu whose required
+// features are the ones in addition to the model.
+func parseBaselineCPU(desc string) (HostCPUModel, error) {
+	var cpu dominfo.DomainCPU
+	if err := xml.Unmarshal([]byte(desc), &cpu); err != nil {
+		return HostCPUModel{}, fmt.Errorf("failed to parse baseline cpu: %w", err)
+	}
+	if cpu.Model == nil || cpu.Model.Value == "" {
+		return HostCPUModel{}, errors.New("baseline cpu has no model")
+	}
+	baseline := HostCPUModel{Model: cpu.Model.Value, Vendor: cpu.Vendor}
+	for _, feature := range cpu.Features {
+		if feature.Policy == "" || feature.Policy == "require" {
+			baseline.Features = append(baseline.Features, feature.Name)
+		}
+	}
+	slices.Sort(baseline.Features)
+	return baseline, nil
+}
+
+// Compute the baseline cpu of the hosts for the hypervisor type of this
+// libvirt, restricted to the features which don't prevent migrations.
+func (l *LibVirt) BaselineCPU(arch string, cpus []HostCPUModel) (HostCPUModel, error) {
+	if len(cpus) == 0 {
+		return HostCPUModel{}, errors.New("no host cpus to compute a baseline of")
+	}
+	inputs, err := baselineInputs(arch, cpus)
+	if err != nil {
+		return HostCPUModel{}, err
+	}
+	desc, err := l.virt.ConnectBaselineHypervisorCPU(nil, libvirt.OptString{arch}, nil, nil, inputs,
+		uint32(libvirt.ConnectBaselineCPUMigratable))
+	if err != nil {
+		return HostCPUModel{}, fmt.Errorf("failed to compute baseline cpu: %w", err)
+	}
+	return parseBaselineCPU(desc)
+}
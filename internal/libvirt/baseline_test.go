@@ -0,0 +1,62 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package libvirt
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBaselineInputs(t *testing.T) {
+	inputs, err := baselineInputs("x86_64", []HostCPUModel{
+		{Model: "Skylake-Server-IBRS", Vendor: "Intel", Features: []string{"invtsc", "ss"}},
+	})
+	if err != nil {
+		t.Fatalf("baselineInputs() returned unexpected error: %v", err)
+	}
+	expected := []string{`<cpu><arch>x86_64</arch><model>Skylake-Server-IBRS</model><vendor>Intel</vendor>` +
+		`<feature name="invtsc"></feature><feature name="ss"></feature></cpu>`}
+	if !reflect.DeepEqual(inputs, expected) {
+		t.Errorf("Expected inputs %v, got %v", expected, inputs)
+	}
+}
+
+func TestParseBaselineCPU(t *testing.T) {
+	baseline, err := parseBaselineCPU(`<cpu mode='custom' match='exact'>
+  <model fallback='forbid'>Skylake-Server-IBRS</model>
+  <vendor>Intel</vendor>
+  <feature policy='require' name='ss'/>
+  <feature policy='disable' name='mpx'/>
+  <feature policy='require' name='hypervisor'/>
+</cpu>`)
+	if err != nil {
+		t.Fatalf("parseBaselineCPU() returned unexpected error: %v", err)
+	}
+	expected := HostCPUModel{
+		Model:    "Skylake-Server-IBRS",
+		Vendor:   "Intel",
+		Features: []string{"hypervisor", "ss"},
+	}
+	if !reflect.DeepEqual(baseline, expected) {
+		t.Errorf("Expected baseline %+v, got %+v", expected, baseline)
+	}
+
+	if _, err := parseBaselineCPU(`<cpu mode='custom'/>`); err == nil {
+		t.Error("Expected error for a baseline cpu without model")
+	}
+}
@@ -0,0 +1,213 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package libvirt
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/digitalocean/go-libvirt"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logger "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/cobaltcore-dev/kvm-node-agent/api/v1alpha1"
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/sys"
+)
+
+// Get the name of the type of a block job.
+func blockJobType(jobType int32) string {
+	switch libvirt.DomainBlockJobType(jobType) {
+	case libvirt.DomainBlockJobTypePull:
+		return "pull"
+	case libvirt.DomainBlockJobTypeCopy:
+		return "copy"
+	case libvirt.DomainBlockJobTypeCommit:
+		return "commit"
+	case libvirt.DomainBlockJobTypeActiveCommit:
+		return "active-commit"
+	case libvirt.DomainBlockJobTypeBackup:
+		return "backup"
+	}
+	return "unknown"
+}
+
+// A block job of a disk, with the source path of the disk the block job
+// events refer to.
+type trackedBlockJob struct {
+	job  v1alpha1.InstanceBlockJob
+	path string
+}
+
+// Build the block job of a disk from the block job info of libvirt.
+func newTrackedBlockJob(disk, path string, jobType int32, bandwidth, cur, end uint64) trackedBlockJob {
+	var progress int32
+	if end > 0 {
+		progress = int32(min(cur, end) * 100 / end)
+	}
+	return trackedBlockJob{
+		job: v1alpha1.InstanceBlockJob{
+			Disk:      disk,
+			Type:      blockJobType(jobType),
+			Progress:  progress,
+			Current:   int64(cur),
+			End:       int64(end),
+			Bandwidth: int64(bandwidth),
+		},
+		path: path,
+	}
+}
+
+// Tracks the block jobs running on the disks of the domains, by domain
+// uuid. The jobs are polled, as libvirt only reports when a job ends or
+// becomes ready. The zero value is ready to use.
+type blockJobTracker struct {
+	lock sync.Mutex
+	jobs map[string][]trackedBlockJob
+}
+
+// Replace the jobs of all domains with the polled ones. Jobs which became
+// ready before stay ready.
+func (t *blockJobTracker) update(jobs map[string][]trackedBlockJob) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	for uuid, domainJobs := range jobs {
+		for i := range domainJobs {
+			if previous, ok := t.find(uuid, domainJobs[i].job.Disk); ok && previous.job.Ready {
+				domainJobs[i].job.Ready = true
+			}
+		}
+	}
+	t.jobs = jobs
+}
+
+// Find the job of the disk given by its target or source path.
+func (t *blockJobTracker) find(uuid, disk string) (trackedBlockJob, bool) {
+	for _, job := range t.jobs[uuid] {
+		if job.job.Disk == disk || job.path == disk {
+			return job, true
+		}
+	}
+	return trackedBlockJob{}, false
+}
+
+// Record that the job of the disk given by its target or source path can
+// be pivoted.
+func (t *blockJobTracker) ready(uuid, disk string) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	for i, job := range t.jobs[uuid] {
+		if job.job.Disk == disk || job.path == disk {
+			t.jobs[uuid][i].job.Ready = true
+		}
+	}
+}
+
+// Remove the ended job of the disk given by its target or source path.
+// Returns the target of the disk if the job was known, or else the disk as
+// given.
+func (t *blockJobTracker) ended(uuid, disk string) string {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	job, ok := t.find(uuid, disk)
+	if !ok {
+		return disk
+	}
+	var remaining []trackedBlockJob
+	for _, other := range t.jobs[uuid] {
+		if other.job.Disk != job.job.Disk {
+			remaining = append(remaining, other)
+		}
+	}
+	t.jobs[uuid] = remaining
+	return job.job.Disk
+}
+
+// Get the block jobs of the domain sorted by disk, nil if none are running.
+func (t *blockJobTracker) status(uuid string) []v1alpha1.InstanceBlockJob {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	var jobs []v1alpha1.InstanceBlockJob
+	for _, job := range t.jobs[uuid] {
+		jobs = append(jobs, job.job)
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].Disk < jobs[j].Disk
+	})
+	return jobs
+}
+
+// Poll the block jobs of the disks of the active domains.
+func (l *LibVirt) pollBlockJobs(ctx context.Context, records []libvirt.DomainStatsRecord, at time.Time) {
+	log := logger.FromContext(ctx)
+	jobs := make(map[string][]trackedBlockJob)
+	for _, record := range records {
+		uuid := GetOpenstackUUID(record.Dom)
+		for disk, sample := range parseBlockStats(record.Params, at) {
+			found, jobType, bandwidth, cur, end, err := l.virt.DomainGetBlockJobInfo(
+				record.Dom, disk, uint32(libvirt.DomainBlockJobInfoBandwidthBytes))
+			if err != nil {
+				log.V(1).Info("unable to get block job info", "server", uuid, "disk", disk, "error", err.Error())
+				continue
+			}
+			if found == 0 {
+				continue
+			}
+			jobs[uuid] = append(jobs[uuid], newTrackedBlockJob(disk, sample.path, jobType, bandwidth, cur, end))
+		}
+	}
+	l.blockJobs.update(jobs)
+}
+
+// Record the end of a block job and report it in an event of the instance.
+func (l *LibVirt) onBlockJob(ctx context.Context, event any) {
+	e := event.(*libvirt.DomainEventCallbackBlockJobMsg)
+	uuid := GetOpenstackUUID(e.Msg.Dom)
+	jobType := blockJobType(e.Msg.Type)
+	log := logger.FromContext(ctx).WithValues("server", uuid, "disk", e.Msg.Path, "type", jobType)
+
+	var eventType, reason, result string
+	switch libvirt.ConnectDomainEventBlockJobStatus(e.Msg.Status) {
+	case libvirt.DomainBlockJobReady:
+		log.Info("block job ready")
+		l.blockJobs.ready(uuid, e.Msg.Path)
+		return
+	case libvirt.DomainBlockJobCompleted:
+		eventType, reason, result = corev1.EventTypeNormal, "BlockJobCompleted", "completed"
+	case libvirt.DomainBlockJobFailed:
+		eventType, reason, result = corev1.EventTypeWarning, "BlockJobFailed", "failed"
+	case libvirt.DomainBlockJobCanceled:
+		eventType, reason, result = corev1.EventTypeNormal, "BlockJobCanceled", "canceled"
+	default:
+		return
+	}
+	disk := l.blockJobs.ended(uuid, e.Msg.Path)
+	log.Info("block job ended", "result", result)
+	if l.recorder == nil {
+		return
+	}
+	var instance v1alpha1.Instance
+	if err := l.client.Get(ctx, client.ObjectKey{Name: uuid, Namespace: sys.Namespace}, &instance); err != nil {
+		log.Error(err, "failed to get instance of domain with block job")
+		return
+	}
+	l.recorder.Eventf(&instance, nil, eventType, reason, "BlockJob",
+		"%s job on disk %s %s", jobType, disk, result)
+}
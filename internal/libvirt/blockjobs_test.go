@@ -0,0 +1,89 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package libvirt
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/digitalocean/go-libvirt"
+
+	"github.com/cobaltcore-dev/kvm-node-agent/api/v1alpha1"
+)
+
+func TestNewTrackedBlockJob(t *testing.T) {
+	job := newTrackedBlockJob("vda", "/var/lib/nova/instances/a/disk",
+		int32(libvirt.DomainBlockJobTypeActiveCommit), 1<<20, 1<<30, 4<<30)
+	expected := v1alpha1.InstanceBlockJob{
+		Disk:      "vda",
+		Type:      "active-commit",
+		Progress:  25,
+		Current:   1 << 30,
+		End:       4 << 30,
+		Bandwidth: 1 << 20,
+	}
+	if !reflect.DeepEqual(job.job, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, job.job)
+	}
+	if job := newTrackedBlockJob("vda", "", 99, 0, 0, 0); job.job.Type != "unknown" || job.job.Progress != 0 {
+		t.Errorf("Unexpected job without progress %+v", job.job)
+	}
+}
+
+func TestBlockJobTracker(t *testing.T) {
+	var tracker blockJobTracker
+	if jobs := tracker.status("a"); jobs != nil {
+		t.Errorf("Expected no jobs of unknown domain, got %+v", jobs)
+	}
+
+	tracker.update(map[string][]trackedBlockJob{"a": {
+		newTrackedBlockJob("vdb", "/dev/sdb", int32(libvirt.DomainBlockJobTypeCopy), 0, 50, 100),
+		newTrackedBlockJob("vda", "/disk", int32(libvirt.DomainBlockJobTypeCommit), 0, 10, 100),
+	}})
+	jobs := tracker.status("a")
+	if len(jobs) != 2 || jobs[0].Disk != "vda" || jobs[1].Disk != "vdb" {
+		t.Fatalf("Expected the jobs sorted by disk, got %+v", jobs)
+	}
+
+	// Events refer to the disk by its source path.
+	tracker.ready("a", "/dev/sdb")
+	tracker.update(map[string][]trackedBlockJob{"a": {
+		newTrackedBlockJob("vdb", "/dev/sdb", int32(libvirt.DomainBlockJobTypeCopy), 0, 100, 100),
+		newTrackedBlockJob("vda", "/disk", int32(libvirt.DomainBlockJobTypeCommit), 0, 20, 100),
+	}})
+	jobs = tracker.status("a")
+	if !jobs[1].Ready || jobs[0].Ready {
+		t.Errorf("Expected only the copy to stay ready, got %+v", jobs)
+	}
+
+	if disk := tracker.ended("a", "/dev/sdb"); disk != "vdb" {
+		t.Errorf("Expected the target of the ended job, got %s", disk)
+	}
+	if jobs := tracker.status("a"); len(jobs) != 1 || jobs[0].Disk != "vda" {
+		t.Errorf("Expected the ended job to be removed, got %+v", jobs)
+	}
+	if disk := tracker.ended("a", "/unknown"); disk != "/unknown" {
+		t.Errorf("Expected the path of an unknown job, got %s", disk)
+	}
+
+	// Jobs of domains which are no longer active are dropped.
+	tracker.update(map[string][]trackedBlockJob{})
+	if jobs := tracker.status("a"); jobs != nil {
+		t.Errorf("Expected no jobs, got %+v", jobs)
+	}
+}
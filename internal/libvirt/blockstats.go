@@ -0,0 +1,262 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package libvirt
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/digitalocean/go-libvirt"
+	"github.com/prometheus/client_golang/prometheus"
+	logger "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// How often block device counters are sampled to compute rates.
+var blockStatsInterval = 1 * time.Minute
+
+// Identifies a block device of a domain across sampling cycles.
+type blockDeviceKey struct {
+	domain string
+	device string
+}
+
+// Cumulative block device counters of a domain disk at a point in time,
+// as reported by the libvirt domain stats api (block.<n>.* fields).
+type blockStatsSample struct {
+	at      time.Time
+	rdReqs  uint64
+	wrReqs  uint64
+	rdBytes uint64
+	wrBytes uint64
+	// Total time spent on read/write requests in nanoseconds.
+	rdTimes uint64
+	wrTimes uint64
+	// Bytes allocated on the host and the size seen by the guest.
+	allocation uint64
+	capacity   uint64
+	// Source path of file backed disks.
+	path string
+}
+
+// Rates of a block device computed from two consecutive samples.
+type blockRates struct {
+	readIOPS         float64
+	writeIOPS        float64
+	readBytesPerSec  float64
+	writeBytesPerSec float64
+	readLatency      float64 // seconds per request
+	writeLatency     float64 // seconds per request
+}
+
+// Convert a typed parameter value to uint64. Libvirt reports block
+// counters as ullong, but we stay lenient towards other integer types.
+func typedParamUint64(v any) (uint64, bool) {
+	switch n := v.(type) {
+	case uint64:
+		return n, true
+	case int64:
+		if n < 0 {
+			return 0, false
+		}
+		return uint64(n), true
+	case uint32:
+		return uint64(n), true
+	case int32:
+		if n < 0 {
+			return 0, false
+		}
+		return uint64(n), true
+	}
+	return 0, false
+}
+
+// Parse the block.<n>.* typed parameters of a domain stats record into
+// samples keyed by the device name (e.g. "vda").
+func parseBlockStats(params []libvirt.TypedParam, at time.Time) map[string]blockStatsSample {
+	names := make(map[int]string)
+	paths := make(map[int]string)
+	samples := make(map[int]blockStatsSample)
+	for _, param := range params {
+		// Format: block.<index>.<field...>
+		parts := strings.SplitN(param.Field, ".", 3)
+		if len(parts) != 3 || parts[0] != "block" {
+			continue
+		}
+		idx, err := strconv.Atoi(parts[1])
+		if err != nil {
+			continue
+		}
+		switch parts[2] {
+		case "name":
+			if name, ok := param.Value.I.(string); ok {
+				names[idx] = name
+			}
+			continue
+		case "path":
+			if path, ok := param.Value.I.(string); ok {
+				paths[idx] = path
+			}
+			continue
+		}
+		value, ok := typedParamUint64(param.Value.I)
+		if !ok {
+			continue
+		}
+		sample := samples[idx]
+		sample.at = at
+		switch parts[2] {
+		case "rd.reqs":
+			sample.rdReqs = value
+		case "wr.reqs":
+			sample.wrReqs = value
+		case "rd.bytes":
+			sample.rdBytes = value
+		case "wr.bytes":
+			sample.wrBytes = value
+		case "rd.times":
+			sample.rdTimes = value
+		case "wr.times":
+			sample.wrTimes = value
+		case "allocation":
+			sample.allocation = value
+		case "capacity":
+			sample.capacity = value
+		default:
+			continue
+		}
+		samples[idx] = sample
+	}
+	byName := make(map[string]blockStatsSample, len(samples))
+	for idx, sample := range samples {
+		name, ok := names[idx]
+		if !ok {
+			name = "block" + strconv.Itoa(idx)
+		}
+		sample.path = paths[idx]
+		byName[name] = sample
+	}
+	return byName
+}
+
+// Compute the rates between two samples of the same block device. Returns
+// false if no meaningful rate can be computed, e.g. because the counters
+// were reset by a domain restart.
+func computeBlockRates(prev, cur blockStatsSample) (blockRates, bool) {
+	elapsed := cur.at.Sub(prev.at).Seconds()
+	if elapsed <= 0 {
+		return blockRates{}, false
+	}
+	if cur.rdReqs < prev.rdReqs || cur.wrReqs < prev.wrReqs ||
+		cur.rdBytes < prev.rdBytes || cur.wrBytes < prev.wrBytes ||
+		cur.rdTimes < prev.rdTimes || cur.wrTimes < prev.wrTimes {
+		return blockRates{}, false
+	}
+	rdReqs := float64(cur.rdReqs - prev.rdReqs)
+	wrReqs := float64(cur.wrReqs - prev.wrReqs)
+	rates := blockRates{
+		readIOPS:         rdReqs / elapsed,
+		writeIOPS:        wrReqs / elapsed,
+		readBytesPerSec:  float64(cur.rdBytes-prev.rdBytes) / elapsed,
+		writeBytesPerSec: float64(cur.wrBytes-prev.wrBytes) / elapsed,
+	}
+	if rdReqs > 0 {
+		rates.readLatency = float64(cur.rdTimes-prev.rdTimes) / rdReqs / float64(time.Second)
+	}
+	if wrReqs > 0 {
+		rates.writeLatency = float64(cur.wrTimes-prev.wrTimes) / wrReqs / float64(time.Second)
+	}
+	return rates, true
+}
+
+// Update the previous samples with the given domain stats records and
+// export the resulting rates. Devices that disappeared since the last
+// cycle are forgotten and their metrics removed.
+func updateBlockStats(
+	samples map[blockDeviceKey]blockStatsSample,
+	records []libvirt.DomainStatsRecord,
+	at time.Time,
+) {
+
+	seen := make(map[blockDeviceKey]struct{})
+	for _, record := range records {
+		domain := GetOpenstackUUID(record.Dom)
+		for device, cur := range parseBlockStats(record.Params, at) {
+			key := blockDeviceKey{domain: domain, device: device}
+			seen[key] = struct{}{}
+			blockAllocation.WithLabelValues(domain, device).Set(float64(cur.allocation))
+			blockCapacity.WithLabelValues(domain, device).Set(float64(cur.capacity))
+			if prev, ok := samples[key]; ok {
+				if rates, valid := computeBlockRates(prev, cur); valid {
+					blockReadIOPS.WithLabelValues(domain, device).Set(rates.readIOPS)
+					blockWriteIOPS.WithLabelValues(domain, device).Set(rates.writeIOPS)
+					blockReadBytes.WithLabelValues(domain, device).Set(rates.readBytesPerSec)
+					blockWriteBytes.WithLabelValues(domain, device).Set(rates.writeBytesPerSec)
+					blockReadLatency.WithLabelValues(domain, device).Set(rates.readLatency)
+					blockWriteLatency.WithLabelValues(domain, device).Set(rates.writeLatency)
+				}
+			}
+			samples[key] = cur
+		}
+	}
+	for key := range samples {
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		delete(samples, key)
+		labels := prometheus.Labels{"domain": key.domain, "device": key.device}
+		blockReadIOPS.Delete(labels)
+		blockWriteIOPS.Delete(labels)
+		blockReadBytes.Delete(labels)
+		blockWriteBytes.Delete(labels)
+		blockReadLatency.Delete(labels)
+		blockWriteLatency.Delete(labels)
+		blockAllocation.Delete(labels)
+		blockCapacity.Delete(labels)
+	}
+}
+
+// Periodically sample the block device and vcpu counters of all active
+// domains and export per-device IOPS, throughput and latency as well as
+// the cpu steal per domain computed from the deltas between two sampling
+// cycles.
+func (l *LibVirt) runBlockStatsLoop(ctx context.Context, i eventloopRunnable) {
+	log := logger.FromContext(ctx, "libvirt", "block-stats")
+	samples := make(map[blockDeviceKey]blockStatsSample)
+	stealSamples := make(map[string]cpuStealSample)
+	ticker := time.NewTicker(blockStatsInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-i.Disconnected():
+			return
+		case <-ticker.C:
+			records, at, err := l.domainStats()
+			if err != nil {
+				log.Error(err, "failed to fetch domain block stats")
+				continue
+			}
+			updateBlockStats(samples, records, at)
+			updateCPUSteal(stealSamples, records, at)
+			l.pollBlockJobs(ctx, records, at)
+		}
+	}
+}
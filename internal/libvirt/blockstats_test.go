@@ -0,0 +1,165 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package libvirt
+
+import (
+	"testing"
+	"time"
+
+	libvirt "github.com/digitalocean/go-libvirt"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func blockParam(field string, value any) libvirt.TypedParam {
+	return libvirt.TypedParam{Field: field, Value: libvirt.TypedParamValue{I: value}}
+}
+
+func TestParseBlockStats(t *testing.T) {
+	now := time.Now()
+	params := []libvirt.TypedParam{
+		blockParam("block.count", uint32(2)),
+		blockParam("block.0.name", "vda"),
+		blockParam("block.0.rd.reqs", uint64(100)),
+		blockParam("block.0.wr.reqs", uint64(50)),
+		blockParam("block.0.rd.bytes", uint64(4096)),
+		blockParam("block.0.wr.bytes", uint64(2048)),
+		blockParam("block.0.rd.times", uint64(1000000)),
+		blockParam("block.0.wr.times", uint64(2000000)),
+		blockParam("block.1.rd.reqs", uint64(7)),
+		blockParam("vcpu.0.state", int32(1)),
+	}
+	samples := parseBlockStats(params, now)
+	if len(samples) != 2 {
+		t.Fatalf("Expected 2 samples, got %d", len(samples))
+	}
+	vda, ok := samples["vda"]
+	if !ok {
+		t.Fatal("Expected sample for vda")
+	}
+	if vda.rdReqs != 100 || vda.wrReqs != 50 {
+		t.Errorf("Unexpected request counters: %+v", vda)
+	}
+	if vda.rdBytes != 4096 || vda.wrBytes != 2048 {
+		t.Errorf("Unexpected byte counters: %+v", vda)
+	}
+	if vda.rdTimes != 1000000 || vda.wrTimes != 2000000 {
+		t.Errorf("Unexpected time counters: %+v", vda)
+	}
+	if !vda.at.Equal(now) {
+		t.Errorf("Expected sample time %v, got %v", now, vda.at)
+	}
+	// Devices without a name fall back to their index.
+	if unnamed, ok := samples["block1"]; !ok || unnamed.rdReqs != 7 {
+		t.Errorf("Expected fallback sample block1, got %+v", samples)
+	}
+}
+
+func TestComputeBlockRates(t *testing.T) {
+	start := time.Now()
+	prev := blockStatsSample{
+		at:      start,
+		rdReqs:  100,
+		wrReqs:  100,
+		rdBytes: 1000,
+		wrBytes: 1000,
+		rdTimes: 0,
+		wrTimes: 0,
+	}
+	cur := blockStatsSample{
+		at:      start.Add(10 * time.Second),
+		rdReqs:  200,
+		wrReqs:  100,
+		rdBytes: 11000,
+		wrBytes: 1000,
+		// 100 reads taking 200ms in total -> 2ms per read.
+		rdTimes: uint64(200 * time.Millisecond),
+		wrTimes: 0,
+	}
+	rates, ok := computeBlockRates(prev, cur)
+	if !ok {
+		t.Fatal("Expected rates to be computed")
+	}
+	if rates.readIOPS != 10 {
+		t.Errorf("Expected read IOPS 10, got %v", rates.readIOPS)
+	}
+	if rates.writeIOPS != 0 {
+		t.Errorf("Expected write IOPS 0, got %v", rates.writeIOPS)
+	}
+	if rates.readBytesPerSec != 1000 {
+		t.Errorf("Expected read throughput 1000, got %v", rates.readBytesPerSec)
+	}
+	if rates.readLatency != 0.002 {
+		t.Errorf("Expected read latency 0.002, got %v", rates.readLatency)
+	}
+	if rates.writeLatency != 0 {
+		t.Errorf("Expected write latency 0 without writes, got %v", rates.writeLatency)
+	}
+}
+
+func TestComputeBlockRates_CounterReset(t *testing.T) {
+	start := time.Now()
+	prev := blockStatsSample{at: start, rdReqs: 100}
+	cur := blockStatsSample{at: start.Add(time.Minute), rdReqs: 10}
+	if _, ok := computeBlockRates(prev, cur); ok {
+		t.Error("Expected no rates after a counter reset")
+	}
+}
+
+func TestComputeBlockRates_NoElapsedTime(t *testing.T) {
+	now := time.Now()
+	if _, ok := computeBlockRates(blockStatsSample{at: now}, blockStatsSample{at: now}); ok {
+		t.Error("Expected no rates without elapsed time")
+	}
+}
+
+func TestUpdateBlockStats_RemovesStaleDevices(t *testing.T) {
+	dom := libvirt.Domain{Name: "instance-1", UUID: libvirt.UUID{1}}
+	domain := GetOpenstackUUID(dom)
+	record := func(reqs uint64) libvirt.DomainStatsRecord {
+		return libvirt.DomainStatsRecord{
+			Dom: dom,
+			Params: []libvirt.TypedParam{
+				blockParam("block.0.name", "vda"),
+				blockParam("block.0.rd.reqs", reqs),
+			},
+		}
+	}
+	samples := make(map[blockDeviceKey]blockStatsSample)
+	start := time.Now()
+
+	// The first cycle only records the baseline.
+	updateBlockStats(samples, []libvirt.DomainStatsRecord{record(0)}, start)
+	if len(samples) != 1 {
+		t.Fatalf("Expected 1 sample, got %d", len(samples))
+	}
+
+	// The second cycle exports the rate.
+	updateBlockStats(samples, []libvirt.DomainStatsRecord{record(60)}, start.Add(time.Minute))
+	if got := testutil.ToFloat64(blockReadIOPS.WithLabelValues(domain, "vda")); got != 1 {
+		t.Errorf("Expected read IOPS 1, got %v", got)
+	}
+
+	// Once the domain is gone, its samples and metrics are removed.
+	updateBlockStats(samples, nil, start.Add(2*time.Minute))
+	if len(samples) != 0 {
+		t.Errorf("Expected no samples, got %d", len(samples))
+	}
+	if n := testutil.CollectAndCount(blockReadIOPS); n != 0 {
+		t.Errorf("Expected no read IOPS series, got %d", n)
+	}
+}
@@ -54,17 +54,29 @@ func (m *client) Get(virt *libvirt.Libvirt) (Capabilities, error) {
 }
 
 // Emulated capabilities client returning an embedded capabilities xml.
-type clientEmulator struct{}
+type clientEmulator struct {
+	example []byte
+}
 
-// Create a new emulated capabilities client.
+// Create a new emulated capabilities client of an x86_64 host.
 func NewClientEmulator() Client {
-	return &clientEmulator{}
+	return NewClientEmulatorForArch("x86_64")
+}
+
+// Create a new emulated capabilities client of a host with the architecture,
+// e.g. aarch64, falling back to x86_64 for unknown ones.
+func NewClientEmulatorForArch(arch string) Client {
+	example, ok := examples[arch]
+	if !ok {
+		example = exampleXML
+	}
+	return &clientEmulator{example: example}
 }
 
 // Get the capabilities of the host we are mounted on.
 func (c *clientEmulator) Get(virt *libvirt.Libvirt) (Capabilities, error) {
 	var capabilities Capabilities
-	if err := xml.Unmarshal(exampleXML, &capabilities); err != nil {
+	if err := xml.Unmarshal(c.example, &capabilities); err != nil {
 		log.Log.Error(err, "failed to unmarshal example capabilities")
 		return Capabilities{}, err
 	}
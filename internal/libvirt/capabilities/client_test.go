@@ -376,3 +376,26 @@ func TestExampleXML_WordSize(t *testing.T) {
 		t.Logf("Note: Unusual word size %d (expected 32 or 64)", wordSize)
 	}
 }
+
+func TestNewClientEmulatorForArch(t *testing.T) {
+	capabilities, err := NewClientEmulatorForArch("aarch64").Get(nil)
+	if err != nil {
+		t.Fatalf("Get() returned unexpected error: %v", err)
+	}
+	if capabilities.Host.CPU.Arch != "aarch64" || capabilities.Guest.Arch.Name != "aarch64" {
+		t.Errorf("Expected aarch64 capabilities, got host %s and guest %s",
+			capabilities.Host.CPU.Arch, capabilities.Guest.Arch.Name)
+	}
+	if capabilities.Host.CPU.Counter != nil {
+		t.Error("Expected no time stamp counter on aarch64")
+	}
+
+	// Unknown architectures fall back to x86_64.
+	capabilities, err = NewClientEmulatorForArch("riscv64").Get(nil)
+	if err != nil {
+		t.Fatalf("Get() returned unexpected error: %v", err)
+	}
+	if capabilities.Host.CPU.Arch != "x86_64" {
+		t.Errorf("Expected x86_64 capabilities, got %s", capabilities.Host.CPU.Arch)
+	}
+}
@@ -35,7 +35,50 @@ type CapabilitiesHost struct {
 }
 
 type CapabilitiesHostCPU struct {
-	Arch string `xml:"arch"`
+	Arch      string                       `xml:"arch"`
+	Model     string                       `xml:"model"`
+	Vendor    string                       `xml:"vendor"`
+	Microcode CapabilitiesHostCPUMicrocode `xml:"microcode"`
+	Features  []CapabilitiesHostCPUFeature `xml:"feature"`
+	// The time stamp counter of the cpu, unset if libvirt couldn't read
+	// its frequency.
+	Counter *CapabilitiesHostCPUCounter `xml:"counter"`
+	// Topology of the host cpus, unset if libvirt couldn't detect it.
+	Topology *CapabilitiesHostCPUTopology `xml:"topology"`
+}
+
+type CapabilitiesHostCPUTopology struct {
+	Sockets  int64 `xml:"sockets,attr"`
+	Dies     int64 `xml:"dies,attr"`
+	Clusters int64 `xml:"clusters,attr"`
+	Cores    int64 `xml:"cores,attr"`
+	Threads  int64 `xml:"threads,attr"`
+}
+
+// CPUs returns the number of host cpus of the topology, with the levels
+// not reported by older libvirt versions counting as one.
+func (t CapabilitiesHostCPUTopology) CPUs() int64 {
+	cpus := int64(1)
+	for _, n := range []int64{t.Sockets, t.Dies, t.Clusters, t.Cores, t.Threads} {
+		cpus *= max(n, 1)
+	}
+	return cpus
+}
+
+type CapabilitiesHostCPUMicrocode struct {
+	Version string `xml:"version,attr"`
+}
+
+type CapabilitiesHostCPUFeature struct {
+	Name string `xml:"name,attr"`
+}
+
+type CapabilitiesHostCPUCounter struct {
+	Name string `xml:"name,attr"`
+	// Frequency in Hz.
+	Frequency uint64 `xml:"frequency,attr"`
+	// Whether the counter of domains can be scaled to another frequency.
+	Scaling string `xml:"scaling,attr"`
 }
 
 type CapabilitiesHostIOMMU struct {
@@ -60,6 +103,22 @@ type CapabilitiesHostTopologyCell struct {
 	CPUs      CapabilitiesHostTopologyCellCPUs      `xml:"cpus"`
 }
 
+// OnlineCPUs returns the number of online cpus of the cell. Offline cpus,
+// e.g. the threads of ppc64le hosts running with reduced smt, are listed
+// without topology but counted in the number of cpus of the cell.
+func (c CapabilitiesHostTopologyCell) OnlineCPUs() int64 {
+	if len(c.CPUs.CPUs) == 0 {
+		return c.CPUs.Num
+	}
+	var online int64
+	for _, cpu := range c.CPUs.CPUs {
+		if cpu.Online() {
+			online++
+		}
+	}
+	return online
+}
+
 type CapabilitiesHostTopologyCellMemory struct {
 	Unit  string `xml:"unit,attr"`
 	Value int64  `xml:",chardata"`
@@ -86,12 +145,21 @@ type CapabilitiesHostTopologyCellCPUs struct {
 }
 
 type CapabilitiesHostTopologyCellCPU struct {
-	ID        int    `xml:"id,attr"`
-	SocketID  int    `xml:"socket_id,attr"`
-	DieID     int    `xml:"die_id,attr"`
-	ClusterID int    `xml:"cluster_id,attr"`
-	CoreID    int    `xml:"core_id,attr"`
-	Siblings  string `xml:"siblings,attr"`
+	ID        int `xml:"id,attr"`
+	SocketID  int `xml:"socket_id,attr"`
+	DieID     int `xml:"die_id,attr"`
+	ClusterID int `xml:"cluster_id,attr"`
+	// Book and drawer of the cpu on s390x, the levels above the socket.
+	BookID   int    `xml:"book_id,attr"`
+	DrawerID int    `xml:"drawer_id,attr"`
+	CoreID   int    `xml:"core_id,attr"`
+	Siblings string `xml:"siblings,attr"`
+}
+
+// Online returns whether the cpu is online, libvirt only reports the
+// topology of online cpus.
+func (c CapabilitiesHostTopologyCellCPU) Online() bool {
+	return c.Siblings != ""
 }
 
 type CapabilitiesHostTopologyInterconnects struct {
@@ -34,6 +34,20 @@ func TestCapabilitiesDeserialization(t *testing.T) {
 	if capabilities.Host.CPU.Arch != "x86_64" {
 		t.Errorf("Expected CPU arch to be 'x86_64', got '%s'", capabilities.Host.CPU.Arch)
 	}
+	if capabilities.Host.CPU.Model != "EPYC-Milan" || capabilities.Host.CPU.Vendor != "AMD" {
+		t.Errorf("Expected CPU model EPYC-Milan by AMD, got '%s' by '%s'",
+			capabilities.Host.CPU.Model, capabilities.Host.CPU.Vendor)
+	}
+	if capabilities.Host.CPU.Microcode.Version != "167776721" {
+		t.Errorf("Expected microcode version 167776721, got '%s'", capabilities.Host.CPU.Microcode.Version)
+	}
+	if len(capabilities.Host.CPU.Features) != 3 || capabilities.Host.CPU.Features[0].Name != "x2apic" {
+		t.Errorf("Expected 3 CPU features starting with x2apic, got %v", capabilities.Host.CPU.Features)
+	}
+	if counter := capabilities.Host.CPU.Counter; counter == nil ||
+		counter.Name != "tsc" || counter.Frequency != 2994374000 || counter.Scaling != "yes" {
+		t.Errorf("Expected tsc counter of 2994374000 Hz with scaling, got %+v", counter)
+	}
 	if capabilities.Host.IOMMU.Support != "no" {
 		t.Errorf("Expected IOMMU support to be 'no', got '%s'", capabilities.Host.IOMMU.Support)
 	}
@@ -0,0 +1,132 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package libvirt
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"syscall"
+	"unsafe"
+
+	logger "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/libvirt/domcapabilities"
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/logging"
+)
+
+// Device of the AMD secure processor, which runs the SEV firmware. The
+// firmware version is unknown unless the device is available to the agent.
+var sevDevicePath = "/dev/sev"
+
+const (
+	// SEV_ISSUE_CMD of linux/psp-sev.h, _IOWR('S', 0x0, struct sev_issue_cmd).
+	sevIssueCmd = 0xc0105300
+	// SEV_PLATFORM_STATUS command of linux/psp-sev.h.
+	sevPlatformStatus = 1
+)
+
+// ConfidentialComputing is the support of the host for confidential domains,
+// whose memory is encrypted with a key the host doesn't know.
+type ConfidentialComputing struct {
+	// AMD Secure Encrypted Virtualization, and SEV-ES encrypting the cpu
+	// registers as well.
+	SEV   bool
+	SEVES bool
+	// Intel Trust Domain Extensions.
+	TDX bool
+	// Number of SEV and SEV-ES domains that can run at the same time, the
+	// memory encryption keys of the cpu are limited.
+	SEVMaxGuests   int
+	SEVESMaxGuests int
+	// Version of the SEV firmware loaded into the secure processor, e.g.
+	// "1.55.21". Empty if unknown.
+	SEVFirmwareVersion string
+}
+
+// ConfidentialComputingDescriber provides the support of the host for
+// confidential domains.
+type ConfidentialComputingDescriber interface {
+	// ConfidentialComputing returns which confidential computing
+	// technologies the host supports.
+	ConfidentialComputing() (ConfidentialComputing, error)
+}
+
+// Get the support for confidential domains from the features of the domain
+// capabilities.
+func confidentialComputing(caps domcapabilities.DomainCapabilities) ConfidentialComputing {
+	var cc ConfidentialComputing
+	for _, feature := range caps.Features.Features {
+		if feature.Supported != supportedYes {
+			continue
+		}
+		switch feature.XMLName.Local {
+		case "sev":
+			cc.SEV = true
+			cc.SEVMaxGuests = feature.MaxGuests
+			cc.SEVESMaxGuests = feature.MaxESGuests
+			// SEV-ES has no feature of its own, it is available if keys
+			// are reserved for it.
+			cc.SEVES = feature.MaxESGuests > 0
+		case "tdx":
+			cc.TDX = true
+		}
+	}
+	return cc
+}
+
+// Read the version of the SEV firmware from the platform status of the
+// secure processor, see struct sev_user_data_status of linux/psp-sev.h.
+func sevFirmwareVersion(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	// Both structs are packed, so they are built byte by byte.
+	var status [12]byte
+	var cmd [16]byte
+	binary.LittleEndian.PutUint32(cmd[0:], sevPlatformStatus)
+	binary.LittleEndian.PutUint64(cmd[4:], uint64(uintptr(unsafe.Pointer(&status[0]))))
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, file.Fd(), sevIssueCmd, uintptr(unsafe.Pointer(&cmd[0])))
+	if errno != 0 {
+		return "", fmt.Errorf("failed to get sev platform status: %w (firmware error %d)",
+			errno, binary.LittleEndian.Uint32(cmd[12:]))
+	}
+	// api_major, api_minor, state, flags and build.
+	return fmt.Sprintf("%d.%d.%d", status[0], status[1], status[7]), nil
+}
+
+// Get the support for confidential domains from the domain capabilities,
+// and the firmware version from the secure processor of SEV hosts.
+func (l *LibVirt) ConfidentialComputing() (ConfidentialComputing, error) {
+	caps, err := l.domainCapabilities()
+	if err != nil {
+		return ConfidentialComputing{}, err
+	}
+	cc := confidentialComputing(caps)
+	if cc.SEV {
+		version, err := sevFirmwareVersion(sevDevicePath)
+		if err != nil {
+			logger.Log.WithName(logging.Libvirt).V(1).Info("unable to get sev firmware version", "error", err.Error())
+		}
+		cc.SEVFirmwareVersion = version
+	}
+	return cc, nil
+}
@@ -0,0 +1,85 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package libvirt
+
+import (
+	"encoding/xml"
+	"path/filepath"
+	"testing"
+
+	v1 "github.com/cobaltcore-dev/openstack-hypervisor-operator/api/v1"
+
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/libvirt/domcapabilities"
+)
+
+func feature(name, supported string) domcapabilities.DomainCapabilitiesFeature {
+	return domcapabilities.DomainCapabilitiesFeature{XMLName: xml.Name{Local: name}, Supported: supported}
+}
+
+func TestConfidentialComputing(t *testing.T) {
+	sev := feature("sev", "yes")
+	sev.MaxGuests = 15
+	sev.MaxESGuests = 494
+	caps := domcapabilities.DomainCapabilities{
+		Features: domcapabilities.DomainCapabilitiesFeatures{
+			Features: []domcapabilities.DomainCapabilitiesFeature{sev, feature("sgx", "no"), feature("tdx", "no")},
+		},
+	}
+	expected := ConfidentialComputing{SEV: true, SEVES: true, SEVMaxGuests: 15, SEVESMaxGuests: 494}
+	if cc := confidentialComputing(caps); cc != expected {
+		t.Errorf("Expected %+v, got %+v", expected, cc)
+	}
+
+	// Without keys reserved for SEV-ES only SEV is available.
+	caps.Features.Features[0].MaxESGuests = 0
+	if cc := confidentialComputing(caps); !cc.SEV || cc.SEVES {
+		t.Errorf("Expected sev without sev-es, got %+v", cc)
+	}
+
+	caps.Features.Features = []domcapabilities.DomainCapabilitiesFeature{feature("sev", "no"), feature("tdx", "yes")}
+	if cc := confidentialComputing(caps); cc != (ConfidentialComputing{TDX: true}) {
+		t.Errorf("Expected tdx only, got %+v", cc)
+	}
+}
+
+func TestSEVFirmwareVersion_NoDevice(t *testing.T) {
+	if _, err := sevFirmwareVersion(filepath.Join(t.TempDir(), "sev")); err == nil {
+		t.Errorf("Expected an error without the sev device")
+	}
+}
+
+func TestAddDomainCapabilities_SEVES(t *testing.T) {
+	sev := feature("sev", "yes")
+	sev.MaxGuests = 15
+	sev.MaxESGuests = 494
+	l := &LibVirt{
+		domainCapabilitiesClient: &mockDomCapabilitiesClient{caps: domcapabilities.DomainCapabilities{
+			Features: domcapabilities.DomainCapabilitiesFeatures{
+				Features: []domcapabilities.DomainCapabilitiesFeature{sev, feature("tdx", "yes")},
+			},
+		}},
+	}
+	result, err := l.addDomainCapabilities(v1.Hypervisor{})
+	if err != nil {
+		t.Fatalf("addDomainCapabilities() returned unexpected error: %v", err)
+	}
+	features := result.Status.DomainCapabilities.SupportedFeatures
+	if len(features) != 3 || features[0] != "sev" || features[1] != "tdx" || features[2] != "sev-es" {
+		t.Errorf("Expected sev, tdx and sev-es, got %v", features)
+	}
+}
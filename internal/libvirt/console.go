@@ -0,0 +1,66 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package libvirt
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/digitalocean/go-libvirt"
+)
+
+// Open the serial console of the domain with the given uuid and stream its
+// output to the given writer. The call blocks until the console stream is
+// closed by libvirt, writing to w fails or the context is cancelled.
+//
+// The console is opened in safe mode, so an existing console session (e.g.
+// a virsh console) is not taken over. As a read-only stream only returns
+// once libvirt sends data, it is opened on a connection of its own, which
+// is closed to abort the stream and release the console when the context
+// is cancelled.
+func (l *LibVirt) OpenConsole(ctx context.Context, uuid string, w io.Writer) error {
+	id, err := ParseUUID(uuid)
+	if err != nil {
+		return err
+	}
+	virt := newLocalConnection()
+	if err := virt.ConnectToURI(libvirt.ConnectURI(l.uri)); err != nil {
+		return fmt.Errorf("failed to connect to libvirt: %w", err)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		<-ctx.Done()
+		_ = virt.Disconnect()
+	}()
+	defer func() { <-closed }()
+
+	domain, err := virt.DomainLookupByUUID(libvirt.UUID(id))
+	if err != nil {
+		return fmt.Errorf("failed to lookup domain %s: %w", uuid, err)
+	}
+	if err := virt.DomainOpenConsole(
+		domain, libvirt.OptString{}, w, uint32(libvirt.DomainConsoleSafe),
+	); err != nil && ctx.Err() == nil {
+		return fmt.Errorf("failed to open console of domain %s: %w", uuid, err)
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package libvirt
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/digitalocean/go-libvirt"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logger "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/cobaltcore-dev/kvm-node-agent/api/v1alpha1"
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/libvirt/dominfo"
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/sys"
+)
+
+const (
+	// Bytes read from the end of the console log of a domain, of which the
+	// end is attached to the events of the domain.
+	consoleLogTailBytes = 4 << 10
+	// Maximum length of the note of an event accepted by the api server.
+	maxEventNote = 1024
+	// Time within which a stop of a domain is taken as the consequence of
+	// a crash reported before, which libvirt reports as well.
+	crashStopWindow = time.Minute
+)
+
+// Get the complete lines within the last maxBytes of the reader.
+func tailLines(r io.ReadSeeker, maxBytes int64) (string, error) {
+	size, err := r.Seek(0, io.SeekEnd)
+	if err != nil {
+		return "", err
+	}
+	// Read one more byte to tell if the first line is cut off.
+	offset := max(size-maxBytes-1, 0)
+	if _, err := r.Seek(offset, io.SeekStart); err != nil {
+		return "", err
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+	lines := strings.Split(strings.TrimRight(string(bytes.ToValidUTF8(data, nil)), "\r\n"), "\n")
+	if offset > 0 && len(lines) > 1 {
+		// Drop the cut off first line, or the empty one before it.
+		lines = lines[1:]
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// Get the end of the console log of the domain, empty if the domain
+// doesn't log its serial console to a file.
+func (l *LibVirt) consoleLogTail(domain libvirt.Domain) (string, error) {
+	domainXML, err := l.virt.DomainGetXMLDesc(domain, 0)
+	if err != nil {
+		return "", err
+	}
+	var info dominfo.DomainInfo
+	if err := xml.Unmarshal([]byte(domainXML), &info); err != nil {
+		return "", err
+	}
+	if info.Devices == nil {
+		return "", nil
+	}
+	for _, serial := range info.Devices.Serials {
+		if serial.Log == nil || serial.Log.File == "" {
+			continue
+		}
+		f, err := os.Open(serial.Log.File)
+		if err != nil {
+			return "", err
+		}
+		defer f.Close()
+		return tailLines(f, consoleLogTailBytes)
+	}
+	return "", nil
+}
+
+// Build the note of an event with the end of the console log, which is cut
+// off at the front to fit into the event.
+func consoleLogEventNote(message, console string) string {
+	if console == "" {
+		return message
+	}
+	prefix := message + ", console:\n"
+	if len(prefix)+len(console) > maxEventNote {
+		prefix += "..."
+		console = console[len(console)-max(maxEventNote-len(prefix), 0):]
+	}
+	return prefix + console
+}
+
+// Emit a warning event for the instance with the end of the console log of
+// its domain, for the triage of guest kernel panics.
+func (l *LibVirt) emitConsoleLogEvent(
+	ctx context.Context, instance *v1alpha1.Instance, domain libvirt.Domain, reason, action, message string,
+) {
+	if l.recorder == nil {
+		return
+	}
+	console, err := l.consoleLogTail(domain)
+	if err != nil {
+		logger.FromContext(ctx).V(1).Info("unable to read console log",
+			"server", GetOpenstackUUID(domain), "error", err.Error())
+	}
+	l.recorder.Eventf(instance, nil, corev1.EventTypeWarning, reason, action,
+		"%s", consoleLogEventNote(message, console))
+}
+
+// Get the reason of an unexpected stop from the detail of the stopped
+// event, empty if the domain was stopped on purpose.
+func unexpectedStopReason(detail int32) string {
+	switch libvirt.DomainEventStoppedDetailType(detail) {
+	case libvirt.DomainEventStoppedCrashed:
+		return "crashed"
+	case libvirt.DomainEventStoppedFailed:
+		return "failed"
+	}
+	return ""
+}
+
+// Report a domain which stopped unexpectedly, e.g. because qemu died, in an
+// event of its instance. Stops following a crash are already reported with
+// the crash.
+func (l *LibVirt) onDomainStopped(ctx context.Context, domain libvirt.Domain, detail int32) {
+	reason := unexpectedStopReason(detail)
+	uuid := GetOpenstackUUID(domain)
+	if reason == "" || l.crashes.crashedWithin(uuid, time.Now(), crashStopWindow) {
+		return
+	}
+	var instance v1alpha1.Instance
+	if err := l.client.Get(ctx, client.ObjectKey{Name: uuid, Namespace: sys.Namespace}, &instance); err != nil {
+		logger.FromContext(ctx).Error(err, "failed to get instance of stopped domain", "server", uuid)
+		return
+	}
+	l.emitConsoleLogEvent(ctx, &instance, domain, "DomainStoppedUnexpectedly", "Stop",
+		"domain stopped unexpectedly ("+reason+")")
+}
@@ -0,0 +1,67 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package libvirt
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestTailLines(t *testing.T) {
+	var lines []string
+	for i := range 20 {
+		lines = append(lines, fmt.Sprintf("line %d", i))
+	}
+	tail, err := tailLines(strings.NewReader(strings.Join(lines, "\n")+"\n"), 24)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tail != "line 17\nline 18\nline 19" {
+		t.Errorf("Unexpected tail %q", tail)
+	}
+
+	long := strings.Repeat("x", 70<<10) + "\nlast"
+	tail, err = tailLines(strings.NewReader(long), consoleLogTailBytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tail != "last" {
+		t.Errorf("Expected the cut off line to be dropped, got %q", tail)
+	}
+}
+
+func TestCrashEventNote(t *testing.T) {
+	if note := consoleLogEventNote("domain crashed (panicked)", ""); note != "domain crashed (panicked)" {
+		t.Errorf("Unexpected note %q", note)
+	}
+	note := consoleLogEventNote("domain crashed (panicked)", strings.Repeat("x", 2000)+"end")
+	if len(note) != maxEventNote || !strings.HasSuffix(note, "end") ||
+		!strings.HasPrefix(note, "domain crashed (panicked), console:\n...") {
+		t.Errorf("Unexpected note of length %d", len(note))
+	}
+}
+
+func TestUnexpectedStopReason(t *testing.T) {
+	if reason := unexpectedStopReason(5); reason != "failed" {
+		t.Errorf("Unexpected reason %q", reason)
+	}
+	if reason := unexpectedStopReason(0); reason != "" {
+		t.Errorf("Expected a shutdown to be expected, got %q", reason)
+	}
+}
@@ -0,0 +1,125 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package libvirt
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/digitalocean/go-libvirt"
+)
+
+// Cumulative cpu counters of all vcpus of a domain at a point in time, as
+// reported by the libvirt domain stats api (vcpu.<n>.* fields).
+type cpuStealSample struct {
+	at    time.Time
+	vcpus int
+	// Total time in nanoseconds the vcpu threads were runnable but waited
+	// for a host cpu, i.e. the steal time seen by the guest.
+	steal uint64
+}
+
+// Parse the vcpu.<n>.* typed parameters of a domain stats record. Libvirt
+// reads the run queue delay of the vcpu threads from the host schedstat
+// (vcpu.<n>.delay), older versions only report the wait time
+// (vcpu.<n>.wait) which is used as a fallback. Returns false if neither
+// is reported.
+func parseCPUSteal(params []libvirt.TypedParam, at time.Time) (cpuStealSample, bool) {
+	delays := make(map[int]uint64)
+	waits := make(map[int]uint64)
+	for _, param := range params {
+		// Format: vcpu.<index>.<field>
+		parts := strings.SplitN(param.Field, ".", 3)
+		if len(parts) != 3 || parts[0] != "vcpu" {
+			continue
+		}
+		idx, err := strconv.Atoi(parts[1])
+		if err != nil {
+			continue
+		}
+		value, ok := typedParamUint64(param.Value.I)
+		if !ok {
+			continue
+		}
+		switch parts[2] {
+		case "delay":
+			delays[idx] = value
+		case "wait":
+			waits[idx] = value
+		}
+	}
+	steal := delays
+	if len(steal) == 0 {
+		steal = waits
+	}
+	if len(steal) == 0 {
+		return cpuStealSample{}, false
+	}
+	sample := cpuStealSample{at: at, vcpus: len(steal)}
+	for _, value := range steal {
+		sample.steal += value
+	}
+	return sample, true
+}
+
+// Compute the share of the time in percent the vcpus of the domain waited
+// for a host cpu between two samples. Returns false if no meaningful
+// value can be computed, e.g. because the counters were reset by a domain
+// restart or vcpus were hotplugged.
+func computeCPUSteal(prev, cur cpuStealSample) (float64, bool) {
+	elapsed := cur.at.Sub(prev.at)
+	if elapsed <= 0 || cur.vcpus != prev.vcpus || cur.steal < prev.steal {
+		return 0, false
+	}
+	total := float64(elapsed.Nanoseconds()) * float64(cur.vcpus)
+	return float64(cur.steal-prev.steal) / total * 100, true
+}
+
+// Update the previous samples with the given domain stats records and
+// export the resulting steal per domain. Domains that disappeared since
+// the last cycle are forgotten and their metrics removed.
+func updateCPUSteal(
+	samples map[string]cpuStealSample,
+	records []libvirt.DomainStatsRecord,
+	at time.Time,
+) {
+
+	seen := make(map[string]struct{})
+	for _, record := range records {
+		cur, ok := parseCPUSteal(record.Params, at)
+		if !ok {
+			continue
+		}
+		domain := GetOpenstackUUID(record.Dom)
+		seen[domain] = struct{}{}
+		if prev, ok := samples[domain]; ok {
+			if steal, valid := computeCPUSteal(prev, cur); valid {
+				cpuSteal.WithLabelValues(domain).Set(steal)
+			}
+		}
+		samples[domain] = cur
+	}
+	for domain := range samples {
+		if _, ok := seen[domain]; ok {
+			continue
+		}
+		delete(samples, domain)
+		cpuSteal.DeleteLabelValues(domain)
+	}
+}
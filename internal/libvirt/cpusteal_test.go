@@ -0,0 +1,113 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package libvirt
+
+import (
+	"testing"
+	"time"
+
+	libvirt "github.com/digitalocean/go-libvirt"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestParseCPUSteal(t *testing.T) {
+	now := time.Now()
+	sample, ok := parseCPUSteal([]libvirt.TypedParam{
+		blockParam("vcpu.current", uint32(2)),
+		blockParam("vcpu.0.time", uint64(900)),
+		blockParam("vcpu.0.wait", uint64(1)),
+		blockParam("vcpu.0.delay", uint64(100)),
+		blockParam("vcpu.1.wait", uint64(2)),
+		blockParam("vcpu.1.delay", uint64(50)),
+		blockParam("block.0.rd.reqs", uint64(7)),
+	}, now)
+	if !ok {
+		t.Fatal("Expected a sample")
+	}
+	if sample.vcpus != 2 || sample.steal != 150 || !sample.at.Equal(now) {
+		t.Errorf("Unexpected sample: %+v", sample)
+	}
+
+	// Without the delay the wait time is used.
+	sample, ok = parseCPUSteal([]libvirt.TypedParam{
+		blockParam("vcpu.0.wait", uint64(10)),
+	}, now)
+	if !ok || sample.vcpus != 1 || sample.steal != 10 {
+		t.Errorf("Unexpected sample from the wait time: %+v", sample)
+	}
+
+	if _, ok := parseCPUSteal([]libvirt.TypedParam{blockParam("vcpu.0.time", uint64(1))}, now); ok {
+		t.Error("Expected no sample without delay or wait time")
+	}
+}
+
+func TestComputeCPUSteal(t *testing.T) {
+	start := time.Now()
+	prev := cpuStealSample{at: start, vcpus: 2, steal: 0}
+
+	// Two vcpus waiting for 1s in total within 10s.
+	cur := cpuStealSample{at: start.Add(10 * time.Second), vcpus: 2, steal: uint64(time.Second)}
+	if steal, ok := computeCPUSteal(prev, cur); !ok || steal != 5 {
+		t.Errorf("Expected 5%% steal, got %v (%v)", steal, ok)
+	}
+
+	// Counter reset, hotplugged vcpus and no elapsed time are skipped.
+	for _, cur := range []cpuStealSample{
+		{at: start.Add(time.Second), vcpus: 2, steal: 0},
+		{at: start.Add(time.Second), vcpus: 4, steal: 10},
+		{at: start, vcpus: 2, steal: 10},
+	} {
+		if _, ok := computeCPUSteal(cpuStealSample{at: start, vcpus: 2, steal: 5}, cur); ok {
+			t.Errorf("Expected no steal for %+v", cur)
+		}
+	}
+}
+
+func TestUpdateCPUSteal_RemovesStaleDomains(t *testing.T) {
+	dom := libvirt.Domain{Name: "instance-1", UUID: libvirt.UUID{2}}
+	domain := GetOpenstackUUID(dom)
+	record := func(delay uint64) libvirt.DomainStatsRecord {
+		return libvirt.DomainStatsRecord{
+			Dom:    dom,
+			Params: []libvirt.TypedParam{blockParam("vcpu.0.delay", delay)},
+		}
+	}
+	samples := make(map[string]cpuStealSample)
+	start := time.Now()
+
+	// The first cycle only records the baseline.
+	updateCPUSteal(samples, []libvirt.DomainStatsRecord{record(0)}, start)
+	if len(samples) != 1 {
+		t.Fatalf("Expected 1 sample, got %d", len(samples))
+	}
+
+	// The second cycle exports the steal.
+	updateCPUSteal(samples, []libvirt.DomainStatsRecord{record(uint64(6 * time.Second))}, start.Add(time.Minute))
+	if got := testutil.ToFloat64(cpuSteal.WithLabelValues(domain)); got != 10 {
+		t.Errorf("Expected 10%% steal, got %v", got)
+	}
+
+	// Once the domain is gone, its sample and metric are removed.
+	updateCPUSteal(samples, nil, start.Add(2*time.Minute))
+	if len(samples) != 0 {
+		t.Errorf("Expected no samples, got %d", len(samples))
+	}
+	if n := testutil.CollectAndCount(cpuSteal); n != 0 {
+		t.Errorf("Expected no steal series, got %d", n)
+	}
+}
@@ -0,0 +1,236 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package libvirt
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	v1 "github.com/cobaltcore-dev/openstack-hypervisor-operator/api/v1"
+	"github.com/digitalocean/go-libvirt"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/events"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logger "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/cobaltcore-dev/kvm-node-agent/api/v1alpha1"
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/sys"
+)
+
+// Annotation of the hypervisor or of an instance with the number of times
+// a crashed domain is restarted automatically. The annotation of the
+// instance takes precedence, crashed domains aren't restarted by default.
+const CrashRestartsAnnotation = "kvm.cloud.sap/crash-restarts"
+
+const (
+	// Delay before the first restart of a crashed domain, doubled with
+	// every further restart up to crashRestartMaxBackoff.
+	crashRestartBackoff    = 10 * time.Second
+	crashRestartMaxBackoff = 5 * time.Minute
+	// Time after the last crash from which the restarts are counted anew.
+	crashRestartReset = time.Hour
+)
+
+// Get the reason of a crash from the detail of the crashed event.
+func crashReason(detail int32) string {
+	switch libvirt.DomainEventCrashedDetailType(detail) {
+	case libvirt.DomainEventCrashedPanicked:
+		return "panicked"
+	case libvirt.DomainEventCrashedCrashloaded:
+		return "crashloaded"
+	}
+	return "unknown"
+}
+
+// Get the number of restarts of a crashed domain from the annotations of
+// its instance and of the hypervisor.
+func crashRestartLimit(instance, hypervisor map[string]string) (int, error) {
+	for _, annotations := range []map[string]string{instance, hypervisor} {
+		value, ok := annotations[CrashRestartsAnnotation]
+		if !ok {
+			continue
+		}
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 0 {
+			return 0, fmt.Errorf("invalid %s annotation %q", CrashRestartsAnnotation, value)
+		}
+		return limit, nil
+	}
+	return 0, nil
+}
+
+// Get the delay before the given restart of a crashed domain, counting
+// from zero.
+func crashRestartDelay(restart int) time.Duration {
+	delay := crashRestartBackoff
+	for range restart {
+		delay *= 2
+		if delay >= crashRestartMaxBackoff {
+			return crashRestartMaxBackoff
+		}
+	}
+	return delay
+}
+
+// Counts the restarts of crashed domains, by domain uuid. The zero value
+// is ready to use.
+type crashTracker struct {
+	lock    sync.Mutex
+	crashes map[string]*crashRecord
+}
+
+type crashRecord struct {
+	restarts  int
+	lastCrash time.Time
+}
+
+// Record a crash of the domain and get the number of restarts since it
+// last ran stable. If the limit isn't reached yet the restart is counted
+// and true is returned.
+func (t *crashTracker) crashed(uuid string, at time.Time, limit int) (int, bool) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if t.crashes == nil {
+		t.crashes = make(map[string]*crashRecord)
+	}
+	record, ok := t.crashes[uuid]
+	if !ok || at.Sub(record.lastCrash) > crashRestartReset {
+		record = &crashRecord{}
+		t.crashes[uuid] = record
+	}
+	record.lastCrash = at
+	restarts := record.restarts
+	if restarts >= limit {
+		return restarts, false
+	}
+	record.restarts++
+	return restarts, true
+}
+
+// Check if the domain crashed within the given time before now, e.g. to
+// tell the stop following a crash from the crash itself.
+func (t *crashTracker) crashedWithin(uuid string, now time.Time, within time.Duration) bool {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	record, ok := t.crashes[uuid]
+	return ok && now.Sub(record.lastCrash) <= within
+}
+
+// Forget the crashes of an undefined domain.
+func (t *crashTracker) forget(uuid string) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	delete(t.crashes, uuid)
+}
+
+// Report the crash of the domain in an event of its instance and restart
+// the domain if the restart policy asks for it.
+func (l *LibVirt) onDomainCrashed(ctx context.Context, domain libvirt.Domain, detail int32) {
+	uuid := GetOpenstackUUID(domain)
+	log := logger.FromContext(ctx).WithValues("server", uuid)
+
+	var instance v1alpha1.Instance
+	if err := l.client.Get(ctx, client.ObjectKey{Name: uuid, Namespace: sys.Namespace}, &instance); err != nil {
+		log.Error(err, "failed to get instance of crashed domain")
+		return
+	}
+	var hypervisor v1.Hypervisor
+	if err := l.client.Get(ctx, client.ObjectKey{Name: sys.NodeLabelName}, &hypervisor); err != nil {
+		log.Error(err, "failed to get hypervisor of crashed domain")
+		return
+	}
+	limit, err := crashRestartLimit(instance.Annotations, hypervisor.Annotations)
+	if err != nil {
+		log.Error(err, "not restarting crashed domain")
+	}
+
+	restarts, restart := l.crashes.crashed(uuid, time.Now(), limit)
+	message := fmt.Sprintf("domain crashed (%s)", crashReason(detail))
+	if restart {
+		delay := crashRestartDelay(restarts)
+		message += fmt.Sprintf(", restart %d of %d in %s", restarts+1, limit, delay)
+		l.loops.Go("crash-restart", func(ctx context.Context) {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(delay):
+			}
+			l.restartCrashedDomain(ctx, domain, &instance)
+		}, nil)
+	} else if limit > 0 {
+		message += fmt.Sprintf(", giving up after %d restarts", restarts)
+	}
+	log.Info("domain crashed", "reason", crashReason(detail), "restart", restart)
+	l.emitConsoleLogEvent(ctx, &instance, domain, "DomainCrashed", "Crash", message)
+}
+
+// Check if the domain is still down from its crash. A domain shut off for
+// another reason was stopped, migrated or saved in the meantime.
+func stillCrashed(state, reason int32) bool {
+	switch libvirt.DomainState(state) {
+	case libvirt.DomainCrashed:
+		return true
+	case libvirt.DomainShutoff:
+		return libvirt.DomainShutoffReason(reason) == libvirt.DomainShutoffCrashed
+	default:
+		return false
+	}
+}
+
+// Start the crashed domain again, unless it was started, stopped or
+// destroyed in the meantime. A domain preserved in the crashed state is
+// destroyed first.
+func (l *LibVirt) restartCrashedDomain(ctx context.Context, domain libvirt.Domain, instance *v1alpha1.Instance) {
+	log := logger.FromContext(ctx).WithValues("server", GetOpenstackUUID(domain))
+	state, reason, err := l.virt.DomainGetState(domain, 0)
+	if err != nil {
+		log.Error(err, "failed to get state of crashed domain")
+		return
+	}
+	if !stillCrashed(state, reason) {
+		log.Info("not restarting crashed domain, it is no longer crashed", "state", state, "reason", reason)
+		return
+	}
+	if libvirt.DomainState(state) == libvirt.DomainCrashed {
+		if err := l.virt.DomainDestroy(domain); err != nil {
+			log.Error(err, "failed to destroy crashed domain")
+			return
+		}
+	}
+	if err := l.virt.DomainCreate(domain); err != nil {
+		log.Error(err, "failed to restart crashed domain")
+		if l.recorder != nil {
+			l.recorder.Eventf(instance, nil, corev1.EventTypeWarning, "DomainRestartFailed", "Restart",
+				"%s", err.Error())
+		}
+		return
+	}
+	log.Info("restarted crashed domain")
+	if l.recorder != nil {
+		l.recorder.Eventf(instance, nil, corev1.EventTypeNormal, "DomainRestarted", "Restart",
+			"restarted crashed domain")
+	}
+}
+
+// Set the recorder of the events of the instances, e.g. of crashed domains.
+func (l *LibVirt) SetEventRecorder(recorder events.EventRecorder) {
+	l.recorder = recorder
+}
@@ -0,0 +1,108 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package libvirt
+
+import (
+	"testing"
+	"time"
+
+	"github.com/digitalocean/go-libvirt"
+)
+
+func TestCrashRestartLimit(t *testing.T) {
+	hypervisor := map[string]string{CrashRestartsAnnotation: "3"}
+	if limit, err := crashRestartLimit(nil, hypervisor); err != nil || limit != 3 {
+		t.Errorf("Expected the limit of the hypervisor, got %d (%v)", limit, err)
+	}
+	instance := map[string]string{CrashRestartsAnnotation: "0"}
+	if limit, err := crashRestartLimit(instance, hypervisor); err != nil || limit != 0 {
+		t.Errorf("Expected the limit of the instance, got %d (%v)", limit, err)
+	}
+	if limit, err := crashRestartLimit(nil, nil); err != nil || limit != 0 {
+		t.Errorf("Expected no restarts by default, got %d (%v)", limit, err)
+	}
+	if _, err := crashRestartLimit(map[string]string{CrashRestartsAnnotation: "-1"}, nil); err == nil {
+		t.Errorf("Expected error for negative limit")
+	}
+}
+
+func TestCrashRestartDelay(t *testing.T) {
+	for restart, expected := range []time.Duration{
+		10 * time.Second, 20 * time.Second, 40 * time.Second, 80 * time.Second,
+		160 * time.Second, 5 * time.Minute, 5 * time.Minute,
+	} {
+		if delay := crashRestartDelay(restart); delay != expected {
+			t.Errorf("Expected delay %s for restart %d, got %s", expected, restart, delay)
+		}
+	}
+}
+
+func TestCrashTracker(t *testing.T) {
+	var tracker crashTracker
+	now := time.Now()
+	for i := range 2 {
+		restarts, restart := tracker.crashed("uuid-1", now, 2)
+		if restarts != i || !restart {
+			t.Errorf("Expected restart %d, got %d (%t)", i, restarts, restart)
+		}
+	}
+	if restarts, restart := tracker.crashed("uuid-1", now, 2); restarts != 2 || restart {
+		t.Errorf("Expected no restart after the limit, got %d (%t)", restarts, restart)
+	}
+	if _, restart := tracker.crashed("uuid-1", now.Add(2*time.Hour), 2); !restart {
+		t.Errorf("Expected the restarts to be reset after running stable")
+	}
+	if !tracker.crashedWithin("uuid-1", now.Add(2*time.Hour), time.Minute) {
+		t.Errorf("Expected a recent crash")
+	}
+	if tracker.crashedWithin("uuid-1", now.Add(3*time.Hour), time.Minute) {
+		t.Errorf("Expected no recent crash")
+	}
+	tracker.forget("uuid-1")
+	if restarts, _ := tracker.crashed("uuid-1", now, 2); restarts != 0 {
+		t.Errorf("Expected the crashes to be forgotten, got %d", restarts)
+	}
+}
+
+func TestCrashReason(t *testing.T) {
+	if reason := crashReason(0); reason != "panicked" {
+		t.Errorf("Unexpected reason %q", reason)
+	}
+	if reason := crashReason(42); reason != "unknown" {
+		t.Errorf("Unexpected reason %q", reason)
+	}
+}
+
+func TestStillCrashed(t *testing.T) {
+	for _, tc := range []struct {
+		state  libvirt.DomainState
+		reason int32
+		want   bool
+	}{
+		{libvirt.DomainCrashed, 0, true},
+		{libvirt.DomainShutoff, int32(libvirt.DomainShutoffCrashed), true},
+		{libvirt.DomainShutoff, int32(libvirt.DomainShutoffShutdown), false},
+		{libvirt.DomainShutoff, int32(libvirt.DomainShutoffDestroyed), false},
+		{libvirt.DomainShutoff, int32(libvirt.DomainShutoffSaved), false},
+		{libvirt.DomainRunning, 0, false},
+	} {
+		if got := stillCrashed(int32(tc.state), tc.reason); got != tc.want {
+			t.Errorf("stillCrashed(%v, %d) = %v, want %v", tc.state, tc.reason, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package libvirt
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/config"
+)
+
+// DaemonMode selects whether the host runs the monolithic libvirtd or the
+// modular daemons like virtqemud.
+type DaemonMode string
+
+const (
+	// A single libvirtd serves all drivers and remote connections.
+	DaemonsMonolithic DaemonMode = "monolithic"
+	// One socket activated daemon per driver, remote connections are
+	// served by virtproxyd.
+	DaemonsModular DaemonMode = "modular"
+)
+
+// Parse the daemon mode from its flag value.
+func ParseDaemonMode(s string) (DaemonMode, error) {
+	switch mode := DaemonMode(s); mode {
+	case DaemonsMonolithic, DaemonsModular:
+		return mode, nil
+	}
+	return "", fmt.Errorf("invalid libvirt daemon mode %q, expected one of monolithic, modular", s)
+}
+
+// Socket of libvirt and the uri the agent connects to without explicit
+// uris, set from the agent configuration.
+var (
+	socketPath = "/run/libvirt/libvirt-sock"
+	defaultURI = "ch:///system"
+)
+
+// Configure the libvirt connections from the agent configuration.
+func Configure(cfg config.Libvirt) {
+	socketPath, defaultURI = cfg.Socket, cfg.DefaultURI
+}
+
+// Get the uri the agent connects to, ch:///system unless configured.
+func DefaultURI() string {
+	return defaultURI
+}
+
+// Get the systemd unit accepting connections to the given uri. The modular
+// daemons are socket activated, their services are only running while
+// there are clients, so the socket is checked instead.
+func (m DaemonMode) ConnectionUnit(uri string) string {
+	if m != DaemonsModular {
+		return "libvirtd.service"
+	}
+	return "virt" + Driver(uri) + "d.socket"
+}
+
+// Get the driver of a libvirt uri, e.g. qemu for qemu+unix:///system.
+func Driver(uri string) string {
+	driver, _, _ := strings.Cut(uri, ":")
+	driver, _, _ = strings.Cut(driver, "+")
+	return driver
+}
+
+// Get the systemd units of the daemons reported as conditions of the
+// hypervisor, for the modular daemons the sockets of the driver connected
+// to, the network and storage drivers and virtproxyd.
+func (m DaemonMode) Units(uri string) []string {
+	if m != DaemonsModular {
+		return []string{"libvirtd.service"}
+	}
+	return []string{
+		m.ConnectionUnit(uri),
+		"virtnetworkd.socket",
+		"virtstoraged.socket",
+		"virtproxyd.socket",
+	}
+}
+
+// Get the systemd unit reloading the TLS certificate of the daemon serving
+// remote connections. The unit is provided by the host and runs e.g.
+// "virt-admin -c virtproxyd:///system server-update-tls virtproxyd".
+func (m DaemonMode) TLSUpdateUnit() string {
+	if m != DaemonsModular {
+		return "virt-admin-server-update-tls.service"
+	}
+	return "virtproxyd-server-update-tls.service"
+}
+
+// Get the systemd unit serving remote connections, which is started if the
+// TLS certificate couldn't be reloaded.
+func (m DaemonMode) RemoteUnit() string {
+	if m != DaemonsModular {
+		return "libvirtd.service"
+	}
+	return "virtproxyd.socket"
+}
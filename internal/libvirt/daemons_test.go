@@ -0,0 +1,66 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package libvirt
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseDaemonMode(t *testing.T) {
+	for _, value := range []string{"monolithic", "modular"} {
+		mode, err := ParseDaemonMode(value)
+		if err != nil || string(mode) != value {
+			t.Errorf("Expected mode %s, got %s (%v)", value, mode, err)
+		}
+	}
+	if _, err := ParseDaemonMode("virtqemud"); err == nil {
+		t.Errorf("Expected error for invalid mode")
+	}
+}
+
+func TestDaemonMode_ConnectionUnit(t *testing.T) {
+	tests := []struct {
+		mode DaemonMode
+		uri  string
+		want string
+	}{
+		{DaemonsMonolithic, "qemu:///system", "libvirtd.service"},
+		{DaemonsModular, "qemu:///system", "virtqemud.socket"},
+		{DaemonsModular, "qemu+unix:///system", "virtqemud.socket"},
+		{DaemonsModular, "ch:///system", "virtchd.socket"},
+	}
+	for _, tt := range tests {
+		if got := tt.mode.ConnectionUnit(tt.uri); got != tt.want {
+			t.Errorf("%s %s: expected %s, got %s", tt.mode, tt.uri, tt.want, got)
+		}
+	}
+}
+
+func TestDaemonMode_Units(t *testing.T) {
+	if got := DaemonsMonolithic.Units("ch:///system"); !slices.Equal(got, []string{"libvirtd.service"}) {
+		t.Errorf("Unexpected units %v", got)
+	}
+	want := []string{"virtqemud.socket", "virtnetworkd.socket", "virtstoraged.socket", "virtproxyd.socket"}
+	if got := DaemonsModular.Units("qemu:///system"); !slices.Equal(got, want) {
+		t.Errorf("Expected units %v, got %v", want, got)
+	}
+	if DaemonsModular.TLSUpdateUnit() == DaemonsMonolithic.TLSUpdateUnit() {
+		t.Errorf("Expected a separate TLS update unit for virtproxyd")
+	}
+}
@@ -0,0 +1,139 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package libvirt
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/digitalocean/go-libvirt"
+)
+
+// Directory nova keeps the ephemeral disks of the instances in.
+const InstancesPath = "/var/lib/nova/instances"
+
+// DiskUsage is the allocation of a file backed disk of a domain.
+type DiskUsage struct {
+	// Uuid of the domain.
+	Domain string
+	Device string
+	Path   string
+	// Bytes allocated on the host and the size seen by the guest.
+	Allocation uint64
+	Capacity   uint64
+}
+
+// Share of the capacity allocated on the host.
+func (u DiskUsage) Ratio() float64 {
+	if u.Capacity == 0 {
+		return 0
+	}
+	return float64(u.Allocation) / float64(u.Capacity)
+}
+
+// Summary of the usage for humans, e.g. "<uuid>/vda 95%".
+func (u DiskUsage) String() string {
+	return fmt.Sprintf("%s/%s %.0f%%", u.Domain, u.Device, u.Ratio()*100)
+}
+
+// FilesystemUsage is the usage of the filesystem holding the disks.
+type FilesystemUsage struct {
+	Path string
+	// Bytes used and the size of the filesystem.
+	Used uint64
+	Size uint64
+}
+
+// Share of the filesystem used.
+func (u FilesystemUsage) Ratio() float64 {
+	if u.Size == 0 {
+		return 0
+	}
+	return float64(u.Used) / float64(u.Size)
+}
+
+// Summary of the usage for humans, e.g. "/var/lib/nova/instances 80%".
+func (u FilesystemUsage) String() string {
+	return fmt.Sprintf("%s %.0f%%", u.Path, u.Ratio()*100)
+}
+
+// DiskUsageReporter provides the usage of the ephemeral disks of the
+// domains.
+type DiskUsageReporter interface {
+	// InstanceDiskUsage returns the file backed disks of the active
+	// domains in InstancesPath and the usage of the filesystem holding
+	// them.
+	InstanceDiskUsage() ([]DiskUsage, FilesystemUsage, error)
+}
+
+// Stat the filesystem holding the path, replaced in tests.
+var statFilesystem = func(path string) (FilesystemUsage, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return FilesystemUsage{}, fmt.Errorf("failed to stat filesystem of %s: %w", path, err)
+	}
+	blockSize := uint64(stat.Bsize)
+	return FilesystemUsage{
+		Path: path,
+		Used: (stat.Blocks - stat.Bfree) * blockSize,
+		Size: stat.Blocks * blockSize,
+	}, nil
+}
+
+// Collect the disks of the domain stats records with a source in the
+// directory, sorted by domain and device.
+func instanceDisks(records []libvirt.DomainStatsRecord, dir string, at time.Time) []DiskUsage {
+	var disks []DiskUsage
+	for _, record := range records {
+		domain := GetOpenstackUUID(record.Dom)
+		for device, sample := range parseBlockStats(record.Params, at) {
+			if !strings.HasPrefix(sample.path, filepath.Clean(dir)+"/") {
+				continue
+			}
+			disks = append(disks, DiskUsage{
+				Domain:     domain,
+				Device:     device,
+				Path:       sample.path,
+				Allocation: sample.allocation,
+				Capacity:   sample.capacity,
+			})
+		}
+	}
+	slices.SortFunc(disks, func(a, b DiskUsage) int {
+		return strings.Compare(a.Domain+"/"+a.Device, b.Domain+"/"+b.Device)
+	})
+	return disks
+}
+
+// Get the usage of the disks in InstancesPath from the cached domain stats
+// and stat the filesystem holding them.
+func (l *LibVirt) InstanceDiskUsage() ([]DiskUsage, FilesystemUsage, error) {
+	records, at, err := l.domainStats()
+	if err != nil {
+		return nil, FilesystemUsage{}, err
+	}
+	filesystem, err := statFilesystem(InstancesPath)
+	if err != nil {
+		return nil, FilesystemUsage{}, err
+	}
+	return instanceDisks(records, InstancesPath, at), filesystem, nil
+}
@@ -0,0 +1,92 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package libvirt
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	libvirt "github.com/digitalocean/go-libvirt"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestInstanceDisks(t *testing.T) {
+	dom := libvirt.Domain{Name: "instance-1", UUID: libvirt.UUID{3}}
+	domain := GetOpenstackUUID(dom)
+	records := []libvirt.DomainStatsRecord{{
+		Dom: dom,
+		Params: []libvirt.TypedParam{
+			blockParam("block.0.name", "vdb"),
+			blockParam("block.0.path", InstancesPath+"/"+domain+"/disk.eph0"),
+			blockParam("block.0.allocation", uint64(90)),
+			blockParam("block.0.capacity", uint64(100)),
+			blockParam("block.1.name", "vda"),
+			blockParam("block.1.path", InstancesPath+"/"+domain+"/disk"),
+			blockParam("block.1.allocation", uint64(10)),
+			blockParam("block.1.capacity", uint64(100)),
+			blockParam("block.2.name", "vdc"),
+			blockParam("block.2.path", "/dev/mapper/volume"),
+			blockParam("block.2.allocation", uint64(100)),
+			blockParam("block.2.capacity", uint64(100)),
+		},
+	}}
+
+	disks := instanceDisks(records, InstancesPath, time.Now())
+	expected := []DiskUsage{
+		{Domain: domain, Device: "vda", Path: InstancesPath + "/" + domain + "/disk", Allocation: 10, Capacity: 100},
+		{Domain: domain, Device: "vdb", Path: InstancesPath + "/" + domain + "/disk.eph0", Allocation: 90, Capacity: 100},
+	}
+	if !reflect.DeepEqual(disks, expected) {
+		t.Errorf("Expected disks %+v, got %+v", expected, disks)
+	}
+	if disks[1].Ratio() != 0.9 || disks[1].String() != domain+"/vdb 90%" {
+		t.Errorf("Unexpected usage of %+v: %v", disks[1], disks[1].String())
+	}
+}
+
+func TestDiskUsageRatio_NoCapacity(t *testing.T) {
+	if ratio := (DiskUsage{Allocation: 10}).Ratio(); ratio != 0 {
+		t.Errorf("Expected ratio 0 without capacity, got %v", ratio)
+	}
+	if ratio := (FilesystemUsage{Used: 10}).Ratio(); ratio != 0 {
+		t.Errorf("Expected ratio 0 without size, got %v", ratio)
+	}
+}
+
+func TestUpdateBlockStats_Allocation(t *testing.T) {
+	dom := libvirt.Domain{Name: "instance-1", UUID: libvirt.UUID{4}}
+	domain := GetOpenstackUUID(dom)
+	records := []libvirt.DomainStatsRecord{{
+		Dom: dom,
+		Params: []libvirt.TypedParam{
+			blockParam("block.0.name", "vda"),
+			blockParam("block.0.allocation", uint64(1024)),
+			blockParam("block.0.capacity", uint64(4096)),
+		},
+	}}
+	samples := make(map[blockDeviceKey]blockStatsSample)
+	updateBlockStats(samples, records, time.Now())
+	if got := testutil.ToFloat64(blockAllocation.WithLabelValues(domain, "vda")); got != 1024 {
+		t.Errorf("Expected allocation 1024, got %v", got)
+	}
+	if got := testutil.ToFloat64(blockCapacity.WithLabelValues(domain, "vda")); got != 4096 {
+		t.Errorf("Expected capacity 4096, got %v", got)
+	}
+	updateBlockStats(samples, nil, time.Now())
+}
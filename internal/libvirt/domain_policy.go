@@ -0,0 +1,181 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package libvirt
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/digitalocean/go-libvirt"
+)
+
+// DomainPolicyMode selects how the domain policy is applied.
+type DomainPolicyMode string
+
+const (
+	// Don't check the domain policy.
+	DomainPolicyOff DomainPolicyMode = "off"
+	// Only report domains violating the policy.
+	DomainPolicyDryRun DomainPolicyMode = "dry-run"
+	// Fix domains violating the policy.
+	DomainPolicyEnforce DomainPolicyMode = "enforce"
+)
+
+// Parse the domain policy mode from its flag value.
+func ParseDomainPolicyMode(s string) (DomainPolicyMode, error) {
+	switch mode := DomainPolicyMode(s); mode {
+	case DomainPolicyOff, DomainPolicyDryRun, DomainPolicyEnforce:
+		return mode, nil
+	}
+	return "", fmt.Errorf("invalid domain policy mode %q, expected one of off, dry-run, enforce", s)
+}
+
+// Directory of the managed save images of the qemu driver.
+var managedSavePath = "/var/lib/libvirt/qemu/save"
+
+// DomainPolicyViolation is a domain created by nova which libvirt would
+// start or restore behind the back of nova.
+type DomainPolicyViolation struct {
+	UUID string
+	Name string
+	// The domain is started together with libvirtd.
+	Autostart bool
+	// The domain has a managed save image, which libvirt restores the next
+	// time the domain is started instead of booting it.
+	ManagedSave bool
+	// The managed save image is older than the maximum age. Only stale
+	// images are removed, the others hold the memory of suspended domains.
+	StaleManagedSave bool
+}
+
+// Whether the violation is fixed when the policy is enforced.
+func (v DomainPolicyViolation) Fixable() bool {
+	return !v.ManagedSave || v.StaleManagedSave
+}
+
+// Summary of the violation for humans, e.g. "instance-0001: autostart".
+func (v DomainPolicyViolation) String() string {
+	var problems []string
+	if v.Autostart {
+		problems = append(problems, "autostart")
+	}
+	if v.StaleManagedSave {
+		problems = append(problems, "stale managed-save")
+	} else if v.ManagedSave {
+		problems = append(problems, "managed-save")
+	}
+	return v.Name + ": " + strings.Join(problems, ", ")
+}
+
+// DomainPolicyEnforcer checks the domains of the host against the policy.
+type DomainPolicyEnforcer interface {
+	// EnforceDomainPolicy returns the domains violating the policy and
+	// fixes them, unless dryRun is set. Managed save images are only
+	// removed if they are older than managedSaveMaxAge, zero keeps all.
+	EnforceDomainPolicy(dryRun bool, managedSaveMaxAge time.Duration) ([]DomainPolicyViolation, error)
+}
+
+// Check whether the managed save image of the domain was written before the
+// maximum age. Images which can't be found are not taken for stale.
+func staleManagedSave(dir, name string, maxAge time.Duration, now time.Time) bool {
+	if maxAge <= 0 {
+		return false
+	}
+	info, err := os.Stat(filepath.Join(dir, name+".save"))
+	if err != nil {
+		return false
+	}
+	return now.Sub(info.ModTime()) > maxAge
+}
+
+// Check that no domain created by nova has autostart enabled or a managed
+// save image. Both break the power state handling of nova, which expects
+// to be the only one starting its domains. Domains not created by nova are
+// left alone.
+//
+// Note that nova suspends instances with a managed save. Removing the image
+// discards the memory state, a suspended instance is booted on resume. So
+// managed save images are only removed once they are older than
+// managedSaveMaxAge, the others are reported.
+func (l *LibVirt) EnforceDomainPolicy(dryRun bool, managedSaveMaxAge time.Duration) ([]DomainPolicyViolation, error) {
+	domains, err := l.domainInfoClient.Get(l.virt)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	var violations []DomainPolicyViolation
+	var errs []error
+	for _, info := range domains {
+		if info.Metadata == nil || info.Metadata.NovaInstance == nil {
+			continue
+		}
+		id, err := ParseUUID(info.UUID)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		domain, err := l.virt.DomainLookupByUUID(libvirt.UUID(id))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to lookup domain %s: %w", info.UUID, err))
+			continue
+		}
+		autostart, err := l.virt.DomainGetAutostart(domain)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to get autostart of domain %s: %w", info.UUID, err))
+			continue
+		}
+		managedSave, err := l.virt.DomainHasManagedSaveImage(domain, 0)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to check managed save of domain %s: %w", info.UUID, err))
+			continue
+		}
+		if autostart == 0 && managedSave == 0 {
+			continue
+		}
+
+		violation := DomainPolicyViolation{
+			UUID:        info.UUID,
+			Name:        info.Name,
+			Autostart:   autostart != 0,
+			ManagedSave: managedSave != 0,
+		}
+		if violation.ManagedSave {
+			violation.StaleManagedSave = staleManagedSave(managedSavePath, info.Name, managedSaveMaxAge, now)
+		}
+		violations = append(violations, violation)
+		if dryRun {
+			continue
+		}
+		if violation.Autostart {
+			if err := l.virt.DomainSetAutostart(domain, 0); err != nil {
+				errs = append(errs, fmt.Errorf("failed to disable autostart of domain %s: %w", info.UUID, err))
+			}
+		}
+		if violation.StaleManagedSave {
+			if err := l.virt.DomainManagedSaveRemove(domain, 0); err != nil {
+				errs = append(errs, fmt.Errorf("failed to remove managed save of domain %s: %w", info.UUID, err))
+			}
+		}
+	}
+	return violations, errors.Join(errs...)
+}
@@ -0,0 +1,73 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package libvirt
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseDomainPolicyMode(t *testing.T) {
+	for _, value := range []string{"off", "dry-run", "enforce"} {
+		mode, err := ParseDomainPolicyMode(value)
+		if err != nil || string(mode) != value {
+			t.Errorf("Expected mode %s, got %s (%v)", value, mode, err)
+		}
+	}
+	if _, err := ParseDomainPolicyMode("on"); err == nil {
+		t.Errorf("Expected error for invalid mode")
+	}
+}
+
+func TestDomainPolicyViolation_String(t *testing.T) {
+	violation := DomainPolicyViolation{Name: "instance-0001", Autostart: true, ManagedSave: true}
+	if got := violation.String(); got != "instance-0001: autostart, managed-save" {
+		t.Errorf("Unexpected summary %q", got)
+	}
+	violation.Autostart = false
+	if got := violation.String(); got != "instance-0001: managed-save" {
+		t.Errorf("Unexpected summary %q", got)
+	}
+}
+
+func TestStaleManagedSave(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "instance-0001.save")
+	if err := os.WriteFile(path, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	if err := os.Chtimes(path, now.Add(-48*time.Hour), now.Add(-48*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	if !staleManagedSave(dir, "instance-0001", 24*time.Hour, now) {
+		t.Errorf("Expected the image to be stale")
+	}
+	if staleManagedSave(dir, "instance-0001", 72*time.Hour, now) {
+		t.Errorf("Expected the image not to be stale before the maximum age")
+	}
+	if staleManagedSave(dir, "instance-0001", 0, now) {
+		t.Errorf("Expected no image to be stale without a maximum age")
+	}
+	if staleManagedSave(dir, "instance-0002", 24*time.Hour, now) {
+		t.Errorf("Expected a missing image not to be stale")
+	}
+}
@@ -0,0 +1,52 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package libvirt
+
+import (
+	"encoding/xml"
+	"fmt"
+
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/libvirt/dominfo"
+)
+
+// DomainCPUDescriber provides the cpu of single domains, to check whether
+// they can be live migrated to another host.
+type DomainCPUDescriber interface {
+	// DomainCPU returns the cpu of the running domain as seen by the guest,
+	// nil if the domain doesn't define one. A domain that doesn't exist is
+	// reported with virterr.ErrDomainNotFound.
+	DomainCPU(uuid string) (*dominfo.DomainCPU, error)
+}
+
+// Get the cpu of the domain from its live definition, in which libvirt
+// expanded a host-model cpu into the model and features the guest sees.
+func (l *LibVirt) DomainCPU(uuid string) (*dominfo.DomainCPU, error) {
+	domain, err := l.lookupDomain(uuid)
+	if err != nil {
+		return nil, err
+	}
+	desc, err := l.virt.DomainGetXMLDesc(domain, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get definition of domain %s: %w", uuid, err)
+	}
+	var info dominfo.DomainInfo
+	if err := xml.Unmarshal([]byte(desc), &info); err != nil {
+		return nil, fmt.Errorf("failed to parse definition of domain %s: %w", uuid, err)
+	}
+	return info.CPU, nil
+}
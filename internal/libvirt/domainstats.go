@@ -0,0 +1,72 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package libvirt
+
+import (
+	"sync"
+	"time"
+
+	"github.com/digitalocean/go-libvirt"
+)
+
+// How long the domain stats are served from the cache before libvirt is
+// asked again.
+var domainStatsTTL = 30 * time.Second
+
+// Stats groups fetched for all active domains at once. Groups not supported
+// by the hypervisor driver are left out by libvirt.
+const domainStatsTypes = libvirt.DomainStatsBlock | libvirt.DomainStatsVCPU | libvirt.DomainStatsDirtyrate
+
+// Cache of the stats of all active domains, shared by the block device
+// metrics and the hypervisor status, so that libvirt is only asked once
+// per TTL instead of once per consumer.
+type domainStatsCache struct {
+	lock      sync.Mutex
+	records   []libvirt.DomainStatsRecord
+	fetchedAt time.Time
+}
+
+// Get the cached records and the time they were fetched at, or fetch them
+// if the cache expired. Errors are not cached.
+func (c *domainStatsCache) get(
+	now time.Time, fetch func() ([]libvirt.DomainStatsRecord, error),
+) ([]libvirt.DomainStatsRecord, time.Time, error) {
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if !c.fetchedAt.IsZero() && now.Sub(c.fetchedAt) < domainStatsTTL {
+		return c.records, c.fetchedAt, nil
+	}
+	records, err := fetch()
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	c.records, c.fetchedAt = records, now
+	return records, now, nil
+}
+
+// Get the stats of all active domains from the cache.
+func (l *LibVirt) domainStats() ([]libvirt.DomainStatsRecord, time.Time, error) {
+	return l.stats.get(time.Now(), func() ([]libvirt.DomainStatsRecord, error) {
+		return l.virt.ConnectGetAllDomainStats(
+			nil,
+			uint32(domainStatsTypes),
+			uint32(libvirt.ConnectGetAllDomainsStatsActive),
+		)
+	})
+}
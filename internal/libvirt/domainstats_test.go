@@ -0,0 +1,64 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package libvirt
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/digitalocean/go-libvirt"
+)
+
+func TestDomainStatsCache(t *testing.T) {
+	var c domainStatsCache
+	fetches := 0
+	fetch := func() ([]libvirt.DomainStatsRecord, error) {
+		fetches++
+		return []libvirt.DomainStatsRecord{{Dom: libvirt.Domain{Name: "instance-1"}}}, nil
+	}
+
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	records, at, err := c.get(start, fetch)
+	if err != nil || len(records) != 1 || !at.Equal(start) {
+		t.Fatalf("Expected records fetched at %v, got %v %v %v", start, records, at, err)
+	}
+	// Served from the cache within the ttl, with the original fetch time.
+	records, at, err = c.get(start.Add(domainStatsTTL-time.Second), fetch)
+	if err != nil || len(records) != 1 || !at.Equal(start) || fetches != 1 {
+		t.Errorf("Expected cached records, got %v %v %v after %d fetches", records, at, err, fetches)
+	}
+	// Fetched again once the ttl expired.
+	later := start.Add(domainStatsTTL)
+	if _, at, _ = c.get(later, fetch); !at.Equal(later) || fetches != 2 {
+		t.Errorf("Expected records to be fetched again, got %v after %d fetches", at, fetches)
+	}
+
+	// Errors are not cached.
+	failing := func() ([]libvirt.DomainStatsRecord, error) {
+		fetches++
+		return nil, errors.New("connection reset")
+	}
+	expired := later.Add(domainStatsTTL)
+	if _, _, err := c.get(expired, failing); err == nil {
+		t.Errorf("Expected an error")
+	}
+	if _, at, err := c.get(expired, fetch); err != nil || !at.Equal(expired) || fetches != 4 {
+		t.Errorf("Expected records to be fetched after an error, got %v %v after %d fetches", at, err, fetches)
+	}
+}
@@ -56,17 +56,29 @@ func (m *client) Get(virt *libvirt.Libvirt) (DomainCapabilities, error) {
 }
 
 // Emulated domain capabilities client returning an embedded capabilities xml.
-type clientEmulator struct{}
+type clientEmulator struct {
+	example []byte
+}
 
-// Create a new emulated domain capabilities client.
+// Create a new emulated domain capabilities client of an x86_64 host.
 func NewClientEmulator() Client {
-	return &clientEmulator{}
+	return NewClientEmulatorForArch("x86_64")
+}
+
+// Create a new emulated domain capabilities client of a host with the architecture,
+// e.g. aarch64, falling back to x86_64 for unknown ones.
+func NewClientEmulatorForArch(arch string) Client {
+	example, ok := examples[arch]
+	if !ok {
+		example = exampleXML
+	}
+	return &clientEmulator{example: example}
 }
 
 // Get the domain capabilities of the host we are mounted on.
 func (c *clientEmulator) Get(virt *libvirt.Libvirt) (DomainCapabilities, error) {
 	var capabilities DomainCapabilities
-	if err := xml.Unmarshal(exampleXML, &capabilities); err != nil {
+	if err := xml.Unmarshal(c.example, &capabilities); err != nil {
 		log.Log.Error(err, "failed to unmarshal example capabilities")
 		return DomainCapabilities{}, err
 	}
@@ -483,3 +483,25 @@ func TestClientEmulator_Get_MultipleFields(t *testing.T) {
 		t.Error("Expected at least one field to be populated in domain capabilities")
 	}
 }
+
+func TestNewClientEmulatorForArch(t *testing.T) {
+	capabilities, err := NewClientEmulatorForArch("aarch64").Get(nil)
+	if err != nil {
+		t.Fatalf("Get() returned unexpected error: %v", err)
+	}
+	if capabilities.Arch != "aarch64" {
+		t.Errorf("Expected arch 'aarch64', got '%s'", capabilities.Arch)
+	}
+	var gic *DomainCapabilitiesFeature
+	for i, feature := range capabilities.Features.Features {
+		if feature.XMLName.Local == "gic" {
+			gic = &capabilities.Features.Features[i]
+		}
+	}
+	if gic == nil || gic.Supported != "yes" {
+		t.Fatal("Expected the gic feature to be supported")
+	}
+	if len(gic.Enums) != 1 || gic.Enums[0].Name != "version" || len(gic.Enums[0].Values) != 2 {
+		t.Errorf("Expected the gic versions 2 and 3, got %+v", gic.Enums)
+	}
+}
@@ -23,3 +23,12 @@ import (
 
 //go:embed example.xml
 var exampleXML []byte
+
+//go:embed example_aarch64.xml
+var exampleAarch64XML []byte
+
+// Example xml by architecture, the x86_64 one is used for unknown ones.
+var examples = map[string][]byte{
+	"x86_64":  exampleXML,
+	"aarch64": exampleAarch64XML,
+}
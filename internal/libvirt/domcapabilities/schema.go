@@ -25,6 +25,8 @@ import "encoding/xml"
 // See: https://www.libvirt.org/manpages/virsh.html#domcapabilities
 // For another reference see: https://gitlab.com/libvirt/libvirt-go-xml-module/-/blob/v1.11010.0/domain_capabilities.go
 type DomainCapabilities struct {
+	// Path of the emulator binary, e.g. /usr/bin/qemu-system-x86_64.
+	Emulator string                     `xml:"path"`
 	Domain   string                     `xml:"domain"`
 	Arch     string                     `xml:"arch"`
 	OS       DomainCapabilitiesOS       `xml:"os"`
@@ -35,14 +37,18 @@ type DomainCapabilities struct {
 
 // DomainCapabilitiesOS represents the OS capabilities section.
 type DomainCapabilitiesOS struct {
-	Supported string                     `xml:"supported,attr"`
-	Loader    DomainCapabilitiesOSLoader `xml:"loader"`
+	Supported string `xml:"supported,attr"`
+	// The firmware types, e.g. bios and efi, in the enum named firmware.
+	Enums  []DomainCapabilitiesEnum   `xml:"enum"`
+	Loader DomainCapabilitiesOSLoader `xml:"loader"`
 }
 
 // DomainCapabilitiesOSLoader represents the loader capabilities.
 type DomainCapabilitiesOSLoader struct {
-	Supported string                   `xml:"supported,attr"`
-	Enums     []DomainCapabilitiesEnum `xml:"enum"`
+	Supported string `xml:"supported,attr"`
+	// Paths of the firmware images available as loader.
+	Values []string                 `xml:"value"`
+	Enums  []DomainCapabilitiesEnum `xml:"enum"`
 }
 
 // DomainCapabilitiesEnum represents an enumeration of possible values.
@@ -79,6 +85,13 @@ type DomainCapabilitiesDevices struct {
 type DomainCapabilitiesFeature struct {
 	XMLName   xml.Name `xml:""`
 	Supported string   `xml:"supported,attr"`
+	// Number of SEV and SEV-ES domains that can run at the same time, only
+	// reported for the sev feature.
+	MaxGuests   int `xml:"maxGuests,omitempty"`
+	MaxESGuests int `xml:"maxESGuests,omitempty"`
+	// Values of the feature, e.g. the supported versions of the interrupt
+	// controller in the enum named version of the gic feature on aarch64.
+	Enums []DomainCapabilitiesEnum `xml:"enum"`
 }
 
 // DomainCapabilitiesFeatures represents the features capabilities section.
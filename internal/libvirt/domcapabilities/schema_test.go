@@ -204,3 +204,31 @@ func TestDomainCapabilitiesRoundTrip(t *testing.T) {
 			len(domainCapabilities.Features.Features), len(roundTripDomainCapabilities.Features.Features))
 	}
 }
+
+func TestDomainCapabilitiesSEVFeature(t *testing.T) {
+	data := []byte(`<domainCapabilities>
+  <features>
+    <sev supported='yes'>
+      <cbitpos>51</cbitpos>
+      <reducedPhysBits>1</reducedPhysBits>
+      <maxGuests>15</maxGuests>
+      <maxESGuests>494</maxESGuests>
+    </sev>
+    <tdx supported='no'/>
+  </features>
+</domainCapabilities>`)
+	var domainCapabilities DomainCapabilities
+	if err := xml.Unmarshal(data, &domainCapabilities); err != nil {
+		t.Fatalf("Failed to unmarshal XML: %v", err)
+	}
+	features := domainCapabilities.Features.Features
+	if len(features) != 2 {
+		t.Fatalf("Expected 2 features, got %d", len(features))
+	}
+	if features[0].XMLName.Local != "sev" || features[0].MaxGuests != 15 || features[0].MaxESGuests != 494 {
+		t.Errorf("Unexpected sev feature %+v", features[0])
+	}
+	if features[1].XMLName.Local != "tdx" || features[1].Supported != "no" {
+		t.Errorf("Unexpected tdx feature %+v", features[1])
+	}
+}
@@ -0,0 +1,94 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package libvirt
+
+import (
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/libvirt/domcapabilities"
+)
+
+// DomainCapabilitiesCache caches the domain capabilities of the host, which
+// only change when libvirt or the hypervisor is upgraded.
+type DomainCapabilitiesCache interface {
+	// InvalidateDomainCapabilities drops the cached domain capabilities, so
+	// that they are fetched again, e.g. after an operating system update.
+	InvalidateDomainCapabilities()
+}
+
+// Domain capabilities with the versions and the emulator binary they were
+// fetched for.
+type domainCapabilitiesCache struct {
+	lock sync.Mutex
+	key  string
+	caps *domcapabilities.DomainCapabilities
+}
+
+// Get the cache key of the domain capabilities from the libvirt and
+// hypervisor versions and the modification time of the emulator binary,
+// if it is visible to the agent.
+func domainCapabilitiesKey(version, hypervisorVersion, emulator string) string {
+	key := version + "/" + hypervisorVersion
+	if emulator == "" {
+		return key
+	}
+	if info, err := os.Stat(emulator); err == nil {
+		key += fmt.Sprintf("/%s@%d", emulator, info.ModTime().UnixNano())
+	}
+	return key
+}
+
+// Get the cached domain capabilities if the versions and the emulator
+// binary are unchanged, or fetch them. Errors are not cached.
+func (c *domainCapabilitiesCache) get(
+	version, hypervisorVersion string, fetch func() (domcapabilities.DomainCapabilities, error),
+) (domcapabilities.DomainCapabilities, error) {
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.caps != nil && c.key == domainCapabilitiesKey(version, hypervisorVersion, c.caps.Emulator) {
+		return *c.caps, nil
+	}
+	caps, err := fetch()
+	if err != nil {
+		return domcapabilities.DomainCapabilities{}, err
+	}
+	c.caps = &caps
+	c.key = domainCapabilitiesKey(version, hypervisorVersion, caps.Emulator)
+	return caps, nil
+}
+
+func (c *domainCapabilitiesCache) invalidate() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.caps = nil
+}
+
+// Get the domain capabilities, from the cache if possible.
+func (l *LibVirt) domainCapabilities() (domcapabilities.DomainCapabilities, error) {
+	return l.domCaps.get(l.version, l.hypervisorVersion, func() (domcapabilities.DomainCapabilities, error) {
+		return l.domainCapabilitiesClient.Get(l.virt)
+	})
+}
+
+// Drop the cached domain capabilities.
+func (l *LibVirt) InvalidateDomainCapabilities() {
+	l.domCaps.invalidate()
+}
@@ -0,0 +1,75 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package libvirt
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/libvirt/domcapabilities"
+)
+
+func TestDomainCapabilitiesCache(t *testing.T) {
+	emulator := filepath.Join(t.TempDir(), "qemu-system-x86_64")
+	if err := os.WriteFile(emulator, nil, 0755); err != nil {
+		t.Fatal(err)
+	}
+	fetches := 0
+	fetch := func() (domcapabilities.DomainCapabilities, error) {
+		fetches++
+		return domcapabilities.DomainCapabilities{Domain: "kvm", Emulator: emulator}, nil
+	}
+
+	var c domainCapabilitiesCache
+	for range 3 {
+		caps, err := c.get("10.5.0", "9.0.0", fetch)
+		if err != nil || caps.Domain != "kvm" {
+			t.Fatalf("Expected domain capabilities, got %+v %v", caps, err)
+		}
+	}
+	if fetches != 1 {
+		t.Errorf("Expected the domain capabilities to be fetched once, got %d", fetches)
+	}
+
+	// Fetched again after an upgrade of libvirt or the emulator.
+	if _, err := c.get("10.6.0", "9.0.0", fetch); err != nil || fetches != 2 {
+		t.Errorf("Expected a fetch after the libvirt upgrade, got %d fetches %v", fetches, err)
+	}
+	later := time.Now().Add(time.Hour)
+	if err := os.Chtimes(emulator, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.get("10.6.0", "9.0.0", fetch); err != nil || fetches != 3 {
+		t.Errorf("Expected a fetch after the emulator changed, got %d fetches %v", fetches, err)
+	}
+
+	// Fetched again after invalidation, errors are not cached.
+	c.invalidate()
+	failing := func() (domcapabilities.DomainCapabilities, error) {
+		return domcapabilities.DomainCapabilities{}, errors.New("connection lost")
+	}
+	if _, err := c.get("10.6.0", "9.0.0", failing); err == nil {
+		t.Errorf("Expected an error")
+	}
+	if _, err := c.get("10.6.0", "9.0.0", fetch); err != nil || fetches != 4 {
+		t.Errorf("Expected a fetch after the invalidation, got %d fetches %v", fetches, err)
+	}
+}
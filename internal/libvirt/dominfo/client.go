@@ -19,9 +19,12 @@ package dominfo
 
 import (
 	"encoding/xml"
+	"errors"
 
 	libvirt "github.com/digitalocean/go-libvirt"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/libvirt/virterr"
 )
 
 // Client that returns information for all domains on our host.
@@ -59,6 +62,10 @@ func (m *client) Get(
 	var domainInfos []DomainInfo
 	for _, domain := range domains {
 		domainXML, err := virt.DomainGetXMLDesc(domain, 0)
+		if err = virterr.Classify(err); errors.Is(err, virterr.ErrDomainNotFound) {
+			// The domain was undefined after listing it.
+			continue
+		}
 		if err != nil {
 			log.Log.Error(err, "failed to get domain xml", "domain", domain.Name)
 			return nil, err
@@ -0,0 +1,134 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dominfo
+
+import (
+	"strings"
+	"time"
+)
+
+// Layout of the creation time in the nova metadata, which is in UTC.
+const novaTimeLayout = "2006-01-02 15:04:05"
+
+// Get the nova metadata of the domain, or nil if the domain
+// was not created by nova.
+func (d DomainInfo) nova() *NovaInstance {
+	if d.Metadata == nil {
+		return nil
+	}
+	return d.Metadata.NovaInstance
+}
+
+// Get the nova flavor the domain was created with, or nil if the domain
+// has no nova metadata.
+func (d DomainInfo) Flavor() *NovaFlavor {
+	nova := d.nova()
+	if nova == nil {
+		return nil
+	}
+	return nova.Flavor
+}
+
+// Get the value of the given extra spec of the nova flavor. Returns an
+// empty string if the extra spec is not set.
+func (d DomainInfo) FlavorExtraSpec(name string) string {
+	nova := d.nova()
+	if nova == nil || nova.Flavor == nil {
+		return ""
+	}
+	for _, spec := range nova.Flavor.ExtraSpecs {
+		if spec.Name == name {
+			return spec.Value
+		}
+	}
+	return ""
+}
+
+// Check if the flavor requests multiple queues per network interface.
+func (d DomainInfo) MultiqueueRequested() bool {
+	return strings.EqualFold(d.FlavorExtraSpec("hw:vif_multiqueue_enabled"), "true")
+}
+
+// Get the uuid of the openstack project owning the domain.
+// Returns an empty string if the domain has no nova metadata.
+func (d DomainInfo) ProjectUUID() string {
+	nova := d.nova()
+	if nova == nil || nova.Owner == nil || nova.Owner.Project == nil {
+		return ""
+	}
+	return nova.Owner.Project.UUID
+}
+
+// Get the uuid of the openstack user who created the domain.
+// Returns an empty string if the domain has no nova metadata.
+func (d DomainInfo) UserUUID() string {
+	nova := d.nova()
+	if nova == nil || nova.Owner == nil || nova.Owner.User == nil {
+		return ""
+	}
+	return nova.Owner.User.UUID
+}
+
+// Get the name of the instance in nova, which differs from the libvirt
+// name of the domain. Returns an empty string if the domain has no nova
+// metadata.
+func (d DomainInfo) InstanceName() string {
+	nova := d.nova()
+	if nova == nil {
+		return ""
+	}
+	return nova.Name
+}
+
+// Get the time the instance was created in nova. Returns false if the
+// domain has no nova metadata or the creation time can't be parsed.
+func (d DomainInfo) CreationTime() (time.Time, bool) {
+	nova := d.nova()
+	if nova == nil || nova.CreationTime == "" {
+		return time.Time{}, false
+	}
+	created, err := time.Parse(novaTimeLayout, nova.CreationTime)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return created, true
+}
+
+// Get the fixed ip addresses of the domain across all ports. IPv4
+// addresses are listed before IPv6 addresses, otherwise the order of the
+// nova metadata is kept. Returns nil if no fixed ip is known.
+func (d DomainInfo) FixedIPs() []string {
+	nova := d.nova()
+	if nova == nil || nova.Ports == nil {
+		return nil
+	}
+	var ipv4, ipv6 []string
+	for _, port := range nova.Ports.Ports {
+		for _, ip := range port.IPs {
+			if ip.Type != "fixed" {
+				continue
+			}
+			if ip.IPVersion == "4" {
+				ipv4 = append(ipv4, ip.Address)
+			} else {
+				ipv6 = append(ipv6, ip.Address)
+			}
+		}
+	}
+	return append(ipv4, ipv6...)
+}
@@ -0,0 +1,107 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dominfo
+
+import (
+	"encoding/xml"
+	"testing"
+	"time"
+)
+
+func TestNovaSummary_ExampleXML(t *testing.T) {
+	var domainInfo DomainInfo
+	if err := xml.Unmarshal(exampleXML, &domainInfo); err != nil {
+		t.Fatalf("Failed to unmarshal XML: %v", err)
+	}
+	if got := domainInfo.ProjectUUID(); got != "12345-abc" {
+		t.Errorf("Expected project uuid '12345-abc', got '%s'", got)
+	}
+	if got := domainInfo.FixedIPs(); len(got) != 1 || got[0] != "0.0.0.0" {
+		t.Errorf("Expected fixed ips [0.0.0.0], got %v", got)
+	}
+	if got := domainInfo.FlavorExtraSpec("hw:vif_multiqueue_enabled"); got != "true" {
+		t.Errorf("Expected multiqueue extra spec 'true', got '%s'", got)
+	}
+	if !domainInfo.MultiqueueRequested() {
+		t.Errorf("Expected multiqueue to be requested")
+	}
+	if flavor := domainInfo.Flavor(); flavor == nil || flavor.Name != "g_k_c6_m24_v2" || flavor.Memory != 24560 || flavor.VCPUs != 6 {
+		t.Errorf("Unexpected flavor: %+v", flavor)
+	}
+	if got := domainInfo.UserUUID(); got != "12345-abc" {
+		t.Errorf("Expected user uuid '12345-abc', got '%s'", got)
+	}
+	if got := domainInfo.InstanceName(); got != "example-12345-abc" {
+		t.Errorf("Expected instance name 'example-12345-abc', got '%s'", got)
+	}
+	expected := time.Date(2025, 12, 18, 0, 49, 23, 0, time.UTC)
+	if got, ok := domainInfo.CreationTime(); !ok || !got.Equal(expected) {
+		t.Errorf("Expected creation time %s, got %s", expected, got)
+	}
+}
+
+func TestNovaSummary_NoMetadata(t *testing.T) {
+	domainInfo := DomainInfo{Name: "instance-without-nova"}
+	if got := domainInfo.ProjectUUID(); got != "" {
+		t.Errorf("Expected empty project uuid, got '%s'", got)
+	}
+	if got := domainInfo.FixedIPs(); len(got) != 0 {
+		t.Errorf("Expected no fixed ips, got %v", got)
+	}
+	if domainInfo.MultiqueueRequested() {
+		t.Errorf("Expected multiqueue not to be requested")
+	}
+	if flavor := domainInfo.Flavor(); flavor != nil {
+		t.Errorf("Expected no flavor, got %+v", flavor)
+	}
+	if got := domainInfo.UserUUID(); got != "" {
+		t.Errorf("Expected empty user uuid, got '%s'", got)
+	}
+	if _, ok := domainInfo.CreationTime(); ok {
+		t.Errorf("Expected no creation time")
+	}
+}
+
+func TestFixedIPs_PrefersIPv4(t *testing.T) {
+	domainInfo := DomainInfo{
+		Metadata: &DomainMetadata{
+			NovaInstance: &NovaInstance{
+				Ports: &NovaPorts{
+					Ports: []NovaPort{
+						{IPs: []NovaIP{
+							{Type: "floating", Address: "10.0.0.1", IPVersion: "4"},
+							{Type: "fixed", Address: "fd00::1", IPVersion: "6"},
+						}},
+						{IPs: []NovaIP{
+							{Type: "fixed", Address: "192.168.0.10", IPVersion: "4"},
+						}},
+					},
+				},
+			},
+		},
+	}
+	if got := domainInfo.FixedIPs(); len(got) != 2 || got[0] != "192.168.0.10" || got[1] != "fd00::1" {
+		t.Errorf("Expected fixed ips [192.168.0.10 fd00::1], got %v", got)
+	}
+
+	// Without any fixed IPv4 address, only the fixed IPv6 address is left.
+	domainInfo.Metadata.NovaInstance.Ports.Ports = domainInfo.Metadata.NovaInstance.Ports.Ports[:1]
+	if got := domainInfo.FixedIPs(); len(got) != 1 || got[0] != "fd00::1" {
+		t.Errorf("Expected fixed ips [fd00::1], got %v", got)
+	}
+}
@@ -70,12 +70,19 @@ type NovaPackage struct {
 
 // NovaFlavor represents the instance flavor.
 type NovaFlavor struct {
-	Name      string `xml:"name,attr"`
-	Memory    int    `xml:"memory"`
-	Disk      int    `xml:"disk"`
-	Swap      int    `xml:"swap"`
-	Ephemeral int    `xml:"ephemeral"`
-	VCPUs     int    `xml:"vcpus"`
+	Name       string          `xml:"name,attr"`
+	Memory     int             `xml:"memory"`
+	Disk       int             `xml:"disk"`
+	Swap       int             `xml:"swap"`
+	Ephemeral  int             `xml:"ephemeral"`
+	VCPUs      int             `xml:"vcpus"`
+	ExtraSpecs []NovaExtraSpec `xml:"extraSpecs>extraSpec,omitempty"`
+}
+
+// NovaExtraSpec represents an extra spec of the flavor.
+type NovaExtraSpec struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:",chardata"`
 }
 
 // NovaOwner represents the instance owner.
@@ -128,7 +135,21 @@ type DomainMemory struct {
 
 // DomainMemoryBacking represents memory backing configuration.
 type DomainMemoryBacking struct {
-	HugePages *DomainHugePages `xml:"hugepages,omitempty"`
+	HugePages *DomainHugePages    `xml:"hugepages,omitempty"`
+	Source    *DomainMemorySource `xml:"source,omitempty"`
+	Access    *DomainMemoryAccess `xml:"access,omitempty"`
+}
+
+// DomainMemorySource represents the backing of the guest memory, e.g.
+// "memfd" or "file".
+type DomainMemorySource struct {
+	Type string `xml:"type,attr"`
+}
+
+// DomainMemoryAccess represents whether the guest memory is shared with
+// other processes, which vhost-user devices like virtio-fs require.
+type DomainMemoryAccess struct {
+	Mode string `xml:"mode,attr"`
 }
 
 // DomainHugePages represents huge pages configuration.
@@ -146,6 +167,7 @@ type DomainPage struct {
 // DomainVCPU represents virtual CPU configuration.
 type DomainVCPU struct {
 	Placement string `xml:"placement,attr,omitempty"`
+	Current   int    `xml:"current,attr,omitempty"`
 	Value     int    `xml:",chardata"`
 }
 
@@ -211,10 +233,27 @@ type DomainBoot struct {
 // DomainCPU represents CPU configuration.
 type DomainCPU struct {
 	Mode     string             `xml:"mode,attr,omitempty"`
+	Model    *DomainCPUModel    `xml:"model,omitempty"`
+	Vendor   string             `xml:"vendor,omitempty"`
 	Topology *DomainCPUTopology `xml:"topology,omitempty"`
+	Features []DomainCPUFeature `xml:"feature"`
 	Numa     *DomainCPUNuma     `xml:"numa,omitempty"`
 }
 
+// DomainCPUModel represents the cpu model of a custom or host-model cpu.
+type DomainCPUModel struct {
+	Fallback string `xml:"fallback,attr,omitempty"`
+	Value    string `xml:",chardata"`
+}
+
+// DomainCPUFeature represents a cpu feature added to or removed from the
+// model.
+type DomainCPUFeature struct {
+	// One of force, require, optional, disable or forbid.
+	Policy string `xml:"policy,attr,omitempty"`
+	Name   string `xml:"name,attr"`
+}
+
 // DomainCPUTopology represents CPU topology.
 type DomainCPUTopology struct {
 	Sockets  int `xml:"sockets,attr"`
@@ -245,10 +284,197 @@ type DomainClock struct {
 
 // DomainDevices represents all devices.
 type DomainDevices struct {
-	Emulator   string            `xml:"emulator,omitempty"`
-	Disks      []DomainDisk      `xml:"disk,omitempty"`
-	Interfaces []DomainInterface `xml:"interface,omitempty"`
-	Serials    []DomainSerial    `xml:"serial,omitempty"`
+	Emulator    string             `xml:"emulator,omitempty"`
+	Disks       []DomainDisk       `xml:"disk,omitempty"`
+	Interfaces  []DomainInterface  `xml:"interface,omitempty"`
+	Serials     []DomainSerial     `xml:"serial,omitempty"`
+	Hostdevs    []DomainHostdev    `xml:"hostdev,omitempty"`
+	RNGs        []DomainRNG        `xml:"rng,omitempty"`
+	Channels    []DomainChannel    `xml:"channel,omitempty"`
+	Filesystems []DomainFilesystem `xml:"filesystem,omitempty"`
+	Controllers []DomainController `xml:"controller,omitempty"`
+	Videos      []DomainVideo      `xml:"video,omitempty"`
+	TPMs        []DomainTPM        `xml:"tpm,omitempty"`
+	MemBalloon  *DomainMemBalloon  `xml:"memballoon,omitempty"`
+}
+
+// DomainController represents a bus controller, e.g. of the pci or usb
+// devices.
+type DomainController struct {
+	Type  string       `xml:"type,attr"`
+	Index int          `xml:"index,attr"`
+	Model string       `xml:"model,attr,omitempty"`
+	Alias *DomainAlias `xml:"alias,omitempty"`
+}
+
+// DomainVideo represents a video device.
+type DomainVideo struct {
+	Model *DomainVideoModel `xml:"model,omitempty"`
+	Alias *DomainAlias      `xml:"alias,omitempty"`
+}
+
+// DomainVideoModel represents the model of a video device, e.g. "virtio"
+// or "vga".
+type DomainVideoModel struct {
+	Type    string `xml:"type,attr"`
+	VRAM    int    `xml:"vram,attr,omitempty"`
+	Heads   int    `xml:"heads,attr,omitempty"`
+	Primary string `xml:"primary,attr,omitempty"`
+}
+
+// DomainTPM represents a trusted platform module.
+type DomainTPM struct {
+	Model   string            `xml:"model,attr,omitempty"`
+	Backend *DomainTPMBackend `xml:"backend,omitempty"`
+	Alias   *DomainAlias      `xml:"alias,omitempty"`
+}
+
+// DomainTPMBackend represents the emulated or passed through tpm of the
+// host.
+type DomainTPMBackend struct {
+	Type            string               `xml:"type,attr"`
+	Version         string               `xml:"version,attr,omitempty"`
+	PersistentState string               `xml:"persistent_state,attr,omitempty"`
+	Encryption      *DomainTPMEncryption `xml:"encryption,omitempty"`
+	Source          *DomainTPMSource     `xml:"source,omitempty"`
+	Device          *DomainTPMDevice     `xml:"device,omitempty"`
+}
+
+// DomainTPMEncryption references the libvirt secret the state of the
+// emulated tpm is encrypted with.
+type DomainTPMEncryption struct {
+	Secret string `xml:"secret,attr"`
+}
+
+// DomainTPMSource overrides where the state of the emulated tpm is kept.
+type DomainTPMSource struct {
+	Type string `xml:"type,attr,omitempty"`
+	Path string `xml:"path,attr"`
+}
+
+// DomainTPMDevice is the tpm of the host passed through to the domain.
+type DomainTPMDevice struct {
+	Path string `xml:"path,attr"`
+}
+
+// DomainMemBalloon represents the memory balloon device.
+type DomainMemBalloon struct {
+	Model       string                 `xml:"model,attr"`
+	Autodeflate string                 `xml:"autodeflate,attr,omitempty"`
+	Stats       *DomainMemBalloonStats `xml:"stats,omitempty"`
+	Alias       *DomainAlias           `xml:"alias,omitempty"`
+}
+
+// DomainMemBalloonStats represents the period in which the guest reports
+// its memory statistics through the balloon.
+type DomainMemBalloonStats struct {
+	Period int `xml:"period,attr"`
+}
+
+// DomainFilesystem represents a host directory shared with the guest,
+// e.g. through virtio-fs.
+type DomainFilesystem struct {
+	Type       string                  `xml:"type,attr,omitempty"`
+	AccessMode string                  `xml:"accessmode,attr,omitempty"`
+	Driver     *DomainFilesystemDriver `xml:"driver,omitempty"`
+	Binary     *DomainFilesystemBinary `xml:"binary,omitempty"`
+	Source     *DomainFilesystemSource `xml:"source,omitempty"`
+	Target     *DomainFilesystemTarget `xml:"target,omitempty"`
+	Alias      *DomainAlias            `xml:"alias,omitempty"`
+}
+
+// DomainFilesystemDriver represents the driver of a filesystem, e.g.
+// "virtiofs" with the size of its request queue.
+type DomainFilesystemDriver struct {
+	Type  string `xml:"type,attr,omitempty"`
+	Queue int    `xml:"queue,attr,omitempty"`
+}
+
+// DomainFilesystemBinary represents the virtiofsd daemon started by
+// libvirt.
+type DomainFilesystemBinary struct {
+	Path  string `xml:"path,attr,omitempty"`
+	Xattr string `xml:"xattr,attr,omitempty"`
+}
+
+// DomainFilesystemSource represents the shared host directory, or the
+// socket of an externally started virtiofsd.
+type DomainFilesystemSource struct {
+	Dir    string `xml:"dir,attr,omitempty"`
+	Socket string `xml:"socket,attr,omitempty"`
+}
+
+// DomainFilesystemTarget represents the tag the guest mounts the
+// filesystem by.
+type DomainFilesystemTarget struct {
+	Dir string `xml:"dir,attr"`
+}
+
+// DomainChannel represents a channel between host and guest, e.g. of the
+// qemu guest agent.
+type DomainChannel struct {
+	Type   string               `xml:"type,attr"`
+	Target *DomainChannelTarget `xml:"target,omitempty"`
+}
+
+// DomainChannelTarget represents the guest side of a channel. The state is
+// only reported for running domains.
+type DomainChannelTarget struct {
+	Type  string `xml:"type,attr"`
+	Name  string `xml:"name,attr,omitempty"`
+	State string `xml:"state,attr,omitempty"`
+}
+
+// DomainRNG represents a random number generator device.
+type DomainRNG struct {
+	Model   string            `xml:"model,attr"`
+	Rate    *DomainRNGRate    `xml:"rate,omitempty"`
+	Backend *DomainRNGBackend `xml:"backend,omitempty"`
+	Alias   *DomainAlias      `xml:"alias,omitempty"`
+}
+
+// DomainRNGBackend represents the host source of the entropy.
+type DomainRNGBackend struct {
+	Model  string `xml:"model,attr"`
+	Source string `xml:",chardata"`
+}
+
+// DomainRNGRate represents the limit of the entropy passed to the guest.
+type DomainRNGRate struct {
+	Bytes  int `xml:"bytes,attr"`
+	Period int `xml:"period,attr,omitempty"`
+}
+
+// DomainHostdev represents a device passed through from the host.
+type DomainHostdev struct {
+	Mode    string               `xml:"mode,attr"`
+	Type    string               `xml:"type,attr"`
+	Managed string               `xml:"managed,attr,omitempty"`
+	Source  *DomainHostdevSource `xml:"source,omitempty"`
+	Alias   *DomainAlias         `xml:"alias,omitempty"`
+}
+
+// DomainHostdevSource represents the host device, by its pci address or
+// by the usb vendor and product id.
+type DomainHostdevSource struct {
+	Address *DomainHostdevAddress `xml:"address,omitempty"`
+	Vendor  *DomainHostdevID      `xml:"vendor,omitempty"`
+	Product *DomainHostdevID      `xml:"product,omitempty"`
+}
+
+// DomainHostdevAddress represents the pci address of a host device, or the
+// bus and device number of a usb device.
+type DomainHostdevAddress struct {
+	Domain   string `xml:"domain,attr,omitempty"`
+	Bus      string `xml:"bus,attr,omitempty"`
+	Slot     string `xml:"slot,attr,omitempty"`
+	Function string `xml:"function,attr,omitempty"`
+	Device   string `xml:"device,attr,omitempty"`
+}
+
+// DomainHostdevID represents a usb vendor or product id.
+type DomainHostdevID struct {
+	ID string `xml:"id,attr"`
 }
 
 // DomainDisk represents a disk device.
@@ -258,6 +484,7 @@ type DomainDisk struct {
 	Driver *DomainDiskDriver `xml:"driver,omitempty"`
 	Source *DomainDiskSource `xml:"source,omitempty"`
 	Target *DomainDiskTarget `xml:"target,omitempty"`
+	IOTune *DomainDiskIOTune `xml:"iotune,omitempty"`
 	Alias  *DomainAlias      `xml:"alias,omitempty"`
 }
 
@@ -273,6 +500,16 @@ type DomainDiskSource struct {
 	File string `xml:"file,attr,omitempty"`
 }
 
+// DomainDiskIOTune represents the io limits of a disk.
+type DomainDiskIOTune struct {
+	TotalBytesSec int64 `xml:"total_bytes_sec,omitempty"`
+	ReadBytesSec  int64 `xml:"read_bytes_sec,omitempty"`
+	WriteBytesSec int64 `xml:"write_bytes_sec,omitempty"`
+	TotalIOPSSec  int64 `xml:"total_iops_sec,omitempty"`
+	ReadIOPSSec   int64 `xml:"read_iops_sec,omitempty"`
+	WriteIOPSSec  int64 `xml:"write_iops_sec,omitempty"`
+}
+
 // DomainDiskTarget represents disk target.
 type DomainDiskTarget struct {
 	Dev string `xml:"dev,attr"`
@@ -300,6 +537,10 @@ type DomainInterfaceMAC struct {
 // DomainInterfaceSource represents network source.
 type DomainInterfaceSource struct {
 	Bridge string `xml:"bridge,attr,omitempty"`
+	// Socket of vhostuser interfaces.
+	Type string `xml:"type,attr,omitempty"`
+	Path string `xml:"path,attr,omitempty"`
+	Mode string `xml:"mode,attr,omitempty"`
 }
 
 // DomainInterfaceTarget represents network target.
@@ -314,8 +555,11 @@ type DomainInterfaceModel struct {
 
 // DomainInterfaceDriver represents network driver.
 type DomainInterfaceDriver struct {
-	Queues string `xml:"queues,attr,omitempty"`
-	Packed string `xml:"packed,attr,omitempty"`
+	Name        string `xml:"name,attr,omitempty"`
+	Queues      string `xml:"queues,attr,omitempty"`
+	RxQueueSize string `xml:"rx_queue_size,attr,omitempty"`
+	TxQueueSize string `xml:"tx_queue_size,attr,omitempty"`
+	Packed      string `xml:"packed,attr,omitempty"`
 }
 
 // DomainInterfaceMTU represents MTU configuration.
@@ -19,6 +19,7 @@ package dominfo
 
 import (
 	"encoding/xml"
+	"reflect"
 	"testing"
 )
 
@@ -180,6 +181,12 @@ func TestDomainInfoDeserialization(t *testing.T) {
 	if page.Nodeset != "0" {
 		t.Errorf("Expected page nodeset to be '0', got '%s'", page.Nodeset)
 	}
+	if domainInfo.MemoryBacking.Source == nil || domainInfo.MemoryBacking.Source.Type != "memfd" {
+		t.Errorf("Expected memfd memory source, got %+v", domainInfo.MemoryBacking.Source)
+	}
+	if domainInfo.MemoryBacking.Access == nil || domainInfo.MemoryBacking.Access.Mode != "shared" {
+		t.Errorf("Expected shared memory access, got %+v", domainInfo.MemoryBacking.Access)
+	}
 
 	// Verify VCPU
 	if domainInfo.VCPU == nil {
@@ -564,5 +571,149 @@ func TestDomainInfoRoundTrip(t *testing.T) {
 			t.Errorf("Serials count mismatch after round trip: expected %d, got %d",
 				len(domainInfo.Devices.Serials), len(roundTripDomainInfo.Devices.Serials))
 		}
+		if !reflect.DeepEqual(domainInfo.Devices.Filesystems, roundTripDomainInfo.Devices.Filesystems) {
+			t.Errorf("Filesystems mismatch after round trip: expected %+v, got %+v",
+				domainInfo.Devices.Filesystems, roundTripDomainInfo.Devices.Filesystems)
+		}
+	}
+	if !reflect.DeepEqual(domainInfo.MemoryBacking, roundTripDomainInfo.MemoryBacking) {
+		t.Errorf("Memory backing mismatch after round trip: expected %+v, got %+v",
+			domainInfo.MemoryBacking, roundTripDomainInfo.MemoryBacking)
+	}
+}
+
+func TestDomainFilesystemDeserialization(t *testing.T) {
+	var domainInfo DomainInfo
+	if err := xml.Unmarshal(exampleXML, &domainInfo); err != nil {
+		t.Fatalf("Failed to unmarshal XML: %v", err)
+	}
+	if len(domainInfo.Devices.Filesystems) != 1 {
+		t.Fatalf("Expected 1 filesystem, got %d", len(domainInfo.Devices.Filesystems))
+	}
+	fs := domainInfo.Devices.Filesystems[0]
+	expected := DomainFilesystem{
+		Type:       "mount",
+		AccessMode: "passthrough",
+		Driver:     &DomainFilesystemDriver{Type: "virtiofs", Queue: 1024},
+		Binary:     &DomainFilesystemBinary{Path: "/usr/libexec/virtiofsd", Xattr: "on"},
+		Source:     &DomainFilesystemSource{Dir: "/var/lib/nova/mnt/share-12345"},
+		Target:     &DomainFilesystemTarget{Dir: "share-12345"},
+		Alias:      &DomainAlias{Name: "fs0"},
+	}
+	if !reflect.DeepEqual(fs, expected) {
+		t.Errorf("Expected filesystem %+v, got %+v", expected, fs)
+	}
+}
+
+func TestDomainCPUModelDeserialization(t *testing.T) {
+	var cpu DomainCPU
+	err := xml.Unmarshal([]byte(`<cpu mode='custom' match='exact' check='full'>
+  <model fallback='forbid'>Cascadelake-Server-noTSX</model>
+  <vendor>Intel</vendor>
+  <feature policy='require' name='ss'/>
+  <feature policy='disable' name='mpx'/>
+</cpu>`), &cpu)
+	if err != nil {
+		t.Fatalf("Failed to unmarshal XML: %v", err)
+	}
+	if cpu.Mode != "custom" {
+		t.Errorf("Expected cpu mode to be 'custom', got '%s'", cpu.Mode)
+	}
+	if cpu.Model == nil || cpu.Model.Value != "Cascadelake-Server-noTSX" || cpu.Model.Fallback != "forbid" {
+		t.Errorf("Expected cpu model 'Cascadelake-Server-noTSX' without fallback, got %+v", cpu.Model)
+	}
+	if cpu.Vendor != "Intel" {
+		t.Errorf("Expected cpu vendor to be 'Intel', got '%s'", cpu.Vendor)
+	}
+	if len(cpu.Features) != 2 || cpu.Features[0] != (DomainCPUFeature{Policy: "require", Name: "ss"}) {
+		t.Errorf("Expected the required feature ss and the disabled feature mpx, got %+v", cpu.Features)
+	}
+}
+
+func TestDomainDevicesDeserialization(t *testing.T) {
+	var devices DomainDevices
+	err := xml.Unmarshal([]byte(`<devices>
+  <controller type='pci' index='0' model='pcie-root'>
+    <alias name='pcie.0'/>
+  </controller>
+  <controller type='usb' index='0' model='qemu-xhci'/>
+  <hostdev mode='subsystem' type='pci' managed='yes'>
+    <source>
+      <address domain='0x0000' bus='0x3b' slot='0x00' function='0x1'/>
+    </source>
+    <alias name='hostdev0'/>
+  </hostdev>
+  <hostdev mode='subsystem' type='usb' managed='no'>
+    <source>
+      <vendor id='0x1234'/>
+      <product id='0xbeef'/>
+    </source>
+  </hostdev>
+  <rng model='virtio'>
+    <rate bytes='1024' period='1000'/>
+    <backend model='random'>/dev/urandom</backend>
+  </rng>
+  <tpm model='tpm-crb'>
+    <backend type='emulator' version='2.0' persistent_state='yes'>
+      <encryption secret='6dd3e4a5-1d76-44ce-961f-f119f5aad935'/>
+    </backend>
+  </tpm>
+  <video>
+    <model type='virtio' heads='1' primary='yes'/>
+  </video>
+  <memballoon model='virtio' autodeflate='on'>
+    <stats period='10'/>
+  </memballoon>
+</devices>`), &devices)
+	if err != nil {
+		t.Fatalf("Failed to unmarshal XML: %v", err)
+	}
+
+	controllers := []DomainController{
+		{Type: "pci", Index: 0, Model: "pcie-root", Alias: &DomainAlias{Name: "pcie.0"}},
+		{Type: "usb", Index: 0, Model: "qemu-xhci"},
+	}
+	if !reflect.DeepEqual(devices.Controllers, controllers) {
+		t.Errorf("Expected controllers %+v, got %+v", controllers, devices.Controllers)
+	}
+	hostdevs := []DomainHostdev{
+		{
+			Mode: "subsystem", Type: "pci", Managed: "yes",
+			Source: &DomainHostdevSource{Address: &DomainHostdevAddress{
+				Domain: "0x0000", Bus: "0x3b", Slot: "0x00", Function: "0x1",
+			}},
+			Alias: &DomainAlias{Name: "hostdev0"},
+		},
+		{
+			Mode: "subsystem", Type: "usb", Managed: "no",
+			Source: &DomainHostdevSource{
+				Vendor:  &DomainHostdevID{ID: "0x1234"},
+				Product: &DomainHostdevID{ID: "0xbeef"},
+			},
+		},
+	}
+	if !reflect.DeepEqual(devices.Hostdevs, hostdevs) {
+		t.Errorf("Expected hostdevs %+v, got %+v", hostdevs, devices.Hostdevs)
+	}
+	rate := &DomainRNGRate{Bytes: 1024, Period: 1000}
+	if len(devices.RNGs) != 1 || !reflect.DeepEqual(devices.RNGs[0].Rate, rate) {
+		t.Errorf("Expected an rng limited to 1024 bytes per second, got %+v", devices.RNGs)
+	}
+	tpms := []DomainTPM{{Model: "tpm-crb", Backend: &DomainTPMBackend{
+		Type:            "emulator",
+		Version:         "2.0",
+		PersistentState: "yes",
+		Encryption:      &DomainTPMEncryption{Secret: "6dd3e4a5-1d76-44ce-961f-f119f5aad935"},
+	}}}
+	if !reflect.DeepEqual(devices.TPMs, tpms) {
+		t.Errorf("Expected tpms %+v, got %+v", tpms, devices.TPMs)
+	}
+	videos := []DomainVideo{{Model: &DomainVideoModel{Type: "virtio", Heads: 1, Primary: "yes"}}}
+	if !reflect.DeepEqual(devices.Videos, videos) {
+		t.Errorf("Expected videos %+v, got %+v", videos, devices.Videos)
+	}
+	balloon := &DomainMemBalloon{Model: "virtio", Autodeflate: "on", Stats: &DomainMemBalloonStats{Period: 10}}
+	if !reflect.DeepEqual(devices.MemBalloon, balloon) {
+		t.Errorf("Expected memory balloon %+v, got %+v", balloon, devices.MemBalloon)
 	}
 }
@@ -0,0 +1,217 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package libvirt
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/digitalocean/go-libvirt"
+
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/libvirt/dominfo"
+)
+
+// Kinds of drift between the live and the persistent domain definition.
+const (
+	DriftDevices = "devices"
+	DriftMemory  = "memory"
+	DriftVCPUs   = "vcpus"
+)
+
+var driftKinds = []string{DriftDevices, DriftMemory, DriftVCPUs}
+
+// DomainDrift is a running domain whose live definition differs from its
+// persistent definition, e.g. because devices were hot-plugged by hand.
+type DomainDrift struct {
+	UUID  string
+	Name  string
+	Kinds []string
+}
+
+// Summary of the drift for humans, e.g. "instance-0001: devices, memory".
+func (d DomainDrift) String() string {
+	return d.Name + ": " + strings.Join(d.Kinds, ", ")
+}
+
+// DomainDriftDetector compares the live and persistent domain definitions.
+type DomainDriftDetector interface {
+	// DetectDomainDrift returns the running domains whose live definition
+	// differs from the persistent one.
+	DetectDomainDrift() ([]DomainDrift, error)
+}
+
+// Keys identifying the devices of a domain, which are stable between the
+// live and the persistent definition. Aliases, addresses and interface
+// targets are only assigned to the live definition, so they are ignored.
+func deviceKeys(domain dominfo.DomainInfo) map[string]struct{} {
+	keys := make(map[string]struct{})
+	if domain.Devices == nil {
+		return keys
+	}
+	for _, disk := range domain.Devices.Disks {
+		key := "disk:"
+		if disk.Target != nil {
+			key += disk.Target.Dev
+		}
+		if disk.Source != nil {
+			key += ":" + disk.Source.File
+		}
+		keys[key] = struct{}{}
+	}
+	for _, iface := range domain.Devices.Interfaces {
+		key := "interface:" + iface.Type
+		if iface.MAC != nil {
+			key += ":" + iface.MAC.Address
+		}
+		keys[key] = struct{}{}
+	}
+	for i, hostdev := range domain.Devices.Hostdevs {
+		if source := hostdevSource(hostdev); source != "" {
+			keys["hostdev:"+hostdev.Type+":"+source] = struct{}{}
+		} else {
+			keys[fmt.Sprintf("hostdev:%s:%d", hostdev.Type, i)] = struct{}{}
+		}
+	}
+	for _, rng := range domain.Devices.RNGs {
+		keys["rng:"+rng.Model] = struct{}{}
+	}
+	for _, fs := range domain.Devices.Filesystems {
+		if fs.Target != nil {
+			keys["filesystem:"+fs.Target.Dir] = struct{}{}
+		}
+	}
+	for _, controller := range domain.Devices.Controllers {
+		keys[fmt.Sprintf("controller:%s:%d", controller.Type, controller.Index)] = struct{}{}
+	}
+	for i, video := range domain.Devices.Videos {
+		if video.Model != nil {
+			keys[fmt.Sprintf("video:%s:%d", video.Model.Type, i)] = struct{}{}
+		}
+	}
+	for _, tpm := range domain.Devices.TPMs {
+		keys["tpm:"+tpm.Model] = struct{}{}
+	}
+	if balloon := domain.Devices.MemBalloon; balloon != nil && balloon.Model != "none" {
+		keys["memballoon:"+balloon.Model] = struct{}{}
+	}
+	return keys
+}
+
+// Identify the host device by its pci address or its usb ids, empty if the
+// source is unknown.
+func hostdevSource(hostdev dominfo.DomainHostdev) string {
+	if hostdev.Source == nil {
+		return ""
+	}
+	if a := hostdev.Source.Address; a != nil {
+		if a.Device != "" {
+			return a.Bus + ":" + a.Device
+		}
+		return fmt.Sprintf("%s:%s:%s.%s", a.Domain, a.Bus, a.Slot, a.Function)
+	}
+	if hostdev.Source.Vendor != nil && hostdev.Source.Product != nil {
+		return hostdev.Source.Vendor.ID + ":" + hostdev.Source.Product.ID
+	}
+	return ""
+}
+
+// Get the number of vcpus, considering vcpus which are not plugged.
+func vcpus(domain dominfo.DomainInfo) int {
+	if domain.VCPU == nil {
+		return 0
+	}
+	if domain.VCPU.Current > 0 {
+		return domain.VCPU.Current
+	}
+	return domain.VCPU.Value
+}
+
+// Compare the live definition of a domain against its persistent
+// definition and return the kinds of drift.
+func compareDomains(live, persistent dominfo.DomainInfo) []string {
+	var kinds []string
+	if !maps.Equal(deviceKeys(live), deviceKeys(persistent)) {
+		kinds = append(kinds, DriftDevices)
+	}
+	if memoryKiB(live.Memory) != memoryKiB(persistent.Memory) ||
+		memoryKiB(live.CurrentMemory) != memoryKiB(persistent.CurrentMemory) {
+		kinds = append(kinds, DriftMemory)
+	}
+	if vcpus(live) != vcpus(persistent) {
+		kinds = append(kinds, DriftVCPUs)
+	}
+	return kinds
+}
+
+// Compare the live definition of all running persistent domains against
+// their persistent definition, and export the result as metric.
+func (l *LibVirt) DetectDomainDrift() ([]DomainDrift, error) {
+	domains, _, err := l.virt.ConnectListAllDomains(1,
+		libvirt.ConnectListDomainsActive|libvirt.ConnectListDomainsPersistent)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list domains: %w", err)
+	}
+
+	uuids := make([]string, 0, len(domains))
+	for _, domain := range domains {
+		uuids = append(uuids, GetOpenstackUUID(domain))
+	}
+	l.driftSeries.replace(uuids, domainDrift)
+	var drifts []DomainDrift
+	var errs []error
+	for _, domain := range domains {
+		uuid := GetOpenstackUUID(domain)
+		var live, persistent dominfo.DomainInfo
+		if err := l.unmarshalDomainXML(domain, 0, &live); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if err := l.unmarshalDomainXML(domain, libvirt.DomainXMLInactive, &persistent); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		kinds := compareDomains(live, persistent)
+		for _, kind := range driftKinds {
+			value := 0.0
+			if slices.Contains(kinds, kind) {
+				value = 1
+			}
+			domainDrift.WithLabelValues(uuid, kind).Set(value)
+		}
+		if len(kinds) > 0 {
+			drifts = append(drifts, DomainDrift{UUID: uuid, Name: domain.Name, Kinds: kinds})
+		}
+	}
+	return drifts, errors.Join(errs...)
+}
+
+// Fetch and parse the xml definition of the domain.
+func (l *LibVirt) unmarshalDomainXML(domain libvirt.Domain, flags libvirt.DomainXMLFlags, info *dominfo.DomainInfo) error {
+	desc, err := l.virt.DomainGetXMLDesc(domain, flags)
+	if err != nil {
+		return fmt.Errorf("failed to get xml of domain %s: %w", domain.Name, err)
+	}
+	if err := xml.Unmarshal([]byte(desc), info); err != nil {
+		return fmt.Errorf("failed to parse xml of domain %s: %w", domain.Name, err)
+	}
+	return nil
+}
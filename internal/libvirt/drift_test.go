@@ -0,0 +1,123 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package libvirt
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/libvirt/dominfo"
+)
+
+func driftTestDomain() dominfo.DomainInfo {
+	return dominfo.DomainInfo{
+		Memory:        &dominfo.DomainMemory{Unit: "KiB", Value: 4 << 20},
+		CurrentMemory: &dominfo.DomainMemory{Unit: "KiB", Value: 4 << 20},
+		VCPU:          &dominfo.DomainVCPU{Value: 4},
+		Devices: &dominfo.DomainDevices{
+			Disks: []dominfo.DomainDisk{{
+				Type:   "network",
+				Device: "disk",
+				Target: &dominfo.DomainDiskTarget{Dev: "vda"},
+			}},
+			Interfaces: []dominfo.DomainInterface{{
+				Type: "ethernet",
+				MAC:  &dominfo.DomainInterfaceMAC{Address: "fa:16:3e:00:00:01"},
+			}},
+		},
+	}
+}
+
+func TestCompareDomains_InSync(t *testing.T) {
+	live, persistent := driftTestDomain(), driftTestDomain()
+	// Only the live definition has interface targets.
+	live.Devices.Interfaces[0].Target = &dominfo.DomainInterfaceTarget{Dev: "tap0"}
+	if kinds := compareDomains(live, persistent); len(kinds) != 0 {
+		t.Errorf("Expected no drift, got %v", kinds)
+	}
+}
+
+func TestCompareDomains_Devices(t *testing.T) {
+	live, persistent := driftTestDomain(), driftTestDomain()
+	live.Devices.Disks = append(live.Devices.Disks, dominfo.DomainDisk{
+		Type:   "network",
+		Device: "disk",
+		Target: &dominfo.DomainDiskTarget{Dev: "vdb"},
+	})
+	if kinds := compareDomains(live, persistent); !slices.Equal(kinds, []string{DriftDevices}) {
+		t.Errorf("Expected device drift, got %v", kinds)
+	}
+
+	live = driftTestDomain()
+	live.Devices.Hostdevs = []dominfo.DomainHostdev{{Mode: "subsystem", Type: "pci"}}
+	if kinds := compareDomains(live, persistent); !slices.Equal(kinds, []string{DriftDevices}) {
+		t.Errorf("Expected device drift, got %v", kinds)
+	}
+
+	live = driftTestDomain()
+	live.Devices.TPMs = []dominfo.DomainTPM{{Model: "tpm-crb"}}
+	if kinds := compareDomains(live, persistent); !slices.Equal(kinds, []string{DriftDevices}) {
+		t.Errorf("Expected device drift, got %v", kinds)
+	}
+}
+
+func TestCompareDomains_HostdevSource(t *testing.T) {
+	hostdev := func(slot string) dominfo.DomainHostdev {
+		return dominfo.DomainHostdev{Mode: "subsystem", Type: "pci", Source: &dominfo.DomainHostdevSource{
+			Address: &dominfo.DomainHostdevAddress{Domain: "0x0000", Bus: "0x3b", Slot: slot, Function: "0x0"},
+		}}
+	}
+	live, persistent := driftTestDomain(), driftTestDomain()
+	live.Devices.Hostdevs = []dominfo.DomainHostdev{hostdev("0x00")}
+	persistent.Devices.Hostdevs = []dominfo.DomainHostdev{hostdev("0x00")}
+	// Only the live definition has aliases.
+	live.Devices.Hostdevs[0].Alias = &dominfo.DomainAlias{Name: "hostdev0"}
+	if kinds := compareDomains(live, persistent); len(kinds) != 0 {
+		t.Errorf("Expected no drift, got %v", kinds)
+	}
+
+	// Another host device of the same type was passed through.
+	live.Devices.Hostdevs = []dominfo.DomainHostdev{hostdev("0x01")}
+	if kinds := compareDomains(live, persistent); !slices.Equal(kinds, []string{DriftDevices}) {
+		t.Errorf("Expected device drift, got %v", kinds)
+	}
+}
+
+func TestCompareDomains_MemoryAndVCPUs(t *testing.T) {
+	live, persistent := driftTestDomain(), driftTestDomain()
+	live.CurrentMemory = &dominfo.DomainMemory{Unit: "KiB", Value: 2 << 20}
+	live.VCPU.Current = 2
+	kinds := compareDomains(live, persistent)
+	if !slices.Equal(kinds, []string{DriftMemory, DriftVCPUs}) {
+		t.Errorf("Expected memory and vcpu drift, got %v", kinds)
+	}
+
+	// The same amount of memory in a different unit is no drift.
+	live = driftTestDomain()
+	live.Memory = &dominfo.DomainMemory{Unit: "GiB", Value: 4}
+	if kinds := compareDomains(live, persistent); len(kinds) != 0 {
+		t.Errorf("Expected no drift, got %v", kinds)
+	}
+}
+
+func TestDomainDrift_String(t *testing.T) {
+	drift := DomainDrift{Name: "instance-0001", Kinds: []string{DriftDevices, DriftMemory}}
+	if got := drift.String(); got != "instance-0001: devices, memory" {
+		t.Errorf("Unexpected summary: %s", got)
+	}
+}
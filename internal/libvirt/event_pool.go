@@ -0,0 +1,99 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package libvirt
+
+import (
+	"context"
+	"hash/fnv"
+
+	"github.com/digitalocean/go-libvirt"
+)
+
+var (
+	// Number of workers handling the libvirt domain events.
+	eventWorkers = 4
+	// Number of events queued per worker. The event loop blocks once the
+	// queue is full, so that a flood of events is buffered by the libvirt
+	// client instead of piling up in goroutines.
+	eventQueueSize = 64
+)
+
+// Pool of workers handling the libvirt domain events. The events of a
+// domain are always handled by the same worker, so that they are handled
+// in order.
+type eventPool struct {
+	queues []chan func()
+}
+
+// Start the workers, they stop once the context is done.
+func newEventPool(ctx context.Context, workers, queueSize int) *eventPool {
+	p := &eventPool{queues: make([]chan func(), workers)}
+	for i := range p.queues {
+		queue := make(chan func(), queueSize)
+		p.queues[i] = queue
+		go func() {
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case handle := <-queue:
+					eventQueueDepth.Dec()
+					handle()
+				}
+			}
+		}()
+	}
+	return p
+}
+
+// Queue the handling of an event of the domain with the given key. Blocks
+// while the queue of the worker is full, unless the context is done.
+func (p *eventPool) dispatch(ctx context.Context, key string, handle func()) {
+	hash := fnv.New32a()
+	_, _ = hash.Write([]byte(key))
+	queue := p.queues[hash.Sum32()%uint32(len(p.queues))]
+	// Counted before the send, the worker may take the event and decrement
+	// the depth right away.
+	eventQueueDepth.Inc()
+	select {
+	case <-ctx.Done():
+		eventQueueDepth.Dec()
+	case queue <- handle:
+	}
+}
+
+// Get the uuid of the domain an event is about, empty for unknown events.
+func eventDomain(event any) string {
+	switch e := event.(type) {
+	case *libvirt.DomainEventCallbackLifecycleMsg:
+		return GetOpenstackUUID(e.Msg.Dom)
+	case *libvirt.DomainEventCallbackMigrationIterationMsg:
+		return GetOpenstackUUID(e.Dom)
+	case *libvirt.DomainEventCallbackJobCompletedMsg:
+		return GetOpenstackUUID(e.Dom)
+	case *libvirt.DomainEventCallbackWatchdogMsg:
+		return GetOpenstackUUID(e.Msg.Dom)
+	case *libvirt.DomainEventCallbackBlockJobMsg:
+		return GetOpenstackUUID(e.Msg.Dom)
+	case *libvirt.DomainEventCallbackIOErrorReasonMsg:
+		return GetOpenstackUUID(e.Msg.Dom)
+	case *libvirt.DomainEventCallbackAgentLifecycleMsg:
+		return GetOpenstackUUID(e.Dom)
+	}
+	return ""
+}
@@ -0,0 +1,135 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package libvirt
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/digitalocean/go-libvirt"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestEventPool_OrderPerDomain(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	pool := newEventPool(ctx, 4, 8)
+
+	var lock sync.Mutex
+	handled := make(map[string][]int)
+	var wg sync.WaitGroup
+	for i := range 50 {
+		for _, domain := range []string{"a", "b", "c"} {
+			wg.Add(1)
+			pool.dispatch(ctx, domain, func() {
+				defer wg.Done()
+				lock.Lock()
+				defer lock.Unlock()
+				handled[domain] = append(handled[domain], i)
+			})
+		}
+	}
+	wg.Wait()
+
+	for domain, events := range handled {
+		for i, event := range events {
+			if event != i {
+				t.Fatalf("Expected events of domain %s in order, got %v", domain, events)
+			}
+		}
+	}
+}
+
+func TestEventPool_Backpressure(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	pool := newEventPool(ctx, 1, 1)
+
+	block := make(chan struct{})
+	started := make(chan struct{})
+	pool.dispatch(ctx, "a", func() { close(started); <-block })
+	<-started
+	// Fills the queue while the worker is busy.
+	pool.dispatch(ctx, "a", func() {})
+
+	dispatched := make(chan struct{})
+	go func() {
+		pool.dispatch(ctx, "a", func() {})
+		close(dispatched)
+	}()
+	select {
+	case <-dispatched:
+		t.Fatal("Expected dispatch to block while the queue is full")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(block)
+	select {
+	case <-dispatched:
+	case <-time.After(time.Second):
+		t.Fatal("Expected dispatch to continue once the queue drained")
+	}
+}
+
+func TestEventPool_QueueDepth(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	pool := newEventPool(ctx, 1, 1)
+	depth := testutil.ToFloat64(eventQueueDepth)
+
+	block := make(chan struct{})
+	defer close(block)
+	started := make(chan struct{})
+	pool.dispatch(ctx, "a", func() { close(started); <-block })
+	<-started
+	pool.dispatch(ctx, "a", func() {})
+	if got := testutil.ToFloat64(eventQueueDepth) - depth; got != 1 {
+		t.Errorf("Expected a queue depth of 1, got %v", got)
+	}
+
+	// Abandoning the dispatch of an event doesn't count it.
+	abandoned, abandon := context.WithCancel(ctx)
+	abandon()
+	pool.dispatch(abandoned, "a", func() {})
+	if got := testutil.ToFloat64(eventQueueDepth) - depth; got != 1 {
+		t.Errorf("Expected a queue depth of 1 after abandoning a dispatch, got %v", got)
+	}
+}
+
+func TestEventDomain(t *testing.T) {
+	domain := libvirt.Domain{Name: "instance-1", UUID: libvirt.UUID{1}}
+	want := GetOpenstackUUID(domain)
+	events := []any{
+		&libvirt.DomainEventCallbackLifecycleMsg{Msg: libvirt.DomainEventLifecycleMsg{Dom: domain}},
+		&libvirt.DomainEventCallbackMigrationIterationMsg{Dom: domain},
+		&libvirt.DomainEventCallbackJobCompletedMsg{Dom: domain},
+		&libvirt.DomainEventCallbackWatchdogMsg{Msg: libvirt.DomainEventWatchdogMsg{Dom: domain}},
+		&libvirt.DomainEventCallbackBlockJobMsg{Msg: libvirt.DomainEventBlockJobMsg{Dom: domain}},
+		&libvirt.DomainEventCallbackIOErrorReasonMsg{Msg: libvirt.DomainEventIOErrorReasonMsg{Dom: domain}},
+		&libvirt.DomainEventCallbackAgentLifecycleMsg{Dom: domain},
+	}
+	for _, event := range events {
+		if got := eventDomain(event); got != want {
+			t.Errorf("Expected domain %s for %T, got %s", want, event, got)
+		}
+	}
+	if got := eventDomain("unknown"); got != "" {
+		t.Errorf("Expected no domain for unknown events, got %s", got)
+	}
+}
@@ -0,0 +1,115 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package libvirt
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/libvirt/domcapabilities"
+)
+
+// Firmware is the inventory of the firmware domains can boot with.
+type Firmware struct {
+	// Firmware types, e.g. "bios" and "efi".
+	Types []string
+	// Whether a loader supporting secure boot is available.
+	SecureBoot bool
+	// Paths of the firmware images available as loader, sorted.
+	Loaders []string
+}
+
+// FirmwareDescriber provides the firmware domains can boot with.
+type FirmwareDescriber interface {
+	// Firmware returns the firmware types and loaders of the host.
+	Firmware() (Firmware, error)
+}
+
+// Get the firmware inventory from the os section of the domain
+// capabilities.
+func firmwareOf(caps domcapabilities.DomainCapabilities) Firmware {
+	var firmware Firmware
+	if caps.OS.Supported != supportedYes {
+		return firmware
+	}
+	for _, enum := range caps.OS.Enums {
+		if enum.Name == "firmware" {
+			firmware.Types = append(firmware.Types, enum.Values...)
+		}
+	}
+	loader := caps.OS.Loader
+	if loader.Supported != supportedYes {
+		return firmware
+	}
+	firmware.Loaders = slices.Sorted(slices.Values(loader.Values))
+	for _, enum := range loader.Enums {
+		if enum.Name == "secure" {
+			firmware.SecureBoot = slices.Contains(enum.Values, "yes")
+		}
+	}
+	return firmware
+}
+
+// Get the firmware inventory from the domain capabilities.
+func (l *LibVirt) Firmware() (Firmware, error) {
+	caps, err := l.domainCapabilities()
+	if err != nil {
+		return Firmware{}, err
+	}
+	return firmwareOf(caps), nil
+}
+
+// Firmware descriptor of qemu, see docs/interop/firmware.json of qemu. Only
+// the fields for the nvram template are parsed.
+type firmwareDescriptor struct {
+	Mapping struct {
+		NVRAMTemplate *struct {
+			Filename string `json:"filename"`
+		} `json:"nvram-template"`
+	} `json:"mapping"`
+}
+
+// NVRAMTemplates reads the nvram templates of the firmware descriptors in
+// the directory, e.g. /usr/share/qemu/firmware. The templates hold the
+// initial uefi variables of the domains, e.g. the enrolled secure boot keys.
+// Returns the sorted paths of the templates.
+func NVRAMTemplates(dir string) ([]string, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	var templates []string
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		var descriptor firmwareDescriptor
+		if err := json.Unmarshal(data, &descriptor); err != nil {
+			return nil, fmt.Errorf("failed to parse firmware descriptor %s: %w", path, err)
+		}
+		if template := descriptor.Mapping.NVRAMTemplate; template != nil && template.Filename != "" {
+			templates = append(templates, template.Filename)
+		}
+	}
+	slices.Sort(templates)
+	return slices.Compact(templates), nil
+}
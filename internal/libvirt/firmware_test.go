@@ -0,0 +1,136 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package libvirt
+
+import (
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	v1 "github.com/cobaltcore-dev/openstack-hypervisor-operator/api/v1"
+
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/libvirt/domcapabilities"
+)
+
+const qemuDomainCapabilitiesOS = `<domainCapabilities>
+  <os supported='yes'>
+    <enum name='firmware'>
+      <value>bios</value>
+      <value>efi</value>
+    </enum>
+    <loader supported='yes'>
+      <value>/usr/share/OVMF/OVMF_CODE_4M.secboot.fd</value>
+      <value>/usr/share/OVMF/OVMF_CODE_4M.fd</value>
+      <enum name='type'>
+        <value>rom</value>
+        <value>pflash</value>
+      </enum>
+      <enum name='secure'>
+        <value>yes</value>
+        <value>no</value>
+      </enum>
+    </loader>
+  </os>
+</domainCapabilities>`
+
+func TestFirmwareOf(t *testing.T) {
+	var caps domcapabilities.DomainCapabilities
+	if err := xml.Unmarshal([]byte(qemuDomainCapabilitiesOS), &caps); err != nil {
+		t.Fatalf("Failed to unmarshal XML: %v", err)
+	}
+	expected := Firmware{
+		Types:      []string{"bios", "efi"},
+		SecureBoot: true,
+		Loaders:    []string{"/usr/share/OVMF/OVMF_CODE_4M.fd", "/usr/share/OVMF/OVMF_CODE_4M.secboot.fd"},
+	}
+	if firmware := firmwareOf(caps); !reflect.DeepEqual(firmware, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, firmware)
+	}
+
+	// The example of cloud hypervisor has no firmware types and no secure
+	// boot.
+	caps, err := domcapabilities.NewClientEmulator().Get(nil)
+	if err != nil {
+		t.Fatalf("Failed to get example domain capabilities: %v", err)
+	}
+	if firmware := firmwareOf(caps); firmware.SecureBoot || len(firmware.Types) != 0 {
+		t.Errorf("Expected no secure boot, got %+v", firmware)
+	}
+}
+
+func TestNVRAMTemplates(t *testing.T) {
+	dir := t.TempDir()
+	descriptors := map[string]string{
+		"40-edk2-x86_64-secure-enrolled.json": `{
+			"interface-types": ["uefi"],
+			"mapping": {
+				"device": "flash",
+				"executable": {"filename": "/usr/share/OVMF/OVMF_CODE_4M.secboot.fd", "format": "raw"},
+				"nvram-template": {"filename": "/usr/share/OVMF/OVMF_VARS_4M.ms.fd", "format": "raw"}
+			},
+			"features": ["enrolled-keys", "requires-smm", "secure-boot"]
+		}`,
+		"50-edk2-x86_64.json": `{
+			"mapping": {
+				"device": "flash",
+				"executable": {"filename": "/usr/share/OVMF/OVMF_CODE_4M.fd", "format": "raw"},
+				"nvram-template": {"filename": "/usr/share/OVMF/OVMF_VARS_4M.fd", "format": "raw"}
+			}
+		}`,
+		"60-edk2-x86_64-memory.json": `{"mapping": {"device": "memory", "filename": "/usr/share/OVMF/OVMF.fd"}}`,
+		"README":                     "not a descriptor",
+	}
+	for name, content := range descriptors {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	templates, err := NVRAMTemplates(dir)
+	if err != nil {
+		t.Fatalf("Failed to read nvram templates: %v", err)
+	}
+	expected := []string{"/usr/share/OVMF/OVMF_VARS_4M.fd", "/usr/share/OVMF/OVMF_VARS_4M.ms.fd"}
+	if !reflect.DeepEqual(templates, expected) {
+		t.Errorf("Expected %v, got %v", expected, templates)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "70-broken.json"), []byte("{"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NVRAMTemplates(dir); err == nil {
+		t.Errorf("Expected an error for a broken descriptor")
+	}
+}
+
+func TestAddDomainCapabilities_Firmware(t *testing.T) {
+	var caps domcapabilities.DomainCapabilities
+	if err := xml.Unmarshal([]byte(qemuDomainCapabilitiesOS), &caps); err != nil {
+		t.Fatalf("Failed to unmarshal XML: %v", err)
+	}
+	l := &LibVirt{domainCapabilitiesClient: &mockDomCapabilitiesClient{caps: caps}}
+	result, err := l.addDomainCapabilities(v1.Hypervisor{})
+	if err != nil {
+		t.Fatalf("addDomainCapabilities() returned unexpected error: %v", err)
+	}
+	expected := []string{"firmware/bios", "firmware/efi", "secure-boot"}
+	if features := result.Status.DomainCapabilities.SupportedFeatures; !reflect.DeepEqual(features, expected) {
+		t.Errorf("Expected features %v, got %v", expected, features)
+	}
+}
@@ -0,0 +1,336 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package libvirt
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/digitalocean/go-libvirt"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	logger "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/cobaltcore-dev/kvm-node-agent/api/v1alpha1"
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/libvirt/dominfo"
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/libvirt/virterr"
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/logging"
+)
+
+// Seconds to wait for the guest agent to answer. Guests without a running
+// agent would otherwise block the caller for the libvirt default timeout.
+const guestAgentTimeout int32 = 5
+
+// Interval the information reported by a connected guest agent is refreshed
+// in. Each refresh runs three guest agent commands.
+const guestInfoInterval = 5 * time.Minute
+
+// Operating system information reported by the guest agent.
+type GuestOSInfo struct {
+	ID            string `json:"id"`
+	Name          string `json:"name"`
+	PrettyName    string `json:"pretty-name"`
+	Version       string `json:"version"`
+	VersionID     string `json:"version-id"`
+	KernelRelease string `json:"kernel-release"`
+	KernelVersion string `json:"kernel-version"`
+	Machine       string `json:"machine"`
+}
+
+// Ip address of a guest network interface.
+type GuestIPAddress struct {
+	// Either "ipv4" or "ipv6".
+	Type    string `json:"ip-address-type"`
+	Address string `json:"ip-address"`
+	Prefix  int    `json:"prefix"`
+}
+
+// Network interface as seen from inside the guest.
+type GuestInterface struct {
+	Name            string           `json:"name"`
+	HardwareAddress string           `json:"hardware-address"`
+	IPAddresses     []GuestIPAddress `json:"ip-addresses"`
+}
+
+// Error reported by the guest agent itself, e.g. for unsupported commands.
+type guestAgentError struct {
+	Class string `json:"class"`
+	Desc  string `json:"desc"`
+}
+
+// Execute the guest agent command and decode its return value into out.
+func (l *LibVirt) guestAgentCommand(uuid, command string, out any) error {
+	id, err := ParseUUID(uuid)
+	if err != nil {
+		return err
+	}
+	domain, err := l.virt.DomainLookupByUUID(libvirt.UUID(id))
+	if err != nil {
+		return fmt.Errorf("failed to lookup domain %s: %w", uuid, virterr.Classify(err))
+	}
+	request, err := json.Marshal(map[string]string{"execute": command})
+	if err != nil {
+		return err
+	}
+	response, err := l.virt.QEMUDomainAgentCommand(domain, string(request), guestAgentTimeout, 0)
+	if err != nil {
+		return fmt.Errorf("failed to run guest agent command %s on domain %s: %w",
+			command, uuid, virterr.Classify(err))
+	}
+	if len(response) == 0 {
+		return fmt.Errorf("empty response to guest agent command %s on domain %s", command, uuid)
+	}
+	return decodeGuestAgentResponse(response[0], out)
+}
+
+// Decode the json response of the guest agent into out.
+func decodeGuestAgentResponse(response string, out any) error {
+	var envelope struct {
+		Return json.RawMessage  `json:"return"`
+		Error  *guestAgentError `json:"error"`
+	}
+	if err := json.Unmarshal([]byte(response), &envelope); err != nil {
+		return fmt.Errorf("failed to decode guest agent response: %w", err)
+	}
+	if envelope.Error != nil {
+		return fmt.Errorf("guest agent error %s: %s", envelope.Error.Class, envelope.Error.Desc)
+	}
+	if envelope.Return == nil {
+		return errors.New("guest agent response without return value")
+	}
+	return json.Unmarshal(envelope.Return, out)
+}
+
+// Get the operating system information of the guest.
+func (l *LibVirt) GuestOSInfo(uuid string) (*GuestOSInfo, error) {
+	var info GuestOSInfo
+	if err := l.guestAgentCommand(uuid, "guest-get-osinfo", &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+// Get the hostname configured inside the guest.
+func (l *LibVirt) GuestHostname(uuid string) (string, error) {
+	var result struct {
+		HostName string `json:"host-name"`
+	}
+	if err := l.guestAgentCommand(uuid, "guest-get-host-name", &result); err != nil {
+		return "", err
+	}
+	return result.HostName, nil
+}
+
+// Get the network interfaces and their ip addresses as seen by the guest.
+func (l *LibVirt) GuestInterfaces(uuid string) ([]GuestInterface, error) {
+	var interfaces []GuestInterface
+	if err := l.guestAgentCommand(uuid, "guest-network-get-interfaces", &interfaces); err != nil {
+		return nil, err
+	}
+	return interfaces, nil
+}
+
+// Ip addresses reported by the guest, excluding loopback interfaces.
+func GuestIPs(interfaces []GuestInterface) []string {
+	var ips []string
+	for _, iface := range interfaces {
+		if iface.Name == "lo" {
+			continue
+		}
+		for _, ip := range iface.IPAddresses {
+			ips = append(ips, ip.Address)
+		}
+	}
+	return ips
+}
+
+// Name of the channel of the qemu guest agent.
+const guestAgentChannel = "org.qemu.guest_agent.0"
+
+// Get the state of the guest agent from the channels of the domain, nil if
+// the domain has no guest agent channel. Libvirt only reports the state of
+// the channel while the domain is running.
+func guestAgent(domain dominfo.DomainInfo, active bool) *v1alpha1.InstanceGuestAgent {
+	if domain.Devices == nil {
+		return nil
+	}
+	for _, channel := range domain.Devices.Channels {
+		if channel.Target == nil || channel.Target.Name != guestAgentChannel {
+			continue
+		}
+		return &v1alpha1.InstanceGuestAgent{
+			Connected: active && channel.Target.State == "connected",
+		}
+	}
+	return nil
+}
+
+// Get the reason of a guest agent lifecycle event.
+func guestAgentReason(reason int32) string {
+	switch libvirt.ConnectDomainEventAgentLifecycleReason(reason) {
+	case libvirt.ConnectDomainEventAgentLifecycleReasonDomainStarted:
+		return "domain-started"
+	case libvirt.ConnectDomainEventAgentLifecycleReasonChannel:
+		return "channel"
+	}
+	return "unknown"
+}
+
+// Information about the guest reported by its agent.
+type guestInfo struct {
+	hostname string
+	ips      []string
+	os       string
+	fetched  time.Time
+}
+
+// Records the changes of the guest agent state of the domains and the
+// information their agents reported, by domain uuid. The zero value is
+// ready to use.
+type guestAgentTracker struct {
+	lock   sync.Mutex
+	agents map[string]*v1alpha1.InstanceGuestAgent
+	info   map[string]guestInfo
+}
+
+// Record that the guest agent of the domain connected or disconnected.
+func (t *guestAgentTracker) changed(uuid string, connected bool, reason string, at time.Time) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if t.agents == nil {
+		t.agents = make(map[string]*v1alpha1.InstanceGuestAgent)
+	}
+	changed := metav1.NewTime(at.Truncate(time.Second))
+	t.agents[uuid] = &v1alpha1.InstanceGuestAgent{
+		Connected:          connected,
+		LastTransitionTime: &changed,
+		Reason:             reason,
+	}
+	// The guest may have been reconfigured while the agent was away.
+	delete(t.info, uuid)
+}
+
+// Check if the information of the guest has to be fetched from its agent.
+func (t *guestAgentTracker) infoDue(uuid string, now time.Time) bool {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	info, ok := t.info[uuid]
+	return !ok || now.Sub(info.fetched) >= guestInfoInterval
+}
+
+// Record the information the agent of the guest reported.
+func (t *guestAgentTracker) setInfo(uuid string, info guestInfo) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if t.info == nil {
+		t.info = make(map[string]guestInfo)
+	}
+	t.info[uuid] = info
+}
+
+// Forget the guest agent of a stopped or undefined domain.
+func (t *guestAgentTracker) forget(uuid string) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	delete(t.agents, uuid)
+	delete(t.info, uuid)
+}
+
+// Continue with the guest agent state recorded in the status of the
+// instance, e.g. after a restart of the agent. Changes observed since are
+// kept.
+func (t *guestAgentTracker) seed(uuid string, agent *v1alpha1.InstanceGuestAgent) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if _, ok := t.agents[uuid]; ok || agent == nil {
+		return
+	}
+	if t.agents == nil {
+		t.agents = make(map[string]*v1alpha1.InstanceGuestAgent)
+	}
+	t.agents[uuid] = agent.DeepCopy()
+}
+
+// Complete the guest agent state of the domain xml with the time and reason
+// of the last change, and the information a connected agent reported. The
+// recorded change is dropped if the xml disagrees, i.e. if an event was
+// missed.
+func (t *guestAgentTracker) status(uuid string, agent *v1alpha1.InstanceGuestAgent) *v1alpha1.InstanceGuestAgent {
+	if agent == nil {
+		return nil
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	status := agent.DeepCopy()
+	if tracked, ok := t.agents[uuid]; ok && tracked.Connected == agent.Connected {
+		status = tracked.DeepCopy()
+	}
+	if !status.Connected {
+		status.Hostname, status.IPs, status.OS = "", nil, ""
+	} else if info, ok := t.info[uuid]; ok {
+		status.Hostname, status.IPs, status.OS = info.hostname, slices.Clone(info.ips), info.os
+	}
+	return status
+}
+
+// Fetch the hostname, ip addresses and operating system from the agent of
+// the running guest, unless they were fetched recently. Commands the agent
+// fails, e.g. as it doesn't support them, leave the information empty.
+func (l *LibVirt) refreshGuestInfo(uuid string) {
+	now := time.Now()
+	if !l.guestAgents.infoDue(uuid, now) {
+		return
+	}
+	info := guestInfo{fetched: now}
+	var errs []error
+	hostname, err := l.GuestHostname(uuid)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	info.hostname = hostname
+	if interfaces, err := l.GuestInterfaces(uuid); err != nil {
+		errs = append(errs, err)
+	} else {
+		info.ips = GuestIPs(interfaces)
+	}
+	if osInfo, err := l.GuestOSInfo(uuid); err != nil {
+		errs = append(errs, err)
+	} else {
+		info.os = osInfo.PrettyName
+	}
+	if err := errors.Join(errs...); err != nil {
+		logger.Log.WithName(logging.Libvirt).Error(err, "incomplete guest information", "server", uuid)
+	}
+	l.guestAgents.setInfo(uuid, info)
+}
+
+// Record the guest agent of a domain connecting or disconnecting.
+func (l *LibVirt) onAgentLifecycle(ctx context.Context, event any) {
+	e := event.(*libvirt.DomainEventCallbackAgentLifecycleMsg)
+	uuid := GetOpenstackUUID(e.Dom)
+	connected := libvirt.ConnectDomainEventAgentLifecycleState(e.State) ==
+		libvirt.ConnectDomainEventAgentLifecycleStateConnected
+	reason := guestAgentReason(e.Reason)
+	logger.FromContext(ctx).WithValues("server", uuid).
+		Info("domain guest agent changed", "connected", connected, "reason", reason)
+	l.guestAgents.changed(uuid, connected, reason, time.Now())
+}
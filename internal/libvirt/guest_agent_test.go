@@ -0,0 +1,166 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package libvirt
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/digitalocean/go-libvirt"
+
+	"github.com/cobaltcore-dev/kvm-node-agent/api/v1alpha1"
+)
+
+func TestDecodeGuestAgentResponse_OSInfo(t *testing.T) {
+	response := `{"return":{"id":"ubuntu","name":"Ubuntu","pretty-name":"Ubuntu 22.04.4 LTS",` +
+		`"version":"22.04.4 LTS (Jammy Jellyfish)","version-id":"22.04","kernel-release":"5.15.0-101-generic",` +
+		`"kernel-version":"#111-Ubuntu SMP","machine":"x86_64"}}`
+	var info GuestOSInfo
+	if err := decodeGuestAgentResponse(response, &info); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if info.ID != "ubuntu" || info.VersionID != "22.04" || info.PrettyName != "Ubuntu 22.04.4 LTS" {
+		t.Errorf("Unexpected os info: %+v", info)
+	}
+	if info.KernelRelease != "5.15.0-101-generic" || info.Machine != "x86_64" {
+		t.Errorf("Unexpected kernel info: %+v", info)
+	}
+}
+
+func TestDecodeGuestAgentResponse_Interfaces(t *testing.T) {
+	response := `{"return":[` +
+		`{"name":"lo","hardware-address":"00:00:00:00:00:00","ip-addresses":[` +
+		`{"ip-address-type":"ipv4","ip-address":"127.0.0.1","prefix":8}]},` +
+		`{"name":"eth0","hardware-address":"fa:16:3e:12:34:56","ip-addresses":[` +
+		`{"ip-address-type":"ipv4","ip-address":"10.180.0.12","prefix":24},` +
+		`{"ip-address-type":"ipv6","ip-address":"fe80::f816:3eff:fe12:3456","prefix":64}]}]}`
+	var interfaces []GuestInterface
+	if err := decodeGuestAgentResponse(response, &interfaces); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(interfaces) != 2 {
+		t.Fatalf("Expected 2 interfaces, got %d", len(interfaces))
+	}
+	if interfaces[1].HardwareAddress != "fa:16:3e:12:34:56" || interfaces[1].IPAddresses[0].Prefix != 24 {
+		t.Errorf("Unexpected interface: %+v", interfaces[1])
+	}
+	expected := []string{"10.180.0.12", "fe80::f816:3eff:fe12:3456"}
+	if ips := GuestIPs(interfaces); !slices.Equal(ips, expected) {
+		t.Errorf("Expected guest ips %v, got %v", expected, ips)
+	}
+}
+
+func TestDecodeGuestAgentResponse_Hostname(t *testing.T) {
+	var hostname struct {
+		HostName string `json:"host-name"`
+	}
+	if err := decodeGuestAgentResponse(`{"return":{"host-name":"vm-1"}}`, &hostname); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if hostname.HostName != "vm-1" {
+		t.Errorf("Expected hostname vm-1, got %s", hostname.HostName)
+	}
+}
+
+func TestDecodeGuestAgentResponse_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+	}{
+		{name: "agent error", response: `{"error":{"class":"CommandNotFound","desc":"command not supported"}}`},
+		{name: "missing return", response: `{}`},
+		{name: "invalid json", response: `not json`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out any
+			if err := decodeGuestAgentResponse(tt.response, &out); err == nil {
+				t.Errorf("Expected error for response %s", tt.response)
+			}
+		})
+	}
+}
+
+func TestGuestAgentTracker(t *testing.T) {
+	var tracker guestAgentTracker
+	start := time.Now()
+
+	connected := &v1alpha1.InstanceGuestAgent{Connected: true}
+	if agent := tracker.status("a", nil); agent != nil {
+		t.Errorf("Expected no guest agent without channel, got %+v", agent)
+	}
+	if agent := tracker.status("a", connected); agent.LastTransitionTime != nil {
+		t.Errorf("Expected no transition time of unknown domain, got %+v", agent)
+	}
+	tracker.changed("a", true, guestAgentReason(int32(libvirt.ConnectDomainEventAgentLifecycleReasonChannel)), start)
+	agent := tracker.status("a", connected)
+	if !agent.Connected || agent.Reason != "channel" || agent.LastTransitionTime == nil ||
+		!agent.LastTransitionTime.Time.Equal(start.Truncate(time.Second)) {
+		t.Errorf("Unexpected guest agent %+v", agent)
+	}
+	// A missed event is corrected by the domain xml.
+	if agent := tracker.status("a", &v1alpha1.InstanceGuestAgent{}); agent.Connected || agent.LastTransitionTime != nil {
+		t.Errorf("Expected the state of the domain xml, got %+v", agent)
+	}
+
+	// Changes observed since the start of the agent win.
+	tracker.seed("a", &v1alpha1.InstanceGuestAgent{Connected: true, Reason: "domain-started"})
+	if agent := tracker.status("a", connected); agent.Reason != "channel" {
+		t.Errorf("Expected the observed change to be kept, got %+v", agent)
+	}
+	tracker.seed("b", &v1alpha1.InstanceGuestAgent{Connected: true, Reason: "domain-started"})
+	if agent := tracker.status("b", connected); agent.Reason != "domain-started" {
+		t.Errorf("Expected the recorded change, got %+v", agent)
+	}
+
+	tracker.forget("a")
+	if agent := tracker.status("a", connected); agent.LastTransitionTime != nil {
+		t.Errorf("Expected the change to be forgotten, got %+v", agent)
+	}
+}
+
+func TestGuestAgentTracker_Info(t *testing.T) {
+	var tracker guestAgentTracker
+	start := time.Now()
+	connected := &v1alpha1.InstanceGuestAgent{Connected: true}
+
+	if !tracker.infoDue("a", start) {
+		t.Error("Expected the information of an unknown guest to be due")
+	}
+	tracker.setInfo("a", guestInfo{hostname: "vm-1", ips: []string{"10.180.0.12"}, os: "Ubuntu 24.04 LTS", fetched: start})
+	if tracker.infoDue("a", start.Add(time.Minute)) {
+		t.Error("Expected recently fetched information not to be due")
+	}
+	if !tracker.infoDue("a", start.Add(guestInfoInterval)) {
+		t.Error("Expected the information to be due after the interval")
+	}
+	agent := tracker.status("a", connected)
+	if agent.Hostname != "vm-1" || !slices.Equal(agent.IPs, []string{"10.180.0.12"}) || agent.OS != "Ubuntu 24.04 LTS" {
+		t.Errorf("Expected the guest information, got %+v", agent)
+	}
+	if agent := tracker.status("a", &v1alpha1.InstanceGuestAgent{}); agent.Hostname != "" || agent.IPs != nil {
+		t.Errorf("Expected no guest information while disconnected, got %+v", agent)
+	}
+
+	// A reconnecting agent reports the information again.
+	tracker.changed("a", true, "channel", start)
+	if !tracker.infoDue("a", start) {
+		t.Error("Expected the information to be due after the agent reconnected")
+	}
+}
@@ -0,0 +1,134 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package libvirt
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Probes which take longer than this are failed, the rpc call itself
+// can't be cancelled.
+var probeTimeout = 5 * time.Second
+
+const (
+	// Round trip from which the connection is considered degraded.
+	degradedLatency = time.Second
+	// Number of failed probes in a row from which the connection is
+	// considered degraded.
+	degradedFailures = 3
+)
+
+var errProbePending = errors.New("previous probe still waiting for libvirt")
+
+// ConnectionHealth of the rpc connection to libvirt, from the probes of
+// the connection.
+type ConnectionHealth struct {
+	// Round trip of the last probe, zero if it failed.
+	Latency time.Duration
+	// Number of probes failed in a row.
+	ConsecutiveFailures int
+	// Error of the last probe, nil if it succeeded.
+	Err error
+}
+
+// Check if libvirt answers slowly or not at all, which usually precedes
+// losing the connection.
+func (h ConnectionHealth) Degraded() bool {
+	return h.ConsecutiveFailures >= degradedFailures || h.Latency >= degradedLatency
+}
+
+// Summary of the health for humans, e.g. "rpc round trip 2ms".
+func (h ConnectionHealth) String() string {
+	if h.Err != nil {
+		return fmt.Sprintf("%d probes failed in a row: %v", h.ConsecutiveFailures, h.Err)
+	}
+	return fmt.Sprintf("rpc round trip %s", h.Latency.Round(time.Millisecond))
+}
+
+// ConnectionProber probes the rpc connection to libvirt.
+type ConnectionProber interface {
+	// ProbeConnection measures the round trip of a cheap rpc call to
+	// libvirt and returns the health of the connection.
+	ProbeConnection() ConnectionHealth
+}
+
+// Tracks the health of the connection over consecutive probes.
+type connectionHealthTracker struct {
+	lock   sync.Mutex
+	health ConnectionHealth
+	// Set while a probe waits for libvirt, which may outlive its timeout.
+	pending bool
+}
+
+// Record the result of a probe.
+func (t *connectionHealthTracker) record(latency time.Duration, err error) ConnectionHealth {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if err != nil {
+		t.health = ConnectionHealth{ConsecutiveFailures: t.health.ConsecutiveFailures + 1, Err: err}
+	} else {
+		t.health = ConnectionHealth{Latency: latency}
+	}
+	return t.health
+}
+
+// Time the call and record its result. Only one call is in flight at a
+// time, a probe while the previous call still waits for libvirt fails.
+func (t *connectionHealthTracker) probe(call func() error) ConnectionHealth {
+	t.lock.Lock()
+	pending := t.pending
+	t.pending = true
+	t.lock.Unlock()
+	if pending {
+		return t.record(0, errProbePending)
+	}
+
+	result := make(chan error, 1)
+	start := time.Now()
+	go func() {
+		err := call()
+		t.lock.Lock()
+		t.pending = false
+		t.lock.Unlock()
+		result <- err
+	}()
+	select {
+	case err := <-result:
+		return t.record(time.Since(start), err)
+	case <-time.After(probeTimeout):
+		return t.record(0, fmt.Errorf("no response within %s", probeTimeout))
+	}
+}
+
+// Probe the connection with ConnectGetVersion, which libvirt answers
+// without talking to the hypervisor, and export the result as metrics.
+func (l *LibVirt) ProbeConnection() ConnectionHealth {
+	health := l.health.probe(func() error {
+		if !l.virt.IsConnected() {
+			return errors.New("not connected")
+		}
+		_, err := l.virt.ConnectGetVersion()
+		return err
+	})
+	rpcLatency.WithLabelValues(l.uri).Set(health.Latency.Seconds())
+	rpcConsecutiveFailures.WithLabelValues(l.uri).Set(float64(health.ConsecutiveFailures))
+	return health
+}
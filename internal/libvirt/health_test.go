@@ -0,0 +1,95 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package libvirt
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestConnectionHealthTracker(t *testing.T) {
+	var tracker connectionHealthTracker
+	health := tracker.probe(func() error { return nil })
+	if health.Err != nil || health.ConsecutiveFailures != 0 || health.Degraded() {
+		t.Errorf("Expected healthy connection, got %+v", health)
+	}
+
+	for i := 1; i <= degradedFailures; i++ {
+		health = tracker.probe(func() error { return errors.New("broken pipe") })
+		if health.ConsecutiveFailures != i {
+			t.Errorf("Expected %d failures, got %d", i, health.ConsecutiveFailures)
+		}
+		if health.Degraded() != (i >= degradedFailures) {
+			t.Errorf("Unexpected degraded %v after %d failures", health.Degraded(), i)
+		}
+	}
+	if health.String() != "3 probes failed in a row: broken pipe" {
+		t.Errorf("Unexpected summary %q", health.String())
+	}
+
+	health = tracker.probe(func() error { return nil })
+	if health.ConsecutiveFailures != 0 || health.Degraded() {
+		t.Errorf("Expected the failures to be reset, got %+v", health)
+	}
+}
+
+func TestConnectionHealthTracker_Timeout(t *testing.T) {
+	old := probeTimeout
+	probeTimeout = 10 * time.Millisecond
+	t.Cleanup(func() { probeTimeout = old })
+
+	var tracker connectionHealthTracker
+	release := make(chan struct{})
+	health := tracker.probe(func() error {
+		<-release
+		return nil
+	})
+	if health.ConsecutiveFailures != 1 || health.Err == nil {
+		t.Errorf("Expected the probe to time out, got %+v", health)
+	}
+
+	// The call of the first probe is still waiting.
+	health = tracker.probe(func() error { return nil })
+	if !errors.Is(health.Err, errProbePending) || health.ConsecutiveFailures != 2 {
+		t.Errorf("Expected the probe to fail while the previous one is pending, got %+v", health)
+	}
+
+	close(release)
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		tracker.lock.Lock()
+		pending := tracker.pending
+		tracker.lock.Unlock()
+		if !pending {
+			break
+		}
+	}
+	health = tracker.probe(func() error { return nil })
+	if health.Err != nil {
+		t.Errorf("Expected the probe to succeed once libvirt answered, got %+v", health)
+	}
+}
+
+func TestConnectionHealth_Degraded(t *testing.T) {
+	if !(ConnectionHealth{Latency: 2 * time.Second}).Degraded() {
+		t.Errorf("Expected a slow connection to be degraded")
+	}
+	if (ConnectionHealth{Latency: 2 * time.Millisecond}).String() != "rpc round trip 2ms" {
+		t.Errorf("Unexpected summary")
+	}
+}
@@ -0,0 +1,302 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package libvirt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	v1 "github.com/cobaltcore-dev/openstack-hypervisor-operator/api/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/cobaltcore-dev/kvm-node-agent/api/v1alpha1"
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/libvirt/dominfo"
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/sys"
+)
+
+// Build the status of the Instance resource mirroring the given domain.
+func instanceStatus(domain dominfo.DomainInfo, active bool) v1alpha1.InstanceStatus {
+	status := v1alpha1.InstanceStatus{
+		Hypervisor:   sys.NodeLabelName,
+		DomainName:   domain.Name,
+		InstanceName: domain.InstanceName(),
+		Active:       active,
+		FixedIPs:     domain.FixedIPs(),
+		Owner: v1alpha1.InstanceOwner{
+			ProjectID: domain.ProjectUUID(),
+			UserID:    domain.UserUUID(),
+		},
+	}
+	if flavor := domain.Flavor(); flavor != nil {
+		status.Flavor = v1alpha1.InstanceFlavor{
+			Name:     flavor.Name,
+			MemoryMB: flavor.Memory,
+			VCPUs:    flavor.VCPUs,
+			DiskGB:   flavor.Disk,
+		}
+	}
+	if created, ok := domain.CreationTime(); ok {
+		status.CreationTime = &metav1.Time{Time: created}
+	}
+
+	if domain.CPUTune != nil {
+		for _, pin := range domain.CPUTune.VCPUPins {
+			status.Pinning.VCPUs = append(status.Pinning.VCPUs, v1alpha1.InstanceVCPUPin{
+				VCPU:   pin.VCPU,
+				CPUSet: pin.CPUSet,
+			})
+		}
+		if domain.CPUTune.EmulatorPin != nil {
+			status.Pinning.EmulatorCPUSet = domain.CPUTune.EmulatorPin.CPUSet
+		}
+	}
+	if domain.NumaTune != nil && domain.NumaTune.Memory != nil {
+		status.Pinning.MemoryNodeset = domain.NumaTune.Memory.Nodeset
+	}
+	if domain.MemoryBacking != nil && domain.MemoryBacking.Access != nil {
+		status.MemoryAccess = domain.MemoryBacking.Access.Mode
+	}
+
+	if domain.Devices == nil {
+		return status
+	}
+	status.GuestAgent = guestAgent(domain, active)
+	for _, disk := range domain.Devices.Disks {
+		d := v1alpha1.InstanceDisk{Device: disk.Device}
+		if disk.Target != nil {
+			d.Target, d.Bus = disk.Target.Dev, disk.Target.Bus
+		}
+		if disk.Source != nil {
+			d.Source = disk.Source.File
+		}
+		if disk.IOTune != nil {
+			d.IOTune = &v1alpha1.InstanceIOTune{
+				TotalIOPS:        disk.IOTune.TotalIOPSSec,
+				ReadIOPS:         disk.IOTune.ReadIOPSSec,
+				WriteIOPS:        disk.IOTune.WriteIOPSSec,
+				TotalBytesPerSec: disk.IOTune.TotalBytesSec,
+				ReadBytesPerSec:  disk.IOTune.ReadBytesSec,
+				WriteBytesPerSec: disk.IOTune.WriteBytesSec,
+			}
+		}
+		status.Devices.Disks = append(status.Devices.Disks, d)
+	}
+	for _, iface := range domain.Devices.Interfaces {
+		i := v1alpha1.InstanceInterface{Type: iface.Type}
+		if iface.MAC != nil {
+			i.MACAddress = iface.MAC.Address
+		}
+		if iface.Target != nil {
+			i.Target = iface.Target.Dev
+		}
+		if iface.Model != nil {
+			i.Model = iface.Model.Type
+		}
+		if iface.Source != nil {
+			i.Bridge = iface.Source.Bridge
+			i.SocketPath = iface.Source.Path
+		}
+		i.Queues = 1
+		if iface.Driver != nil {
+			i.Driver = iface.Driver.Name
+			if queues, err := strconv.Atoi(iface.Driver.Queues); err == nil {
+				i.Queues = queues
+			}
+			i.RxQueueSize, _ = strconv.Atoi(iface.Driver.RxQueueSize)
+			i.TxQueueSize, _ = strconv.Atoi(iface.Driver.TxQueueSize)
+		}
+		// A single queue limits the throughput to one vcpu, which is a
+		// common misconfiguration if the flavor asks for multiqueue.
+		i.QueueMismatch = domain.MultiqueueRequested() && i.Queues <= 1
+		status.Devices.Interfaces = append(status.Devices.Interfaces, i)
+	}
+	for _, rng := range domain.Devices.RNGs {
+		r := v1alpha1.InstanceRNG{Model: rng.Model}
+		if rng.Backend != nil {
+			r.Backend = rng.Backend.Model
+			r.Source = strings.TrimSpace(rng.Backend.Source)
+		}
+		status.Devices.RNGs = append(status.Devices.RNGs, r)
+	}
+	for _, fs := range domain.Devices.Filesystems {
+		f := v1alpha1.InstanceFilesystem{}
+		if fs.Driver != nil {
+			f.Driver, f.QueueSize = fs.Driver.Type, fs.Driver.Queue
+		}
+		if fs.Source != nil {
+			f.Source = fs.Source.Dir
+			if f.Source == "" {
+				f.Source = fs.Source.Socket
+			}
+		}
+		if fs.Target != nil {
+			f.Tag = fs.Target.Dir
+		}
+		status.Devices.Filesystems = append(status.Devices.Filesystems, f)
+	}
+	return status
+}
+
+// Export the queue configuration of the domain interfaces.
+func updateInterfaceQueueMetrics(statuses map[string]v1alpha1.InstanceStatus) {
+	for uuid, status := range statuses {
+		for idx, iface := range status.Devices.Interfaces {
+			name := iface.Target
+			if name == "" {
+				name = strconv.Itoa(idx)
+			}
+			interfaceQueues.WithLabelValues(uuid, name).Set(float64(iface.Queues))
+			mismatch := 0.0
+			if iface.QueueMismatch {
+				mismatch = 1
+			}
+			interfaceQueueMismatch.WithLabelValues(uuid, name).Set(mismatch)
+		}
+	}
+}
+
+// Export the number of random number generator devices of the domains, so
+// that guests without one can be found.
+func updateRNGMetrics(statuses map[string]v1alpha1.InstanceStatus) {
+	for uuid, status := range statuses {
+		rngDevices.WithLabelValues(uuid).Set(float64(len(status.Devices.RNGs)))
+	}
+}
+
+// Create, update and delete the Instance resources of this hypervisor, so
+// that there is exactly one per domain, keyed by the domain uuid. The io
+// limits of the instance spec are applied to the domain, the status picks
+// them up with the next sync.
+//
+// Instances still owned by another hypervisor, e.g. the source of a
+// migration, are left alone. They are recreated by this hypervisor once
+// the other hypervisor removed them. The same applies to the instances of
+// other libvirt drivers of this hypervisor.
+func (l *LibVirt) syncInstances(
+	ctx context.Context, hv v1.Hypervisor, statuses map[string]v1alpha1.InstanceStatus,
+) error {
+
+	var list v1alpha1.InstanceList
+	if err := l.client.List(ctx, &list,
+		client.InNamespace(sys.Namespace),
+		client.MatchingLabels{v1alpha1.LabelHypervisor: sys.NodeLabelName},
+	); err != nil {
+		return fmt.Errorf("failed to list instances: %w", err)
+	}
+
+	var errs []error
+	existing := make(map[string]*v1alpha1.Instance, len(list.Items))
+	for i := range list.Items {
+		instance := &list.Items[i]
+		if driver := instance.Status.Driver; driver != "" && driver != l.driver() {
+			continue
+		}
+		if _, ok := statuses[instance.Name]; !ok {
+			if err := l.client.Delete(ctx, instance); client.IgnoreNotFound(err) != nil {
+				errs = append(errs, fmt.Errorf("failed to delete instance %s: %w", instance.Name, err))
+			}
+			continue
+		}
+		existing[instance.Name] = instance
+	}
+
+	for uuid, status := range statuses {
+		instance, ok := existing[uuid]
+		if !ok {
+			instance = &v1alpha1.Instance{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      uuid,
+					Namespace: sys.Namespace,
+					Labels:    map[string]string{v1alpha1.LabelHypervisor: sys.NodeLabelName},
+				},
+			}
+			if hv.UID != "" {
+				// Clean up the instances together with the hypervisor.
+				instance.OwnerReferences = []metav1.OwnerReference{{
+					APIVersion: v1.GroupVersion.String(),
+					Kind:       "Hypervisor",
+					Name:       hv.Name,
+					UID:        hv.UID,
+				}}
+			}
+			if err := l.client.Create(ctx, instance); err != nil {
+				if !apierrors.IsAlreadyExists(err) {
+					errs = append(errs, fmt.Errorf("failed to create instance %s: %w", uuid, err))
+				}
+				continue
+			}
+		} else {
+			if status.Pauses == nil && instance.Status.Pauses != nil {
+				// The agent restarted, continue with the recorded pauses.
+				l.pauses.seed(uuid, instance.Status.Pauses)
+				status.Pauses = l.pauses.status(uuid)
+			}
+			if status.Watchdog == nil && instance.Status.Watchdog != nil {
+				l.watchdogs.seed(uuid, instance.Status.Watchdog)
+				status.Watchdog = l.watchdogs.status(uuid)
+			}
+			if status.GuestAgent != nil && status.GuestAgent.LastTransitionTime == nil &&
+				instance.Status.GuestAgent != nil &&
+				instance.Status.GuestAgent.Connected == status.GuestAgent.Connected {
+				l.guestAgents.seed(uuid, instance.Status.GuestAgent)
+				status.GuestAgent = l.guestAgents.status(uuid, status.GuestAgent)
+			}
+			for _, condition := range instance.Status.Conditions {
+				// The shutdown and power actions of the instance are
+				// followed by their own controllers.
+				if condition.Type != v1alpha1.ConditionTypeDiskIOError {
+					meta.SetStatusCondition(&status.Conditions, condition)
+				}
+			}
+			if pending := pendingIOTune(instance.Spec.IOTune, status); len(pending) > 0 {
+				if err := l.applyIOTune(uuid, status.Active, pending); err != nil {
+					errs = append(errs, err)
+				}
+			}
+			if equality.Semantic.DeepEqual(instance.Status, status) {
+				continue
+			}
+		}
+		base := instance.DeepCopy()
+		instance.Status = status
+		if err := l.client.Status().Patch(ctx, instance, client.MergeFrom(base)); err != nil {
+			errs = append(errs, fmt.Errorf("failed to patch instance %s: %w", uuid, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Export the host numa nodes of the pinned vcpus of the domains, so that
+// the telemetry of the host numa nodes can be attributed to the domains.
+func updateVCPUNUMAMetrics(statuses map[string]v1alpha1.InstanceStatus) {
+	for uuid, status := range statuses {
+		for _, pin := range status.Pinning.VCPUs {
+			for _, node := range pin.NUMANodes {
+				vcpuNUMANodes.WithLabelValues(uuid, strconv.Itoa(pin.VCPU),
+					pin.CPUSet, strconv.Itoa(node)).Set(1)
+			}
+		}
+	}
+}
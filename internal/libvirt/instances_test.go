@@ -0,0 +1,249 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package libvirt
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	v1 "github.com/cobaltcore-dev/openstack-hypervisor-operator/api/v1"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/cobaltcore-dev/kvm-node-agent/api/v1alpha1"
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/libvirt/dominfo"
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/sys"
+)
+
+func TestInstanceStatus(t *testing.T) {
+	domains, err := dominfo.NewClientEmulator().Get(nil)
+	if err != nil {
+		t.Fatalf("Failed to get example domain: %v", err)
+	}
+	status := instanceStatus(domains[0], true)
+
+	if !status.Active || status.Hypervisor != sys.NodeLabelName {
+		t.Errorf("Unexpected state: %+v", status)
+	}
+	if status.Flavor.Name != "g_k_c6_m24_v2" {
+		t.Errorf("Expected flavor g_k_c6_m24_v2, got %s", status.Flavor.Name)
+	}
+	if status.Flavor.MemoryMB != 24560 || status.Flavor.VCPUs != 6 || status.Flavor.DiskGB != 0 {
+		t.Errorf("Unexpected flavor: %+v", status.Flavor)
+	}
+	if status.Owner.ProjectID != "12345-abc" || status.Owner.UserID != "12345-abc" {
+		t.Errorf("Unexpected owner: %+v", status.Owner)
+	}
+	if len(status.FixedIPs) != 1 || status.FixedIPs[0] != "0.0.0.0" {
+		t.Errorf("Unexpected fixed ips: %v", status.FixedIPs)
+	}
+	if status.InstanceName != "example-12345-abc" {
+		t.Errorf("Expected instance name example-12345-abc, got %s", status.InstanceName)
+	}
+	if status.CreationTime == nil || status.CreationTime.UTC().Format(time.DateTime) != "2025-12-18 00:49:23" {
+		t.Errorf("Unexpected creation time: %v", status.CreationTime)
+	}
+	if len(status.Pinning.VCPUs) != 6 || status.Pinning.VCPUs[5].CPUSet != "32-63,160-191" {
+		t.Errorf("Unexpected vcpu pinning: %+v", status.Pinning.VCPUs)
+	}
+	if status.Pinning.EmulatorCPUSet != "32-63,160-191" || status.Pinning.MemoryNodeset != "1" {
+		t.Errorf("Unexpected pinning: %+v", status.Pinning)
+	}
+	if len(status.Devices.Disks) != 1 || status.Devices.Disks[0].Target != "vda" {
+		t.Errorf("Unexpected disks: %+v", status.Devices.Disks)
+	}
+	expected := v1alpha1.InstanceInterface{
+		Type:       "bridge",
+		MACAddress: "ab:cd:ef:12:34:56",
+		Target:     "abcdef",
+		Model:      "virtio",
+		Bridge:     "abcdef",
+		// The flavor requests multiqueue, but a single queue is configured.
+		Queues:        1,
+		QueueMismatch: true,
+	}
+	if len(status.Devices.Interfaces) != 1 || status.Devices.Interfaces[0] != expected {
+		t.Errorf("Unexpected interfaces: %+v", status.Devices.Interfaces)
+	}
+	rng := v1alpha1.InstanceRNG{Model: "virtio", Backend: "random", Source: "/dev/urandom"}
+	if len(status.Devices.RNGs) != 1 || status.Devices.RNGs[0] != rng {
+		t.Errorf("Unexpected rng devices: %+v", status.Devices.RNGs)
+	}
+	filesystem := v1alpha1.InstanceFilesystem{
+		Driver: "virtiofs", Source: "/var/lib/nova/mnt/share-12345", Tag: "share-12345", QueueSize: 1024,
+	}
+	if len(status.Devices.Filesystems) != 1 || status.Devices.Filesystems[0] != filesystem {
+		t.Errorf("Unexpected filesystems: %+v", status.Devices.Filesystems)
+	}
+	if status.MemoryAccess != "shared" {
+		t.Errorf("Expected shared memory access, got %q", status.MemoryAccess)
+	}
+	if status.GuestAgent == nil || !status.GuestAgent.Connected {
+		t.Errorf("Expected a connected guest agent, got %+v", status.GuestAgent)
+	}
+	if status := instanceStatus(domains[0], false); status.GuestAgent == nil || status.GuestAgent.Connected {
+		t.Errorf("Expected a disconnected guest agent of an inactive domain, got %+v", status.GuestAgent)
+	}
+}
+
+func TestSyncInstances(t *testing.T) {
+	ctx := context.Background()
+	scheme := runtime.NewScheme()
+	if err := v1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatalf("Failed to build scheme: %v", err)
+	}
+	labels := map[string]string{v1alpha1.LabelHypervisor: sys.NodeLabelName}
+	stale := &v1alpha1.Instance{
+		ObjectMeta: metav1.ObjectMeta{Name: "stale", Namespace: sys.Namespace, Labels: labels},
+	}
+	foreign := &v1alpha1.Instance{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "migrating",
+			Namespace: sys.Namespace,
+			Labels:    map[string]string{v1alpha1.LabelHypervisor: "other-node"},
+		},
+		Status: v1alpha1.InstanceStatus{Hypervisor: "other-node", Active: true},
+	}
+	l := &LibVirt{client: fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithStatusSubresource(&v1alpha1.Instance{}).
+		WithObjects(stale, foreign).
+		Build()}
+
+	hv := v1.Hypervisor{ObjectMeta: metav1.ObjectMeta{Name: sys.Hostname, UID: "hv-uid"}}
+	statuses := map[string]v1alpha1.InstanceStatus{
+		"new":       {Hypervisor: sys.NodeLabelName, DomainName: "instance-1", Active: true},
+		"migrating": {Hypervisor: sys.NodeLabelName, DomainName: "instance-2", Active: true},
+	}
+	if err := l.syncInstances(ctx, hv, statuses); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	var instance v1alpha1.Instance
+	key := client.ObjectKey{Name: "stale", Namespace: sys.Namespace}
+	if err := l.client.Get(ctx, key, &instance); err == nil {
+		t.Errorf("Expected stale instance to be deleted")
+	}
+
+	key.Name = "new"
+	if err := l.client.Get(ctx, key, &instance); err != nil {
+		t.Fatalf("Expected new instance to be created, got %v", err)
+	}
+	if instance.Status.DomainName != "instance-1" || !instance.Status.Active {
+		t.Errorf("Unexpected status of new instance: %+v", instance.Status)
+	}
+	if len(instance.OwnerReferences) != 1 || instance.OwnerReferences[0].UID != "hv-uid" {
+		t.Errorf("Expected instance to be owned by the hypervisor, got %+v", instance.OwnerReferences)
+	}
+
+	// The instance of the migration source is not taken over.
+	key.Name = "migrating"
+	if err := l.client.Get(ctx, key, &instance); err != nil {
+		t.Fatalf("Expected migrating instance to exist, got %v", err)
+	}
+	if instance.Status.Hypervisor != "other-node" {
+		t.Errorf("Expected migrating instance to stay with other-node, got %s", instance.Status.Hypervisor)
+	}
+
+	// Domains that stopped are updated.
+	statuses["new"] = v1alpha1.InstanceStatus{Hypervisor: sys.NodeLabelName, DomainName: "instance-1"}
+	if err := l.syncInstances(ctx, hv, statuses); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	key.Name = "new"
+	if err := l.client.Get(ctx, key, &instance); err != nil {
+		t.Fatalf("Expected new instance to exist, got %v", err)
+	}
+	if instance.Status.Active {
+		t.Errorf("Expected instance to be inactive")
+	}
+}
+
+func TestSyncInstancesOfOtherDriver(t *testing.T) {
+	ctx := context.Background()
+	scheme := runtime.NewScheme()
+	if err := v1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatalf("Failed to build scheme: %v", err)
+	}
+	labels := map[string]string{v1alpha1.LabelHypervisor: sys.NodeLabelName}
+	qemu := &v1alpha1.Instance{
+		ObjectMeta: metav1.ObjectMeta{Name: "qemu-domain", Namespace: sys.Namespace, Labels: labels},
+		Status:     v1alpha1.InstanceStatus{Hypervisor: sys.NodeLabelName, Driver: "qemu"},
+	}
+	l := &LibVirt{uri: "ch:///system", client: fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithStatusSubresource(&v1alpha1.Instance{}).
+		WithObjects(qemu).
+		Build()}
+
+	hv := v1.Hypervisor{ObjectMeta: metav1.ObjectMeta{Name: sys.Hostname}}
+	if err := l.syncInstances(ctx, hv, map[string]v1alpha1.InstanceStatus{}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	var instance v1alpha1.Instance
+	key := client.ObjectKey{Name: "qemu-domain", Namespace: sys.Namespace}
+	if err := l.client.Get(ctx, key, &instance); err != nil {
+		t.Errorf("Expected the instance of the qemu driver to be kept, got %v", err)
+	}
+}
+
+func TestDomainSeries_KeepsOtherDrivers(t *testing.T) {
+	rngDevices.Reset()
+	var qemu, ch domainSeries
+	qemu.replace([]string{"uuid-qemu"}, rngDevices)
+	rngDevices.WithLabelValues("uuid-qemu").Set(1)
+	ch.replace([]string{"uuid-ch"}, rngDevices)
+	rngDevices.WithLabelValues("uuid-ch").Set(0)
+
+	// The qemu driver exporting again doesn't remove the series of the
+	// cloud-hypervisor driver.
+	qemu.replace(nil, rngDevices)
+	if n := testutil.CollectAndCount(rngDevices); n != 1 {
+		t.Fatalf("Expected the series of the other driver, got %d series", n)
+	}
+	if got := testutil.ToFloat64(rngDevices.WithLabelValues("uuid-ch")); got != 0 {
+		t.Errorf("Unexpected value %v", got)
+	}
+	ch.replace(nil, rngDevices)
+	if n := testutil.CollectAndCount(rngDevices); n != 0 {
+		t.Errorf("Expected no series, got %d", n)
+	}
+}
+
+func TestUpdateInterfaceQueueMetrics_ByUUID(t *testing.T) {
+	interfaceQueues.Reset()
+	interfaceQueueMismatch.Reset()
+	updateInterfaceQueueMetrics(map[string]v1alpha1.InstanceStatus{
+		"uuid-1": {DomainName: "instance-1", Devices: v1alpha1.InstanceDevices{
+			Interfaces: []v1alpha1.InstanceInterface{{Target: "tap1", Queues: 1, QueueMismatch: true}},
+		}},
+	})
+	if got := testutil.ToFloat64(interfaceQueues.WithLabelValues("uuid-1", "tap1")); got != 1 {
+		t.Errorf("Expected 1 queue, got %v", got)
+	}
+	if got := testutil.ToFloat64(interfaceQueueMismatch.WithLabelValues("uuid-1", "tap1")); got != 1 {
+		t.Errorf("Expected a queue mismatch, got %v", got)
+	}
+	if n := testutil.CollectAndCount(interfaceQueues); n != 1 {
+		t.Errorf("Expected only the series of the uuid, got %d series", n)
+	}
+}
@@ -53,5 +53,5 @@ type Interface interface {
 	// Add information extracted from the libvirt socket to the hypervisor instance.
 	// If an error occurs, the instance is returned unmodified. The libvirt
 	// connection needs to be established before calling this function.
-	Process(hv v1.Hypervisor) (v1.Hypervisor, error)
+	Process(ctx context.Context, hv v1.Hypervisor) (v1.Hypervisor, error)
 }
@@ -30,7 +30,7 @@ var _ Interface = &InterfaceMock{}
 //			WatchDomainChangesFunc: func(eventId libvirt.DomainEventID, handlerId string, handler func(context.Context, any)) {
 //				panic("mock out the WatchDomainChanges method")
 //			},
-//			ProcessFunc: func(hv v1.Hypervisor) (v1.Hypervisor, error) {
+//			ProcessFunc: func(ctx context.Context, hv v1.Hypervisor) (v1.Hypervisor, error) {
 //				panic("mock out the Process method")
 //			},
 //		}
@@ -50,7 +50,7 @@ type InterfaceMock struct {
 	WatchDomainChangesFunc func(eventId libvirt.DomainEventID, handlerId string, handler func(context.Context, any))
 
 	// ProcessFunc mocks the Process method.
-	ProcessFunc func(hv v1.Hypervisor) (v1.Hypervisor, error)
+	ProcessFunc func(ctx context.Context, hv v1.Hypervisor) (v1.Hypervisor, error)
 
 	// calls tracks calls to the methods.
 	calls struct {
@@ -68,7 +68,8 @@ type InterfaceMock struct {
 		}
 		// Process holds details about calls to the Process method.
 		Process []struct {
-			Hv v1.Hypervisor
+			Ctx context.Context
+			Hv  v1.Hypervisor
 		}
 	}
 	lockClose              sync.RWMutex
@@ -172,19 +173,21 @@ func (mock *InterfaceMock) WatchDomainChangesCalls() []struct {
 }
 
 // Process calls ProcessFunc.
-func (mock *InterfaceMock) Process(hv v1.Hypervisor) (v1.Hypervisor, error) {
+func (mock *InterfaceMock) Process(ctx context.Context, hv v1.Hypervisor) (v1.Hypervisor, error) {
 	if mock.ProcessFunc == nil {
 		panic("InterfaceMock.ProcessFunc: method is nil but Interface.Process was just called")
 	}
 	callInfo := struct {
-		Hv v1.Hypervisor
+		Ctx context.Context
+		Hv  v1.Hypervisor
 	}{
-		Hv: hv,
+		Ctx: ctx,
+		Hv:  hv,
 	}
 	mock.lockProcess.Lock()
 	mock.calls.Process = append(mock.calls.Process, callInfo)
 	mock.lockProcess.Unlock()
-	return mock.ProcessFunc(hv)
+	return mock.ProcessFunc(ctx, hv)
 }
 
 // ProcessCalls gets all the calls that were made to Process.
@@ -192,10 +195,12 @@ func (mock *InterfaceMock) Process(hv v1.Hypervisor) (v1.Hypervisor, error) {
 //
 //	len(mockedInterface.ProcessCalls())
 func (mock *InterfaceMock) ProcessCalls() []struct {
-	Hv v1.Hypervisor
+	Ctx context.Context
+	Hv  v1.Hypervisor
 } {
 	var calls []struct {
-		Hv v1.Hypervisor
+		Ctx context.Context
+		Hv  v1.Hypervisor
 	}
 	mock.lockProcess.RLock()
 	calls = mock.calls.Process
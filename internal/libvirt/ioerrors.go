@@ -0,0 +1,169 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package libvirt
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/digitalocean/go-libvirt"
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logger "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/cobaltcore-dev/kvm-node-agent/api/v1alpha1"
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/sys"
+)
+
+// Time after the last io error of a disk until it is no longer reported.
+// Disks reporting errors to the guest don't pause the domain, so there is
+// no event telling that the storage recovered.
+const ioErrorExpiry = 10 * time.Minute
+
+// Get the action taken on an io error from the io error event.
+func ioErrorAction(action int32) string {
+	switch libvirt.DomainEventIOErrorAction(action) {
+	case libvirt.DomainEventIoErrorNone:
+		return "none"
+	case libvirt.DomainEventIoErrorPause:
+		return "pause"
+	case libvirt.DomainEventIoErrorReport:
+		return "report"
+	}
+	return "unknown"
+}
+
+// The io errors of a disk of a domain.
+type diskIOError struct {
+	path   string
+	reason string
+	count  int64
+	since  time.Time
+	last   time.Time
+}
+
+// Tracks the io errors of the disks of the domains by domain uuid and disk
+// alias, until the domain is resumed or stopped. The zero value is ready
+// to use.
+type ioErrorTracker struct {
+	lock   sync.Mutex
+	errors map[string]map[string]*diskIOError
+}
+
+// Record an io error of the disk. Returns true for the first error of the
+// disk or if the reason changed, which is worth an event.
+func (t *ioErrorTracker) failed(uuid, disk, path, reason string, at time.Time) bool {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if t.errors == nil {
+		t.errors = make(map[string]map[string]*diskIOError)
+	}
+	disks, ok := t.errors[uuid]
+	if !ok {
+		disks = make(map[string]*diskIOError)
+		t.errors[uuid] = disks
+	}
+	ioError, ok := disks[disk]
+	if !ok || at.Sub(ioError.last) > ioErrorExpiry {
+		ioError = &diskIOError{since: at}
+		disks[disk] = ioError
+		ok = false
+	}
+	changed := !ok || ioError.reason != reason
+	ioError.path = path
+	ioError.reason = reason
+	ioError.count++
+	ioError.last = at
+	return changed
+}
+
+// Forget the io errors of the domain, e.g. once it is resumed after the
+// storage recovered.
+func (t *ioErrorTracker) clear(uuid string) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	delete(t.errors, uuid)
+}
+
+// Get the condition of the io errors of the domain, nil if none of its
+// disks reported an io error recently.
+func (t *ioErrorTracker) condition(uuid string, now time.Time) *metav1.Condition {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	var problems []string
+	var since time.Time
+	for disk, ioError := range t.errors[uuid] {
+		if now.Sub(ioError.last) > ioErrorExpiry {
+			continue
+		}
+		reason := ioError.reason
+		if reason == "" {
+			reason = "unknown"
+		}
+		problems = append(problems, fmt.Sprintf("%s (%s): %s, %d errors", disk, ioError.path, reason, ioError.count))
+		if since.IsZero() || ioError.since.Before(since) {
+			since = ioError.since
+		}
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+	sort.Strings(problems)
+	return &metav1.Condition{
+		Type:               v1alpha1.ConditionTypeDiskIOError,
+		Status:             metav1.ConditionTrue,
+		Reason:             "IOError",
+		Message:            strings.Join(problems, "; "),
+		LastTransitionTime: metav1.NewTime(since.Truncate(time.Second)),
+	}
+}
+
+// Record an io error of a disk and report it in an event of the instance,
+// once per disk and reason.
+func (l *LibVirt) onIOError(ctx context.Context, event any) {
+	e := event.(*libvirt.DomainEventCallbackIOErrorReasonMsg)
+	uuid := GetOpenstackUUID(e.Msg.Dom)
+	action := ioErrorAction(e.Msg.Action)
+	log := logger.FromContext(ctx).WithValues("server", uuid, "disk", e.Msg.DevAlias)
+	domainIOErrors.WithLabelValues(uuid, e.Msg.DevAlias, e.Msg.Reason).Inc()
+	if !l.ioErrors.failed(uuid, e.Msg.DevAlias, e.Msg.SrcPath, e.Msg.Reason, time.Now()) {
+		return
+	}
+	log.Info("disk io error", "path", e.Msg.SrcPath, "reason", e.Msg.Reason, "action", action)
+	if l.recorder == nil {
+		return
+	}
+	var instance v1alpha1.Instance
+	if err := l.client.Get(ctx, client.ObjectKey{Name: uuid, Namespace: sys.Namespace}, &instance); err != nil {
+		log.Error(err, "failed to get instance of domain with io error")
+		return
+	}
+	l.recorder.Eventf(&instance, nil, corev1.EventTypeWarning, "DiskIOError", "IOError",
+		"io error on disk %s (%s): %s, action %s", e.Msg.DevAlias, e.Msg.SrcPath, e.Msg.Reason, action)
+}
+
+// Forget the io error metrics of an undefined domain.
+func forgetIOErrorMetrics(uuid string) {
+	domainIOErrors.DeletePartialMatch(prometheus.Labels{"domain": uuid})
+}
@@ -0,0 +1,79 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package libvirt
+
+import (
+	"testing"
+	"time"
+
+	"github.com/digitalocean/go-libvirt"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestIOErrorAction(t *testing.T) {
+	if action := ioErrorAction(int32(libvirt.DomainEventIoErrorPause)); action != "pause" {
+		t.Errorf("Unexpected action %q", action)
+	}
+	if action := ioErrorAction(99); action != "unknown" {
+		t.Errorf("Unexpected action %q", action)
+	}
+}
+
+func TestIOErrorTracker(t *testing.T) {
+	var tracker ioErrorTracker
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	if condition := tracker.condition("a", start); condition != nil {
+		t.Errorf("Expected no condition without io errors, got %+v", condition)
+	}
+	if !tracker.failed("a", "virtio-disk0", "/dev/sdb", "eio", start) {
+		t.Errorf("Expected the first error to be reported")
+	}
+	if tracker.failed("a", "virtio-disk0", "/dev/sdb", "eio", start.Add(time.Second)) {
+		t.Errorf("Expected repeated errors not to be reported")
+	}
+	if !tracker.failed("a", "virtio-disk0", "/dev/sdb", "enospc", start.Add(2*time.Second)) {
+		t.Errorf("Expected a new reason to be reported")
+	}
+	tracker.failed("a", "virtio-disk1", "/dev/sdc", "", start.Add(3*time.Second))
+
+	condition := tracker.condition("a", start.Add(time.Minute))
+	if condition == nil {
+		t.Fatal("Expected a condition")
+	}
+	if condition.Status != metav1.ConditionTrue || !condition.LastTransitionTime.Time.Equal(start) {
+		t.Errorf("Unexpected condition %+v", condition)
+	}
+	expected := "virtio-disk0 (/dev/sdb): enospc, 3 errors; virtio-disk1 (/dev/sdc): unknown, 1 errors"
+	if condition.Message != expected {
+		t.Errorf("Expected message %q, got %q", expected, condition.Message)
+	}
+
+	// Old errors expire, and start over once the disk fails again.
+	if condition := tracker.condition("a", start.Add(time.Hour)); condition != nil {
+		t.Errorf("Expected expired errors not to be reported, got %+v", condition)
+	}
+	if !tracker.failed("a", "virtio-disk0", "/dev/sdb", "enospc", start.Add(time.Hour)) {
+		t.Errorf("Expected an error after the expiry to be reported")
+	}
+
+	tracker.clear("a")
+	if condition := tracker.condition("a", start.Add(time.Hour)); condition != nil {
+		t.Errorf("Expected no condition after clearing, got %+v", condition)
+	}
+}
@@ -0,0 +1,101 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package libvirt
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/digitalocean/go-libvirt"
+
+	"github.com/cobaltcore-dev/kvm-node-agent/api/v1alpha1"
+)
+
+// Get the io limits of the disks which differ from the ones configured in
+// the domain. Limits for disks the domain doesn't have are ignored.
+func pendingIOTune(spec []v1alpha1.InstanceDiskIOTune, status v1alpha1.InstanceStatus) []v1alpha1.InstanceDiskIOTune {
+	current := make(map[string]v1alpha1.InstanceIOTune)
+	for _, disk := range status.Devices.Disks {
+		if disk.Target == "" {
+			continue
+		}
+		var tune v1alpha1.InstanceIOTune
+		if disk.IOTune != nil {
+			tune = *disk.IOTune
+		}
+		current[disk.Target] = tune
+	}
+	var pending []v1alpha1.InstanceDiskIOTune
+	for _, tune := range spec {
+		if cur, ok := current[tune.Target]; ok && cur != tune.InstanceIOTune {
+			pending = append(pending, tune)
+		}
+	}
+	return pending
+}
+
+// Convert the io limits to the typed parameters of the libvirt block io
+// tune api. All limits are passed, so that zero removes a limit.
+func ioTuneParams(tune v1alpha1.InstanceIOTune) []libvirt.TypedParam {
+	limits := []struct {
+		field string
+		value int64
+	}{
+		{"total_iops_sec", tune.TotalIOPS},
+		{"read_iops_sec", tune.ReadIOPS},
+		{"write_iops_sec", tune.WriteIOPS},
+		{"total_bytes_sec", tune.TotalBytesPerSec},
+		{"read_bytes_sec", tune.ReadBytesPerSec},
+		{"write_bytes_sec", tune.WriteBytesPerSec},
+	}
+	params := make([]libvirt.TypedParam, 0, len(limits))
+	for _, limit := range limits {
+		params = append(params, libvirt.TypedParam{
+			Field: limit.field,
+			Value: *libvirt.NewTypedParamValueUllong(uint64(max(limit.value, 0))),
+		})
+	}
+	return params
+}
+
+// Apply the io limits to the disks of the domain. The limits are written
+// to the persistent config, and to the running domain if it is active.
+func (l *LibVirt) applyIOTune(uuid string, active bool, tunes []v1alpha1.InstanceDiskIOTune) error {
+	id, err := ParseUUID(uuid)
+	if err != nil {
+		return err
+	}
+	domain, err := l.virt.DomainLookupByUUID(libvirt.UUID(id))
+	if err != nil {
+		return fmt.Errorf("failed to lookup domain %s: %w", uuid, err)
+	}
+	flags := libvirt.DomainAffectConfig
+	if active {
+		flags |= libvirt.DomainAffectLive
+	}
+	var errs []error
+	for _, tune := range tunes {
+		if err := l.virt.DomainSetBlockIOTune(
+			domain, tune.Target, ioTuneParams(tune.InstanceIOTune), uint32(flags),
+		); err != nil {
+			errs = append(errs, fmt.Errorf("failed to set io limits of disk %s of domain %s: %w",
+				tune.Target, uuid, err))
+		}
+	}
+	return errors.Join(errs...)
+}
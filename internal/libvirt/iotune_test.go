@@ -0,0 +1,103 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package libvirt
+
+import (
+	"encoding/xml"
+	"reflect"
+	"testing"
+
+	"github.com/cobaltcore-dev/kvm-node-agent/api/v1alpha1"
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/libvirt/dominfo"
+)
+
+func TestInstanceStatus_IOTune(t *testing.T) {
+	var domain dominfo.DomainInfo
+	if err := xml.Unmarshal([]byte(`<domain type="kvm">
+  <devices>
+    <disk type="network" device="disk">
+      <target dev="vda" bus="virtio"/>
+      <iotune>
+        <total_iops_sec>1000</total_iops_sec>
+        <read_bytes_sec>1048576</read_bytes_sec>
+      </iotune>
+    </disk>
+    <disk type="network" device="disk">
+      <target dev="vdb" bus="virtio"/>
+    </disk>
+  </devices>
+</domain>`), &domain); err != nil {
+		t.Fatalf("Failed to parse domain: %v", err)
+	}
+	status := instanceStatus(domain, true)
+	if len(status.Devices.Disks) != 2 {
+		t.Fatalf("Expected 2 disks, got %+v", status.Devices.Disks)
+	}
+	expected := &v1alpha1.InstanceIOTune{TotalIOPS: 1000, ReadBytesPerSec: 1048576}
+	if !reflect.DeepEqual(status.Devices.Disks[0].IOTune, expected) {
+		t.Errorf("Expected io limits %+v, got %+v", expected, status.Devices.Disks[0].IOTune)
+	}
+	if status.Devices.Disks[1].IOTune != nil {
+		t.Errorf("Expected no io limits, got %+v", status.Devices.Disks[1].IOTune)
+	}
+}
+
+func TestPendingIOTune(t *testing.T) {
+	status := v1alpha1.InstanceStatus{Devices: v1alpha1.InstanceDevices{Disks: []v1alpha1.InstanceDisk{
+		{Target: "vda", IOTune: &v1alpha1.InstanceIOTune{TotalIOPS: 1000}},
+		{Target: "vdb"},
+	}}}
+	spec := []v1alpha1.InstanceDiskIOTune{
+		{Target: "vda", InstanceIOTune: v1alpha1.InstanceIOTune{TotalIOPS: 1000}},
+		{Target: "vdb", InstanceIOTune: v1alpha1.InstanceIOTune{WriteBytesPerSec: 1 << 20}},
+		{Target: "vdc", InstanceIOTune: v1alpha1.InstanceIOTune{TotalIOPS: 10}},
+	}
+	pending := pendingIOTune(spec, status)
+	if len(pending) != 1 || pending[0].Target != "vdb" {
+		t.Errorf("Expected only vdb to be pending, got %+v", pending)
+	}
+
+	// Removing all limits of a disk is pending as well.
+	spec = []v1alpha1.InstanceDiskIOTune{{Target: "vda"}}
+	if pending := pendingIOTune(spec, status); len(pending) != 1 || pending[0].Target != "vda" {
+		t.Errorf("Expected vda to be pending, got %+v", pending)
+	}
+
+	if pending := pendingIOTune(nil, status); len(pending) != 0 {
+		t.Errorf("Expected nothing pending without a spec, got %+v", pending)
+	}
+}
+
+func TestIOTuneParams(t *testing.T) {
+	params := ioTuneParams(v1alpha1.InstanceIOTune{ReadIOPS: 500, TotalBytesPerSec: 1 << 20})
+	values := make(map[string]any)
+	for _, param := range params {
+		values[param.Field] = param.Value.I
+	}
+	expected := map[string]any{
+		"total_iops_sec":  uint64(0),
+		"read_iops_sec":   uint64(500),
+		"write_iops_sec":  uint64(0),
+		"total_bytes_sec": uint64(1 << 20),
+		"read_bytes_sec":  uint64(0),
+		"write_bytes_sec": uint64(0),
+	}
+	if !reflect.DeepEqual(values, expected) {
+		t.Errorf("Expected params %v, got %v", expected, values)
+	}
+}
@@ -0,0 +1,228 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package libvirt
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/digitalocean/go-libvirt"
+
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/libvirt/dominfo"
+)
+
+// Directories searched for leftovers of crashed domains. The agent runs in
+// its own network namespace, so the network devices are read from the sysfs
+// of the host mounted into the pod.
+var (
+	hostNetDevicesPath  = "/host/sys/class/net"
+	qemuStatePath       = "/var/lib/libvirt/qemu"
+	vhostUserSocketPath = "/run/openvswitch"
+)
+
+// Kinds of leftovers of crashed domains.
+const (
+	LeftoverTapDevice       = "TapDevice"
+	LeftoverVhostUserSocket = "VhostUserSocket"
+	LeftoverStateDir        = "StateDir"
+)
+
+// Prefixes of the network devices created for domains by libvirt and nova.
+var tapDevicePrefixes = []string{"tap", "vnet", "macvtap"}
+
+// Leftovers are only removed if they were found on two consecutive passes
+// and not modified for this long. A domain being started creates its state
+// directory and socket before it is listed as running.
+var leftoverGracePeriod = 10 * time.Minute
+
+// Leftover is a host resource of a domain which is no longer running, e.g.
+// left behind by a crashed qemu process.
+type Leftover struct {
+	Kind string
+	Path string
+	// Whether the leftover was removed in this pass.
+	Removed bool
+}
+
+// Summary of the leftover for humans, e.g. "TapDevice tap0a1b2c3d-4e".
+func (l Leftover) String() string {
+	return l.Kind + " " + filepath.Base(l.Path)
+}
+
+// LeftoverJanitor finds the leftovers of crashed domains.
+type LeftoverJanitor interface {
+	// CleanLeftovers returns the leftovers of domains no longer running and
+	// removes those already found on the previous pass and older than the
+	// grace period, unless dryRun is set. Tap devices are only reported.
+	// The ovsSockets are the names of the vhost-user sockets served by Open
+	// vSwitch (dpdkvhostuser), they are never taken for leftovers.
+	CleanLeftovers(dryRun bool, ovsSockets []string) ([]Leftover, error)
+}
+
+// leftoverTracker remembers the leftovers found on the previous pass.
+type leftoverTracker struct {
+	lock sync.Mutex
+	seen map[string]bool
+}
+
+// Get the name of the state directory libvirt creates for a running qemu
+// domain, see virDomainDefGetShortName.
+func qemuStateDirName(domain dominfo.DomainInfo) string {
+	name := domain.Name
+	if len(name) > 20 {
+		name = name[:20]
+	}
+	return "domain-" + domain.ID + "-" + name
+}
+
+// Find the tap devices, vhost-user sockets and qemu state directories not
+// belonging to any of the running domains. The sockets Open vSwitch serves
+// itself belong to it and are skipped.
+func findLeftovers(active []dominfo.DomainInfo, ovsSockets []string, netDir, stateDir, socketDir string) ([]Leftover, error) {
+	devices := make(map[string]bool)
+	sockets := make(map[string]bool)
+	stateDirs := make(map[string]bool)
+	for _, name := range ovsSockets {
+		sockets[name] = true
+	}
+	for _, domain := range active {
+		stateDirs[qemuStateDirName(domain)] = true
+		if domain.Devices == nil {
+			continue
+		}
+		for _, iface := range domain.Devices.Interfaces {
+			if iface.Target != nil && iface.Target.Dev != "" {
+				devices[iface.Target.Dev] = true
+			}
+			if iface.Source != nil && iface.Source.Path != "" {
+				sockets[filepath.Base(iface.Source.Path)] = true
+			}
+		}
+	}
+
+	var leftovers []Leftover
+	var errs []error
+	collect := func(dir, kind string, orphan func(name string) bool) {
+		entries, err := os.ReadDir(dir)
+		if errors.Is(err, os.ErrNotExist) {
+			return
+		}
+		if err != nil {
+			errs = append(errs, err)
+			return
+		}
+		for _, entry := range entries {
+			if orphan(entry.Name()) {
+				leftovers = append(leftovers, Leftover{Kind: kind, Path: filepath.Join(dir, entry.Name())})
+			}
+		}
+	}
+	collect(netDir, LeftoverTapDevice, func(name string) bool {
+		for _, prefix := range tapDevicePrefixes {
+			if strings.HasPrefix(name, prefix) {
+				return !devices[name]
+			}
+		}
+		return false
+	})
+	collect(socketDir, LeftoverVhostUserSocket, func(name string) bool {
+		return strings.HasPrefix(name, "vhu") && !sockets[name]
+	})
+	collect(stateDir, LeftoverStateDir, func(name string) bool {
+		return strings.HasPrefix(name, "domain-") && !stateDirs[name]
+	})
+	sort.Slice(leftovers, func(i, j int) bool {
+		return leftovers[i].Path < leftovers[j].Path
+	})
+	return leftovers, errors.Join(errs...)
+}
+
+// Remove the leftovers found which are expired and mark them removed. Tap
+// devices live in the network namespace of the host and are left to the
+// operator.
+func removeLeftovers(leftovers []Leftover, expired func(Leftover) bool) error {
+	var errs []error
+	for i, leftover := range leftovers {
+		if leftover.Kind == LeftoverTapDevice || !expired(leftover) {
+			continue
+		}
+		var err error
+		switch leftover.Kind {
+		case LeftoverVhostUserSocket:
+			err = os.Remove(leftover.Path)
+		case LeftoverStateDir:
+			err = os.RemoveAll(leftover.Path)
+		default:
+			continue
+		}
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			errs = append(errs, fmt.Errorf("failed to remove %s: %w", leftover, err))
+			continue
+		}
+		leftovers[i].Removed = true
+	}
+	return errors.Join(errs...)
+}
+
+// Check if the file was not modified since the given time. Files which
+// can't be read are kept.
+func modifiedBefore(path string, t time.Time) bool {
+	info, err := os.Lstat(path)
+	return err == nil && info.ModTime().Before(t)
+}
+
+// Find the leftovers of domains of this driver which are no longer running.
+// Only use this if the driver is the only one on the host, the domains of
+// other drivers would be taken for leftovers.
+func (l *LibVirt) CleanLeftovers(dryRun bool, ovsSockets []string) ([]Leftover, error) {
+	active, err := l.domainInfoClient.Get(l.virt, libvirt.ConnectListDomainsActive)
+	if err != nil {
+		return nil, err
+	}
+	return l.leftovers.clean(active, ovsSockets, dryRun, time.Now())
+}
+
+// Find the leftovers not belonging to any of the running domains and remove
+// those found on the previous pass too and older than the grace period,
+// unless dryRun is set.
+func (t *leftoverTracker) clean(active []dominfo.DomainInfo, ovsSockets []string, dryRun bool, now time.Time) ([]Leftover, error) {
+	leftovers, err := findLeftovers(active, ovsSockets, hostNetDevicesPath, qemuStatePath, vhostUserSocketPath)
+	if err != nil {
+		return nil, err
+	}
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	previous := t.seen
+	t.seen = make(map[string]bool, len(leftovers))
+	for _, leftover := range leftovers {
+		t.seen[leftover.Path] = true
+	}
+	if dryRun {
+		return leftovers, nil
+	}
+	return leftovers, removeLeftovers(leftovers, func(leftover Leftover) bool {
+		return previous[leftover.Path] && modifiedBefore(leftover.Path, now.Add(-leftoverGracePeriod))
+	})
+}
@@ -0,0 +1,147 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package libvirt
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/libvirt/dominfo"
+)
+
+func TestQemuStateDirName(t *testing.T) {
+	domain := dominfo.DomainInfo{ID: "7", Name: "instance-0000000a-with-a-long-name"}
+	if got := qemuStateDirName(domain); got != "domain-7-instance-0000000a-wi" {
+		t.Errorf("Unexpected state dir %q", got)
+	}
+}
+
+func TestFindLeftovers(t *testing.T) {
+	dir := t.TempDir()
+	netDir := filepath.Join(dir, "net")
+	stateDir := filepath.Join(dir, "qemu")
+	socketDir := filepath.Join(dir, "openvswitch")
+	for _, path := range []string{
+		filepath.Join(netDir, "eth0"),
+		filepath.Join(netDir, "tap1111"),
+		filepath.Join(netDir, "tap2222"),
+		filepath.Join(stateDir, "domain-1-instance-1"),
+		filepath.Join(stateDir, "domain-2-instance-2"),
+		filepath.Join(stateDir, "channel"),
+		socketDir,
+	} {
+		if err := os.MkdirAll(path, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, name := range []string{"vhu1111", "vhu2222", "vhu3333", "db.sock"} {
+		if err := os.WriteFile(filepath.Join(socketDir, name), nil, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	active := []dominfo.DomainInfo{{
+		ID:   "1",
+		Name: "instance-1",
+		Devices: &dominfo.DomainDevices{Interfaces: []dominfo.DomainInterface{
+			{Target: &dominfo.DomainInterfaceTarget{Dev: "tap1111"}},
+			{Source: &dominfo.DomainInterfaceSource{Path: "/var/run/openvswitch/vhu1111"}},
+		}},
+	}}
+	leftovers, err := findLeftovers(active, []string{"vhu3333"}, netDir, stateDir, socketDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Leftover{
+		{Kind: LeftoverTapDevice, Path: filepath.Join(netDir, "tap2222")},
+		{Kind: LeftoverVhostUserSocket, Path: filepath.Join(socketDir, "vhu2222")},
+		{Kind: LeftoverStateDir, Path: filepath.Join(stateDir, "domain-2-instance-2")},
+	}
+	if !reflect.DeepEqual(leftovers, expected) {
+		t.Errorf("Expected %v, got %v", expected, leftovers)
+	}
+
+	if err := removeLeftovers(leftovers, func(Leftover) bool { return true }); err != nil {
+		t.Fatal(err)
+	}
+	for _, leftover := range leftovers {
+		_, err := os.Stat(leftover.Path)
+		if removed := os.IsNotExist(err); removed == (leftover.Kind == LeftoverTapDevice) || removed != leftover.Removed {
+			t.Errorf("Unexpected removal state of %s", leftover)
+		}
+	}
+}
+
+func TestLeftoverTracker_RemovesOnSecondPass(t *testing.T) {
+	dir := t.TempDir()
+	defer func(net, state, socket string) {
+		hostNetDevicesPath, qemuStatePath, vhostUserSocketPath = net, state, socket
+	}(hostNetDevicesPath, qemuStatePath, vhostUserSocketPath)
+	hostNetDevicesPath = filepath.Join(dir, "net")
+	qemuStatePath = filepath.Join(dir, "qemu")
+	vhostUserSocketPath = filepath.Join(dir, "openvswitch")
+
+	old := filepath.Join(qemuStatePath, "domain-1-instance-1")
+	fresh := filepath.Join(qemuStatePath, "domain-2-instance-2")
+	for _, path := range []string{old, fresh} {
+		if err := os.MkdirAll(path, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	now := time.Now()
+	past := now.Add(-2 * leftoverGracePeriod)
+	if err := os.Chtimes(old, past, past); err != nil {
+		t.Fatal(err)
+	}
+
+	var tracker leftoverTracker
+	leftovers, err := tracker.clean(nil, nil, false, now)
+	if err != nil || len(leftovers) != 2 {
+		t.Fatalf("Expected two leftovers, got %v (%v)", leftovers, err)
+	}
+	for _, leftover := range leftovers {
+		if leftover.Removed {
+			t.Errorf("Removed %s on the first pass", leftover)
+		}
+	}
+
+	leftovers, err = tracker.clean(nil, nil, false, now)
+	if err != nil || len(leftovers) != 2 {
+		t.Fatalf("Expected two leftovers, got %v (%v)", leftovers, err)
+	}
+	if !leftovers[0].Removed || leftovers[1].Removed {
+		t.Errorf("Expected only the old leftover to be removed, got %+v", leftovers)
+	}
+	if _, err := os.Stat(old); !os.IsNotExist(err) {
+		t.Errorf("Expected %s to be removed", old)
+	}
+	if _, err := os.Stat(fresh); err != nil {
+		t.Errorf("Expected %s to be kept: %v", fresh, err)
+	}
+}
+
+func TestFindLeftovers_MissingDirs(t *testing.T) {
+	dir := t.TempDir()
+	leftovers, err := findLeftovers(nil, nil, filepath.Join(dir, "net"), filepath.Join(dir, "qemu"), filepath.Join(dir, "ovs"))
+	if err != nil || len(leftovers) != 0 {
+		t.Errorf("Expected no leftovers, got %v (%v)", leftovers, err)
+	}
+}
@@ -0,0 +1,62 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package libvirt
+
+import (
+	"github.com/digitalocean/go-libvirt"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Gauge set from a typed parameter of the job stats of a domain, with the
+// factor converting the value to the unit of the gauge.
+type jobStatsGauge struct {
+	gauge *prometheus.GaugeVec
+	scale float64
+}
+
+// Gauges of the running job of a domain by the field of the job stats they
+// are set from.
+var jobStatsGauges = map[string]jobStatsGauge{
+	"data_processed":         {jobDataProcessed, 1},
+	"data_remaining":         {jobDataRemaining, 1},
+	"memory_dirty_rate":      {jobMemoryDirtyRate, 1},
+	"memory_iteration":       {jobMemoryIterations, 1},
+	"downtime":               {jobDowntime, 1e-3},
+	"auto_converge_throttle": {jobAutoConvergeThrottle, 1},
+}
+
+// Export the stats of the running job of the domain, e.g. of a live
+// migration, as returned by DomainGetJobStats.
+func updateJobMetrics(uuid, operation string, params []libvirt.TypedParam) {
+	for _, param := range params {
+		metric, ok := jobStatsGauges[param.Field]
+		if !ok {
+			continue
+		}
+		if value, ok := typedParamUint64(param.Value.I); ok {
+			metric.gauge.WithLabelValues(uuid, operation).Set(float64(value) * metric.scale)
+		}
+	}
+}
+
+// Drop the stats of the job of the domain once it isn't watched anymore.
+func deleteJobMetrics(uuid string) {
+	for _, metric := range jobStatsGauges {
+		metric.gauge.DeletePartialMatch(prometheus.Labels{"domain": uuid})
+	}
+}
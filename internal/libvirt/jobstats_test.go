@@ -0,0 +1,61 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package libvirt
+
+import (
+	"testing"
+
+	libvirt "github.com/digitalocean/go-libvirt"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestJobMetrics(t *testing.T) {
+	uuid := "7c3a1d52-0000-0000-0000-000000000001"
+	updateJobMetrics(uuid, "migration_out", []libvirt.TypedParam{
+		blockParam("data_processed", uint64(4<<30)),
+		blockParam("data_remaining", uint64(1<<30)),
+		blockParam("memory_dirty_rate", uint64(12000)),
+		blockParam("memory_iteration", uint64(3)),
+		blockParam("downtime", int64(250)),
+		blockParam("auto_converge_throttle", int32(20)),
+		blockParam("errmsg", "ignored"),
+	})
+	if got := testutil.ToFloat64(jobDataProcessed.WithLabelValues(uuid, "migration_out")); got != 4<<30 {
+		t.Errorf("Expected 4 GiB processed, got %v", got)
+	}
+	if got := testutil.ToFloat64(jobDataRemaining.WithLabelValues(uuid, "migration_out")); got != 1<<30 {
+		t.Errorf("Expected 1 GiB remaining, got %v", got)
+	}
+	if got := testutil.ToFloat64(jobMemoryDirtyRate.WithLabelValues(uuid, "migration_out")); got != 12000 {
+		t.Errorf("Expected dirty rate of 12000 pages/s, got %v", got)
+	}
+	if got := testutil.ToFloat64(jobMemoryIterations.WithLabelValues(uuid, "migration_out")); got != 3 {
+		t.Errorf("Expected 3 iterations, got %v", got)
+	}
+	if got := testutil.ToFloat64(jobDowntime.WithLabelValues(uuid, "migration_out")); got != 0.25 {
+		t.Errorf("Expected downtime of 0.25s, got %v", got)
+	}
+	if got := testutil.ToFloat64(jobAutoConvergeThrottle.WithLabelValues(uuid, "migration_out")); got != 20 {
+		t.Errorf("Expected throttle of 20%%, got %v", got)
+	}
+
+	deleteJobMetrics(uuid)
+	if count := testutil.CollectAndCount(jobDataProcessed); count != 0 {
+		t.Errorf("Expected no job metrics after the job, got %d", count)
+	}
+}
@@ -21,21 +21,28 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"os"
+	"maps"
 	"reflect"
+	"slices"
 	"sync"
 	"time"
 
 	v1 "github.com/cobaltcore-dev/openstack-hypervisor-operator/api/v1"
 	"github.com/digitalocean/go-libvirt"
 	"github.com/digitalocean/go-libvirt/socket/dialers"
+	"go.opentelemetry.io/otel/attribute"
 	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/events"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	logger "sigs.k8s.io/controller-runtime/pkg/log"
 
+	"github.com/cobaltcore-dev/kvm-node-agent/api/v1alpha1"
 	"github.com/cobaltcore-dev/kvm-node-agent/internal/libvirt/capabilities"
 	"github.com/cobaltcore-dev/kvm-node-agent/internal/libvirt/domcapabilities"
 	"github.com/cobaltcore-dev/kvm-node-agent/internal/libvirt/dominfo"
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/logging"
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/tracing"
 )
 
 const supportedYes = "yes"
@@ -65,21 +72,55 @@ type LibVirt struct {
 	// Client that connects to libvirt and fetches domain information.
 	// The domain information client abstracts the xml parsing away.
 	domainInfoClient dominfo.Client
+
+	// Time the domains spent paused, from the lifecycle events.
+	pauses pauseTracker
+	// Expirations of the watchdogs of the domains, from the watchdog events.
+	watchdogs watchdogTracker
+	// Block jobs running on the disks of the domains.
+	blockJobs blockJobTracker
+	// Io errors of the disks of the domains, from the io error events.
+	ioErrors ioErrorTracker
+	// Guest agent state changes of the domains, from the agent lifecycle
+	// events.
+	guestAgents guestAgentTracker
+
+	// Uri of the libvirt driver connected to.
+	uri string
+
+	// Stats of the active domains, shared by the block device metrics and
+	// the dirty rates.
+	stats domainStatsCache
+	// Domain capabilities, which only change on upgrades.
+	domCaps domainCapabilitiesCache
+	// Health of the connection from the last probes.
+	health connectionHealthTracker
+	// Background loops of the connection.
+	loops loopGroup
+	// Overcommit ratios applied to resources without an overcommit in the
+	// spec of the hypervisor.
+	defaultOvercommit map[v1.ResourceName]float64
+	// Restarts of crashed domains.
+	crashes crashTracker
+	// Recorder of the events of the instances, nil if none are emitted.
+	recorder events.EventRecorder
+	// Domains the instance metrics and the drift metric were exported for.
+	instanceSeries domainSeries
+	driftSeries    domainSeries
+	// Leftovers of crashed domains found on the previous pass.
+	leftovers leftoverTracker
 }
 
+// Create a libvirt client connecting to DefaultURI.
 func NewLibVirt(k client.Client) *LibVirt {
-	socketPath := os.Getenv("LIBVIRT_SOCKET")
-	if socketPath == "" {
-		socketPath = "/run/libvirt/libvirt-sock"
-	}
-	logger.Log.Info("Using libvirt unix domain socket", "socket", socketPath)
+	return NewLibVirtForURI(k, DefaultURI())
+}
+
+// Create a libvirt client connecting to the given uri, e.g. qemu:///system.
+func NewLibVirtForURI(k client.Client, uri string) *LibVirt {
+	logger.Log.WithName(logging.Libvirt).Info("Using libvirt unix domain socket", "socket", socketPath)
 	return &LibVirt{
-		libvirt.NewWithDialer(
-			dialers.NewLocal(
-				dialers.WithSocket(socketPath),
-				dialers.WithLocalTimeout(15*time.Second),
-			),
-		),
+		newLocalConnection(),
 		k,
 		make(map[string]context.CancelFunc),
 		sync.Mutex{},
@@ -92,9 +133,35 @@ func NewLibVirt(k client.Client) *LibVirt {
 		capabilities.NewClient(),
 		domcapabilities.NewClient(),
 		dominfo.NewClient(),
+		pauseTracker{},
+		watchdogTracker{},
+		blockJobTracker{},
+		ioErrorTracker{},
+		guestAgentTracker{},
+		uri,
+		domainStatsCache{},
+		domainCapabilitiesCache{},
+		connectionHealthTracker{},
+		loopGroup{},
+		nil,
+		crashTracker{},
+		nil,
+		domainSeries{},
+		domainSeries{},
+		leftoverTracker{},
 	}
 }
 
+// Create a client for the local libvirt socket, which is not connected yet.
+func newLocalConnection() *libvirt.Libvirt {
+	return libvirt.NewWithDialer(
+		dialers.NewLocal(
+			dialers.WithSocket(socketPath),
+			dialers.WithLocalTimeout(15*time.Second),
+		),
+	)
+}
+
 // formatLibvirtVersion converts a libvirt version integer to a semver string.
 // Libvirt versions are encoded as major*1000000 + minor*1000 + release.
 // For example, version 8001002 becomes "8.1.2".
@@ -109,25 +176,21 @@ func (l *LibVirt) Connect() error {
 		return nil
 	}
 
-	var libVirtUri = libvirt.ConnectURI("ch:///system")
-	if uri, present := os.LookupEnv("LIBVIRT_DEFAULT_URI"); present {
-		libVirtUri = libvirt.ConnectURI(uri)
-	}
-	err := l.virt.ConnectToURI(libVirtUri)
+	err := l.virt.ConnectToURI(libvirt.ConnectURI(l.uri))
 	if err != nil {
 		return err
 	}
 
 	// Update the libvirt library version
 	if version, err := l.virt.ConnectGetLibVersion(); err != nil {
-		logger.Log.Error(err, "unable to fetch libvirt version")
+		logger.Log.WithName(logging.Libvirt).Error(err, "unable to fetch libvirt version")
 	} else {
 		l.version = formatLibvirtVersion(version)
 	}
 
 	// Update the hypervisor version
 	if hvVersion, err := l.virt.ConnectGetVersion(); err != nil {
-		logger.Log.Error(err, "unable to fetch hypervisor version")
+		logger.Log.WithName(logging.Libvirt).Error(err, "unable to fetch hypervisor version")
 	} else {
 		l.hypervisorVersion = formatLibvirtVersion(hvVersion)
 	}
@@ -147,13 +210,60 @@ func (l *LibVirt) Connect() error {
 		"job-completed-handler",
 		l.onJobCompleted,
 	)
+	l.WatchDomainChanges(
+		libvirt.DomainEventIDWatchdog,
+		"watchdog-handler",
+		l.onWatchdog,
+	)
+	l.WatchDomainChanges(
+		libvirt.DomainEventIDBlockJob,
+		"block-job-handler",
+		l.onBlockJob,
+	)
+	l.WatchDomainChanges(
+		libvirt.DomainEventIDIoErrorReason,
+		"io-error-handler",
+		l.onIOError,
+	)
+	l.WatchDomainChanges(
+		libvirt.DomainEventIDAgentLifecycle,
+		"agent-lifecycle-handler",
+		l.onAgentLifecycle,
+	)
 
 	// Start the event loop
-	go l.runEventLoop(context.Background(), l.virt)
+	l.loops.Go("event-loop", func(ctx context.Context) {
+		l.runEventLoop(ctx, l.virt)
+	}, l.checkConnected)
+	// Start sampling block device stats of the running domains
+	l.loops.Go("block-stats", func(ctx context.Context) {
+		l.runBlockStatsLoop(ctx, l.virt)
+	}, l.checkConnected)
 
 	return nil
 }
 
+// Run the background loops of the connection until the manager stops.
+// Returns an error if a loop failed because the connection was closed,
+// the events are only subscribed again after a restart of the agent.
+func (l *LibVirt) Start(ctx context.Context) error {
+	return l.loops.Start(ctx)
+}
+
+// Check if the connection is still open, the background loops are only
+// restarted if it is.
+func (l *LibVirt) checkConnected() error {
+	if !l.virt.IsConnected() {
+		return errors.New("libvirt connection closed")
+	}
+	return nil
+}
+
+// Get the libvirt driver connected to, empty if the uri is unknown.
+func (l *LibVirt) driver() string {
+	return Driver(l.uri)
+}
+
 func (l *LibVirt) Close() error {
 	if err := l.virt.ConnectRegisterCloseCallback(); err != nil {
 		return err
@@ -169,6 +279,7 @@ type eventloopRunnable interface{ Disconnected() <-chan struct{} }
 // channels and distributes them to the subscribed listeners.
 func (l *LibVirt) runEventLoop(ctx context.Context, i eventloopRunnable) {
 	log := logger.FromContext(ctx, "libvirt", "event-loop")
+	pool := newEventPool(ctx, eventWorkers, eventQueueSize)
 	for {
 		// The reflect.Select function works the same way as a
 		// regular select statement, but allows selecting over
@@ -219,14 +330,17 @@ func (l *LibVirt) runEventLoop(ctx context.Context, i eventloopRunnable) {
 		// Distribute the event to all registered handlers.
 		eventId := eventIds[chosen] // safe as chosen < len(eventIds)
 		l.domEventChangeHandlersLock.Lock()
-		handlers, exists := l.domEventChangeHandlers[eventId]
+		handlers := slices.Collect(maps.Values(l.domEventChangeHandlers[eventId]))
 		l.domEventChangeHandlersLock.Unlock()
-		if !exists {
+		if len(handlers) == 0 {
 			continue
 		}
-		for _, handler := range handlers {
-			handler(ctx, value.Interface())
-		}
+		event := value.Interface()
+		pool.dispatch(ctx, eventDomain(event), func() {
+			for _, handler := range handlers {
+				handler(ctx, event)
+			}
+		})
 	}
 }
 
@@ -260,7 +374,7 @@ func (l *LibVirt) WatchDomainChanges(
 	}
 	ch, err := l.virt.SubscribeEvents(context.Background(), eventId, libvirt.OptDomain{})
 	if err != nil {
-		logger.Log.Error(err, "failed to subscribe to libvirt event", "eventId", eventId)
+		logger.Log.WithName(logging.Libvirt).Error(err, "failed to subscribe to libvirt event", "eventId", eventId)
 		return
 	}
 	l.domEventChs[eventId] = ch
@@ -269,19 +383,27 @@ func (l *LibVirt) WatchDomainChanges(
 // Add information extracted from the libvirt socket to the hypervisor instance.
 // If an error occurs, the instance is returned unmodified. The libvirt
 // connection needs to be established before calling this function.
-func (l *LibVirt) Process(hv v1.Hypervisor) (v1.Hypervisor, error) {
-	processors := []func(v1.Hypervisor) (v1.Hypervisor, error){
-		l.addVersion,
-		l.addInstancesInfo,
-		l.addCapabilities,
-		l.addDomainCapabilities,
-		l.addAllocationCapacity,
-		l.addEffectiveCapacity,
+func (l *LibVirt) Process(ctx context.Context, hv v1.Hypervisor) (v1.Hypervisor, error) {
+	processors := []struct {
+		name    string
+		process func(v1.Hypervisor) (v1.Hypervisor, error)
+	}{
+		{"addVersion", l.addVersion},
+		{"addInstancesInfo", l.addInstancesInfo},
+		{"addCapabilities", l.addCapabilities},
+		{"addDomainCapabilities", l.addDomainCapabilities},
+		{"addAllocationCapacity", l.addAllocationCapacity},
+		{"addEffectiveCapacity", l.addEffectiveCapacity},
 	}
 	var err error
 	for _, processor := range processors {
-		if hv, err = processor(hv); err != nil {
-			logger.Log.Error(err, "failed to process hypervisor", "step", processor)
+		// Each step is traced separately, the libvirt calls make up most
+		// of the time of a reconcile.
+		_, span := tracing.Start(ctx, "libvirt."+processor.name, attribute.String("libvirt.uri", l.uri))
+		hv, err = processor.process(hv)
+		tracing.End(span, err)
+		if err != nil {
+			logger.Log.WithName(logging.Libvirt).Error(err, "failed to process hypervisor", "step", processor.name)
 			return hv, err
 		}
 	}
@@ -298,9 +420,26 @@ func (l *LibVirt) addVersion(old v1.Hypervisor) (v1.Hypervisor, error) {
 
 // Add the domains to the hypervisor instance, i.e. how many
 // instances are running and how many are inactive.
+//
+// Note: v1.Instance is owned by the hypervisor operator api and only
+// carries the id, name and state. The details of each domain (flavor,
+// owner, pinning and devices) are mirrored into a v1alpha1.Instance per
+// domain instead, which keeps the hypervisor object small on hosts with
+// many domains and allows to watch single domains.
 func (l *LibVirt) addInstancesInfo(old v1.Hypervisor) (v1.Hypervisor, error) {
 	newHv := *old.DeepCopy()
 	var instances []v1.Instance
+	statuses := make(map[string]v1alpha1.InstanceStatus)
+
+	// The numa nodes of the pinned cpus are best effort as well.
+	var cellsByCPU map[int]uint64
+	if l.capabilitiesClient != nil {
+		if cells, err := l.HostCPUs(); err != nil {
+			logger.Log.WithName(logging.Libvirt).Error(err, "failed to get host cpu topology")
+		} else {
+			cellsByCPU = invertCells(cells)
+		}
+	}
 
 	flags := []libvirt.ConnectListAllDomainsFlags{
 		libvirt.ConnectListDomainsActive,
@@ -312,12 +451,61 @@ func (l *LibVirt) addInstancesInfo(old v1.Hypervisor) (v1.Hypervisor, error) {
 		if err != nil {
 			return old, err
 		}
+		var dirtyRates map[string]float64
+		if flag == libvirt.ConnectListDomainsActive {
+			dirtyRates = l.dirtyRates(domains)
+		}
 		for _, domain := range domains {
 			instances = append(instances, v1.Instance{
 				ID:     domain.UUID,
 				Name:   domain.Name,
 				Active: flag == libvirt.ConnectListDomainsActive,
 			})
+			status := instanceStatus(domain, flag == libvirt.ConnectListDomainsActive)
+			status.Driver = l.driver()
+			status.Migration = instanceMigration(domain, dirtyRates[domain.UUID])
+			status.Pauses = l.pauses.status(domain.UUID)
+			status.Watchdog = l.watchdogs.status(domain.UUID)
+			status.BlockJobs = l.blockJobs.status(domain.UUID)
+			if status.GuestAgent != nil && status.GuestAgent.Connected {
+				l.refreshGuestInfo(domain.UUID)
+			}
+			status.GuestAgent = l.guestAgents.status(domain.UUID, status.GuestAgent)
+			if flag != libvirt.ConnectListDomainsActive {
+				status.ManagedSave = l.hasManagedSave(domain.UUID)
+			}
+			tpm, err := instanceTPM(domain, flag == libvirt.ConnectListDomainsActive)
+			if err != nil {
+				logger.Log.WithName(logging.Libvirt).Error(err, "failed to get tpm state")
+			}
+			status.TPM = tpm
+			if blocker := tpmMigrationBlocker(tpm); blocker != nil {
+				status.Migration.Blockers = append(status.Migration.Blockers, *blocker)
+				status.Migration.LiveMigratable = false
+			}
+			if condition := l.ioErrors.condition(domain.UUID, time.Now()); condition != nil {
+				status.Conditions = []metav1.Condition{*condition}
+			}
+			addPinnedNUMANodes(&status.Pinning, cellsByCPU)
+			statuses[domain.UUID] = status
+		}
+	}
+
+	uuids := make([]string, 0, len(statuses))
+	for uuid := range statuses {
+		uuids = append(uuids, uuid)
+	}
+	l.instanceSeries.replace(uuids,
+		interfaceQueues, interfaceQueueMismatch, rngDevices, tpmStateBytes, swtpmUp, vcpuNUMANodes)
+	updateInterfaceQueueMetrics(statuses)
+	updateRNGMetrics(statuses)
+	updateTPMMetrics(statuses)
+	updateVCPUNUMAMetrics(statuses)
+	if l.client != nil {
+		if err := l.syncInstances(context.Background(), old, statuses); err != nil {
+			// The instance details are best effort, don't fail the
+			// hypervisor status because of them.
+			logger.Log.WithName(logging.Libvirt).Error(err, "failed to sync instances")
 		}
 	}
 
@@ -339,27 +527,39 @@ func (l *LibVirt) addCapabilities(old v1.Hypervisor) (v1.Hypervisor, error) {
 	totalMemory := resource.NewQuantity(0, resource.BinarySI)
 	totalCpus := resource.NewQuantity(0, resource.DecimalSI)
 	for _, cell := range caps.Host.Topology.CellSpec.Cells {
-		mem, err := MemoryToResource(cell.Memory.Value, cell.Memory.Unit)
+		mem, cpu, err := cellCapacity(cell)
 		if err != nil {
 			return old, err
 		}
 		totalMemory.Add(mem)
-		cpu := resource.NewQuantity(cell.CPUs.Num, resource.DecimalSI)
-		if cpu == nil {
-			return old, fmt.Errorf("invalid CPU count for cell %d", cell.ID)
-		}
-		totalCpus.Add(*cpu)
+		totalCpus.Add(cpu)
+	}
+	if len(caps.Host.Topology.CellSpec.Cells) == 0 && caps.Host.CPU.Topology != nil {
+		// Without numa topology only the cpus are known.
+		totalCpus = resource.NewQuantity(caps.Host.CPU.Topology.CPUs(), resource.DecimalSI)
 	}
 	newHv.Status.Capabilities.HostMemory = *totalMemory
 	newHv.Status.Capabilities.HostCpus = *totalCpus
 	return newHv, nil
 }
 
+// Get the memory and the online cpus of a numa cell. Cells without memory,
+// e.g. the cpu-less cells of gpus on ppc64le, don't report a memory unit.
+func cellCapacity(cell capabilities.CapabilitiesHostTopologyCell) (memory, cpus resource.Quantity, err error) {
+	memory = *resource.NewQuantity(0, resource.BinarySI)
+	if cell.Memory.Unit != "" || cell.Memory.Value != 0 {
+		if memory, err = MemoryToResource(cell.Memory.Value, cell.Memory.Unit); err != nil {
+			return memory, cpus, fmt.Errorf("invalid memory of cell %d: %w", cell.ID, err)
+		}
+	}
+	return memory, *resource.NewQuantity(cell.OnlineCPUs(), resource.DecimalSI), nil
+}
+
 // Call the libvirt domcapabilities api and add the resulting information
 // to the hypervisor domain capabilities status.
 func (l *LibVirt) addDomainCapabilities(old v1.Hypervisor) (v1.Hypervisor, error) {
 	newHv := *old.DeepCopy()
-	domCapabilities, err := l.domainCapabilitiesClient.Get(l.virt)
+	domCapabilities, err := l.domainCapabilities()
 	if err != nil {
 		return old, err
 	}
@@ -415,25 +615,65 @@ func (l *LibVirt) addDomainCapabilities(old v1.Hypervisor) (v1.Hypervisor, error
 		}
 	}
 
-	// Convert the supported features into a flat list.
+	// Convert the supported features into a flat list like the devices,
+	// e.g. <gic supported="yes"><enum name="version"><value>3</value></enum>
+	// </gic> on aarch64 becomes "gic" and "gic/3". SEV-ES has no feature of
+	// its own, "sev-es" is appended if it is available.
 	newHv.Status.DomainCapabilities.SupportedFeatures = []string{}
 	for _, feature := range domCapabilities.Features.Features {
-		if feature.Supported == supportedYes {
-			newHv.Status.DomainCapabilities.SupportedFeatures = append(
-				newHv.Status.DomainCapabilities.SupportedFeatures,
-				feature.XMLName.Local,
-			)
+		if feature.Supported != supportedYes {
+			continue
+		}
+		newHv.Status.DomainCapabilities.SupportedFeatures = append(
+			newHv.Status.DomainCapabilities.SupportedFeatures,
+			feature.XMLName.Local,
+		)
+		for _, enum := range feature.Enums {
+			for _, value := range enum.Values {
+				newHv.Status.DomainCapabilities.SupportedFeatures = append(
+					newHv.Status.DomainCapabilities.SupportedFeatures,
+					fmt.Sprintf("%s/%s", feature.XMLName.Local, value),
+				)
+			}
 		}
 	}
+	if confidentialComputing(domCapabilities).SEVES {
+		newHv.Status.DomainCapabilities.SupportedFeatures = append(
+			newHv.Status.DomainCapabilities.SupportedFeatures,
+			"sev-es",
+		)
+	}
+
+	// Add the firmware types as "firmware/efi" and "secure-boot" if a
+	// loader supports it, to check that images will boot on this host.
+	firmware := firmwareOf(domCapabilities)
+	for _, firmwareType := range firmware.Types {
+		newHv.Status.DomainCapabilities.SupportedFeatures = append(
+			newHv.Status.DomainCapabilities.SupportedFeatures,
+			"firmware/"+firmwareType,
+		)
+	}
+	if firmware.SecureBoot {
+		newHv.Status.DomainCapabilities.SupportedFeatures = append(
+			newHv.Status.DomainCapabilities.SupportedFeatures,
+			"secure-boot",
+		)
+	}
 
 	return newHv, nil
 }
 
 // Add total allocation, total capacity, and numa cell information
 // to the hypervisor instance, by combining domain infos and hypervisor
-// capabilities in libvirt.
+// capabilities in libvirt. The resources reserved for the host are
+// subtracted from the total capacity, the capacity of the cells is left
+// as is.
 func (l *LibVirt) addAllocationCapacity(old v1.Hypervisor) (v1.Hypervisor, error) {
 	newHv := *old.DeepCopy()
+	reserved, err := reservedResources(old.Annotations)
+	if err != nil {
+		return old, err
+	}
 
 	// First get all the numa cells from the capabilities
 	caps, err := l.capabilitiesClient.Get(l.virt)
@@ -444,19 +684,11 @@ func (l *LibVirt) addAllocationCapacity(old v1.Hypervisor) (v1.Hypervisor, error
 	totalCpuCapacity := resource.NewQuantity(0, resource.DecimalSI)
 	cellsById := make(map[uint64]v1.Cell)
 	for _, cell := range caps.Host.Topology.CellSpec.Cells {
-		memoryCapacity, err := MemoryToResource(
-			cell.Memory.Value,
-			cell.Memory.Unit,
-		)
+		memoryCapacity, cpuCapacity, err := cellCapacity(cell)
 		if err != nil {
 			return old, err
 		}
 		totalMemoryCapacity.Add(memoryCapacity)
-
-		cpuCapacity := *resource.NewQuantity(
-			cell.CPUs.Num,
-			resource.DecimalSI,
-		)
 		totalCpuCapacity.Add(cpuCapacity)
 
 		cellsById[cell.ID] = v1.Cell{
@@ -550,6 +782,7 @@ func (l *LibVirt) addAllocationCapacity(old v1.Hypervisor) (v1.Hypervisor, error
 	newHv.Status.Capacity = make(map[v1.ResourceName]resource.Quantity)
 	newHv.Status.Capacity[v1.ResourceMemory] = *totalMemoryCapacity
 	newHv.Status.Capacity[v1.ResourceCPU] = *totalCpuCapacity
+	subtractReserved(newHv.Status.Capacity, reserved)
 	newHv.Status.Allocation = make(map[v1.ResourceName]resource.Quantity)
 	newHv.Status.Allocation[v1.ResourceMemory] = *totalMemoryAlloc
 	newHv.Status.Allocation[v1.ResourceCPU] = *totalCpuAlloc
@@ -557,6 +790,24 @@ func (l *LibVirt) addAllocationCapacity(old v1.Hypervisor) (v1.Hypervisor, error
 	return newHv, nil
 }
 
+// Set the overcommit ratios applied to resources without an overcommit in
+// the spec of the hypervisor, like the allocation ratios of nova.
+func (l *LibVirt) SetDefaultOvercommit(overcommit map[v1.ResourceName]float64) {
+	l.defaultOvercommit = overcommit
+}
+
+// Get the overcommit ratio of the resource, from the spec of the hypervisor
+// or the default overcommit, and 1.0 if neither sets one.
+func (l *LibVirt) overcommit(hv v1.Hypervisor, resourceName v1.ResourceName) float64 {
+	if overcommit, ok := hv.Spec.Overcommit[resourceName]; ok {
+		return overcommit
+	}
+	if overcommit, ok := l.defaultOvercommit[resourceName]; ok {
+		return overcommit
+	}
+	return 1.0
+}
+
 // Add the effective capacity to the hypervisor instance.
 //
 // The effective capacity is calculated as the physical capacity times the
@@ -567,10 +818,7 @@ func (l *LibVirt) addEffectiveCapacity(old v1.Hypervisor) (v1.Hypervisor, error)
 	// Always recreate the EffectiveCapacity map to remove stale entries
 	newHv.Status.EffectiveCapacity = make(map[v1.ResourceName]resource.Quantity)
 	for resourceName, capacity := range newHv.Status.Capacity {
-		overcommit, ok := newHv.Spec.Overcommit[resourceName]
-		if !ok {
-			overcommit = 1.0
-		}
+		overcommit := l.overcommit(newHv, resourceName)
 		flooredValue := int64(float64(capacity.Value()) * overcommit)
 		effectiveCapacity := resource.NewQuantity(flooredValue, capacity.Format)
 		newHv.Status.EffectiveCapacity[resourceName] = *effectiveCapacity
@@ -580,10 +828,7 @@ func (l *LibVirt) addEffectiveCapacity(old v1.Hypervisor) (v1.Hypervisor, error)
 		// Always recreate the cell's EffectiveCapacity map to remove stale entries
 		cell.EffectiveCapacity = make(map[v1.ResourceName]resource.Quantity)
 		for resourceName, capacity := range cell.Capacity {
-			overcommit, ok := newHv.Spec.Overcommit[resourceName]
-			if !ok {
-				overcommit = 1.0
-			}
+			overcommit := l.overcommit(newHv, resourceName)
 			flooredValue := int64(float64(capacity.Value()) * overcommit)
 			effectiveCapacity := resource.NewQuantity(flooredValue, capacity.Format)
 			cell.EffectiveCapacity[resourceName] = *effectiveCapacity
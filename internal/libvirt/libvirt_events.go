@@ -25,12 +25,17 @@ import (
 	"time"
 
 	"github.com/digitalocean/go-libvirt"
+	"go.opentelemetry.io/otel/attribute"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	logger "sigs.k8s.io/controller-runtime/pkg/log"
 
 	"github.com/cobaltcore-dev/kvm-node-agent/api/v1alpha1"
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/libvirt/virterr"
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/logging"
 	"github.com/cobaltcore-dev/kvm-node-agent/internal/sys"
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/tracing"
 )
 
 const (
@@ -56,14 +61,12 @@ const (
 	VIR_DOMAIN_JOB_OPERATION_SNAPSHOT_DELETE        /* (Since: 9.0.0) */
 )
 
-var errDomainNotFoud = errors.New("domain not found")
-
 func GetOpenstackUUID(domain libvirt.Domain) string {
 	return UUID(domain.UUID).String()
 }
 
 func (l *LibVirt) onMigrationIteration(ctx context.Context, event any) {
-	log := logger.FromContext(ctx).WithName("libvirt-migration-listener")
+	log := logger.FromContext(ctx).WithName(logging.Migration)
 	e := event.(*libvirt.DomainEventCallbackMigrationIterationMsg)
 	domain := e.Dom
 	uuid := GetOpenstackUUID(domain)
@@ -77,17 +80,18 @@ func (l *LibVirt) onMigrationIteration(ctx context.Context, event any) {
 }
 
 func (l *LibVirt) onJobCompleted(ctx context.Context, event any) {
-	log := logger.FromContext(ctx).WithName("libvirt-migration-listener")
+	log := logger.FromContext(ctx).WithName(logging.Migration)
 	e := event.(*libvirt.DomainEventCallbackJobCompletedMsg)
 	uuid := GetOpenstackUUID(e.Dom)
 	log.Info("job completed", "server", uuid, "params", e.Params)
 }
 
 func (l *LibVirt) onLifecycleEvent(ctx context.Context, event any) {
-	log := logger.FromContext(ctx).WithName("libvirt-migration-listener")
+	log := logger.FromContext(ctx).WithName(logging.Migration)
 	e := event.(*libvirt.DomainEventCallbackLifecycleMsg)
 	domain := e.Msg.Dom
 	serverLog := log.WithValues("server", GetOpenstackUUID(domain))
+	l.emitLifecycleEvent(ctx, domain, e.Msg.Event, e.Msg.Detail)
 
 	switch e.Msg.Event {
 	case int32(libvirt.DomainEventDefined):
@@ -103,42 +107,61 @@ func (l *LibVirt) onLifecycleEvent(ctx context.Context, event any) {
 		}
 	case int32(libvirt.DomainEventUndefined):
 		serverLog.Info("domain undefined")
+		l.pauses.forget(GetOpenstackUUID(domain))
+		l.crashes.forget(GetOpenstackUUID(domain))
+		l.watchdogs.forget(GetOpenstackUUID(domain))
+		l.guestAgents.forget(GetOpenstackUUID(domain))
+		l.ioErrors.clear(GetOpenstackUUID(domain))
+		forgetIOErrorMetrics(GetOpenstackUUID(domain))
 	case int32(libvirt.DomainEventStarted):
 		switch e.Msg.Detail {
 		case int32(libvirt.DomainEventStartedBooted):
 			serverLog.Info("domain booted")
 		case int32(libvirt.DomainEventStartedMigrated):
 			serverLog.Info("incoming migration started")
+			if err := l.startIncomingMigration(ctx, domain); err != nil {
+				serverLog.Error(err, "failed to register incoming migration")
+			}
 		case int32(libvirt.DomainEventStartedRestored):
 			serverLog.Info("domain restored")
 		case int32(libvirt.DomainEventStartedFromSnapshot):
 			serverLog.Info("domain started from snapshot")
 		case int32(libvirt.DomainEventStartedWakeup):
 			serverLog.Info("domain woken up")
+			l.pauses.resumed(GetOpenstackUUID(domain), time.Now())
 		}
 	case int32(libvirt.DomainEventSuspended):
-		serverLog.Info("domain suspended")
+		reason := pauseReason(e.Msg.Detail)
+		serverLog.Info("domain suspended", "reason", reason)
+		l.pauses.paused(GetOpenstackUUID(domain), reason, time.Now())
 	case int32(libvirt.DomainEventResumed):
 		serverLog.Info("domain resumed")
+		l.pauses.resumed(GetOpenstackUUID(domain), time.Now())
+		l.ioErrors.clear(GetOpenstackUUID(domain))
 		// incoming migration completed, finalize migration status
 		if err := l.patchMigration(ctx, domain, true); client.IgnoreNotFound(err) != nil {
 			serverLog.Error(err, "failed to update migration status")
 		}
 	case int32(libvirt.DomainEventStopped):
 		serverLog.Info("domain stopped")
+		l.onDomainStopped(ctx, domain, e.Msg.Detail)
+		l.ioErrors.clear(GetOpenstackUUID(domain))
+		l.guestAgents.forget(GetOpenstackUUID(domain))
+		l.pauses.resumed(GetOpenstackUUID(domain), time.Now())
 		l.stopMigrationWatch(ctx, domain)
 	case int32(libvirt.DomainEventShutdown):
 		serverLog.Info("domain shutdown")
 		l.stopMigrationWatch(ctx, domain)
 	case int32(libvirt.DomainEventPmsuspended):
 		serverLog.Info("domain PM suspended")
+		l.pauses.paused(GetOpenstackUUID(domain), pauseReasonPMSuspended, time.Now())
 	case int32(libvirt.DomainEventCrashed):
-		serverLog.Info("domain crashed")
+		l.onDomainCrashed(ctx, domain, e.Msg.Detail)
 	}
 }
 
 func (l *LibVirt) startMigrationWatch(ctx context.Context, domain libvirt.Domain) error {
-	log := logger.FromContext(ctx, "server", GetOpenstackUUID(domain))
+	log := logger.FromContext(ctx, "server", GetOpenstackUUID(domain)).WithName(logging.Migration)
 
 	// ensure migration object exists
 	migr := v1alpha1.Migration{
@@ -173,27 +196,91 @@ func (l *LibVirt) startMigrationWatch(ctx context.Context, domain libvirt.Domain
 	}
 	patched := original.DeepCopy()
 	patched.Status.Started = metav1.Now()
-	patched.Status.Origin = sys.NodeLabelName
+	setMigrationEndpoint(patched, v1alpha1.MigrationDirectionOutgoing)
 	if err := l.client.Status().Patch(ctx, patched, client.MergeFrom(&original)); err != nil {
 		return fmt.Errorf("failed to patch migration status time: %w", err)
 	}
 
 	// start migration watch
-	timeoutCtx, cancel := context.WithTimeout(context.Background(), 60*time.Minute)
+	timeoutCtx, cancel := context.WithTimeout(l.loops.context(), 60*time.Minute)
 	l.migrationJobs[domain.Name] = cancel
-	go l.watchMigrationLoop(timeoutCtx, cancel, domain)
+	l.loops.Go("migration-watch", func(context.Context) {
+		l.watchMigrationLoop(timeoutCtx, cancel, domain)
+	}, nil)
+	return nil
+}
+
+// Register this host as the destination of an incoming migration. Only the
+// start time and the own endpoint are written, the origin and the endpoint
+// of the source may already be reported and are kept.
+func (l *LibVirt) startIncomingMigration(ctx context.Context, domain libvirt.Domain) error {
+	migr := v1alpha1.Migration{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      GetOpenstackUUID(domain),
+			Namespace: sys.Namespace,
+		},
+	}
+	if err := l.client.Create(ctx, &migr); client.IgnoreAlreadyExists(err) != nil {
+		return fmt.Errorf("failed to create migration object: %w", err)
+	}
+
+	object := client.ObjectKey{
+		Name:      GetOpenstackUUID(domain),
+		Namespace: sys.Namespace,
+	}
+	var original v1alpha1.Migration
+	if err := l.client.Get(ctx, object, &original); err != nil {
+		return fmt.Errorf("failed to get migration status: %w", err)
+	}
+	patched := original.DeepCopy()
+	patched.Status.Started = metav1.Now()
+	setMigrationEndpoint(patched, v1alpha1.MigrationDirectionIncoming)
+	if err := l.client.Status().Patch(ctx, patched, client.MergeFrom(&original)); err != nil {
+		return fmt.Errorf("failed to patch migration endpoint: %w", err)
+	}
 	return nil
 }
 
+// Record this host as one end of the migration. The source sets the origin
+// and the destination sets the destination host, so that neither host
+// overwrites what the other end reported.
+func setMigrationEndpoint(migration *v1alpha1.Migration, direction string) {
+	var peer string
+	switch direction {
+	case v1alpha1.MigrationDirectionOutgoing:
+		migration.Status.Origin = sys.NodeLabelName
+		peer = migration.Status.Destination
+	case v1alpha1.MigrationDirectionIncoming:
+		migration.Status.Destination = sys.NodeLabelName
+		peer = migration.Status.Origin
+	}
+	if migration.Status.Endpoints == nil {
+		migration.Status.Endpoints = make(map[string]v1alpha1.MigrationEndpoint)
+	}
+	migration.Status.Endpoints[sys.NodeLabelName] = v1alpha1.MigrationEndpoint{
+		Direction: direction,
+		Peer:      peer,
+	}
+}
+
 func (l *LibVirt) stopMigrationWatch(ctx context.Context, domain libvirt.Domain) {
+	l.migrationLock.Lock()
+	defer l.migrationLock.Unlock()
+
 	if cancel, ok := l.migrationJobs[domain.Name]; ok {
-		logger.FromContext(ctx).Info("stopping migration watch", "server", GetOpenstackUUID(domain))
+		logger.FromContext(ctx).WithName(logging.Migration).Info("stopping migration watch", "server", GetOpenstackUUID(domain))
 		cancel()
 		delete(l.migrationJobs, domain.Name)
+		deleteJobMetrics(GetOpenstackUUID(domain))
 	}
 }
 
-func (l *LibVirt) patchMigration(ctx context.Context, domain libvirt.Domain, completed bool) error {
+func (l *LibVirt) patchMigration(ctx context.Context, domain libvirt.Domain, completed bool) (err error) {
+	ctx, span := tracing.Start(ctx, "LibVirt.patchMigration",
+		attribute.String("domain", GetOpenstackUUID(domain)), attribute.Bool("completed", completed))
+	defer func() { tracing.End(span, err) }()
+	log := logger.FromContext(ctx, "server", GetOpenstackUUID(domain)).WithName(logging.Migration)
+
 	object := client.ObjectKey{
 		Name:      GetOpenstackUUID(domain),
 		Namespace: sys.Namespace,
@@ -205,16 +292,22 @@ func (l *LibVirt) patchMigration(ctx context.Context, domain libvirt.Domain, com
 	}
 
 	migration := original.DeepCopy()
-	if err := l.populateDomainJobInfo(domain, migration, completed); err != nil {
+	_, rpcSpan := tracing.Start(ctx, "libvirt.DomainGetJobStats")
+	err = l.populateDomainJobInfo(domain, migration, completed)
+	tracing.End(rpcSpan, err)
+	if err != nil {
 		// ignore domain not running error due to race condition with cancel job
-		if strings.HasSuffix(err.Error(), "domain is not running") {
+		if errors.Is(err, virterr.ErrNotRunning) {
 			return nil
 		}
 
-		// quirk if the domain job details have been reaped, set migration type to completed
-		if completed && errors.Is(err, errDomainNotFoud) {
-			logger.FromContext(ctx).Info("migration job details reaped, setting migration status to completed")
-			migration.Status.Type = "completed"
+		// quirk if the domain job details have been reaped, set migration phase to completed
+		if completed && errors.Is(err, virterr.ErrDomainNotFound) {
+			log.Info("migration job details reaped, setting migration status to completed")
+			setMigrationPhase(migration, v1alpha1.MigrationPhaseCompleted)
+			if migration.Status.Origin != sys.NodeLabelName {
+				setMigrationEndpoint(migration, v1alpha1.MigrationDirectionIncoming)
+			}
 		}
 	}
 
@@ -223,13 +316,25 @@ func (l *LibVirt) patchMigration(ctx context.Context, domain libvirt.Domain, com
 		return fmt.Errorf("failed to patch migration status: %w", err)
 	}
 
+	// remember that this host pair has proven migration compatibility
+	if completed && migrationSucceeded(migration) && !migrationSucceeded(&original) &&
+		migration.Status.Origin != "" && migration.Status.Origin != migration.Status.Destination {
+		if err := l.recordMigrationPair(
+			ctx, migration.Status.Origin, migration.Status.Destination, time.Now(),
+		); err != nil {
+			log.Error(err, "failed to record successful migration pair")
+		}
+	}
+
 	return nil
 }
 
 // watchMigrationLoop watches the migration progress of a domain on the source hypervisor
 func (l *LibVirt) watchMigrationLoop(ctx context.Context, cancel context.CancelFunc, domain libvirt.Domain) {
 	defer cancel()
-	log := logger.FromContext(ctx, "server", GetOpenstackUUID(domain))
+	log := logger.FromContext(ctx, "server", GetOpenstackUUID(domain)).WithName(logging.Migration)
+	ctx, span := tracing.Start(ctx, "LibVirt.watchMigration", attribute.String("domain", GetOpenstackUUID(domain)))
+	defer span.End()
 
 	// Watch migration progress in a loop
 	for {
@@ -244,7 +349,7 @@ func (l *LibVirt) watchMigrationLoop(ctx context.Context, cancel context.CancelF
 
 			// Patch migration status
 			if err := l.patchMigration(ctx, domain, false); err != nil {
-				if errors.Is(err, errDomainNotFoud) {
+				if errors.Is(err, virterr.ErrDomainNotFound) {
 					// quirk if the domain job details have been reaped, stop migration watch
 					// could happen if the migration fails
 					log.Info("migration job details reaped, stopping migration watch")
@@ -266,25 +371,23 @@ func (l *LibVirt) populateDomainJobInfo(domain libvirt.Domain, migration *v1alph
 		flags = libvirt.DomainJobStatsCompleted
 	}
 
-	migration.Status.Destination = sys.NodeLabelName
 	rType, params, err := l.virt.DomainGetJobStats(domain, flags)
 	if err != nil {
-		return err
+		return l.classifyDomainError(domain, err)
 	}
 
+	// Bounded and unbounded jobs are still running, their phase is
+	// derived from the memory transfer progress below.
+	var phase v1alpha1.MigrationPhase
 	switch rType {
 	case VIR_DOMAIN_JOB_NONE:
-		return errDomainNotFoud
-	case VIR_DOMAIN_JOB_BOUNDED:
-		migration.Status.Type = "bounded"
-	case VIR_DOMAIN_JOB_UNBOUNDED:
-		migration.Status.Type = "unbounded"
+		return virterr.ErrDomainNotFound
 	case VIR_DOMAIN_JOB_COMPLETED:
-		migration.Status.Type = "completed"
+		phase = v1alpha1.MigrationPhaseCompleted
 	case VIR_DOMAIN_JOB_FAILED:
-		migration.Status.Type = "failed"
+		phase = v1alpha1.MigrationPhaseFailed
 	case VIR_DOMAIN_JOB_CANCELLED:
-		migration.Status.Type = "cancelled"
+		phase = v1alpha1.MigrationPhaseCancelled
 	}
 
 	for _, param := range params {
@@ -301,8 +404,10 @@ func (l *LibVirt) populateDomainJobInfo(domain libvirt.Domain, migration *v1alph
 				migration.Status.Operation = "restore"
 			case VIR_DOMAIN_JOB_OPERATION_MIGRATION_IN:
 				migration.Status.Operation = "migration_in"
+				setMigrationEndpoint(migration, v1alpha1.MigrationDirectionIncoming)
 			case VIR_DOMAIN_JOB_OPERATION_MIGRATION_OUT:
 				migration.Status.Operation = "migration_out"
+				setMigrationEndpoint(migration, v1alpha1.MigrationDirectionOutgoing)
 			case VIR_DOMAIN_JOB_OPERATION_SNAPSHOT:
 				migration.Status.Operation = "snapshot"
 			case VIR_DOMAIN_JOB_OPERATION_SNAPSHOT_REVERT:
@@ -361,10 +466,59 @@ func (l *LibVirt) populateDomainJobInfo(domain libvirt.Domain, migration *v1alph
 		case "auto_converge_throttle":
 			migration.Status.AutoConvergeThrottle = fmt.Sprintf("%d%%", param.Value.I.(uint64))
 		case "success":
-			migration.Status.Type = "success"
+			phase = v1alpha1.MigrationPhaseCompleted
 		case "errmsg":
 			migration.Status.ErrMsg = param.Value.I.(string)
 		}
 	}
+
+	if !completed {
+		updateJobMetrics(GetOpenstackUUID(domain), migration.Status.Operation, params)
+	}
+
+	if phase == "" {
+		switch {
+		case migration.Status.MemPostcopyRequests > 0:
+			phase = v1alpha1.MigrationPhasePostCopy
+		case migration.Status.MemIteration > 0:
+			phase = v1alpha1.MigrationPhasePreCopy
+		default:
+			phase = v1alpha1.MigrationPhasePreparing
+		}
+	}
+	setMigrationPhase(migration, phase)
 	return err
 }
+
+// Set the phase of the migration and update its conditions accordingly.
+// The transition time of a condition only changes when its status changes.
+func setMigrationPhase(migration *v1alpha1.Migration, phase v1alpha1.MigrationPhase) {
+	migration.Status.Phase = phase
+
+	completed := metav1.Condition{
+		Type:    v1alpha1.ConditionTypeMigrationCompleted,
+		Status:  metav1.ConditionFalse,
+		Reason:  string(phase),
+		Message: "Migration is in phase " + string(phase),
+	}
+	if phase == v1alpha1.MigrationPhaseCompleted {
+		completed.Status = metav1.ConditionTrue
+		completed.Message = "Migration completed successfully"
+	}
+	meta.SetStatusCondition(&migration.Status.Conditions, completed)
+
+	failed := metav1.Condition{
+		Type:    v1alpha1.ConditionTypeMigrationFailed,
+		Status:  metav1.ConditionFalse,
+		Reason:  string(phase),
+		Message: "Migration is in phase " + string(phase),
+	}
+	if phase == v1alpha1.MigrationPhaseFailed || phase == v1alpha1.MigrationPhaseCancelled {
+		failed.Status = metav1.ConditionTrue
+		failed.Message = "Migration " + strings.ToLower(string(phase))
+		if migration.Status.ErrMsg != "" {
+			failed.Message += ": " + migration.Status.ErrMsg
+		}
+	}
+	meta.SetStatusCondition(&migration.Status.Conditions, failed)
+}
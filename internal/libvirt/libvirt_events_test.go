@@ -0,0 +1,159 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package libvirt
+
+import (
+	"context"
+	"testing"
+
+	"github.com/digitalocean/go-libvirt"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/cobaltcore-dev/kvm-node-agent/api/v1alpha1"
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/sys"
+)
+
+func TestSetMigrationEndpoint_Outgoing(t *testing.T) {
+	migration := &v1alpha1.Migration{
+		Status: v1alpha1.MigrationStatus{Destination: "other-node"},
+	}
+	setMigrationEndpoint(migration, v1alpha1.MigrationDirectionOutgoing)
+
+	if migration.Status.Origin != sys.NodeLabelName {
+		t.Errorf("Expected origin %q, got %q", sys.NodeLabelName, migration.Status.Origin)
+	}
+	if migration.Status.Destination != "other-node" {
+		t.Errorf("Expected destination to be preserved, got %q", migration.Status.Destination)
+	}
+	endpoint := migration.Status.Endpoints[sys.NodeLabelName]
+	if endpoint.Direction != v1alpha1.MigrationDirectionOutgoing {
+		t.Errorf("Expected outgoing direction, got %q", endpoint.Direction)
+	}
+	if endpoint.Peer != "other-node" {
+		t.Errorf("Expected peer other-node, got %q", endpoint.Peer)
+	}
+}
+
+func TestSetMigrationEndpoint_IncomingKeepsOtherEnd(t *testing.T) {
+	migration := &v1alpha1.Migration{
+		Status: v1alpha1.MigrationStatus{
+			Origin: "other-node",
+			Endpoints: map[string]v1alpha1.MigrationEndpoint{
+				"other-node": {Direction: v1alpha1.MigrationDirectionOutgoing},
+			},
+		},
+	}
+	setMigrationEndpoint(migration, v1alpha1.MigrationDirectionIncoming)
+
+	if migration.Status.Origin != "other-node" {
+		t.Errorf("Expected origin to be preserved, got %q", migration.Status.Origin)
+	}
+	if migration.Status.Destination != sys.NodeLabelName {
+		t.Errorf("Expected destination %q, got %q", sys.NodeLabelName, migration.Status.Destination)
+	}
+	if len(migration.Status.Endpoints) != 2 {
+		t.Fatalf("Expected both endpoints, got %v", migration.Status.Endpoints)
+	}
+	endpoint := migration.Status.Endpoints[sys.NodeLabelName]
+	if endpoint.Direction != v1alpha1.MigrationDirectionIncoming || endpoint.Peer != "other-node" {
+		t.Errorf("Unexpected endpoint %+v", endpoint)
+	}
+}
+
+func TestSetMigrationPhase_Conditions(t *testing.T) {
+	migration := &v1alpha1.Migration{}
+
+	setMigrationPhase(migration, v1alpha1.MigrationPhasePreCopy)
+	if migration.Status.Phase != v1alpha1.MigrationPhasePreCopy {
+		t.Errorf("Expected phase PreCopy, got %q", migration.Status.Phase)
+	}
+	if meta.IsStatusConditionTrue(migration.Status.Conditions, v1alpha1.ConditionTypeMigrationCompleted) {
+		t.Error("Expected migration not to be completed while copying")
+	}
+	completed := meta.FindStatusCondition(migration.Status.Conditions, v1alpha1.ConditionTypeMigrationCompleted)
+	if completed == nil || completed.Reason != string(v1alpha1.MigrationPhasePreCopy) {
+		t.Errorf("Expected completed condition with reason PreCopy, got %+v", completed)
+	}
+
+	setMigrationPhase(migration, v1alpha1.MigrationPhaseCompleted)
+	if !meta.IsStatusConditionTrue(migration.Status.Conditions, v1alpha1.ConditionTypeMigrationCompleted) {
+		t.Error("Expected migration to be completed")
+	}
+	if meta.IsStatusConditionTrue(migration.Status.Conditions, v1alpha1.ConditionTypeMigrationFailed) {
+		t.Error("Expected migration not to be failed")
+	}
+}
+
+func TestSetMigrationPhase_FailedWithMessage(t *testing.T) {
+	migration := &v1alpha1.Migration{
+		Status: v1alpha1.MigrationStatus{ErrMsg: "connection reset"},
+	}
+	setMigrationPhase(migration, v1alpha1.MigrationPhaseFailed)
+
+	failed := meta.FindStatusCondition(migration.Status.Conditions, v1alpha1.ConditionTypeMigrationFailed)
+	if failed == nil || failed.Status != "True" {
+		t.Fatalf("Expected failed condition to be true, got %+v", failed)
+	}
+	if failed.Message != "Migration failed: connection reset" {
+		t.Errorf("Unexpected failed condition message %q", failed.Message)
+	}
+}
+
+func TestStartIncomingMigration_KeepsSourceStatus(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := v1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatalf("Failed to add scheme: %v", err)
+	}
+	domain := libvirt.Domain{Name: "instance-00000001", UUID: libvirt.UUID{1}}
+	existing := &v1alpha1.Migration{
+		ObjectMeta: metav1.ObjectMeta{Name: GetOpenstackUUID(domain), Namespace: sys.Namespace},
+		Status: v1alpha1.MigrationStatus{
+			Origin: "other-node",
+			Endpoints: map[string]v1alpha1.MigrationEndpoint{
+				"other-node": {Direction: v1alpha1.MigrationDirectionOutgoing},
+			},
+		},
+	}
+	l := &LibVirt{client: fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithStatusSubresource(&v1alpha1.Migration{}).
+		WithObjects(existing).
+		Build()}
+
+	if err := l.startIncomingMigration(context.Background(), domain); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	var migration v1alpha1.Migration
+	if err := l.client.Get(context.Background(), client.ObjectKeyFromObject(existing), &migration); err != nil {
+		t.Fatalf("Failed to get migration: %v", err)
+	}
+	if migration.Status.Origin != "other-node" {
+		t.Errorf("Expected origin to be preserved, got %q", migration.Status.Origin)
+	}
+	if len(migration.Status.Endpoints) != 2 {
+		t.Errorf("Expected both endpoints, got %v", migration.Status.Endpoints)
+	}
+	if migration.Status.Started.IsZero() {
+		t.Error("Expected the start time to be set")
+	}
+}
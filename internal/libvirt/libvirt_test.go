@@ -19,6 +19,8 @@ package libvirt
 
 import (
 	"context"
+	"encoding/xml"
+	"slices"
 	"testing"
 	"time"
 
@@ -338,6 +340,81 @@ func TestAddCapabilities_MultipleCells(t *testing.T) {
 	}
 }
 
+func TestAddCapabilities_NonX86Topologies(t *testing.T) {
+	// A ppc64le host with reduced smt, whose offline threads are listed
+	// without topology, and a cell of a gpu without cpus.
+	caps := capabilities.Capabilities{}
+	if err := xml.Unmarshal([]byte(`<capabilities><host>
+		<cpu><arch>ppc64le</arch><topology sockets='1' cores='2' threads='4'/></cpu>
+		<topology><cells num='2'>
+			<cell id='0'>
+				<memory unit='KiB'>1048576</memory>
+				<cpus num='8'>
+					<cpu id='0' socket_id='0' core_id='0' siblings='0'/>
+					<cpu id='1'/><cpu id='2'/><cpu id='3'/>
+					<cpu id='4' socket_id='0' core_id='4' siblings='4'/>
+					<cpu id='5'/><cpu id='6'/><cpu id='7'/>
+				</cpus>
+			</cell>
+			<cell id='255'>
+				<memory unit='KiB'>2097152</memory>
+				<cpus num='0'/>
+			</cell>
+		</cells></topology>
+	</host></capabilities>`), &caps); err != nil {
+		t.Fatal(err)
+	}
+	l := &LibVirt{capabilitiesClient: &mockCapabilitiesClient{caps: caps}}
+	result, err := l.addCapabilities(v1.Hypervisor{})
+	if err != nil {
+		t.Fatalf("addCapabilities() returned unexpected error: %v", err)
+	}
+	if result.Status.Capabilities.HostCpus.Value() != 2 {
+		t.Errorf("Expected 2 online cpus, got %s", result.Status.Capabilities.HostCpus.String())
+	}
+	if expected := resource.NewQuantity(3<<30, resource.BinarySI); !result.Status.Capabilities.HostMemory.Equal(*expected) {
+		t.Errorf("Expected HostMemory %s, got %s", expected, result.Status.Capabilities.HostMemory.String())
+	}
+
+	// A s390x host with books and drawers, and a cell without memory.
+	caps = capabilities.Capabilities{}
+	if err := xml.Unmarshal([]byte(`<capabilities><host>
+		<cpu><arch>s390x</arch></cpu>
+		<topology><cells num='1'>
+			<cell id='0'>
+				<cpus num='2'>
+					<cpu id='0' socket_id='0' book_id='1' drawer_id='2' core_id='0' siblings='0'/>
+					<cpu id='1' socket_id='0' book_id='1' drawer_id='2' core_id='1' siblings='1'/>
+				</cpus>
+			</cell>
+		</cells></topology>
+	</host></capabilities>`), &caps); err != nil {
+		t.Fatal(err)
+	}
+	if cpu := caps.Host.Topology.CellSpec.Cells[0].CPUs.CPUs[1]; cpu.BookID != 1 || cpu.DrawerID != 2 {
+		t.Errorf("Expected book 1 and drawer 2, got %+v", cpu)
+	}
+	l = &LibVirt{capabilitiesClient: &mockCapabilitiesClient{caps: caps}}
+	if result, err = l.addCapabilities(v1.Hypervisor{}); err != nil {
+		t.Fatalf("addCapabilities() returned unexpected error: %v", err)
+	}
+	if result.Status.Capabilities.HostCpus.Value() != 2 || !result.Status.Capabilities.HostMemory.IsZero() {
+		t.Errorf("Expected 2 cpus without memory, got %s and %s",
+			result.Status.Capabilities.HostCpus.String(), result.Status.Capabilities.HostMemory.String())
+	}
+
+	// Without numa topology the cpus are taken from the cpu topology.
+	caps = capabilities.Capabilities{}
+	caps.Host.CPU.Topology = &capabilities.CapabilitiesHostCPUTopology{Sockets: 2, Cores: 4, Threads: 2}
+	l = &LibVirt{capabilitiesClient: &mockCapabilitiesClient{caps: caps}}
+	if result, err = l.addCapabilities(v1.Hypervisor{}); err != nil {
+		t.Fatalf("addCapabilities() returned unexpected error: %v", err)
+	}
+	if result.Status.Capabilities.HostCpus.Value() != 16 {
+		t.Errorf("Expected 16 cpus, got %s", result.Status.Capabilities.HostCpus.String())
+	}
+}
+
 func TestAddDomainCapabilities_Success(t *testing.T) {
 	domCaps := domcapabilities.DomainCapabilities{
 		Domain: "kvm",
@@ -435,6 +512,43 @@ func TestAddDomainCapabilities_Success(t *testing.T) {
 	}
 }
 
+func TestAddCapabilities_Aarch64(t *testing.T) {
+	l := &LibVirt{
+		capabilitiesClient:       capabilities.NewClientEmulatorForArch("aarch64"),
+		domainCapabilitiesClient: domcapabilities.NewClientEmulatorForArch("aarch64"),
+	}
+	result, err := l.addCapabilities(v1.Hypervisor{})
+	if err != nil {
+		t.Fatalf("addCapabilities() returned unexpected error: %v", err)
+	}
+	if result.Status.Capabilities.HostCpuArch != "aarch64" {
+		t.Errorf("Expected HostCpuArch 'aarch64', got '%s'", result.Status.Capabilities.HostCpuArch)
+	}
+	if result.Status.Capabilities.HostCpus.Value() != 8 {
+		t.Errorf("Expected 8 host cpus, got %d", result.Status.Capabilities.HostCpus.Value())
+	}
+
+	result, err = l.addDomainCapabilities(result)
+	if err != nil {
+		t.Fatalf("addDomainCapabilities() returned unexpected error: %v", err)
+	}
+	if result.Status.DomainCapabilities.Arch != "aarch64" {
+		t.Errorf("Expected Arch 'aarch64', got '%s'", result.Status.DomainCapabilities.Arch)
+	}
+	for _, feature := range []string{"gic", "gic/2", "gic/3", "vmcoreinfo", "firmware/efi"} {
+		if !slices.Contains(result.Status.DomainCapabilities.SupportedFeatures, feature) {
+			t.Errorf("Expected feature %q, got %v", feature, result.Status.DomainCapabilities.SupportedFeatures)
+		}
+	}
+	// Kvm on aarch64 provides no custom cpu models.
+	if slices.Contains(result.Status.DomainCapabilities.SupportedCpuModes, "mode/custom") {
+		t.Errorf("Expected no custom cpu mode, got %v", result.Status.DomainCapabilities.SupportedCpuModes)
+	}
+	if !slices.Contains(result.Status.DomainCapabilities.SupportedCpuModes, "mode/host-passthrough") {
+		t.Errorf("Expected the host-passthrough cpu mode, got %v", result.Status.DomainCapabilities.SupportedCpuModes)
+	}
+}
+
 func TestAddDomainCapabilities_UnsupportedFiltered(t *testing.T) {
 	domCaps := domcapabilities.DomainCapabilities{
 		Domain: "kvm",
@@ -833,7 +947,7 @@ func TestProcess_Success(t *testing.T) {
 	}
 
 	hv := v1.Hypervisor{}
-	result, err := l.Process(hv)
+	result, err := l.Process(context.Background(), hv)
 
 	if err != nil {
 		t.Fatalf("Process() returned unexpected error: %v", err)
@@ -866,7 +980,7 @@ func TestProcess_PreservesOriginalOnError(t *testing.T) {
 		},
 	}
 
-	result, err := l.Process(originalHv)
+	result, err := l.Process(context.Background(), originalHv)
 
 	if err == nil {
 		t.Fatal("Expected error from Process(), got nil")
@@ -1657,6 +1771,53 @@ func TestAddEffectiveCapacity_WithMemoryOvercommit(t *testing.T) {
 	}
 }
 
+func TestAddEffectiveCapacity_DefaultOvercommit(t *testing.T) {
+	// Test that the spec takes precedence over the default overcommit
+	l := &LibVirt{}
+	l.SetDefaultOvercommit(map[v1.ResourceName]float64{
+		v1.ResourceMemory: 1.2,
+		v1.ResourceCPU:    4.0,
+	})
+
+	hv := v1.Hypervisor{
+		Spec: v1.HypervisorSpec{
+			Overcommit: map[v1.ResourceName]float64{
+				v1.ResourceCPU: 2.0,
+			},
+		},
+		Status: v1.HypervisorStatus{
+			Capacity: map[v1.ResourceName]resource.Quantity{
+				v1.ResourceMemory: *resource.NewQuantity(10*1024*1024*1024, resource.BinarySI),
+				v1.ResourceCPU:    *resource.NewQuantity(16, resource.DecimalSI),
+			},
+			EffectiveCapacity: make(map[v1.ResourceName]resource.Quantity),
+			Cells:             []v1.Cell{},
+		},
+	}
+
+	result, err := l.addEffectiveCapacity(hv)
+
+	if err != nil {
+		t.Fatalf("addEffectiveCapacity() returned unexpected error: %v", err)
+	}
+
+	// Memory should be 10 GiB * 1.2 = 12 GiB from the default overcommit
+	expectedMemory := resource.NewQuantity(12*1024*1024*1024, resource.BinarySI)
+	memEffective := result.Status.EffectiveCapacity[v1.ResourceMemory]
+	if !memEffective.Equal(*expectedMemory) {
+		t.Errorf("Expected effective memory capacity %s, got %s",
+			expectedMemory.String(), memEffective.String())
+	}
+
+	// CPU should be 16 * 2.0 = 32 from the spec
+	expectedCPU := resource.NewQuantity(32, resource.DecimalSI)
+	cpuEffective := result.Status.EffectiveCapacity[v1.ResourceCPU]
+	if !cpuEffective.Equal(*expectedCPU) {
+		t.Errorf("Expected effective CPU capacity %s, got %s",
+			expectedCPU.String(), cpuEffective.String())
+	}
+}
+
 func TestAddEffectiveCapacity_WithCPUOvercommit(t *testing.T) {
 	// Test CPU overcommit ratio of 4.0
 	l := &LibVirt{}
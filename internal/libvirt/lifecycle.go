@@ -0,0 +1,117 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package libvirt
+
+import (
+	"context"
+
+	v1 "github.com/cobaltcore-dev/openstack-hypervisor-operator/api/v1"
+	"github.com/digitalocean/go-libvirt"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logger "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/cobaltcore-dev/kvm-node-agent/api/v1alpha1"
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/sys"
+)
+
+// Reasons of the events of the lifecycle transitions of the domains.
+const (
+	EventReasonDomainAdded    = "DomainAdded"
+	EventReasonDomainStarted  = "DomainStarted"
+	EventReasonDomainStopped  = "DomainStopped"
+	EventReasonDomainMigrated = "DomainMigrated"
+)
+
+// Get the reason, action and message of the event of a lifecycle transition
+// of a domain. Returns false for transitions without event, crashes and
+// unexpected stops are reported together with the console log instead.
+func lifecycleEvent(event, detail int32) (reason, action, message string, ok bool) {
+	switch libvirt.DomainEventType(event) {
+	case libvirt.DomainEventDefined:
+		if libvirt.DomainEventDefinedDetailType(detail) == libvirt.DomainEventDefinedAdded {
+			return EventReasonDomainAdded, "Define", "domain added", true
+		}
+	case libvirt.DomainEventStarted:
+		switch libvirt.DomainEventStartedDetailType(detail) {
+		case libvirt.DomainEventStartedBooted:
+			return EventReasonDomainStarted, "Start", "domain booted", true
+		case libvirt.DomainEventStartedRestored:
+			return EventReasonDomainStarted, "Start", "domain restored", true
+		case libvirt.DomainEventStartedFromSnapshot:
+			return EventReasonDomainStarted, "Start", "domain started from snapshot", true
+		case libvirt.DomainEventStartedWakeup:
+			return EventReasonDomainStarted, "Start", "domain woken up", true
+		}
+	case libvirt.DomainEventResumed:
+		// The destination of a migration resumes the domain once it took over.
+		if libvirt.DomainEventResumedDetailType(detail) == libvirt.DomainEventResumedMigrated {
+			return EventReasonDomainMigrated, "Migrate", "domain migrated to " + sys.NodeLabelName, true
+		}
+	case libvirt.DomainEventStopped:
+		if unexpectedStopReason(detail) != "" {
+			return "", "", "", false
+		}
+		switch libvirt.DomainEventStoppedDetailType(detail) {
+		case libvirt.DomainEventStoppedMigrated:
+			return EventReasonDomainMigrated, "Migrate", "domain migrated off " + sys.NodeLabelName, true
+		case libvirt.DomainEventStoppedShutdown:
+			return EventReasonDomainStopped, "Stop", "domain shut down", true
+		case libvirt.DomainEventStoppedDestroyed:
+			return EventReasonDomainStopped, "Stop", "domain destroyed", true
+		case libvirt.DomainEventStoppedSaved:
+			return EventReasonDomainStopped, "Stop", "domain saved", true
+		case libvirt.DomainEventStoppedFromSnapshot:
+			return EventReasonDomainStopped, "Stop", "domain stopped from snapshot", true
+		}
+	}
+	return "", "", "", false
+}
+
+// Emit a normal event for a lifecycle transition of a domain. The event is
+// emitted for the instance of the domain, so that its name is the openstack
+// uuid, or for the hypervisor with the uuid in the note if the instance does
+// not exist yet, e.g. for domains which were just added.
+func (l *LibVirt) emitLifecycleEvent(ctx context.Context, domain libvirt.Domain, event, detail int32) {
+	if l.recorder == nil {
+		return
+	}
+	reason, action, message, ok := lifecycleEvent(event, detail)
+	if !ok {
+		return
+	}
+	uuid := GetOpenstackUUID(domain)
+	log := logger.FromContext(ctx).WithValues("server", uuid)
+	var instance v1alpha1.Instance
+	err := l.client.Get(ctx, client.ObjectKey{Name: uuid, Namespace: sys.Namespace}, &instance)
+	if err == nil {
+		l.recorder.Eventf(&instance, nil, corev1.EventTypeNormal, reason, action, "%s", message)
+		return
+	}
+	if !apierrors.IsNotFound(err) {
+		log.Error(err, "failed to get instance of domain", "reason", reason)
+		return
+	}
+	var hypervisor v1.Hypervisor
+	if err := l.client.Get(ctx, client.ObjectKey{Name: sys.NodeLabelName}, &hypervisor); err != nil {
+		log.Error(err, "failed to get hypervisor of domain", "reason", reason)
+		return
+	}
+	l.recorder.Eventf(&hypervisor, nil, corev1.EventTypeNormal, reason, action, "%s: %s", uuid, message)
+}
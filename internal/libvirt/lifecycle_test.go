@@ -0,0 +1,103 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package libvirt
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	v1 "github.com/cobaltcore-dev/openstack-hypervisor-operator/api/v1"
+	"github.com/digitalocean/go-libvirt"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/cobaltcore-dev/kvm-node-agent/api/v1alpha1"
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/sys"
+)
+
+// Records the name of the object each event is emitted for.
+type regardingRecorder struct {
+	events []string
+}
+
+func (r *regardingRecorder) Eventf(regarding, _ runtime.Object, eventtype, reason, _, note string, args ...any) {
+	name := regarding.(client.Object).GetName()
+	r.events = append(r.events, fmt.Sprintf("%s %s %s %s", name, eventtype, reason, fmt.Sprintf(note, args...)))
+}
+
+func TestLifecycleEvent(t *testing.T) {
+	tests := []struct {
+		event, detail int32
+		reason        string
+	}{
+		{int32(libvirt.DomainEventDefined), int32(libvirt.DomainEventDefinedAdded), EventReasonDomainAdded},
+		{int32(libvirt.DomainEventDefined), int32(libvirt.DomainEventDefinedUpdated), ""},
+		{int32(libvirt.DomainEventStarted), int32(libvirt.DomainEventStartedBooted), EventReasonDomainStarted},
+		{int32(libvirt.DomainEventStarted), int32(libvirt.DomainEventStartedMigrated), ""},
+		{int32(libvirt.DomainEventResumed), int32(libvirt.DomainEventResumedMigrated), EventReasonDomainMigrated},
+		{int32(libvirt.DomainEventResumed), int32(libvirt.DomainEventResumedUnpaused), ""},
+		{int32(libvirt.DomainEventStopped), int32(libvirt.DomainEventStoppedShutdown), EventReasonDomainStopped},
+		{int32(libvirt.DomainEventStopped), int32(libvirt.DomainEventStoppedMigrated), EventReasonDomainMigrated},
+		{int32(libvirt.DomainEventStopped), int32(libvirt.DomainEventStoppedCrashed), ""},
+		{int32(libvirt.DomainEventStopped), int32(libvirt.DomainEventStoppedFailed), ""},
+		{int32(libvirt.DomainEventCrashed), 0, ""},
+	}
+	for _, tt := range tests {
+		reason, _, _, ok := lifecycleEvent(tt.event, tt.detail)
+		if ok != (tt.reason != "") || reason != tt.reason {
+			t.Errorf("lifecycleEvent(%d, %d) = %q, %v, expected %q", tt.event, tt.detail, reason, ok, tt.reason)
+		}
+	}
+}
+
+func TestEmitLifecycleEvent(t *testing.T) {
+	ctx := context.Background()
+	scheme := runtime.NewScheme()
+	if err := v1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := v1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	known := libvirt.Domain{UUID: libvirt.UUID{1}}
+	unknown := libvirt.Domain{UUID: libvirt.UUID{2}}
+	instance := &v1alpha1.Instance{ObjectMeta: metav1.ObjectMeta{
+		Name: GetOpenstackUUID(known), Namespace: sys.Namespace,
+	}}
+	hypervisor := &v1.Hypervisor{ObjectMeta: metav1.ObjectMeta{Name: sys.NodeLabelName}}
+	recorder := &regardingRecorder{}
+	l := &LibVirt{
+		client:   fake.NewClientBuilder().WithScheme(scheme).WithObjects(instance, hypervisor).Build(),
+		recorder: recorder,
+	}
+
+	l.emitLifecycleEvent(ctx, known, int32(libvirt.DomainEventStarted), int32(libvirt.DomainEventStartedBooted))
+	l.emitLifecycleEvent(ctx, unknown, int32(libvirt.DomainEventDefined), int32(libvirt.DomainEventDefinedAdded))
+	l.emitLifecycleEvent(ctx, known, int32(libvirt.DomainEventSuspended), 0)
+
+	expected := []string{
+		GetOpenstackUUID(known) + " Normal DomainStarted domain booted",
+		sys.NodeLabelName + " Normal DomainAdded " + GetOpenstackUUID(unknown) + ": domain added",
+	}
+	if fmt.Sprint(recorder.events) != fmt.Sprint(expected) {
+		t.Errorf("Expected events %q, got %q", expected, recorder.events)
+	}
+}
@@ -0,0 +1,144 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package libvirt
+
+import (
+	"context"
+	"fmt"
+	"runtime/pprof"
+	"sync"
+	"time"
+
+	logger "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/logging"
+)
+
+// Delay before a background loop is restarted after a panic.
+var loopRestartDelay = 5 * time.Second
+
+// Profiler label of the goroutines of a background loop with the name of
+// the loop, e.g. migration-watch. Goroutines started by the loop inherit it.
+const LoopLabel = "libvirt-loop"
+
+// Background loops of a libvirt connection, e.g. the event loop. The loops
+// run with the context of the manager, so that they stop on shutdown, and
+// are restarted if they panic. Loops started before the manager are held
+// back until it starts.
+type loopGroup struct {
+	lock    sync.Mutex
+	ctx     context.Context
+	pending []func(ctx context.Context)
+	wg      sync.WaitGroup
+	// Failures of loops which can't be restarted, which stop the manager.
+	errs chan error
+}
+
+// Run the loop in the background until it returns. If the loop panics it
+// is restarted, unless check returns an error. A nil check never restarts
+// the loop.
+func (g *loopGroup) Go(name string, run func(ctx context.Context), check func() error) {
+	start := func(ctx context.Context) {
+		g.wg.Go(func() {
+			g.run(ctx, name, run, check)
+		})
+	}
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	if g.errs == nil {
+		g.errs = make(chan error, 1)
+	}
+	if g.ctx == nil {
+		g.pending = append(g.pending, start)
+		return
+	}
+	start(g.ctx)
+}
+
+// Run the loop until it returns without a panic, the context is done or
+// the loop can't be restarted.
+func (g *loopGroup) run(ctx context.Context, name string, run func(ctx context.Context), check func() error) {
+	ctx = pprof.WithLabels(ctx, pprof.Labels(LoopLabel, name))
+	pprof.SetGoroutineLabels(ctx)
+	log := logger.FromContext(ctx, "loop", name).WithName(logging.Libvirt)
+	ctx = logger.IntoContext(ctx, log)
+	for {
+		recovered := func() (recovered any) {
+			defer func() { recovered = recover() }()
+			run(ctx)
+			return nil
+		}()
+		if recovered == nil || ctx.Err() != nil {
+			return
+		}
+		err := fmt.Errorf("loop %s failed: %v", name, recovered)
+		if check == nil {
+			log.Error(err, "not restarting loop")
+			return
+		}
+		if checkErr := check(); checkErr != nil {
+			select {
+			case g.errs <- fmt.Errorf("%w, not restarting: %w", err, checkErr):
+			default:
+			}
+			return
+		}
+		log.Error(err, "restarting loop", "delay", loopRestartDelay)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(loopRestartDelay):
+		}
+	}
+}
+
+// Get the context the loops run with, the background context until the
+// manager started.
+func (g *loopGroup) context() context.Context {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	if g.ctx == nil {
+		return context.Background()
+	}
+	return g.ctx
+}
+
+// Start the loops and block until the manager stops, or a loop failed and
+// can't be restarted.
+func (g *loopGroup) Start(ctx context.Context) error {
+	g.lock.Lock()
+	if g.errs == nil {
+		g.errs = make(chan error, 1)
+	}
+	g.ctx = ctx
+	for _, start := range g.pending {
+		start(ctx)
+	}
+	g.pending = nil
+	errs := g.errs
+	g.lock.Unlock()
+
+	select {
+	case <-ctx.Done():
+		g.wg.Wait()
+		return nil
+	case err := <-errs:
+		// The manager cancels the context of the other loops.
+		return err
+	}
+}
@@ -0,0 +1,89 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package libvirt
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLoopGroup(t *testing.T) {
+	old := loopRestartDelay
+	loopRestartDelay = time.Millisecond
+	t.Cleanup(func() { loopRestartDelay = old })
+
+	var g loopGroup
+	var runs atomic.Int32
+	started := make(chan struct{})
+	g.Go("test", func(ctx context.Context) {
+		if runs.Add(1) == 1 {
+			panic("first run fails")
+		}
+		close(started)
+		<-ctx.Done()
+	}, func() error { return nil })
+	if runs.Load() != 0 {
+		t.Fatalf("Expected the loop to wait for the manager")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- g.Start(ctx) }()
+	select {
+	case <-started:
+	case <-time.After(time.Second):
+		t.Fatalf("Expected the loop to be restarted after the panic")
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Expected the loops to stop without error, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("Expected the loops to stop with the manager")
+	}
+	if runs.Load() != 2 {
+		t.Errorf("Expected 2 runs, got %d", runs.Load())
+	}
+}
+
+func TestLoopGroup_Fatal(t *testing.T) {
+	var g loopGroup
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error)
+	go func() { done <- g.Start(ctx) }()
+
+	g.Go("test", func(context.Context) {
+		panic("connection closed")
+	}, func() error { return errors.New("libvirt connection closed") })
+	select {
+	case err := <-done:
+		if err == nil || !strings.Contains(err.Error(), "loop test failed: connection closed") {
+			t.Errorf("Expected the failure of the loop, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("Expected the manager to be stopped")
+	}
+}
@@ -0,0 +1,202 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package libvirt
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var (
+	blockReadIOPS = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "libvirt_domain_block_read_iops",
+		Help: "Read operations per second of a domain block device, averaged over the last sampling interval.",
+	}, []string{"domain", "device"})
+	blockWriteIOPS = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "libvirt_domain_block_write_iops",
+		Help: "Write operations per second of a domain block device, averaged over the last sampling interval.",
+	}, []string{"domain", "device"})
+	blockReadBytes = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "libvirt_domain_block_read_bytes_per_second",
+		Help: "Bytes read per second from a domain block device, averaged over the last sampling interval.",
+	}, []string{"domain", "device"})
+	blockWriteBytes = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "libvirt_domain_block_write_bytes_per_second",
+		Help: "Bytes written per second to a domain block device, averaged over the last sampling interval.",
+	}, []string{"domain", "device"})
+	blockReadLatency = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "libvirt_domain_block_read_latency_seconds",
+		Help: "Average latency of read operations of a domain block device over the last sampling interval.",
+	}, []string{"domain", "device"})
+	blockWriteLatency = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "libvirt_domain_block_write_latency_seconds",
+		Help: "Average latency of write operations of a domain block device over the last sampling interval.",
+	}, []string{"domain", "device"})
+	cpuSteal = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "libvirt_domain_cpu_steal_percent",
+		Help: "Share of the time the vcpus of the domain were runnable but waited for a host cpu.",
+	}, []string{"domain"})
+	blockAllocation = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "libvirt_domain_block_allocation_bytes",
+		Help: "Bytes of the block device allocated on the host.",
+	}, []string{"domain", "device"})
+	blockCapacity = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "libvirt_domain_block_capacity_bytes",
+		Help: "Size of the block device as seen by the guest.",
+	}, []string{"domain", "device"})
+	interfaceQueues = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "libvirt_domain_interface_queues",
+		Help: "Number of queues configured for a domain network interface.",
+	}, []string{"domain", "interface"})
+	interfaceQueueMismatch = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "libvirt_domain_interface_queue_mismatch",
+		Help: "1 if the flavor requests multiqueue but the domain network interface has a single queue.",
+	}, []string{"domain", "interface"})
+	rngDevices = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "libvirt_domain_rng_devices",
+		Help: "Number of random number generator devices of the domain, guests without one may hang at boot.",
+	}, []string{"domain"})
+	tpmStateBytes = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "libvirt_domain_tpm_state_bytes",
+		Help: "Size of the state of the emulated tpm of the domain on the host.",
+	}, []string{"domain"})
+	swtpmUp = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "libvirt_domain_swtpm_up",
+		Help: "1 if the swtpm process emulating the tpm of the active domain is running.",
+	}, []string{"domain"})
+	vcpuNUMANodes = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "libvirt_domain_vcpu_numa_node",
+		Help: "1 for each host numa node the cpus pinned to a vcpu of the domain belong to.",
+	}, []string{"domain", "vcpu", "cpuset", "node"})
+	domainPauses = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "libvirt_domain_pauses_total",
+		Help: "Number of times a domain was paused, by the reason of the pause.",
+	}, []string{"domain", "reason"})
+	domainPausedSeconds = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "libvirt_domain_paused_seconds_total",
+		Help: "Time a domain spent paused, counted when the domain resumes.",
+	}, []string{"domain"})
+	domainIOErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "libvirt_domain_io_errors_total",
+		Help: "Number of io errors of a domain disk, by disk alias and reason.",
+	}, []string{"domain", "disk", "reason"})
+	domainWatchdogExpirations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "libvirt_domain_watchdog_expirations_total",
+		Help: "Number of times the watchdog of a domain expired, by the action taken.",
+	}, []string{"domain", "action"})
+	domainDrift = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "libvirt_domain_xml_drift",
+		Help: "1 if the live definition of a domain drifted from its persistent definition, by kind of drift.",
+	}, []string{"domain", "kind"})
+	jobDataProcessed = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "libvirt_domain_job_data_processed_bytes",
+		Help: "Bytes transferred by the running job of a domain, e.g. a live migration, by operation.",
+	}, []string{"domain", "operation"})
+	jobDataRemaining = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "libvirt_domain_job_data_remaining_bytes",
+		Help: "Bytes still to be transferred by the running job of a domain, by operation.",
+	}, []string{"domain", "operation"})
+	jobMemoryDirtyRate = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "libvirt_domain_job_memory_dirty_rate_pages_per_second",
+		Help: "Memory pages dirtied per second by the guest during the running job of a domain, by operation.",
+	}, []string{"domain", "operation"})
+	jobMemoryIterations = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "libvirt_domain_job_memory_iterations",
+		Help: "Number of passes over the guest memory of the running job of a domain, by operation.",
+	}, []string{"domain", "operation"})
+	jobDowntime = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "libvirt_domain_job_downtime_seconds",
+		Help: "Expected downtime of the guest at the end of the running job of a domain, by operation.",
+	}, []string{"domain", "operation"})
+	jobAutoConvergeThrottle = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "libvirt_domain_job_auto_converge_throttle_percent",
+		Help: "Share of the vcpu time taken from the guest to make the running job of a domain converge.",
+	}, []string{"domain", "operation"})
+	eventQueueDepth = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "libvirt_event_queue_depth",
+		Help: "Number of libvirt domain events waiting to be handled.",
+	})
+	rpcLatency = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "libvirt_rpc_latency_seconds",
+		Help: "Round trip of the last probe of the libvirt connection, 0 if it failed.",
+	}, []string{"uri"})
+	rpcConsecutiveFailures = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "libvirt_rpc_consecutive_failures",
+		Help: "Number of probes of the libvirt connection failed in a row.",
+	}, []string{"uri"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(
+		blockReadIOPS,
+		blockWriteIOPS,
+		blockReadBytes,
+		blockWriteBytes,
+		blockReadLatency,
+		blockWriteLatency,
+		blockAllocation,
+		blockCapacity,
+		cpuSteal,
+		interfaceQueues,
+		interfaceQueueMismatch,
+		rngDevices,
+		tpmStateBytes,
+		swtpmUp,
+		vcpuNUMANodes,
+		domainPauses,
+		domainPausedSeconds,
+		domainWatchdogExpirations,
+		domainIOErrors,
+		domainDrift,
+		jobDataProcessed,
+		jobDataRemaining,
+		jobMemoryDirtyRate,
+		jobMemoryIterations,
+		jobDowntime,
+		jobAutoConvergeThrottle,
+		eventQueueDepth,
+		rpcLatency,
+		rpcConsecutiveFailures,
+	)
+}
+
+// Domains a driver exported the series of domain metrics for. The metric
+// vectors are shared by the drivers of MultiLibVirt, so each driver only
+// removes the series of its own domains instead of resetting the vectors.
+type domainSeries struct {
+	lock    sync.Mutex
+	domains map[string]struct{}
+}
+
+// Remove the series of the domains exported before from the vectors, and
+// remember the domains whose series are exported next.
+func (s *domainSeries) replace(domains []string, vecs ...*prometheus.GaugeVec) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for domain := range s.domains {
+		for _, vec := range vecs {
+			vec.DeletePartialMatch(prometheus.Labels{"domain": domain})
+		}
+	}
+	s.domains = make(map[string]struct{}, len(domains))
+	for _, domain := range domains {
+		s.domains[domain] = struct{}{}
+	}
+}
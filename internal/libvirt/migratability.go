@@ -0,0 +1,185 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package libvirt
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/digitalocean/go-libvirt"
+	logger "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/cobaltcore-dev/kvm-node-agent/api/v1alpha1"
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/libvirt/dominfo"
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/logging"
+)
+
+// Reasons preventing the live migration of a domain.
+const (
+	BlockerPassthroughDevice = "PassthroughDevice"
+	BlockerCPUMode           = "CPUMode"
+	BlockerLocalStorage      = "LocalStorage"
+	BlockerHighDirtyRate     = "HighDirtyRate"
+	BlockerTPMState          = "TPMState"
+)
+
+const (
+	// Domains with at least this much memory are checked for their dirty
+	// rate, smaller domains converge fast enough anyway.
+	hugeMemoryKiB = 128 << 20
+	// Dirty rate from which the migration of a huge domain is not expected
+	// to converge.
+	highDirtyRateMiBps = 1024
+)
+
+// Convert the memory of the domain to KiB.
+func memoryKiB(memory *dominfo.DomainMemory) int64 {
+	if memory == nil {
+		return 0
+	}
+	switch strings.ToLower(memory.Unit) {
+	case "b", "bytes":
+		return memory.Value >> 10
+	case "m", "mib":
+		return memory.Value << 10
+	case "g", "gib":
+		return memory.Value << 20
+	}
+	// Libvirt reports KiB by default.
+	return memory.Value
+}
+
+// Check if the domain can be live migrated. The dirty rate of the domain
+// memory in MiB/s is only taken into account if known, i.e. if positive.
+func instanceMigration(domain dominfo.DomainInfo, dirtyRateMiBps float64) v1alpha1.InstanceMigration {
+	var blockers []v1alpha1.InstanceMigrationBlocker
+	if domain.Devices != nil {
+		for _, hostdev := range domain.Devices.Hostdevs {
+			blockers = append(blockers, v1alpha1.InstanceMigrationBlocker{
+				Reason:  BlockerPassthroughDevice,
+				Message: fmt.Sprintf("%s device passed through from the host", hostdev.Type),
+			})
+		}
+		for _, tpm := range domain.Devices.TPMs {
+			if tpm.Backend != nil && tpm.Backend.Type == "passthrough" {
+				blockers = append(blockers, v1alpha1.InstanceMigrationBlocker{
+					Reason:  BlockerPassthroughDevice,
+					Message: "tpm passed through from the host",
+				})
+			}
+		}
+	}
+	if domain.CPU != nil && (domain.CPU.Mode == "host-passthrough" || domain.CPU.Mode == "maximum") {
+		blockers = append(blockers, v1alpha1.InstanceMigrationBlocker{
+			Reason:  BlockerCPUMode,
+			Message: fmt.Sprintf("cpu mode %s requires an identical cpu on the destination", domain.CPU.Mode),
+		})
+	}
+	if domain.Devices != nil {
+		for _, disk := range domain.Devices.Disks {
+			if disk.Type != "file" || disk.Device != "disk" {
+				continue
+			}
+			target := ""
+			if disk.Target != nil {
+				target = disk.Target.Dev
+			}
+			blockers = append(blockers, v1alpha1.InstanceMigrationBlocker{
+				Reason:  BlockerLocalStorage,
+				Message: fmt.Sprintf("disk %s is stored locally", target),
+			})
+		}
+	}
+	if memory := memoryKiB(domain.Memory); memory >= hugeMemoryKiB && dirtyRateMiBps >= highDirtyRateMiBps {
+		blockers = append(blockers, v1alpha1.InstanceMigrationBlocker{
+			Reason: BlockerHighDirtyRate,
+			Message: fmt.Sprintf("%d GiB of memory dirtied at %.0f MiB/s",
+				memory>>20, dirtyRateMiBps),
+		})
+	}
+	return v1alpha1.InstanceMigration{
+		LiveMigratable: len(blockers) == 0,
+		Blockers:       blockers,
+	}
+}
+
+// Parse the dirty rate in MiB/s from the dirtyrate.* typed parameters of a
+// domain stats record. Returns false if no measurement is available.
+func parseDirtyRate(params []libvirt.TypedParam) (float64, bool) {
+	var measured bool
+	var rate uint64
+	var hasRate bool
+	for _, param := range params {
+		switch param.Field {
+		case "dirtyrate.calc_status":
+			// 2 is VIR_DOMAIN_DIRTYRATE_MEASURED.
+			status, ok := typedParamUint64(param.Value.I)
+			measured = ok && status == 2
+		case "dirtyrate.megabytes_per_second":
+			rate, hasRate = typedParamUint64(param.Value.I)
+		}
+	}
+	if !measured || !hasRate {
+		return 0, false
+	}
+	return float64(rate), true
+}
+
+// Get the last measured dirty rate of all active domains with huge memory
+// by their uuid, and start the next measurement. Not all hypervisor
+// drivers support measuring the dirty rate, so errors are only logged.
+func (l *LibVirt) dirtyRates(domains []dominfo.DomainInfo) map[string]float64 {
+	var huge []dominfo.DomainInfo
+	for _, domain := range domains {
+		if memoryKiB(domain.Memory) >= hugeMemoryKiB {
+			huge = append(huge, domain)
+		}
+	}
+	if len(huge) == 0 {
+		return nil
+	}
+
+	log := logger.Log.WithName(logging.Migration).WithName("dirty-rate")
+	rates := make(map[string]float64)
+	records, _, err := l.domainStats()
+	if err != nil {
+		log.V(1).Info("unable to get domain dirty rates", "error", err.Error())
+		return rates
+	}
+	for _, record := range records {
+		if rate, ok := parseDirtyRate(record.Params); ok {
+			rates[GetOpenstackUUID(record.Dom)] = rate
+		}
+	}
+
+	for _, domain := range huge {
+		id, err := ParseUUID(domain.UUID)
+		if err != nil {
+			continue
+		}
+		dom, err := l.virt.DomainLookupByUUID(libvirt.UUID(id))
+		if err != nil {
+			continue
+		}
+		// Measure for one second, the result is picked up next time.
+		if err := l.virt.DomainStartDirtyRateCalc(dom, 1, 0); err != nil {
+			log.V(1).Info("unable to start dirty rate calculation", "domain", domain.UUID, "error", err.Error())
+		}
+	}
+	return rates
+}
@@ -0,0 +1,101 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package libvirt
+
+import (
+	"testing"
+
+	"github.com/digitalocean/go-libvirt"
+
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/libvirt/dominfo"
+)
+
+func blockerReasons(domain dominfo.DomainInfo, dirtyRate float64) []string {
+	var reasons []string
+	for _, blocker := range instanceMigration(domain, dirtyRate).Blockers {
+		reasons = append(reasons, blocker.Reason)
+	}
+	return reasons
+}
+
+func TestInstanceMigration_ExampleDomain(t *testing.T) {
+	domains, err := dominfo.NewClientEmulator().Get(nil)
+	if err != nil {
+		t.Fatalf("Failed to get example domain: %v", err)
+	}
+	migration := instanceMigration(domains[0], 0)
+	if migration.LiveMigratable {
+		t.Errorf("Expected example domain not to be live migratable")
+	}
+	reasons := blockerReasons(domains[0], 0)
+	if len(reasons) != 2 || reasons[0] != BlockerCPUMode || reasons[1] != BlockerLocalStorage {
+		t.Errorf("Unexpected blockers: %v", reasons)
+	}
+}
+
+func TestInstanceMigration(t *testing.T) {
+	domain := dominfo.DomainInfo{
+		CPU:     &dominfo.DomainCPU{Mode: "host-model"},
+		Devices: &dominfo.DomainDevices{Disks: []dominfo.DomainDisk{{Type: "network", Device: "disk"}}},
+	}
+	if migration := instanceMigration(domain, 0); !migration.LiveMigratable || len(migration.Blockers) != 0 {
+		t.Errorf("Expected domain to be live migratable, got %+v", migration)
+	}
+
+	domain.Devices.Hostdevs = []dominfo.DomainHostdev{{Mode: "subsystem", Type: "pci"}}
+	if reasons := blockerReasons(domain, 0); len(reasons) != 1 || reasons[0] != BlockerPassthroughDevice {
+		t.Errorf("Expected passthrough device blocker, got %v", reasons)
+	}
+	domain.Devices.Hostdevs = nil
+
+	// The dirty rate only matters for domains with huge memory.
+	domain.Memory = &dominfo.DomainMemory{Unit: "GiB", Value: 64}
+	if reasons := blockerReasons(domain, 2048); len(reasons) != 0 {
+		t.Errorf("Expected no blockers for small domain, got %v", reasons)
+	}
+	domain.Memory = &dominfo.DomainMemory{Unit: "KiB", Value: 512 << 20}
+	if reasons := blockerReasons(domain, 512); len(reasons) != 0 {
+		t.Errorf("Expected no blockers for low dirty rate, got %v", reasons)
+	}
+	if reasons := blockerReasons(domain, 2048); len(reasons) != 1 || reasons[0] != BlockerHighDirtyRate {
+		t.Errorf("Expected high dirty rate blocker, got %v", reasons)
+	}
+}
+
+func TestParseDirtyRate(t *testing.T) {
+	param := func(field string, value any) libvirt.TypedParam {
+		return libvirt.TypedParam{Field: field, Value: libvirt.TypedParamValue{I: value}}
+	}
+	rate, ok := parseDirtyRate([]libvirt.TypedParam{
+		param("dirtyrate.calc_status", int32(2)),
+		param("dirtyrate.megabytes_per_second", int64(1500)),
+	})
+	if !ok || rate != 1500 {
+		t.Errorf("Expected dirty rate 1500, got %v (%v)", rate, ok)
+	}
+	// Still measuring.
+	if _, ok := parseDirtyRate([]libvirt.TypedParam{
+		param("dirtyrate.calc_status", int32(1)),
+		param("dirtyrate.megabytes_per_second", int64(1500)),
+	}); ok {
+		t.Errorf("Expected no dirty rate while measuring")
+	}
+	if _, ok := parseDirtyRate(nil); ok {
+		t.Errorf("Expected no dirty rate without stats")
+	}
+}
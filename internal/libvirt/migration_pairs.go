@@ -0,0 +1,85 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package libvirt
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/cobaltcore-dev/kvm-node-agent/api/v1alpha1"
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/sys"
+)
+
+// Name of the config map that holds the time of the last successful
+// migration per (source, destination) host pair. It is shared by all
+// node agents and lives in the agent namespace.
+const MigrationPairsConfigMapName = "kvm-node-agent-migration-pairs"
+
+// Build the config map key for a host pair. Underscores are not valid
+// in hostnames, so the key can be split unambiguously.
+func migrationPairKey(source, destination string) string {
+	return source + "_" + destination
+}
+
+// Check if the migration status reports a successfully finished migration.
+func migrationSucceeded(migration *v1alpha1.Migration) bool {
+	return migration.Status.Phase == v1alpha1.MigrationPhaseCompleted
+}
+
+// Record the time of a successful migration from source to destination in
+// the shared migration pairs config map, creating the config map if needed.
+func (l *LibVirt) recordMigrationPair(ctx context.Context, source, destination string, at time.Time) error {
+	key := migrationPairKey(source, destination)
+	value := at.UTC().Format(time.RFC3339)
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		var cm corev1.ConfigMap
+		object := client.ObjectKey{Name: MigrationPairsConfigMapName, Namespace: sys.Namespace}
+		if err := l.client.Get(ctx, object, &cm); err != nil {
+			if !apierrors.IsNotFound(err) {
+				return fmt.Errorf("failed to get migration pairs: %w", err)
+			}
+			cm = corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      MigrationPairsConfigMapName,
+					Namespace: sys.Namespace,
+				},
+				Data: map[string]string{key: value},
+			}
+			if err := l.client.Create(ctx, &cm); err != nil {
+				if apierrors.IsAlreadyExists(err) {
+					// Another agent was faster, retry as a conflict.
+					return apierrors.NewConflict(corev1.Resource("configmaps"), cm.Name, err)
+				}
+				return fmt.Errorf("failed to create migration pairs: %w", err)
+			}
+			return nil
+		}
+		if cm.Data == nil {
+			cm.Data = make(map[string]string)
+		}
+		cm.Data[key] = value
+		return l.client.Update(ctx, &cm)
+	})
+}
@@ -0,0 +1,79 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package libvirt
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/cobaltcore-dev/kvm-node-agent/api/v1alpha1"
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/sys"
+)
+
+func TestMigrationSucceeded(t *testing.T) {
+	tests := map[v1alpha1.MigrationPhase]bool{
+		v1alpha1.MigrationPhaseCompleted: true,
+		v1alpha1.MigrationPhaseFailed:    false,
+		v1alpha1.MigrationPhaseCancelled: false,
+		v1alpha1.MigrationPhasePreCopy:   false,
+		"":                               false,
+	}
+	for phase, expected := range tests {
+		migration := &v1alpha1.Migration{Status: v1alpha1.MigrationStatus{Phase: phase}}
+		if got := migrationSucceeded(migration); got != expected {
+			t.Errorf("migrationSucceeded(%q) = %v, expected %v", phase, got, expected)
+		}
+	}
+}
+
+func TestRecordMigrationPair(t *testing.T) {
+	ctx := context.Background()
+	l := &LibVirt{client: fake.NewClientBuilder().Build()}
+
+	first := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	if err := l.recordMigrationPair(ctx, "node-a", "node-b", first); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	second := first.Add(time.Hour)
+	if err := l.recordMigrationPair(ctx, "node-a", "node-b", second); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := l.recordMigrationPair(ctx, "node-b", "node-a", first); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	var cm corev1.ConfigMap
+	object := client.ObjectKey{Name: MigrationPairsConfigMapName, Namespace: sys.Namespace}
+	if err := l.client.Get(ctx, object, &cm); err != nil {
+		t.Fatalf("Expected config map to exist, got %v", err)
+	}
+	if len(cm.Data) != 2 {
+		t.Errorf("Expected 2 host pairs, got %d", len(cm.Data))
+	}
+	if got := cm.Data["node-a_node-b"]; got != "2025-01-01T13:00:00Z" {
+		t.Errorf("Expected latest migration time for node-a_node-b, got %q", got)
+	}
+	if got := cm.Data["node-b_node-a"]; got != "2025-01-01T12:00:00Z" {
+		t.Errorf("Expected migration time for node-b_node-a, got %q", got)
+	}
+}
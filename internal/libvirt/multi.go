@@ -0,0 +1,428 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package libvirt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	v1 "github.com/cobaltcore-dev/openstack-hypervisor-operator/api/v1"
+	"github.com/digitalocean/go-libvirt"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/events"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logger "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/libvirt/dominfo"
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/libvirt/virterr"
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/logging"
+)
+
+// Prefix of the hypervisor conditions reporting the libvirt drivers of a
+// host with multiple drivers, e.g. "LibVirtDriver.qemu".
+const DriverConditionPrefix = "LibVirtDriver."
+
+// Get the type of the hypervisor condition reporting the given driver.
+func DriverConditionType(driver string) string {
+	return DriverConditionPrefix + driver
+}
+
+// MultiLibVirt connects to several libvirt drivers of the same host, e.g.
+// qemu:///system alongside ch:///system, and represents them as a single
+// hypervisor.
+//
+// The first driver is the primary one. It has to be connected and provides
+// the versions and capabilities of the hypervisor. The domains and their
+// allocation are aggregated over all connected drivers, each driver is
+// reported in a condition of its own.
+type MultiLibVirt struct {
+	drivers []*LibVirt
+
+	// Last connection error of each driver.
+	errs     []error
+	errsLock sync.Mutex
+
+	// Leftovers found on the previous pass, over the domains of all drivers.
+	leftovers leftoverTracker
+}
+
+// Create a libvirt client connecting to all given uris, the first one is
+// the primary driver.
+func NewMultiLibVirt(k client.Client, uris []string) *MultiLibVirt {
+	m := &MultiLibVirt{errs: make([]error, len(uris))}
+	for _, uri := range uris {
+		m.drivers = append(m.drivers, NewLibVirtForURI(k, uri))
+	}
+	return m
+}
+
+// Connect to all drivers. Only failing to connect to the primary driver is
+// an error, the other drivers are retried on the next call.
+func (m *MultiLibVirt) Connect() error {
+	m.errsLock.Lock()
+	defer m.errsLock.Unlock()
+	for i, l := range m.drivers {
+		m.errs[i] = l.Connect()
+		if m.errs[i] != nil && i > 0 {
+			logger.Log.WithName(logging.Libvirt).Error(m.errs[i], "unable to connect to libvirt driver", "uri", l.uri)
+		}
+	}
+	return m.errs[0]
+}
+
+// Run the background loops of all drivers until the manager stops, or a
+// loop of any driver failed.
+func (m *MultiLibVirt) Start(ctx context.Context) error {
+	errs := make(chan error, len(m.drivers))
+	for _, l := range m.drivers {
+		go func() { errs <- l.Start(ctx) }()
+	}
+	for range m.drivers {
+		if err := <-errs; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *MultiLibVirt) Close() error {
+	var errs []error
+	for _, l := range m.drivers {
+		if l.virt.IsConnected() {
+			errs = append(errs, l.Close())
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Watch the domain changes of all drivers.
+func (m *MultiLibVirt) WatchDomainChanges(
+	eventId libvirt.DomainEventID,
+	handlerId string,
+	handler func(context.Context, any),
+) {
+	for _, l := range m.drivers {
+		l.WatchDomainChanges(eventId, handlerId, handler)
+	}
+}
+
+// Add the information of all drivers to the hypervisor instance. If the
+// primary driver fails, the instance is returned unmodified.
+func (m *MultiLibVirt) Process(ctx context.Context, hv v1.Hypervisor) (v1.Hypervisor, error) {
+	m.errsLock.Lock()
+	errs := append([]error(nil), m.errs...)
+	m.errsLock.Unlock()
+
+	processed, err := m.drivers[0].Process(ctx, hv)
+	if err != nil {
+		return hv, err
+	}
+	m.drivers[0].setDriverCondition(&processed, processed, nil)
+	for i, l := range m.drivers[1:] {
+		if err := errs[i+1]; err != nil {
+			l.setDriverCondition(&processed, v1.Hypervisor{}, err)
+			continue
+		}
+		other, err := l.Process(ctx, hv)
+		if err == nil {
+			mergeDriver(&processed, other)
+		}
+		l.setDriverCondition(&processed, other, err)
+	}
+	return processed, nil
+}
+
+// Set the condition of the driver from the hypervisor processed by it, or
+// from the error connecting to or processing the driver.
+func (l *LibVirt) setDriverCondition(hv *v1.Hypervisor, processed v1.Hypervisor, err error) {
+	condition := metav1.Condition{
+		Type:   DriverConditionType(l.driver()),
+		Status: metav1.ConditionTrue,
+		Reason: "Connected",
+		Message: fmt.Sprintf("%s: libvirt %s, hypervisor %s, %d instances", l.uri,
+			processed.Status.LibVirtVersion, processed.Status.HypervisorVersion, processed.Status.NumInstances),
+	}
+	if err != nil {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "Failed"
+		condition.Message = fmt.Sprintf("%s: %v", l.uri, err)
+	}
+	meta.SetStatusCondition(&hv.Status.Conditions, condition)
+}
+
+// Add the domains of a secondary driver and their allocation to the
+// hypervisor processed by the primary driver. The capacity is the one of
+// the host and stays the same.
+func mergeDriver(hv *v1.Hypervisor, other v1.Hypervisor) {
+	hv.Status.Instances = append(hv.Status.Instances, other.Status.Instances...)
+	hv.Status.NumInstances += other.Status.NumInstances
+	if hv.Status.Allocation == nil {
+		hv.Status.Allocation = make(map[v1.ResourceName]resource.Quantity)
+	}
+	for name, quantity := range other.Status.Allocation {
+		total := hv.Status.Allocation[name]
+		total.Add(quantity)
+		hv.Status.Allocation[name] = total
+	}
+	for _, otherCell := range other.Status.Cells {
+		for i, cell := range hv.Status.Cells {
+			if cell.CellID != otherCell.CellID {
+				continue
+			}
+			for name, quantity := range otherCell.Allocation {
+				total := cell.Allocation[name]
+				total.Add(quantity)
+				cell.Allocation[name] = total
+			}
+			hv.Status.Cells[i] = cell
+		}
+	}
+}
+
+// Check the domain policy of the domains of all connected drivers.
+func (m *MultiLibVirt) EnforceDomainPolicy(
+	dryRun bool, managedSaveMaxAge time.Duration,
+) ([]DomainPolicyViolation, error) {
+	var violations []DomainPolicyViolation
+	var errs []error
+	for _, l := range m.connected() {
+		driverViolations, err := l.EnforceDomainPolicy(dryRun, managedSaveMaxAge)
+		violations = append(violations, driverViolations...)
+		errs = append(errs, err)
+	}
+	return violations, errors.Join(errs...)
+}
+
+// Find the leftovers of crashed domains of all drivers. All drivers need to
+// be connected, the domains of a disconnected driver would be taken for
+// leftovers.
+func (m *MultiLibVirt) CleanLeftovers(dryRun bool, ovsSockets []string) ([]Leftover, error) {
+	var active []dominfo.DomainInfo
+	for _, l := range m.drivers {
+		if !l.virt.IsConnected() {
+			return nil, fmt.Errorf("libvirt driver %s is not connected", l.driver())
+		}
+		domains, err := l.domainInfoClient.Get(l.virt, libvirt.ConnectListDomainsActive)
+		if err != nil {
+			return nil, err
+		}
+		active = append(active, domains...)
+	}
+	return m.leftovers.clean(active, ovsSockets, dryRun, time.Now())
+}
+
+// Detect the drift of the domains of all connected drivers.
+func (m *MultiLibVirt) DetectDomainDrift() ([]DomainDrift, error) {
+	var drifts []DomainDrift
+	var errs []error
+	for _, l := range m.connected() {
+		driverDrifts, err := l.DetectDomainDrift()
+		drifts = append(drifts, driverDrifts...)
+		errs = append(errs, err)
+	}
+	return drifts, errors.Join(errs...)
+}
+
+// Get the host cpus from the primary driver, they are the same for all.
+func (m *MultiLibVirt) HostCPUs() (map[uint64][]int, error) {
+	return m.drivers[0].HostCPUs()
+}
+
+// Get the cpu model of the host from the primary driver.
+func (m *MultiLibVirt) HostCPUModel() (HostCPUModel, error) {
+	return m.drivers[0].HostCPUModel()
+}
+
+// Compute the baseline cpu with the primary driver.
+func (m *MultiLibVirt) BaselineCPU(arch string, cpus []HostCPUModel) (HostCPUModel, error) {
+	return m.drivers[0].BaselineCPU(arch, cpus)
+}
+
+// Get the support for confidential domains from the primary driver.
+func (m *MultiLibVirt) ConfidentialComputing() (ConfidentialComputing, error) {
+	return m.drivers[0].ConfidentialComputing()
+}
+
+// Get the firmware inventory from the primary driver.
+func (m *MultiLibVirt) Firmware() (Firmware, error) {
+	return m.drivers[0].Firmware()
+}
+
+// Check the iommu support of the host with the primary driver.
+func (m *MultiLibVirt) HostIOMMUSupported() (bool, error) {
+	return m.drivers[0].HostIOMMUSupported()
+}
+
+// Probe the connections of all drivers and return the health of the
+// primary driver, the other drivers are only exported as metrics.
+func (m *MultiLibVirt) ProbeConnection() ConnectionHealth {
+	health := m.drivers[0].ProbeConnection()
+	for _, l := range m.drivers[1:] {
+		l.ProbeConnection()
+	}
+	return health
+}
+
+// Set the recorder of the events of the instances of all drivers.
+func (m *MultiLibVirt) SetEventRecorder(recorder events.EventRecorder) {
+	for _, l := range m.drivers {
+		l.SetEventRecorder(recorder)
+	}
+}
+
+// Set the default overcommit ratios of all drivers, only the ones of the
+// primary driver end up in the effective capacity.
+func (m *MultiLibVirt) SetDefaultOvercommit(overcommit map[v1.ResourceName]float64) {
+	for _, l := range m.drivers {
+		l.SetDefaultOvercommit(overcommit)
+	}
+}
+
+// Drop the cached domain capabilities of all drivers.
+func (m *MultiLibVirt) InvalidateDomainCapabilities() {
+	for _, l := range m.drivers {
+		l.InvalidateDomainCapabilities()
+	}
+}
+
+// Open the serial console of the domain in the driver defining it.
+func (m *MultiLibVirt) OpenConsole(ctx context.Context, uuid string, w io.Writer) error {
+	id, err := ParseUUID(uuid)
+	if err != nil {
+		return err
+	}
+	for _, l := range m.connected() {
+		if _, err := l.virt.DomainLookupByUUID(libvirt.UUID(id)); err == nil {
+			return l.OpenConsole(ctx, uuid, w)
+		}
+	}
+	return fmt.Errorf("domain %s not found in any libvirt driver", uuid)
+}
+
+// Get the running domains created by nova of all connected drivers.
+func (m *MultiLibVirt) NovaDomains() ([]NovaDomain, error) {
+	var domains []NovaDomain
+	var errs []error
+	for _, l := range m.connected() {
+		driverDomains, err := l.NovaDomains()
+		domains = append(domains, driverDomains...)
+		errs = append(errs, err)
+	}
+	return domains, errors.Join(errs...)
+}
+
+// Shut the domain down with the driver it runs on.
+func (m *MultiLibVirt) ShutdownDomain(uuid string, grace time.Duration) error {
+	return m.withDomain(uuid, func(l *LibVirt, uuid string) error {
+		return l.ShutdownDomain(uuid, grace)
+	})
+}
+
+// Ask the guest of the domain to shut down with the driver it runs on.
+func (m *MultiLibVirt) RequestShutdown(uuid string) error {
+	return m.withDomain(uuid, (*LibVirt).RequestShutdown)
+}
+
+// Get the cpu of the domain from the driver it runs on.
+func (m *MultiLibVirt) DomainCPU(uuid string) (*dominfo.DomainCPU, error) {
+	var cpu *dominfo.DomainCPU
+	err := m.withDomain(uuid, func(l *LibVirt, uuid string) error {
+		var err error
+		cpu, err = l.DomainCPU(uuid)
+		return err
+	})
+	return cpu, err
+}
+
+// Power the domain off with the driver it runs on.
+func (m *MultiLibVirt) DestroyDomain(uuid string) error {
+	return m.withDomain(uuid, (*LibVirt).DestroyDomain)
+}
+
+// Balloon the domain with the driver it runs on.
+func (m *MultiLibVirt) BalloonDomain(uuid string, memoryKiB uint64) error {
+	return m.withDomain(uuid, func(l *LibVirt, uuid string) error {
+		return l.BalloonDomain(uuid, memoryKiB)
+	})
+}
+
+// Pause the vcpus of the domain with the driver it runs on.
+func (m *MultiLibVirt) SuspendDomain(uuid string) error {
+	return m.withDomain(uuid, (*LibVirt).SuspendDomain)
+}
+
+// Continue the paused domain with the driver it runs on.
+func (m *MultiLibVirt) ResumeDomain(uuid string) error {
+	return m.withDomain(uuid, (*LibVirt).ResumeDomain)
+}
+
+// Save the domain with the driver it runs on.
+func (m *MultiLibVirt) ManagedSaveDomain(uuid string) error {
+	return m.withDomain(uuid, (*LibVirt).ManagedSaveDomain)
+}
+
+// Restore the domain with the driver it is defined in.
+func (m *MultiLibVirt) RestoreDomain(uuid string) error {
+	return m.withDomain(uuid, (*LibVirt).RestoreDomain)
+}
+
+// Run the operation on the domain with the driver it is defined in, trying
+// the connected drivers until one knows the domain.
+func (m *MultiLibVirt) withDomain(uuid string, op func(l *LibVirt, uuid string) error) error {
+	for _, l := range m.connected() {
+		err := op(l, uuid)
+		if !errors.Is(err, virterr.ErrDomainNotFound) {
+			return err
+		}
+	}
+	return fmt.Errorf("domain %s not found in any libvirt driver: %w", uuid, virterr.ErrDomainNotFound)
+}
+
+// Get the drivers currently connected.
+func (m *MultiLibVirt) connected() []*LibVirt {
+	var drivers []*LibVirt
+	for _, l := range m.drivers {
+		if l.virt.IsConnected() {
+			drivers = append(drivers, l)
+		}
+	}
+	return drivers
+}
+
+// Get the disk usage of the domains of all drivers. The filesystem is the
+// same for all drivers, it is taken from the primary driver.
+func (m *MultiLibVirt) InstanceDiskUsage() ([]DiskUsage, FilesystemUsage, error) {
+	disks, filesystem, err := m.drivers[0].InstanceDiskUsage()
+	if err != nil {
+		return nil, FilesystemUsage{}, err
+	}
+	for _, l := range m.drivers[1:] {
+		records, at, err := l.domainStats()
+		if err != nil {
+			return nil, FilesystemUsage{}, err
+		}
+		disks = append(disks, instanceDisks(records, InstancesPath, at)...)
+	}
+	return disks, filesystem, nil
+}
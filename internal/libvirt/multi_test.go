@@ -0,0 +1,97 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package libvirt
+
+import (
+	"errors"
+	"testing"
+
+	v1 "github.com/cobaltcore-dev/openstack-hypervisor-operator/api/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestMergeDriver(t *testing.T) {
+	hv := v1.Hypervisor{Status: v1.HypervisorStatus{
+		Instances:    []v1.Instance{{ID: "ch-1", Active: true}},
+		NumInstances: 1,
+		Capacity:     map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse("64")},
+		Allocation: map[v1.ResourceName]resource.Quantity{
+			v1.ResourceCPU:    resource.MustParse("4"),
+			v1.ResourceMemory: resource.MustParse("8Gi"),
+		},
+		Cells: []v1.Cell{
+			{CellID: 0, Allocation: map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse("4")}},
+			{CellID: 1, Allocation: map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse("0")}},
+		},
+	}}
+	other := v1.Hypervisor{Status: v1.HypervisorStatus{
+		Instances:    []v1.Instance{{ID: "qemu-1", Active: true}, {ID: "qemu-2"}},
+		NumInstances: 2,
+		Capacity:     map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse("64")},
+		Allocation: map[v1.ResourceName]resource.Quantity{
+			v1.ResourceCPU:    resource.MustParse("8"),
+			v1.ResourceMemory: resource.MustParse("16Gi"),
+		},
+		Cells: []v1.Cell{
+			{CellID: 1, Allocation: map[v1.ResourceName]resource.Quantity{v1.ResourceCPU: resource.MustParse("8")}},
+		},
+	}}
+
+	mergeDriver(&hv, other)
+	if hv.Status.NumInstances != 3 || len(hv.Status.Instances) != 3 {
+		t.Errorf("Expected 3 instances, got %d %v", hv.Status.NumInstances, hv.Status.Instances)
+	}
+	if cpu := hv.Status.Allocation[v1.ResourceCPU]; cpu.Value() != 12 {
+		t.Errorf("Expected 12 allocated cpus, got %s", cpu.String())
+	}
+	if memory := hv.Status.Allocation[v1.ResourceMemory]; memory.Cmp(resource.MustParse("24Gi")) != 0 {
+		t.Errorf("Expected 24Gi allocated memory, got %s", memory.String())
+	}
+	if cpu := hv.Status.Capacity[v1.ResourceCPU]; cpu.Value() != 64 {
+		t.Errorf("Expected the capacity to stay the same, got %s", cpu.String())
+	}
+	if cpu := hv.Status.Cells[0].Allocation[v1.ResourceCPU]; cpu.Value() != 4 {
+		t.Errorf("Expected 4 cpus allocated in cell 0, got %s", cpu.String())
+	}
+	if cpu := hv.Status.Cells[1].Allocation[v1.ResourceCPU]; cpu.Value() != 8 {
+		t.Errorf("Expected 8 cpus allocated in cell 1, got %s", cpu.String())
+	}
+}
+
+func TestSetDriverCondition(t *testing.T) {
+	l := &LibVirt{uri: "qemu:///system"}
+	var hv v1.Hypervisor
+	l.setDriverCondition(&hv, v1.Hypervisor{Status: v1.HypervisorStatus{
+		LibVirtVersion: "10.5.0", HypervisorVersion: "9.0.0", NumInstances: 2,
+	}}, nil)
+	condition := meta.FindStatusCondition(hv.Status.Conditions, "LibVirtDriver.qemu")
+	if condition == nil || condition.Status != metav1.ConditionTrue {
+		t.Fatalf("Expected driver condition to be true, got %+v", condition)
+	}
+	if condition.Message != "qemu:///system: libvirt 10.5.0, hypervisor 9.0.0, 2 instances" {
+		t.Errorf("Unexpected message %q", condition.Message)
+	}
+
+	l.setDriverCondition(&hv, v1.Hypervisor{}, errors.New("connection refused"))
+	condition = meta.FindStatusCondition(hv.Status.Conditions, "LibVirtDriver.qemu")
+	if condition.Status != metav1.ConditionFalse || condition.Message != "qemu:///system: connection refused" {
+		t.Errorf("Expected driver condition to be false, got %+v", condition)
+	}
+}
@@ -0,0 +1,139 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package libvirt
+
+import (
+	"sync"
+	"time"
+
+	"github.com/digitalocean/go-libvirt"
+	"github.com/prometheus/client_golang/prometheus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/cobaltcore-dev/kvm-node-agent/api/v1alpha1"
+)
+
+// Reason of a pause of a domain guest initiated suspend to ram.
+const pauseReasonPMSuspended = "pmsuspended"
+
+// Get the reason of a pause from the detail of the suspended event.
+func pauseReason(detail int32) string {
+	switch libvirt.DomainEventSuspendedDetailType(detail) {
+	case libvirt.DomainEventSuspendedPaused:
+		return "paused"
+	case libvirt.DomainEventSuspendedMigrated:
+		return "migrated"
+	case libvirt.DomainEventSuspendedIoerror:
+		return "ioerror"
+	case libvirt.DomainEventSuspendedWatchdog:
+		return "watchdog"
+	case libvirt.DomainEventSuspendedRestored:
+		return "restored"
+	case libvirt.DomainEventSuspendedFromSnapshot:
+		return "from_snapshot"
+	case libvirt.DomainEventSuspendedAPIError:
+		return "api_error"
+	case libvirt.DomainEventSuspendedPostcopy:
+		return "postcopy"
+	case libvirt.DomainEventSuspendedPostcopyFailed:
+		return "postcopy_failed"
+	}
+	return "unknown"
+}
+
+// Accumulates the time the domains spent paused, by domain uuid. The zero
+// value is ready to use.
+type pauseTracker struct {
+	lock   sync.Mutex
+	pauses map[string]*v1alpha1.InstancePauses
+}
+
+// Get the pauses of the domain, creating them if needed.
+func (t *pauseTracker) get(uuid string) *v1alpha1.InstancePauses {
+	if t.pauses == nil {
+		t.pauses = make(map[string]*v1alpha1.InstancePauses)
+	}
+	pauses, ok := t.pauses[uuid]
+	if !ok {
+		pauses = &v1alpha1.InstancePauses{}
+		t.pauses[uuid] = pauses
+	}
+	return pauses
+}
+
+// Record that the domain was paused. Repeated suspended events of an
+// already paused domain only update the reason.
+func (t *pauseTracker) paused(uuid, reason string, at time.Time) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	pauses := t.get(uuid)
+	pauses.LastPausedReason = reason
+	if pauses.Since != nil {
+		return
+	}
+	since := metav1.NewTime(at)
+	pauses.Since = &since
+	pauses.Count++
+	domainPauses.WithLabelValues(uuid, reason).Inc()
+}
+
+// Record that the domain runs again, or stopped while being paused.
+func (t *pauseTracker) resumed(uuid string, at time.Time) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	pauses, ok := t.pauses[uuid]
+	if !ok || pauses.Since == nil {
+		return
+	}
+	if paused := at.Sub(pauses.Since.Time); paused > 0 {
+		pauses.Duration.Duration += paused
+		domainPausedSeconds.WithLabelValues(uuid).Add(paused.Seconds())
+	}
+	pauses.Since = nil
+}
+
+// Forget the pauses of an undefined domain.
+func (t *pauseTracker) forget(uuid string) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	delete(t.pauses, uuid)
+	domainPauses.DeletePartialMatch(prometheus.Labels{"domain": uuid})
+	domainPausedSeconds.DeleteLabelValues(uuid)
+}
+
+// Continue with the pauses recorded in the status of the instance, e.g.
+// after a restart of the agent. Pauses observed since are kept.
+func (t *pauseTracker) seed(uuid string, pauses *v1alpha1.InstancePauses) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if _, ok := t.pauses[uuid]; ok || pauses == nil {
+		return
+	}
+	*t.get(uuid) = *pauses.DeepCopy()
+}
+
+// Get a copy of the pauses of the domain, nil if it was never paused.
+func (t *pauseTracker) status(uuid string) *v1alpha1.InstancePauses {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	pauses, ok := t.pauses[uuid]
+	if !ok {
+		return nil
+	}
+	return pauses.DeepCopy()
+}
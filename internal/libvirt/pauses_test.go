@@ -0,0 +1,121 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package libvirt
+
+import (
+	"testing"
+	"time"
+
+	"github.com/digitalocean/go-libvirt"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/cobaltcore-dev/kvm-node-agent/api/v1alpha1"
+)
+
+func TestPauseReason(t *testing.T) {
+	tests := map[libvirt.DomainEventSuspendedDetailType]string{
+		libvirt.DomainEventSuspendedPaused:   "paused",
+		libvirt.DomainEventSuspendedMigrated: "migrated",
+		libvirt.DomainEventSuspendedIoerror:  "ioerror",
+		libvirt.DomainEventSuspendedAPIError: "api_error",
+		99:                                   "unknown",
+	}
+	for detail, expected := range tests {
+		if reason := pauseReason(int32(detail)); reason != expected {
+			t.Errorf("Expected reason %q for detail %d, got %q", expected, detail, reason)
+		}
+	}
+}
+
+func TestPauseTracker(t *testing.T) {
+	var tracker pauseTracker
+	start := time.Now()
+
+	if pauses := tracker.status("a"); pauses != nil {
+		t.Errorf("Expected no pauses of unknown domain, got %+v", pauses)
+	}
+	// Resuming a domain which was never paused is ignored.
+	tracker.resumed("a", start)
+	if pauses := tracker.status("a"); pauses != nil {
+		t.Errorf("Expected no pauses after resume only, got %+v", pauses)
+	}
+
+	tracker.paused("a", "ioerror", start)
+	// Repeated events while paused don't count as another pause.
+	tracker.paused("a", "paused", start.Add(time.Second))
+	pauses := tracker.status("a")
+	if pauses == nil || pauses.Count != 1 || pauses.Since == nil || pauses.LastPausedReason != "paused" {
+		t.Fatalf("Expected one ongoing pause for reason paused, got %+v", pauses)
+	}
+
+	tracker.resumed("a", start.Add(3*time.Second))
+	tracker.paused("a", "migrated", start.Add(10*time.Second))
+	tracker.resumed("a", start.Add(12*time.Second))
+	pauses = tracker.status("a")
+	if pauses.Count != 2 || pauses.Since != nil {
+		t.Errorf("Expected two completed pauses, got %+v", pauses)
+	}
+	if pauses.Duration.Duration != 5*time.Second {
+		t.Errorf("Expected 5s paused, got %s", pauses.Duration.Duration)
+	}
+	if pauses.LastPausedReason != "migrated" {
+		t.Errorf("Expected last reason migrated, got %q", pauses.LastPausedReason)
+	}
+
+	// The status is a copy.
+	pauses.Count = 10
+	if tracker.status("a").Count != 2 {
+		t.Errorf("Expected status to be a copy")
+	}
+
+	tracker.forget("a")
+	if pauses := tracker.status("a"); pauses != nil {
+		t.Errorf("Expected no pauses after forget, got %+v", pauses)
+	}
+}
+
+func TestPauseTrackerSeed(t *testing.T) {
+	var tracker pauseTracker
+	since := metav1.NewTime(time.Now().Add(-time.Minute))
+	recorded := &v1alpha1.InstancePauses{
+		Count:            3,
+		Duration:         metav1.Duration{Duration: time.Hour},
+		Since:            &since,
+		LastPausedReason: "watchdog",
+	}
+	tracker.seed("a", recorded)
+	tracker.resumed("a", since.Add(time.Minute))
+	pauses := tracker.status("a")
+	if pauses.Count != 3 || pauses.Duration.Duration != time.Hour+time.Minute {
+		t.Errorf("Expected seeded pauses to continue, got %+v", pauses)
+	}
+	if recorded.Since == nil {
+		t.Errorf("Expected seeded pauses not to be modified")
+	}
+
+	// Pauses observed since the start of the agent win.
+	tracker.seed("a", &v1alpha1.InstancePauses{Count: 42})
+	if tracker.status("a").Count != 3 {
+		t.Errorf("Expected seed to keep observed pauses")
+	}
+	tracker.seed("b", nil)
+	if pauses := tracker.status("b"); pauses != nil {
+		t.Errorf("Expected no pauses from nil seed, got %+v", pauses)
+	}
+	tracker.forget("a")
+}
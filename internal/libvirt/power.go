@@ -0,0 +1,134 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package libvirt
+
+import (
+	"fmt"
+
+	"github.com/digitalocean/go-libvirt"
+
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/libvirt/virterr"
+)
+
+// DomainPowerManager suspends, resumes, saves and restores single domains,
+// e.g. to reclaim host memory in an emergency.
+type DomainPowerManager interface {
+	// SuspendDomain pauses the vcpus of the running domain.
+	SuspendDomain(uuid string) error
+	// ResumeDomain continues the paused domain.
+	ResumeDomain(uuid string) error
+	// ManagedSaveDomain saves the memory of the domain to disk and stops
+	// it, blocking until the memory is written.
+	ManagedSaveDomain(uuid string) error
+	// RestoreDomain starts the domain from its managed save image.
+	RestoreDomain(uuid string) error
+}
+
+// MemoryReclaimer reclaims host memory from running domains when the host
+// runs out of memory.
+type MemoryReclaimer interface {
+	// BalloonDomain inflates the balloon of the domain, so that the guest
+	// is left with the given memory.
+	BalloonDomain(uuid string, memoryKiB uint64) error
+	// SuspendDomain pauses the vcpus of the domain, so that the guest
+	// stops allocating memory.
+	SuspendDomain(uuid string) error
+	// ResumeDomain continues the domain once the memory pressure ended.
+	ResumeDomain(uuid string) error
+}
+
+// Set the memory of the running domain with its balloon device. The guest
+// returns the memory above the target to the host, if its balloon driver
+// cooperates.
+func (l *LibVirt) BalloonDomain(uuid string, memoryKiB uint64) error {
+	domain, err := l.lookupDomain(uuid)
+	if err != nil {
+		return err
+	}
+	if err := l.virt.DomainSetMemoryFlags(domain, memoryKiB, uint32(libvirt.DomainAffectLive)); err != nil {
+		return fmt.Errorf("failed to balloon domain %s: %w", uuid, l.classifyDomainError(domain, err))
+	}
+	return nil
+}
+
+// Pause the vcpus of the domain.
+func (l *LibVirt) SuspendDomain(uuid string) error {
+	domain, err := l.lookupDomain(uuid)
+	if err != nil {
+		return err
+	}
+	if err := l.virt.DomainSuspend(domain); err != nil {
+		return fmt.Errorf("failed to suspend domain %s: %w", uuid, l.classifyDomainError(domain, err))
+	}
+	return nil
+}
+
+// Continue the paused domain.
+func (l *LibVirt) ResumeDomain(uuid string) error {
+	domain, err := l.lookupDomain(uuid)
+	if err != nil {
+		return err
+	}
+	if err := l.virt.DomainResume(domain); err != nil {
+		return fmt.Errorf("failed to resume domain %s: %w", uuid, l.classifyDomainError(domain, err))
+	}
+	return nil
+}
+
+// Save the memory of the domain to its managed save image and stop it.
+func (l *LibVirt) ManagedSaveDomain(uuid string) error {
+	domain, err := l.lookupDomain(uuid)
+	if err != nil {
+		return err
+	}
+	if err := l.virt.DomainManagedSave(domain, 0); err != nil {
+		return fmt.Errorf("failed to save domain %s: %w", uuid, l.classifyDomainError(domain, err))
+	}
+	return nil
+}
+
+// Start the domain from its managed save image. Libvirt would boot the
+// domain from scratch without one, which is refused.
+func (l *LibVirt) RestoreDomain(uuid string) error {
+	domain, err := l.lookupDomain(uuid)
+	if err != nil {
+		return err
+	}
+	saved, err := l.virt.DomainHasManagedSaveImage(domain, 0)
+	if err != nil {
+		return fmt.Errorf("failed to check managed save image of domain %s: %w", uuid, virterr.Classify(err))
+	}
+	if saved == 0 {
+		return fmt.Errorf("domain %s has no managed save image", uuid)
+	}
+	if err := l.virt.DomainCreate(domain); err != nil {
+		return fmt.Errorf("failed to restore domain %s: %w", uuid, virterr.Classify(err))
+	}
+	return nil
+}
+
+// Check if the inactive domain has a managed save image, which is restored
+// on its next start.
+func (l *LibVirt) hasManagedSave(uuid string) bool {
+	domain, err := l.lookupDomain(uuid)
+	if err != nil {
+		return false
+	}
+	saved, err := l.virt.DomainHasManagedSaveImage(domain, 0)
+	return err == nil && saved != 0
+}
@@ -0,0 +1,73 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package libvirt
+
+import (
+	"fmt"
+
+	v1 "github.com/cobaltcore-dev/openstack-hypervisor-operator/api/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// Annotations of the hypervisor reserving host cpus and memory for the
+// operating system and the agents, e.g. "4" and "16Gi". The reservation is
+// subtracted from the capacity, like the reserved_host_cpus and
+// reserved_host_memory_mb of nova.
+const (
+	ReservedCPUsAnnotation   = "kvm.cloud.sap/reserved-cpus"
+	ReservedMemoryAnnotation = "kvm.cloud.sap/reserved-memory"
+)
+
+// Parse the resources reserved by the annotations of the hypervisor.
+func reservedResources(annotations map[string]string) (map[v1.ResourceName]resource.Quantity, error) {
+	reserved := make(map[v1.ResourceName]resource.Quantity)
+	for resourceName, annotation := range map[v1.ResourceName]string{
+		v1.ResourceCPU:    ReservedCPUsAnnotation,
+		v1.ResourceMemory: ReservedMemoryAnnotation,
+	} {
+		value, ok := annotations[annotation]
+		if !ok {
+			continue
+		}
+		quantity, err := resource.ParseQuantity(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s annotation %q: %w", annotation, value, err)
+		}
+		if quantity.Sign() < 0 {
+			return nil, fmt.Errorf("invalid %s annotation %q: must not be negative", annotation, value)
+		}
+		reserved[resourceName] = quantity
+	}
+	return reserved, nil
+}
+
+// Subtract the reserved resources from the capacity, which doesn't drop
+// below zero.
+func subtractReserved(capacity, reserved map[v1.ResourceName]resource.Quantity) {
+	for resourceName, quantity := range reserved {
+		total, ok := capacity[resourceName]
+		if !ok {
+			continue
+		}
+		total.Sub(quantity)
+		if total.Sign() < 0 {
+			total = *resource.NewQuantity(0, total.Format)
+		}
+		capacity[resourceName] = total
+	}
+}
@@ -0,0 +1,125 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package libvirt
+
+import (
+	"testing"
+
+	v1 "github.com/cobaltcore-dev/openstack-hypervisor-operator/api/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/libvirt/capabilities"
+)
+
+func TestReservedResources(t *testing.T) {
+	reserved, err := reservedResources(map[string]string{
+		ReservedCPUsAnnotation:   "4",
+		ReservedMemoryAnnotation: "16Gi",
+	})
+	if err != nil {
+		t.Fatalf("reservedResources() returned unexpected error: %v", err)
+	}
+	cpus := reserved[v1.ResourceCPU]
+	memory := reserved[v1.ResourceMemory]
+	if cpus.Value() != 4 || memory.Value() != 16<<30 {
+		t.Errorf("Unexpected reservation %v", reserved)
+	}
+
+	for _, annotations := range []map[string]string{
+		{ReservedCPUsAnnotation: "four"},
+		{ReservedMemoryAnnotation: "-1Gi"},
+	} {
+		if _, err := reservedResources(annotations); err == nil {
+			t.Errorf("Expected %v to be invalid", annotations)
+		}
+	}
+}
+
+func TestSubtractReserved(t *testing.T) {
+	capacity := map[v1.ResourceName]resource.Quantity{
+		v1.ResourceMemory: *resource.NewQuantity(64<<30, resource.BinarySI),
+		v1.ResourceCPU:    *resource.NewQuantity(16, resource.DecimalSI),
+	}
+	subtractReserved(capacity, map[v1.ResourceName]resource.Quantity{
+		v1.ResourceMemory: resource.MustParse("16Gi"),
+		v1.ResourceCPU:    resource.MustParse("32"),
+	})
+	memory := capacity[v1.ResourceMemory]
+	if memory.String() != "48Gi" {
+		t.Errorf("Expected memory capacity 48Gi, got %s", memory.String())
+	}
+	cpus := capacity[v1.ResourceCPU]
+	if !cpus.IsZero() {
+		t.Errorf("Expected cpu capacity to stop at zero, got %s", cpus.String())
+	}
+}
+
+func TestAddAllocationCapacity_Reserved(t *testing.T) {
+	caps := capabilities.Capabilities{
+		Host: capabilities.CapabilitiesHost{
+			Topology: capabilities.CapabilitiesHostTopology{
+				CellSpec: capabilities.CapabilitiesHostTopologyCells{
+					Num: 1,
+					Cells: []capabilities.CapabilitiesHostTopologyCell{
+						{
+							ID: 0,
+							Memory: capabilities.CapabilitiesHostTopologyCellMemory{
+								Unit:  "GiB",
+								Value: 64,
+							},
+							CPUs: capabilities.CapabilitiesHostTopologyCellCPUs{
+								Num: 16,
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	l := &LibVirt{
+		capabilitiesClient: &mockCapabilitiesClient{caps: caps},
+		domainInfoClient:   &mockDomInfoClient{},
+	}
+
+	hv := v1.Hypervisor{}
+	hv.Annotations = map[string]string{
+		ReservedCPUsAnnotation:   "2",
+		ReservedMemoryAnnotation: "8Gi",
+	}
+	result, err := l.addAllocationCapacity(hv)
+	if err != nil {
+		t.Fatalf("addAllocationCapacity() returned unexpected error: %v", err)
+	}
+	memCap := result.Status.Capacity[v1.ResourceMemory]
+	if memCap.Value() != 56<<30 {
+		t.Errorf("Expected memory capacity 56Gi, got %s", memCap.String())
+	}
+	cpuCap := result.Status.Capacity[v1.ResourceCPU]
+	if cpuCap.Value() != 14 {
+		t.Errorf("Expected cpu capacity 14, got %s", cpuCap.String())
+	}
+	cellCap := result.Status.Cells[0].Capacity[v1.ResourceCPU]
+	if cellCap.Value() != 16 {
+		t.Errorf("Expected the cell capacity to be left as is, got %s", cellCap.String())
+	}
+
+	hv.Annotations[ReservedMemoryAnnotation] = "lots"
+	if _, err := l.addAllocationCapacity(hv); err == nil {
+		t.Errorf("Expected an invalid reservation to fail")
+	}
+}
@@ -0,0 +1,196 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package libvirt
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/digitalocean/go-libvirt"
+
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/libvirt/virterr"
+)
+
+// NovaDomain is a running domain created by nova.
+type NovaDomain struct {
+	UUID string
+	Name string
+	// Size of the flavor of the instance.
+	MemoryMiB int
+	VCPUs     int
+}
+
+// DomainShutdowner shuts down the domains created by nova, e.g. if they
+// can't be migrated off the host before it reboots.
+type DomainShutdowner interface {
+	// NovaDomains returns the running domains created by nova.
+	NovaDomains() ([]NovaDomain, error)
+	// ShutdownDomain shuts the domain down gracefully, and destroys it if
+	// it is still running after the grace period.
+	ShutdownDomain(uuid string, grace time.Duration) error
+}
+
+// DomainStopper stops single domains without waiting for them, for callers
+// that follow the shutdown of the domain themselves.
+type DomainStopper interface {
+	// RequestShutdown asks the guest of the domain to shut down. A domain
+	// that isn't running is not an error.
+	RequestShutdown(uuid string) error
+	// DestroyDomain powers the domain off immediately. A domain that isn't
+	// running is not an error.
+	DestroyDomain(uuid string) error
+}
+
+// Interval in which the state of a domain is checked while it shuts down.
+const shutdownPollInterval = time.Second
+
+// Get the running domains created by nova.
+func (l *LibVirt) NovaDomains() ([]NovaDomain, error) {
+	domains, err := l.domainInfoClient.Get(l.virt, libvirt.ConnectListDomainsActive)
+	if err != nil {
+		return nil, err
+	}
+	var novaDomains []NovaDomain
+	for _, info := range domains {
+		if info.Metadata == nil || info.Metadata.NovaInstance == nil {
+			continue
+		}
+		domain := NovaDomain{UUID: info.UUID, Name: info.Name}
+		if flavor := info.Metadata.NovaInstance.Flavor; flavor != nil {
+			domain.MemoryMiB = flavor.Memory
+			domain.VCPUs = flavor.VCPUs
+		}
+		novaDomains = append(novaDomains, domain)
+	}
+	return novaDomains, nil
+}
+
+// Look up the domain by its uuid. A domain that doesn't exist is reported
+// with virterr.ErrDomainNotFound.
+func (l *LibVirt) lookupDomain(uuid string) (libvirt.Domain, error) {
+	id, err := ParseUUID(uuid)
+	if err != nil {
+		return libvirt.Domain{}, err
+	}
+	domain, err := l.virt.DomainLookupByUUID(libvirt.UUID(id))
+	if err != nil {
+		return libvirt.Domain{}, fmt.Errorf("failed to lookup domain %s: %w", uuid, virterr.Classify(err))
+	}
+	return domain, nil
+}
+
+// Classify the error of an operation on the domain. Libvirt reports
+// operations which are invalid in the current state of the domain with
+// virterr.ErrOperationInvalid, for a domain that isn't running as well as
+// e.g. for a conflicting job. It is only reported with
+// virterr.ErrNotRunning if the state of the domain confirms it.
+func (l *LibVirt) classifyDomainError(domain libvirt.Domain, err error) error {
+	err = virterr.Classify(err)
+	if !errors.Is(err, virterr.ErrOperationInvalid) {
+		return err
+	}
+	state, _, stateErr := l.virt.DomainGetState(domain, 0)
+	if stateErr != nil || !notRunning(state) {
+		return err
+	}
+	return &virterr.Error{Sentinel: virterr.ErrNotRunning, Cause: err}
+}
+
+// Check if the domain in the state has no running process.
+func notRunning(state int32) bool {
+	switch libvirt.DomainState(state) {
+	case libvirt.DomainShutoff, libvirt.DomainCrashed:
+		return true
+	}
+	return false
+}
+
+// Ask the guest of the domain to shut down, with an acpi request or through
+// the guest agent, whichever libvirt picks.
+func (l *LibVirt) RequestShutdown(uuid string) error {
+	domain, err := l.lookupDomain(uuid)
+	if err != nil {
+		return err
+	}
+	if err := l.virt.DomainShutdown(domain); err != nil {
+		err = l.classifyDomainError(domain, err)
+		if errors.Is(err, virterr.ErrNotRunning) {
+			return nil
+		}
+		return fmt.Errorf("failed to shut down domain %s: %w", uuid, err)
+	}
+	return nil
+}
+
+// Power the domain off immediately.
+func (l *LibVirt) DestroyDomain(uuid string) error {
+	domain, err := l.lookupDomain(uuid)
+	if err != nil {
+		return err
+	}
+	if err := l.virt.DomainDestroy(domain); err != nil {
+		err = l.classifyDomainError(domain, err)
+		if errors.Is(err, virterr.ErrNotRunning) {
+			return nil
+		}
+		return fmt.Errorf("failed to destroy domain %s: %w", uuid, err)
+	}
+	return nil
+}
+
+// Shut the domain down with an acpi request to the guest, and destroy it if
+// the guest didn't power off after the grace period. A domain that doesn't
+// exist anymore is reported with virterr.ErrDomainNotFound.
+func (l *LibVirt) ShutdownDomain(uuid string, grace time.Duration) error {
+	domain, err := l.lookupDomain(uuid)
+	if err != nil {
+		return err
+	}
+	if err := l.virt.DomainShutdown(domain); err != nil {
+		err = l.classifyDomainError(domain, err)
+		if errors.Is(err, virterr.ErrNotRunning) {
+			return nil
+		}
+		return fmt.Errorf("failed to shut down domain %s: %w", uuid, err)
+	}
+
+	deadline := time.Now().Add(grace)
+	for time.Now().Before(deadline) {
+		state, _, err := l.virt.DomainGetState(domain, 0)
+		if err != nil {
+			if errors.Is(virterr.Classify(err), virterr.ErrDomainNotFound) {
+				// Transient domains are gone once shut down.
+				return nil
+			}
+			return fmt.Errorf("failed to get state of domain %s: %w", uuid, err)
+		}
+		if libvirt.DomainState(state) == libvirt.DomainShutoff {
+			return nil
+		}
+		time.Sleep(shutdownPollInterval)
+	}
+	if err := l.virt.DomainDestroy(domain); err != nil {
+		err = l.classifyDomainError(domain, err)
+		if errors.Is(err, virterr.ErrNotRunning) {
+			return nil
+		}
+		return fmt.Errorf("failed to destroy domain %s: %w", uuid, err)
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package libvirt
+
+import (
+	"testing"
+
+	"github.com/digitalocean/go-libvirt"
+)
+
+func TestNotRunning(t *testing.T) {
+	for _, tc := range []struct {
+		state libvirt.DomainState
+		want  bool
+	}{
+		{libvirt.DomainShutoff, true},
+		{libvirt.DomainCrashed, true},
+		{libvirt.DomainRunning, false},
+		{libvirt.DomainPaused, false},
+		{libvirt.DomainShutdown, false},
+		{libvirt.DomainPmsuspended, false},
+	} {
+		if got := notRunning(int32(tc.state)); got != tc.want {
+			t.Errorf("notRunning(%v) = %v, want %v", tc.state, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package libvirt
+
+import (
+	"slices"
+	"strconv"
+	"time"
+)
+
+// State of a libvirt connection for diagnostics, e.g. of stuck migrations.
+type State struct {
+	// Uri of the libvirt driver.
+	URI string `json:"uri"`
+	// Whether the rpc connection to libvirt is open.
+	Connected         bool   `json:"connected"`
+	Version           string `json:"version"`
+	HypervisorVersion string `json:"hypervisorVersion"`
+	// Health of the connection from the last probe.
+	Health string `json:"health"`
+	// Names of the domains whose outgoing migration is watched.
+	MigrationWatches []string `json:"migrationWatches"`
+	// Ids of the handlers of the domain events by the libvirt event id.
+	EventHandlers map[string][]string `json:"eventHandlers"`
+	// Uuids of the domains in the stats cache and when they were fetched.
+	CachedDomains  []string  `json:"cachedDomains"`
+	StatsFetchedAt time.Time `json:"statsFetchedAt"`
+	// Key of the cached domain capabilities, empty if none are cached.
+	DomainCapabilitiesKey string `json:"domainCapabilitiesKey,omitempty"`
+}
+
+// StateDumper dumps the internal state of the libvirt connections.
+type StateDumper interface {
+	// DumpState returns the state of each connection, the primary first.
+	DumpState() []State
+}
+
+// Dump the internal state of the connection.
+func (l *LibVirt) DumpState() []State {
+	state := State{
+		URI:               l.uri,
+		Connected:         l.virt.IsConnected(),
+		Version:           l.version,
+		HypervisorVersion: l.hypervisorVersion,
+		EventHandlers:     make(map[string][]string),
+	}
+
+	l.health.lock.Lock()
+	state.Health = l.health.health.String()
+	l.health.lock.Unlock()
+
+	l.migrationLock.Lock()
+	for name := range l.migrationJobs {
+		state.MigrationWatches = append(state.MigrationWatches, name)
+	}
+	l.migrationLock.Unlock()
+	slices.Sort(state.MigrationWatches)
+
+	l.domEventChangeHandlersLock.Lock()
+	for eventID, handlers := range l.domEventChangeHandlers {
+		id := strconv.Itoa(int(eventID))
+		for handlerID := range handlers {
+			state.EventHandlers[id] = append(state.EventHandlers[id], handlerID)
+		}
+		slices.Sort(state.EventHandlers[id])
+	}
+	l.domEventChangeHandlersLock.Unlock()
+
+	l.stats.lock.Lock()
+	for _, record := range l.stats.records {
+		state.CachedDomains = append(state.CachedDomains, GetOpenstackUUID(record.Dom))
+	}
+	state.StatsFetchedAt = l.stats.fetchedAt
+	l.stats.lock.Unlock()
+
+	l.domCaps.lock.Lock()
+	if l.domCaps.caps != nil {
+		state.DomainCapabilitiesKey = l.domCaps.key
+	}
+	l.domCaps.lock.Unlock()
+	return []State{state}
+}
+
+// Dump the internal state of all drivers.
+func (m *MultiLibVirt) DumpState() []State {
+	var states []State
+	for _, l := range m.drivers {
+		states = append(states, l.DumpState()...)
+	}
+	return states
+}
@@ -0,0 +1,78 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package libvirt
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/digitalocean/go-libvirt"
+	"github.com/digitalocean/go-libvirt/socket/dialers"
+)
+
+func TestDumpState(t *testing.T) {
+	fetchedAt := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	l := &LibVirt{
+		virt:    libvirt.NewWithDialer(dialers.NewLocal()),
+		uri:     "qemu:///system",
+		version: "10.0.0",
+		migrationJobs: map[string]context.CancelFunc{
+			"instance-2": func() {},
+			"instance-1": func() {},
+		},
+		domEventChangeHandlers: map[libvirt.DomainEventID]map[string]func(context.Context, any){
+			libvirt.DomainEventIDLifecycle: {
+				"migration-handler": nil,
+				"lifecycle-handler": nil,
+			},
+		},
+		stats: domainStatsCache{
+			records:   []libvirt.DomainStatsRecord{{Dom: libvirt.Domain{UUID: libvirt.UUID{1}}}},
+			fetchedAt: fetchedAt,
+		},
+	}
+
+	states := l.DumpState()
+	if len(states) != 1 {
+		t.Fatalf("Expected one state, got %d", len(states))
+	}
+	state := states[0]
+	if state.Connected {
+		t.Error("Expected the connection to be closed")
+	}
+	if state.URI != "qemu:///system" || state.Version != "10.0.0" {
+		t.Errorf("Unexpected connection %q %q", state.URI, state.Version)
+	}
+	if !reflect.DeepEqual(state.MigrationWatches, []string{"instance-1", "instance-2"}) {
+		t.Errorf("Unexpected migration watches %v", state.MigrationWatches)
+	}
+	if !reflect.DeepEqual(state.EventHandlers, map[string][]string{
+		"0": {"lifecycle-handler", "migration-handler"},
+	}) {
+		t.Errorf("Unexpected event handlers %v", state.EventHandlers)
+	}
+	if !reflect.DeepEqual(state.CachedDomains, []string{GetOpenstackUUID(libvirt.Domain{UUID: libvirt.UUID{1}})}) ||
+		!state.StatsFetchedAt.Equal(fetchedAt) {
+		t.Errorf("Unexpected stats cache %v %v", state.CachedDomains, state.StatsFetchedAt)
+	}
+	if state.DomainCapabilitiesKey != "" {
+		t.Errorf("Expected no cached domain capabilities, got %q", state.DomainCapabilitiesKey)
+	}
+}
@@ -0,0 +1,144 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package libvirt
+
+import (
+	"slices"
+
+	"github.com/cobaltcore-dev/kvm-node-agent/api/v1alpha1"
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/kernel"
+)
+
+// HostTopology provides the cpu topology of the host.
+type HostTopology interface {
+	// HostCPUs returns the ids of the host cpus by numa cell id.
+	HostCPUs() (map[uint64][]int, error)
+}
+
+// Get the ids of the online host cpus by numa cell id from the capabilities.
+func (l *LibVirt) HostCPUs() (map[uint64][]int, error) {
+	caps, err := l.capabilitiesClient.Get(l.virt)
+	if err != nil {
+		return nil, err
+	}
+	cells := make(map[uint64][]int)
+	for _, cell := range caps.Host.Topology.CellSpec.Cells {
+		cpus := make([]int, 0, len(cell.CPUs.CPUs))
+		for _, cpu := range cell.CPUs.CPUs {
+			if cpu.Online() {
+				cpus = append(cpus, cpu.ID)
+			}
+		}
+		cells[cell.ID] = cpus
+	}
+	return cells, nil
+}
+
+// HostCPUModel is the cpu of the host as detected by libvirt, which decides
+// whether domains can be live migrated between hosts.
+type HostCPUModel struct {
+	Model     string
+	Vendor    string
+	Microcode string
+	// Names of the cpu features in addition to the ones of the model,
+	// sorted.
+	Features []string
+	// Frequency of the time stamp counter in Hz, 0 if unknown. Guests
+	// with an invariant tsc can only be migrated to hosts with the same
+	// frequency, unless the target can scale the tsc of the guest.
+	TSCFrequency uint64
+	TSCScaling   bool
+}
+
+// HostCPUDescriber provides the cpu model of the host.
+type HostCPUDescriber interface {
+	// HostCPUModel returns the cpu model, vendor and features of the host.
+	HostCPUModel() (HostCPUModel, error)
+}
+
+// Get the cpu model of the host from the capabilities.
+func (l *LibVirt) HostCPUModel() (HostCPUModel, error) {
+	caps, err := l.capabilitiesClient.Get(l.virt)
+	if err != nil {
+		return HostCPUModel{}, err
+	}
+	cpu := caps.Host.CPU
+	model := HostCPUModel{
+		Model:     cpu.Model,
+		Vendor:    cpu.Vendor,
+		Microcode: cpu.Microcode.Version,
+	}
+	for _, feature := range cpu.Features {
+		model.Features = append(model.Features, feature.Name)
+	}
+	slices.Sort(model.Features)
+	if cpu.Counter != nil && cpu.Counter.Name == "tsc" {
+		model.TSCFrequency = cpu.Counter.Frequency
+		model.TSCScaling = cpu.Counter.Scaling == "yes"
+	}
+	return model, nil
+}
+
+// HostIOMMU provides whether libvirt can pass devices through to domains.
+type HostIOMMU interface {
+	// HostIOMMUSupported returns whether libvirt found an iommu on the host.
+	HostIOMMUSupported() (bool, error)
+}
+
+// Check the iommu support of the host in the capabilities.
+func (l *LibVirt) HostIOMMUSupported() (bool, error) {
+	caps, err := l.capabilitiesClient.Get(l.virt)
+	if err != nil {
+		return false, err
+	}
+	return caps.Host.IOMMU.Support == "yes", nil
+}
+
+// Invert the host cpus by numa cell id to the numa cell id by host cpu.
+func invertCells(cells map[uint64][]int) map[int]uint64 {
+	byCPU := make(map[int]uint64)
+	for cell, cpus := range cells {
+		for _, cpu := range cpus {
+			byCPU[cpu] = cell
+		}
+	}
+	return byCPU
+}
+
+// Add the host numa nodes of the cpuset of each pinned vcpu, sorted and
+// without duplicates. Cpus missing from the topology and unparsable
+// cpusets are skipped.
+func addPinnedNUMANodes(pinning *v1alpha1.InstancePinning, cellsByCPU map[int]uint64) {
+	if len(cellsByCPU) == 0 {
+		return
+	}
+	for i := range pinning.VCPUs {
+		cpus, err := kernel.ParseCPUList(pinning.VCPUs[i].CPUSet)
+		if err != nil {
+			continue
+		}
+		var nodes []int
+		for _, cpu := range cpus {
+			if cell, ok := cellsByCPU[cpu]; ok {
+				nodes = append(nodes, int(cell))
+			}
+		}
+		slices.Sort(nodes)
+		pinning.VCPUs[i].NUMANodes = slices.Compact(nodes)
+	}
+}
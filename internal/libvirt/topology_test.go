@@ -0,0 +1,116 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package libvirt
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/cobaltcore-dev/kvm-node-agent/api/v1alpha1"
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/libvirt/capabilities"
+)
+
+func TestHostCPUs(t *testing.T) {
+	cpus := func(ids ...int) capabilities.CapabilitiesHostTopologyCellCPUs {
+		result := capabilities.CapabilitiesHostTopologyCellCPUs{Num: int64(len(ids))}
+		for _, id := range ids {
+			result.CPUs = append(result.CPUs, capabilities.CapabilitiesHostTopologyCellCPU{ID: id, Siblings: strconv.Itoa(id)})
+		}
+		return result
+	}
+	caps := capabilities.Capabilities{}
+	caps.Host.Topology.CellSpec.Cells = []capabilities.CapabilitiesHostTopologyCell{
+		{ID: 0, CPUs: cpus(0, 1, 4, 5)},
+		{ID: 1, CPUs: cpus(2, 3, 6, 7)},
+	}
+	// Offline cpus are listed without topology.
+	caps.Host.Topology.CellSpec.Cells[1].CPUs.CPUs = append(caps.Host.Topology.CellSpec.Cells[1].CPUs.CPUs,
+		capabilities.CapabilitiesHostTopologyCellCPU{ID: 8})
+
+	l := &LibVirt{capabilitiesClient: &mockCapabilitiesClient{caps: caps}}
+	cells, err := l.HostCPUs()
+	if err != nil {
+		t.Fatalf("HostCPUs() returned unexpected error: %v", err)
+	}
+	expected := map[uint64][]int{0: {0, 1, 4, 5}, 1: {2, 3, 6, 7}}
+	if !reflect.DeepEqual(cells, expected) {
+		t.Errorf("Expected cells %v, got %v", expected, cells)
+	}
+
+	l = &LibVirt{capabilitiesClient: &mockCapabilitiesClient{err: errors.New("connection lost")}}
+	if _, err := l.HostCPUs(); err == nil {
+		t.Error("Expected error when the capabilities are not available")
+	}
+}
+
+func TestHostCPUModel(t *testing.T) {
+	caps := capabilities.Capabilities{}
+	caps.Host.CPU = capabilities.CapabilitiesHostCPU{
+		Arch:      "x86_64",
+		Model:     "EPYC-Milan",
+		Vendor:    "AMD",
+		Microcode: capabilities.CapabilitiesHostCPUMicrocode{Version: "167776721"},
+		Features: []capabilities.CapabilitiesHostCPUFeature{
+			{Name: "x2apic"}, {Name: "invtsc"}, {Name: "tsc-deadline"},
+		},
+		Counter: &capabilities.CapabilitiesHostCPUCounter{Name: "tsc", Frequency: 2994374000, Scaling: "yes"},
+	}
+
+	l := &LibVirt{capabilitiesClient: &mockCapabilitiesClient{caps: caps}}
+	model, err := l.HostCPUModel()
+	if err != nil {
+		t.Fatalf("HostCPUModel() returned unexpected error: %v", err)
+	}
+	expected := HostCPUModel{
+		Model:        "EPYC-Milan",
+		Vendor:       "AMD",
+		Microcode:    "167776721",
+		Features:     []string{"invtsc", "tsc-deadline", "x2apic"},
+		TSCFrequency: 2994374000,
+		TSCScaling:   true,
+	}
+	if !reflect.DeepEqual(model, expected) {
+		t.Errorf("Expected cpu model %+v, got %+v", expected, model)
+	}
+}
+
+func TestAddPinnedNUMANodes(t *testing.T) {
+	cellsByCPU := invertCells(map[uint64][]int{0: {0, 1, 4, 5}, 1: {2, 3, 6, 7}})
+	pinning := v1alpha1.InstancePinning{VCPUs: []v1alpha1.InstanceVCPUPin{
+		{VCPU: 0, CPUSet: "0-1"},
+		{VCPU: 1, CPUSet: "6,1,5"},
+		{VCPU: 2, CPUSet: "64"},
+		{VCPU: 3, CPUSet: "garbage"},
+	}}
+	addPinnedNUMANodes(&pinning, cellsByCPU)
+
+	expected := [][]int{{0}, {0, 1}, nil, nil}
+	for i, pin := range pinning.VCPUs {
+		if !reflect.DeepEqual(pin.NUMANodes, expected[i]) {
+			t.Errorf("Expected numa nodes %v for vcpu %d, got %v", expected[i], pin.VCPU, pin.NUMANodes)
+		}
+	}
+
+	pinning = v1alpha1.InstancePinning{VCPUs: []v1alpha1.InstanceVCPUPin{{VCPU: 0, CPUSet: "0"}}}
+	addPinnedNUMANodes(&pinning, nil)
+	if pinning.VCPUs[0].NUMANodes != nil {
+		t.Errorf("Expected no numa nodes without topology, got %v", pinning.VCPUs[0].NUMANodes)
+	}
+}
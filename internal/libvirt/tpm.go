@@ -0,0 +1,178 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package libvirt
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/cobaltcore-dev/kvm-node-agent/api/v1alpha1"
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/libvirt/dominfo"
+)
+
+// Directories of the state and the pid files of swtpm, which emulates the
+// tpm of domains. The swtpm processes are looked up in the process table of
+// the host, the agent runs in the host pid namespace.
+var (
+	swtpmStatePath = "/var/lib/libvirt/swtpm"
+	swtpmRunPath   = "/run/libvirt/qemu/swtpm"
+	procPath       = "/proc"
+)
+
+// Get the host directory the state of the emulated tpm of the domain is kept
+// in, unless overridden in the domain definition below the swtpm directory
+// named after the domain uuid.
+func tpmStateDir(domain dominfo.DomainInfo, backend *dominfo.DomainTPMBackend) string {
+	if backend.Source != nil && backend.Source.Path != "" {
+		return backend.Source.Path
+	}
+	if backend.Version == "1.2" {
+		return filepath.Join(swtpmStatePath, domain.UUID, "tpm1.2")
+	}
+	return filepath.Join(swtpmStatePath, domain.UUID, "tpm2")
+}
+
+// Sum up the size of the files below the directory. Returns false if the
+// directory doesn't exist.
+func dirBytes(dir string) (int64, bool, error) {
+	var total int64
+	err := filepath.WalkDir(dir, func(_ string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.Type().IsRegular() {
+			info, err := entry.Info()
+			if err != nil {
+				return err
+			}
+			total += info.Size()
+		}
+		return nil
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	return total, true, nil
+}
+
+// Check if the swtpm process of the active domain is running. Libvirt writes
+// the pid of swtpm to a file named after the short name of the domain, see
+// qemuTPMEmulatorPidFileBuildPath. Returns nil if the pid files are not
+// available to the agent.
+func swtpmRunning(domain dominfo.DomainInfo) *bool {
+	if _, err := os.Stat(swtpmRunPath); err != nil {
+		return nil
+	}
+	running := false
+	name := strings.TrimPrefix(qemuStateDirName(domain), "domain-") + "-swtpm.pid"
+	data, err := os.ReadFile(filepath.Join(swtpmRunPath, name))
+	if err != nil {
+		return &running
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return &running
+	}
+	comm, err := os.ReadFile(filepath.Join(procPath, strconv.Itoa(pid), "comm"))
+	running = err == nil && strings.TrimSpace(string(comm)) == "swtpm"
+	return &running
+}
+
+// Build the tpm status of the domain, nil if it has no tpm. The state and
+// the swtpm process are only checked for emulated tpms.
+func instanceTPM(domain dominfo.DomainInfo, active bool) (*v1alpha1.InstanceTPM, error) {
+	if domain.Devices == nil || len(domain.Devices.TPMs) == 0 {
+		return nil, nil
+	}
+	tpm := domain.Devices.TPMs[0]
+	status := &v1alpha1.InstanceTPM{Model: tpm.Model}
+	backend := tpm.Backend
+	if backend == nil {
+		return status, nil
+	}
+	status.Backend = backend.Type
+	status.Version = backend.Version
+	if backend.Type != "emulator" {
+		return status, nil
+	}
+	status.Encrypted = backend.Encryption != nil
+	status.PersistentState = backend.PersistentState == "yes"
+	if active {
+		status.Running = swtpmRunning(domain)
+	}
+	// The libvirt directory is only mounted into the agent with the host
+	// storage, the state is unknown without it.
+	if _, err := os.Stat(filepath.Dir(swtpmStatePath)); err != nil {
+		return status, nil
+	}
+	status.StatePath = tpmStateDir(domain, backend)
+	size, found, err := dirBytes(status.StatePath)
+	if err != nil {
+		return status, fmt.Errorf("failed to read tpm state of domain %s: %w", domain.UUID, err)
+	}
+	status.StateBytes = size
+	status.StatePresent = found
+	return status, nil
+}
+
+// Get the reason the tpm of the domain prevents its live migration, nil if
+// it doesn't. The state of an emulated tpm is migrated along with the
+// domain, so it needs to be there.
+func tpmMigrationBlocker(tpm *v1alpha1.InstanceTPM) *v1alpha1.InstanceMigrationBlocker {
+	if tpm == nil || tpm.Backend != "emulator" {
+		return nil
+	}
+	if tpm.StatePath != "" && !tpm.StatePresent {
+		return &v1alpha1.InstanceMigrationBlocker{
+			Reason:  BlockerTPMState,
+			Message: "state of the emulated tpm is missing in " + tpm.StatePath,
+		}
+	}
+	if tpm.Running != nil && !*tpm.Running {
+		return &v1alpha1.InstanceMigrationBlocker{
+			Reason:  BlockerTPMState,
+			Message: "swtpm of the emulated tpm is not running",
+		}
+	}
+	return nil
+}
+
+// Export the size of the tpm state and the health of swtpm of the domains.
+func updateTPMMetrics(statuses map[string]v1alpha1.InstanceStatus) {
+	for uuid, status := range statuses {
+		if status.TPM == nil || status.TPM.Backend != "emulator" {
+			continue
+		}
+		tpmStateBytes.WithLabelValues(uuid).Set(float64(status.TPM.StateBytes))
+		if running := status.TPM.Running; running != nil {
+			up := 0.0
+			if *running {
+				up = 1
+			}
+			swtpmUp.WithLabelValues(uuid).Set(up)
+		}
+	}
+}
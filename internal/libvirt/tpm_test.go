@@ -0,0 +1,174 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package libvirt
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cobaltcore-dev/kvm-node-agent/api/v1alpha1"
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/libvirt/dominfo"
+)
+
+// Point the swtpm directories to a temporary directory for the test.
+func withSwtpmPaths(t *testing.T) string {
+	dir := t.TempDir()
+	oldState, oldRun, oldProc := swtpmStatePath, swtpmRunPath, procPath
+	swtpmStatePath = filepath.Join(dir, "lib", "swtpm")
+	swtpmRunPath = filepath.Join(dir, "run")
+	procPath = filepath.Join(dir, "proc")
+	t.Cleanup(func() {
+		swtpmStatePath, swtpmRunPath, procPath = oldState, oldRun, oldProc
+	})
+	for _, path := range []string{filepath.Dir(swtpmStatePath), swtpmRunPath, filepath.Join(procPath, "42")} {
+		if err := os.MkdirAll(path, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func tpmDomain(backend *dominfo.DomainTPMBackend) dominfo.DomainInfo {
+	return dominfo.DomainInfo{
+		ID:      "3",
+		Name:    "instance-00000001",
+		UUID:    "0a1b2c3d-0000-0000-0000-000000000001",
+		Devices: &dominfo.DomainDevices{TPMs: []dominfo.DomainTPM{{Model: "tpm-crb", Backend: backend}}},
+	}
+}
+
+func TestInstanceTPM_NoTPM(t *testing.T) {
+	tpm, err := instanceTPM(dominfo.DomainInfo{Devices: &dominfo.DomainDevices{}}, true)
+	if err != nil || tpm != nil {
+		t.Errorf("Expected no tpm, got %+v, %v", tpm, err)
+	}
+}
+
+func TestInstanceTPM_Emulator(t *testing.T) {
+	withSwtpmPaths(t)
+	domain := tpmDomain(&dominfo.DomainTPMBackend{
+		Type:            "emulator",
+		Version:         "2.0",
+		PersistentState: "yes",
+		Encryption:      &dominfo.DomainTPMEncryption{Secret: "6dd3e4a5-1d76-44ce-961f-f119f5aad935"},
+	})
+
+	tpm, err := instanceTPM(domain, true)
+	if err != nil {
+		t.Fatalf("Failed to get tpm: %v", err)
+	}
+	statePath := filepath.Join(swtpmStatePath, domain.UUID, "tpm2")
+	if tpm.StatePath != statePath || tpm.StatePresent || !tpm.Encrypted || !tpm.PersistentState {
+		t.Errorf("Unexpected tpm %+v", tpm)
+	}
+	if tpm.Running == nil || *tpm.Running {
+		t.Errorf("Expected swtpm without pid file not to be running, got %v", tpm.Running)
+	}
+	if blocker := tpmMigrationBlocker(tpm); blocker == nil || blocker.Reason != BlockerTPMState {
+		t.Errorf("Expected tpm state blocker, got %+v", blocker)
+	}
+
+	if err := os.MkdirAll(statePath, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(statePath, "tpm2-00.permall"), make([]byte, 1024), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	pidFile := filepath.Join(swtpmRunPath, "3-instance-00000001-swtpm.pid")
+	if err := os.WriteFile(pidFile, []byte("42\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(procPath, "42", "comm"), []byte("swtpm\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	tpm, err = instanceTPM(domain, true)
+	if err != nil {
+		t.Fatalf("Failed to get tpm: %v", err)
+	}
+	if !tpm.StatePresent || tpm.StateBytes != 1024 {
+		t.Errorf("Expected 1024 bytes of tpm state, got %+v", tpm)
+	}
+	if tpm.Running == nil || !*tpm.Running {
+		t.Errorf("Expected swtpm to be running, got %v", tpm.Running)
+	}
+	if blocker := tpmMigrationBlocker(tpm); blocker != nil {
+		t.Errorf("Expected no blocker, got %+v", blocker)
+	}
+
+	// The pid of a crashed swtpm may be reused by another process.
+	if err := os.WriteFile(filepath.Join(procPath, "42", "comm"), []byte("bash\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	tpm, _ = instanceTPM(domain, true)
+	if tpm.Running == nil || *tpm.Running {
+		t.Errorf("Expected swtpm not to be running, got %v", tpm.Running)
+	}
+
+	// Inactive domains have no swtpm.
+	tpm, _ = instanceTPM(domain, false)
+	if tpm.Running != nil {
+		t.Errorf("Expected unknown swtpm state of inactive domain, got %v", *tpm.Running)
+	}
+}
+
+func TestInstanceTPM_StateSource(t *testing.T) {
+	dir := withSwtpmPaths(t)
+	source := filepath.Join(dir, "shared", "tpm")
+	domain := tpmDomain(&dominfo.DomainTPMBackend{
+		Type:   "emulator",
+		Source: &dominfo.DomainTPMSource{Type: "dir", Path: source},
+	})
+	tpm, err := instanceTPM(domain, false)
+	if err != nil {
+		t.Fatalf("Failed to get tpm: %v", err)
+	}
+	if tpm.StatePath != source {
+		t.Errorf("Expected state path %s, got %s", source, tpm.StatePath)
+	}
+}
+
+func TestInstanceTPM_StateUnknown(t *testing.T) {
+	dir := withSwtpmPaths(t)
+	swtpmStatePath = filepath.Join(dir, "unmounted", "swtpm")
+	swtpmRunPath = filepath.Join(dir, "unmounted", "run")
+	tpm, err := instanceTPM(tpmDomain(&dominfo.DomainTPMBackend{Type: "emulator"}), true)
+	if err != nil {
+		t.Fatalf("Failed to get tpm: %v", err)
+	}
+	if tpm.StatePath != "" || tpm.Running != nil {
+		t.Errorf("Expected unknown tpm state, got %+v", tpm)
+	}
+	if blocker := tpmMigrationBlocker(tpm); blocker != nil {
+		t.Errorf("Expected no blocker for unknown state, got %+v", blocker)
+	}
+}
+
+func TestTPMMigrationBlocker_Passthrough(t *testing.T) {
+	tpm := &v1alpha1.InstanceTPM{Backend: "passthrough"}
+	if blocker := tpmMigrationBlocker(tpm); blocker != nil {
+		t.Errorf("Expected passthrough tpm to be reported as passthrough device, got %+v", blocker)
+	}
+	domain := tpmDomain(&dominfo.DomainTPMBackend{
+		Type:   "passthrough",
+		Device: &dominfo.DomainTPMDevice{Path: "/dev/tpm0"},
+	})
+	if reasons := blockerReasons(domain, 0); len(reasons) != 1 || reasons[0] != BlockerPassthroughDevice {
+		t.Errorf("Expected passthrough device blocker, got %v", reasons)
+	}
+}
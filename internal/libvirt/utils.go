@@ -20,6 +20,7 @@ package libvirt
 import (
 	"encoding/hex"
 	"fmt"
+	"strings"
 
 	"k8s.io/apimachinery/pkg/api/resource"
 )
@@ -47,6 +48,20 @@ func (uuid UUID) String() string {
 	return string(tmp[:])
 }
 
+// Parse the textual representation of a UUID, e.g. as used by openstack
+// for the server id, into its binary form.
+func ParseUUID(s string) (UUID, error) {
+	var uuid UUID
+	raw := strings.ReplaceAll(s, "-", "")
+	if hex.DecodedLen(len(raw)) != len(uuid) {
+		return uuid, fmt.Errorf("invalid uuid %q", s)
+	}
+	if _, err := hex.Decode(uuid[:], []byte(raw)); err != nil {
+		return uuid, fmt.Errorf("invalid uuid %q: %w", s, err)
+	}
+	return uuid, nil
+}
+
 func ByteCountIEC(b uint64) string {
 	const unit = 1024
 	if b < unit {
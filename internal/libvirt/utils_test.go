@@ -420,3 +420,20 @@ func TestMemoryToResourceNegativeValues(t *testing.T) {
 		})
 	}
 }
+
+func TestParseUUID(t *testing.T) {
+	const id = "0a1b2c3d-4e5f-6071-8293-a4b5c6d7e8f9"
+	uuid, err := ParseUUID(id)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if uuid.String() != id {
+		t.Errorf("Expected round trip to %s, got %s", id, uuid.String())
+	}
+
+	for _, invalid := range []string{"", "not-a-uuid", "0a1b2c3d-4e5f-6071-8293", "zz1b2c3d-4e5f-6071-8293-a4b5c6d7e8f9"} {
+		if _, err := ParseUUID(invalid); err == nil {
+			t.Errorf("Expected error for invalid uuid %q", invalid)
+		}
+	}
+}
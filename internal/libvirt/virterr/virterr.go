@@ -0,0 +1,79 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package virterr classifies the errors returned by libvirt by their error
+// code, so that callers don't depend on the wording of the messages.
+package virterr
+
+import (
+	"errors"
+
+	"github.com/digitalocean/go-libvirt"
+)
+
+var (
+	// The domain doesn't exist (anymore), e.g. it was undefined or
+	// migrated away concurrently.
+	ErrDomainNotFound = errors.New("domain not found")
+	// The operation isn't valid in the current state of the domain, which
+	// libvirt reports e.g. for a domain that is not running, but also for
+	// conflicting jobs or unsupported flags.
+	ErrOperationInvalid = errors.New("operation invalid")
+	// The domain is not running. It isn't classified by an error code, the
+	// callers report it after checking the state of the domain.
+	ErrNotRunning = errors.New("domain is not running")
+	// The operation or the guest agent didn't respond in time.
+	ErrTimeout = errors.New("libvirt operation timed out")
+)
+
+// Sentinel errors by libvirt error code.
+var sentinels = map[libvirt.ErrorNumber]error{
+	libvirt.ErrNoDomain:          ErrDomainNotFound,
+	libvirt.ErrOperationInvalid:  ErrOperationInvalid,
+	libvirt.ErrOperationTimeout:  ErrTimeout,
+	libvirt.ErrAgentUnresponsive: ErrTimeout,
+}
+
+// Error is a libvirt error matching one of the sentinel errors. Both the
+// sentinel and the original libvirt.Error can be checked with errors.Is
+// and errors.As.
+type Error struct {
+	Sentinel error
+	Cause    error
+}
+
+func (e *Error) Error() string {
+	return e.Cause.Error()
+}
+
+func (e *Error) Unwrap() []error {
+	return []error{e.Sentinel, e.Cause}
+}
+
+// Classify the error by the code of the libvirt error it wraps. Errors
+// without a known code are returned unchanged.
+func Classify(err error) error {
+	var virtErr libvirt.Error
+	if !errors.As(err, &virtErr) {
+		return err
+	}
+	sentinel, ok := sentinels[libvirt.ErrorNumber(virtErr.Code)]
+	if !ok {
+		return err
+	}
+	return &Error{Sentinel: sentinel, Cause: err}
+}
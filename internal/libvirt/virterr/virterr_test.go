@@ -0,0 +1,71 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package virterr
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/digitalocean/go-libvirt"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		code libvirt.ErrorNumber
+		want error
+	}{
+		{libvirt.ErrNoDomain, ErrDomainNotFound},
+		{libvirt.ErrOperationInvalid, ErrOperationInvalid},
+		{libvirt.ErrOperationTimeout, ErrTimeout},
+		{libvirt.ErrAgentUnresponsive, ErrTimeout},
+	}
+	for _, tt := range tests {
+		cause := fmt.Errorf("failed to get job stats: %w",
+			libvirt.Error{Code: uint32(tt.code), Message: "some message"})
+		err := Classify(cause)
+		if !errors.Is(err, tt.want) {
+			t.Errorf("Expected error code %d to be %v, got %v", tt.code, tt.want, err)
+		}
+		var virtErr libvirt.Error
+		if !errors.As(err, &virtErr) || virtErr.Code != uint32(tt.code) {
+			t.Errorf("Expected the libvirt error to be kept, got %v", err)
+		}
+		if err.Error() != cause.Error() {
+			t.Errorf("Expected the message to be kept, got %q", err.Error())
+		}
+	}
+}
+
+func TestClassifyUnknown(t *testing.T) {
+	if Classify(nil) != nil {
+		t.Errorf("Expected nil to stay nil")
+	}
+	cause := libvirt.Error{Code: uint32(libvirt.ErrInternalError), Message: "internal error"}
+	if err := Classify(cause); err != error(cause) {
+		t.Errorf("Expected unknown codes to be returned unchanged, got %v", err)
+	}
+	cause2 := errors.New("domain is not running")
+	if err := Classify(cause2); errors.Is(err, ErrNotRunning) {
+		t.Errorf("Expected errors to be classified by code only")
+	}
+	cause3 := libvirt.Error{Code: uint32(libvirt.ErrOperationInvalid), Message: "domain is not running"}
+	if err := Classify(cause3); errors.Is(err, ErrNotRunning) {
+		t.Errorf("Expected invalid operations not to be classified as not running")
+	}
+}
@@ -0,0 +1,133 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package libvirt
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/digitalocean/go-libvirt"
+	"github.com/prometheus/client_golang/prometheus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logger "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/cobaltcore-dev/kvm-node-agent/api/v1alpha1"
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/sys"
+)
+
+// Get the action taken on a watchdog expiration from the watchdog event.
+func watchdogAction(action int32) string {
+	switch libvirt.DomainEventWatchdogAction(action) {
+	case libvirt.DomainEventWatchdogNone:
+		return "none"
+	case libvirt.DomainEventWatchdogPause:
+		return "pause"
+	case libvirt.DomainEventWatchdogReset:
+		return "reset"
+	case libvirt.DomainEventWatchdogPoweroff:
+		return "poweroff"
+	case libvirt.DomainEventWatchdogShutdown:
+		return "shutdown"
+	case libvirt.DomainEventWatchdogDebug:
+		return "debug"
+	case libvirt.DomainEventWatchdogInjectnmi:
+		return "inject-nmi"
+	}
+	return "unknown"
+}
+
+// Records the expirations of the watchdogs of the domains, by domain uuid.
+// The zero value is ready to use.
+type watchdogTracker struct {
+	lock      sync.Mutex
+	watchdogs map[string]*v1alpha1.InstanceWatchdog
+}
+
+// Record that the watchdog of the domain expired.
+func (t *watchdogTracker) expired(uuid, action string, at time.Time) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if t.watchdogs == nil {
+		t.watchdogs = make(map[string]*v1alpha1.InstanceWatchdog)
+	}
+	watchdog, ok := t.watchdogs[uuid]
+	if !ok {
+		watchdog = &v1alpha1.InstanceWatchdog{}
+		t.watchdogs[uuid] = watchdog
+	}
+	expired := metav1.NewTime(at)
+	watchdog.Expirations++
+	watchdog.LastExpired = &expired
+	watchdog.LastAction = action
+	domainWatchdogExpirations.WithLabelValues(uuid, action).Inc()
+}
+
+// Forget the watchdog expirations of an undefined domain.
+func (t *watchdogTracker) forget(uuid string) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	delete(t.watchdogs, uuid)
+	domainWatchdogExpirations.DeletePartialMatch(prometheus.Labels{"domain": uuid})
+}
+
+// Continue with the expirations recorded in the status of the instance,
+// e.g. after a restart of the agent. Expirations observed since are kept.
+func (t *watchdogTracker) seed(uuid string, watchdog *v1alpha1.InstanceWatchdog) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if _, ok := t.watchdogs[uuid]; ok || watchdog == nil {
+		return
+	}
+	if t.watchdogs == nil {
+		t.watchdogs = make(map[string]*v1alpha1.InstanceWatchdog)
+	}
+	t.watchdogs[uuid] = watchdog.DeepCopy()
+}
+
+// Get a copy of the watchdog expirations of the domain, nil if its
+// watchdog never expired.
+func (t *watchdogTracker) status(uuid string) *v1alpha1.InstanceWatchdog {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	watchdog, ok := t.watchdogs[uuid]
+	if !ok {
+		return nil
+	}
+	return watchdog.DeepCopy()
+}
+
+// Record the expiration of the watchdog of a domain and report it in an
+// event of its instance.
+func (l *LibVirt) onWatchdog(ctx context.Context, event any) {
+	e := event.(*libvirt.DomainEventCallbackWatchdogMsg)
+	uuid := GetOpenstackUUID(e.Msg.Dom)
+	action := watchdogAction(e.Msg.Action)
+	log := logger.FromContext(ctx).WithValues("server", uuid)
+	log.Info("domain watchdog expired", "action", action)
+	l.watchdogs.expired(uuid, action, time.Now())
+
+	var instance v1alpha1.Instance
+	if err := l.client.Get(ctx, client.ObjectKey{Name: uuid, Namespace: sys.Namespace}, &instance); err != nil {
+		log.Error(err, "failed to get instance of domain with expired watchdog")
+		return
+	}
+	l.emitConsoleLogEvent(ctx, &instance, e.Msg.Dom, "WatchdogExpired", "Watchdog",
+		"watchdog expired, action "+action)
+}
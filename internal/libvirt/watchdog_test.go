@@ -0,0 +1,72 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package libvirt
+
+import (
+	"testing"
+	"time"
+
+	"github.com/digitalocean/go-libvirt"
+
+	"github.com/cobaltcore-dev/kvm-node-agent/api/v1alpha1"
+)
+
+func TestWatchdogAction(t *testing.T) {
+	tests := map[libvirt.DomainEventWatchdogAction]string{
+		libvirt.DomainEventWatchdogReset:     "reset",
+		libvirt.DomainEventWatchdogPoweroff:  "poweroff",
+		libvirt.DomainEventWatchdogInjectnmi: "inject-nmi",
+		99:                                   "unknown",
+	}
+	for action, expected := range tests {
+		if got := watchdogAction(int32(action)); got != expected {
+			t.Errorf("Expected action %q for %d, got %q", expected, action, got)
+		}
+	}
+}
+
+func TestWatchdogTracker(t *testing.T) {
+	var tracker watchdogTracker
+	start := time.Now()
+
+	if watchdog := tracker.status("a"); watchdog != nil {
+		t.Errorf("Expected no expirations of unknown domain, got %+v", watchdog)
+	}
+	tracker.expired("a", "reset", start)
+	tracker.expired("a", "poweroff", start.Add(time.Minute))
+	watchdog := tracker.status("a")
+	if watchdog == nil || watchdog.Expirations != 2 || watchdog.LastAction != "poweroff" ||
+		!watchdog.LastExpired.Time.Equal(start.Add(time.Minute)) {
+		t.Errorf("Unexpected expirations %+v", watchdog)
+	}
+
+	// Expirations observed since the start of the agent win.
+	tracker.seed("a", &v1alpha1.InstanceWatchdog{Expirations: 42})
+	if watchdog := tracker.status("a"); watchdog.Expirations != 2 {
+		t.Errorf("Expected the observed expirations to be kept, got %d", watchdog.Expirations)
+	}
+	tracker.seed("b", &v1alpha1.InstanceWatchdog{Expirations: 42})
+	if watchdog := tracker.status("b"); watchdog == nil || watchdog.Expirations != 42 {
+		t.Errorf("Expected the recorded expirations, got %+v", watchdog)
+	}
+
+	tracker.forget("a")
+	if watchdog := tracker.status("a"); watchdog != nil {
+		t.Errorf("Expected the expirations to be forgotten, got %+v", watchdog)
+	}
+}
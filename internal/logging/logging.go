@@ -0,0 +1,127 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package logging filters the log of the agent by the level of the
+// subsystem that logs, so that e.g. the migrations can be debugged on a
+// busy host without the debug log of everything else.
+package logging
+
+import (
+	"strings"
+	"sync"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// Subsystems with their own log level, used as the name of their loggers.
+const (
+	Libvirt      = "libvirt"
+	Migration    = "migration"
+	Systemd      = "systemd"
+	Certificates = "certificates"
+)
+
+// Subsystems lists the subsystems with their own log level.
+var Subsystems = []string{Libvirt, Migration, Systemd, Certificates}
+
+// Levels of the log, globally and per subsystem. The subsystem of an entry
+// is the innermost name of its logger with a level, e.g. migration for
+// libvirt.migration.
+type Levels struct {
+	mu         sync.RWMutex
+	global     zapcore.Level
+	subsystems map[string]zapcore.Level
+}
+
+// NewLevels returns the levels with the global level and none for the
+// subsystems.
+func NewLevels(global zapcore.Level) *Levels {
+	return &Levels{global: global}
+}
+
+// Set the global level and the levels of the subsystems, subsystems
+// without level log with the global one.
+func (l *Levels) Set(global zapcore.Level, subsystems map[string]zapcore.Level) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.global = global
+	l.subsystems = subsystems
+}
+
+// Global returns the global level.
+func (l *Levels) Global() zapcore.Level {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.global
+}
+
+// Enabled implements zapcore.LevelEnabler. The level is enabled if it is
+// enabled globally or for any subsystem, the entries are filtered by their
+// logger in the core returned by WrapCore.
+func (l *Levels) Enabled(level zapcore.Level) bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if l.global.Enabled(level) {
+		return true
+	}
+	for _, subsystem := range l.subsystems {
+		if subsystem.Enabled(level) {
+			return true
+		}
+	}
+	return false
+}
+
+// Check if the level is enabled for the logger with the name.
+func (l *Levels) enabledFor(name string, level zapcore.Level) bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	for len(l.subsystems) > 0 && name != "" {
+		segment := name
+		if i := strings.LastIndexByte(name, '.'); i >= 0 {
+			segment, name = name[i+1:], name[:i]
+		} else {
+			name = ""
+		}
+		if subsystem, ok := l.subsystems[segment]; ok {
+			return subsystem.Enabled(level)
+		}
+	}
+	return l.global.Enabled(level)
+}
+
+// WrapCore returns the core filtering the entries by the level of their
+// subsystem, e.g. for zap.WrapCore. The core must be enabled by the levels.
+func (l *Levels) WrapCore(core zapcore.Core) zapcore.Core {
+	return &filterCore{Core: core, levels: l}
+}
+
+type filterCore struct {
+	zapcore.Core
+	levels *Levels
+}
+
+func (c *filterCore) With(fields []zapcore.Field) zapcore.Core {
+	return &filterCore{Core: c.Core.With(fields), levels: c.levels}
+}
+
+func (c *filterCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.levels.enabledFor(entry.LoggerName, entry.Level) {
+		return checked
+	}
+	return c.Core.Check(entry, checked)
+}
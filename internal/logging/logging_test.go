@@ -0,0 +1,68 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package logging
+
+import (
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestLevels(t *testing.T) {
+	levels := NewLevels(zapcore.InfoLevel)
+	observed, logs := observer.New(levels)
+	log := zap.New(observed, zap.WrapCore(levels.WrapCore))
+
+	log.Named(Libvirt).Debug("hidden")
+	levels.Set(zapcore.InfoLevel, map[string]zapcore.Level{Migration: zapcore.DebugLevel})
+	log.Named(Libvirt).Debug("still hidden")
+	log.Named(Libvirt).Named(Migration).Debug("migration debug")
+	log.Named(Migration).With(zap.String("server", "a")).Debug("migration debug with fields")
+	log.Named(Systemd).Info("systemd info")
+	log.Debug("root debug")
+
+	var messages []string
+	for _, entry := range logs.All() {
+		messages = append(messages, entry.Message)
+	}
+	expected := []string{"migration debug", "migration debug with fields", "systemd info"}
+	if len(messages) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, messages)
+	}
+	for i := range expected {
+		if messages[i] != expected[i] {
+			t.Errorf("Expected %v, got %v", expected, messages)
+		}
+	}
+}
+
+func TestLevelsQuieterSubsystem(t *testing.T) {
+	levels := NewLevels(zapcore.DebugLevel)
+	levels.Set(zapcore.DebugLevel, map[string]zapcore.Level{Libvirt: zapcore.ErrorLevel, Migration: zapcore.DebugLevel})
+	observed, logs := observer.New(levels)
+	log := zap.New(observed, zap.WrapCore(levels.WrapCore))
+
+	log.Named(Libvirt).Info("hidden")
+	log.Named(Libvirt).Named(Migration).Debug("innermost subsystem wins")
+	log.Named(Certificates).Debug("global debug")
+	if logs.Len() != 2 {
+		t.Errorf("Expected 2 entries, got %v", logs.All())
+	}
+}
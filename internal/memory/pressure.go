@@ -0,0 +1,196 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package memory reads the memory pressure of the host, to reclaim memory
+// from the domains before the kernel kills them.
+package memory
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Pressure is the memory pressure of the host.
+type Pressure struct {
+	// Share of the time in percent in which some tasks stalled on memory,
+	// averaged over the last 10 seconds.
+	SomeAvg10 float64
+	// Share of the time in percent in which all tasks stalled on memory,
+	// averaged over the last 10 seconds.
+	FullAvg10 float64
+	// Memory of the host and the memory available for new allocations.
+	TotalKiB     int64
+	AvailableKiB int64
+}
+
+// Share of the memory available for new allocations.
+func (p Pressure) Available() float64 {
+	if p.TotalKiB <= 0 {
+		return 0
+	}
+	return float64(p.AvailableKiB) / float64(p.TotalKiB)
+}
+
+// Summary of the pressure for humans, e.g. for condition messages.
+func (p Pressure) String() string {
+	return fmt.Sprintf("%.0f%% available (%d of %d MiB), some %.1f%%, full %.1f%%",
+		p.Available()*100, p.AvailableKiB>>10, p.TotalKiB>>10, p.SomeAvg10, p.FullAvg10)
+}
+
+// Thresholds from which the memory pressure of the host is critical.
+type Thresholds struct {
+	// Share of the time in percent in which some tasks stalled on memory,
+	// 0 disables the threshold.
+	SomeAvg10 float64
+	// Share of the memory below which too little is available, 0 disables
+	// the threshold.
+	MinAvailable float64
+}
+
+// Check if the pressure crossed any of the thresholds.
+func (p Pressure) Critical(t Thresholds) bool {
+	return (t.SomeAvg10 > 0 && p.SomeAvg10 >= t.SomeAvg10) ||
+		(t.MinAvailable > 0 && p.Available() < t.MinAvailable)
+}
+
+// Interface provides the memory pressure of the host.
+type Interface interface {
+	// ReadPressure reads the current memory pressure of the host.
+	ReadPressure() (*Pressure, error)
+}
+
+// SystemReader reads the memory pressure from the system files.
+type SystemReader struct {
+	pressurePath string
+	meminfoPath  string
+}
+
+// NewSystemReader creates a new SystemReader with the default system paths.
+func NewSystemReader() *SystemReader {
+	return &SystemReader{
+		pressurePath: "/proc/pressure/memory",
+		meminfoPath:  "/proc/meminfo",
+	}
+}
+
+// ReadPressure reads the current memory pressure of the host.
+func (r *SystemReader) ReadPressure() (*Pressure, error) {
+	var p Pressure
+	dat, err := os.ReadFile(r.pressurePath)
+	if err != nil {
+		return nil, err
+	}
+	if p.SomeAvg10, p.FullAvg10, err = parsePressure(dat); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", r.pressurePath, err)
+	}
+	if dat, err = os.ReadFile(r.meminfoPath); err != nil {
+		return nil, err
+	}
+	if p.TotalKiB, p.AvailableKiB, err = parseMeminfo(dat); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", r.meminfoPath, err)
+	}
+	return &p, nil
+}
+
+// Parse the avg10 values of the some and full lines of a pressure stall
+// information file.
+func parsePressure(dat []byte) (some, full float64, err error) {
+	var found int
+	scanner := bufio.NewScanner(bytes.NewReader(dat))
+	for scanner.Scan() {
+		kind, fields, ok := strings.Cut(scanner.Text(), " ")
+		if !ok || (kind != "some" && kind != "full") {
+			continue
+		}
+		for field := range strings.FieldsSeq(fields) {
+			value, ok := strings.CutPrefix(field, "avg10=")
+			if !ok {
+				continue
+			}
+			avg, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return 0, 0, fmt.Errorf("invalid %s avg10 %q: %w", kind, value, err)
+			}
+			if kind == "some" {
+				some = avg
+			} else {
+				full = avg
+			}
+			found++
+		}
+	}
+	if found == 0 {
+		return 0, 0, fmt.Errorf("avg10 not found")
+	}
+	return some, full, scanner.Err()
+}
+
+// Parse the total and available memory in KiB from meminfo.
+func parseMeminfo(dat []byte) (total, available int64, err error) {
+	scanner := bufio.NewScanner(bytes.NewReader(dat))
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), ":")
+		if !ok || (key != "MemTotal" && key != "MemAvailable") {
+			continue
+		}
+		kib, err := strconv.ParseInt(strings.TrimSuffix(strings.TrimSpace(value), " kB"), 10, 64)
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid %s %q: %w", key, value, err)
+		}
+		if key == "MemTotal" {
+			total = kib
+		} else {
+			available = kib
+		}
+	}
+	if total == 0 {
+		return 0, 0, fmt.Errorf("MemTotal not found")
+	}
+	return total, available, scanner.Err()
+}
+
+// Mitigation is a measure against critical memory pressure of the host.
+type Mitigation string
+
+const (
+	// MitigationAlert emits an event for the hypervisor.
+	MitigationAlert Mitigation = "alert"
+	// MitigationBalloon inflates the balloons of the selected instances.
+	MitigationBalloon Mitigation = "balloon"
+	// MitigationPause pauses the selected instance with the lowest
+	// priority.
+	MitigationPause Mitigation = "pause"
+)
+
+// Parse a comma separated chain of mitigations, e.g. "alert,balloon,pause".
+func ParseMitigations(s string) ([]Mitigation, error) {
+	var mitigations []Mitigation
+	for name := range strings.SplitSeq(s, ",") {
+		switch mitigation := Mitigation(strings.TrimSpace(name)); mitigation {
+		case "":
+		case MitigationAlert, MitigationBalloon, MitigationPause:
+			mitigations = append(mitigations, mitigation)
+		default:
+			return nil, fmt.Errorf("invalid memory pressure mitigation %q, expected one of alert, balloon, pause", name)
+		}
+	}
+	return mitigations, nil
+}
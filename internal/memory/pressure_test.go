@@ -0,0 +1,71 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package memory
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSystemReaderReadPressure(t *testing.T) {
+	tmpDir := t.TempDir()
+	pressure := "some avg10=12.50 avg60=3.00 avg300=1.00 total=123456\n" +
+		"full avg10=2.25 avg60=0.50 avg300=0.10 total=23456\n"
+	meminfo := "MemTotal:       1048576 kB\nMemFree:          65536 kB\nMemAvailable:     52428 kB\n"
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "memory"), []byte(pressure), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "meminfo"), []byte(meminfo), 0644))
+
+	reader := &SystemReader{
+		pressurePath: filepath.Join(tmpDir, "memory"),
+		meminfoPath:  filepath.Join(tmpDir, "meminfo"),
+	}
+	p, err := reader.ReadPressure()
+	require.NoError(t, err)
+	assert.Equal(t, Pressure{SomeAvg10: 12.5, FullAvg10: 2.25, TotalKiB: 1048576, AvailableKiB: 52428}, *p)
+	assert.Equal(t, "5% available (51 of 1024 MiB), some 12.5%, full 2.2%", p.String())
+
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "memory"), []byte("bogus\n"), 0644))
+	_, err = reader.ReadPressure()
+	assert.Error(t, err)
+}
+
+func TestPressureCritical(t *testing.T) {
+	p := Pressure{SomeAvg10: 12.5, TotalKiB: 1000, AvailableKiB: 100}
+	assert.False(t, p.Critical(Thresholds{}))
+	assert.True(t, p.Critical(Thresholds{SomeAvg10: 10}))
+	assert.False(t, p.Critical(Thresholds{SomeAvg10: 20}))
+	assert.True(t, p.Critical(Thresholds{MinAvailable: 0.2}))
+	assert.False(t, p.Critical(Thresholds{SomeAvg10: 20, MinAvailable: 0.05}))
+}
+
+func TestParseMitigations(t *testing.T) {
+	mitigations, err := ParseMitigations("alert, balloon,pause")
+	require.NoError(t, err)
+	assert.Equal(t, []Mitigation{MitigationAlert, MitigationBalloon, MitigationPause}, mitigations)
+
+	mitigations, err = ParseMitigations("")
+	require.NoError(t, err)
+	assert.Empty(t, mitigations)
+
+	_, err = ParseMitigations("alert,kill")
+	assert.Error(t, err)
+}
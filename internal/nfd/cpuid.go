@@ -0,0 +1,142 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nfd
+
+// Names of the cpu features published by the NFD cpu source, which uses the
+// feature ids of github.com/klauspost/cpuid, keyed by the flags the kernel
+// reports for them in /proc/cpuinfo. Flags the cpu source has no feature for
+// are not published by NFD.
+var cpuidFeatures = map[string]string{
+	"3dnow":               "AMD3DNOW",
+	"3dnowext":            "AMD3DNOWEXT",
+	"abm":                 "LZCNT",
+	"adx":                 "ADX",
+	"aes":                 "AESNI",
+	"amx_bf16":            "AMXBF16",
+	"amx_fp16":            "AMXFP16",
+	"amx_int8":            "AMXINT8",
+	"amx_tile":            "AMXTILE",
+	"arch_capabilities":   "IA32_ARCH_CAP",
+	"avx":                 "AVX",
+	"avx2":                "AVX2",
+	"avx512_bf16":         "AVX512BF16",
+	"avx512_bitalg":       "AVX512BITALG",
+	"avx512_fp16":         "AVX512FP16",
+	"avx512_vbmi2":        "AVX512VBMI2",
+	"avx512_vnni":         "AVX512VNNI",
+	"avx512_vp2intersect": "AVX512VP2INTERSECT",
+	"avx512_vpopcntdq":    "AVX512VPOPCNTDQ",
+	"avx512bw":            "AVX512BW",
+	"avx512cd":            "AVX512CD",
+	"avx512dq":            "AVX512DQ",
+	"avx512er":            "AVX512ER",
+	"avx512f":             "AVX512F",
+	"avx512ifma":          "AVX512IFMA",
+	"avx512pf":            "AVX512PF",
+	"avx512vbmi":          "AVX512VBMI",
+	"avx512vl":            "AVX512VL",
+	"avx_vnni":            "AVXVNNI",
+	"bmi1":                "BMI1",
+	"bmi2":                "BMI2",
+	"cldemote":            "CLDEMOTE",
+	"clzero":              "CLZERO",
+	"cmov":                "CMOV",
+	"cpb":                 "CPBOOST",
+	"cppc":                "CPPC",
+	"cx16":                "CX16",
+	"cx8":                 "CMPXCHG8",
+	"decodeassists":       "SVMDA",
+	"enqcmd":              "ENQCMD",
+	"erms":                "ERMS",
+	"f16c":                "F16C",
+	"flush_l1d":           "FLUSH_L1D",
+	"flushbyasid":         "SVMFBASID",
+	"fma":                 "FMA3",
+	"fma4":                "FMA4",
+	"fpu":                 "X87",
+	"fsrm":                "FSRM",
+	"fxsr":                "FXSR",
+	"fxsr_opt":            "FXSROPT",
+	"gfni":                "GFNI",
+	"hle":                 "HLE",
+	"ht":                  "HTT",
+	"hypervisor":          "HYPERVISOR",
+	"ibpb":                "IBPB",
+	"ibrs":                "IBRS",
+	"lahf_lm":             "LAHF",
+	"lbrv":                "LBRVIRT",
+	"md_clear":            "MD_CLEAR",
+	"mmx":                 "MMX",
+	"mmxext":              "MMXEXT",
+	"movbe":               "MOVBE",
+	"movdir64b":           "MOVDIR64B",
+	"movdiri":             "MOVDIRI",
+	"mpx":                 "MPX",
+	"npt":                 "SVMNP",
+	"nrip_save":           "NRIPS",
+	"nx":                  "NX",
+	"osxsave":             "OSXSAVE",
+	"pausefilter":         "SVMPF",
+	"pclmulqdq":           "CLMUL",
+	"pconfig":             "PCONFIG",
+	"pfthreshold":         "SVMPFT",
+	"pni":                 "SSE3",
+	"popcnt":              "POPCNT",
+	"rdpru":               "RDPRU",
+	"rdrand":              "RDRAND",
+	"rdseed":              "RDSEED",
+	"rdtscp":              "RDTSCP",
+	"rtm":                 "RTM",
+	"sep":                 "SYSEE",
+	"serialize":           "SERIALIZE",
+	"sev":                 "SEV",
+	"sev_es":              "SEV_ES",
+	"sev_snp":             "SEV_SNP",
+	"sgx":                 "SGX",
+	"sgx_lc":              "SGXLC",
+	"sha_ni":              "SHA",
+	"sme":                 "SME",
+	"ssbd":                "SPEC_CTRL_SSBD",
+	"sse":                 "SSE",
+	"sse2":                "SSE2",
+	"sse4_1":              "SSE4",
+	"sse4_2":              "SSE42",
+	"sse4a":               "SSE4A",
+	"ssse3":               "SSSE3",
+	"stibp":               "STIBP",
+	"succor":              "SUCCOR",
+	"svm":                 "SVM",
+	"svm_lock":            "SVML",
+	"syscall":             "SYSCALL",
+	"tbm":                 "TBM",
+	"tme":                 "TME",
+	"topoext":             "TOPEXT",
+	"tsc_scale":           "TSCRATEMSR",
+	"tsxldtrk":            "TSXLDTRK",
+	"vaes":                "VAES",
+	"vmcb_clean":          "VMCBCLEAN",
+	"vmx":                 "VMX",
+	"vpclmulqdq":          "VPCLMULQDQ",
+	"waitpkg":             "WAITPKG",
+	"wbnoinvd":            "WBNOINVD",
+	"xop":                 "XOP",
+	"xsave":               "XSAVE",
+	"xsavec":              "XSAVEC",
+	"xsaveopt":            "XSAVEOPT",
+	"xsaves":              "XSAVES",
+}
@@ -0,0 +1,219 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package nfd integrates with node-feature-discovery (NFD). Depending on the
+// mode, the node agent either produces NFD-compatible feature labels through
+// the NFD local feature source, or consumes the labels NFD already put on the
+// node instead of discovering the features itself.
+package nfd
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Mode selects how the node agent integrates with node-feature-discovery.
+type Mode string
+
+const (
+	// Don't integrate with node-feature-discovery.
+	ModeOff Mode = "off"
+	// Write the discovered features into the NFD local feature source.
+	ModeProduce Mode = "produce"
+	// Read the features from the labels NFD put on the node.
+	ModeConsume Mode = "consume"
+)
+
+// Parse the mode from its flag value.
+func ParseMode(s string) (Mode, error) {
+	switch mode := Mode(s); mode {
+	case ModeOff, ModeProduce, ModeConsume:
+		return mode, nil
+	}
+	return "", fmt.Errorf("invalid node-feature-discovery mode %q, expected one of off, produce, consume", s)
+}
+
+const (
+	// Prefix of the labels published by node-feature-discovery.
+	LabelPrefix = "feature.node.kubernetes.io/"
+
+	labelCPUIDPrefix   = "cpu-cpuid."
+	labelKernelVersion = "kernel-version.full"
+	labelIOMMUEnabled  = "iommu-enabled"
+
+	// Default path of the feature file read by the NFD local feature source.
+	DefaultFeatureFilePath = "/etc/kubernetes/node-feature-discovery/features.d/kvm-node-agent"
+)
+
+// Features of the node that are shared with node-feature-discovery.
+type Features struct {
+	// Release of the running kernel, e.g. "6.6.62-cloud-amd64".
+	KernelRelease string
+	// Whether an IOMMU is enabled on the node.
+	IOMMUEnabled bool
+	// CPU feature flags in NFD notation, e.g. "AVX512F", sorted.
+	CPUFeatures []string
+}
+
+// Labels returns the features as NFD labels without the label prefix, as
+// expected by the NFD local feature source.
+func (f Features) Labels() map[string]string {
+	labels := make(map[string]string)
+	for _, feature := range f.CPUFeatures {
+		labels[labelCPUIDPrefix+feature] = "true"
+	}
+	if f.KernelRelease != "" {
+		labels[labelKernelVersion] = f.KernelRelease
+	}
+	if f.IOMMUEnabled {
+		labels[labelIOMMUEnabled] = "true"
+	}
+	return labels
+}
+
+// FromLabels extracts the features from the labels NFD put on a node.
+func FromLabels(labels map[string]string) Features {
+	var f Features
+	for key, value := range labels {
+		name, ok := strings.CutPrefix(key, LabelPrefix)
+		if !ok {
+			continue
+		}
+		switch {
+		case name == labelKernelVersion:
+			f.KernelRelease = value
+		case name == labelIOMMUEnabled:
+			f.IOMMUEnabled = value == "true"
+		case strings.HasPrefix(name, labelCPUIDPrefix) && value == "true":
+			f.CPUFeatures = append(f.CPUFeatures, strings.TrimPrefix(name, labelCPUIDPrefix))
+		}
+	}
+	slices.Sort(f.CPUFeatures)
+	return f
+}
+
+// Check if any of the features is known.
+func (f Features) Empty() bool {
+	return f.KernelRelease == "" && !f.IOMMUEnabled && len(f.CPUFeatures) == 0
+}
+
+// Write the features into the feature file of the NFD local feature source.
+// The file is only rewritten if its content changed, so that NFD does not
+// relabel the node needlessly. Returns whether the file was written.
+func WriteFeatureFile(path string, f Features) (bool, error) {
+	labels := f.Labels()
+	keys := make([]string, 0, len(labels))
+	for key := range labels {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	var buf bytes.Buffer
+	buf.WriteString("# Generated by kvm-node-agent, do not edit.\n")
+	for _, key := range keys {
+		fmt.Fprintf(&buf, "%s=%s\n", key, labels[key])
+	}
+
+	if current, err := os.ReadFile(path); err == nil && bytes.Equal(current, buf.Bytes()) {
+		return false, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return false, fmt.Errorf("failed to create directory %s: %w", filepath.Dir(path), err)
+	}
+	// Write atomically, NFD may read the file at any time.
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0644); err != nil {
+		return false, fmt.Errorf("failed to write feature file %s: %w", tmp, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return false, fmt.Errorf("failed to rename feature file %s: %w", tmp, err)
+	}
+	return true, nil
+}
+
+// SystemReader discovers the features from the system files, the same way
+// node-feature-discovery would.
+type SystemReader struct {
+	cpuinfoPath   string
+	osReleasePath string
+	iommuPath     string
+}
+
+// NewSystemReader creates a new SystemReader with the default system paths.
+func NewSystemReader() *SystemReader {
+	return &SystemReader{
+		cpuinfoPath:   "/proc/cpuinfo",
+		osReleasePath: "/proc/sys/kernel/osrelease",
+		iommuPath:     "/sys/class/iommu",
+	}
+}
+
+// Read the features of the system.
+func (r *SystemReader) Read() (*Features, error) {
+	var f Features
+
+	release, err := os.ReadFile(r.osReleasePath)
+	if err != nil {
+		return nil, err
+	}
+	f.KernelRelease = strings.TrimSpace(string(release))
+
+	// An IOMMU is enabled if the kernel registered any iommu device.
+	entries, err := os.ReadDir(r.iommuPath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	f.IOMMUEnabled = len(entries) > 0
+
+	if f.CPUFeatures, err = readCPUFlags(r.cpuinfoPath); err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+// Read the cpu flags of the first processor from cpuinfo, converted to the
+// feature names used by NFD. Flags NFD doesn't know are dropped.
+func readCPUFlags(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), ":")
+		if !ok || strings.TrimSpace(key) != "flags" {
+			continue
+		}
+		var flags []string
+		for flag := range strings.FieldsSeq(value) {
+			if feature, ok := cpuidFeatures[flag]; ok {
+				flags = append(flags, feature)
+			}
+		}
+		slices.Sort(flags)
+		return slices.Compact(flags), nil
+	}
+	return nil, scanner.Err()
+}
@@ -0,0 +1,113 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nfd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMode(t *testing.T) {
+	for _, valid := range []string{"off", "produce", "consume"} {
+		mode, err := ParseMode(valid)
+		require.NoError(t, err)
+		assert.Equal(t, Mode(valid), mode)
+	}
+
+	_, err := ParseMode("both")
+	assert.Error(t, err)
+}
+
+func TestSystemReaderRead(t *testing.T) {
+	tmpDir := t.TempDir()
+	cpuinfo := "processor\t: 0\nflags\t\t: fpu vmx avx512f avx512_vnni sse4_2 constant_tsc vmx\n\nprocessor\t: 1\nflags\t\t: fpu\n"
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "cpuinfo"), []byte(cpuinfo), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "osrelease"), []byte("6.6.62-cloud-amd64\n"), 0644))
+	require.NoError(t, os.MkdirAll(filepath.Join(tmpDir, "iommu", "dmar0"), 0755))
+
+	reader := &SystemReader{
+		cpuinfoPath:   filepath.Join(tmpDir, "cpuinfo"),
+		osReleasePath: filepath.Join(tmpDir, "osrelease"),
+		iommuPath:     filepath.Join(tmpDir, "iommu"),
+	}
+	features, err := reader.Read()
+	require.NoError(t, err)
+	assert.Equal(t, "6.6.62-cloud-amd64", features.KernelRelease)
+	assert.True(t, features.IOMMUEnabled)
+	assert.Equal(t, []string{"AVX512F", "AVX512VNNI", "SSE42", "VMX", "X87"}, features.CPUFeatures)
+
+	// A missing iommu class means no iommu is enabled.
+	reader.iommuPath = filepath.Join(tmpDir, "missing")
+	features, err = reader.Read()
+	require.NoError(t, err)
+	assert.False(t, features.IOMMUEnabled)
+
+	reader.osReleasePath = filepath.Join(tmpDir, "missing")
+	_, err = reader.Read()
+	assert.Error(t, err)
+}
+
+func TestLabelsRoundTrip(t *testing.T) {
+	features := Features{
+		KernelRelease: "6.6.62-cloud-amd64",
+		IOMMUEnabled:  true,
+		CPUFeatures:   []string{"AVX512F", "VMX"},
+	}
+	labels := features.Labels()
+	assert.Equal(t, map[string]string{
+		"cpu-cpuid.AVX512F":   "true",
+		"cpu-cpuid.VMX":       "true",
+		"kernel-version.full": "6.6.62-cloud-amd64",
+		"iommu-enabled":       "true",
+	}, labels)
+
+	nodeLabels := map[string]string{"kubernetes.io/hostname": "node001"}
+	for key, value := range labels {
+		nodeLabels[LabelPrefix+key] = value
+	}
+	assert.Equal(t, features, FromLabels(nodeLabels))
+	assert.True(t, FromLabels(map[string]string{"kubernetes.io/hostname": "node001"}).Empty())
+}
+
+func TestWriteFeatureFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "features.d", "kvm-node-agent")
+	features := Features{KernelRelease: "6.6.62", CPUFeatures: []string{"VMX"}}
+
+	written, err := WriteFeatureFile(path, features)
+	require.NoError(t, err)
+	assert.True(t, written)
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "# Generated by kvm-node-agent, do not edit.\n"+
+		"cpu-cpuid.VMX=true\nkernel-version.full=6.6.62\n", string(content))
+
+	// Unchanged features don't rewrite the file.
+	written, err = WriteFeatureFile(path, features)
+	require.NoError(t, err)
+	assert.False(t, written)
+
+	features.IOMMUEnabled = true
+	written, err = WriteFeatureFile(path, features)
+	require.NoError(t, err)
+	assert.True(t, written)
+}
@@ -0,0 +1,61 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ovs
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var (
+	bridgeFlows = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "ovs_bridge_flows",
+		Help: "Number of openflow flows of the bridge.",
+	}, []string{"bridge"})
+	portLinkUp = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "ovs_port_link_up",
+		Help: "1 if the link of a port plugged for a domain is up.",
+	}, []string{"port", "instance"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(bridgeFlows, portLinkUp)
+}
+
+// Export the flow counts of the bridges and the link state of the ports
+// of the domains.
+func UpdateMetrics(status *Status) {
+	bridgeFlows.Reset()
+	portLinkUp.Reset()
+	if status == nil {
+		return
+	}
+	for bridge, count := range status.FlowCounts {
+		bridgeFlows.WithLabelValues(bridge).Set(float64(count))
+	}
+	for _, port := range status.Ports {
+		if !port.IsInstancePort() {
+			continue
+		}
+		up := 0.0
+		if port.LinkState == "up" {
+			up = 1
+		}
+		portLinkUp.WithLabelValues(port.Name, port.InstanceID).Set(up)
+	}
+}
@@ -0,0 +1,121 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ovs
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"time"
+)
+
+// OpenFlow 1.0 message types and constants, see the OpenFlow switch
+// specification 1.0.0.
+const (
+	ofVersion         = 0x01
+	ofTypeHello       = 0
+	ofTypeError       = 1
+	ofTypeStatsReq    = 16
+	ofTypeStatsReply  = 17
+	ofStatsAggregate  = 2
+	ofWildcardAll     = 0x003fffff
+	ofTableAll        = 0xff
+	ofPortNone        = 0xffff
+	ofHeaderLen       = 8
+	ofMatchLen        = 40
+	ofAggregateXID    = 1
+	ofAggregateReqLen = ofHeaderLen + 4 + ofMatchLen + 4
+)
+
+// Build an aggregate stats request matching all flows of all tables.
+func aggregateRequest() []byte {
+	msg := make([]byte, ofAggregateReqLen)
+	msg[0], msg[1] = ofVersion, ofTypeStatsReq
+	binary.BigEndian.PutUint16(msg[2:], ofAggregateReqLen)
+	binary.BigEndian.PutUint32(msg[4:], ofAggregateXID)
+	binary.BigEndian.PutUint16(msg[8:], ofStatsAggregate)
+	body := msg[12:]
+	binary.BigEndian.PutUint32(body, ofWildcardAll)
+	body[ofMatchLen] = ofTableAll
+	binary.BigEndian.PutUint16(body[ofMatchLen+2:], ofPortNone)
+	return msg
+}
+
+// Count the openflow flows of a bridge through its management socket,
+// e.g. /run/openvswitch/br-int.mgmt. Requires the bridge to allow
+// OpenFlow 1.0, which Open vSwitch does by default.
+func countFlows(ctx context.Context, socketPath string) (int, error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "unix", socketPath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to connect to %s: %w", socketPath, err)
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	} else {
+		_ = conn.SetDeadline(time.Now().Add(10 * time.Second))
+	}
+
+	hello := make([]byte, ofHeaderLen)
+	hello[0], hello[1] = ofVersion, ofTypeHello
+	binary.BigEndian.PutUint16(hello[2:], ofHeaderLen)
+	if _, err := conn.Write(append(hello, aggregateRequest()...)); err != nil {
+		return 0, fmt.Errorf("failed to send openflow request: %w", err)
+	}
+	return readAggregateReply(conn)
+}
+
+// Read messages until the reply to the aggregate stats request, skipping
+// the hello of the switch.
+func readAggregateReply(r io.Reader) (int, error) {
+	header := make([]byte, ofHeaderLen)
+	for {
+		if _, err := io.ReadFull(r, header); err != nil {
+			return 0, fmt.Errorf("failed to read openflow message: %w", err)
+		}
+		length := int(binary.BigEndian.Uint16(header[2:]))
+		if length < ofHeaderLen {
+			return 0, fmt.Errorf("invalid openflow message length %d", length)
+		}
+		body := make([]byte, length-ofHeaderLen)
+		if _, err := io.ReadFull(r, body); err != nil {
+			return 0, fmt.Errorf("failed to read openflow message: %w", err)
+		}
+		switch header[1] {
+		case ofTypeError:
+			if len(body) >= 4 {
+				return 0, fmt.Errorf("openflow error type %d code %d",
+					binary.BigEndian.Uint16(body), binary.BigEndian.Uint16(body[2:]))
+			}
+			return 0, errors.New("openflow error")
+		case ofTypeStatsReply:
+			if binary.BigEndian.Uint32(header[4:]) != ofAggregateXID {
+				continue
+			}
+			// Stats type and flags, then the packet, byte and flow counts.
+			if len(body) < 4+8+8+4 {
+				return 0, fmt.Errorf("short aggregate stats reply of %d bytes", len(body))
+			}
+			return int(binary.BigEndian.Uint32(body[4+8+8:])), nil
+		}
+	}
+}
@@ -0,0 +1,115 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ovs
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"path/filepath"
+	"testing"
+)
+
+// Build an openflow 1.0 message with the given type, xid and body.
+func ofMessage(msgType uint8, xid uint32, body []byte) []byte {
+	msg := make([]byte, ofHeaderLen, ofHeaderLen+len(body))
+	msg[0], msg[1] = ofVersion, msgType
+	binary.BigEndian.PutUint16(msg[2:], uint16(ofHeaderLen+len(body)))
+	binary.BigEndian.PutUint32(msg[4:], xid)
+	return append(msg, body...)
+}
+
+// Build the reply to the aggregate stats request.
+func aggregateReply(flows uint32) []byte {
+	body := make([]byte, 4+8+8+4+4)
+	binary.BigEndian.PutUint16(body, ofStatsAggregate)
+	binary.BigEndian.PutUint64(body[4:], 1000)
+	binary.BigEndian.PutUint64(body[12:], 64000)
+	binary.BigEndian.PutUint32(body[20:], flows)
+	return ofMessage(ofTypeStatsReply, ofAggregateXID, body)
+}
+
+// Serve the flow count of a bridge on a management socket, like
+// ovs-vswitchd does.
+func serveFlowCount(t *testing.T, socketPath string, flows uint32) {
+	t.Helper()
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		request := make([]byte, ofHeaderLen+ofAggregateReqLen)
+		if _, err := io.ReadFull(conn, request); err != nil {
+			return
+		}
+		if !bytes.Equal(request[ofHeaderLen:], aggregateRequest()) {
+			return
+		}
+		_, _ = conn.Write(append(ofMessage(ofTypeHello, 0, nil), aggregateReply(flows)...))
+	}()
+}
+
+func TestAggregateRequest(t *testing.T) {
+	request := aggregateRequest()
+	if len(request) != 56 {
+		t.Fatalf("Expected a request of 56 bytes, got %d", len(request))
+	}
+	if binary.BigEndian.Uint16(request[2:]) != 56 || binary.BigEndian.Uint32(request[12:]) != ofWildcardAll {
+		t.Errorf("Unexpected request: %x", request)
+	}
+}
+
+func TestReadAggregateReply(t *testing.T) {
+	stream := append(ofMessage(ofTypeHello, 0, nil), aggregateReply(42)...)
+	flows, err := readAggregateReply(bytes.NewReader(stream))
+	if err != nil {
+		t.Fatalf("Failed to read reply: %v", err)
+	}
+	if flows != 42 {
+		t.Errorf("Expected 42 flows, got %d", flows)
+	}
+
+	// The switch rejects the version.
+	errBody := []byte{0, 0, 0, 0}
+	if _, err := readAggregateReply(bytes.NewReader(ofMessage(ofTypeError, 0, errBody))); err == nil {
+		t.Error("Expected error for an openflow error message")
+	}
+	if _, err := readAggregateReply(bytes.NewReader(stream[:len(stream)-4])); err == nil {
+		t.Error("Expected error for a truncated reply")
+	}
+}
+
+func TestCountFlows(t *testing.T) {
+	socketPath := filepath.Join(t.TempDir(), "br-int.mgmt")
+	serveFlowCount(t, socketPath, 7)
+	flows, err := countFlows(context.Background(), socketPath)
+	if err != nil {
+		t.Fatalf("Failed to count flows: %v", err)
+	}
+	if flows != 7 {
+		t.Errorf("Expected 7 flows, got %d", flows)
+	}
+}
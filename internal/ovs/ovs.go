@@ -0,0 +1,338 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package ovs reads the datapath configuration of the local Open vSwitch
+// through its OVSDB unix socket.
+package ovs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ErrNotPresent is returned if Open vSwitch is not running on the host.
+var ErrNotPresent = errors.New("open vswitch is not present")
+
+// Datapath type of bridges using the userspace (DPDK) datapath.
+const DatapathNetdev = "netdev"
+
+// Status of the Open vSwitch datapath configuration.
+type Status struct {
+	// Datapath type by bridge name. An empty type means the kernel datapath.
+	Bridges map[string]string
+	// Whether DPDK was requested in the configuration (other_config:dpdk-init).
+	DPDKEnabled bool
+	// Whether DPDK was successfully initialized.
+	DPDKInitialized bool
+	// Version of the DPDK library, if initialized.
+	DPDKVersion string
+	// Whether hardware offload is enabled (other_config:hw-offload).
+	HWOffload bool
+	// Ports of all bridges, sorted by name.
+	Ports []Port
+	// Number of openflow flows by bridge name. Bridges whose flows could
+	// not be counted are missing.
+	FlowCounts map[string]int
+}
+
+// Port is an interface of an Open vSwitch bridge.
+type Port struct {
+	Name string
+	// Interface type, e.g. "internal" or "dpdkvhostuserclient". An empty
+	// type means a system network device, e.g. a tap device.
+	Type string
+	// Link state as reported by the datapath, "up" or "down".
+	LinkState string
+	// Error of the datapath adding the interface, e.g. because the network
+	// device is gone.
+	Error string
+	// Uuid of the domain the interface was plugged for, from
+	// external_ids:vm-uuid as set by os-vif.
+	InstanceID string
+}
+
+// Check if the interface was plugged for a domain.
+func (p Port) IsInstancePort() bool {
+	return p.InstanceID != ""
+}
+
+// Check if domain interfaces of type vhostuser can be served, which
+// requires DPDK and at least one bridge with the userspace datapath.
+func (s Status) SupportsVhostUser() bool {
+	if !s.DPDKInitialized {
+		return false
+	}
+	for _, datapath := range s.Bridges {
+		if datapath == DatapathNetdev {
+			return true
+		}
+	}
+	return false
+}
+
+// Summary of the status for humans, e.g. for condition messages.
+func (s Status) String() string {
+	bridges := make([]string, 0, len(s.Bridges))
+	for name, datapath := range s.Bridges {
+		if datapath == "" {
+			datapath = "system"
+		}
+		bridges = append(bridges, name+"="+datapath)
+	}
+	slices.Sort(bridges)
+
+	dpdk := "disabled"
+	switch {
+	case s.DPDKInitialized:
+		dpdk = "initialized"
+		if s.DPDKVersion != "" {
+			dpdk += " (" + s.DPDKVersion + ")"
+		}
+	case s.DPDKEnabled:
+		dpdk = "enabled but not initialized"
+	}
+	hwOffload := "disabled"
+	if s.HWOffload {
+		hwOffload = "enabled"
+	}
+	return fmt.Sprintf("datapaths: %s, dpdk: %s, hw-offload: %s",
+		strings.Join(bridges, " "), dpdk, hwOffload)
+}
+
+type Interface interface {
+	// Status returns the datapath configuration, the interfaces and the
+	// flow counts of Open vSwitch, or ErrNotPresent if Open vSwitch is not
+	// running.
+	Status(ctx context.Context) (*Status, error)
+}
+
+// Client queries the OVSDB server of the host.
+type Client struct {
+	socketPath string
+}
+
+// NewClient creates a new Client using the default OVSDB socket.
+func NewClient() *Client {
+	return &Client{socketPath: "/run/openvswitch/db.sock"}
+}
+
+// Request selecting the configuration of Open vSwitch and all bridges.
+var statusRequest = map[string]any{
+	"id":     0,
+	"method": "transact",
+	"params": []any{
+		"Open_vSwitch",
+		map[string]any{
+			"op":      "select",
+			"table":   "Open_vSwitch",
+			"where":   []any{},
+			"columns": []string{"other_config", "dpdk_initialized", "dpdk_version"},
+		},
+		map[string]any{
+			"op":      "select",
+			"table":   "Bridge",
+			"where":   []any{},
+			"columns": []string{"name", "datapath_type"},
+		},
+		map[string]any{
+			"op":      "select",
+			"table":   "Interface",
+			"where":   []any{},
+			"columns": []string{"name", "type", "link_state", "error", "external_ids"},
+		},
+	},
+}
+
+// Status returns the datapath configuration of Open vSwitch. The flows of
+// the bridges are counted through their management sockets next to the
+// OVSDB socket, bridges whose flows can't be counted are left out.
+func (c *Client) Status(ctx context.Context) (*Status, error) {
+	if _, err := os.Stat(c.socketPath); errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotPresent
+	}
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "unix", c.socketPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to ovsdb: %w", err)
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	} else {
+		_ = conn.SetDeadline(time.Now().Add(10 * time.Second))
+	}
+
+	if err := json.NewEncoder(conn).Encode(statusRequest); err != nil {
+		return nil, fmt.Errorf("failed to send ovsdb request: %w", err)
+	}
+	var response json.RawMessage
+	if err := json.NewDecoder(conn).Decode(&response); err != nil {
+		return nil, fmt.Errorf("failed to read ovsdb response: %w", err)
+	}
+	status, err := parseStatus(response)
+	if err != nil {
+		return nil, err
+	}
+
+	status.FlowCounts = make(map[string]int)
+	for bridge := range status.Bridges {
+		socketPath := filepath.Join(filepath.Dir(c.socketPath), bridge+".mgmt")
+		if count, err := countFlows(ctx, socketPath); err == nil {
+			status.FlowCounts[bridge] = count
+		}
+	}
+	return status, nil
+}
+
+// Parse the response to the status request.
+func parseStatus(data []byte) (*Status, error) {
+	var response struct {
+		Result []struct {
+			Rows  []map[string]json.RawMessage `json:"rows"`
+			Error string                       `json:"error"`
+		} `json:"result"`
+		Error any `json:"error"`
+	}
+	if err := json.Unmarshal(data, &response); err != nil {
+		return nil, fmt.Errorf("failed to decode ovsdb response: %w", err)
+	}
+	if response.Error != nil {
+		return nil, fmt.Errorf("ovsdb error: %v", response.Error)
+	}
+	if len(response.Result) != 3 {
+		return nil, fmt.Errorf("unexpected number of ovsdb results: %d", len(response.Result))
+	}
+	for _, result := range response.Result {
+		if result.Error != "" {
+			return nil, fmt.Errorf("ovsdb error: %s", result.Error)
+		}
+	}
+	if len(response.Result[0].Rows) != 1 {
+		return nil, errors.New("expected exactly one Open_vSwitch row")
+	}
+
+	status := Status{Bridges: make(map[string]string)}
+	row := response.Result[0].Rows[0]
+	otherConfig, err := parseMap(row["other_config"])
+	if err != nil {
+		return nil, fmt.Errorf("invalid other_config: %w", err)
+	}
+	status.DPDKEnabled = otherConfig["dpdk-init"] == "true" || otherConfig["dpdk-init"] == "try"
+	status.HWOffload = otherConfig["hw-offload"] == "true"
+	if raw, ok := row["dpdk_initialized"]; ok {
+		if err := json.Unmarshal(raw, &status.DPDKInitialized); err != nil {
+			return nil, fmt.Errorf("invalid dpdk_initialized: %w", err)
+		}
+	}
+	if status.DPDKVersion, err = parseOptionalString(row["dpdk_version"]); err != nil {
+		return nil, fmt.Errorf("invalid dpdk_version: %w", err)
+	}
+
+	for _, bridge := range response.Result[1].Rows {
+		var name string
+		if err := json.Unmarshal(bridge["name"], &name); err != nil {
+			return nil, fmt.Errorf("invalid bridge name: %w", err)
+		}
+		if status.Bridges[name], err = parseOptionalString(bridge["datapath_type"]); err != nil {
+			return nil, fmt.Errorf("invalid datapath_type of bridge %s: %w", name, err)
+		}
+	}
+
+	for _, row := range response.Result[2].Rows {
+		var iface Port
+		if err := json.Unmarshal(row["name"], &iface.Name); err != nil {
+			return nil, fmt.Errorf("invalid interface name: %w", err)
+		}
+		if iface.Type, err = parseOptionalString(row["type"]); err != nil {
+			return nil, fmt.Errorf("invalid type of interface %s: %w", iface.Name, err)
+		}
+		if iface.LinkState, err = parseOptionalString(row["link_state"]); err != nil {
+			return nil, fmt.Errorf("invalid link_state of interface %s: %w", iface.Name, err)
+		}
+		if iface.Error, err = parseOptionalString(row["error"]); err != nil {
+			return nil, fmt.Errorf("invalid error of interface %s: %w", iface.Name, err)
+		}
+		externalIDs, err := parseMap(row["external_ids"])
+		if err != nil {
+			return nil, fmt.Errorf("invalid external_ids of interface %s: %w", iface.Name, err)
+		}
+		iface.InstanceID = externalIDs["vm-uuid"]
+		status.Ports = append(status.Ports, iface)
+	}
+	slices.SortFunc(status.Ports, func(a, b Port) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return &status, nil
+}
+
+// Parse an OVSDB map of strings, encoded as ["map", [[key, value], ...]].
+func parseMap(raw json.RawMessage) (map[string]string, error) {
+	result := make(map[string]string)
+	if raw == nil {
+		return result, nil
+	}
+	var encoded []json.RawMessage
+	if err := json.Unmarshal(raw, &encoded); err != nil {
+		return nil, err
+	}
+	var pairs [][2]string
+	if len(encoded) != 2 || string(encoded[0]) != `"map"` {
+		return nil, fmt.Errorf("not a map: %s", raw)
+	}
+	if err := json.Unmarshal(encoded[1], &pairs); err != nil {
+		return nil, err
+	}
+	for _, pair := range pairs {
+		result[pair[0]] = pair[1]
+	}
+	return result, nil
+}
+
+// Parse an optional OVSDB string, which is either encoded as the string
+// itself or as a set of at most one string, ["set", []] if unset.
+func parseOptionalString(raw json.RawMessage) (string, error) {
+	if raw == nil {
+		return "", nil
+	}
+	var value string
+	if err := json.Unmarshal(raw, &value); err == nil {
+		return value, nil
+	}
+	var encoded []json.RawMessage
+	if err := json.Unmarshal(raw, &encoded); err != nil {
+		return "", err
+	}
+	var values []string
+	if len(encoded) != 2 || string(encoded[0]) != `"set"` {
+		return "", fmt.Errorf("not a set: %s", raw)
+	}
+	if err := json.Unmarshal(encoded[1], &values); err != nil {
+		return "", err
+	}
+	if len(values) == 0 {
+		return "", nil
+	}
+	return values[0], nil
+}
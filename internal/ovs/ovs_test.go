@@ -0,0 +1,148 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ovs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const dpdkResponse = `{"id":0,"error":null,"result":[` +
+	`{"rows":[{"other_config":["map",[["dpdk-init","true"],["hw-offload","false"]]],` +
+	`"dpdk_initialized":true,"dpdk_version":"DPDK 23.11.1"}]},` +
+	`{"rows":[{"name":"br-int","datapath_type":"netdev"},{"name":"br-ex","datapath_type":""}]},` +
+	`{"rows":[{"name":"vhu1234","type":"dpdkvhostuserclient","link_state":"up","error":["set",[]],` +
+	`"external_ids":["map",[["iface-id","1234"],["vm-uuid","uuid-1"]]]},` +
+	`{"name":"br-int","type":"internal","link_state":"up","error":["set",[]],"external_ids":["map",[]]}]}]}`
+
+const kernelResponse = `{"id":0,"error":null,"result":[` +
+	`{"rows":[{"other_config":["map",[["hw-offload","true"]]],` +
+	`"dpdk_initialized":false,"dpdk_version":["set",[]]}]},` +
+	`{"rows":[{"name":"br-int","datapath_type":"system"}]},` +
+	`{"rows":[{"name":"tap5678","type":"","link_state":["set",[]],` +
+	`"error":"could not open network device tap5678 (No such device)",` +
+	`"external_ids":["map",[["vm-uuid","uuid-2"]]]}]}]}`
+
+func TestParseStatus(t *testing.T) {
+	status, err := parseStatus([]byte(dpdkResponse))
+	if err != nil {
+		t.Fatalf("Failed to parse status: %v", err)
+	}
+	if !status.DPDKEnabled || !status.DPDKInitialized || status.DPDKVersion != "DPDK 23.11.1" {
+		t.Errorf("Unexpected dpdk status: %+v", status)
+	}
+	if status.HWOffload {
+		t.Errorf("Expected hw-offload to be disabled")
+	}
+	if status.Bridges["br-int"] != DatapathNetdev || status.Bridges["br-ex"] != "" {
+		t.Errorf("Unexpected bridges: %v", status.Bridges)
+	}
+	if !status.SupportsVhostUser() {
+		t.Errorf("Expected vhost-user to be supported")
+	}
+	expectedPorts := []Port{
+		{Name: "br-int", Type: "internal", LinkState: "up"},
+		{Name: "vhu1234", Type: "dpdkvhostuserclient", LinkState: "up", InstanceID: "uuid-1"},
+	}
+	if !reflect.DeepEqual(status.Ports, expectedPorts) {
+		t.Errorf("Expected ports %+v, got %+v", expectedPorts, status.Ports)
+	}
+	if status.Ports[0].IsInstancePort() || !status.Ports[1].IsInstancePort() {
+		t.Errorf("Unexpected instance ports: %+v", status.Ports)
+	}
+	expected := "datapaths: br-ex=system br-int=netdev, dpdk: initialized (DPDK 23.11.1), hw-offload: disabled"
+	if status.String() != expected {
+		t.Errorf("Expected summary %q, got %q", expected, status.String())
+	}
+
+	status, err = parseStatus([]byte(kernelResponse))
+	if err != nil {
+		t.Fatalf("Failed to parse status: %v", err)
+	}
+	if status.DPDKEnabled || status.DPDKInitialized || status.DPDKVersion != "" || !status.HWOffload {
+		t.Errorf("Unexpected status: %+v", status)
+	}
+	if status.SupportsVhostUser() {
+		t.Errorf("Expected vhost-user not to be supported")
+	}
+	if len(status.Ports) != 1 || status.Ports[0].LinkState != "" ||
+		status.Ports[0].Error != "could not open network device tap5678 (No such device)" {
+		t.Errorf("Unexpected ports: %+v", status.Ports)
+	}
+}
+
+func TestParseStatus_Errors(t *testing.T) {
+	tests := map[string]string{
+		"invalid json":   `{`,
+		"rpc error":      `{"id":0,"error":"unknown method","result":null}`,
+		"missing result": `{"id":0,"error":null,"result":[]}`,
+		"op error":       `{"id":0,"error":null,"result":[{"error":"syntax error"},{"rows":[]},{"rows":[]}]}`,
+		"no ovs row":     `{"id":0,"error":null,"result":[{"rows":[]},{"rows":[]},{"rows":[]}]}`,
+	}
+	for name, response := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := parseStatus([]byte(response)); err == nil {
+				t.Errorf("Expected error for response %s", response)
+			}
+		})
+	}
+}
+
+func TestClientStatus(t *testing.T) {
+	socketPath := filepath.Join(t.TempDir(), "db.sock")
+	client := &Client{socketPath: socketPath}
+	if _, err := client.Status(context.Background()); !errors.Is(err, ErrNotPresent) {
+		t.Errorf("Expected ErrNotPresent without socket, got %v", err)
+	}
+
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer listener.Close()
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		var request map[string]any
+		if err := json.NewDecoder(conn).Decode(&request); err != nil || request["method"] != "transact" {
+			return
+		}
+		_, _ = conn.Write([]byte(dpdkResponse))
+	}()
+	// Only the flows of br-int can be counted.
+	serveFlowCount(t, filepath.Join(filepath.Dir(socketPath), "br-int.mgmt"), 12)
+
+	status, err := client.Status(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to get status: %v", err)
+	}
+	if !status.SupportsVhostUser() {
+		t.Errorf("Expected vhost-user to be supported, got %+v", status)
+	}
+	if !reflect.DeepEqual(status.FlowCounts, map[string]int{"br-int": 12}) {
+		t.Errorf("Expected flow counts of br-int, got %v", status.FlowCounts)
+	}
+}
@@ -0,0 +1,152 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package reboot decides when a hypervisor may be rebooted.
+package reboot
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// Window is a recurring maintenance window in UTC.
+type Window struct {
+	// Days the window starts on, every day if empty.
+	Days []time.Weekday
+	// Start of the window as offset from midnight.
+	Start time.Duration
+	// Length of the window. Windows may extend into the next day.
+	Length time.Duration
+}
+
+// Parse maintenance windows like "Sat,Sun 02:00-06:00; 22:00-01:00",
+// separated by semicolons. The days are optional.
+func ParseWindows(s string) ([]Window, error) {
+	var windows []Window
+	for _, spec := range strings.Split(s, ";") {
+		if spec = strings.TrimSpace(spec); spec == "" {
+			continue
+		}
+		window, err := parseWindow(spec)
+		if err != nil {
+			return nil, err
+		}
+		windows = append(windows, window)
+	}
+	if len(windows) == 0 {
+		return nil, fmt.Errorf("no maintenance window in %q", s)
+	}
+	return windows, nil
+}
+
+func parseWindow(spec string) (Window, error) {
+	var window Window
+	fields := strings.Fields(spec)
+	switch len(fields) {
+	case 1:
+	case 2:
+		for _, day := range strings.Split(fields[0], ",") {
+			weekday, ok := weekdays[strings.ToLower(day)]
+			if !ok {
+				return Window{}, fmt.Errorf("invalid day %q in maintenance window %q", day, spec)
+			}
+			window.Days = append(window.Days, weekday)
+		}
+	default:
+		return Window{}, fmt.Errorf("invalid maintenance window %q, expected e.g. Sat,Sun 02:00-06:00", spec)
+	}
+
+	from, to, ok := strings.Cut(fields[len(fields)-1], "-")
+	if !ok {
+		return Window{}, fmt.Errorf("invalid time range in maintenance window %q", spec)
+	}
+	start, err := parseTimeOfDay(from)
+	if err != nil {
+		return Window{}, fmt.Errorf("invalid maintenance window %q: %w", spec, err)
+	}
+	end, err := parseTimeOfDay(to)
+	if err != nil {
+		return Window{}, fmt.Errorf("invalid maintenance window %q: %w", spec, err)
+	}
+	if end == start {
+		return Window{}, fmt.Errorf("invalid maintenance window %q: empty time range", spec)
+	}
+	window.Start = start
+	window.Length = end - start
+	if end < start {
+		window.Length += 24 * time.Hour
+	}
+	return window, nil
+}
+
+func parseTimeOfDay(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", s)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// Contains checks if the time is within the window.
+func (w Window) Contains(t time.Time) bool {
+	t = t.UTC()
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	// The window may have started the day before.
+	for _, day := range []time.Time{midnight, midnight.AddDate(0, 0, -1)} {
+		if !w.startsOn(day.Weekday()) {
+			continue
+		}
+		start := day.Add(w.Start)
+		if !t.Before(start) && t.Before(start.Add(w.Length)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (w Window) startsOn(weekday time.Weekday) bool {
+	if len(w.Days) == 0 {
+		return true
+	}
+	for _, day := range w.Days {
+		if day == weekday {
+			return true
+		}
+	}
+	return false
+}
+
+// InWindow checks if the time is within any of the windows.
+func InWindow(windows []Window, t time.Time) bool {
+	for _, window := range windows {
+		if window.Contains(t) {
+			return true
+		}
+	}
+	return false
+}
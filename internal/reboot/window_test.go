@@ -0,0 +1,67 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reboot
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseWindows(t *testing.T) {
+	windows, err := ParseWindows("Sat,Sun 02:00-06:00; 22:30-01:00")
+	require.NoError(t, err)
+	assert.Equal(t, []Window{
+		{Days: []time.Weekday{time.Saturday, time.Sunday}, Start: 2 * time.Hour, Length: 4 * time.Hour},
+		{Start: 22*time.Hour + 30*time.Minute, Length: 2*time.Hour + 30*time.Minute},
+	}, windows)
+
+	for _, s := range []string{"", " ; ", "Sat", "Sat 02:00", "Xyz 02:00-03:00", "02:00-25:00", "02:00-02:00",
+		"Sat Sun 02:00-03:00"} {
+		_, err := ParseWindows(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestInWindow(t *testing.T) {
+	windows, err := ParseWindows("Sat 02:00-06:00; Mon 23:00-01:00")
+	require.NoError(t, err)
+
+	tests := map[string]bool{
+		// 2025-01-04 is a Saturday.
+		"2025-01-04T01:59:00Z": false,
+		"2025-01-04T02:00:00Z": true,
+		"2025-01-04T05:59:00Z": true,
+		"2025-01-04T06:00:00Z": false,
+		"2025-01-05T03:00:00Z": false,
+		// The monday window extends into tuesday.
+		"2025-01-06T23:30:00Z": true,
+		"2025-01-07T00:30:00Z": true,
+		"2025-01-07T01:00:00Z": false,
+		"2025-01-07T23:30:00Z": false,
+		// Times are compared in UTC.
+		"2025-01-04T04:00:00+01:00": true,
+	}
+	for s, expected := range tests {
+		ts, err := time.Parse(time.RFC3339, s)
+		require.NoError(t, err)
+		assert.Equal(t, expected, InWindow(windows, ts), s)
+	}
+}
@@ -18,7 +18,13 @@ limitations under the License.
 package sys
 
 import (
+	"bufio"
+	"bytes"
+	"fmt"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"sigs.k8s.io/controller-runtime/pkg/log"
 )
@@ -30,3 +36,28 @@ func GetVersion() string {
 	}
 	return string(dat)
 }
+
+// BootTime returns the time the host was booted, read from /proc/stat.
+func BootTime() (time.Time, error) {
+	dat, err := os.ReadFile("/proc/stat")
+	if err != nil {
+		return time.Time{}, err
+	}
+	return parseBootTime(dat)
+}
+
+func parseBootTime(dat []byte) (time.Time, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(dat))
+	for scanner.Scan() {
+		value, ok := strings.CutPrefix(scanner.Text(), "btime ")
+		if !ok {
+			continue
+		}
+		seconds, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid btime %q: %w", value, err)
+		}
+		return time.Unix(seconds, 0), nil
+	}
+	return time.Time{}, fmt.Errorf("btime not found")
+}
@@ -0,0 +1,192 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package sysctl applies the kernel parameters of the host requested by the
+// annotations of the hypervisor and reports values changed behind its back.
+package sysctl
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+const (
+	// Prefix of the hypervisor annotations holding the desired value of a
+	// sysctl, e.g. "sysctl.kvm.cloud.sap/vm.swappiness".
+	AnnotationPrefix = "sysctl.kvm.cloud.sap/"
+
+	// Default mount point of the sysctl file system.
+	DefaultRoot = "/proc/sys"
+)
+
+// Valid sysctl names, e.g. "net.core.rmem_max". Dots separate the path
+// components, so names can't escape the sysctl file system.
+var namePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+(\.[A-Za-z0-9_-]+)*$`)
+
+// DefaultAllowed are the sysctls which may be set by default. Sysctls like
+// kernel.core_pattern or kernel.modprobe run programs as root on the host,
+// so anyone allowed to annotate the hypervisor could take over the host.
+var DefaultAllowed = []string{"vm.", "net.core.", "kernel.sched_"}
+
+// Setting is the desired value of a sysctl.
+type Setting struct {
+	Name  string
+	Value string
+}
+
+func (s Setting) String() string {
+	return s.Name + "=" + s.Value
+}
+
+// Drift is a sysctl found with another value than the desired one.
+type Drift struct {
+	Setting
+	// Value found on the host.
+	Found string
+}
+
+// Summary of the drift for humans, e.g. "vm.swappiness=60 (want 10)".
+func (d Drift) String() string {
+	return fmt.Sprintf("%s=%s (want %s)", d.Name, d.Found, d.Value)
+}
+
+// Result of applying the settings.
+type Result struct {
+	// Settings which had another value and were written.
+	Corrected []Drift
+	// Settings which still have another value after writing them, e.g.
+	// because the kernel clamped the value.
+	Rejected []Drift
+}
+
+// DesiredSettings returns the sysctls requested by the annotations of the
+// hypervisor, sorted by name. Annotations with an invalid sysctl name are
+// skipped and reported in the error.
+func DesiredSettings(annotations map[string]string) ([]Setting, error) {
+	var settings []Setting
+	var errs []error
+	for key, value := range annotations {
+		name, ok := strings.CutPrefix(key, AnnotationPrefix)
+		if !ok {
+			continue
+		}
+		if !namePattern.MatchString(name) {
+			errs = append(errs, fmt.Errorf("invalid sysctl name %q", name))
+			continue
+		}
+		settings = append(settings, Setting{Name: name, Value: value})
+	}
+	slices.SortFunc(settings, func(a, b Setting) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return settings, errors.Join(errs...)
+}
+
+// Interface applies sysctls to the host.
+type Interface interface {
+	// Apply writes the settings whose value differs on the host, and
+	// verifies them by reading them back.
+	Apply(settings []Setting) (*Result, error)
+}
+
+// Manager applies sysctls through the sysctl file system.
+type Manager struct {
+	root    string
+	allowed []string
+}
+
+// NewManager returns a manager for the sysctl file system mounted at root,
+// which only writes the allowed sysctls. Entries ending with a dot or an
+// underscore are prefixes, e.g. "vm.", the others exact names.
+func NewManager(root string, allowed []string) *Manager {
+	return &Manager{root: root, allowed: allowed}
+}
+
+// Check if the sysctl may be written.
+func (m *Manager) allows(name string) bool {
+	return slices.ContainsFunc(m.allowed, func(entry string) bool {
+		if strings.HasSuffix(entry, ".") || strings.HasSuffix(entry, "_") {
+			return strings.HasPrefix(name, entry)
+		}
+		return name == entry
+	})
+}
+
+func (m *Manager) path(name string) string {
+	return filepath.Join(m.root, strings.ReplaceAll(name, ".", "/"))
+}
+
+// Read the value of a sysctl, with the fields of multi-value sysctls like
+// net.ipv4.tcp_rmem separated by a single space.
+func (m *Manager) Read(name string) (string, error) {
+	data, err := os.ReadFile(m.path(name))
+	if err != nil {
+		return "", fmt.Errorf("failed to read sysctl %s: %w", name, err)
+	}
+	return normalize(string(data)), nil
+}
+
+func normalize(value string) string {
+	return strings.Join(strings.Fields(value), " ")
+}
+
+// Apply writes the settings whose value differs on the host, and verifies
+// them by reading them back. Settings failing to be read or written are
+// reported in the error, the others are applied nevertheless.
+func (m *Manager) Apply(settings []Setting) (*Result, error) {
+	var result Result
+	var errs []error
+	for _, setting := range settings {
+		if !namePattern.MatchString(setting.Name) {
+			errs = append(errs, fmt.Errorf("invalid sysctl name %q", setting.Name))
+			continue
+		}
+		if !m.allows(setting.Name) {
+			errs = append(errs, fmt.Errorf("sysctl %s is not allowed", setting.Name))
+			continue
+		}
+		found, err := m.Read(setting.Name)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		desired := normalize(setting.Value)
+		if found == desired {
+			continue
+		}
+		if err := os.WriteFile(m.path(setting.Name), []byte(desired+"\n"), 0644); err != nil {
+			errs = append(errs, fmt.Errorf("failed to write sysctl %s: %w", setting.Name, err))
+			continue
+		}
+		result.Corrected = append(result.Corrected, Drift{Setting: setting, Found: found})
+
+		written, err := m.Read(setting.Name)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if written != desired {
+			result.Rejected = append(result.Rejected, Drift{Setting: setting, Found: written})
+		}
+	}
+	return &result, errors.Join(errs...)
+}
@@ -0,0 +1,119 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sysctl
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDesiredSettings(t *testing.T) {
+	settings, err := DesiredSettings(map[string]string{
+		AnnotationPrefix + "vm.swappiness":     "10",
+		AnnotationPrefix + "net.core.rmem_max": "16777216",
+		"kernel.kvm.cloud.sap/isolcpus":        "2-31",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []Setting{
+		{Name: "net.core.rmem_max", Value: "16777216"},
+		{Name: "vm.swappiness", Value: "10"},
+	}, settings)
+
+	settings, err = DesiredSettings(map[string]string{
+		AnnotationPrefix + "vm..swappiness": "10",
+		AnnotationPrefix + "vm.overcommit":  "1",
+	})
+	assert.ErrorContains(t, err, `invalid sysctl name "vm..swappiness"`)
+	assert.Equal(t, []Setting{{Name: "vm.overcommit", Value: "1"}}, settings)
+
+	settings, err = DesiredSettings(nil)
+	require.NoError(t, err)
+	assert.Empty(t, settings)
+}
+
+func writeSysctl(t *testing.T, root, name, value string) {
+	t.Helper()
+	path := filepath.Join(root, filepath.FromSlash(name))
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, os.WriteFile(path, []byte(value), 0644))
+}
+
+func TestApply(t *testing.T) {
+	root := t.TempDir()
+	writeSysctl(t, root, "vm/swappiness", "60\n")
+	writeSysctl(t, root, "net/ipv4/tcp_rmem", "4096\t131072\t6291456\n")
+	writeSysctl(t, root, "net/core/rmem_max", "212992\n")
+
+	manager := NewManager(root, []string{"vm.", "net.ipv4.tcp_rmem", "net.core."})
+	result, err := manager.Apply([]Setting{
+		{Name: "vm.swappiness", Value: "10"},
+		{Name: "net.ipv4.tcp_rmem", Value: "4096 131072  6291456"},
+		{Name: "net.core.rmem_max", Value: "16777216"},
+		{Name: "vm.missing", Value: "1"},
+	})
+	assert.ErrorContains(t, err, "failed to read sysctl vm.missing")
+	assert.Equal(t, []Drift{
+		{Setting: Setting{Name: "vm.swappiness", Value: "10"}, Found: "60"},
+		{Setting: Setting{Name: "net.core.rmem_max", Value: "16777216"}, Found: "212992"},
+	}, result.Corrected)
+	assert.Empty(t, result.Rejected)
+
+	value, err := manager.Read("vm.swappiness")
+	require.NoError(t, err)
+	assert.Equal(t, "10", value)
+
+	// Applying again finds no drift.
+	result, err = manager.Apply([]Setting{{Name: "vm.swappiness", Value: "10"}})
+	require.NoError(t, err)
+	assert.Empty(t, result.Corrected)
+
+	_, err = manager.Apply([]Setting{{Name: "../etc/passwd", Value: "x"}})
+	assert.ErrorContains(t, err, "invalid sysctl name")
+}
+
+func TestApply_RejectsSysctlsNotAllowed(t *testing.T) {
+	root := t.TempDir()
+	writeSysctl(t, root, "kernel/core_pattern", "core\n")
+	writeSysctl(t, root, "kernel/sched_autogroup_enabled", "1\n")
+	writeSysctl(t, root, "net/ipv4/tcp_rmem", "4096\t131072\t6291456\n")
+
+	manager := NewManager(root, DefaultAllowed)
+	result, err := manager.Apply([]Setting{
+		{Name: "kernel.core_pattern", Value: "|/tmp/exploit"},
+		{Name: "kernel.sched_autogroup_enabled", Value: "0"},
+		{Name: "net.ipv4.tcp_rmem", Value: "4096 87380 6291456"},
+	})
+	assert.ErrorContains(t, err, "sysctl kernel.core_pattern is not allowed")
+	assert.ErrorContains(t, err, "sysctl net.ipv4.tcp_rmem is not allowed")
+	assert.Equal(t, []Drift{
+		{Setting: Setting{Name: "kernel.sched_autogroup_enabled", Value: "0"}, Found: "1"},
+	}, result.Corrected)
+
+	value, err := manager.Read("kernel.core_pattern")
+	require.NoError(t, err)
+	assert.Equal(t, "core", value)
+}
+
+func TestDriftString(t *testing.T) {
+	drift := Drift{Setting: Setting{Name: "vm.swappiness", Value: "10"}, Found: "60"}
+	assert.Equal(t, "vm.swappiness=60 (want 10)", drift.String())
+}
@@ -0,0 +1,264 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package systemd
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrChecksumMismatch is returned if the downloaded image doesn't match
+// the expected checksum.
+var ErrChecksumMismatch = errors.New("checksum mismatch")
+
+var sha256Regexp = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// Time without any data received after which a download is aborted. The
+// images are too large for a timeout of the whole download.
+var downloadIdleTimeout = 5 * time.Minute
+
+// Default client the images are downloaded with. The timeouts only cover
+// connecting and waiting for the response, the body is read with the idle
+// timeout.
+var defaultImageClient = func() *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.ResponseHeaderTimeout = time.Minute
+	return &http.Client{Transport: transport}
+}()
+
+// Image is an operating system image to install with systemd-sysupdate.
+type Image struct {
+	// URL the image is downloaded from.
+	URL string
+	// Expected sha256 checksum of the image, hex encoded.
+	SHA256 string
+}
+
+// Name of the staged image, the last element of the url path.
+func (i Image) Name() (string, error) {
+	u, err := url.Parse(i.URL)
+	if err != nil {
+		return "", fmt.Errorf("invalid image url: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", fmt.Errorf("invalid image url %s, expected http or https", i.URL)
+	}
+	name := path.Base(u.Path)
+	if name == "/" || name == "." || strings.HasPrefix(name, ".") {
+		return "", fmt.Errorf("invalid image url %s, expected a file name", i.URL)
+	}
+	return name, nil
+}
+
+// Validate the url and checksum of the image.
+func (i Image) Validate() error {
+	if _, err := i.Name(); err != nil {
+		return err
+	}
+	if !sha256Regexp.MatchString(i.SHA256) {
+		return fmt.Errorf("invalid sha256 checksum %q, expected 64 lower case hex digits", i.SHA256)
+	}
+	return nil
+}
+
+// Checksum of the file, hex encoded.
+func fileSHA256(name string) (string, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// Reader aborting the download if no data is received for the idle timeout.
+type idleReader struct {
+	r     io.Reader
+	timer *time.Timer
+}
+
+func (r *idleReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	if n > 0 {
+		r.timer.Reset(downloadIdleTimeout)
+	}
+	return n, err
+}
+
+// Remove the images staged before, so that only the last one is kept in
+// the directory. Temporary files of downloads are left alone.
+func pruneImages(dir, keep string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, entry := range entries {
+		if entry.Name() == keep || strings.HasPrefix(entry.Name(), ".") || !entry.Type().IsRegular() {
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir, entry.Name())); err != nil && !errors.Is(err, os.ErrNotExist) {
+			errs = append(errs, fmt.Errorf("failed to remove staged image: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// DownloadImage downloads the image into the directory and verifies its
+// checksum, returns the path of the image. The image is written to a
+// temporary file first, so that systemd-sysupdate only ever sees verified
+// images. Images already staged are not downloaded again, the images staged
+// before are removed.
+func DownloadImage(ctx context.Context, client *http.Client, dir string, image Image) (string, error) {
+	if err := image.Validate(); err != nil {
+		return "", err
+	}
+	name, _ := image.Name()
+	target := filepath.Join(dir, name)
+	if sum, err := fileSHA256(target); err == nil && sum == image.SHA256 {
+		return target, pruneImages(dir, name)
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	idle := time.AfterFunc(downloadIdleTimeout, cancel)
+	defer idle.Stop()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, image.URL, http.NoBody)
+	if err != nil {
+		return "", err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to download %s: %w", image.URL, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to download %s: %s", image.URL, resp.Status)
+	}
+
+	tmp, err := os.CreateTemp(dir, "."+name+".*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+	hash := sha256.New()
+	_, err = io.Copy(io.MultiWriter(tmp, hash), &idleReader{r: resp.Body, timer: idle})
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		if ctx.Err() != nil && !idle.Stop() {
+			err = fmt.Errorf("no data received for %s", downloadIdleTimeout)
+		}
+		return "", fmt.Errorf("failed to download %s: %w", image.URL, err)
+	}
+	if sum := hex.EncodeToString(hash.Sum(nil)); sum != image.SHA256 {
+		return "", fmt.Errorf("%w: %s has sha256 %s, expected %s", ErrChecksumMismatch, name, sum, image.SHA256)
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return "", err
+	}
+	if err := os.Rename(tmp.Name(), target); err != nil {
+		return "", err
+	}
+	return target, pruneImages(dir, name)
+}
+
+// ImageStatus is the progress of staging an image.
+type ImageStatus struct {
+	Image Image
+	// Path of the staged image, empty until staged.
+	Path string
+	// The staging finished, successfully if Err is nil.
+	Done bool
+	Err  error
+}
+
+// ImageStager stages operating system images for systemd-sysupdate.
+type ImageStager interface {
+	// StageImage starts staging the image in the background unless it
+	// is already staged or in progress, and returns its status. Staging
+	// another image cancels the previous one.
+	StageImage(image Image) ImageStatus
+}
+
+// ImageDownloader stages images in a directory, which a transfer definition
+// of systemd-sysupdate with a regular-file source reads the updates from.
+type ImageDownloader struct {
+	// Directory the images are staged in.
+	Dir string
+	// Client the images are downloaded with, defaults to a client with
+	// timeouts for connecting and the response.
+	Client *http.Client
+
+	lock   sync.Mutex
+	status *ImageStatus
+	cancel context.CancelFunc
+}
+
+// StageImage starts staging the image in the background unless it is
+// already staged or in progress, and returns its status.
+func (d *ImageDownloader) StageImage(image Image) ImageStatus {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	// Retry failed downloads, but not verification failures of the same
+	// image, which would fail again.
+	if d.status != nil && d.status.Image == image &&
+		(!d.status.Done || d.status.Err == nil || errors.Is(d.status.Err, ErrChecksumMismatch)) {
+		return *d.status
+	}
+	if d.cancel != nil {
+		d.cancel()
+	}
+
+	client := d.Client
+	if client == nil {
+		client = defaultImageClient
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	status := &ImageStatus{Image: image}
+	d.status, d.cancel = status, cancel
+	go func() {
+		defer cancel()
+		path, err := DownloadImage(ctx, client, d.Dir, image)
+		d.lock.Lock()
+		defer d.lock.Unlock()
+		status.Path, status.Err, status.Done = path, err, true
+	}()
+	return *status
+}
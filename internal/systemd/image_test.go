@@ -0,0 +1,133 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package systemd
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImageValidate(t *testing.T) {
+	sum := sha256.Sum256([]byte("image"))
+	valid := hex.EncodeToString(sum[:])
+	assert.NoError(t, Image{URL: "https://example.com/gardenlinux_1877.3.raw", SHA256: valid}.Validate())
+	assert.Error(t, Image{URL: "https://example.com/gardenlinux_1877.3.raw", SHA256: "abc"}.Validate())
+	assert.Error(t, Image{URL: "file:///etc/passwd", SHA256: valid}.Validate())
+	assert.Error(t, Image{URL: "https://example.com/", SHA256: valid}.Validate())
+}
+
+func TestDownloadImage(t *testing.T) {
+	content := []byte("operating system image")
+	sum := sha256.Sum256(content)
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Path == "/missing.raw" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write(content)
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	image := Image{URL: server.URL + "/gardenlinux_1877.3.raw", SHA256: hex.EncodeToString(sum[:])}
+	path, err := DownloadImage(context.Background(), server.Client(), dir, image)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "gardenlinux_1877.3.raw"), path)
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, content, data)
+
+	// Staged images are not downloaded again.
+	_, err = DownloadImage(context.Background(), server.Client(), dir, image)
+	require.NoError(t, err)
+	assert.Equal(t, 1, requests)
+
+	image.URL = server.URL + "/other.raw"
+	image.SHA256 = hex.EncodeToString(make([]byte, 32))
+	_, err = DownloadImage(context.Background(), server.Client(), dir, image)
+	assert.ErrorIs(t, err, ErrChecksumMismatch)
+	assert.NoFileExists(t, filepath.Join(dir, "other.raw"))
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1, "temporary files are removed")
+
+	image.URL = server.URL + "/missing.raw"
+	_, err = DownloadImage(context.Background(), server.Client(), dir, image)
+	assert.Error(t, err)
+	assert.False(t, errors.Is(err, ErrChecksumMismatch))
+
+	// The images staged before are removed.
+	image = Image{URL: server.URL + "/gardenlinux_1877.4.raw", SHA256: hex.EncodeToString(sum[:])}
+	path, err = DownloadImage(context.Background(), server.Client(), dir, image)
+	require.NoError(t, err)
+	entries, err = os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, filepath.Base(path), entries[0].Name())
+}
+
+func TestDownloadImage_IdleTimeout(t *testing.T) {
+	timeout := downloadIdleTimeout
+	downloadIdleTimeout = 50 * time.Millisecond
+	defer func() { downloadIdleTimeout = timeout }()
+
+	stalled := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("partial"))
+		w.(http.Flusher).Flush()
+		select {
+		case <-stalled:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(stalled)
+
+	image := Image{URL: server.URL + "/gardenlinux_1877.3.raw", SHA256: hex.EncodeToString(make([]byte, 32))}
+	_, err := DownloadImage(context.Background(), server.Client(), t.TempDir(), image)
+	assert.ErrorContains(t, err, "no data received")
+}
+
+func TestImageDownloader(t *testing.T) {
+	content := []byte("operating system image")
+	sum := sha256.Sum256(content)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(content)
+	}))
+	defer server.Close()
+
+	downloader := &ImageDownloader{Dir: t.TempDir(), Client: server.Client()}
+	image := Image{URL: server.URL + "/gardenlinux_1877.3.raw", SHA256: hex.EncodeToString(sum[:])}
+	assert.Eventually(t, func() bool {
+		status := downloader.StageImage(image)
+		return status.Done && status.Err == nil && status.Path != ""
+	}, 5*time.Second, 10*time.Millisecond)
+}
@@ -21,6 +21,7 @@ package systemd
 
 import (
 	"context"
+	"time"
 
 	v1 "github.com/cobaltcore-dev/openstack-hypervisor-operator/api/v1"
 	"github.com/coreos/go-systemd/v22/dbus"
@@ -75,6 +76,10 @@ type Interface interface {
 	// ReloadUnit reloads the unit with the given name.
 	ReloadUnit(ctx context.Context, unit string) (int, error)
 
+	// RestartUnit restarts the unit with the given name and waits for the
+	// job to finish. Returns the result of the job, e.g. "done" or "failed".
+	RestartUnit(ctx context.Context, unit string) (string, error)
+
 	// ReconcileSysUpdate reconciles orchestrates a systemd-sysupdate via the systemd-sysupdate@.service unit.
 	ReconcileSysUpdate(ctx context.Context, hv *v1.Hypervisor) (bool, error)
 
@@ -84,6 +89,20 @@ type Interface interface {
 	// DisableShutdownInhibit disables the shutdown inhibition
 	DisableShutdownInhibit() error
 
+	// InhibitDelayMax returns how long the shutdown is delayed at most by
+	// the shutdown inhibition.
+	InhibitDelayMax(ctx context.Context) (time.Duration, error)
+
 	// Describe returns hostname and related machine metadata
 	Describe(ctx context.Context) (*Descriptor, error)
 }
+
+// UnitWatcher notifies about changes of systemd units as they happen.
+type UnitWatcher interface {
+	// WatchUnits calls the handler with the name of the unit whenever the
+	// properties of one of the units change, until the context is done.
+	// The handler is called in a blocking manner. The units are looked up
+	// for every change, so that units added later, e.g. by reloading the
+	// runtime configuration, are watched as well.
+	WatchUnits(ctx context.Context, units func() []string, handler func(unit string)) error
+}
@@ -8,6 +8,7 @@ import (
 	v1 "github.com/cobaltcore-dev/openstack-hypervisor-operator/api/v1"
 	systemd "github.com/coreos/go-systemd/v22/dbus"
 	"sync"
+	"time"
 )
 
 // Ensure, that InterfaceMock does implement Interface.
@@ -35,6 +36,9 @@ var _ Interface = &InterfaceMock{}
 //			GetUnitByNameFunc: func(ctx context.Context, unit string) (systemd.UnitStatus, error) {
 //				panic("mock out the GetUnitByName method")
 //			},
+//			InhibitDelayMaxFunc: func(ctx context.Context) (time.Duration, error) {
+//				panic("mock out the InhibitDelayMax method")
+//			},
 //			IsConnectedFunc: func() bool {
 //				panic("mock out the IsConnected method")
 //			},
@@ -47,6 +51,9 @@ var _ Interface = &InterfaceMock{}
 //			ReloadUnitFunc: func(ctx context.Context, unit string) (int, error) {
 //				panic("mock out the ReloadUnit method")
 //			},
+//			RestartUnitFunc: func(ctx context.Context, unit string) (string, error) {
+//				panic("mock out the RestartUnit method")
+//			},
 //			StartUnitFunc: func(ctx context.Context, unit string) (int, error) {
 //				panic("mock out the StartUnit method")
 //			},
@@ -72,6 +79,9 @@ type InterfaceMock struct {
 	// GetUnitByNameFunc mocks the GetUnitByName method.
 	GetUnitByNameFunc func(ctx context.Context, unit string) (systemd.UnitStatus, error)
 
+	// InhibitDelayMaxFunc mocks the InhibitDelayMax method.
+	InhibitDelayMaxFunc func(ctx context.Context) (time.Duration, error)
+
 	// IsConnectedFunc mocks the IsConnected method.
 	IsConnectedFunc func() bool
 
@@ -84,6 +94,9 @@ type InterfaceMock struct {
 	// ReloadUnitFunc mocks the ReloadUnit method.
 	ReloadUnitFunc func(ctx context.Context, unit string) (int, error)
 
+	// RestartUnitFunc mocks the RestartUnit method.
+	RestartUnitFunc func(ctx context.Context, unit string) (string, error)
+
 	// StartUnitFunc mocks the StartUnit method.
 	StartUnitFunc func(ctx context.Context, unit string) (int, error)
 
@@ -114,6 +127,11 @@ type InterfaceMock struct {
 			// Unit is the unit argument value.
 			Unit string
 		}
+		// InhibitDelayMax holds details about calls to the InhibitDelayMax method.
+		InhibitDelayMax []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+		}
 		// IsConnected holds details about calls to the IsConnected method.
 		IsConnected []struct {
 		}
@@ -138,6 +156,13 @@ type InterfaceMock struct {
 			// Unit is the unit argument value.
 			Unit string
 		}
+		// RestartUnit holds details about calls to the RestartUnit method.
+		RestartUnit []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Unit is the unit argument value.
+			Unit string
+		}
 		// StartUnit holds details about calls to the StartUnit method.
 		StartUnit []struct {
 			// Ctx is the ctx argument value.
@@ -151,10 +176,12 @@ type InterfaceMock struct {
 	lockDisableShutdownInhibit sync.RWMutex
 	lockEnableShutdownInhibit  sync.RWMutex
 	lockGetUnitByName          sync.RWMutex
+	lockInhibitDelayMax        sync.RWMutex
 	lockIsConnected            sync.RWMutex
 	lockListUnitsByNames       sync.RWMutex
 	lockReconcileSysUpdate     sync.RWMutex
 	lockReloadUnit             sync.RWMutex
+	lockRestartUnit            sync.RWMutex
 	lockStartUnit              sync.RWMutex
 }
 
@@ -316,6 +343,38 @@ func (mock *InterfaceMock) GetUnitByNameCalls() []struct {
 	return calls
 }
 
+// InhibitDelayMax calls InhibitDelayMaxFunc.
+func (mock *InterfaceMock) InhibitDelayMax(ctx context.Context) (time.Duration, error) {
+	if mock.InhibitDelayMaxFunc == nil {
+		panic("InterfaceMock.InhibitDelayMaxFunc: method is nil but Interface.InhibitDelayMax was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+	}{
+		Ctx: ctx,
+	}
+	mock.lockInhibitDelayMax.Lock()
+	mock.calls.InhibitDelayMax = append(mock.calls.InhibitDelayMax, callInfo)
+	mock.lockInhibitDelayMax.Unlock()
+	return mock.InhibitDelayMaxFunc(ctx)
+}
+
+// InhibitDelayMaxCalls gets all the calls that were made to InhibitDelayMax.
+// Check the length with:
+//
+//	len(mockedInterface.InhibitDelayMaxCalls())
+func (mock *InterfaceMock) InhibitDelayMaxCalls() []struct {
+	Ctx context.Context
+} {
+	var calls []struct {
+		Ctx context.Context
+	}
+	mock.lockInhibitDelayMax.RLock()
+	calls = mock.calls.InhibitDelayMax
+	mock.lockInhibitDelayMax.RUnlock()
+	return calls
+}
+
 // IsConnected calls IsConnectedFunc.
 func (mock *InterfaceMock) IsConnected() bool {
 	if mock.IsConnectedFunc == nil {
@@ -451,6 +510,42 @@ func (mock *InterfaceMock) ReloadUnitCalls() []struct {
 	return calls
 }
 
+// RestartUnit calls RestartUnitFunc.
+func (mock *InterfaceMock) RestartUnit(ctx context.Context, unit string) (string, error) {
+	if mock.RestartUnitFunc == nil {
+		panic("InterfaceMock.RestartUnitFunc: method is nil but Interface.RestartUnit was just called")
+	}
+	callInfo := struct {
+		Ctx  context.Context
+		Unit string
+	}{
+		Ctx:  ctx,
+		Unit: unit,
+	}
+	mock.lockRestartUnit.Lock()
+	mock.calls.RestartUnit = append(mock.calls.RestartUnit, callInfo)
+	mock.lockRestartUnit.Unlock()
+	return mock.RestartUnitFunc(ctx, unit)
+}
+
+// RestartUnitCalls gets all the calls that were made to RestartUnit.
+// Check the length with:
+//
+//	len(mockedInterface.RestartUnitCalls())
+func (mock *InterfaceMock) RestartUnitCalls() []struct {
+	Ctx  context.Context
+	Unit string
+} {
+	var calls []struct {
+		Ctx  context.Context
+		Unit string
+	}
+	mock.lockRestartUnit.RLock()
+	calls = mock.calls.RestartUnit
+	mock.lockRestartUnit.RUnlock()
+	return calls
+}
+
 // StartUnit calls StartUnitFunc.
 func (mock *InterfaceMock) StartUnit(ctx context.Context, unit string) (int, error) {
 	if mock.StartUnitFunc == nil {
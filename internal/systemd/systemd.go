@@ -23,13 +23,18 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"slices"
 	"strconv"
+	"sync"
 	"syscall"
+	"time"
 
 	v1 "github.com/cobaltcore-dev/openstack-hypervisor-operator/api/v1"
 	systemd "github.com/coreos/go-systemd/v22/dbus"
 	"github.com/godbus/dbus/v5"
 	logger "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/logging"
 )
 
 const (
@@ -56,11 +61,24 @@ type SystemdConn struct {
 	shutdownCh chan bool
 
 	// file descriptor for inhibition
-	fd int
+	fd     int
+	fdLock sync.Mutex
+
+	// whether the shutdown callback is registered
+	inhibitEnabled bool
 }
 
 var systemdConn *SystemdConn
 
+// ErrShutdownAborted is the cause of the cancellation of the shutdown
+// callback if the shutdown was aborted, e.g. with shutdown -c.
+var ErrShutdownAborted = errors.New("shutdown aborted")
+
+// Time before logind stops waiting for the delay inhibitor in which the
+// shutdown callback is cancelled, so that the inhibition is still released
+// in an orderly way.
+const inhibitReleaseMargin = 5 * time.Second
+
 func dialBus() (*dbus.Conn, error) {
 	conn, err := dbus.SystemBusPrivate()
 	if err != nil {
@@ -115,11 +133,11 @@ func NewSystemd(ctx context.Context) (*SystemdConn, error) {
 // EnableShutdownInhibit blocks shutdown by using systemd inhibition lock,
 // and registers a shutdown callback
 func (s *SystemdConn) EnableShutdownInhibit(ctx context.Context, cb func(context.Context) error) error {
-	if s.fd != -1 {
+	if s.inhibitEnabled {
 		return errors.New("shutdown inhibition already enabled")
 	}
 
-	log := logger.Log.WithName("systemd")
+	log := logger.Log.WithName(logging.Systemd)
 	log.Info("enabling shutdown inhibition")
 
 	// List inhibitors
@@ -133,7 +151,34 @@ func (s *SystemdConn) EnableShutdownInhibit(ctx context.Context, cb func(context
 	}
 	log.Info("existing inhibitors", "inhibitors", inhibitors)
 
-	// create inhibitor
+	if err := s.inhibit(ctx); err != nil {
+		return err
+	}
+
+	log.Info("registering shutdown callback")
+	s.inhibitEnabled = true
+	go s.handleShutdown(ctx, cb)
+
+	// register signal handler
+	if err := s.login1conn.AddMatchSignal(
+		dbus.WithMatchInterface("org.freedesktop.login1.Manager"),
+		dbus.WithMatchObjectPath("/org/freedesktop/login1"),
+		dbus.WithMatchMember("PrepareForShutdown"),
+	); err != nil {
+		return fmt.Errorf("failed to add match signal: %w", err)
+	}
+	s.login1conn.Signal(s.prepareForShutdownSignal)
+
+	return nil
+}
+
+// Take the delay inhibitor, unless it is already held.
+func (s *SystemdConn) inhibit(ctx context.Context) error {
+	s.fdLock.Lock()
+	defer s.fdLock.Unlock()
+	if s.fd != -1 {
+		return nil
+	}
 	if err := s.login1obj.CallWithContext(
 		ctx,
 		"org.freedesktop.login1.Manager.Inhibit",
@@ -146,58 +191,138 @@ func (s *SystemdConn) EnableShutdownInhibit(ctx context.Context, cb func(context
 		// ignore error if not running in k8s, so we can debug remotely
 		return fmt.Errorf("error storing file descriptor: %w", err)
 	}
+	return nil
+}
 
-	log.Info("registering shutdown callback")
-	go func() {
-		for {
-			select {
-			case <-s.shutdownCh:
-				log.Info("stopping shutdown callback goroutine")
+// Release the delay inhibitor, so that the shutdown continues.
+func (s *SystemdConn) release() error {
+	s.fdLock.Lock()
+	defer s.fdLock.Unlock()
+	if s.fd == -1 {
+		return nil
+	}
+	if err := syscall.Close(s.fd); err != nil {
+		return fmt.Errorf("failed to close file descriptor: %w", err)
+	}
+	s.fd = -1
+	return nil
+}
+
+// Run the shutdown callback on PrepareForShutdown(true) and release the
+// inhibitor once it returns. PrepareForShutdown(false) is sent if the
+// shutdown was aborted, which cancels the callback with ErrShutdownAborted
+// and takes the inhibitor again for the next shutdown.
+func (s *SystemdConn) handleShutdown(ctx context.Context, cb func(context.Context) error) {
+	log := logger.Log.WithName(logging.Systemd)
+
+	var cancel context.CancelCauseFunc
+	var done chan struct{}
+	stop := func(cause error) {
+		if cancel != nil {
+			cancel(cause)
+			<-done
+			cancel, done = nil, nil
+		}
+	}
+	for {
+		select {
+		case <-s.shutdownCh:
+			log.Info("stopping shutdown callback goroutine")
+			stop(context.Canceled)
+			return
+		case signal, ok := <-s.prepareForShutdownSignal:
+			if !ok {
+				log.Info("prepareForShutdownSignal channel closed")
+				stop(context.Canceled)
 				return
-			case signal, ok := <-s.prepareForShutdownSignal:
-				if !ok {
-					log.Info("prepareForShutdownSignal channel closed")
-					return
-				}
-				log.Info("received shutdown signal", "signal", signal)
+			}
+			log.Info("received shutdown signal", "signal", signal)
 
-				// execute the shutdown callback
-				if err := cb(ctx); err != nil {
-					log.Error(err, "failed to execute shutdown callback")
+			if !shutdownStarted(signal) {
+				log.Info("shutdown aborted, cancelling shutdown callback")
+				stop(ErrShutdownAborted)
+				if err := s.inhibit(ctx); err != nil {
+					log.Error(err, "failed to enable shutdown inhibition again")
 				}
+				continue
+			}
+			if cancel != nil {
+				// the callback is already running
+				continue
+			}
 
-				log.Info("releasing shutdown inhibition")
-				// release the inhibition lock to continue shutdown
-				if err := s.DisableShutdownInhibit(); err != nil {
-					log.Error(err, "failed to release shutdown inhibition")
+			// execute the shutdown callback within the inhibit delay
+			cbCtx, cbCancel := context.WithCancelCause(ctx)
+			cancel = cbCancel
+			timeoutCtx, cancelTimeout := cbCtx, context.CancelFunc(func() {})
+			if delay, err := s.InhibitDelayMax(ctx); err != nil {
+				log.Error(err, "failed to get inhibit delay, not limiting shutdown callback")
+			} else if delay > inhibitReleaseMargin {
+				log.Info("limiting shutdown callback to inhibit delay", "delay", delay)
+				timeoutCtx, cancelTimeout = context.WithTimeout(cbCtx, delay-inhibitReleaseMargin)
+			}
+			done = make(chan struct{})
+			go func(done chan struct{}) {
+				defer close(done)
+				defer cancelTimeout()
+				if err := cb(timeoutCtx); err != nil {
+					log.Error(err, "failed to execute shutdown callback")
 				}
-				return
+			}(done)
+		case <-done:
+			cancel(context.Canceled)
+			cancel, done = nil, nil
+
+			log.Info("releasing shutdown inhibition")
+			// release the inhibition lock to continue shutdown
+			if err := s.release(); err != nil {
+				log.Error(err, "failed to release shutdown inhibition")
 			}
 		}
-	}()
+	}
+}
 
-	// register signal handler
-	if err := s.login1conn.AddMatchSignal(
-		dbus.WithMatchInterface("org.freedesktop.login1.Manager"),
-		dbus.WithMatchObjectPath("/org/freedesktop/login1"),
-		dbus.WithMatchMember("PrepareForShutdown"),
-	); err != nil {
-		return fmt.Errorf("failed to add match signal: %w", err)
+// Check the argument of the PrepareForShutdown signal, which is true once
+// the shutdown starts and false if it was aborted.
+func shutdownStarted(signal *dbus.Signal) bool {
+	if len(signal.Body) == 0 {
+		// be on the safe side and evacuate
+		return true
 	}
-	s.login1conn.Signal(s.prepareForShutdownSignal)
+	started, ok := signal.Body[0].(bool)
+	return !ok || started
+}
 
-	return nil
+// InhibitDelayMax returns how long logind waits for delay inhibitors once
+// a shutdown started, i.e. InhibitDelayMaxSec of logind.conf.
+func (s *SystemdConn) InhibitDelayMax(ctx context.Context) (time.Duration, error) {
+	var variant dbus.Variant
+	if err := s.login1obj.CallWithContext(
+		ctx,
+		"org.freedesktop.DBus.Properties.Get",
+		0,
+		"org.freedesktop.login1.Manager",
+		"InhibitDelayMaxUSec",
+	).Store(&variant); err != nil {
+		return 0, fmt.Errorf("failed to get InhibitDelayMaxUSec: %w", err)
+	}
+	usec, ok := variant.Value().(uint64)
+	if !ok {
+		return 0, fmt.Errorf("unexpected InhibitDelayMaxUSec %s", variant)
+	}
+	return time.Duration(usec) * time.Microsecond, nil
 }
 
 // DisableShutdownInhibit releases the systemd inhibition lock
 func (s *SystemdConn) DisableShutdownInhibit() error {
-	log := logger.Log.WithName("systemd")
+	log := logger.Log.WithName(logging.Systemd)
 	log.Info("disabling shutdown inhibition")
 
-	if s.fd == -1 {
+	if !s.inhibitEnabled {
 		// nothing to do
 		return nil
 	}
+	s.inhibitEnabled = false
 
 	// remove signal handler
 	s.login1conn.RemoveSignal(s.prepareForShutdownSignal)
@@ -205,12 +330,7 @@ func (s *SystemdConn) DisableShutdownInhibit() error {
 	// stopping the shutdown callback goroutine
 	s.shutdownCh <- true
 
-	err := syscall.Close(s.fd)
-	if err != nil {
-		return fmt.Errorf("failed to close file descriptor: %w", err)
-	}
-	s.fd = -1
-	return nil
+	return s.release()
 }
 
 func (s *SystemdConn) Close() {
@@ -237,6 +357,37 @@ func (s *SystemdConn) GetUnitByName(ctx context.Context, unit string) (systemd.U
 	return units[0], nil
 }
 
+// WatchUnits subscribes to the PropertiesChanged signals of systemd and calls
+// the handler for the signals of the units. systemd sends the signals of all
+// units to subscribers, so a changed list of units doesn't need a new
+// subscription.
+func (s *SystemdConn) WatchUnits(ctx context.Context, units func() []string, handler func(unit string)) error {
+	if err := s.conn.Subscribe(); err != nil {
+		return fmt.Errorf("failed to subscribe to systemd signals: %w", err)
+	}
+	updates := make(chan *systemd.PropertiesUpdate, 16)
+	errs := make(chan error, 16)
+	s.conn.SetPropertiesSubscriber(updates, errs)
+
+	go func() {
+		log := logger.Log.WithName(logging.Systemd)
+		for {
+			select {
+			case <-ctx.Done():
+				s.conn.SetPropertiesSubscriber(nil, nil)
+				return
+			case update := <-updates:
+				if slices.Contains(units(), update.UnitName) {
+					handler(update.UnitName)
+				}
+			case err := <-errs:
+				log.Error(err, "unable to receive unit properties")
+			}
+		}
+	}()
+	return nil
+}
+
 func (s *SystemdConn) StartUnit(ctx context.Context, unit string) (int, error) {
 	return s.conn.StartUnitContext(ctx, unit, "replace", nil)
 }
@@ -245,12 +396,25 @@ func (s *SystemdConn) ReloadUnit(ctx context.Context, unit string) (int, error)
 	return s.conn.ReloadUnitContext(ctx, unit, "replace", nil)
 }
 
+func (s *SystemdConn) RestartUnit(ctx context.Context, unit string) (string, error) {
+	done := make(chan string, 1)
+	if _, err := s.conn.RestartUnitContext(ctx, unit, "replace", done); err != nil {
+		return "", err
+	}
+	select {
+	case result := <-done:
+		return result, nil
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
 var ErrFailed = errors.New("update has failed")
 
 // ReconcileSysUpdate orchestrates a systemd-sysupdate via the systemd-sysupdate@.service unit.
 func (s *SystemdConn) ReconcileSysUpdate(ctx context.Context, hv *v1.Hypervisor) (bool, error) {
 	version := hv.Spec.OperatingSystemVersion
-	log := logger.FromContext(ctx, "systemd", "reconcileSysUpdate", "version", version)
+	log := logger.FromContext(ctx, "step", "reconcileSysUpdate", "version", version).WithName(logging.Systemd)
 
 	// Needs to be connected to systemd
 	if !s.IsConnected() {
@@ -0,0 +1,33 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package systemd
+
+import (
+	"testing"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShutdownStarted(t *testing.T) {
+	assert.True(t, shutdownStarted(&dbus.Signal{Body: []any{true}}))
+	assert.False(t, shutdownStarted(&dbus.Signal{Body: []any{false}}))
+	// Evacuate if in doubt.
+	assert.True(t, shutdownStarted(&dbus.Signal{}))
+	assert.True(t, shutdownStarted(&dbus.Signal{Body: []any{"true"}}))
+}
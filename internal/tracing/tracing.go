@@ -0,0 +1,109 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package tracing exports OpenTelemetry spans of the reconcile loops, the
+// evacuation and the migration watch to an OTLP collector, so that slow
+// reconciles and the latency of libvirt calls can be followed across hosts.
+package tracing
+
+import (
+	"context"
+	"os"
+
+	"github.com/sapcc/go-api-declarations/bininfo"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/cobaltcore-dev/kvm-node-agent/internal/sys"
+)
+
+const instrumentationName = "github.com/cobaltcore-dev/kvm-node-agent"
+
+// Standard environment variables of the OTLP exporter, tracing is enabled
+// if one of them is set even without an endpoint flag.
+var endpointEnv = []string{
+	"OTEL_EXPORTER_OTLP_ENDPOINT",
+	"OTEL_EXPORTER_OTLP_TRACES_ENDPOINT",
+}
+
+// Enabled returns true if spans are exported to the given endpoint url or
+// to the endpoint configured by the environment.
+func Enabled(endpoint string) bool {
+	if endpoint != "" {
+		return true
+	}
+	for _, env := range endpointEnv {
+		if os.Getenv(env) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// Setup installs the global tracer provider exporting to the endpoint url,
+// e.g. http://otel-collector:4317, via OTLP over gRPC. An empty endpoint
+// falls back to the OTEL_EXPORTER_OTLP_* environment. The returned function
+// flushes the pending spans and has to be called on shutdown.
+func Setup(ctx context.Context, endpoint string) (func(context.Context) error, error) {
+	var opts []otlptracegrpc.Option
+	if endpoint != "" {
+		opts = append(opts, otlptracegrpc.WithEndpointURL(endpoint))
+	}
+	exporter, err := otlptracegrpc.New(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(bininfo.Component()),
+		semconv.ServiceVersion(bininfo.VersionOr("unknown")),
+		semconv.HostName(sys.Hostname),
+	))
+	if err != nil {
+		return nil, err
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{}, propagation.Baggage{}))
+	return provider.Shutdown, nil
+}
+
+// Start a span as child of the span in the context. Without Setup the span
+// is a no-op.
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// End the span and mark it as failed if err is not nil.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
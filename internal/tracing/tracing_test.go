@@ -0,0 +1,78 @@
+/*
+SPDX-FileCopyrightText: Copyright 2025 SAP SE or an SAP affiliate company and cobaltcore-dev contributors
+SPDX-License-Identifier: Apache-2.0
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestEnabled(t *testing.T) {
+	for _, env := range endpointEnv {
+		t.Setenv(env, "")
+	}
+	if Enabled("") {
+		t.Error("expected tracing to be disabled without endpoint")
+	}
+	if !Enabled("http://localhost:4317") {
+		t.Error("expected tracing to be enabled with endpoint flag")
+	}
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "http://localhost:4317")
+	if !Enabled("") {
+		t.Error("expected tracing to be enabled with endpoint environment")
+	}
+}
+
+func TestStartEnd(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	ctx, parent := Start(context.Background(), "parent")
+	_, child := Start(ctx, "child")
+	End(child, errors.New("rpc failed"))
+	End(parent, nil)
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+	if spans[0].Name() != "child" || spans[1].Name() != "parent" {
+		t.Fatalf("unexpected spans %s, %s", spans[0].Name(), spans[1].Name())
+	}
+	if spans[0].Parent().SpanID() != spans[1].SpanContext().SpanID() {
+		t.Error("expected child span to have the parent span as parent")
+	}
+	if spans[0].Status().Code != codes.Error || spans[0].Status().Description != "rpc failed" {
+		t.Errorf("expected error status, got %v", spans[0].Status())
+	}
+	if len(spans[0].Events()) != 1 {
+		t.Errorf("expected the error to be recorded, got %d events", len(spans[0].Events()))
+	}
+	if spans[1].Status().Code != codes.Unset {
+		t.Errorf("expected unset status, got %v", spans[1].Status())
+	}
+}